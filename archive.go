@@ -0,0 +1,111 @@
+package xls
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ZipOption is a functional option for configuring WriteZip.
+type ZipOption func(*zipConfig)
+
+type zipConfig struct {
+	method uint16
+}
+
+// WithZipStore stores the workbooks uncompressed instead of deflating them.
+func WithZipStore() ZipOption {
+	return func(c *zipConfig) {
+		c.method = zip.Store
+	}
+}
+
+// WriteZip writes a zip archive to w holding each Writer's XLS file under
+// its map key, in name order. Files are deflated unless WithZipStore is
+// given. Names are slash-separated paths within the archive; it is an error
+// for the map to be empty, for a name to be empty, absolute or to contain
+// "..", and for two names to differ only in case, since they would collide
+// when extracted on Windows or macOS. With WithErrorCollection, files with
+// failed cells are archived and their *WriteErrors returned together.
+func WriteZip(w io.Writer, files map[string]*Writer, opts ...ZipOption) error {
+	if len(files) == 0 {
+		return fmt.Errorf("xls: no files to zip")
+	}
+	cfg := zipConfig{method: zip.Deflate}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	names := make([]string, 0, len(files))
+	seen := make(map[string]string, len(files))
+	for name := range files {
+		if name == "" || path.IsAbs(name) || strings.Contains(name, "\\") || strings.Contains("/"+name+"/", "/../") {
+			return fmt.Errorf("xls: invalid file name %q in zip", name)
+		}
+		folded := strings.ToLower(name)
+		if other, ok := seen[folded]; ok {
+			return fmt.Errorf("xls: duplicate file names %q and %q in zip", other, name)
+		}
+		seen[folded] = name
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	var cellErrs []error
+	for _, name := range names {
+		file, err := files[name].build()
+		if file == nil {
+			return fmt.Errorf("failed to build %q: %w", name, err)
+		}
+		if err != nil {
+			cellErrs = append(cellErrs, fmt.Errorf("file %q: %w", name, err))
+		}
+		// No modification time is set, so the same workbooks always give
+		// the same archive
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: cfg.method})
+		if err != nil {
+			return fmt.Errorf("failed to add %q to zip: %w", name, err)
+		}
+		if _, err := entry.Write(file); err != nil {
+			return fmt.Errorf("failed to add %q to zip: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	return errors.Join(cellErrs...)
+}
+
+// SaveAsGzip writes the XLS file gzip-compressed to the specified path,
+// typically named with an .xls.gz extension. The gzip header carries the
+// file name without the .gz extension. Like SaveAs, it writes under a
+// temporary name and renames the file into place.
+func (w *Writer) SaveAsGzip(filename string) error {
+	data, cellErr := w.build()
+	if data == nil {
+		return cellErr
+	}
+
+	// A failed write leaves no truncated file
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Name = strings.TrimSuffix(filepath.Base(filename), ".gz")
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := saveFile(osFS{}, filename, compressed.Bytes()); err != nil {
+		return err
+	}
+	return cellErr
+}
@@ -0,0 +1,141 @@
+package xls
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteZip(t *testing.T) {
+	contents := map[string][][]interface{}{
+		"sales.xls":         {{"Month", "Sales"}, {"January", 100}},
+		"reports/stock.xls": {{"Item", "Qty"}, {"Apple", 3}},
+	}
+	files := make(map[string]*Writer)
+	for name, data := range contents {
+		w := New()
+		w.Write(data)
+		files[name] = w
+	}
+
+	for _, tt := range []struct {
+		name   string
+		opts   []ZipOption
+		method uint16
+	}{
+		{"deflate", nil, zip.Deflate},
+		{"store", []ZipOption{WithZipStore()}, zip.Store},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteZip(&buf, files, tt.opts...); err != nil {
+				t.Fatalf("WriteZip() failed: %v", err)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("Failed to open the zip: %v", err)
+			}
+			if len(zr.File) != len(contents) || zr.File[0].Name != "reports/stock.xls" {
+				t.Fatalf("Expected the files in name order, got %d files", len(zr.File))
+			}
+			for _, f := range zr.File {
+				if f.Method != tt.method {
+					t.Errorf("%s: expected method %d, got %d", f.Name, tt.method, f.Method)
+				}
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("Failed to open %s: %v", f.Name, err)
+				}
+				file, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", f.Name, err)
+				}
+				if err := Verify(file, "Sheet1", contents[f.Name]); err != nil {
+					t.Errorf("%s does not read back: %v", f.Name, err)
+				}
+			}
+
+			var again bytes.Buffer
+			WriteZip(&again, files, tt.opts...)
+			if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+				t.Error("Expected the same archive for the same workbooks")
+			}
+		})
+	}
+}
+
+func TestWriteZipErrors(t *testing.T) {
+	w := New()
+	defer w.Close()
+	tests := []struct {
+		name  string
+		files map[string]*Writer
+		want  string
+	}{
+		{"empty", map[string]*Writer{}, "no files"},
+		{"duplicate", map[string]*Writer{"Report.xls": w, "report.xls": w}, "duplicate"},
+		{"empty name", map[string]*Writer{"": w}, "invalid"},
+		{"absolute", map[string]*Writer{"/etc/a.xls": w}, "invalid"},
+		{"parent", map[string]*Writer{"../a.xls": w}, "invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WriteZip(io.Discard, tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSaveAsGzip(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := [][]interface{}{{"Name", "Qty"}, {"Apple", 3}}
+	w.Write(data)
+
+	path := filepath.Join(t.TempDir(), "report.xls.gz")
+	if err := w.SaveAsGzip(path); err != nil {
+		t.Fatalf("SaveAsGzip() failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open the file: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read the gzip header: %v", err)
+	}
+	if zr.Name != "report.xls" {
+		t.Errorf("Expected the gzip name report.xls, got %q", zr.Name)
+	}
+	file, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if err := Verify(file, "Sheet1", data); err != nil {
+		t.Errorf("Decompressed file does not read back: %v", err)
+	}
+
+	// A file that cannot be put in place leaves nothing behind
+	dir := t.TempDir()
+	taken := filepath.Join(dir, "taken.xls.gz")
+	if err := os.Mkdir(taken, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SaveAsGzip(taken); err == nil {
+		t.Error("Expected an error saving over a directory")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the directory to be left, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,331 @@
+package xls
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+)
+
+// benchmarkInput generates the data of a benchmark: rows by cols cells of
+// one kind. The generator is seeded, so every run writes the same workbook.
+//
+//   - numeric: floats and integers
+//   - unique: strings that are all different
+//   - repeated: strings drawn from 100 distinct values
+//   - mixed: a text, integer, float, bool and repeated-string column cycle
+func benchmarkInput(kind string, rows, cols int) [][]interface{} {
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([][]interface{}, rows)
+	for i := range data {
+		row := make([]interface{}, cols)
+		for j := range row {
+			switch kind {
+			case "numeric":
+				if j%2 == 0 {
+					row[j] = rng.Float64() * 1e6
+				} else {
+					row[j] = rng.IntN(1 << 20)
+				}
+			case "unique":
+				row[j] = fmt.Sprintf("r%dc%d-%08x", i, j, rng.Uint32())
+			case "repeated":
+				row[j] = fmt.Sprintf("value %d", rng.IntN(100))
+			case "mixed":
+				switch j % 5 {
+				case 0:
+					row[j] = fmt.Sprintf("id-%d-%d", i, j)
+				case 1:
+					row[j] = rng.IntN(1000)
+				case 2:
+					row[j] = rng.Float64() * 100
+				case 3:
+					row[j] = rng.IntN(2) == 0
+				default:
+					row[j] = fmt.Sprintf("category %d", rng.IntN(20))
+				}
+			}
+		}
+		data[i] = row
+	}
+	return data
+}
+
+// BenchmarkSaveAs measures writing a workbook to disk. Medians of three
+// runs of go test -bench SaveAs -benchmem on a single-core Linux VM:
+//
+//	                   before                          after
+//	numeric-10kx10      35.1 ms   17.7 MB   220263     20.9 ms    9.5 MB    94
+//	unique-10kx10      183.2 ms  114.9 MB   720860     84.2 ms   43.9 MB   656
+//	repeated-10kx10     39.3 ms   15.3 MB   220791     21.6 ms    8.7 MB   115
+//	mixed-50kx20       559.3 ms  299.3 MB  3101488    351.8 ms  129.3 MB  1182
+//
+// The two hotspots were the allocation of every record header and body,
+// which escaped through the io.Writer, and the SST encoder, which built a
+// UTF-16 transcoder and an intermediate slice per string. Records written to
+// a bytes.Buffer no longer allocate, SST strings are appended to a buffer
+// sized up front, and the container no longer copies the workbook stream to
+// pad it. The golden files are unchanged.
+func BenchmarkSaveAs(b *testing.B) {
+	benchmarks := []struct {
+		kind       string
+		rows, cols int
+	}{
+		{"numeric", 10000, 10},
+		{"unique", 10000, 10},
+		{"repeated", 10000, 10},
+		{"mixed", 50000, 20},
+	}
+	for _, bm := range benchmarks {
+		b.Run(fmt.Sprintf("%s-%dkx%d", bm.kind, bm.rows/1000, bm.cols), func(b *testing.B) {
+			data := benchmarkInput(bm.kind, bm.rows, bm.cols)
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := New()
+				w.Write(data)
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSaveAsSheets measures writing a workbook of 10 sheets of 50k rows
+// of mixed data, one sheet at a time and with the default concurrency. The
+// SST, the globals and the container are still written by one goroutine, so
+// the speedup is bounded by the share of the sheets in the total. On the
+// single-core VM of BenchmarkSaveAs both take about 670 ms, and 700 ms for
+// the concurrent path forced to 4 workers, which adds a pass over the
+// strings; no speedup can be shown there.
+func BenchmarkSaveAsSheets(b *testing.B) {
+	data := benchmarkInput("mixed", 50000, 5)
+	for _, bm := range []struct {
+		name        string
+		concurrency int
+	}{
+		{"sequential", 1},
+		{"concurrent", 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := New(WithConcurrency(bm.concurrency))
+				w.Write(data)
+				for j := 2; j <= 10; j++ {
+					s, _ := w.AddSheet(fmt.Sprintf("Sheet%d", j))
+					s.Write(data)
+				}
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCellConverter measures the mixed 50k x 20 workbook of
+// BenchmarkSaveAs without a cell converter and with one that returns every
+// value unchanged. Without one, Write only checks that none is set; the
+// converter adds one call per cell and no allocations while it returns the
+// values unchanged. Medians of three runs on the single-core VM of
+// BenchmarkSaveAs: 443 ms unset and 387 ms with the converter, within the
+// noise of the VM, with the same 1179 allocations.
+func BenchmarkCellConverter(b *testing.B) {
+	data := benchmarkInput("mixed", 50000, 20)
+	identity := func(row, col int, v interface{}) (interface{}, *Style, error) {
+		return v, nil, nil
+	}
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{"unset", nil},
+		{"identity", []Option{WithCellConverter(identity)}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := New(bm.opts...)
+				if err := w.Write(data); err != nil {
+					b.Fatal(err)
+				}
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSaveAsUniqueURLs measures writing 1M unique URLs, 62,500 rows
+// of 16, with and without string deduplication. Without it the SST has no map of
+// the strings to their indices, which costs memory and time and saves
+// nothing on data where no string repeats; the file is the same size.
+// Medians on the single-core VM of BenchmarkSaveAs:
+//
+//	                  time      bytes     allocs
+//	dedup           1.78 s   688.7 MB     8400
+//	without-dedup   0.78 s   577.1 MB      200
+//	auto-tuning     0.82 s   577.9 MB      280
+//
+// Auto-tuning samples the first 10,000 strings, finds them all unique and
+// turns deduplication off.
+func BenchmarkSaveAsUniqueURLs(b *testing.B) {
+	data := make([][]interface{}, 62500)
+	for i := range data {
+		data[i] = make([]interface{}, 16)
+		for j := range data[i] {
+			n := i*16 + j
+			data[i][j] = fmt.Sprintf("https://example.com/items/%d?ref=%08x", n, uint32(n)*2654435761)
+		}
+	}
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{"dedup", nil},
+		{"without-dedup", []Option{WithoutStringDeduplication()}},
+		{"auto-tuning", []Option{WithAutoTuning()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := New(bm.opts...)
+				w.Write(data)
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRangeStyle measures styling a full sheet of 60k rows of 20
+// columns with one SetRangeStyle call, then banding every other row and
+// bordering a block over it, against the same styles set cell by cell
+// with SetCellStyle. The 30,002 ranges are resolved by a sweep over the
+// rows, while the cell styles are 1.2M map entries. On the single-core VM
+// of BenchmarkSaveAs:
+//
+//	          time      bytes     allocs
+//	ranges   0.52 s   152.4 MB       215
+//	cells    3.94 s   549.5 MB    368887
+func BenchmarkRangeStyle(b *testing.B) {
+	const rows, cols = 60000, 20
+	data := benchmarkInput("numeric", rows, cols)
+	base := Style{NumberFormat: "#,##0.00"}
+	band := Style{NumberFormat: "#,##0.00", Fill: ColorLightYellow}
+	block := Style{NumberFormat: "#,##0.00", Border: Border{Top: BorderThin, Bottom: BorderThin}}
+	for _, bm := range []struct {
+		name  string
+		style func(w *Writer)
+	}{
+		{"ranges", func(w *Writer) {
+			w.SetRangeStyle(0, rows-1, 0, cols-1, base)
+			for r := 1; r < rows; r += 2 {
+				w.SetRangeStyle(r, r, 0, cols-1, band)
+			}
+			w.SetRangeStyle(100, 199, 5, 9, block)
+		}},
+		{"cells", func(w *Writer) {
+			for r := range rows {
+				for c := range cols {
+					st := base
+					switch {
+					case r >= 100 && r <= 199 && c >= 5 && c <= 9:
+						st = block
+					case r%2 == 1:
+						st = band
+					}
+					w.SetCellStyle(r, c, st)
+				}
+			}
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := New()
+				w.Write(data)
+				bm.style(w)
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWriteColumns measures writing 500k rows of 10 numeric columns,
+// as 10 sheets of 50k rows, from typed columns with WriteColumns, against
+// building the rows of interface{} values Write takes from the same
+// columns. On the single-core VM of BenchmarkSaveAs:
+//
+//	           time      bytes     allocs
+//	columns   0.70 s   611.0 MB        696
+//	rows      2.07 s   743.0 MB    5500701
+func BenchmarkWriteColumns(b *testing.B) {
+	const sheets, rows, cols = 10, 50000, 10
+	rng := rand.New(rand.NewPCG(1, 2))
+	batches := make([][]Column, sheets)
+	for i := range batches {
+		for range cols {
+			values := make([]float64, rows)
+			for r := range values {
+				values[r] = rng.Float64() * 1e6
+			}
+			batches[i] = append(batches[i], Float64Column{Values: values})
+		}
+	}
+	for _, bm := range []struct {
+		name  string
+		write func(s *SheetWriter, batch []Column) error
+	}{
+		{"columns", func(s *SheetWriter, batch []Column) error {
+			return s.WriteColumns(nil, batch)
+		}},
+		{"rows", func(s *SheetWriter, batch []Column) error {
+			data := make([][]interface{}, rows)
+			for r := range data {
+				row := make([]interface{}, cols)
+				for c, col := range batch {
+					row[c] = col.Cell(r)
+				}
+				data[r] = row
+			}
+			return s.Write(data)
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := New()
+				for j, batch := range batches {
+					s := w.sheets[0]
+					if j > 0 {
+						s, _ = w.AddSheet(fmt.Sprintf("Sheet%d", j+1))
+					}
+					if err := bm.write(s, batch); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
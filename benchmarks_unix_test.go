@@ -0,0 +1,58 @@
+//go:build unix
+
+package xls
+
+import (
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// BenchmarkLowMemorySST measures the peak RSS of writing 2M unique strings
+// with the SST in memory and in a temporary file. The peak of a process
+// never goes down, so run each variant in a process of its own:
+//
+//	go test -run '^$' -bench 'LowMemorySST/memory$' -benchtime 1x
+//	go test -run '^$' -bench 'LowMemorySST/spill$' -benchtime 1x
+//
+// The 2M strings fill 50,000 rows of 40 columns, within the row limit. On
+// the single-core VM of BenchmarkSaveAs the input alone takes 145 MB and the
+// peak is about 860 MB in memory and 360 MB spilled. With WithLowMemorySST,
+// SaveAs copies the workbook stream from a temporary file, as WriteChunks
+// does; building the stream and the container in memory instead, the
+// spilled peak was 740 MB.
+func BenchmarkLowMemorySST(b *testing.B) {
+	data := benchmarkInput("unique", 50000, 40)
+	for _, bm := range []struct {
+		name string
+		opt  Option
+	}{
+		{"memory", WithSSTSpillThreshold(-1)},
+		{"spill", WithLowMemorySST()},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "bench.xls")
+			for i := 0; i < b.N; i++ {
+				w := New(bm.opt)
+				w.Write(data)
+				if err := w.SaveAs(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(peakRSS(), "peak-MB")
+		})
+	}
+}
+
+// peakRSS returns the peak resident set size of the process in megabytes.
+func peakRSS() float64 {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	if runtime.GOOS == "darwin" {
+		return float64(ru.Maxrss) / (1 << 20) // Bytes
+	}
+	return float64(ru.Maxrss) / (1 << 10) // Kilobytes
+}
@@ -0,0 +1,103 @@
+package xls
+
+// BoolRendering is how bool cells are written: see BoolNative, BoolText
+// and BoolNumeric. The zero value leaves the choice to the Writer, which
+// writes them natively unless WithBoolRendering says otherwise.
+type BoolRendering struct {
+	mode    boolMode
+	yes, no string
+}
+
+type boolMode int
+
+const (
+	boolDefault boolMode = iota
+	boolNative
+	boolText
+	boolNumeric
+)
+
+// BoolNative writes bool cells as booleans, which Excel displays as TRUE
+// and FALSE in the language of its user interface.
+func BoolNative() BoolRendering {
+	return BoolRendering{mode: boolNative}
+}
+
+// BoolText writes bool cells as the text trueStr or falseStr, such as "YES"
+// and "NO".
+func BoolText(trueStr, falseStr string) BoolRendering {
+	return BoolRendering{mode: boolText, yes: trueStr, no: falseStr}
+}
+
+// BoolNumeric writes bool cells as the numbers 1 and 0.
+func BoolNumeric() BoolRendering {
+	return BoolRendering{mode: boolNumeric}
+}
+
+// WithBoolRendering sets how bool cells are written: natively, as text or
+// as numbers. The Bools field of a ColumnSpec overrides it for a column.
+func WithBoolRendering(r BoolRendering) Option {
+	return func(w *Writer) {
+		w.bools = r
+	}
+}
+
+// boolRendering returns the rendering of the bool cells of a column.
+func (s *SheetWriter) boolRendering(col int) BoolRendering {
+	if r, ok := s.boolColumns[col]; ok {
+		return r
+	}
+	return s.w.bools
+}
+
+// boolValue returns the bool of a cell, or false if it holds none.
+func boolValue(value interface{}) (b, ok bool) {
+	if sc, styled := value.(StyledCell); styled {
+		value = sc.Value
+	}
+	b, ok = value.(bool)
+	return b, ok
+}
+
+// boolText returns the text a bool cell is written as under BoolText, or
+// false if it is not written as text.
+func (s *SheetWriter) boolText(col int, value interface{}) (string, bool) {
+	b, ok := boolValue(value)
+	if !ok {
+		return "", false
+	}
+	r := s.boolRendering(col)
+	if r.mode != boolText {
+		return "", false
+	}
+	if b {
+		return r.yes, true
+	}
+	return r.no, true
+}
+
+// boolNumber returns the number a bool cell is written as under
+// BoolNumeric, or false if it is not written as a number.
+func (s *SheetWriter) boolNumber(col int, value interface{}) (float64, bool) {
+	b, ok := boolValue(value)
+	if !ok || s.boolRendering(col).mode != boolNumeric {
+		return 0, false
+	}
+	if b {
+		return 1, true
+	}
+	return 0, true
+}
+
+// renderedText returns the text a cell that holds no string is written as:
+// a number under GeneralText, a bool under BoolText or a byte slice under
+// WithByteEncoding.
+func (s *SheetWriter) renderedText(row, col int, value interface{}) (string, bool) {
+	if str, ok := s.generalText(row, col, value); ok {
+		return str, true
+	}
+	if str, ok := s.w.byteText(value); ok {
+		return str, true
+	}
+	return s.boolText(col, value)
+}
@@ -0,0 +1,91 @@
+package xls
+
+import (
+	"bytes"
+	"testing"
+)
+
+// readBack writes w and returns its first sheet as the reader sees it.
+func readBack(t *testing.T, w *Writer) *Sheet {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	return wb.Sheets()[0]
+}
+
+func TestWithBoolRendering(t *testing.T) {
+	data := [][]interface{}{{true, false, Styled(true, Style{Font: Font{Bold: true}}), "YES"}}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want []Cell
+	}{
+		{"default", nil, []Cell{
+			{Kind: KindBool, Value: true}, {Kind: KindBool, Value: false}, {Kind: KindBool, Value: true},
+		}},
+		{"native", []Option{WithBoolRendering(BoolNative())}, []Cell{
+			{Kind: KindBool, Value: true}, {Kind: KindBool, Value: false}, {Kind: KindBool, Value: true},
+		}},
+		{"text", []Option{WithBoolRendering(BoolText("YES", "NO"))}, []Cell{
+			{Kind: KindText, Value: "YES"}, {Kind: KindText, Value: "NO"}, {Kind: KindText, Value: "YES"},
+		}},
+		{"hybrid text", []Option{WithBoolRendering(BoolText("YES", "NO")), WithHybridStrings(3)}, []Cell{
+			{Kind: KindText, Value: "YES"}, {Kind: KindText, Value: "NO"}, {Kind: KindText, Value: "YES"},
+		}},
+		{"numeric", []Option{WithBoolRendering(BoolNumeric())}, []Cell{
+			{Kind: KindNumber, Value: 1.0}, {Kind: KindNumber, Value: 0.0}, {Kind: KindNumber, Value: 1.0},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := New(append(tc.opts, WithPostWriteVerification())...)
+			w.Write(data)
+			sheet := readBack(t, w)
+			for col, want := range append(tc.want, Cell{Kind: KindText, Value: "YES"}) {
+				if got := sheet.Cell(0, col); got.Kind != want.Kind || got.Value != want.Value {
+					t.Errorf("Column %d: expected %s %v, got %s %v", col, want.Kind, want.Value, got.Kind, got.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaBoolRendering(t *testing.T) {
+	schema := ColumnSchema{
+		{Name: "active", Type: ColumnBool, Bools: BoolText("Y", "N")},
+		{Name: "flag", Type: ColumnBool, Bools: BoolNative()},
+		{Name: "count", Type: ColumnBool},
+	}
+	w := New(WithSchema(schema), WithBoolRendering(BoolNumeric()), WithPostWriteVerification())
+	w.Write([][]interface{}{
+		{"active", "flag", "count", "other"},
+		{"yes", true, "no", false},
+	})
+	sheet := readBack(t, w)
+	want := []Cell{
+		{Kind: KindText, Value: "Y"},
+		{Kind: KindBool, Value: true},
+		{Kind: KindNumber, Value: 0.0},
+		{Kind: KindNumber, Value: 0.0},
+	}
+	for col, want := range want {
+		if got := sheet.Cell(1, col); got.Kind != want.Kind || got.Value != want.Value {
+			t.Errorf("Column %d: expected %s %v, got %s %v", col, want.Kind, want.Value, got.Kind, got.Value)
+		}
+	}
+
+	// The overrides follow the headers of each Write
+	w.Write([][]interface{}{{"other", "active"}, {true, false}})
+	sheet = readBack(t, w)
+	if got := sheet.Cell(1, 0); got.Kind != KindNumber || got.Value != 1.0 {
+		t.Errorf("Expected the Writer's rendering for a column without an override, got %s %v", got.Kind, got.Value)
+	}
+	if got := sheet.Cell(1, 1); got.Kind != KindText || got.Value != "N" {
+		t.Errorf("Expected the override of the moved column, got %s %v", got.Kind, got.Value)
+	}
+}
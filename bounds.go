@@ -0,0 +1,97 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// ErrOutOfRange is returned for a coordinate, count or length that does
+// not fit in a BIFF8 file, such as a cell beyond row 65,536 or column 256,
+// or a cell text longer than 32,767 characters.
+var ErrOutOfRange = errors.New("xls: out of range")
+
+const (
+	// maxTextLength is the most characters, in UTF-16 code units, of a
+	// cell text.
+	maxTextLength = 32767
+
+	// maxShortText is the most characters of a font name or number format.
+	maxShortText = 255
+
+	// maxXFs is the most XF records Excel reads.
+	maxXFs = 4050
+)
+
+// rowIdx and colIdx are a zero-based row and column within a worksheet.
+// They are only made by cellIndex, so the records written with them cannot
+// silently wrap around.
+type (
+	rowIdx uint16
+	colIdx uint16
+)
+
+// cellIndex checks a zero-based row and column.
+func cellIndex(row, col int) (rowIdx, colIdx, error) {
+	if row < 0 || row > maxRow || col < 0 || col > maxColumn {
+		return 0, 0, fmt.Errorf("%w: cell (%d, %d), the last is (%d, %d)", ErrOutOfRange, row, col, maxRow, maxColumn)
+	}
+	return rowIdx(row), colIdx(col), nil
+}
+
+// textLength returns the length of s in UTF-16 code units.
+func textLength(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// checkData checks that the data of the sheet has no value beyond the last
+// row or column of the Limits of its Writer. Empty cells there are allowed,
+// as they are not written.
+func (s *SheetWriter) checkData() error {
+	limits := s.w.limits
+	if d := s.cols; d != nil {
+		for rowIndex := 0; rowIndex < d.rows; rowIndex++ {
+			if rowIndex < limits.MaxRows && d.width() <= limits.MaxCols {
+				continue
+			}
+			for colIndex := 0; colIndex < d.width(); colIndex++ {
+				if !d.present(rowIndex, colIndex) {
+					continue
+				}
+				if err := limits.checkCell(rowIndex, colIndex, false); err != nil {
+					return fmt.Errorf("sheet %q: %w", s.name, err)
+				}
+			}
+		}
+		return nil
+	}
+	for rowIndex, row := range s.data {
+		if rowIndex < limits.MaxRows && len(row) <= limits.MaxCols {
+			continue
+		}
+		for colIndex, cell := range row {
+			if cell == nil {
+				continue
+			}
+			if err := limits.checkCell(rowIndex, colIndex, false); err != nil {
+				return fmt.Errorf("sheet %q: %w", s.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkStyleText checks the font name and number format of a style.
+func checkStyleText(st Style) error {
+	if n := textLength(st.Font.Name); n > maxShortText {
+		return fmt.Errorf("%w: font name of %d characters, more than %d", ErrOutOfRange, n, maxShortText)
+	}
+	if n := textLength(st.NumberFormat); n > maxShortText {
+		return fmt.Errorf("%w: number format of %d characters, more than %d", ErrOutOfRange, n, maxShortText)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// boundaryValues are coordinates around the limits of a sheet.
+var boundaryValues = []int{
+	math.MinInt, -65537, -256, -1, 0, 1,
+	maxColumn, maxColumn + 1, maxRow, maxRow + 1, 1 << 20, math.MaxInt,
+}
+
+func validRow(row int) bool { return row >= 0 && row <= maxRow }
+func validCol(col int) bool { return col >= 0 && col <= maxColumn }
+
+// errSkip is returned by a boundsCase for arguments it cannot try.
+var errSkip = errors.New("skip")
+
+// boundsCase is a public method taking coordinates: valid reports whether
+// the arguments are in range, run calls the method and check, if set, looks
+// for what it wrote in the file.
+type boundsCase struct {
+	name  string
+	valid func(a, b int) bool
+	run   func(w *Writer, a, b int) error
+	check func(wb *Workbook, a, b int) error
+}
+
+// cellAt checks that the first sheet has "x" at row a, column b.
+func cellAt(wb *Workbook, a, b int) error {
+	if got := wb.Sheets()[0].Cell(a, b); got.Kind != KindText || got.FormattedValue() != "x" {
+		return fmt.Errorf("expected \"x\" at (%d, %d), got %v", a, b, got)
+	}
+	return nil
+}
+
+var boundsCases = []boundsCase{
+	{
+		name:  "SetCell",
+		valid: func(a, b int) bool { return validRow(a) && validCol(b) },
+		run:   func(w *Writer, a, b int) error { return w.SetCell(a, b, "x") },
+		check: cellAt,
+	},
+	{
+		name:  "SetCellStyle",
+		valid: func(a, b int) bool { return validRow(a) && validCol(b) },
+		run: func(w *Writer, a, b int) error {
+			w.SetCellStyle(a, b, Style{Font: Font{Bold: true}})
+			return nil
+		},
+	},
+	{
+		name:  "SetRowStyle",
+		valid: func(a, b int) bool { return validRow(a) },
+		run: func(w *Writer, a, b int) error {
+			w.SetRowStyle(a, Style{Font: Font{Bold: true}})
+			return nil
+		},
+	},
+	{
+		name:  "SetColStyle",
+		valid: func(a, b int) bool { return validCol(b) },
+		run: func(w *Writer, a, b int) error {
+			w.SetColStyle(b, Style{Font: Font{Bold: true}})
+			return nil
+		},
+	},
+	{
+		name:  "SetColWidth",
+		valid: func(a, b int) bool { return validCol(b) },
+		run: func(w *Writer, a, b int) error {
+			w.sheets[0].SetColWidth(b, 12)
+			return nil
+		},
+	},
+	{
+		name:  "FreezePanes",
+		valid: func(a, b int) bool { return validRow(a) && validCol(b) },
+		run: func(w *Writer, a, b int) error {
+			w.sheets[0].FreezePanes(a, b)
+			return nil
+		},
+	},
+	{
+		name:  "FormatAsTable",
+		valid: func(a, b int) bool { return validRow(a) && validCol(b) },
+		run:   func(w *Writer, a, b int) error { return w.FormatAsTable(a, a, b, b, TableStyle{}) },
+	},
+	{
+		name:  "InsertRow",
+		valid: func(a, b int) bool { return a == 0 },
+		run:   func(w *Writer, a, b int) error { return w.InsertRow(a, []interface{}{"x"}) },
+	},
+	{
+		name:  "DeleteRow",
+		valid: func(a, b int) bool { return false },
+		run:   func(w *Writer, a, b int) error { return w.DeleteRow(a) },
+	},
+	{
+		name:  "Write",
+		valid: func(a, b int) bool { return validRow(a) && validCol(b) },
+		run: func(w *Writer, a, b int) error {
+			if a < 0 || b < 0 || a > maxRow+1 || b > maxColumn+1 {
+				return errSkip // Data cannot hold the cell
+			}
+			data := make([][]interface{}, a+1)
+			data[a] = make([]interface{}, b+1)
+			data[a][b] = "x"
+			return w.Write(data)
+		},
+		check: cellAt,
+	},
+	{
+		name:  "InsertRow cells",
+		valid: func(a, b int) bool { return validCol(b) },
+		run: func(w *Writer, a, b int) error {
+			if b < 0 || b > maxColumn+1 {
+				return errSkip
+			}
+			cells := make([]interface{}, b+1)
+			cells[b] = "x"
+			return w.InsertRow(0, cells)
+		},
+		check: func(wb *Workbook, a, b int) error { return cellAt(wb, 0, b) },
+	},
+}
+
+// runBoundsCase calls the method of c and writes the file, failing the
+// test on a panic, on an error for valid arguments, and on a file written
+// for invalid ones.
+func runBoundsCase(t *testing.T, c boundsCase, a, b int) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s(%d, %d) panicked: %v", c.name, a, b, r)
+		}
+	}()
+
+	w := New()
+	err := c.run(w, a, b)
+	if err == errSkip {
+		return
+	}
+	var file bytes.Buffer
+	if err == nil {
+		_, err = w.WriteTo(&file)
+	}
+
+	if !c.valid(a, b) {
+		if err == nil {
+			t.Errorf("%s(%d, %d): expected an error, got a file", c.name, a, b)
+		} else if !errors.Is(err, ErrOutOfRange) && !errors.Is(err, ErrRowOutOfRange) {
+			t.Errorf("%s(%d, %d): expected an out of range error, got %v", c.name, a, b, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%s(%d, %d) failed: %v", c.name, a, b, err)
+		return
+	}
+	wb, err := openWorkbook(file.Bytes())
+	if err != nil {
+		t.Errorf("%s(%d, %d): failed to read the file back: %v", c.name, a, b, err)
+		return
+	}
+	if c.check != nil {
+		if err := c.check(wb, a, b); err != nil {
+			t.Errorf("%s(%d, %d): %v", c.name, a, b, err)
+		}
+	}
+}
+
+func TestCoordinateBounds(t *testing.T) {
+	for _, c := range boundsCases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, a := range boundaryValues {
+				for _, b := range boundaryValues {
+					runBoundsCase(t, c, a, b)
+				}
+			}
+
+			// Random coordinates, most of them close to the limits
+			r := rand.New(rand.NewPCG(1, uint64(len(c.name))))
+			random := func() int {
+				if r.IntN(4) == 0 {
+					return int(r.Int64())
+				}
+				return r.IntN(2*maxRow+4) - maxRow - 2
+			}
+			for range 100 {
+				runBoundsCase(t, c, random(), r.IntN(2*maxColumn+4)-maxColumn-2)
+			}
+		})
+	}
+}
+
+func TestLengthBounds(t *testing.T) {
+	long := strings.Repeat("a", maxTextLength+1)
+	for _, tc := range []struct {
+		name string
+		set  func(w *Writer)
+	}{
+		{"text", func(w *Writer) { w.Write([][]interface{}{{long}}) }},
+		{"surrogate pairs", func(w *Writer) { w.Write([][]interface{}{{strings.Repeat("😀", maxTextLength/2+1)}}) }},
+		{"stringer", func(w *Writer) { w.Write([][]interface{}{{[]byte(long)}}) }},
+		{"cached formula result", func(w *Writer) {
+			w.Write([][]interface{}{{FormulaCell{Expr: "A2", Cached: long}}})
+		}},
+		{"number format", func(w *Writer) {
+			w.SetColStyle(0, Style{NumberFormat: strings.Repeat("0", maxShortText+1)})
+		}},
+		{"font name", func(w *Writer) {
+			w.SetCellStyle(0, 0, Style{Font: Font{Name: strings.Repeat("F", maxShortText+1)}})
+		}},
+		{"styles", func(w *Writer) {
+			for i := range maxXFs - firstCustomXF + 1 {
+				w.SetCellStyle(i, 0, Style{NumberFormat: fmt.Sprintf("0.%d", i)})
+			}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := New()
+			tc.set(w)
+			_, err := w.WriteTo(&bytes.Buffer{})
+			if !errors.Is(err, ErrOutOfRange) {
+				t.Errorf("Expected an out of range error, got %v", err)
+			}
+		})
+	}
+
+	// The longest text fits
+	w := New()
+	w.Write([][]interface{}{{long[1:]}})
+	var file bytes.Buffer
+	if _, err := w.WriteTo(&file); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(file.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to open the workbook: %v", err)
+	}
+	if got := wb.Sheets()[0].Cell(0, 0).FormattedValue(); got != long[1:] {
+		t.Errorf("Expected %d characters, got %d", maxTextLength, len(got))
+	}
+}
+
+// TestNumberFormatUnicode checks that a number format outside Latin-1 is
+// written as UTF-16 rather than as its UTF-8 bytes.
+func TestNumberFormatUnicode(t *testing.T) {
+	w := New()
+	w.Write([][]interface{}{{1234.5}})
+	w.SetColStyle(0, Style{NumberFormat: `#,##0 "€"`})
+	var file bytes.Buffer
+	if _, err := w.WriteTo(&file); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(file.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to open the workbook: %v", err)
+	}
+	if got := wb.Sheets()[0].Cell(0, 0).FormattedValue(); got != "1,235 €" {
+		t.Errorf("Expected %q, got %q", "1,235 €", got)
+	}
+}
@@ -1,8 +1,15 @@
 package xls
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math/bits"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf16"
 )
 
 // CFB (Compound File Binary) / OLE2 container implementation for XLS (BIFF8) files
@@ -10,9 +17,11 @@ import (
 const (
 	cfbHeaderSize     = 512
 	cfbSectorSize     = 512
+	cfbV4SectorSize   = 4096
 	cfbMiniSectorSize = 64
 	cfbDIFATSize      = 109
 	cfbMaxRegSector   = 0xFFFFFFFA
+	cfbDIFATSector    = 0xFFFFFFFC
 	cfbFATSector      = 0xFFFFFFFD
 	cfbEndOfChain     = 0xFFFFFFFE
 	cfbFreeSector     = 0xFFFFFFFF
@@ -28,7 +37,7 @@ type CFBHeader struct {
 	SectorShift        uint16
 	MiniSectorShift    uint16
 	Reserved           [6]byte
-	TotalSectors       uint32
+	TotalSectors       uint32 // Directory sectors, which must be zero in version 3
 	FATSectors         uint32
 	FirstDirSector     uint32
 	TransactionSig     uint32
@@ -61,9 +70,15 @@ func NewCFBHeader() *CFBHeader {
 	return h
 }
 
-// WriteTo writes the header to the writer
-func (h *CFBHeader) WriteTo(w io.Writer) error {
-	buf := make([]byte, cfbHeaderSize)
+// WriteTo writes the header to the writer, followed in version 4 by zeros
+// up to the end of its 4096-byte sector, and returns the number of bytes
+// written. It implements io.WriterTo.
+func (h *CFBHeader) WriteTo(w io.Writer) (int64, error) {
+	size := cfbHeaderSize
+	if h.MajorVersion == 4 {
+		size = cfbV4SectorSize
+	}
+	buf := make([]byte, size)
 
 	copy(buf[0:8], h.Signature[:])
 	copy(buf[8:24], h.CLSID[:])
@@ -87,8 +102,8 @@ func (h *CFBHeader) WriteTo(w io.Writer) error {
 		binary.LittleEndian.PutUint32(buf[76+i*4:80+i*4], v)
 	}
 
-	_, err := w.Write(buf)
-	return err
+	n, err := w.Write(buf)
+	return int64(n), err
 }
 
 // CFBDirectoryEntry represents a directory entry
@@ -108,8 +123,9 @@ type CFBDirectoryEntry struct {
 	StreamSize      uint64
 }
 
-// WriteTo writes the directory entry to the writer
-func (e *CFBDirectoryEntry) WriteTo(w io.Writer) error {
+// WriteTo writes the directory entry to the writer and returns the number
+// of bytes written. It implements io.WriterTo.
+func (e *CFBDirectoryEntry) WriteTo(w io.Writer) (int64, error) {
 	buf := make([]byte, 128)
 
 	copy(buf[0:64], e.Name[:])
@@ -126,131 +142,455 @@ func (e *CFBDirectoryEntry) WriteTo(w io.Writer) error {
 	binary.LittleEndian.PutUint32(buf[116:120], e.StartSector)
 	binary.LittleEndian.PutUint64(buf[120:128], e.StreamSize)
 
-	_, err := w.Write(buf)
-	return err
+	n, err := w.Write(buf)
+	return int64(n), err
 }
 
 // stringToUTF16LE converts a string to UTF-16LE
 func stringToUTF16LE(s string) []byte {
-	runes := []rune(s)
-	buf := make([]byte, len(runes)*2)
-	for i, r := range runes {
-		binary.LittleEndian.PutUint16(buf[i*2:], uint16(r))
+	units := utf16.Encode([]rune(s))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[i*2:], u)
 	}
 	return buf
 }
 
+// cfbMiniStreamCutoff is the size from which streams are stored in regular
+// sectors; smaller ones go to the mini stream.
+const cfbMiniStreamCutoff = 4096
+
+// NamedStream is a stream of a compound file written by WriteCFBStreams.
+type NamedStream struct {
+	// Name is the name of the stream, such as "Workbook": 1 to 31 UTF-16
+	// code units, without any of /\:! and unique regardless of case.
+	Name string
+	Data []byte
+}
+
+// cfbPlan is the sector layout of a compound file. The sectors follow the
+// header in this order: the streams stored in regular sectors, the mini
+// stream, the mini FAT, the FAT, the DIFAT and the directory.
+type cfbPlan struct {
+	// starts holds the first sector of each stream, a mini sector for the
+	// streams in the mini stream, or cfbEndOfChain for empty streams
+	starts []uint32
+	mini   []bool
+
+	dataSectors       int // Regular sectors of the streams
+	miniSectors       int // Mini sectors of the streams in the mini stream
+	miniStreamSectors int // Regular sectors holding the mini stream
+	miniFATSectors    int
+	fatSectors        int
+	difatSectors      int
+	dirSectors        int
+}
+
+// planCFB lays out a compound file of sectorSize-byte sectors holding
+// streams of the given sizes.
+func planCFB(sizes []int, sectorSize int) cfbPlan {
+	p := cfbPlan{starts: make([]uint32, len(sizes)), mini: make([]bool, len(sizes))}
+	for i, size := range sizes {
+		switch {
+		case size == 0:
+			p.starts[i] = cfbEndOfChain
+		case size < cfbMiniStreamCutoff:
+			p.starts[i], p.mini[i] = uint32(p.miniSectors), true
+			p.miniSectors += (size + cfbMiniSectorSize - 1) / cfbMiniSectorSize
+		default:
+			p.starts[i] = uint32(p.dataSectors)
+			p.dataSectors += (size + sectorSize - 1) / sectorSize
+		}
+	}
+	entriesPerSector := sectorSize / 4
+	p.miniStreamSectors = (p.miniSectors*cfbMiniSectorSize + sectorSize - 1) / sectorSize
+	p.miniFATSectors = (p.miniSectors + entriesPerSector - 1) / entriesPerSector
+	p.dirSectors = (1 + len(sizes) + sectorSize/128 - 1) / (sectorSize / 128)
+
+	// Each FAT sector maps a sector per 4 bytes, including the FAT, DIFAT
+	// and directory sectors themselves. The header holds the first 109 FAT
+	// sector locations; each DIFAT sector holds as many more as it has
+	// entries, less a next pointer.
+	others := p.dataSectors + p.miniStreamSectors + p.miniFATSectors + p.dirSectors
+	for p.fatSectors = 1; ; p.fatSectors++ {
+		p.difatSectors = 0
+		if p.fatSectors > cfbDIFATSize {
+			p.difatSectors = (p.fatSectors - cfbDIFATSize + entriesPerSector - 2) / (entriesPerSector - 1)
+		}
+		if others+p.fatSectors+p.difatSectors <= p.fatSectors*entriesPerSector {
+			return p
+		}
+	}
+}
+
+// sectors returns the number of sectors after the header.
+func (p cfbPlan) sectors() int {
+	return p.dataSectors + p.miniStreamSectors + p.miniFATSectors + p.fatSectors + p.difatSectors + p.dirSectors
+}
+
+// cfbLayout returns the layout writeCFBWorkbook uses for a workbook stream
+// of streamSize bytes and the extra streams in sectors of sectorSize bytes.
+func cfbLayout(streamSize, sectorSize int, extra ...NamedStream) cfbPlan {
+	// Workbook streams are padded to the cutoff to stay out of the mini
+	// stream
+	sizes := []int{max(streamSize, cfbMiniStreamCutoff)}
+	for _, s := range extra {
+		sizes = append(sizes, len(s.Data))
+	}
+	return planCFB(sizes, sectorSize)
+}
+
+// cfbFileSize returns the size of the file writeCFBWorkbook produces for a
+// workbook stream of streamSize bytes and the extra streams in sectors of
+// sectorSize bytes: the header, which takes a whole sector, and the sectors
+// after it.
+func cfbFileSize(streamSize, sectorSize int, extra ...NamedStream) int {
+	return sectorSize * (1 + cfbLayout(streamSize, sectorSize, extra...).sectors())
+}
+
 // WriteCFB wraps BIFF8 data in a CFB container and writes it to the writer
 func WriteCFB(w io.Writer, workbookData []byte) error {
-	// Set minimum size to 4096 bytes to avoid Mini Stream requirement
-	dataSize := len(workbookData)
-	if dataSize < 4096 {
-		dataSize = 4096
+	return writeCFBWorkbook(w, workbookData, cfbSectorSize)
+}
+
+// writeCFBWorkbook writes the compound file WriteCFB does, in sectors of
+// sectorSize bytes, with the extra streams after the Workbook stream.
+func writeCFBWorkbook(w io.Writer, workbookData []byte, sectorSize int, extra ...NamedStream) error {
+	return copyCFBWorkbook(w, bytes.NewReader(workbookData), len(workbookData), sectorSize, extra...)
+}
+
+// copyCFBWorkbook writes the compound file writeCFBWorkbook does, with the
+// size bytes of the Workbook stream read from r as they are written.
+func copyCFBWorkbook(w io.Writer, r io.Reader, size, sectorSize int, extra ...NamedStream) error {
+	if pad := cfbMiniStreamCutoff - size; pad > 0 {
+		r, size = io.MultiReader(r, bytes.NewReader(make([]byte, pad))), cfbMiniStreamCutoff
+	}
+	streams := []cfbStream{{name: "Workbook", size: size, data: r}}
+	for _, s := range extra {
+		streams = append(streams, cfbStream{name: s.Name, size: len(s.Data), data: bytes.NewReader(s.Data)})
+	}
+	return copyCFBStreams(w, streams, sectorSize)
+}
+
+// WriteCFBStreams writes a compound file holding the streams, in the root
+// storage, to w. Streams of 4096 bytes or more get chains of regular
+// sectors, in the order given, and smaller ones chains of 64-byte mini
+// sectors in the mini stream. The directory lists the streams in the order
+// given, linked in the red-black tree the CFB specification requires.
+func WriteCFBStreams(w io.Writer, streams []NamedStream) error {
+	return writeCFBStreams(w, streams, cfbSectorSize)
+}
+
+// writeCFBStreams writes the compound file WriteCFBStreams does, as a
+// version 3 file of 512-byte sectors or a version 4 file of 4096-byte ones.
+func writeCFBStreams(w io.Writer, streams []NamedStream, sectorSize int) error {
+	copied := make([]cfbStream, len(streams))
+	for i, s := range streams {
+		copied[i] = cfbStream{name: s.Name, size: len(s.Data), data: bytes.NewReader(s.Data)}
+	}
+	return copyCFBStreams(w, copied, sectorSize)
+}
+
+// cfbStream is a stream of a compound file: size bytes, read from data
+// when the stream is written.
+type cfbStream struct {
+	name string
+	size int
+	data io.Reader
+}
+
+// copyCFBStreams writes the compound file writeCFBStreams does, copying
+// each stream from its reader. Only the directory and a sector of the FAT
+// at a time are held in memory, so the streams can come from files.
+func copyCFBStreams(w io.Writer, streams []cfbStream, sectorSize int) error {
+	sizes := make([]int, len(streams))
+	for i, s := range streams {
+		if err := checkStreamName(s.name); err != nil {
+			return err
+		}
+		for _, prev := range streams[:i] {
+			if strings.EqualFold(prev.name, s.name) {
+				return fmt.Errorf("xls: stream name %q is used twice", s.name)
+			}
+		}
+		sizes[i] = s.size
 	}
-	dataSectors := (dataSize + cfbSectorSize - 1) / cfbSectorSize
+	p := planCFB(sizes, sectorSize)
+	entriesPerSector := sectorSize / 4
 
-	// Sector layout:
-	// Sector 0-(dataSectors-1): Data
-	// Sector dataSectors: FAT
-	// Sector dataSectors+1: Directory
-	fatSector := dataSectors
-	dirSector := dataSectors + 1
+	miniStreamStart := p.dataSectors
+	miniFATStart := miniStreamStart + p.miniStreamSectors
+	fatStart := miniFATStart + p.miniFATSectors
+	difatStart := fatStart + p.fatSectors
+	dirStart := difatStart + p.difatSectors
 
 	header := NewCFBHeader()
-	header.FATSectors = 1
-	header.FirstDirSector = uint32(dirSector)
-	header.DIFAT[0] = uint32(fatSector)
+	if sectorSize == cfbV4SectorSize {
+		header.MajorVersion = 0x0004
+		header.SectorShift = 0x000C
+		header.TotalSectors = uint32(p.dirSectors)
+	}
+	header.FATSectors = uint32(p.fatSectors)
+	header.FirstDirSector = uint32(dirStart)
+	for i := 0; i < p.fatSectors && i < cfbDIFATSize; i++ {
+		header.DIFAT[i] = uint32(fatStart + i)
+	}
+	if p.difatSectors > 0 {
+		header.FirstDIFATSector = uint32(difatStart)
+		header.DIFATSectors = uint32(p.difatSectors)
+	}
+	if p.miniFATSectors > 0 {
+		header.FirstMiniFATSector = uint32(miniFATStart)
+		header.MiniFATSectors = uint32(p.miniFATSectors)
+	}
 
-	if err := header.WriteTo(w); err != nil {
+	if _, err := header.WriteTo(w); err != nil {
 		return err
 	}
 
-	paddedData := make([]byte, dataSectors*cfbSectorSize)
-	copy(paddedData, workbookData)
-	if _, err := w.Write(paddedData); err != nil {
+	// Streams in regular sectors, then the mini stream
+	for i, s := range streams {
+		if p.mini[i] || s.size == 0 {
+			continue
+		}
+		if err := copyPadded(w, s, sectorSize); err != nil {
+			return err
+		}
+	}
+	if p.miniSectors > 0 {
+		for i, s := range streams {
+			if !p.mini[i] {
+				continue
+			}
+			if err := copyPadded(w, s, cfbMiniSectorSize); err != nil {
+				return err
+			}
+		}
+		if pad := p.miniStreamSectors*sectorSize - p.miniSectors*cfbMiniSectorSize; pad > 0 {
+			if _, err := w.Write(make([]byte, pad)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Write the mini FAT and the FAT (File Allocation Table), from the runs
+	// of sectors of each stream in the order of the layout
+	var miniRuns, runs []cfbRun
+	for i, s := range streams {
+		if s.size == 0 {
+			continue
+		}
+		if p.mini[i] {
+			miniRuns = append(miniRuns, cfbRun{start: int(p.starts[i]), n: (s.size + cfbMiniSectorSize - 1) / cfbMiniSectorSize})
+		} else {
+			runs = append(runs, cfbRun{start: int(p.starts[i]), n: (s.size + sectorSize - 1) / sectorSize})
+		}
+	}
+	if p.miniStreamSectors > 0 {
+		runs = append(runs, cfbRun{start: miniStreamStart, n: p.miniStreamSectors}, cfbRun{start: miniFATStart, n: p.miniFATSectors})
+	}
+	runs = append(runs,
+		cfbRun{start: fatStart, n: p.fatSectors, mark: cfbFATSector},
+		cfbRun{start: difatStart, n: p.difatSectors, mark: cfbDIFATSector},
+		cfbRun{start: dirStart, n: p.dirSectors})
+	if err := writeCFBTable(w, miniRuns, p.miniFATSectors*entriesPerSector, sectorSize); err != nil {
+		return err
+	}
+	if err := writeCFBTable(w, runs, p.fatSectors*entriesPerSector, sectorSize); err != nil {
 		return err
 	}
 
-	// Write FAT (File Allocation Table)
-	fat := make([]uint32, cfbSectorSize/4)
-	for i := range fat {
-		fat[i] = cfbFreeSector
+	// Write DIFAT sectors for FAT sectors beyond the 109 held by the header
+	difatBuf := make([]byte, sectorSize)
+	for i := 0; i < p.difatSectors; i++ {
+		for j := 0; j < entriesPerSector-1; j++ {
+			v := uint32(cfbFreeSector)
+			if k := cfbDIFATSize + i*(entriesPerSector-1) + j; k < p.fatSectors {
+				v = uint32(fatStart + k)
+			}
+			binary.LittleEndian.PutUint32(difatBuf[j*4:], v)
+		}
+		next := uint32(cfbEndOfChain)
+		if i < p.difatSectors-1 {
+			next = uint32(difatStart + i + 1)
+		}
+		binary.LittleEndian.PutUint32(difatBuf[(entriesPerSector-1)*4:], next)
+		if _, err := w.Write(difatBuf); err != nil {
+			return err
+		}
 	}
 
-	for i := 0; i < dataSectors; i++ {
-		if i == dataSectors-1 {
-			fat[i] = cfbEndOfChain
-		} else {
-			fat[i] = uint32(i + 1)
+	// Write Directory
+	entries := make([]*CFBDirectoryEntry, p.dirSectors*sectorSize/128)
+	root := newCFBEntry("Root Entry", 5)
+	root.StartSector = cfbEndOfChain
+	if p.miniSectors > 0 {
+		root.StartSector = uint32(miniStreamStart)
+		root.StreamSize = uint64(p.miniSectors * cfbMiniSectorSize)
+	}
+	entries[0] = root
+	names := make([]string, len(streams))
+	for i, s := range streams {
+		e := newCFBEntry(s.name, 2)
+		e.StartSector = p.starts[i]
+		e.StreamSize = uint64(s.size)
+		entries[1+i] = e
+		names[i] = s.name
+	}
+	root.ChildDID = linkCFBTree(entries[1:1+len(streams)], names)
+	for i := 1 + len(streams); i < len(entries); i++ {
+		entries[i] = &CFBDirectoryEntry{
+			ObjectType:      0,
+			LeftSiblingDID:  cfbFreeSector,
+			RightSiblingDID: cfbFreeSector,
+			ChildDID:        cfbFreeSector,
+			StartSector:     cfbEndOfChain,
 		}
 	}
 
-	fat[fatSector] = cfbFATSector
-	fat[dirSector] = cfbEndOfChain
+	dirBuf := make([]byte, len(entries)*128)
+	for i, e := range entries {
+		e.WriteTo(&bufferWriter{buf: dirBuf[i*128 : (i+1)*128]})
+	}
+	if _, err := w.Write(dirBuf); err != nil {
+		return err
+	}
 
-	fatBuf := make([]byte, cfbSectorSize)
-	for i, v := range fat {
-		binary.LittleEndian.PutUint32(fatBuf[i*4:], v)
+	return nil
+}
+
+// checkStreamName checks that name can name a stream.
+func checkStreamName(name string) error {
+	if n := len(utf16.Encode([]rune(name))); n == 0 || n > 31 {
+		return fmt.Errorf("xls: stream name %q is %d UTF-16 code units, need 1 to 31", name, n)
+	}
+	if i := strings.IndexAny(name, `/\:!`); i >= 0 {
+		return fmt.Errorf("xls: stream name %q contains %q", name, name[i])
 	}
-	if _, err := w.Write(fatBuf); err != nil {
+	return nil
+}
+
+// cfbRun is a run of n consecutive sectors in a FAT or mini FAT: a chain,
+// or sectors marked as FAT or DIFAT sectors if mark is set.
+type cfbRun struct {
+	start, n int
+	mark     uint32
+}
+
+// writeCFBTable writes a FAT or mini FAT of the given number of entries, a
+// sector at a time, from the runs of sectors it maps, in order. The entries
+// outside the runs are free.
+func writeCFBTable(w io.Writer, runs []cfbRun, entries, sectorSize int) error {
+	buf := make([]byte, 0, sectorSize)
+	next := 0
+	put := func(v uint32) error {
+		buf = binary.LittleEndian.AppendUint32(buf, v)
+		next++
+		if len(buf) < sectorSize {
+			return nil
+		}
+		_, err := w.Write(buf)
+		buf = buf[:0]
 		return err
 	}
+	for _, r := range runs {
+		for next < r.start {
+			if err := put(cfbFreeSector); err != nil {
+				return err
+			}
+		}
+		for i := range r.n {
+			v := r.mark
+			switch {
+			case v != 0:
+			case i < r.n-1:
+				v = uint32(r.start + i + 1)
+			default:
+				v = cfbEndOfChain
+			}
+			if err := put(v); err != nil {
+				return err
+			}
+		}
+	}
+	for next < entries {
+		if err := put(cfbFreeSector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Write Directory
-	dirBuf := make([]byte, cfbSectorSize)
+// copyPadded copies the stream to w, followed by zeros up to a multiple of
+// size.
+func copyPadded(w io.Writer, s cfbStream, size int) error {
+	n, err := io.Copy(w, s.data)
+	if err != nil {
+		return err
+	}
+	if n != int64(s.size) {
+		return fmt.Errorf("xls: stream %q is %d bytes, expected %d", s.name, n, s.size)
+	}
+	if pad := (size - s.size%size) % size; pad > 0 {
+		if _, err := w.Write(make([]byte, pad)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	rootName := stringToUTF16LE("Root Entry")
-	root := &CFBDirectoryEntry{
-		NameLength:      uint16(len(rootName) + 2),
-		ObjectType:      5,
-		ColorFlag:       1,
-		LeftSiblingDID:  cfbFreeSector,
-		RightSiblingDID: cfbFreeSector,
-		ChildDID:        1,
-		StartSector:     cfbEndOfChain,
-		StreamSize:      0,
-	}
-	copy(root.Name[:], rootName)
-	root.Name[len(rootName)] = 0
-	root.Name[len(rootName)+1] = 0
-
-	wbName := stringToUTF16LE("Workbook")
-	workbook := &CFBDirectoryEntry{
-		NameLength:      uint16(len(wbName) + 2),
-		ObjectType:      2,
+// newCFBEntry returns a black directory entry without siblings or children.
+func newCFBEntry(name string, objectType byte) *CFBDirectoryEntry {
+	utf16Name := stringToUTF16LE(name)
+	e := &CFBDirectoryEntry{
+		NameLength:      uint16(len(utf16Name) + 2),
+		ObjectType:      objectType,
 		ColorFlag:       1,
 		LeftSiblingDID:  cfbFreeSector,
 		RightSiblingDID: cfbFreeSector,
 		ChildDID:        cfbFreeSector,
-		StartSector:     0,
-		StreamSize:      uint64(dataSize),
 	}
-	copy(workbook.Name[:], wbName)
-	workbook.Name[len(wbName)] = 0
-	workbook.Name[len(wbName)+1] = 0
+	copy(e.Name[:], utf16Name)
+	return e
+}
 
-	empty := &CFBDirectoryEntry{
-		ObjectType:      0,
-		LeftSiblingDID:  cfbFreeSector,
-		RightSiblingDID: cfbFreeSector,
-		ChildDID:        cfbFreeSector,
-		StartSector:     cfbEndOfChain,
+// linkCFBTree links the entries, which follow the root entry in the
+// directory, into a red-black tree ordered by name as the CFB specification
+// requires, and returns the directory index of the root of the tree. The
+// tree is balanced, with its lowest level red and the others black.
+func linkCFBTree(entries []*CFBDirectoryEntry, names []string) uint32 {
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
 	}
+	sort.Slice(order, func(a, b int) bool { return cfbNameLess(names[order[a]], names[order[b]]) })
+	height := bits.Len(uint(len(entries))) - 1
 
-	tmpBuf := make([]byte, 128)
-
-	root.WriteTo(&bufferWriter{buf: dirBuf[0:128]})
-	workbook.WriteTo(&bufferWriter{buf: dirBuf[128:256]})
-	empty.WriteTo(&bufferWriter{buf: tmpBuf})
-	copy(dirBuf[256:384], tmpBuf)
-	empty.WriteTo(&bufferWriter{buf: tmpBuf})
-	copy(dirBuf[384:512], tmpBuf)
-
-	if _, err := w.Write(dirBuf); err != nil {
-		return err
+	var link func(lo, hi, depth int) uint32
+	link = func(lo, hi, depth int) uint32 {
+		if lo >= hi {
+			return cfbFreeSector
+		}
+		mid := (lo + hi) / 2
+		e := entries[order[mid]]
+		e.LeftSiblingDID = link(lo, mid, depth+1)
+		e.RightSiblingDID = link(mid+1, hi, depth+1)
+		if depth == height && depth > 0 {
+			e.ColorFlag = 0
+		}
+		return uint32(1 + order[mid])
 	}
+	return link(0, len(entries), 0)
+}
 
-	return nil
+// cfbNameLess orders directory entry names as the CFB specification does:
+// shorter names first, then by the upper case of their UTF-16 code units.
+func cfbNameLess(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(strings.ToUpper(a))), utf16.Encode([]rune(strings.ToUpper(b)))
+	if len(ua) != len(ub) {
+		return len(ua) < len(ub)
+	}
+	return slices.Compare(ua, ub) < 0
 }
 
 // bufferWriter writes to a fixed-size buffer
@@ -0,0 +1,514 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// CFBFile is a parsed CFB (Compound File Binary) container. Its sectors
+// are read on demand, as its streams are read.
+type CFBFile struct {
+	r              io.ReaderAt
+	size           int64
+	header         []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     uint64
+	fatSectors     []uint32 // Locations of the sectors of the FAT
+	entries        []CFBEntry
+
+	mu  sync.Mutex
+	fat map[int][]uint32 // Sectors of the FAT read, by index in fatSectors
+
+	miniOnce   sync.Once
+	miniFAT    []uint32
+	miniStream []byte
+	miniErr    error
+}
+
+// CFB directory entry object types
+const (
+	CFBObjectUnknown = 0
+	CFBObjectStorage = 1
+	CFBObjectStream  = 2
+	CFBObjectRoot    = 5
+)
+
+// CFBEntry is a directory entry of a CFB container.
+type CFBEntry struct {
+	Name        string
+	ObjectType  byte
+	StartSector uint32
+	Size        uint64
+}
+
+// ReadCFB parses the CFB container held in data.
+func ReadCFB(data []byte) (*CFBFile, error) {
+	return readCFB(bytes.NewReader(data), int64(len(data)))
+}
+
+// readCFB parses the CFB container of size bytes read from r. Only the
+// header, the DIFAT and the directory are read.
+func readCFB(r io.ReaderAt, size int64) (*CFBFile, error) {
+	if size < cfbHeaderSize {
+		return nil, fmt.Errorf("%w: file too short for CFB header", ErrInvalidFormat)
+	}
+	data := make([]byte, cfbHeaderSize)
+	if _, err := r.ReadAt(data, 0); err != nil {
+		return nil, fmt.Errorf("failed to read CFB header: %w", err)
+	}
+	signature := NewCFBHeader().Signature
+	if string(data[0:8]) != string(signature[:]) {
+		return nil, fmt.Errorf("%w: missing CFB signature", ErrInvalidFormat)
+	}
+
+	f := &CFBFile{r: r, size: size, header: data, fat: make(map[int][]uint32)}
+
+	sectorShift := binary.LittleEndian.Uint16(data[30:32])
+	miniSectorShift := binary.LittleEndian.Uint16(data[32:34])
+	if sectorShift != 9 && sectorShift != 12 {
+		return nil, fmt.Errorf("%w: unsupported sector shift %d", ErrInvalidFormat, sectorShift)
+	}
+	if miniSectorShift != 6 {
+		return nil, fmt.Errorf("%w: unsupported mini sector shift %d", ErrInvalidFormat, miniSectorShift)
+	}
+	f.sectorSize = 1 << sectorShift
+	f.miniSectorSize = 1 << miniSectorShift
+	f.miniCutoff = uint64(binary.LittleEndian.Uint32(data[56:60]))
+
+	fatSectors := binary.LittleEndian.Uint32(data[44:48])
+	firstDirSector := binary.LittleEndian.Uint32(data[48:52])
+	firstDIFATSector := binary.LittleEndian.Uint32(data[68:72])
+
+	// Collect FAT sector locations from the header DIFAT and the DIFAT chain
+	var fatLocations []uint32
+	for i := 0; i < cfbDIFATSize; i++ {
+		fatLocations = append(fatLocations, binary.LittleEndian.Uint32(data[76+i*4:]))
+	}
+	entriesPerSector := f.sectorSize / 4
+	seen := make(map[uint32]bool)
+	for sector := firstDIFATSector; sector <= cfbMaxRegSector; {
+		if seen[sector] {
+			return nil, fmt.Errorf("%w: DIFAT chain contains a cycle", ErrInvalidFormat)
+		}
+		seen[sector] = true
+
+		buf, err := f.readSector(nil, sector)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < entriesPerSector-1; i++ {
+			fatLocations = append(fatLocations, binary.LittleEndian.Uint32(buf[i*4:]))
+		}
+		sector = binary.LittleEndian.Uint32(buf[(entriesPerSector-1)*4:])
+	}
+
+	for i, sector := range fatLocations {
+		if uint32(i) >= fatSectors && fatSectors != 0 {
+			break
+		}
+		if sector > cfbMaxRegSector {
+			continue
+		}
+		if (len(f.fatSectors)+1)*entriesPerSector > maxSectors {
+			return nil, fmt.Errorf("%w: more than %d sectors", ErrFileTooComplex, maxSectors)
+		}
+		f.fatSectors = append(f.fatSectors, sector)
+	}
+
+	dirData, err := f.readChain(firstDirSector, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	for i := 0; i+128 <= len(dirData); i += 128 {
+		f.entries = append(f.entries, parseCFBEntry(dirData[i:i+128]))
+	}
+	if len(f.entries) == 0 || f.entries[0].ObjectType != CFBObjectRoot {
+		return nil, fmt.Errorf("%w: missing root directory entry", ErrInvalidFormat)
+	}
+
+	return f, nil
+}
+
+// loadMini reads the mini FAT and the mini stream once, when a stream in
+// the mini stream is first read.
+func (f *CFBFile) loadMini() error {
+	f.miniOnce.Do(func() {
+		if first := binary.LittleEndian.Uint32(f.header[60:64]); first <= cfbMaxRegSector {
+			miniFATData, err := f.readChain(first, 0)
+			if err != nil {
+				f.miniErr = fmt.Errorf("failed to read mini FAT: %w", err)
+				return
+			}
+			for i := 0; i+4 <= len(miniFATData); i += 4 {
+				f.miniFAT = append(f.miniFAT, binary.LittleEndian.Uint32(miniFATData[i:]))
+			}
+		}
+
+		root := f.entries[0]
+		if root.StartSector <= cfbMaxRegSector {
+			var err error
+			if f.miniStream, err = f.readChain(root.StartSector, root.Size); err != nil {
+				f.miniErr = fmt.Errorf("failed to read mini stream: %w", err)
+			}
+		}
+	})
+	return f.miniErr
+}
+
+func parseCFBEntry(buf []byte) CFBEntry {
+	nameLen := int(binary.LittleEndian.Uint16(buf[64:66]))
+	if nameLen > 64 {
+		nameLen = 64
+	}
+	units := make([]uint16, 0, nameLen/2)
+	for i := 0; i+1 < nameLen; i += 2 {
+		u := binary.LittleEndian.Uint16(buf[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+
+	return CFBEntry{
+		Name:        string(utf16.Decode(units)),
+		ObjectType:  buf[66],
+		StartSector: binary.LittleEndian.Uint32(buf[116:120]),
+		Size:        binary.LittleEndian.Uint64(buf[120:128]),
+	}
+}
+
+// fatLen returns the number of entries of the FAT.
+func (f *CFBFile) fatLen() int {
+	return len(f.fatSectors) * (f.sectorSize / 4)
+}
+
+// next returns the FAT entry of sector, the sector following it in its
+// chain, reading the sector of the FAT holding it the first time.
+func (f *CFBFile) next(sector uint32) (uint32, error) {
+	entriesPerSector := f.sectorSize / 4
+	if int64(sector) >= int64(f.fatLen()) {
+		return 0, fmt.Errorf("%w: broken sector chain at sector %d", ErrInvalidFormat, sector)
+	}
+	index := int(sector) / entriesPerSector
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, ok := f.fat[index]
+	if !ok {
+		buf, err := f.readSector(nil, f.fatSectors[index])
+		if err != nil {
+			return 0, err
+		}
+		entries = make([]uint32, entriesPerSector)
+		for i := range entries {
+			entries[i] = binary.LittleEndian.Uint32(buf[i*4:])
+		}
+		f.fat[index] = entries
+	}
+	return entries[int(sector)%entriesPerSector], nil
+}
+
+// readSector appends the contents of a regular sector to dst. Of a sector
+// cut off by the end of the file, it appends what the file holds, and
+// returns an error.
+func (f *CFBFile) readSector(dst []byte, index uint32) ([]byte, error) {
+	start := (int64(index) + 1) * int64(f.sectorSize)
+	n := int64(f.sectorSize)
+	cut := start+n > f.size
+	if cut {
+		n = max(f.size-start, 0)
+	}
+	dst = slices.Grow(dst, int(n))
+	buf := dst[len(dst) : len(dst)+int(n)]
+	if read, err := f.r.ReadAt(buf, start); read < len(buf) {
+		return dst, fmt.Errorf("failed to read sector %d: %w", index, err)
+	}
+	dst = dst[:len(dst)+int(n)]
+	if cut {
+		return dst, fmt.Errorf("%w: sector %d beyond end of file", ErrInvalidFormat, index)
+	}
+	return dst, nil
+}
+
+// readChain reads a sector chain from the FAT. If size is non-zero, the
+// result is truncated to size bytes. On a broken chain, it returns the
+// sectors read before the break with the error.
+func (f *CFBFile) readChain(start uint32, size uint64) ([]byte, error) {
+	if size > uint64(f.size) {
+		return nil, fmt.Errorf("%w: stream size %d exceeds file size", ErrInvalidFormat, size)
+	}
+	var out []byte
+	var err error
+	for sector := start; sector != cfbEndOfChain; {
+		// Without a cycle, a chain cannot be longer than the file
+		if int64(sector) >= int64(f.fatLen()) || int64(len(out)) >= f.size {
+			return out, fmt.Errorf("%w: broken sector chain at sector %d", ErrInvalidFormat, sector)
+		}
+		if out, err = f.readSector(out, sector); err != nil {
+			return out, err
+		}
+		if size != 0 && uint64(len(out)) >= size {
+			break
+		}
+		if sector, err = f.next(sector); err != nil {
+			return out, err
+		}
+	}
+	if size != 0 {
+		if uint64(len(out)) < size {
+			return out, fmt.Errorf("%w: stream shorter than its declared size", ErrInvalidFormat)
+		}
+		out = out[:size]
+	}
+	return out, nil
+}
+
+// readMiniChain reads a chain of mini sectors from the mini stream. As
+// readChain, it returns the sectors read before a broken chain.
+func (f *CFBFile) readMiniChain(start uint32, size uint64) ([]byte, error) {
+	if err := f.loadMini(); err != nil {
+		return nil, err
+	}
+	if size > uint64(len(f.miniStream)) {
+		return nil, fmt.Errorf("%w: stream size %d exceeds mini stream size", ErrInvalidFormat, size)
+	}
+	var out []byte
+	for sector := start; uint64(len(out)) < size; {
+		if int(sector) >= len(f.miniFAT) {
+			return out, fmt.Errorf("%w: broken mini sector chain at sector %d", ErrInvalidFormat, sector)
+		}
+		begin := int(sector) * f.miniSectorSize
+		end := begin + f.miniSectorSize
+		if end > len(f.miniStream) {
+			return out, fmt.Errorf("%w: mini sector %d beyond end of mini stream", ErrInvalidFormat, sector)
+		}
+		out = append(out, f.miniStream[begin:end]...)
+		sector = f.miniFAT[sector]
+	}
+	return out[:size], nil
+}
+
+// Stream returns the contents of the named stream. Names are compared
+// case-insensitively as in the CFB specification.
+func (f *CFBFile) Stream(name string) ([]byte, error) {
+	e, err := f.streamEntry(name)
+	if err != nil {
+		return nil, err
+	}
+	if e.Size == 0 {
+		return []byte{}, nil
+	}
+	if e.Size < f.miniCutoff {
+		return f.readMiniChain(e.StartSector, e.Size)
+	}
+	return f.readChain(e.StartSector, e.Size)
+}
+
+// salvageStream returns the named stream as Stream does, or, when its
+// sector chain is broken, runs past the end of the file or is shorter than
+// the stream's size, the part of it read and the error that cut it short.
+func (f *CFBFile) salvageStream(name string) ([]byte, error) {
+	e, err := f.streamEntry(name)
+	if err != nil {
+		return nil, err
+	}
+	if e.Size < f.miniCutoff {
+		return f.readMiniChain(e.StartSector, e.Size)
+	}
+	data, err := f.readChain(e.StartSector, 0)
+	if uint64(len(data)) > e.Size {
+		return data[:e.Size], err
+	}
+	if err == nil && uint64(len(data)) < e.Size {
+		err = fmt.Errorf("%w: stream shorter than its declared size", ErrInvalidFormat)
+	}
+	return data, err
+}
+
+// streamAt returns a reader of the named stream and its size. Streams in
+// the mini stream are read at once, and those of regular sectors on
+// demand.
+func (f *CFBFile) streamAt(name string) (io.ReaderAt, int64, error) {
+	e, err := f.streamEntry(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if e.Size < f.miniCutoff {
+		data, err := f.Stream(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		return bytes.NewReader(data), int64(len(data)), nil
+	}
+	if e.Size > uint64(f.size) {
+		return nil, 0, fmt.Errorf("%w: stream size %d exceeds file size", ErrInvalidFormat, e.Size)
+	}
+	return &chainReader{f: f, chain: []uint32{e.StartSector}}, int64(e.Size), nil
+}
+
+// chainReader reads a stream of regular sectors, following its chain as
+// far as the reads need.
+type chainReader struct {
+	f     *CFBFile
+	mu    sync.Mutex
+	chain []uint32 // The sectors of the stream found so far
+}
+
+// sector returns the location of the sector of the stream at index.
+func (c *chainReader) sector(index int) (uint32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.chain) <= index {
+		next, err := c.f.next(c.chain[len(c.chain)-1])
+		if err != nil {
+			return 0, err
+		}
+		if next > cfbMaxRegSector || len(c.chain) >= c.f.fatLen() {
+			return 0, fmt.Errorf("%w: stream shorter than its declared size", ErrInvalidFormat)
+		}
+		c.chain = append(c.chain, next)
+	}
+	return c.chain[index], nil
+}
+
+func (c *chainReader) ReadAt(p []byte, off int64) (int, error) {
+	size := int64(c.f.sectorSize)
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		sector, err := c.sector(int(pos / size))
+		if err != nil {
+			return n, err
+		}
+		chunk := p[n:min(len(p), n+int(size-pos%size))]
+		start := (int64(sector)+1)*size + pos%size
+		if start+int64(len(chunk)) > c.f.size {
+			return n, fmt.Errorf("%w: sector %d beyond end of file", ErrInvalidFormat, sector)
+		}
+		if read, err := c.f.r.ReadAt(chunk, start); read < len(chunk) {
+			return n + read, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// streamEntry returns the directory entry of the named stream.
+func (f *CFBFile) streamEntry(name string) (CFBEntry, error) {
+	for _, e := range f.entries {
+		if e.ObjectType == CFBObjectStream && strings.EqualFold(e.Name, name) {
+			return e, nil
+		}
+	}
+	return CFBEntry{}, fmt.Errorf("%w: stream %q not found", ErrInvalidFormat, name)
+}
+
+// SectorSize returns the size of a regular sector in bytes.
+func (f *CFBFile) SectorSize() int {
+	return f.sectorSize
+}
+
+// MiniStreamCutoff returns the size below which streams are stored in the
+// mini stream.
+func (f *CFBFile) MiniStreamCutoff() int {
+	return int(f.miniCutoff)
+}
+
+// Entries returns the directory entries in directory order. Entry 0 is the
+// root entry.
+func (f *CFBFile) Entries() []CFBEntry {
+	return f.entries
+}
+
+// Chain returns the sectors holding the contents of e and whether they are
+// mini sectors. The root entry's chain holds the mini stream.
+func (f *CFBFile) Chain(e CFBEntry) ([]uint32, bool, error) {
+	mini := e.ObjectType == CFBObjectStream && e.Size < f.miniCutoff
+	if e.Size == 0 && e.ObjectType != CFBObjectRoot {
+		return nil, mini, nil
+	}
+	entries, next := f.fatLen(), f.next
+	if mini {
+		if err := f.loadMini(); err != nil {
+			return nil, mini, err
+		}
+		entries = len(f.miniFAT)
+		next = func(sector uint32) (uint32, error) { return f.miniFAT[sector], nil }
+	}
+
+	var chain []uint32
+	for sector := e.StartSector; sector <= cfbMaxRegSector; {
+		if int64(sector) >= int64(entries) || len(chain) > entries {
+			return nil, mini, fmt.Errorf("%w: broken sector chain at sector %d", ErrInvalidFormat, sector)
+		}
+		chain = append(chain, sector)
+		var err error
+		if sector, err = next(sector); err != nil {
+			return nil, mini, err
+		}
+	}
+	return chain, mini, nil
+}
+
+// checkHeader recomputes the sector counts of the header from the FAT and
+// the chains they describe, and reports the first that differs from the
+// header: the FAT sectors, the DIFAT sectors, the mini FAT sectors and the
+// directory sectors, which version 3 files must leave at zero.
+func (f *CFBFile) checkHeader() error {
+	header := func(offset int) uint32 { return binary.LittleEndian.Uint32(f.header[offset:]) }
+
+	var fatMarked, difatMarked uint32
+	sectors := uint32(f.size/int64(f.sectorSize) - 1) // The header takes the first sector
+	for i := 0; i < f.fatLen(); i++ {
+		next, err := f.next(uint32(i))
+		if err != nil {
+			return err
+		}
+		switch {
+		case next == cfbFATSector:
+			fatMarked++
+		case next == cfbDIFATSector:
+			difatMarked++
+		case uint32(i) >= sectors && next != cfbFreeSector:
+			return fmt.Errorf("%w: FAT entry %d is used beyond the %d sectors of the file", ErrInvalidFormat, i, sectors)
+		}
+	}
+	if n := header(44); n != fatMarked {
+		return fmt.Errorf("%w: header declares %d FAT sectors, the FAT marks %d", ErrInvalidFormat, n, fatMarked)
+	}
+	if n := header(72); n != difatMarked {
+		return fmt.Errorf("%w: header declares %d DIFAT sectors, the FAT marks %d", ErrInvalidFormat, n, difatMarked)
+	}
+	if first := header(68); (difatMarked == 0) != (first > cfbMaxRegSector) {
+		return fmt.Errorf("%w: first DIFAT sector %#x does not match %d DIFAT sectors", ErrInvalidFormat, first, difatMarked)
+	}
+
+	// Chains of the FAT are read as that of a root entry, whatever its size
+	miniFAT, _, err := f.Chain(CFBEntry{ObjectType: CFBObjectRoot, StartSector: header(60)})
+	if err != nil {
+		return err
+	}
+	if n := header(64); n != uint32(len(miniFAT)) {
+		return fmt.Errorf("%w: header declares %d mini FAT sectors, its chain has %d", ErrInvalidFormat, n, len(miniFAT))
+	}
+
+	dirSectors := uint32(0)
+	if binary.LittleEndian.Uint16(f.header[26:28]) == 4 {
+		dir, _, err := f.Chain(CFBEntry{ObjectType: CFBObjectRoot, StartSector: header(48)})
+		if err != nil {
+			return err
+		}
+		dirSectors = uint32(len(dir))
+	}
+	if n := header(40); n != dirSectors {
+		return fmt.Errorf("%w: header declares %d directory sectors, expected %d", ErrInvalidFormat, n, dirSectors)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func randomBytes(rng *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.Uint32())
+	}
+	return b
+}
+
+// checkCFBTree checks that the directory of f links its streams into a
+// red-black tree ordered by name, and returns the names in tree order.
+func checkCFBTree(t *testing.T, f *CFBFile) []string {
+	t.Helper()
+	dirData, err := f.readChain(binary.LittleEndian.Uint32(f.header[48:52]), 0)
+	if err != nil {
+		t.Fatalf("Reading the directory failed: %v", err)
+	}
+	entry := func(id uint32) []byte { return dirData[id*128 : (id+1)*128] }
+
+	var names []string
+	var walk func(id uint32, parentRed bool) int
+	walk = func(id uint32, parentRed bool) int {
+		if id == cfbFreeSector {
+			return 1
+		}
+		e := entry(id)
+		red := e[67] == 0
+		if red && parentRed {
+			t.Errorf("Red entry %d has a red parent", id)
+		}
+		left := walk(binary.LittleEndian.Uint32(e[68:72]), red)
+		names = append(names, parseCFBEntry(e).Name)
+		right := walk(binary.LittleEndian.Uint32(e[72:76]), red)
+		if left != right {
+			t.Errorf("Entry %d has %d black entries on the left and %d on the right", id, left, right)
+		}
+		if red {
+			return left
+		}
+		return left + 1
+	}
+	top := binary.LittleEndian.Uint32(entry(0)[76:80])
+	if top != cfbFreeSector && entry(top)[67] != 1 {
+		t.Error("Expected a black root of the tree")
+	}
+	walk(top, false)
+	for i := 1; i < len(names); i++ {
+		if !cfbNameLess(names[i-1], names[i]) {
+			t.Errorf("Entries %q and %q are out of order", names[i-1], names[i])
+		}
+	}
+	return names
+}
+
+func TestWriteCFBStreams(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	streams := []NamedStream{
+		{Name: "Workbook", Data: randomBytes(rng, 200*1024)},
+		{Name: "\x05SummaryInformation", Data: randomBytes(rng, 100)},
+		{Name: "Data", Data: randomBytes(rng, 5*1024)},
+		{Name: "Empty"},
+		{Name: "Cutoff", Data: randomBytes(rng, 4095)},
+	}
+	var buf bytes.Buffer
+	if err := WriteCFBStreams(&buf, streams); err != nil {
+		t.Fatalf("WriteCFBStreams() failed: %v", err)
+	}
+	f, err := ReadCFB(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+	for _, s := range streams {
+		got, err := f.Stream(s.Name)
+		if err != nil {
+			t.Errorf("Stream(%q) failed: %v", s.Name, err)
+			continue
+		}
+		if !bytes.Equal(got, s.Data) {
+			t.Errorf("Stream(%q): expected %d bytes, got %d different ones", s.Name, len(s.Data), len(got))
+		}
+	}
+
+	for i, e := range f.Entries()[1:6] {
+		if e.Name != streams[i].Name || e.Size != uint64(len(streams[i].Data)) {
+			t.Errorf("Entry %d: expected %q of %d bytes, got %q of %d", i+1, streams[i].Name, len(streams[i].Data), e.Name, e.Size)
+		}
+		_, mini, _ := f.Chain(e)
+		if wantMini := len(streams[i].Data) < 4096; mini != wantMini {
+			t.Errorf("Entry %q: expected mini %v, got %v", e.Name, wantMini, mini)
+		}
+	}
+	checkCFBTree(t, f)
+}
+
+func TestWriteCFBStreamsMany(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	var streams []NamedStream
+	for i := range 100 {
+		streams = append(streams, NamedStream{Name: fmt.Sprintf("s%d", i), Data: randomBytes(rng, 1000+i*50)})
+	}
+	var buf bytes.Buffer
+	if err := WriteCFBStreams(&buf, streams); err != nil {
+		t.Fatalf("WriteCFBStreams() failed: %v", err)
+	}
+	f, err := ReadCFB(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+	for _, s := range streams {
+		if got, err := f.Stream(s.Name); err != nil || !bytes.Equal(got, s.Data) {
+			t.Errorf("Stream(%q): expected the data back, got %d bytes, %v", s.Name, len(got), err)
+		}
+	}
+	if names := checkCFBTree(t, f); len(names) != len(streams) {
+		t.Errorf("Expected %d entries in the tree, got %d", len(streams), len(names))
+	}
+}
+
+func TestWriteCFBStreamsErrors(t *testing.T) {
+	tests := []struct {
+		streams []NamedStream
+		want    string
+	}{
+		{[]NamedStream{{Name: ""}}, "need 1 to 31"},
+		{[]NamedStream{{Name: strings.Repeat("x", 32)}}, "need 1 to 31"},
+		{[]NamedStream{{Name: "a/b"}}, `contains '/'`},
+		{[]NamedStream{{Name: "Data"}, {Name: "DATA"}}, "used twice"},
+	}
+	for _, tt := range tests {
+		err := WriteCFBStreams(&bytes.Buffer{}, tt.streams)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("WriteCFBStreams(%v): expected an error containing %q, got %v", tt.streams, tt.want, err)
+		}
+	}
+}
+
+func TestWriteCFBMatchesStreams(t *testing.T) {
+	for _, size := range []int{100, 4096, 70000} {
+		data := make([]byte, size)
+		var got, want bytes.Buffer
+		if err := WriteCFB(&got, data); err != nil {
+			t.Fatalf("WriteCFB(%d bytes) failed: %v", size, err)
+		}
+		padded := append(data, make([]byte, max(0, 4096-size))...)
+		WriteCFBStreams(&want, []NamedStream{{Name: "Workbook", Data: padded}})
+		if !bytes.Equal(got.Bytes(), want.Bytes()) || got.Len() != cfbFileSize(size, cfbSectorSize) {
+			t.Errorf("WriteCFB(%d bytes): expected the one-stream file of %d bytes, got %d", size, cfbFileSize(size, cfbSectorSize), got.Len())
+		}
+	}
+}
+
+func TestCFBHeaderCounts(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	layouts := map[string][]NamedStream{
+		"empty":  nil,
+		"mini":   {{Name: "a", Data: randomBytes(rng, 100)}, {Name: "b", Data: randomBytes(rng, 9000)}},
+		"difat":  {{Name: "Workbook", Data: make([]byte, 8<<20)}},
+		"stream": {{Name: "Workbook", Data: make([]byte, 5000)}},
+	}
+	for name, streams := range layouts {
+		var buf bytes.Buffer
+		if err := WriteCFBStreams(&buf, streams); err != nil {
+			t.Fatalf("%s: WriteCFBStreams() failed: %v", name, err)
+		}
+		f, err := ReadCFB(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: ReadCFB() failed: %v", name, err)
+		}
+		if err := f.checkHeader(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestVerifyCFBHeader(t *testing.T) {
+	data := [][]interface{}{{"a", 1}}
+	var buf bytes.Buffer
+	w := New()
+	w.Write(data)
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Sheet1", data); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		offset int
+		want   string
+	}{
+		{40, "1 directory sectors, expected 0"},
+		{44, "2 FAT sectors, the FAT marks 1"},
+		{64, "1 mini FAT sectors, its chain has 0"},
+		{72, "1 DIFAT sectors, the FAT marks 0"},
+	} {
+		file := bytes.Clone(buf.Bytes())
+		binary.LittleEndian.PutUint32(file[tt.offset:], binary.LittleEndian.Uint32(file[tt.offset:])+1)
+		err := Verify(file, "Sheet1", data)
+		if !errors.Is(err, ErrVerificationFailed) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Header offset %d: expected an error containing %q, got %v", tt.offset, tt.want, err)
+		}
+	}
+}
+
+func TestWriteCFBVersion4(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 8))
+	streams := []NamedStream{
+		{Name: "Workbook", Data: randomBytes(rng, 3<<20)},
+		{Name: "Small", Data: randomBytes(rng, 300)},
+	}
+	var buf bytes.Buffer
+	if err := writeCFBStreams(&buf, streams, cfbV4SectorSize); err != nil {
+		t.Fatalf("writeCFBStreams() failed: %v", err)
+	}
+	file := buf.Bytes()
+	if major, shift := binary.LittleEndian.Uint16(file[26:]), binary.LittleEndian.Uint16(file[30:]); major != 4 || shift != 12 {
+		t.Errorf("Expected version 4 with sector shift 12, got %d and %d", major, shift)
+	}
+	if len(file)%cfbV4SectorSize != 0 || !bytes.Equal(file[cfbHeaderSize:cfbV4SectorSize], make([]byte, cfbV4SectorSize-cfbHeaderSize)) {
+		t.Error("Expected the header padded with zeros to a whole sector")
+	}
+
+	f, err := ReadCFB(file)
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+	if f.SectorSize() != cfbV4SectorSize {
+		t.Errorf("Expected %d-byte sectors, got %d", cfbV4SectorSize, f.SectorSize())
+	}
+	for _, s := range streams {
+		if got, err := f.Stream(s.Name); err != nil || !bytes.Equal(got, s.Data) {
+			t.Errorf("Stream(%q): expected the data back, got %d bytes, %v", s.Name, len(got), err)
+		}
+	}
+	if err := f.checkHeader(); err != nil {
+		t.Error(err)
+	}
+	checkCFBTree(t, f)
+}
+
+func TestWithCFBVersion4(t *testing.T) {
+	// About 20 MB of number cells
+	data := make([][]interface{}, 65536)
+	for i := range data {
+		data[i] = make([]interface{}, 18)
+		for j := range data[i] {
+			data[i][j] = float64(i*18+j) + 0.5
+		}
+	}
+
+	sizes, fats := make(map[bool]int), make(map[bool]int)
+	for _, v4 := range []bool{false, true} {
+		opts := []Option{WithPostWriteVerification()}
+		if v4 {
+			opts = append(opts, WithCFBVersion4())
+		}
+		w := New(opts...)
+		w.Write(data)
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Fatalf("v4 %v: WriteTo() failed: %v", v4, err)
+		}
+		if want := w.Stats().EstimatedSize; int64(buf.Len()) != want {
+			t.Errorf("v4 %v: expected the estimated %d bytes, got %d", v4, want, buf.Len())
+		}
+		f, err := ReadCFB(buf.Bytes())
+		if err != nil {
+			t.Fatalf("v4 %v: ReadCFB() failed: %v", v4, err)
+		}
+		if err := f.checkHeader(); err != nil {
+			t.Errorf("v4 %v: %v", v4, err)
+		}
+		fatSectors := binary.LittleEndian.Uint32(buf.Bytes()[44:])
+		difatSectors := binary.LittleEndian.Uint32(buf.Bytes()[72:])
+		t.Logf("v4 %v: %d bytes, %d FAT and %d DIFAT sectors", v4, buf.Len(), fatSectors, difatSectors)
+		if v4 && difatSectors != 0 {
+			t.Errorf("Expected no DIFAT sectors in version 4, got %d", difatSectors)
+		}
+		sizes[v4], fats[v4] = buf.Len(), int(fatSectors)
+	}
+	if sizes[false] < 20<<20 || sizes[true] > sizes[false] {
+		t.Errorf("Expected a version 3 workbook of at least 20 MiB and a smaller version 4 one, got %d and %d bytes", sizes[false], sizes[true])
+	}
+	// FAT sectors of 4096 bytes map 64 times the bytes of 512-byte ones
+	if fats[true]*64 > fats[false]+63 {
+		t.Errorf("Expected version 4 to need a 64th of the %d FAT sectors of version 3, got %d", fats[false], fats[true])
+	}
+}
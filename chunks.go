@@ -0,0 +1,238 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WriteChunks writes the XLS file as successive chunks of exactly chunkSize
+// bytes, the last one possibly smaller, calling fn with each chunk and its
+// part number, starting at 1. This suits multipart uploads to object
+// storage, which take parts of a fixed minimum size.
+//
+// The compound file header records the size of the workbook stream, so the
+// stream is first written to a temporary file, in os.TempDir or the
+// directory set with WithTempDir, and the chunks are then read from it:
+// beyond the data of the Writer, memory use stays around chunkSize however
+// large the file. The file is removed when WriteChunks returns. With
+// WithPostWriteVerification, WithStreamTransform or WithLegacyXORPassword,
+// which need the whole stream at once, the file is built in memory as for
+// WriteTo.
+//
+// The chunk passed to fn is reused for the next one, so fn must copy the
+// bytes it keeps. Iteration stops at the first error returned by fn, which
+// WriteChunks returns.
+func (w *Writer) WriteChunks(chunkSize int, fn func(part int, data []byte) error) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("xls: chunk size %d is not positive", chunkSize)
+	}
+	cw := &chunkWriter{buf: make([]byte, 0, chunkSize), fn: fn}
+	if !w.streamsFromFile() {
+		file, err := w.build()
+		if file == nil {
+			return err
+		}
+		if _, werr := cw.Write(file); werr != nil {
+			return werr
+		}
+		if ferr := cw.flush(); ferr != nil {
+			return ferr
+		}
+		return err
+	}
+
+	f, err := w.spillFile()
+	if err != nil {
+		return err
+	}
+	defer f.close()
+	if err := f.writeTo(cw); err != nil {
+		if cw.err != nil {
+			return cw.err
+		}
+		return fmt.Errorf("failed to write CFB container: %w", err)
+	}
+	if err := cw.flush(); err != nil {
+		return err
+	}
+	return f.err
+}
+
+// chunkWriter passes the bytes written to it to fn in chunks of the
+// capacity of buf.
+type chunkWriter struct {
+	buf  []byte
+	part int
+	fn   func(part int, data []byte) error
+	err  error // The error of fn, which ends the writes
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		if c.err != nil {
+			return written, c.err
+		}
+		n := copy(c.buf[len(c.buf):cap(c.buf)], p[written:])
+		c.buf = c.buf[:len(c.buf)+n]
+		written += n
+		if len(c.buf) == cap(c.buf) {
+			c.flush()
+		}
+	}
+	return written, c.err
+}
+
+// flush passes the bytes not yet passed to fn, if any, as a chunk.
+func (c *chunkWriter) flush() error {
+	if c.err != nil || len(c.buf) == 0 {
+		return c.err
+	}
+	c.part++
+	c.err = c.fn(c.part, c.buf)
+	c.buf = c.buf[:0]
+	return c.err
+}
+
+// streamsFromFile reports whether the Workbook stream can be written to a
+// temporary file and copied from it, which the options that need the
+// whole stream or file in memory prevent.
+func (w *Writer) streamsFromFile() bool {
+	return !w.verify && w.transform == nil && w.xorPassword == ""
+}
+
+// spilledFile is an XLS file whose Workbook stream is in a temporary file:
+// the worksheet substreams, then the globals, which come first in the
+// stream.
+type spilledFile struct {
+	file       *os.File
+	tempFiles  *tempFiles
+	sheets     int // Size of the worksheet substreams
+	globals    int // Size of the globals, after them in the file
+	size       int // Size of the XLS file
+	sectorSize int
+	extra      []NamedStream
+	err        error // The *WriteErrors of WithErrorCollection, if any
+}
+
+// spillFile writes the Workbook stream to a temporary file, as build does
+// in memory, with the same checks. The worksheets are written one at a
+// time, whatever WithConcurrency says, so that they can follow one another
+// in the file.
+func (w *Writer) spillFile() (*spilledFile, error) {
+	if w.logger != nil {
+		w.recordCounts = make(map[uint16]int)
+		defer func() { w.recordCounts = nil }()
+	}
+
+	done, err := w.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if w.warn != nil {
+		w.reportWarnings()
+	}
+	file, err := os.CreateTemp(w.tempDir, "xls-stream-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream file: %w", err)
+	}
+	f := &spilledFile{file: file, tempFiles: w.tempFiles, sectorSize: w.cfbSectorSize()}
+	if f.globals, f.sheets, err = w.spillBIFF8(newSpillStream(file)); err != nil {
+		f.close()
+		if w.cellErrs != nil && w.cellErrs.aborted {
+			return nil, w.cellErrs.result(w)
+		}
+		return nil, fmt.Errorf("failed to write BIFF8 data: %w", err)
+	}
+
+	if f.extra, err = w.manifestStreams(); err != nil {
+		f.close()
+		return nil, err
+	}
+	f.size = cfbFileSize(f.globals+f.sheets, f.sectorSize, f.extra...)
+	if err := check("MaxFileBytes", int64(f.size), w.limits.MaxFileBytes, false); err != nil {
+		f.close()
+		return nil, err
+	}
+	if w.logger != nil {
+		w.logWritten(f.globals+f.sheets, f.size)
+	}
+	if w.cellErrs != nil {
+		f.err = w.cellErrs.result(w)
+	}
+	return f, nil
+}
+
+// spillBIFF8 writes the worksheet substreams to stream, then the globals,
+// and returns their sizes. The offsets of BOUNDSHEET and the positions of
+// INDEX are those of the Workbook stream, where the globals come first.
+func (w *Writer) spillBIFF8(stream *spillStream) (globalsSize, sheetsSize int, err error) {
+	if err := w.checkBIFF8(); err != nil {
+		return 0, 0, err
+	}
+
+	sst := w.newStringTable()
+	defer w.closeStringTable(sst)
+	if err := w.prepareStringTable(sst); err != nil {
+		return 0, 0, err
+	}
+	starts := make([]int, len(w.sheets))
+	for i, s := range w.sheets {
+		starts[i] = stream.Len()
+		if err := w.writeSheet(sectionStream{stream, starts[i]}, s, sst); err != nil {
+			return 0, 0, err
+		}
+	}
+	sheetsSize = stream.Len()
+	globals := sectionStream{stream, sheetsSize}
+	st, err := w.writeGlobals(globals, sst)
+	if err != nil {
+		return 0, 0, err
+	}
+	globalsSize = globals.Len()
+
+	index := w.spec().index
+	for i, s := range w.sheets {
+		sheetStart := globalsSize + starts[i]
+		var offset [4]byte
+		binary.LittleEndian.PutUint32(offset[:], uint32(sheetStart))
+		if _, err := globals.WriteAt(offset[:], int64(st.boundSheets[i])); err != nil {
+			return 0, 0, err
+		}
+		if index {
+			if err := relocateIndex(sectionStream{stream, starts[i]}, sheetStart); err != nil {
+				return 0, 0, err
+			}
+		}
+		if w.logger != nil {
+			end := sheetsSize
+			if i+1 < len(starts) {
+				end = starts[i+1]
+			}
+			w.logger.Debug("xls: sheet written", "sheet", s.name, "start", sheetStart, "end", globalsSize+end)
+		}
+	}
+	return globalsSize, sheetsSize, stream.Flush()
+}
+
+// writeTo writes the XLS file to out, copying the Workbook stream from the
+// temporary file.
+func (f *spilledFile) writeTo(out io.Writer) error {
+	stream := io.MultiReader(
+		io.NewSectionReader(f.file, int64(f.sheets), int64(f.globals)),
+		io.NewSectionReader(f.file, 0, int64(f.sheets)))
+	return copyCFBWorkbook(out, stream, f.globals+f.sheets, f.sectorSize, f.extra...)
+}
+
+// close removes the temporary file, leaving it to Close if it cannot be
+// removed.
+func (f *spilledFile) close() {
+	f.file.Close()
+	if err := os.Remove(f.file.Name()); err != nil {
+		f.tempFiles.leak(f.file.Name())
+	}
+}
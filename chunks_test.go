@@ -0,0 +1,183 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestWriteChunks(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := make([][]interface{}, 500)
+	for i := range data {
+		data[i] = []interface{}{"row", i, float64(i) / 7}
+	}
+	w.Write(data)
+
+	var whole bytes.Buffer
+	if _, err := w.WriteTo(&whole); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	for _, size := range []int{1000, 4096, whole.Len(), whole.Len() + 1} {
+		var joined bytes.Buffer
+		var sizes []int
+		err := w.WriteChunks(size, func(part int, chunk []byte) error {
+			if part != len(sizes)+1 {
+				t.Errorf("Expected part %d, got %d", len(sizes)+1, part)
+			}
+			sizes = append(sizes, len(chunk))
+			joined.Write(chunk)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WriteChunks(%d) failed: %v", size, err)
+		}
+		if !bytes.Equal(joined.Bytes(), whole.Bytes()) {
+			t.Errorf("WriteChunks(%d): joined chunks differ from WriteTo", size)
+		}
+		for i, n := range sizes {
+			if i < len(sizes)-1 && n != size || n > size || n == 0 {
+				t.Errorf("WriteChunks(%d): part %d has %d bytes", size, i+1, n)
+			}
+		}
+	}
+}
+
+func TestWriteChunksErrors(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.Write([][]interface{}{{"a"}})
+
+	for _, size := range []int{0, -1} {
+		if err := w.WriteChunks(size, func(int, []byte) error { return nil }); err == nil {
+			t.Errorf("WriteChunks(%d): expected an error", size)
+		}
+	}
+
+	errUpload := errors.New("upload failed")
+	calls := 0
+	err := w.WriteChunks(512, func(int, []byte) error {
+		calls++
+		return errUpload
+	})
+	if !errors.Is(err, errUpload) || calls != 1 {
+		t.Errorf("Expected the upload error after 1 call, got %v after %d", err, calls)
+	}
+}
+
+// chunkedBytes returns the file w writes in chunks of size bytes, joined,
+// and the error of WriteChunks.
+func chunkedBytes(w *Writer, size int) ([]byte, error) {
+	var joined bytes.Buffer
+	err := w.WriteChunks(size, func(_ int, chunk []byte) error {
+		joined.Write(chunk)
+		return nil
+	})
+	return joined.Bytes(), err
+}
+
+func TestWriteChunksSpilled(t *testing.T) {
+	strs := func(n int) [][]interface{} {
+		rows := make([][]interface{}, n)
+		for i := range rows {
+			rows[i] = []interface{}{fmt.Sprintf("name %d", i%300), i, float64(i) / 3, i%2 == 0}
+		}
+		return rows
+	}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"excel97", []Option{WithCompatibility(ProfileExcel97)}},
+		{"poi", []Option{WithCompatibility(ProfilePOI)}},
+		{"v4", []Option{WithCFBVersion4()}},
+		{"low memory SST", []Option{WithLowMemorySST()}},
+		{"concurrency", []Option{WithConcurrency(4), WithCompatibility(ProfileExcel97)}},
+		{"verification", []Option{WithPostWriteVerification()}},
+	} {
+		dir := t.TempDir()
+		w := New(append(tc.opts, WithTempDir(dir))...)
+		w.Write(strs(3000))
+		second, _ := w.AddSheet("Second")
+		second.Write(strs(70))
+		w.AddSheet("Empty")
+
+		want := writtenBytes(t, w)
+		got, err := chunkedBytes(w, 10000)
+		if err != nil {
+			t.Fatalf("%s: WriteChunks() failed: %v", tc.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: joined chunks differ from WriteTo:\n%s", tc.name, diffXLS(want, got))
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: expected the temporary files to be removed, got %d", tc.name, len(entries))
+		}
+		w.Close()
+	}
+
+	// Cells that failed, with the file
+	w, failed := collectWriter(WithErrorCollection(0), WithConcurrency(1))
+	defer w.Close()
+	var want bytes.Buffer
+	w.WriteTo(&want)
+	got, err := chunkedBytes(w, 4096)
+	var werrs *WriteErrors
+	if !errors.As(err, &werrs) || len(werrs.Cells) != len(failed) {
+		t.Fatalf("Expected the %d cells that failed, got %v", len(failed), err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Error("Expected the chunks of the file written without the failed cells")
+	}
+}
+
+// TestWriteChunksMemory checks that the heap in use while the chunks are
+// passed on does not grow with the size of the file.
+func TestWriteChunksMemory(t *testing.T) {
+	const chunkSize = 64 << 10
+	growth := func(rows, cols int) (uint64, int) {
+		w := New(WithTempDir(t.TempDir()))
+		defer w.Close()
+		data := make([][]interface{}, rows)
+		for i := range data {
+			data[i] = make([]interface{}, cols)
+			for j := range data[i] {
+				data[i][j] = float64(i) / float64(j+3)
+			}
+		}
+		w.Write(data)
+
+		var ms runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		base := ms.HeapAlloc
+		var peak uint64
+		size := 0
+		err := w.WriteChunks(chunkSize, func(_ int, chunk []byte) error {
+			size += len(chunk)
+			runtime.GC()
+			runtime.ReadMemStats(&ms)
+			peak = max(peak, ms.HeapAlloc)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WriteChunks() failed: %v", err)
+		}
+		return peak - min(peak, base), size
+	}
+
+	small, smallSize := growth(8000, 4)
+	large, largeSize := growth(32000, 16)
+	if largeSize < 10*smallSize {
+		t.Fatalf("Expected the large file to be 16 times the size, got %d and %d bytes", largeSize, smallSize)
+	}
+	if large > small+chunkSize || large > uint64(largeSize)/4 {
+		t.Errorf("Expected the heap to grow as much for %d bytes as for %d, got %d and %d", largeSize, smallSize, large, small)
+	}
+}
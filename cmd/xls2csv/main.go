@@ -0,0 +1,57 @@
+// Command xls2csv converts a worksheet of an XLS file to CSV.
+//
+// Usage:
+//
+//	xls2csv [--sheet name] [--delimiter ,] [--rfc3339] file.xls > out.csv
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/tkuchiki/go-xls"
+)
+
+func main() {
+	sheet := flag.String("sheet", "", "sheet to convert (default: the first sheet)")
+	delimiter := flag.String("delimiter", ",", `field delimiter; "\t" or "tab" for tabs`)
+	rfc3339 := flag.Bool("rfc3339", false, "render dates as RFC 3339 instead of their number format")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file.xls\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	comma := *delimiter
+	if comma == `\t` || comma == "tab" {
+		comma = "\t"
+	}
+	r, size := utf8.DecodeRuneInString(comma)
+	if r == utf8.RuneError || size != len(comma) {
+		fmt.Fprintf(os.Stderr, "xls2csv: delimiter must be a single character, got %q\n", *delimiter)
+		os.Exit(2)
+	}
+
+	opts := []xls.CSVOption{xls.WithDelimiter(r)}
+	if *rfc3339 {
+		opts = append(opts, xls.WithRFC3339Dates())
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if err := xls.ConvertToCSV(flag.Arg(0), *sheet, out, opts...); err != nil {
+		fmt.Fprintf(os.Stderr, "xls2csv: %v\n", err)
+		os.Exit(1)
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "xls2csv: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,311 @@
+// Command xlscompat writes the compatibility corpus, a workbook for each
+// combination of cell types, styles, sizes and writer options, and checks
+// every file with Verify, with the package's reader and, when LibreOffice is
+// installed, with a headless conversion of the file to CSV. It prints a
+// markdown or JSON report and exits with status 1 if any check fails and 2
+// on trouble.
+//
+// Usage:
+//
+//	xlscompat [--dir corpus] [--json] [--soffice path] [--no-libreoffice]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tkuchiki/go-xls"
+)
+
+// Check outcomes
+const (
+	pass = "pass"
+	fail = "fail"
+	skip = "skip"
+)
+
+// check is the outcome of a check of a corpus file.
+type check struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// result holds the checks of a corpus file.
+type result struct {
+	Name        string `json:"name"`
+	Types       string `json:"types"`
+	Style       string `json:"style"`
+	Size        string `json:"size"`
+	Options     string `json:"options"`
+	Verify      check  `json:"verify"`
+	Reader      check  `json:"reader"`
+	LibreOffice check  `json:"libreoffice"`
+}
+
+func main() {
+	dir := flag.String("dir", "", "directory to write the corpus to (default: a temporary directory, removed afterwards)")
+	asJSON := flag.Bool("json", false, "print the report as JSON instead of markdown")
+	soffice := flag.String("soffice", "", "path of the LibreOffice soffice command (default: soffice or libreoffice on the PATH)")
+	noLibreOffice := flag.Bool("no-libreoffice", false, "skip the LibreOffice conversion")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *noLibreOffice {
+		*soffice = ""
+	} else if *soffice == "" {
+		*soffice = findSoffice()
+	}
+	os.Exit(compat(*dir, *soffice, *asJSON))
+}
+
+// compat writes the corpus to dir, or to a temporary directory if dir is
+// empty, checks it and prints the report. It returns the exit status.
+func compat(dir, soffice string, asJSON bool) int {
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "xlscompat-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "xlscompat: %v\n", err)
+			return 2
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	results, err := run(dir, soffice)
+	out := bufio.NewWriter(os.Stdout)
+	if err == nil {
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(results)
+		} else {
+			report(out, results, soffice)
+		}
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xlscompat: %v\n", err)
+		return 2
+	}
+	for _, r := range results {
+		if r.Verify.Status == fail || r.Reader.Status == fail || r.LibreOffice.Status == fail {
+			return 1
+		}
+	}
+	return 0
+}
+
+// findSoffice returns the LibreOffice command on the PATH, or "" if there
+// is none.
+func findSoffice() string {
+	for _, name := range []string{"soffice", "libreoffice"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// run writes the corpus to dir and checks its files, with LibreOffice too
+// if soffice is not empty.
+func run(dir, soffice string) ([]result, error) {
+	cases, err := xls.GenerateCompatibilityCorpus(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var converted string
+	var convertErr error
+	if soffice != "" {
+		converted, convertErr = convert(soffice, dir, cases)
+	}
+
+	results := make([]result, len(cases))
+	for i, cc := range cases {
+		r := result{Name: cc.Name, Types: cc.Types, Style: cc.Style, Size: cc.Size, Options: cc.Options}
+		r.Verify = verify(cc)
+		r.Reader = readBack(cc)
+		switch {
+		case soffice == "":
+			r.LibreOffice = check{Status: skip}
+		case convertErr != nil:
+			r.LibreOffice = check{Status: fail, Detail: convertErr.Error()}
+		default:
+			r.LibreOffice = compareFile(filepath.Join(converted, cc.Name+".csv"), cc)
+		}
+		results[i] = r
+	}
+	return results, nil
+}
+
+// verify checks a corpus file with xls.Verify.
+func verify(cc xls.CorpusCase) check {
+	file, err := os.ReadFile(cc.Path)
+	if err == nil {
+		err = xls.Verify(file, cc.Sheet, cc.Data)
+	}
+	if err != nil {
+		return check{Status: fail, Detail: err.Error()}
+	}
+	return check{Status: pass}
+}
+
+// readBack checks a corpus file read by the package's reader and rendered
+// as CSV.
+func readBack(cc xls.CorpusCase) check {
+	var buf bytes.Buffer
+	if err := xls.ConvertToCSV(cc.Path, cc.Sheet, &buf); err != nil {
+		return check{Status: fail, Detail: err.Error()}
+	}
+	return compare(&buf, cc)
+}
+
+// convert converts the corpus files to CSV with a headless LibreOffice and
+// returns the directory of the CSV files. LibreOffice runs once for all
+// files, with a profile of its own so it does not clash with a running
+// instance.
+func convert(soffice, dir string, cases []xls.CorpusCase) (string, error) {
+	out := filepath.Join(dir, "libreoffice")
+	profile, err := filepath.Abs(filepath.Join(dir, "profile"))
+	if err != nil {
+		return "", err
+	}
+	args := []string{
+		"-env:UserInstallation=file://" + filepath.ToSlash(profile),
+		"--headless",
+		"--convert-to", "csv:Text - txt - csv (StarCalc):44,34,76", // Commas, double quotes, UTF-8
+		"--outdir", out,
+	}
+	for _, cc := range cases {
+		args = append(args, cc.Path)
+	}
+	cmd := exec.Command(soffice, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", filepath.Base(soffice), err, strings.TrimSpace(string(output)))
+	}
+	return out, nil
+}
+
+// compareFile compares the CSV file LibreOffice converted a corpus file to
+// with the data of the case.
+func compareFile(path string, cc xls.CorpusCase) check {
+	f, err := os.Open(path)
+	if err != nil {
+		return check{Status: fail, Detail: "not converted: " + err.Error()}
+	}
+	defer f.Close()
+	return compare(f, cc)
+}
+
+// compare compares a sheet rendered as CSV with the data of a case: it
+// must have as many rows, and the cells written as strings must hold their
+// text. Numbers, dates and booleans are left out, since each application
+// renders them its own way.
+func compare(r io.Reader, cc xls.CorpusCase) check {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return check{Status: fail, Detail: "invalid CSV: " + err.Error()}
+	}
+	if len(records) != len(cc.Data) {
+		return check{Status: fail, Detail: fmt.Sprintf("%d rows, expected %d", len(records), len(cc.Data))}
+	}
+	for row, cells := range cc.Data {
+		for col, cell := range cells {
+			want, ok := text(cell)
+			if !ok {
+				continue
+			}
+			got := ""
+			if col < len(records[row]) {
+				got = records[row][col]
+			}
+			if got != want {
+				return check{Status: fail, Detail: fmt.Sprintf("row %d, column %d: %q, expected %q", row+1, col+1, got, want)}
+			}
+		}
+	}
+	return check{Status: pass}
+}
+
+// text returns the text of a cell written as a string or a formula with a
+// string result.
+func text(cell interface{}) (string, bool) {
+	switch v := cell.(type) {
+	case xls.StyledCell:
+		return text(v.Value)
+	case xls.FormulaCell:
+		return text(v.Cached)
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// report prints the results as a markdown report.
+func report(w io.Writer, results []result, soffice string) {
+	failed := map[string]int{}
+	for _, r := range results {
+		for name, c := range map[string]check{"verify": r.Verify, "reader": r.Reader, "libreoffice": r.LibreOffice} {
+			if c.Status == fail {
+				failed[name]++
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "# XLS compatibility report")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d workbooks.\n\n", len(results))
+	fmt.Fprintf(w, "- Verify: %d failed\n", failed["verify"])
+	fmt.Fprintf(w, "- Reader: %d failed\n", failed["reader"])
+	if soffice == "" {
+		fmt.Fprintln(w, "- LibreOffice: skipped")
+	} else {
+		fmt.Fprintf(w, "- LibreOffice (%s): %d failed\n", soffice, failed["libreoffice"])
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "| Workbook | Types | Style | Size | Options | Verify | Reader | LibreOffice |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|---|---|")
+	for _, r := range results {
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			r.Name, r.Types, r.Style, r.Size, r.Options, r.Verify.Status, r.Reader.Status, r.LibreOffice.Status)
+	}
+
+	if failed["verify"]+failed["reader"]+failed["libreoffice"] == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Failures")
+	fmt.Fprintln(w)
+	for _, r := range results {
+		for _, c := range []struct {
+			name string
+			check
+		}{{"Verify", r.Verify}, {"Reader", r.Reader}, {"LibreOffice", r.LibreOffice}} {
+			if c.Status == fail {
+				fmt.Fprintf(w, "- %s, %s: %s\n", r.Name, c.name, c.Detail)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Command xlsdiff compares two XLS files cell by cell and prints their
+// differences as a unified-style report. It exits with status 1 if the
+// files differ and 2 on trouble, like diff.
+//
+// Usage:
+//
+//	xlsdiff [--ignore-sheets a,b] [--ignore-columns A,C] [--tolerance 1e-9] [--formats] old.xls new.xls
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tkuchiki/go-xls"
+)
+
+func main() {
+	ignoreSheets := flag.String("ignore-sheets", "", "comma-separated names of sheets to leave out")
+	ignoreColumns := flag.String("ignore-columns", "", "comma-separated columns to leave out, such as A,C")
+	tolerance := flag.Float64("tolerance", 0, "largest difference between numbers that counts as equal")
+	formats := flag.Bool("formats", false, "also compare number formats")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] old.xls new.xls\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := xls.DiffOptions{Tolerance: *tolerance, Formats: *formats}
+	if *ignoreSheets != "" {
+		opts.IgnoreSheets = strings.Split(*ignoreSheets, ",")
+	}
+	if *ignoreColumns != "" {
+		for _, name := range strings.Split(*ignoreColumns, ",") {
+			col, ok := columnIndex(name)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "xlsdiff: invalid column %q\n", name)
+				os.Exit(2)
+			}
+			opts.IgnoreColumns = append(opts.IgnoreColumns, col)
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	differ, err := diff(out, flag.Arg(0), flag.Arg(1), opts)
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xlsdiff: %v\n", err)
+		os.Exit(2)
+	}
+	if differ {
+		os.Exit(1)
+	}
+}
+
+// diff prints the differences of the files at pathA and pathB to w and
+// reports whether there are any.
+func diff(w io.Writer, pathA, pathB string, opts xls.DiffOptions) (bool, error) {
+	a, err := os.Open(pathA)
+	if err != nil {
+		return false, err
+	}
+	defer a.Close()
+	b, err := os.Open(pathB)
+	if err != nil {
+		return false, err
+	}
+	defer b.Close()
+
+	infoA, err := a.Stat()
+	if err != nil {
+		return false, err
+	}
+	infoB, err := b.Stat()
+	if err != nil {
+		return false, err
+	}
+	diffs, err := xls.Diff(a, b, infoA.Size(), infoB.Size(), opts)
+	if err != nil || len(diffs) == 0 {
+		return false, err
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", pathA, pathB)
+	sheet := ""
+	for _, d := range diffs {
+		switch d.Kind {
+		case xls.DiffSheetOnlyInA:
+			fmt.Fprintf(w, "@@ %s @@ only in %s\n", d.Sheet, pathA)
+			sheet = ""
+			continue
+		case xls.DiffSheetOnlyInB:
+			fmt.Fprintf(w, "@@ %s @@ only in %s\n", d.Sheet, pathB)
+			sheet = ""
+			continue
+		}
+		if d.Sheet != sheet {
+			fmt.Fprintf(w, "@@ %s @@\n", d.Sheet)
+			sheet = d.Sheet
+		}
+		ref := columnName(d.Col) + strconv.Itoa(d.Row+1)
+		if d.A.Kind != xls.KindBlank {
+			fmt.Fprintf(w, "-%s\t%s\n", ref, cellText(d.A, d.Kind))
+		}
+		if d.B.Kind != xls.KindBlank {
+			fmt.Fprintf(w, "+%s\t%s\n", ref, cellText(d.B, d.Kind))
+		}
+	}
+	return true, nil
+}
+
+// cellText renders a cell of a difference of the given kind.
+func cellText(c xls.Cell, kind xls.DiffKind) string {
+	text := c.FormattedValue()
+	if c.Kind == xls.KindFormula {
+		text = "=" + c.Formula + "\t" + text
+	}
+	if kind == xls.DiffFormat {
+		text += "\t[" + c.FormatString + "]"
+	}
+	return text
+}
+
+// columnIndex returns the zero-based index of a column name such as "C".
+func columnIndex(name string) (int, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" || len(name) > 3 {
+		return 0, false
+	}
+	col := 0
+	for _, c := range name {
+		if c < 'A' || c > 'Z' {
+			return 0, false
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+	return col - 1, true
+}
+
+// columnName returns the name of a zero-based column, such as "C".
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,301 @@
+// Command xlsdump prints the CFB container layout and an annotated BIFF8
+// record listing of an XLS file, for diagnosing files that Excel rejects.
+//
+// Usage:
+//
+//	xlsdump [--hex] [--stream Workbook] file.xls
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tkuchiki/go-xls"
+)
+
+var objectTypes = map[byte]string{
+	xls.CFBObjectUnknown: "empty",
+	xls.CFBObjectStorage: "storage",
+	xls.CFBObjectStream:  "stream",
+	xls.CFBObjectRoot:    "root",
+}
+
+func main() {
+	dumpHex := flag.Bool("hex", false, "dump record bodies in hex")
+	streamName := flag.String("stream", "Workbook", "stream to list records of")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file.xls\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	err := dump(out, flag.Arg(0), *streamName, *dumpHex)
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xlsdump: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dump(w io.Writer, path, streamName string, dumpHex bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfb, err := xls.ReadCFB(data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "File: %s (%d bytes)\n", path, len(data))
+	fmt.Fprintf(w, "Sector size: %d, mini stream cutoff: %d\n\n", cfb.SectorSize(), cfb.MiniStreamCutoff())
+
+	fmt.Fprintln(w, "Directory:")
+	for i, e := range cfb.Entries() {
+		if e.ObjectType == xls.CFBObjectUnknown {
+			continue
+		}
+		fmt.Fprintf(w, "  [%d] %-20q %-7s size %-9d", i, e.Name, objectTypes[e.ObjectType], e.Size)
+		chain, mini, err := cfb.Chain(e)
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, " chain error: %v\n", err)
+		case len(chain) == 0:
+			fmt.Fprintln(w, " no sectors")
+		case mini:
+			fmt.Fprintf(w, " mini sectors %s\n", sectorRanges(chain))
+		default:
+			fmt.Fprintf(w, " sectors %s\n", sectorRanges(chain))
+		}
+	}
+	fmt.Fprintln(w)
+
+	stream, err := cfb.Stream(streamName)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Records of %q (%d bytes):\n", streamName, len(stream))
+
+	r := xls.NewRecordReader(stream)
+	for {
+		rec, err := r.Next()
+		if err == nil && rec.Type == 0 && len(rec.Data) == 0 && isZero(stream[rec.Offset:]) {
+			// Streams are often padded with zeros past the last EOF record
+			fmt.Fprintf(w, "%08X %d bytes of zero padding\n", rec.Offset, len(stream)-rec.Offset)
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := xls.RecordName(rec.Type)
+		if name == "" {
+			name = "?"
+		}
+		fmt.Fprintf(w, "%08X %04X %-20s %5d", rec.Offset, rec.Type, name, len(rec.Data))
+		if details := describe(rec); details != "" {
+			fmt.Fprintf(w, "  %s", details)
+		}
+		fmt.Fprintln(w)
+
+		if dumpHex && len(rec.Data) > 0 {
+			for _, line := range strings.SplitAfter(strings.TrimRight(hex.Dump(rec.Data), "\n"), "\n") {
+				fmt.Fprintf(w, "    %s", line)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sectorRanges formats a sector chain compactly, e.g. "0-7 9 12-13 (11)".
+func sectorRanges(chain []uint32) string {
+	var parts []string
+	for i := 0; i < len(chain); {
+		j := i
+		for j+1 < len(chain) && chain[j+1] == chain[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(chain[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", chain[i], chain[j]))
+		}
+		i = j + 1
+	}
+	return fmt.Sprintf("%s (%d)", strings.Join(parts, " "), len(chain))
+}
+
+const tooShort = "(record too short)"
+
+// describe decodes the key fields of the records most useful for diagnosis.
+func describe(rec xls.Record) string {
+	d := rec.Data
+	u16 := func(off int) uint16 { return binary.LittleEndian.Uint16(d[off:]) }
+	u32 := func(off int) uint32 { return binary.LittleEndian.Uint32(d[off:]) }
+
+	need := func(n int) bool { return len(d) >= n }
+	switch xls.RecordName(rec.Type) {
+	case "BOF":
+		if !need(8) {
+			return tooShort
+		}
+		kinds := map[uint16]string{0x0005: "globals", 0x0006: "VB module", 0x0010: "worksheet", 0x0020: "chart", 0x0040: "macro sheet", 0x0100: "workspace"}
+		kind := kinds[u16(2)]
+		if kind == "" {
+			kind = fmt.Sprintf("0x%04X", u16(2))
+		}
+		return fmt.Sprintf("version 0x%04X %s build %d year %d", u16(0), kind, u16(4), u16(6))
+	case "BOUNDSHEET":
+		if !need(8) {
+			return tooShort
+		}
+		visibility := [...]string{"visible", "hidden", "very hidden", "?"}[d[4]&0x03]
+		kinds := map[byte]string{0x00: "worksheet", 0x01: "macro sheet", 0x02: "chart", 0x06: "VB module"}
+		return fmt.Sprintf("%q offset 0x%08X %s %s", shortString(d[6:]), u32(0), kinds[d[5]], visibility)
+	case "SST":
+		if !need(8) {
+			return tooShort
+		}
+		return fmt.Sprintf("total %d unique %d", u32(0), u32(4))
+	case "DIMENSIONS":
+		if !need(12) {
+			return tooShort
+		}
+		return fmt.Sprintf("rows %d-%d cols %d-%d", u32(0), u32(4), u16(8), u16(10))
+	case "ROW":
+		if !need(8) {
+			return tooShort
+		}
+		if need(16) && u32(12)&0x80 != 0 {
+			return fmt.Sprintf("row %d cols %d-%d xf %d", u16(0), u16(2), u16(4), u16(14)&0x0FFF)
+		}
+		return fmt.Sprintf("row %d cols %d-%d", u16(0), u16(2), u16(4))
+	case "NUMBER":
+		if !need(14) {
+			return tooShort
+		}
+		return fmt.Sprintf("%s xf %d value %v", cellName(u16(0), u16(2)), u16(4), math.Float64frombits(binary.LittleEndian.Uint64(d[6:])))
+	case "LABELSST":
+		if !need(10) {
+			return tooShort
+		}
+		return fmt.Sprintf("%s xf %d sst %d", cellName(u16(0), u16(2)), u16(4), u32(6))
+	case "RK", "BLANK", "BOOLERR", "FORMULA", "LABEL", "RSTRING":
+		if !need(6) {
+			return tooShort
+		}
+		return fmt.Sprintf("%s xf %d", cellName(u16(0), u16(2)), u16(4))
+	case "MULRK", "MULBLANK":
+		if !need(6) {
+			return tooShort
+		}
+		return fmt.Sprintf("row %d cols %d-%d", u16(0), u16(2), u16(len(d)-2))
+	case "FORMAT":
+		if !need(5) {
+			return tooShort
+		}
+		return fmt.Sprintf("index %d %q", u16(0), longString(d[2:]))
+	case "XF":
+		if !need(6) {
+			return tooShort
+		}
+		kind := "cell"
+		if u16(4)&0x0004 != 0 {
+			kind = "style"
+		}
+		return fmt.Sprintf("font %d format %d %s", u16(0), u16(2), kind)
+	case "COLINFO":
+		if !need(8) {
+			return tooShort
+		}
+		return fmt.Sprintf("cols %d-%d width %d xf %d", u16(0), u16(2), u16(4), u16(6))
+	case "CODEPAGE":
+		if !need(2) {
+			return tooShort
+		}
+		return fmt.Sprintf("%d", u16(0))
+	case "DATEMODE":
+		if !need(2) {
+			return tooShort
+		}
+		if u16(0) == 1 {
+			return "1904"
+		}
+		return "1900"
+	}
+	return ""
+}
+
+// cellName returns the A1-style name of a cell.
+func cellName(row, col uint16) string {
+	name := ""
+	for c := int(col) + 1; c > 0; c = (c - 1) / 26 {
+		name = string(rune('A'+(c-1)%26)) + name
+	}
+	return fmt.Sprintf("%s%d", name, int(row)+1)
+}
+
+// shortString decodes a string with an 8-bit length prefix.
+func shortString(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	return decodeChars(b[2:], int(b[0]), b[1])
+}
+
+// longString decodes a string with a 16-bit length prefix.
+func longString(b []byte) string {
+	if len(b) < 3 {
+		return ""
+	}
+	return decodeChars(b[3:], int(binary.LittleEndian.Uint16(b)), b[2])
+}
+
+func decodeChars(b []byte, n int, flags byte) string {
+	if flags&0x01 == 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		runes := make([]rune, n)
+		for i := range runes {
+			runes[i] = rune(b[i])
+		}
+		return string(runes)
+	}
+	if n > len(b)/2 {
+		n = len(b) / 2
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,124 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ErrUnsupportedCodePage is returned for a code page without an encoding
+// in this package: by the reader for a file whose 8-bit strings are in it,
+// and by writes with WithCodePage.
+var ErrUnsupportedCodePage = errors.New("xls: unsupported code page")
+
+// Code pages of the CODEPAGE record
+const (
+	codePageASCII = 367
+	codePageUTF16 = 1200 // What Excel writes in BIFF8 files
+)
+
+// codePages are the encodings of the code pages of legacy files, which
+// store 8-bit strings in the code page of their CODEPAGE record.
+var codePages = map[uint16]encoding.Encoding{
+	437:   charmap.CodePage437,
+	850:   charmap.CodePage850,
+	852:   charmap.CodePage852,
+	866:   charmap.CodePage866,
+	874:   charmap.Windows874,
+	932:   japanese.ShiftJIS,
+	936:   simplifiedchinese.GBK,
+	949:   korean.EUCKR,
+	950:   traditionalchinese.Big5,
+	1250:  charmap.Windows1250,
+	1251:  charmap.Windows1251,
+	1252:  charmap.Windows1252,
+	1253:  charmap.Windows1253,
+	1254:  charmap.Windows1254,
+	1255:  charmap.Windows1255,
+	1256:  charmap.Windows1256,
+	1257:  charmap.Windows1257,
+	1258:  charmap.Windows1258,
+	10000: charmap.Macintosh,
+	32768: charmap.Macintosh,   // Apple Roman, as Excel for Mac writes it
+	32769: charmap.Windows1252, // ANSI Latin I, as BIFF2 and BIFF3 write it
+}
+
+// codePage decodes the compressed 8-bit characters of strings. BIFF8
+// defines them as Latin-1, which the zero value decodes, but legacy
+// writers store them in the code page of the CODEPAGE record.
+type codePage struct {
+	id          uint16
+	enc         encoding.Encoding // nil for Latin-1
+	unsupported bool
+}
+
+// newCodePage returns the codePage of a CODEPAGE record.
+func newCodePage(id uint16) codePage {
+	if id == codePageUTF16 || id == codePageASCII {
+		return codePage{}
+	}
+	if enc, ok := codePages[id]; ok {
+		return codePage{id: id, enc: enc}
+	}
+	return codePage{id: id, unsupported: true}
+}
+
+// decode decodes compressed characters. Those of an unsupported code page
+// are only decoded if they are ASCII, which all code pages share.
+func (cp codePage) decode(b []byte) (string, error) {
+	switch {
+	case cp.enc != nil:
+		return cp.enc.NewDecoder().String(string(b))
+	case cp.unsupported:
+		for _, c := range b {
+			if c >= 0x80 {
+				return "", fmt.Errorf("%w: %d", ErrUnsupportedCodePage, cp.id)
+			}
+		}
+	}
+	return latin1String(b), nil
+}
+
+// WithCodePage sets the code page of the CODEPAGE record, which defaults to
+// UTF-16 (1200) as Excel writes it, for consumers of legacy files that
+// expect the code page of their locale, such as 932 for Japanese or 1251
+// for Cyrillic. Writes fail with ErrUnsupportedCodePage for a code page
+// the reader cannot decode.
+//
+// With a code page other than UTF-16, only ASCII strings are written as
+// 8-bit characters and the others as UTF-16, so that readers that decode
+// 8-bit characters as Latin-1, as Excel does, and those that honor the
+// code page read the same text.
+func WithCodePage(id uint16) Option {
+	return func(w *Writer) {
+		w.codePage = id
+	}
+}
+
+// checkCodePage returns ErrUnsupportedCodePage for a code page set with
+// WithCodePage the reader cannot decode.
+func (w *Writer) checkCodePage() error {
+	if w.codePage == 0 || w.codePage == codePageUTF16 || w.codePage == codePageASCII {
+		return nil
+	}
+	if _, ok := codePages[w.codePage]; !ok {
+		return fmt.Errorf("%w: %d", ErrUnsupportedCodePage, w.codePage)
+	}
+	return nil
+}
+
+// maxCompressed returns the largest character written as an 8-bit
+// character: Latin-1 in UTF-16 files and ASCII in those of another code
+// page.
+func (w *Writer) maxCompressed() rune {
+	if w.codePage == 0 || w.codePage == codePageUTF16 {
+		return 0xFF
+	}
+	return 0x7F
+}
@@ -0,0 +1,79 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestReadCodePageSheetName(t *testing.T) {
+	data := buildFixture(t, []testRecord{{recTypeCODEPAGE, le(1251)}}, nil)
+	i := bytes.Index(data, []byte("Sheet1"))
+	copy(data[i:], "\xd1\xef\xe8\xf1\xee\xea")
+
+	wb, err := openWorkbook(data)
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	if name := wb.Sheets()[0].Name; name != "Список" {
+		t.Errorf("Expected the sheet name decoded as CP1251, got %q", name)
+	}
+}
+
+func TestReadUnsupportedCodePage(t *testing.T) {
+	globals := []testRecord{{recTypeCODEPAGE, le(1361)}}
+
+	// ASCII is the same in every code page
+	wb, err := openWorkbook(buildFixture(t, globals, []testRecord{{recTypeLABEL, le(0, 0, 0, 2, byte(0), "ok")}}))
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	if got := wb.Sheets()[0].Cell(0, 0).Value; got != "ok" {
+		t.Errorf("Expected an ASCII string read, got %v", got)
+	}
+
+	_, err = openWorkbook(buildFixture(t, append(globals,
+		testRecord{recTypeSST, le(uint32(1), uint32(1), 2, byte(0), []byte("\xb0\xa1"))}), nil))
+	if !errors.Is(err, ErrUnsupportedCodePage) {
+		t.Errorf("Expected ErrUnsupportedCodePage, got %v", err)
+	}
+}
+
+func TestWithCodePage(t *testing.T) {
+	data := [][]interface{}{{"plain", "Привет", "café"}}
+	w := New(WithCodePage(1251))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	records := writtenRecords(t, w)
+
+	cp := recordsOfType(records, recTypeCODEPAGE)
+	if len(cp) != 1 || binary.LittleEndian.Uint16(cp[0].Data) != 1251 {
+		t.Errorf("Expected CODEPAGE 1251, got %v", cp)
+	}
+	sheet := readBack(t, w)
+	for c, want := range data[0] {
+		if got := sheet.Cell(0, c).Value; got != want {
+			t.Errorf("Cell (0, %d): expected %q, got %v", c, want, got)
+		}
+	}
+
+	// Latin-1 is only written as 8-bit characters in UTF-16 files
+	for _, tc := range []struct {
+		opts  []Option
+		flags byte
+	}{
+		{nil, 0x00},
+		{[]Option{WithCodePage(codePageUTF16)}, 0x00},
+		{[]Option{WithCodePage(1251)}, 0x01},
+	} {
+		if got := encodeText("café", 2, New(tc.opts...).maxCompressed())[2]; got != tc.flags {
+			t.Errorf("%v: expected flags 0x%02X, got 0x%02X", tc.opts, tc.flags, got)
+		}
+	}
+
+	if _, err := New(WithCodePage(1361)).WriteTo(new(bytes.Buffer)); !errors.Is(err, ErrUnsupportedCodePage) {
+		t.Errorf("Expected ErrUnsupportedCodePage, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CellWriteError reports a cell that failed to write under
+// WithErrorCollection, such as text longer than a cell holds or a value of
+// another type under WithStrictTypes.
+type CellWriteError struct {
+	Cell CellRef
+	Err  error
+}
+
+func (e *CellWriteError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Cell, e.Err)
+}
+
+func (e *CellWriteError) Unwrap() error {
+	return e.Err
+}
+
+// WriteErrors lists the cells that failed to write under
+// WithErrorCollection, by sheet, row and column. Its Unwrap returns them,
+// so errors.Is and errors.As match the error of any of them.
+type WriteErrors struct {
+	Cells []*CellWriteError
+
+	// Aborted reports that the write stopped at the most errors allowed,
+	// without a file.
+	Aborted bool
+}
+
+func (e *WriteErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "xls: %d cells failed to write", len(e.Cells))
+	if e.Aborted {
+		b.WriteString(", write aborted")
+	}
+	for i, c := range e.Cells {
+		if i == maxReportedMismatches {
+			b.WriteString("; ...")
+			break
+		}
+		fmt.Fprintf(&b, "; %v", c)
+	}
+	return b.String()
+}
+
+func (e *WriteErrors) Unwrap() []error {
+	errs := make([]error, len(e.Cells))
+	for i, c := range e.Cells {
+		errs[i] = c
+	}
+	return errs
+}
+
+// WithErrorCollection makes a write go on past cells that fail to write,
+// writing them as blank cells with their style, instead of failing at the
+// first. SaveAs, WriteTo and the other writes then write the file and
+// return a *WriteErrors listing the cells, or, once max cells have failed,
+// stop and return it without a file. A max of 0 or less sets no limit.
+// Errors of the workbook as a whole, such as a cell beyond the last row,
+// still fail the write at once.
+func WithErrorCollection(max int) Option {
+	return func(w *Writer) {
+		w.collectErrors, w.maxCellErrors = true, max
+	}
+}
+
+// cellErrors collects the cells that failed to write during a write with
+// WithErrorCollection. Sheets written concurrently share it.
+type cellErrors struct {
+	mu      sync.Mutex
+	max     int
+	cells   []*CellWriteError
+	aborted bool
+}
+
+// errCellErrorLimit stops a write that reached the most cell errors
+// allowed; the write returns the *WriteErrors instead.
+var errCellErrorLimit = errors.New("xls: too many cell errors")
+
+// add records a cell error and reports whether the write goes on.
+func (c *cellErrors) add(e *CellWriteError) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.aborted {
+		return false
+	}
+	c.cells = append(c.cells, e)
+	if c.max > 0 && len(c.cells) >= c.max {
+		c.aborted = true
+	}
+	return !c.aborted
+}
+
+// result returns the *WriteErrors of the write, sorted by the sheets of w,
+// rows and columns, or nil if no cell failed.
+func (c *cellErrors) result(w *Writer) error {
+	if len(c.cells) == 0 {
+		return nil
+	}
+	sheets := make(map[string]int, len(w.sheets))
+	for i, s := range w.sheets {
+		sheets[s.name] = i
+	}
+	cells := append([]*CellWriteError(nil), c.cells...)
+	sort.SliceStable(cells, func(i, j int) bool {
+		a, b := cells[i].Cell, cells[j].Cell
+		if a.Sheet != b.Sheet {
+			return sheets[a.Sheet] < sheets[b.Sheet]
+		}
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+	if c.max > 0 && len(cells) > c.max {
+		cells = cells[:c.max]
+	}
+	return &WriteErrors{Cells: cells, Aborted: c.aborted}
+}
+
+// cellFailed handles the error of a cell of the sheet: with
+// WithErrorCollection, it records it and writes the cell as a blank cell,
+// returning an error only once the write stops; otherwise it returns err.
+func (w *Writer) cellFailed(writer io.Writer, s *SheetWriter, row rowIdx, col colIdx, err error) error {
+	if w.cellErrs == nil {
+		return err
+	}
+	r, c := int(row), int(col)
+	if !w.cellErrs.add(&CellWriteError{Cell: CellRef{Sheet: s.name, Row: r, Col: c}, Err: err}) {
+		return errCellErrorLimit
+	}
+	return w.writeBlanks(writer, row, col, []uint16{s.cellXF(r, c, s.cellAt(r, c))})
+}
+
+// verifiedData returns the data of the sheet as convertedData does, with
+// the cells that failed to write, which were written blank, left empty.
+func (s *SheetWriter) verifiedData() [][]interface{} {
+	data := s.convertedData()
+	c := s.w.cellErrs
+	if c == nil || len(c.cells) == 0 {
+		return data
+	}
+	copied := make(map[int]bool)
+	for _, e := range c.cells {
+		r, col := e.Cell.Row, e.Cell.Col
+		if e.Cell.Sheet != s.name || r >= len(data) || col >= len(data[r]) {
+			continue
+		}
+		if len(copied) == 0 {
+			data = append([][]interface{}(nil), data...)
+		}
+		if !copied[r] {
+			data[r], copied[r] = append([]interface{}(nil), data[r]...), true
+		}
+		data[r][col] = nil
+	}
+	return data
+}
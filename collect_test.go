@@ -0,0 +1,154 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collectWriter returns a Writer with two sheets of 2,000 rows holding, at
+// regular intervals, text longer than its limits, a struct under
+// WithStrictTypes and an invalid formula, and the cells that fail, in
+// order.
+func collectWriter(opts ...Option) (*Writer, []CellRef) {
+	opts = append([]Option{WithStrictTypes(), WithLimits(Limits{MaxCellChars: 40})}, opts...)
+	w := New(opts...)
+	var failed []CellRef
+	data := func(sheet string) [][]interface{} {
+		rows := make([][]interface{}, 2000)
+		for r := range rows {
+			rows[r] = []interface{}{r, "name", 1.5, FormulaCell{Expr: "A1*2"}}
+			if r%97 == 5 {
+				rows[r][1] = strings.Repeat("x", 41)
+				failed = append(failed, CellRef{Sheet: sheet, Row: r, Col: 1})
+			}
+			if r%131 == 7 {
+				rows[r][2] = Styled(struct{}{}, Style{Fill: ColorRed})
+				failed = append(failed, CellRef{Sheet: sheet, Row: r, Col: 2})
+			}
+			if r%211 == 9 {
+				rows[r][3] = FormulaCell{Expr: "SUM(A1"}
+				failed = append(failed, CellRef{Sheet: sheet, Row: r, Col: 3})
+			}
+		}
+		return rows
+	}
+	w.Write(data("Sheet1"))
+	s, _ := w.AddSheet("Other")
+	s.Write(data("Other"))
+	return w, failed
+}
+
+func TestWithErrorCollection(t *testing.T) {
+	w, failed := collectWriter()
+	if _, err := w.WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expected the first error without WithErrorCollection, got %v", err)
+	}
+
+	w, _ = collectWriter(WithErrorCollection(0), WithPostWriteVerification(), WithConcurrency(1))
+	var buf bytes.Buffer
+	n, err := w.WriteTo(&buf)
+	var collected *WriteErrors
+	if !errors.As(err, &collected) {
+		t.Fatalf("Expected *WriteErrors, got %v", err)
+	}
+	if n == 0 || n != int64(buf.Len()) {
+		t.Fatalf("Expected the file to be written, got %d bytes", n)
+	}
+	if collected.Aborted {
+		t.Error("Expected the write not to abort without a limit")
+	}
+	var cells []CellRef
+	for _, c := range collected.Cells {
+		cells = append(cells, c.Cell)
+	}
+	if !reflect.DeepEqual(cells, failed) {
+		t.Errorf("Expected the cells %v, got %v", failed, cells)
+	}
+	for _, target := range []error{ErrLimitExceeded, ErrOutOfRange, ErrUnsupportedCellType} {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected the error to match %v", target)
+		}
+	}
+	var cellErr *CellWriteError
+	if !errors.As(err, &cellErr) || cellErr != collected.Cells[0] {
+		t.Errorf("Expected the first cell error, got %v", cellErr)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "xls: 94 cells failed to write; Sheet1!B6: ") {
+		t.Errorf("Unexpected error message %q", msg)
+	}
+
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	for _, ref := range failed {
+		sheet, _ := wb.Sheet(ref.Sheet)
+		if cell := sheet.Cell(ref.Row, ref.Col); cell.Kind != KindBlank {
+			t.Errorf("Expected %v to be blank, got %v", ref, cell)
+		}
+	}
+	sheet := wb.Sheets()[0]
+	if got := sheet.Cell(7, 2); cellStyleOf(w, got).Fill != ColorRed {
+		t.Error("Expected the failed cell to keep its style")
+	}
+	if got := sheet.Cell(6, 1).Value; got != "name" {
+		t.Errorf("Expected the cells around to be written, got %v", got)
+	}
+
+	// Sheets written concurrently give the same file and errors
+	cw, _ := collectWriter(WithErrorCollection(0), WithPostWriteVerification(), WithConcurrency(2))
+	var concurrent bytes.Buffer
+	_, cerr := cw.WriteTo(&concurrent)
+	if !bytes.Equal(concurrent.Bytes(), buf.Bytes()) {
+		t.Error("Expected the same file with sheets written concurrently")
+	}
+	if cerr == nil || cerr.Error() != collected.Error() || len(cerr.(*WriteErrors).Cells) != len(failed) {
+		t.Errorf("Expected the same errors with sheets written concurrently, got %v", cerr)
+	}
+
+	path := filepath.Join(t.TempDir(), "collected.xls")
+	if err := w.SaveAs(path); !errors.As(err, &collected) {
+		t.Errorf("Expected SaveAs() to return *WriteErrors, got %v", err)
+	}
+	if saved, err := os.ReadFile(path); err != nil || !bytes.Equal(saved, buf.Bytes()) {
+		t.Errorf("Expected SaveAs() to write the file, got %v", err)
+	}
+}
+
+func TestWithErrorCollectionLimit(t *testing.T) {
+	w, failed := collectWriter(WithErrorCollection(5), WithConcurrency(1))
+	var buf bytes.Buffer
+	n, err := w.WriteTo(&buf)
+	var collected *WriteErrors
+	if !errors.As(err, &collected) {
+		t.Fatalf("Expected *WriteErrors, got %v", err)
+	}
+	if n != 0 || buf.Len() != 0 {
+		t.Errorf("Expected no file once the limit is reached, got %d bytes", buf.Len())
+	}
+	if !collected.Aborted || !strings.Contains(err.Error(), "write aborted") {
+		t.Errorf("Expected the write to be aborted, got %v", err)
+	}
+	if len(collected.Cells) != 5 || collected.Cells[4].Cell != failed[4] {
+		t.Errorf("Expected the first 5 cells, got %v", err)
+	}
+
+	// Concurrent sheets stop at the limit too
+	w, _ = collectWriter(WithErrorCollection(5), WithConcurrency(2))
+	if _, err := w.WriteTo(&bytes.Buffer{}); !errors.As(err, &collected) || len(collected.Cells) != 5 {
+		t.Errorf("Expected 5 cells with sheets written concurrently, got %v", err)
+	}
+
+	// The limit applies to each write
+	w, _ = collectWriter(WithErrorCollection(len(failed) + 1))
+	for i := 0; i < 2; i++ {
+		if _, err := w.WriteTo(&bytes.Buffer{}); !errors.As(err, &collected) || collected.Aborted {
+			t.Errorf("Write %d: expected the file with its errors, got %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package xls
+
+import (
+	"io"
+	"time"
+)
+
+// Column is a column of values for WriteColumns, such as a column of an
+// Apache Arrow record batch. Cell returns the value of row i, from 0 to
+// Len()-1, as a value Write accepts, or nil for an empty cell.
+//
+// Float64Column, StringColumn, TimeColumn and BoolColumn hold typed values
+// that are written without converting them to interface{}; other columns
+// are written cell by cell through Cell.
+type Column interface {
+	Len() int
+	Cell(i int) interface{}
+}
+
+// Float64Column is a column of numbers. Valid is a validity bitmap, as in
+// Apache Arrow: bit i%8 of Valid[i/8] is set when row i has a value. A nil
+// Valid means every row has one.
+type Float64Column struct {
+	Values []float64
+	Valid  []byte
+}
+
+// StringColumn is a column of strings, with a validity bitmap as in
+// Float64Column. An empty string is a value, written as empty text.
+type StringColumn struct {
+	Values []string
+	Valid  []byte
+}
+
+// TimeColumn is a column of dates and times, with a validity bitmap as in
+// Float64Column.
+type TimeColumn struct {
+	Values []time.Time
+	Valid  []byte
+}
+
+// BoolColumn is a column of bools, with a validity bitmap as in
+// Float64Column.
+type BoolColumn struct {
+	Values []bool
+	Valid  []byte
+}
+
+func (c Float64Column) Len() int { return len(c.Values) }
+func (c StringColumn) Len() int  { return len(c.Values) }
+func (c TimeColumn) Len() int    { return len(c.Values) }
+func (c BoolColumn) Len() int    { return len(c.Values) }
+
+func (c Float64Column) Cell(i int) interface{} {
+	if !validAt(c.Valid, i) {
+		return nil
+	}
+	return c.Values[i]
+}
+
+func (c StringColumn) Cell(i int) interface{} {
+	if !validAt(c.Valid, i) {
+		return nil
+	}
+	return c.Values[i]
+}
+
+func (c TimeColumn) Cell(i int) interface{} {
+	if !validAt(c.Valid, i) {
+		return nil
+	}
+	return c.Values[i]
+}
+
+func (c BoolColumn) Cell(i int) interface{} {
+	if !validAt(c.Valid, i) {
+		return nil
+	}
+	return c.Values[i]
+}
+
+// validAt reports whether row i has a value by the validity bitmap valid.
+// Rows beyond the bitmap have none.
+func validAt(valid []byte, i int) bool {
+	if valid == nil {
+		return true
+	}
+	return i/8 < len(valid) && valid[i/8]&(1<<(i%8)) != 0
+}
+
+// WriteColumns writes columnar data to the first sheet. See
+// SheetWriter.WriteColumns.
+func (w *Writer) WriteColumns(headers []string, cols []Column) error {
+	return w.sheets[0].WriteColumns(headers, cols)
+}
+
+// WriteColumns sets the data of the sheet from columns, such as those of an
+// Apache Arrow record batch, replacing the data of earlier writes like
+// Write. A non-empty headers is written as the first row, and the values
+// of the columns below it; columns shorter than the others end with empty
+// cells.
+//
+// The columns are kept as they are and written row by row when the sheet is
+// written, so they must not change until then. Float64Column and
+// StringColumn values are written directly as NUMBER and shared string
+// records, without converting each to interface{}, unless a number policy
+// or number conversion applies to them. Options that rework the rows as
+// Write does, such as WithSchema, WithCellConverter,
+// WithStringNormalization, WithDateStringDetection, WithFormulaEscaping,
+// WithMetadataColumn, WithGroupSubtotals and WithSummaryRow, and methods that edit the data,
+// such as SetCell and InsertRow, convert the columns to rows first.
+func (s *SheetWriter) WriteColumns(headers []string, cols []Column) error {
+	d := &columnData{headers: headers, cols: cols}
+	for _, c := range cols {
+		d.rows = max(d.rows, c.Len())
+	}
+	if len(headers) > 0 {
+		d.rows++
+	}
+
+	w := s.w
+	if w.schema != nil || w.converter != nil || w.norm != nil || w.dateLayouts != nil || w.escapePrefix != "" || w.metadata != nil || w.subtotals != nil || w.summary != nil {
+		return s.Write(d.rowsData())
+	}
+	s.data, s.dataOwned, s.schemaWarnings = nil, false, nil
+	s.rowLevels = nil
+	s.cols = d
+	return nil
+}
+
+// columnData is the data of a sheet set with WriteColumns.
+type columnData struct {
+	headers []string
+	cols    []Column
+	rows    int // Rows of the data, the header row included
+}
+
+// width returns the number of columns.
+func (d *columnData) width() int {
+	return max(len(d.headers), len(d.cols))
+}
+
+// column returns the column of the cells of col and the row of the cell of
+// row in it, or nil for a header cell or a cell beyond the columns.
+func (d *columnData) column(row, col int) (Column, int) {
+	if len(d.headers) > 0 {
+		if row == 0 || col >= len(d.cols) {
+			return nil, 0
+		}
+		row--
+	}
+	if col >= len(d.cols) {
+		return nil, 0
+	}
+	return d.cols[col], row
+}
+
+// present reports whether the cell holds a value, without converting it to
+// interface{} for the typed columns.
+func (d *columnData) present(row, col int) bool {
+	if row == 0 && len(d.headers) > 0 {
+		return col < len(d.headers)
+	}
+	c, i := d.column(row, col)
+	if c == nil || i >= c.Len() {
+		return false
+	}
+	switch c := c.(type) {
+	case Float64Column:
+		return validAt(c.Valid, i)
+	case StringColumn:
+		return validAt(c.Valid, i)
+	case TimeColumn:
+		return validAt(c.Valid, i)
+	case BoolColumn:
+		return validAt(c.Valid, i)
+	}
+	return d.cell(row, col) != nil
+}
+
+// cell returns the value of the cell, nil if it is empty.
+func (d *columnData) cell(row, col int) interface{} {
+	if row == 0 && len(d.headers) > 0 {
+		if col < len(d.headers) {
+			return d.headers[col]
+		}
+		return nil
+	}
+	c, i := d.column(row, col)
+	if c == nil || i >= c.Len() {
+		return nil
+	}
+	value, _ := plainValue(c.Cell(i))
+	return value
+}
+
+// rowsData returns the data as rows, as Write takes them.
+func (d *columnData) rowsData() [][]interface{} {
+	data := make([][]interface{}, d.rows)
+	width := d.width()
+	for r := range data {
+		row := make([]interface{}, width)
+		for c := range row {
+			row[c] = d.cell(r, c)
+		}
+		data[r] = row
+	}
+	return data
+}
+
+// rowCount returns the number of rows of the data of the sheet.
+func (s *SheetWriter) rowCount() int {
+	if s.cols != nil {
+		return s.cols.rows
+	}
+	return len(s.data)
+}
+
+// rowWidth returns the number of cells of a row of the data of the sheet.
+func (s *SheetWriter) rowWidth(row int) int {
+	if s.cols != nil {
+		if row >= s.cols.rows {
+			return 0
+		}
+		return s.cols.width()
+	}
+	if row < len(s.data) {
+		return len(s.data[row])
+	}
+	return 0
+}
+
+// cellAt returns the value of a cell of the data of the sheet, nil if it
+// is empty or beyond the data.
+func (s *SheetWriter) cellAt(row, col int) interface{} {
+	if s.cols != nil {
+		return s.cols.cell(row, col)
+	}
+	if row < len(s.data) && col < len(s.data[row]) {
+		return s.data[row][col]
+	}
+	return nil
+}
+
+// hasValue reports whether a cell of the data of the sheet holds a value.
+func (s *SheetWriter) hasValue(row, col int) bool {
+	if s.cols != nil {
+		return s.cols.present(row, col)
+	}
+	return s.cellAt(row, col) != nil
+}
+
+// rowsData returns the data of the sheet as rows: the data itself, or rows
+// built from the columns of WriteColumns.
+func (s *SheetWriter) rowsData() [][]interface{} {
+	if s.cols != nil {
+		return s.cols.rowsData()
+	}
+	return s.data
+}
+
+// materialize converts the columns of WriteColumns to rows, for the methods
+// that edit the data.
+func (s *SheetWriter) materialize() {
+	if s.cols != nil {
+		s.data, s.dataOwned, s.cols = s.cols.rowsData(), true, nil
+	}
+}
+
+// eachText calls fn with the text of every cell of the sheet written as a
+// string, as cellText decides, in row order. Cells that fail to write are
+// skipped, as are typed columns whose cells are never text.
+func (s *SheetWriter) eachText(fn func(row, col int, str string)) {
+	if s.cols == nil {
+		for r, row := range s.data {
+			for c, cell := range row {
+				if str, ok, _ := s.cellText(r, c, cell); ok {
+					fn(r, c, str)
+				}
+			}
+		}
+		return
+	}
+
+	d := s.cols
+	width := d.width()
+	for r := 0; r < d.rows; r++ {
+		for c := 0; c < width; c++ {
+			col, i := d.column(r, c)
+			switch col := col.(type) {
+			case StringColumn:
+				if s.w.convert == nil {
+					if i >= len(col.Values) || !validAt(col.Valid, i) {
+						continue
+					}
+					if str := cleanText(col.Values[i]); s.w.limits.checkText(str, r, c, false) == nil {
+						fn(r, c, str)
+					}
+					continue
+				}
+			case Float64Column:
+				if s.w.general.Mode != GeneralText {
+					continue
+				}
+			case TimeColumn:
+				continue
+			}
+			if str, ok, _ := s.cellText(r, c, d.cell(r, c)); ok {
+				fn(r, c, str)
+			}
+		}
+	}
+}
+
+// styled reports whether the sheet has styles other than those of its
+// data.
+func (s *SheetWriter) styled() bool {
+	return len(s.cellStyles) > 0 || len(s.ranges.list) > 0 || len(s.colStyles) > 0 || len(s.rowStyles) > 0
+}
+
+// plainColumn reports whether the cells of a typed column are written as
+// they are, without a style of their own and with no option turning them
+// into other values, so writeColumnCell may write them directly.
+func (s *SheetWriter) plainColumn(col Column) bool {
+	switch col.(type) {
+	case Float64Column:
+		return s.w.general.Mode == GeneralKeep
+	case StringColumn:
+		return s.w.convert == nil
+	}
+	return false
+}
+
+// prepareColumnStyles registers the styles of the cells of the data of
+// WriteColumns, in the order prepareStyles registers those of rows. Unless
+// the sheet is styled, only the cells that may carry a style of their own
+// are visited: those of columns other than the typed ones, numbers under
+// GeneralFixed, dates in the formats of a theme and text with line breaks.
+func (s *SheetWriter) prepareColumnStyles() {
+	d := s.cols
+	styled := s.styled()
+	width := d.width()
+	for r := 0; r < d.rows; r++ {
+		for c := 0; c < width; c++ {
+			if !styled {
+				col, _ := d.column(r, c)
+				switch col.(type) {
+				case nil, StringColumn:
+					if !s.w.wrapsText(d.cell(r, c)) {
+						continue
+					}
+				case TimeColumn:
+					if s.w.theme.builtinDates() {
+						continue
+					}
+				case BoolColumn:
+					continue
+				case Float64Column:
+					if s.w.general.Mode != GeneralFixed {
+						continue
+					}
+				}
+			}
+			s.cellXF(r, c, d.cell(r, c))
+		}
+	}
+}
+
+// columnXF returns the XF index of a cell of a plain column.
+func (s *SheetWriter) columnXF(row, col int, value interface{}) uint16 {
+	if !s.styled() && !s.w.wrapsText(value) {
+		return 0
+	}
+	return s.cellXF(row, col, value)
+}
+
+// writeColumnCell writes a cell of the data of WriteColumns, writing the
+// values of plain typed columns directly and the others with writeCell.
+func (w *Writer) writeColumnCell(writer io.Writer, s *SheetWriter, row rowIdx, col colIdx, sst *sharedStringTable) error {
+	r, c := int(row), int(col)
+	column, i := s.cols.column(r, c)
+	if column == nil {
+		if r == 0 && c < len(s.cols.headers) {
+			return w.writeColumnString(writer, s, row, col, s.cols.headers[c], sst)
+		}
+		return nil
+	}
+	if s.plainColumn(column) {
+		switch column := column.(type) {
+		case Float64Column:
+			if i >= len(column.Values) || !validAt(column.Valid, i) {
+				return nil
+			}
+			f := column.Values[i]
+			return w.writeNumber(writer, row, col, s.columnXF(r, c, f), f)
+		case StringColumn:
+			if i >= len(column.Values) || !validAt(column.Valid, i) {
+				return nil
+			}
+			return w.writeColumnString(writer, s, row, col, column.Values[i], sst)
+		}
+	}
+	return w.writeCell(writer, s, row, col, s.cols.cell(r, c), sst)
+}
+
+// writeColumnString writes a string of the data of WriteColumns that needs
+// no conversion, as writeCell writes strings.
+func (w *Writer) writeColumnString(writer io.Writer, s *SheetWriter, row rowIdx, col colIdx, str string, sst *sharedStringTable) error {
+	if w.convert != nil {
+		return w.writeCell(writer, s, row, col, str, sst)
+	}
+	str = cleanText(str)
+	if err := w.limits.checkText(str, int(row), int(col), false); err != nil {
+		return err
+	}
+	if w.dict != nil {
+		if err := w.dict.check(str, int(row), int(col)); err != nil {
+			return err
+		}
+	}
+	xf := s.columnXF(int(row), int(col), str)
+	if sst.inline(str) {
+		return w.writeLabel(writer, row, col, xf, str)
+	}
+	return w.writeLabelSST(writer, row, col, xf, sst.addString(str))
+}
@@ -0,0 +1,161 @@
+package xls
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// anyColumn is a Column other than the typed ones.
+type anyColumn []interface{}
+
+func (c anyColumn) Len() int               { return len(c) }
+func (c anyColumn) Cell(i int) interface{} { return c[i] }
+
+// testColumns returns columns of every kind, with nulls and columns of
+// different lengths, and the rows Write takes for the same data.
+func testColumns() ([]string, []Column, [][]interface{}) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	headers := []string{"Note", "Name", "Amount", "Date", "Paid", "Extra"}
+	cols := []Column{
+		anyColumn{Number(7), nil, Styled("x", Style{Font: Font{Bold: true}}), 8, "n"},
+		StringColumn{Values: []string{"a", "", "b", "a", "c"}, Valid: []byte{0b10111}},
+		Float64Column{Values: []float64{1.5, 0, -3, 42, 1e12}, Valid: []byte{0b11011}},
+		TimeColumn{Values: []time.Time{date, date.Add(90 * time.Minute)}},
+		BoolColumn{Values: []bool{true, false, true}, Valid: []byte{0b101}},
+	}
+	rows := [][]interface{}{
+		{"Note", "Name", "Amount", "Date", "Paid", "Extra"},
+		{Number(7), "a", 1.5, date, true, nil},
+		{nil, "", 0.0, date.Add(90 * time.Minute), nil, nil},
+		{Styled("x", Style{Font: Font{Bold: true}}), "b", nil, nil, true, nil},
+		{8, nil, 42.0, nil, nil, nil},
+		{"n", "c", 1e12},
+	}
+	return headers, cols, rows
+}
+
+// writtenBytes returns the file w writes.
+func writtenBytes(t *testing.T, w *Writer) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		setup func(w *Writer)
+	}{
+		{"plain", nil, nil},
+		{"code page", []Option{WithCodePage(1252)}, nil},
+		{"styled", nil, func(w *Writer) {
+			w.SetColStyle(2, Style{NumberFormat: "0.00"})
+			w.SetRowStyle(2, Style{Fill: ColorLightYellow})
+			w.SetRangeStyle(1, 6, 1, 2, Style{Fill: ColorRed})
+			w.SetCellStyle(3, 0, Style{Border: Border{Top: BorderThin}})
+		}},
+		{"general fixed", []Option{WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralFixed})}, nil},
+		{"general text", []Option{WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralText})}, nil},
+		{"numbers", []Option{WithNumberConversion(func(string) bool { return true })}, nil},
+		{"bools", []Option{WithBoolRendering(BoolNumeric())}, nil},
+		{"escaping", []Option{WithFormulaEscaping()}, nil},
+		{"inline strings", []Option{WithHybridStrings(2)}, nil},
+		{"auto tuning", []Option{WithAutoTuning()}, nil},
+		{"concurrent sheets", []Option{WithConcurrency(2)}, func(w *Writer) {
+			headers, cols, rows := testColumns()
+			s, _ := w.AddSheet("Sheet2")
+			if w.sheets[0].cols != nil {
+				s.WriteColumns(headers, cols)
+			} else {
+				s.Write(rows)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, cols, rows := testColumns()
+			columns := New(tt.opts...)
+			if err := columns.WriteColumns(headers, cols); err != nil {
+				t.Fatalf("WriteColumns() failed: %v", err)
+			}
+			written := New(tt.opts...)
+			if err := written.Write(rows); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			if tt.setup != nil {
+				tt.setup(columns)
+				tt.setup(written)
+			}
+			if !bytes.Equal(writtenBytes(t, columns), writtenBytes(t, written)) {
+				t.Error("Expected the file Write writes for the same rows")
+			}
+			if got, want := columns.Stats(), written.Stats(); got != want {
+				t.Errorf("Expected the stats %+v of Write, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestWriteColumnsValues(t *testing.T) {
+	w := New()
+	err := w.WriteColumns(nil, []Column{
+		Float64Column{Values: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, Valid: []byte{0xFF}},
+		StringColumn{Values: []string{"x"}},
+	})
+	if err != nil {
+		t.Fatalf("WriteColumns() failed: %v", err)
+	}
+	sheet := readBack(t, w)
+	if got := sheet.Cell(0, 0).Value; got != 1.0 {
+		t.Errorf("Expected the first row without headers, got %v", got)
+	}
+	if got := sheet.Cell(8, 0).Value; got != nil {
+		t.Errorf("Expected row 8 beyond the bitmap to be empty, got %v", got)
+	}
+	if got := sheet.Cell(0, 1).Value; got != "x" {
+		t.Errorf("Expected %q, got %v", "x", got)
+	}
+	if got := sheet.Cell(1, 1).Value; got != nil {
+		t.Errorf("Expected the shorter column to end with empty cells, got %v", got)
+	}
+
+	// Editing the data turns the columns into rows
+	if err := w.SetCell(1, 1, "y"); err != nil {
+		t.Fatalf("SetCell() failed: %v", err)
+	}
+	if w.sheets[0].cols != nil {
+		t.Error("Expected SetCell to convert the columns to rows")
+	}
+	sheet = readBack(t, w)
+	if got := sheet.Cell(1, 1).Value; got != "y" {
+		t.Errorf("Expected %q, got %v", "y", got)
+	}
+	if got := sheet.Cell(2, 0).Value; got != 3.0 {
+		t.Errorf("Expected the other cells kept, got %v", got)
+	}
+
+	// Write replaces the columns
+	w.Write([][]interface{}{{"row"}})
+	if sheet := readBack(t, w); sheet.Cell(1, 0).Value != nil || sheet.Cell(0, 0).Value != "row" {
+		t.Error("Expected Write to replace the data of WriteColumns")
+	}
+}
+
+func TestWriteColumnsOutOfRange(t *testing.T) {
+	w := New(WithLimits(Limits{MaxRows: 2}))
+	if err := w.WriteColumns([]string{"a"}, []Column{Float64Column{Values: []float64{1, 2}, Valid: []byte{0b01}}}); err != nil {
+		t.Fatalf("WriteColumns() failed: %v", err)
+	}
+	if _, err := w.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Errorf("Expected the null beyond the last row to be allowed, got %v", err)
+	}
+	w.WriteColumns([]string{"a"}, []Column{Float64Column{Values: []float64{1, 2}}})
+	if _, err := w.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for a value beyond the last row")
+	}
+}
@@ -0,0 +1,47 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// recTypeSTANDARDWIDTH is the STANDARDWIDTH record, the default column
+// width of a worksheet in 1/256 of a character.
+const recTypeSTANDARDWIDTH = 0x0099
+
+// WithDefaultColumnWidth sets the width, in characters of the default font
+// from 0 to 255, of the columns without a width of their own. It is
+// written both in the DEFCOLWIDTH record, in whole characters, and in a
+// STANDARDWIDTH record, which Excel writes but the Writer otherwise leaves
+// out, for consumers that need both to size columns. Columns with a style
+// and no width get it too. Widths set with SetColWidth override it.
+func WithDefaultColumnWidth(chars float64) Option {
+	return func(w *Writer) {
+		w.defColWidth = chars
+	}
+}
+
+// checkDefColWidth checks the width of WithDefaultColumnWidth.
+func (w *Writer) checkDefColWidth() error {
+	if w.defColWidth < 0 || w.defColWidth > 255 || math.IsNaN(w.defColWidth) {
+		return fmt.Errorf("%w: default column width %g", ErrOutOfRange, w.defColWidth)
+	}
+	return nil
+}
+
+// colWidth returns the width of the columns without one of their own, in
+// 1/256 of a character.
+func (w *Writer) colWidth() uint16 {
+	if w.defColWidth == 0 {
+		return defaultColWidth
+	}
+	return uint16(math.Round(w.defColWidth * 256))
+}
+
+func (w *Writer) writeStandardWidth(writer io.Writer) error {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data[0:2], w.colWidth())
+	return w.writeRecord(writer, recTypeSTANDARDWIDTH, data)
+}
@@ -0,0 +1,59 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestWithDefaultColumnWidth(t *testing.T) {
+	w := New(WithDefaultColumnWidth(12.5))
+	w.SetColStyle(1, Style{NumberFormat: "0.00"})
+	sheet, _ := w.Sheet("Sheet1")
+	sheet.SetColWidth(2, 30)
+	if err := w.Write([][]interface{}{{"a", 1.5, "b", "c"}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	records := writtenRecords(t, w)
+
+	def := recordsOfType(records, recTypeDEFCOLWIDTH)
+	if len(def) != 1 || binary.LittleEndian.Uint16(def[0].Data) != 12 {
+		t.Errorf("Expected DEFCOLWIDTH of 12 characters, got %v", def)
+	}
+	std := recordsOfType(records, recTypeSTANDARDWIDTH)
+	if len(std) != 1 || binary.LittleEndian.Uint16(std[0].Data) != 12*256+128 {
+		t.Errorf("Expected STANDARDWIDTH of 12.5 characters, got %v", std)
+	}
+
+	// The styled column takes the default width, the sized one its own
+	widths := readBack(t, w).ColWidths()
+	if len(widths) != 2 || widths[1] != 12.5 || widths[2] != 30 {
+		t.Errorf("Expected COLINFO widths 12.5 and 30, got %v", widths)
+	}
+}
+
+func TestDefaultColumnWidthUnset(t *testing.T) {
+	w := New()
+	w.SetColStyle(0, Style{NumberFormat: "0.00"})
+	if err := w.Write([][]interface{}{{1.5}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	records := writtenRecords(t, w)
+	if std := recordsOfType(records, recTypeSTANDARDWIDTH); len(std) != 0 {
+		t.Errorf("Expected no STANDARDWIDTH record, got %v", std)
+	}
+	if def := recordsOfType(records, recTypeDEFCOLWIDTH); len(def) != 1 || binary.LittleEndian.Uint16(def[0].Data) != 8 {
+		t.Errorf("Expected DEFCOLWIDTH of 8 characters, got %v", def)
+	}
+	if info := recordsOfType(records, recTypeCOLINFO); len(info) != 1 || binary.LittleEndian.Uint16(info[0].Data[4:6]) != defaultColWidth {
+		t.Errorf("Expected the styled column at Excel's default width, got %v", info)
+	}
+
+	for _, width := range []float64{-1, 256, math.NaN()} {
+		if _, err := New(WithDefaultColumnWidth(width)).WriteTo(new(bytes.Buffer)); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Width %g: expected ErrOutOfRange, got %v", width, err)
+		}
+	}
+}
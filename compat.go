@@ -0,0 +1,199 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Records only some profiles write
+const (
+	recTypeINDEX  = 0x020B
+	recTypeDBCELL = 0x00D7
+)
+
+// rowBlockSize is the number of ROW records in a row block, which a DBCELL
+// record closes.
+const rowBlockSize = 32
+
+// Profile selects the optional records and record order of the output for
+// a family of readers.
+type Profile int
+
+const (
+	// ProfileDefault is the output of this package without a profile.
+	// Excel, LibreOffice and xlrd read it.
+	ProfileDefault Profile = iota
+	// ProfileExcel97 orders the workbook globals as Excel 97 does, with
+	// the SST after the BOUNDSHEET and NAME records, and adds the INDEX,
+	// DBCELL and EXTSST lookup records Excel writes.
+	ProfileExcel97
+	// ProfilePOI orders the workbook globals as ProfileExcel97 does and
+	// adds the INDEX and DBCELL records, which Apache POI looks for.
+	ProfilePOI
+	// ProfileStrict is ProfileExcel97 with every optional record written,
+	// even with WithMinimalRecords, for validators that reject files
+	// missing any record Excel writes.
+	ProfileStrict
+)
+
+func (p Profile) String() string {
+	if p >= 0 && int(p) < len(profiles) {
+		return profiles[p].name
+	}
+	return fmt.Sprintf("Profile(%d)", int(p))
+}
+
+// globalsPart is a group of records that ends the workbook globals.
+type globalsPart int
+
+const (
+	globalsSST         globalsPart = iota // SST, then EXTSST if the profile writes it
+	globalsBoundSheets                    // One BOUNDSHEET per sheet
+	globalsNames                          // NAME records, then the raw globals
+)
+
+// profileSpec is what a Profile changes in the output.
+type profileSpec struct {
+	name         string
+	index        bool // INDEX after each worksheet BOF, DBCELL after each row block
+	extSST       bool // EXTSST after the SST
+	keepOptional bool // Optional records are written even with WithMinimalRecords
+	// The order of the records after the styles, up to the EOF of the
+	// workbook globals
+	tail []globalsPart
+}
+
+// profiles is the table of profiles, by Profile.
+var profiles = [...]profileSpec{
+	ProfileDefault: {
+		name: "default",
+		tail: []globalsPart{globalsSST, globalsBoundSheets, globalsNames},
+	},
+	ProfileExcel97: {
+		name:   "Excel 97",
+		index:  true,
+		extSST: true,
+		tail:   []globalsPart{globalsBoundSheets, globalsNames, globalsSST},
+	},
+	ProfilePOI: {
+		name:  "POI",
+		index: true,
+		tail:  []globalsPart{globalsBoundSheets, globalsNames, globalsSST},
+	},
+	ProfileStrict: {
+		name:         "strict",
+		index:        true,
+		extSST:       true,
+		keepOptional: true,
+		tail:         []globalsPart{globalsBoundSheets, globalsNames, globalsSST},
+	},
+}
+
+// WithCompatibility selects the optional records and record order of the
+// output for a family of readers; see Profile. Unknown profiles are treated
+// as ProfileDefault.
+func WithCompatibility(p Profile) Option {
+	return func(w *Writer) {
+		w.profile = p
+	}
+}
+
+// spec returns the table entry of the Writer's profile.
+func (w *Writer) spec() *profileSpec {
+	if w.profile < 0 || int(w.profile) >= len(profiles) {
+		return &profiles[ProfileDefault]
+	}
+	return &profiles[w.profile]
+}
+
+// writeIndex writes an INDEX record for a worksheet with the given rows,
+// with room for the stream positions of the DEFCOLWIDTH record and of one
+// DBCELL record per row block. The positions are filled in by patchIndex.
+func (w *Writer) writeIndex(buf io.Writer, rows []rowExtent) error {
+	blocks := (len(rows) + rowBlockSize - 1) / rowBlockSize
+	data := make([]byte, 16+4*blocks)
+	if len(rows) > 0 {
+		binary.LittleEndian.PutUint32(data[4:8], uint32(rows[0].row))
+		binary.LittleEndian.PutUint32(data[8:12], uint32(rows[len(rows)-1].row+1))
+	}
+	return w.writeRecord(buf, recTypeINDEX, data)
+}
+
+// patchIndex sets the positions of the INDEX record at position index of a
+// worksheet substream: defColWidth and dbCells are positions in sheet.
+func patchIndex(sheet io.WriterAt, index, defColWidth int, dbCells []int) error {
+	data := make([]byte, 4+4*len(dbCells))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(defColWidth))
+	for i, pos := range dbCells {
+		binary.LittleEndian.PutUint32(data[4+4*i:], uint32(pos))
+	}
+	_, err := sheet.WriteAt(data, int64(index+4+12))
+	return err
+}
+
+// relocateIndex adds offset, the position of a worksheet substream in the
+// Workbook stream, to the positions of the INDEX record that follows its
+// BOF, which patchIndex set relative to the substream.
+func relocateIndex(sheet streamBuffer, offset int) error {
+	var header [4]byte
+	if _, err := sheet.ReadAt(header[:], 0); err != nil {
+		return err
+	}
+	bofSize := 4 + int(binary.LittleEndian.Uint16(header[2:4]))
+	if _, err := sheet.ReadAt(header[:], int64(bofSize)); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint16(header[0:2]) != recTypeINDEX {
+		return nil
+	}
+	body := make([]byte, binary.LittleEndian.Uint16(header[2:4]))
+	if _, err := sheet.ReadAt(body, int64(bofSize+4)); err != nil {
+		return err
+	}
+	for i := 12; i+4 <= len(body); i += 4 {
+		binary.LittleEndian.PutUint32(body[i:], binary.LittleEndian.Uint32(body[i:])+uint32(offset))
+	}
+	_, err := sheet.WriteAt(body, int64(bofSize+4))
+	return err
+}
+
+// writeDBCell writes the DBCELL record that closes a row block. firstRow is
+// the position of the block's first ROW record and cells the position of
+// the first cell of each of its rows, where the next row's cells start for
+// rows without cells.
+func (w *Writer) writeDBCell(buf streamBuffer, firstRow int, cells []int) error {
+	data := make([]byte, 4+2*len(cells))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(buf.Len()-firstRow))
+	// The first offset is from the second ROW record, each other from the
+	// first cell of the previous row
+	prev := firstRow + 4 + 16
+	for i, pos := range cells {
+		binary.LittleEndian.PutUint16(data[4+2*i:], uint16(pos-prev))
+		prev = pos
+	}
+	return w.writeRecord(buf, recTypeDBCELL, data)
+}
+
+// maxExtSSTBuckets is the most buckets extSSTBucket makes.
+const maxExtSSTBuckets = 128
+
+// extSSTBucket returns the number of strings per EXTSST bucket for an SST
+// of unique strings: 8, or more so that there are at most 128 buckets.
+func extSSTBucket(unique int) int {
+	return max(8, (unique+maxExtSSTBuckets-1)/maxExtSSTBuckets)
+}
+
+// writeExtSST writes the EXTSST record for the SST record that starts at
+// position sstPos: the stream position of the first string of each bucket
+// of n strings, and its offset in the SST or CONTINUE record holding it.
+func (w *Writer) writeExtSST(buf io.Writer, sstPos, n int, buckets []extSSTEntry) error {
+	data := make([]byte, 2, 2+8*len(buckets))
+	binary.LittleEndian.PutUint16(data[0:2], uint16(n))
+	for _, b := range buckets {
+		data = binary.LittleEndian.AppendUint32(data, uint32(sstPos+b.pos))
+		data = binary.LittleEndian.AppendUint16(data, uint16(b.offset))
+		data = append(data, 0, 0)
+	}
+	return w.writeRecord(buf, recTypeEXTSST, data)
+}
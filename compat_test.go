@@ -0,0 +1,133 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// profileWorkbook returns a two-sheet workbook with several row blocks,
+// a named range and enough strings for several EXTSST buckets.
+func profileWorkbook(p Profile, opts ...Option) *Writer {
+	w := New(append([]Option{WithCompatibility(p), WithSheetName("Data")}, opts...)...)
+	data := [][]interface{}{{"Name", "Value", "Even"}}
+	for i := range 70 {
+		data = append(data, []interface{}{fmt.Sprintf("name %d", i), i, i%2 == 0})
+	}
+	w.Write(data)
+	w.SetRowStyle(75, Style{Font: Font{Bold: true}}) // A styled row without cells
+	w.FormatAsTable(0, 70, 0, 2, TableStyle{AutoFilter: true})
+	s, _ := w.AddSheet("Empty")
+	s.SetColWidth(0, 20)
+	return w
+}
+
+func TestWithCompatibility(t *testing.T) {
+	for _, tc := range []struct {
+		profile        Profile
+		index, extSST  bool
+		sstBeforeNames bool
+		optional       bool // The optional records survive WithMinimalRecords
+	}{
+		{ProfileDefault, false, false, true, false},
+		{ProfileExcel97, true, true, false, false},
+		{ProfilePOI, true, false, false, false},
+		{ProfileStrict, true, true, false, true},
+	} {
+		t.Run(tc.profile.String(), func(t *testing.T) {
+			w := profileWorkbook(tc.profile, WithMinimalRecords())
+			stream, err := w.WorkbookStream()
+			if err != nil {
+				t.Fatalf("WorkbookStream() failed: %v", err)
+			}
+			records, err := readAllRecords(stream)
+			if err != nil {
+				t.Fatalf("Failed to read records: %v", err)
+			}
+			at := make(map[int]uint16)
+			first := make(map[uint16]int)
+			for i, rec := range records {
+				at[rec.Offset] = rec.Type
+				if _, ok := first[rec.Type]; !ok {
+					first[rec.Type] = i
+				}
+			}
+
+			if _, ok := first[recTypeINDEX]; ok != tc.index {
+				t.Errorf("Expected INDEX records %v, got %v", tc.index, ok)
+			}
+			if _, ok := first[recTypeEXTSST]; ok != tc.extSST {
+				t.Errorf("Expected an EXTSST record %v, got %v", tc.extSST, ok)
+			}
+			if got := first[recTypeSST] < first[recTypeBOUNDSHEET]; got != tc.sstBeforeNames {
+				t.Errorf("Expected SST before BOUNDSHEET %v, got %v", tc.sstBeforeNames, got)
+			}
+			if got := first[recTypeNAME] < first[recTypeSST]; got == tc.sstBeforeNames {
+				t.Errorf("Expected NAME before SST %v, got %v", !tc.sstBeforeNames, got)
+			}
+			if _, ok := first[recTypeWRITEACCESS]; ok != tc.optional {
+				t.Errorf("Expected WRITEACCESS %v, got %v", tc.optional, ok)
+			}
+
+			le := binary.LittleEndian
+			for i, rec := range records {
+				switch rec.Type {
+				case recTypeINDEX:
+					if at[int(le.Uint32(rec.Data[12:]))] != recTypeDEFCOLWIDTH {
+						t.Errorf("INDEX at %d: expected the position of DEFCOLWIDTH", rec.Offset)
+					}
+					for j := 16; j < len(rec.Data); j += 4 {
+						if at[int(le.Uint32(rec.Data[j:]))] != recTypeDBCELL {
+							t.Errorf("INDEX at %d: expected the position of a DBCELL", rec.Offset)
+						}
+					}
+				case recTypeDBCELL:
+					firstRow := rec.Offset - int(le.Uint32(rec.Data))
+					rows := 0
+					for at[firstRow+20*rows] == recTypeROW {
+						rows++
+					}
+					if rows == 0 || rows > rowBlockSize || len(rec.Data) != 4+2*rows {
+						t.Fatalf("DBCELL at %d: expected an offset to up to 32 ROW records, got %d rows", rec.Offset, rows)
+					}
+					pos := firstRow + 20
+					for j := range rows {
+						pos += int(le.Uint16(rec.Data[4+2*j:]))
+						row := le.Uint16(stream[firstRow+20*j+4:])
+						if typ := at[pos]; typ != recTypeDBCELL && le.Uint16(stream[pos+4:]) != row {
+							t.Errorf("DBCELL at %d: row %d: expected a cell of the row at %d", rec.Offset, row, pos)
+						}
+					}
+				case recTypeEXTSST:
+					sst := records[i-1]
+					n := int(le.Uint16(rec.Data))
+					for j := 2; j < len(rec.Data); j += 8 {
+						pos, off := int(le.Uint32(rec.Data[j:])), int(le.Uint16(rec.Data[j+4:]))
+						str, _, err := readUnicodeString(stream[pos:], 2)
+						// The header row's three strings come first
+						want := "Name"
+						if idx := (j - 2) / 8 * n; idx >= 3 {
+							want = fmt.Sprintf("name %d", idx-3)
+						}
+						if err != nil || pos != sst.Offset+off || str != want {
+							t.Errorf("EXTSST bucket %d: expected %q at %d, got %q at %d", (j-2)/8, want, sst.Offset+off, str, pos)
+						}
+					}
+				}
+			}
+
+			var file bytes.Buffer
+			if _, err := profileWorkbook(tc.profile, WithPostWriteVerification()).WriteTo(&file); err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+			wb, err := openWorkbook(file.Bytes())
+			if err != nil {
+				t.Fatalf("openWorkbook() failed: %v", err)
+			}
+			if got := wb.Sheets()[0].Cell(70, 0).Value; got != "name 69" {
+				t.Errorf("Expected the last name read back, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package xls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rowPlaceholder is replaced in the formula of a computed cell with the
+// one-based number of its row.
+const rowPlaceholder = "{row}"
+
+// AddComputedColumn appends a computed column to the data of the first
+// sheet. See SheetWriter.AddComputedColumn.
+func (w *Writer) AddComputedColumn(header string, fn func(row []interface{}) interface{}) error {
+	return w.sheets[0].AddComputedColumn(header, fn)
+}
+
+// AddComputedColumn appends a column after the widest row of the data, with
+// header in the first row and, in every row after the header rows set with
+// WithHeaderRows, the value fn returns for the row's cells, which fn must
+// not modify. A summary row is a row like any other, so add the column
+// before AddSummaryRow.
+//
+// fn may return a FormulaCell to have Excel compute the cell, with {row} in
+// Expr standing for the number of its row: "B{row}/SUM($B$2:$B$9)" is the
+// share of the total of B2:B9. If fn returns an error, no cell is added and
+// the error is returned with the zero-based index of the row.
+//
+// Like Excel's inserted columns, the new column is formatted as the column
+// to its left: it takes its width, its column style and the styles of its
+// cells.
+func (s *SheetWriter) AddComputedColumn(header string, fn func(row []interface{}) interface{}) error {
+	_, err := s.addColumn(header, func(_ int, row []interface{}) interface{} {
+		return fn(row)
+	})
+	return err
+}
+
+// addColumn appends a computed column, calling fn with the index of each
+// row, and returns the column.
+func (s *SheetWriter) addColumn(header string, fn func(r int, row []interface{}) interface{}) (int, error) {
+	s.materialize()
+	width := 0
+	for _, cells := range s.data {
+		width = max(width, len(cells))
+	}
+	if _, _, err := cellIndex(max(len(s.data), 1)-1, width); err != nil {
+		return 0, err
+	}
+
+	first := max(s.w.headerRows, 1)
+	values := make([]interface{}, max(len(s.data), 1))
+	values[0] = header
+	for r := first; r < len(s.data); r++ {
+		value := fn(r, s.data[r])
+		if err, ok := value.(error); ok {
+			return 0, fmt.Errorf("xls: computed column %q, row %d: %w", header, r, err)
+		}
+		if s.w.norm != nil {
+			value, _ = s.w.normalizeCell(value)
+		}
+		value, _ = plainValue(value)
+		values[r] = computedFormula(value, r)
+	}
+	for r, value := range values {
+		if s.w.converter != nil {
+			var err error
+			if value, _, err = s.w.convertCell(r, width, value); err != nil {
+				return 0, err
+			}
+		}
+		if s.w.escapePrefix != "" {
+			value, _ = s.w.escapeFormula(value)
+		}
+		values[r] = value
+	}
+
+	if !s.dataOwned {
+		s.data, s.dataOwned = append([][]interface{}(nil), s.data...), true
+	}
+	if len(s.data) == 0 {
+		s.data = append(s.data, nil)
+	}
+	for r, value := range values {
+		cells := make([]interface{}, width+1)
+		copy(cells, s.data[r])
+		if width > 0 {
+			value = sameAsLeft(value, cells[width-1])
+		}
+		cells[width] = value
+		s.data[r] = cells
+	}
+	if width > 0 {
+		s.formatSameAsLeft(width)
+	}
+	return width, nil
+}
+
+// computedFormula replaces the row placeholder of a formula computed for a
+// zero-based row.
+func computedFormula(value interface{}, row int) interface{} {
+	switch v := value.(type) {
+	case FormulaCell:
+		v.Expr = strings.ReplaceAll(v.Expr, rowPlaceholder, strconv.Itoa(row+1))
+		return v
+	case StyledCell:
+		v.Value = computedFormula(v.Value, row)
+		return v
+	}
+	return value
+}
+
+// sameAsLeft styles a value of a new column as its left neighbour: the style
+// of a StyledCell to its left is merged under its own.
+func sameAsLeft(value, left interface{}) interface{} {
+	sc, ok := left.(StyledCell)
+	if !ok {
+		return value
+	}
+	if own, ok := value.(StyledCell); ok {
+		own.Style = own.Style.over(sc.Style)
+		return own
+	}
+	return Styled(value, sc.Style)
+}
+
+// formatSameAsLeft copies the width, column style and cell and range
+// styles of the column left of col to col.
+func (s *SheetWriter) formatSameAsLeft(col int) {
+	if width, ok := s.colWidths[col-1]; ok {
+		s.SetColWidth(col, width)
+	}
+	if st, ok := s.colStyles[col-1]; ok {
+		s.SetColStyle(col, st)
+	}
+	for r := range s.data {
+		if st, ok := s.cellStyles[[2]int{r, col - 1}]; ok {
+			s.SetCellStyle(r, col, st)
+		}
+	}
+	s.ranges.extendCol(col)
+}
+
+// metadataColumn holds the settings of WithMetadataColumn.
+type metadataColumn struct {
+	header string
+	fn     func(row, col int, v interface{}) string
+}
+
+// WithMetadataColumn makes Write append a hidden column of annotations,
+// such as the source system, record ID or validation messages of a row,
+// for files whose readers strip comments. fn is called with the zero-based
+// row and column and the value, without its style, of every cell of the
+// rows after the header rows set with WithHeaderRows, and the notes it
+// returns for a row are joined with "; ". The column has header in the
+// first row and width 0, so the visible grid is left as it is; see
+// AddComputedColumn.
+func WithMetadataColumn(header string, fn func(row, col int, v interface{}) string) Option {
+	return func(w *Writer) {
+		w.metadata = &metadataColumn{header: header, fn: fn}
+	}
+}
+
+// addMetadataColumn appends the hidden column of WithMetadataColumn.
+func (s *SheetWriter) addMetadataColumn(m *metadataColumn) error {
+	col, err := s.addColumn(m.header, func(r int, row []interface{}) interface{} {
+		var notes []string
+		for c, v := range row {
+			if sc, ok := v.(StyledCell); ok {
+				v = sc.Value
+			}
+			if note := m.fn(r, c, v); note != "" {
+				notes = append(notes, note)
+			}
+		}
+		if notes == nil {
+			return nil
+		}
+		return strings.Join(notes, "; ")
+	})
+	if err != nil {
+		return err
+	}
+	s.SetColWidth(col, 0)
+	return nil
+}
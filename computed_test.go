@@ -0,0 +1,151 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAddComputedColumn(t *testing.T) {
+	data := [][]interface{}{
+		{"Item", "Qty", "Price"},
+		{"Apple", 3, 1.5},
+		{"Pear", 2},
+	}
+	w := New(WithPostWriteVerification())
+	w.Write(data)
+	if err := w.AddComputedColumn("Source", func(row []interface{}) interface{} {
+		return "stock"
+	}); err != nil {
+		t.Fatalf("AddComputedColumn() failed: %v", err)
+	}
+	if err := w.AddComputedColumn("Share", func(row []interface{}) interface{} {
+		return FormulaCell{Expr: "B{row}/SUM(B2:B3)"}
+	}); err != nil {
+		t.Fatalf("AddComputedColumn() failed: %v", err)
+	}
+	if len(data[2]) != 2 {
+		t.Errorf("Expected the written rows to be left as they are, got %v", data[2])
+	}
+
+	sheet := readBack(t, w)
+	for col, want := range []string{"Item", "Qty", "Price", "Source", "Share"} {
+		if got := sheet.Cell(0, col); got.Value != want {
+			t.Errorf("Header %d: expected %q, got %v", col, want, got.Value)
+		}
+	}
+	for row := 1; row <= 2; row++ {
+		if got := sheet.Cell(row, 3); got.Value != "stock" {
+			t.Errorf("Row %d: expected the constant, got %v", row, got.Value)
+		}
+	}
+	for row, want := range []string{"", "B2/SUM(B2:B3)", "B3/SUM(B2:B3)"} {
+		if got := sheet.Cell(row, 4); row > 0 && (got.Kind != KindFormula || got.Formula != want) {
+			t.Errorf("Row %d: expected the formula %s, got %s %q", row, want, got.Kind, got.Formula)
+		}
+	}
+}
+
+func TestAddComputedColumnFormat(t *testing.T) {
+	percent := Style{NumberFormat: "0.00%"}
+	bold := Style{Font: Font{Bold: true}}
+	w := New()
+	w.Write([][]interface{}{
+		{"Name", Styled("Rate", bold)},
+		{"a", 0.5},
+		{"b", 0.25},
+	})
+	w.sheets[0].SetColWidth(1, 14)
+	w.SetColStyle(1, percent)
+	w.SetCellStyle(2, 1, Style{Fill: ColorYellow})
+	if err := w.AddComputedColumn("Double", func(row []interface{}) interface{} {
+		return row[1].(float64) * 2
+	}); err != nil {
+		t.Fatalf("AddComputedColumn() failed: %v", err)
+	}
+
+	xfs := cellXFs(writtenRecords(t, w))
+	for row := 0; row <= 2; row++ {
+		if xfs[[2]int{row, 2}] != xfs[[2]int{row, 1}] {
+			t.Errorf("Row %d: expected the XF of the column to the left, got %d and %d",
+				row, xfs[[2]int{row, 1}], xfs[[2]int{row, 2}])
+		}
+	}
+	if xfs[[2]int{0, 2}] == xfs[[2]int{1, 2}] || xfs[[2]int{1, 2}] == xfs[[2]int{2, 2}] {
+		t.Errorf("Expected the header, plain and filled cells to differ, got %v", xfs)
+	}
+	sheet := readBack(t, w)
+	if got := sheet.ColWidths()[2]; got != 14 {
+		t.Errorf("Expected the width of the column to the left, got %v", got)
+	}
+	if got := sheet.Cell(1, 2); got.Value != 1.0 || got.FormatString != "0.00%" {
+		t.Errorf("Expected 1 formatted as a percentage, got %v %q", got.Value, got.FormatString)
+	}
+}
+
+func TestAddComputedColumnError(t *testing.T) {
+	errMissing := errors.New("missing price")
+	w := New(WithHeaderRows(2))
+	w.Write([][]interface{}{{"Item", "Price"}, {"", "EUR"}, {"Apple", 1.5}, {"Pear"}})
+	err := w.AddComputedColumn("Tax", func(row []interface{}) interface{} {
+		if len(row) < 2 {
+			return errMissing
+		}
+		return row[1].(float64) * 0.2
+	})
+	if !errors.Is(err, errMissing) || err.Error() != `xls: computed column "Tax", row 3: missing price` {
+		t.Errorf("Expected the error of row 3, got %v", err)
+	}
+	if got := w.sheets[0].data[2]; len(got) != 2 {
+		t.Errorf("Expected no cell to be added, got %v", got)
+	}
+
+	if err := New().AddComputedColumn("Only", func([]interface{}) interface{} { return 1 }); err != nil {
+		t.Fatalf("AddComputedColumn() failed: %v", err)
+	}
+}
+
+func TestWithMetadataColumn(t *testing.T) {
+	w := New(WithHeaderRows(1), WithPostWriteVerification(),
+		WithMetadataColumn("_meta", func(row, col int, v interface{}) string {
+			switch {
+			case col == 0:
+				return fmt.Sprintf("crm:%d", row)
+			case v == nil:
+				return columnName(col) + " missing"
+			}
+			return ""
+		}))
+	w.Write([][]interface{}{
+		{"ID", "Amount"},
+		{"a", Styled(nil, Style{Fill: ColorYellow})},
+		{"b", 2},
+	})
+
+	records := writtenRecords(t, w)
+	var hidden []int
+	for _, rec := range recordsOfType(records, recTypeCOLINFO) {
+		first := int(binary.LittleEndian.Uint16(rec.Data[0:2]))
+		if binary.LittleEndian.Uint16(rec.Data[4:6]) == 0 && rec.Data[8]&0x01 != 0 {
+			hidden = append(hidden, first)
+		}
+	}
+	if len(hidden) != 1 || hidden[0] != 2 {
+		t.Errorf("Expected column 2 hidden, got %v", hidden)
+	}
+
+	sheet := readBack(t, w)
+	for row, want := range []interface{}{"_meta", "crm:1; B missing", "crm:2"} {
+		if got := sheet.Cell(row, 2); got.Value != want {
+			t.Errorf("Row %d: expected %v, got %v", row, want, got.Value)
+		}
+	}
+	if got := sheet.Cell(2, 1); got.Value != 2.0 {
+		t.Errorf("Expected the visible cells to be left as they are, got %v", got.Value)
+	}
+	if got := sheet.ColWidths(); !reflect.DeepEqual(got, map[int]float64{2: 0}) {
+		t.Errorf("Expected only the metadata column sized, got %v", got)
+	}
+}
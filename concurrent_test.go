@@ -0,0 +1,94 @@
+package xls
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// concurrentWorkbook returns a workbook of four sheets, whose cells hold
+// their sheet, row and column.
+func concurrentWorkbook(t *testing.T) *Workbook {
+	t.Helper()
+	w := New(WithSheetName("Sheet 0"))
+	for i := 0; i < 4; i++ {
+		s := w.sheets[0]
+		if i > 0 {
+			var err error
+			if s, err = w.AddSheet(fmt.Sprintf("Sheet %d", i)); err != nil {
+				t.Fatalf("AddSheet() failed: %v", err)
+			}
+		}
+		data := make([][]interface{}, 200)
+		for r := range data {
+			data[r] = []interface{}{fmt.Sprintf("%d-%d", i, r), float64(i*1000 + r)}
+		}
+		if err := s.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		s.SetColWidth(1, 12)
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	return wb
+}
+
+// readSheetConcurrently reads each of the four sheets from two goroutines
+// at once, through each of the accessors, from the workbook workbooks
+// returns for it.
+func readSheetConcurrently(t *testing.T, workbooks func(sheet int) *Workbook) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for reader := 0; reader < 2; reader++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				wb := workbooks(i)
+				s, err := wb.Sheet(fmt.Sprintf("Sheet %d", i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got := s.Cell(150, 1).Value; got != float64(i*1000+150) {
+					t.Errorf("Sheet %d: expected %d, got %v", i, i*1000+150, got)
+				}
+				if rows := s.Rows(); len(rows) != 200 || rows[199][0].Value != fmt.Sprintf("%d-199", i) {
+					t.Errorf("Sheet %d: unexpected rows", i)
+				}
+				if w := s.ColWidths()[1]; w != 12 {
+					t.Errorf("Sheet %d: expected width 12, got %v", i, w)
+				}
+				n := 0
+				if err := wb.ForEachRow(s.Name, func(int, []Cell) error { n++; return nil }); err != nil || n != 200 {
+					t.Errorf("Sheet %d: ForEachRow read %d rows: %v", i, n, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestWorkbookConcurrentSheets(t *testing.T) {
+	wb := concurrentWorkbook(t)
+	readSheetConcurrently(t, func(int) *Workbook { return wb })
+}
+
+func TestWorkbookClone(t *testing.T) {
+	wb := concurrentWorkbook(t)
+	readSheetConcurrently(t, func(int) *Workbook { return wb.Clone() })
+
+	clone := wb.Clone()
+	if clone.Sheets()[0] == wb.Sheets()[0] || clone.Sheets()[0].Name != "Sheet 0" {
+		t.Error("Expected the clone to have sheets of its own")
+	}
+	if wb.Sheets()[2].Cell(0, 0).Value != "2-0" || clone.Sheets()[2].Cell(0, 0).Value != "2-0" {
+		t.Error("Expected the workbook and its clone to read the same cells")
+	}
+}
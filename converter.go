@@ -0,0 +1,104 @@
+package xls
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CellConverter rewrites the value of the cell at the zero-based row and
+// column before it is stored. It returns the value to write, a style for the
+// cell or nil to keep its style, and an error to fail the write.
+type CellConverter func(row, col int, v interface{}) (interface{}, *Style, error)
+
+// WithCellConverter makes Write and SetCell pass every cell through fn, so
+// an application can keep its formatting policy, such as a red font for
+// negative numbers, in one function. Cells are processed in this order:
+//
+//  1. WithSchema converts the values of its columns (Write only)
+//  2. Pointers are replaced by the value they point to, and nil pointers
+//     by nil
+//  3. fn is called with the value; a StyledCell is passed as its Value and
+//     keeps its style unless fn returns one
+//  4. The value returned is written like any other cell
+//
+// fn is also called for nil cells within each row. An error from fn fails
+// Write or SetCell, which then leave the sheet unchanged. The data passed to
+// Write is not modified.
+func WithCellConverter(fn CellConverter) Option {
+	return func(w *Writer) {
+		w.converter = fn
+	}
+}
+
+// convertCells returns data with every cell passed through the cell
+// converter, copying the rows that change. owned reports whether the outer
+// slice of data is already a copy.
+func (s *SheetWriter) convertCells(data [][]interface{}, owned bool) ([][]interface{}, bool, error) {
+	out := data
+	for r, row := range data {
+		var converted []interface{}
+		for c, cell := range row {
+			value, changed, err := s.w.convertCell(r, c, cell)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if converted == nil {
+				converted = append([]interface{}(nil), row...)
+			}
+			converted[c] = value
+		}
+		if converted == nil {
+			continue
+		}
+		if !owned {
+			out, owned = append([][]interface{}(nil), data...), true
+		}
+		out[r] = converted
+	}
+	return out, owned, nil
+}
+
+// convertCell passes a cell through the cell converter and reports whether
+// its value changed.
+func (w *Writer) convertCell(row, col int, cell interface{}) (interface{}, bool, error) {
+	value := cell
+	sc, styled := cell.(StyledCell)
+	if styled {
+		value = sc.Value
+	}
+	value = derefValue(value)
+	v, style, err := w.converter(row, col, value)
+	if err != nil {
+		return nil, false, fmt.Errorf("cell converter failed at row %d, column %d: %w", row, col, err)
+	}
+	switch {
+	case style != nil:
+		return StyledCell{Value: v, Style: *style}, true, nil
+	case styled:
+		sc.Value = v
+		return sc, !sameValue(v, cell.(StyledCell).Value), nil
+	}
+	return v, !sameValue(v, cell), nil
+}
+
+// derefValue returns the value a pointer points to, or nil for a nil
+// pointer. Other values are returned as they are.
+func derefValue(value interface{}) interface{} {
+	if value == nil || reflect.TypeOf(value).Kind() != reflect.Pointer {
+		return value
+	}
+	return fieldValue(reflect.ValueOf(value))
+}
+
+// sameValue reports whether a and b are known to be the same value. Values
+// of types that cannot be compared are reported as different.
+func sameValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
@@ -0,0 +1,116 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCellConverter(t *testing.T) {
+	red := Style{Font: Font{Color: ColorRed}}
+	bold := Style{Font: Font{Bold: true}}
+	type call struct {
+		row, col int
+		v        interface{}
+	}
+	var calls []call
+	negativeRed := func(row, col int, v interface{}) (interface{}, *Style, error) {
+		calls = append(calls, call{row, col, v})
+		if f, ok := toFloat64(v); ok && f < 0 {
+			return v, &red, nil
+		}
+		if s, ok := v.(string); ok && s == "n/a" {
+			return nil, nil, nil
+		}
+		return v, nil, nil
+	}
+
+	n := 5
+	var missing *int
+	data := [][]interface{}{
+		{"Item", "Delta"},
+		{"a", -3},
+		{"b", &n},
+		{"c", missing},
+		{Styled("d", bold), "n/a"},
+	}
+	w := New(WithCellConverter(negativeRed))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	want := [][]interface{}{
+		{"Item", "Delta"},
+		{"a", Styled(-3, red)},
+		{"b", 5},
+		{"c", nil},
+		{Styled("d", bold), nil},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if data[1][1] != -3 || data[4][1] != "n/a" {
+		t.Error("Expected the data passed to Write unchanged")
+	}
+	if len(calls) != 10 || calls[5] != (call{2, 1, 5}) || calls[8] != (call{4, 0, "d"}) {
+		t.Errorf("Expected every cell converted with pointers dereferenced, got %v", calls)
+	}
+	if &w.sheets[0].data[0][0] != &data[0][0] {
+		t.Error("Expected unchanged rows to be shared with the data")
+	}
+
+	if err := w.SetCell(5, 1, -1.5); err != nil {
+		t.Fatalf("SetCell() failed: %v", err)
+	}
+	if got := w.sheets[0].data[5][1]; got != Styled(-1.5, red) {
+		t.Errorf("Expected SetCell to convert the value, got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+}
+
+func TestCellConverterAfterSchema(t *testing.T) {
+	var seen []interface{}
+	w := New(
+		WithSchema(ColumnSchema{{Name: "Amount", Type: ColumnNumber}}),
+		WithCellConverter(func(row, col int, v interface{}) (interface{}, *Style, error) {
+			if row > 0 {
+				seen = append(seen, v)
+			}
+			return v, nil, nil
+		}),
+	)
+	if err := w.Write([][]interface{}{{"Amount"}, {"12.5"}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if !reflect.DeepEqual(seen, []interface{}{12.5}) {
+		t.Errorf("Expected the converter to see the value converted by the schema, got %v", seen)
+	}
+}
+
+func TestCellConverterError(t *testing.T) {
+	veto := errors.New("no secrets")
+	w := New(WithCellConverter(func(row, col int, v interface{}) (interface{}, *Style, error) {
+		if v == "secret" {
+			return nil, nil, veto
+		}
+		return v, nil, nil
+	}))
+	w.Write([][]interface{}{{"ok"}})
+
+	if err := w.Write([][]interface{}{{"a", "b"}, {"c", "secret"}}); !errors.Is(err, veto) {
+		t.Errorf("Expected the converter's error from Write, got %v", err)
+	} else if want := "cell converter failed at row 1, column 1: no secrets"; err.Error() != want {
+		t.Errorf("Expected error %q, got %q", want, err)
+	}
+	if err := w.SetCell(0, 1, "secret"); !errors.Is(err, veto) {
+		t.Errorf("Expected the converter's error from SetCell, got %v", err)
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, [][]interface{}{{"ok"}}) {
+		t.Errorf("Expected the sheet unchanged after an error, got %v", got)
+	}
+}
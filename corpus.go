@@ -0,0 +1,195 @@
+package xls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CorpusCase is a workbook of the compatibility corpus: one combination of
+// the cell types, styles, size and writer options of the matrix.
+type CorpusCase struct {
+	Name    string // File name without the .xls extension, such as "dates-styled-medium-excel97"
+	Path    string // Path of the written file
+	Types   string // Cell types: "numbers", "strings", "bools", "dates" or "formulas"
+	Style   string // "plain" or "styled"
+	Size    string // "small", "medium" or "large"
+	Options string // Writer options: "default", "excel97", "poi", "hybrid", "cfbv4" or "minimal"
+	Sheet   string // Name of the sheet holding Data
+	Data    [][]interface{}
+}
+
+// corpusAxis is a value of an axis of the corpus matrix.
+type corpusAxis[T any] struct {
+	name  string
+	value T
+}
+
+// The axes of the corpus matrix
+var (
+	corpusTypes = []corpusAxis[func(r, c int) interface{}]{
+		{"numbers", corpusNumber},
+		{"strings", corpusString},
+		{"bools", func(r, c int) interface{} { return (r+c)%3 == 0 }},
+		{"dates", corpusDate},
+		{"formulas", corpusFormula},
+	}
+	corpusStyles = []corpusAxis[bool]{{"plain", false}, {"styled", true}}
+	// Small files stay in the mini stream, medium ones continue the SST and
+	// large ones need several FAT sectors
+	corpusSizes   = []corpusAxis[int]{{"small", 10}, {"medium", 500}, {"large", 5000}}
+	corpusOptions = []corpusAxis[[]Option]{
+		{"default", nil},
+		{"excel97", []Option{WithCompatibility(ProfileExcel97)}},
+		{"poi", []Option{WithCompatibility(ProfilePOI)}},
+		{"hybrid", []Option{WithHybridStrings(2)}},
+		{"cfbv4", []Option{WithCFBVersion4()}},
+		{"minimal", []Option{WithMinimalRecords()}},
+	}
+)
+
+// corpusColumns is the number of columns of a corpus sheet.
+const corpusColumns = 4
+
+// corpusSheet is the name of the sheet of the corpus workbooks.
+const corpusSheet = "Data"
+
+// corpusStrings are the strings the string cells of the corpus cycle
+// through, besides the unique ones.
+var corpusStrings = []string{"open", "Zürich", "東京", "naïve café", "emoji \U0001F600", "tab\there", "line\nbreak", ""}
+
+func corpusNumber(r, c int) interface{} {
+	switch c {
+	case 0:
+		return r
+	case 1:
+		return float64(r) / 7
+	case 2:
+		return -float64(r) * 1e6
+	}
+	return 1e-5 * float64(r)
+}
+
+func corpusString(r, c int) interface{} {
+	switch c {
+	case 0:
+		return fmt.Sprintf("row %d", r) // Unique, so larger files continue the SST
+	case 1:
+		return corpusStrings[r%len(corpusStrings)]
+	case 2:
+		if r%50 == 0 {
+			return strings.Repeat("long ", 60) // Longer than a LABEL holds
+		}
+		return "same"
+	}
+	return fmt.Sprintf("%d-%d", r%10, c)
+}
+
+func corpusDate(r, c int) interface{} {
+	t := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, r)
+	if c%2 == 1 {
+		return t.Add(time.Duration(r*c) * time.Minute)
+	}
+	return t
+}
+
+func corpusFormula(r, c int) interface{} {
+	switch c {
+	case 0:
+		return r
+	case 1:
+		return FormulaCell{Expr: fmt.Sprintf("A%d*2", r+2), Cached: float64(r * 2)}
+	case 2:
+		cached := "small"
+		if r > 5 {
+			cached = "big"
+		}
+		return FormulaCell{Expr: fmt.Sprintf("IF(A%d>5,\"big\",\"small\")", r+2), Cached: cached}
+	}
+	return FormulaCell{Expr: fmt.Sprintf("A%d>3", r+2), Cached: r > 3}
+}
+
+// corpusStyle returns the style of a cell of column c of the given types.
+func corpusStyle(types string, c int) Style {
+	st := Style{Font: Font{Italic: c%2 == 1}, Fill: []Color{ColorAuto, ColorYellow, ColorCyan, ColorAuto}[c]}
+	if c == 3 {
+		st.Border = Border{Bottom: BorderThin}
+	}
+	switch types {
+	case "numbers":
+		st.NumberFormat = "#,##0.00"
+	case "dates":
+		st.NumberFormat = "yyyy-mm-dd hh:mm"
+	case "strings":
+		st.WrapText = true
+	}
+	return st
+}
+
+// GenerateCompatibilityCorpus writes the compatibility corpus to dir,
+// creating it if needed: a workbook for each combination of cell types,
+// styles, sizes and writer options, named after the combination. It
+// returns the cases with the data each was written from, for Verify, the
+// reader and other applications to check the files against. Maintainers
+// run it, through cmd/xlscompat, before releases and after adding records.
+func GenerateCompatibilityCorpus(dir string) ([]CorpusCase, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var cases []CorpusCase
+	for _, types := range corpusTypes {
+		for _, style := range corpusStyles {
+			for _, size := range corpusSizes {
+				data := corpusData(types.name, types.value, style.value, size.value)
+				for _, opts := range corpusOptions {
+					name := strings.Join([]string{types.name, style.name, size.name, opts.name}, "-")
+					cc := CorpusCase{
+						Name:    name,
+						Path:    filepath.Join(dir, name+".xls"),
+						Types:   types.name,
+						Style:   style.name,
+						Size:    size.name,
+						Options: opts.name,
+						Sheet:   corpusSheet,
+						Data:    data,
+					}
+					w := New(append([]Option{WithSheetName(corpusSheet)}, opts.value...)...)
+					if err := w.Write(data); err != nil {
+						return nil, fmt.Errorf("corpus %s: %w", name, err)
+					}
+					if err := w.SaveAs(cc.Path); err != nil {
+						return nil, fmt.Errorf("corpus %s: %w", name, err)
+					}
+					cases = append(cases, cc)
+				}
+			}
+		}
+	}
+	return cases, nil
+}
+
+// corpusData returns a header row and rows of cells of the given types.
+func corpusData(types string, cell func(r, c int) interface{}, styled bool, rows int) [][]interface{} {
+	data := make([][]interface{}, 0, rows+1)
+	header := make([]interface{}, corpusColumns)
+	for c := range header {
+		header[c] = fmt.Sprintf("%s %d", types, c+1)
+		if styled {
+			header[c] = Styled(header[c], Style{Font: Font{Bold: true}, HAlign: HAlignCenter})
+		}
+	}
+	data = append(data, header)
+	for r := range rows {
+		row := make([]interface{}, corpusColumns)
+		for c := range row {
+			row[c] = cell(r, c)
+			if styled {
+				row[c] = Styled(row[c], corpusStyle(types, c))
+			}
+		}
+		data = append(data, row)
+	}
+	return data
+}
@@ -0,0 +1,38 @@
+package xls
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateCompatibilityCorpus(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "corpus")
+	cases, err := GenerateCompatibilityCorpus(dir)
+	if err != nil {
+		t.Fatalf("GenerateCompatibilityCorpus() failed: %v", err)
+	}
+	want := len(corpusTypes) * len(corpusStyles) * len(corpusSizes) * len(corpusOptions)
+	if len(cases) != want {
+		t.Fatalf("Expected %d cases, got %d", want, len(cases))
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != want {
+		t.Errorf("Expected %d files, got %d", want, len(entries))
+	}
+
+	names := make(map[string]bool)
+	for _, cc := range cases {
+		if names[cc.Name] {
+			t.Errorf("Case %s generated twice", cc.Name)
+		}
+		names[cc.Name] = true
+		file, err := os.ReadFile(cc.Path)
+		if err != nil {
+			t.Fatalf("%s: %v", cc.Name, err)
+		}
+		if err := Verify(file, cc.Sheet, cc.Data); err != nil {
+			t.Errorf("%s: Verify() failed: %v", cc.Name, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package xls
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Crosstab turns long-format rows, such as (region, month, sales), into a
+// grid ready to pass to Write: the unique values of column rowKey down the
+// first column, the unique values of column colKey across the first row,
+// and in each cell the aggregate of column valueCol over the rows with that
+// pair of keys. Combinations without rows are blank, as are Sum, Avg, Min
+// and Max of values without numbers; see Aggregate. Min, Max and Avg of
+// dates are dates.
+//
+// rows holds no header row; the top left cell of the grid is blank. Keys
+// are matched and sorted as Excel sorts: numbers, then dates, text in any
+// case, booleans and errors, with a blank key last. Keys of other types are
+// matched by their text, as the Writer writes them. A missing cell of a
+// short row counts as blank. Crosstab returns ErrOutOfRange for a negative
+// column or a grid larger than a sheet, and ErrUnsupportedCellType for a
+// formula used as a key.
+func Crosstab(rows [][]interface{}, rowKey, colKey, valueCol int, agg Aggregate) ([][]interface{}, error) {
+	if rowKey < 0 || colKey < 0 || valueCol < 0 {
+		return nil, fmt.Errorf("%w: crosstab columns %d, %d and %d", ErrOutOfRange, rowKey, colKey, valueCol)
+	}
+	if agg < Sum || agg > Max {
+		return nil, fmt.Errorf("xls: unknown aggregate %d", int(agg))
+	}
+
+	var rowKeys, colKeys []crosstabKey
+	var rowValues, colValues []interface{}
+	rowIndex := make(map[crosstabKey]int)
+	colIndex := make(map[crosstabKey]int)
+	cells := make(map[[2]int]*aggregator)
+	for i, row := range rows {
+		r, rv, err := newCrosstabKey(cellAtIndex(row, rowKey))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		c, cv, err := newCrosstabKey(cellAtIndex(row, colKey))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		if _, ok := rowIndex[r]; !ok {
+			rowIndex[r] = len(rowKeys)
+			rowKeys, rowValues = append(rowKeys, r), append(rowValues, rv)
+		}
+		if _, ok := colIndex[c]; !ok {
+			colIndex[c] = len(colKeys)
+			colKeys, colValues = append(colKeys, c), append(colValues, cv)
+		}
+
+		cell := [2]int{rowIndex[r], colIndex[c]}
+		a := cells[cell]
+		if a == nil {
+			a = new(aggregator)
+			cells[cell] = a
+		}
+		value, _ := plainValue(cellAtIndex(row, valueCol))
+		f, isDate, ok := cellNumber(value)
+		a.add(value, f, isDate, ok)
+	}
+	if len(rowKeys) > maxRow || len(colKeys) > maxColumn {
+		return nil, fmt.Errorf("%w: crosstab of %d rows and %d columns", ErrOutOfRange, len(rowKeys)+1, len(colKeys)+1)
+	}
+
+	rowOrder := sortedKeys(rowKeys)
+	colOrder := sortedKeys(colKeys)
+	grid := make([][]interface{}, 0, len(rowKeys)+1)
+	header := make([]interface{}, 1, len(colKeys)+1)
+	for _, c := range colOrder {
+		header = append(header, colValues[c])
+	}
+	grid = append(grid, header)
+	for _, r := range rowOrder {
+		line := make([]interface{}, 1, len(colKeys)+1)
+		line[0] = rowValues[r]
+		for _, c := range colOrder {
+			var value interface{}
+			if a := cells[[2]int{r, c}]; a != nil {
+				var dates bool
+				value, dates = a.result(agg)
+				if f, ok := value.(float64); ok && dates {
+					value, _ = serialToTime(f, false, time.UTC)
+				}
+			}
+			line = append(line, value)
+		}
+		grid = append(grid, line)
+	}
+	return grid, nil
+}
+
+// cellAtIndex returns the cell at col of row, or nil past its end.
+func cellAtIndex(row []interface{}, col int) interface{} {
+	if col < len(row) {
+		return row[col]
+	}
+	return nil
+}
+
+// Kinds of crosstab keys, in the order Excel sorts them
+const (
+	keyNumber = iota
+	keyDate
+	keyText
+	keyBool
+	keyError
+	keyBlank
+)
+
+// crosstabKey is a comparable form of a key cell: its kind and value.
+type crosstabKey struct {
+	kind int
+	num  float64
+	text string
+	sec  int64
+	nsec int
+}
+
+// newCrosstabKey returns the key of a cell value and the value the grid
+// shows for it.
+func newCrosstabKey(value interface{}) (crosstabKey, interface{}, error) {
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	value, _ = plainValue(value)
+	switch v := value.(type) {
+	case nil:
+		return crosstabKey{kind: keyBlank}, nil, nil
+	case bool:
+		return crosstabKey{kind: keyBool, text: fmt.Sprint(v)}, v, nil
+	case CellError:
+		return crosstabKey{kind: keyError, num: float64(v)}, v, nil
+	case time.Time:
+		// The location is left out, so that equal times match
+		return crosstabKey{kind: keyDate, sec: v.Unix(), nsec: v.Nanosecond()}, v, nil
+	case FormulaCell:
+		return crosstabKey{}, nil, fmt.Errorf("%w: formula %q as a crosstab key", ErrUnsupportedCellType, v.Expr)
+	}
+	if f, ok := toFloat64(value); ok {
+		return crosstabKey{kind: keyNumber, num: f}, f, nil
+	}
+	text, _ := sstString(value)
+	return crosstabKey{kind: keyText, text: text}, text, nil
+}
+
+// compare orders keys as Excel sorts cells.
+func (k crosstabKey) compare(o crosstabKey) int {
+	return cmp.Or(
+		cmp.Compare(k.kind, o.kind),
+		cmp.Compare(k.num, o.num),
+		cmp.Compare(k.sec, o.sec),
+		cmp.Compare(k.nsec, o.nsec),
+		cmp.Compare(strings.ToLower(k.text), strings.ToLower(o.text)),
+		cmp.Compare(k.text, o.text),
+	)
+}
+
+// sortedKeys returns the indexes of keys in sorted order.
+func sortedKeys(keys []crosstabKey) []int {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return keys[a].compare(keys[b])
+	})
+	return order
+}
@@ -0,0 +1,144 @@
+package xls
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCrosstab(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	sales := [][]interface{}{
+		{"west", "Feb", 5},
+		{"east", "Jan", 10},
+		{"west", "Jan", 1.5},
+		{"east", "Jan", 20}, // Same pair as the second row
+		{"north", "Feb", "n/a"},
+		{"west", "Feb", Number(7)},
+	}
+
+	for _, tc := range []struct {
+		name                     string
+		rows                     [][]interface{}
+		rowKey, colKey, valueCol int
+		agg                      Aggregate
+		want                     [][]interface{}
+	}{
+		{
+			name: "sum", rows: sales, rowKey: 0, colKey: 1, valueCol: 2, agg: Sum,
+			want: [][]interface{}{
+				{nil, "Feb", "Jan"},
+				{"east", nil, 30.0},
+				{"north", nil, nil},
+				{"west", 12.0, 1.5},
+			},
+		},
+		{
+			name: "count", rows: sales, rowKey: 0, colKey: 1, valueCol: 2, agg: Count,
+			want: [][]interface{}{
+				{nil, "Feb", "Jan"},
+				{"east", nil, 2.0},
+				{"north", 1.0, nil},
+				{"west", 2.0, 1.0},
+			},
+		},
+		{
+			name: "transposed average", rows: sales, rowKey: 1, colKey: 0, valueCol: 2, agg: Avg,
+			want: [][]interface{}{
+				{nil, "east", "north", "west"},
+				{"Feb", nil, nil, 6.0},
+				{"Jan", 15.0, nil, 1.5},
+			},
+		},
+		{
+			name: "min and max", rows: sales, rowKey: 0, colKey: 1, valueCol: 2, agg: Max,
+			want: [][]interface{}{
+				{nil, "Feb", "Jan"},
+				{"east", nil, 20.0},
+				{"north", nil, nil},
+				{"west", 7.0, 1.5},
+			},
+		},
+		{
+			name: "key kinds",
+			rows: [][]interface{}{
+				{"b", true, 1},
+				{"A", 2, 1},
+				{"a", 1.0, 1},
+				{nil, jan, 1},
+				{2, 1, 1}, // Ties with the 1.0 column
+				{"a", "x", 1},
+				{"a", CellErrorNA, 1},
+				{"a", feb, 1},
+				{"a", nil, 1},
+				{Text("b"), Styled("x", Style{}), 1},
+			},
+			rowKey: 0, colKey: 1, valueCol: 2, agg: Sum,
+			want: [][]interface{}{
+				{nil, 1.0, 2.0, jan, feb, "x", true, CellErrorNA, nil},
+				{2.0, 1.0, nil, nil, nil, nil, nil, nil, nil},
+				{"A", nil, 1.0, nil, nil, nil, nil, nil, nil},
+				{"a", 1.0, nil, nil, 1.0, 1.0, nil, 1.0, 1.0},
+				{"b", nil, nil, nil, nil, 1.0, 1.0, nil, nil},
+				{nil, nil, nil, 1.0, nil, nil, nil, nil, nil},
+			},
+		},
+		{
+			name: "dates and short rows",
+			rows: [][]interface{}{
+				{"x", "min", feb},
+				{"x", "min", jan},
+				{"y", "min"},
+			},
+			rowKey: 0, colKey: 1, valueCol: 2, agg: Min,
+			want: [][]interface{}{
+				{nil, "min"},
+				{"x", jan},
+				{"y", nil},
+			},
+		},
+		{
+			name: "empty", rowKey: 0, colKey: 1, valueCol: 2, agg: Sum,
+			want: [][]interface{}{{nil}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Crosstab(tc.rows, tc.rowKey, tc.colKey, tc.valueCol, tc.agg)
+			if err != nil {
+				t.Fatalf("Crosstab() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected:\n%v\ngot:\n%v", tc.want, got)
+			}
+			if err := New().Write(got); err != nil {
+				t.Errorf("Write() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCrosstabErrors(t *testing.T) {
+	rows := [][]interface{}{{"a", "b", 1}}
+	if _, err := Crosstab(rows, -1, 1, 2, Sum); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected an out of range error for a negative column, got %v", err)
+	}
+	if _, err := Crosstab(rows, 0, 1, 2, Max+1); err == nil {
+		t.Error("Expected an error for an unknown aggregate")
+	}
+	formula := [][]interface{}{{FormulaCell{Expr: "1"}, "b", 1}}
+	if _, err := Crosstab(formula, 0, 1, 2, Sum); !errors.Is(err, ErrUnsupportedCellType) {
+		t.Errorf("Expected an unsupported type error for a formula key, got %v", err)
+	}
+	var wide [][]interface{}
+	for i := range maxColumn + 1 {
+		wide = append(wide, []interface{}{"a", i, 1})
+	}
+	if _, err := Crosstab(wide, 0, 1, 2, Sum); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected an out of range error for too many columns, got %v", err)
+	}
+	if _, err := Crosstab(wide[1:], 0, 1, 2, Sum); err != nil {
+		t.Errorf("Expected the widest grid to fit, got %v", err)
+	}
+}
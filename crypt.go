@@ -0,0 +1,378 @@
+package xls
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+)
+
+var (
+	// ErrWrongPassword is returned when an encrypted workbook is opened
+	// without its password, or with another one.
+	ErrWrongPassword = errors.New("xls: wrong password")
+	// ErrUnsupportedEncryption is returned for workbooks encrypted with a
+	// method the reader does not implement.
+	ErrUnsupportedEncryption = errors.New("xls: unsupported encryption")
+)
+
+// Records of an encrypted workbook stream left in the clear
+const (
+	recTypeFILEPASS = 0x002F
+	recTypeUSREXCL  = 0x0194
+	recTypeFILELOCK = 0x0195
+	recTypeRRDINFO  = 0x0196
+	recTypeRRDHEAD  = 0x0138
+)
+
+// defaultPassword is the password Excel encrypts with when a workbook is
+// only protected against changes, and tries before asking for one.
+const defaultPassword = "VelvetSweatshop"
+
+// rc4BlockSize is the number of bytes of the workbook stream encrypted
+// with each key.
+const rc4BlockSize = 1024
+
+// OpenFileWithPassword opens and parses the XLS file at path, decrypting it
+// with password if it is encrypted. Files encrypted with RC4, either the
+// Office 97 method or the CryptoAPI method of later versions, and files
+// under the XOR obfuscation of Excel 95 can be read; a wrong password
+// returns ErrWrongPassword and other methods ErrUnsupportedEncryption.
+// Files that are not encrypted are read as by OpenFile.
+func OpenFileWithPassword(path, password string, opts ...ReadOption) (*Workbook, error) {
+	limits := newReadOptions(opts).limits
+	data, err := readFile(path, limits)
+	if err != nil {
+		return nil, err
+	}
+	return openWorkbookWithPassword(data, password, limits)
+}
+
+// decryptStream returns the workbook stream decrypted with password, or the
+// stream itself if it holds no FILEPASS record.
+func decryptStream(stream []byte, password string) ([]byte, error) {
+	r := &recordReader{data: stream}
+	for {
+		recType, data, offset, err := r.next()
+		if err == io.EOF || recType == recTypeEOF {
+			return stream, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if recType != recTypeFILEPASS {
+			continue
+		}
+
+		decrypt, err := filePassDecrypter(data, password)
+		if err != nil {
+			if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrUnsupportedEncryption) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: FILEPASS record at offset %d: %v", ErrInvalidFormat, offset, err)
+		}
+		out := bytes.Clone(stream)
+		if err := cryptRecords(out, decrypt); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+}
+
+// recordCrypter encrypts or decrypts b in place, the part of the body of a
+// record of recSize bytes at position pos of the stream.
+type recordCrypter func(b []byte, pos, recSize int)
+
+// filePassDecrypter returns the decrypter of a stream encrypted as the
+// FILEPASS record body data describes, after checking password against its
+// verifier.
+func filePassDecrypter(data []byte, password string) (recordCrypter, error) {
+	if len(data) < 6 {
+		return nil, errors.New("record too short")
+	}
+	switch binary.LittleEndian.Uint16(data) {
+	case 0:
+		x, ok := newXORObfuscation(password)
+		if !ok || x.key != binary.LittleEndian.Uint16(data[2:]) || x.verifier != binary.LittleEndian.Uint16(data[4:]) {
+			return nil, wrongPassword(password)
+		}
+		return x.decrypt, nil
+	case 1:
+		keys, err := rc4FilePassKeys(data, password)
+		if err != nil {
+			return nil, err
+		}
+		return (&rc4Stream{key: keys}).crypt, nil
+	default:
+		return nil, fmt.Errorf("%w: encryption type %d", ErrUnsupportedEncryption, binary.LittleEndian.Uint16(data))
+	}
+}
+
+// wrongPassword returns the error of a password that does not match the
+// verifier of a file.
+func wrongPassword(password string) error {
+	if password == defaultPassword {
+		return fmt.Errorf("%w: the file is encrypted, open it with OpenFileWithPassword", ErrWrongPassword)
+	}
+	return ErrWrongPassword
+}
+
+// rc4FilePassKeys returns the keys of the blocks of a stream encrypted with
+// RC4 as the FILEPASS record body data describes, after checking password
+// against its verifier.
+func rc4FilePassKeys(data []byte, password string) (func(block uint32) []byte, error) {
+	major, minor := binary.LittleEndian.Uint16(data[2:]), binary.LittleEndian.Uint16(data[4:])
+	data = data[6:]
+
+	var (
+		keys                   func(block uint32) []byte
+		verifier, verifierHash []byte
+		hash                   func([]byte) []byte
+	)
+	switch {
+	case major == 1 && minor == 1:
+		// Office 97 RC4: the salt, the verifier and its MD5 hash
+		if len(data) < 48 {
+			return nil, errors.New("record too short")
+		}
+		keys = rc4Keys(password, data[:16])
+		verifier, verifierHash = data[16:32], data[32:48]
+		hash = func(b []byte) []byte { h := md5.Sum(b); return h[:] }
+	case major >= 2 && major <= 4 && minor == 2:
+		// CryptoAPI RC4: flags, the size and body of an encryption header,
+		// then the salt, the verifier and its SHA-1 hash, each with its size
+		if len(data) < 8 {
+			return nil, errors.New("record too short")
+		}
+		headerSize := int(binary.LittleEndian.Uint32(data[4:]))
+		if headerSize < 32 || len(data) < 8+headerSize+4+16+16+4+20 {
+			return nil, errors.New("record too short")
+		}
+		header := data[8 : 8+headerSize]
+		algID, hashID := binary.LittleEndian.Uint32(header[8:]), binary.LittleEndian.Uint32(header[12:])
+		keyBits := int(binary.LittleEndian.Uint32(header[16:]))
+		if keyBits == 0 {
+			keyBits = 40
+		}
+		if algID != 0x6801 || (hashID != 0x8004 && hashID != 0) || keyBits < 40 || keyBits > 128 || keyBits%8 != 0 {
+			return nil, fmt.Errorf("%w: CryptoAPI algorithm %#x, hash %#x, %d-bit key", ErrUnsupportedEncryption, algID, hashID, keyBits)
+		}
+		v := data[8+headerSize:]
+		if binary.LittleEndian.Uint32(v) != 16 || binary.LittleEndian.Uint32(v[36:]) != 20 {
+			return nil, errors.New("unexpected salt or verifier hash size")
+		}
+		keys = cryptoAPIKeys(password, v[4:20], keyBits)
+		verifier, verifierHash = v[20:36], v[40:60]
+		hash = func(b []byte) []byte { h := sha1.Sum(b); return h[:] }
+	default:
+		return nil, fmt.Errorf("%w: RC4 version %d.%d", ErrUnsupportedEncryption, major, minor)
+	}
+
+	// The verifier and its hash are encrypted in turn with the key of
+	// block 0
+	c, _ := rc4.NewCipher(keys(0))
+	plain := make([]byte, len(verifier)+len(verifierHash))
+	c.XORKeyStream(plain, append(bytes.Clone(verifier), verifierHash...))
+	if !bytes.Equal(hash(plain[:len(verifier)]), plain[len(verifier):]) {
+		return nil, wrongPassword(password)
+	}
+	return keys, nil
+}
+
+// rc4Keys returns the keys of the Office 97 RC4 encryption: an MD5 hash of
+// a hash of the password and salt, truncated to 40 bits, and the block
+// number.
+func rc4Keys(password string, salt []byte) func(block uint32) []byte {
+	h0 := md5.Sum(stringToUTF16LE(password))
+	buf := make([]byte, 0, 16*(5+len(salt)))
+	for range 16 {
+		buf = append(buf, h0[:5]...)
+		buf = append(buf, salt...)
+	}
+	h1 := md5.Sum(buf)
+	return func(block uint32) []byte {
+		h := md5.Sum(binary.LittleEndian.AppendUint32(h1[:5:5], block))
+		return h[:]
+	}
+}
+
+// cryptoAPIKeys returns the keys of the CryptoAPI RC4 encryption: a SHA-1
+// hash of a hash of the salt and password and the block number, truncated
+// to keyBits. 40-bit keys are padded with zeros to 128 bits.
+func cryptoAPIKeys(password string, salt []byte, keyBits int) func(block uint32) []byte {
+	h0 := sha1.Sum(append(bytes.Clone(salt), stringToUTF16LE(password)...))
+	return func(block uint32) []byte {
+		h := sha1.Sum(binary.LittleEndian.AppendUint32(h0[:], block))
+		if keyBits == 40 {
+			return append(h[:5:5], make([]byte, 11)...)
+		}
+		return h[:keyBits/8]
+	}
+}
+
+// rc4Stream encrypts or decrypts bytes of a stream at their position, with
+// a key for each block of rc4BlockSize bytes.
+type rc4Stream struct {
+	key    func(block uint32) []byte
+	cipher *rc4.Cipher
+	block  int // Block of the cipher
+	pos    int // Stream position of the next byte of the cipher
+}
+
+// crypt encrypts or decrypts b, which is at position pos of the stream, in
+// place. It is a recordCrypter.
+func (s *rc4Stream) crypt(b []byte, pos, _ int) {
+	for len(b) > 0 {
+		block := pos / rc4BlockSize
+		if s.cipher == nil || s.block != block || s.pos > pos {
+			s.cipher, _ = rc4.NewCipher(s.key(uint32(block)))
+			s.block, s.pos = block, block*rc4BlockSize
+		}
+		if skip := pos - s.pos; skip > 0 {
+			s.cipher.XORKeyStream(make([]byte, skip), make([]byte, skip))
+		}
+		n := min(len(b), (block+1)*rc4BlockSize-pos)
+		s.cipher.XORKeyStream(b[:n], b[:n])
+		s.pos, pos, b = pos+n, pos+n, b[n:]
+	}
+}
+
+// cryptRecords encrypts or decrypts the record bodies of a workbook stream
+// in place. Record headers, the records that identify the stream and its
+// encryption and the stream offset of each BOUNDSHEET record stay in the
+// clear, but take their positions in the key stream.
+func cryptRecords(stream []byte, crypt recordCrypter) error {
+	r := &recordReader{data: stream}
+	for {
+		recType, data, offset, err := r.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		body, size := offset+4, len(data)
+		switch recType {
+		case recTypeBOF, recTypeFILEPASS, recTypeUSREXCL, recTypeFILELOCK, recTypeINTERFACEHDR, recTypeRRDINFO, recTypeRRDHEAD:
+			continue
+		case recTypeBOUNDSHEET:
+			if len(data) < 4 {
+				return recordError(recType, offset)
+			}
+			data, body = data[4:], body+4
+		}
+		crypt(data, body, size)
+	}
+}
+
+// WithLegacyXORPassword makes SaveAs and WriteTo protect the workbook with
+// the XOR obfuscation of Excel 95, which applications ask the password of
+// before opening it. It is only meant for old software that understands
+// nothing else: the 16-bit key is easily recovered from the file, so it
+// protects nothing. The password must be 1 to 15 ASCII characters; an empty
+// one leaves the workbook in the clear. WorkbookStream returns the
+// obfuscated stream.
+func WithLegacyXORPassword(password string) Option {
+	return func(w *Writer) {
+		w.xorPassword = password
+	}
+}
+
+// writeFilePass writes the FILEPASS record of the XOR obfuscation set with
+// WithLegacyXORPassword.
+func (w *Writer) writeFilePass(writer io.Writer) error {
+	if w.xorPassword == "" {
+		return nil
+	}
+	x, ok := newXORObfuscation(w.xorPassword)
+	if !ok || strings.ContainsFunc(w.xorPassword, func(r rune) bool { return r >= 0x80 }) {
+		return fmt.Errorf("xls: XOR password %q is not 1 to 15 ASCII characters", w.xorPassword)
+	}
+	data := make([]byte, 6)
+	binary.LittleEndian.PutUint16(data[2:4], x.key)
+	binary.LittleEndian.PutUint16(data[4:6], x.verifier)
+	return w.writeRecord(writer, recTypeFILEPASS, data)
+}
+
+// password returns the password the files of the Writer open with.
+func (w *Writer) password() string {
+	if w.xorPassword != "" {
+		return w.xorPassword
+	}
+	return defaultPassword
+}
+
+// xorPadding fills the XOR array beyond the password.
+var xorPadding = [15]byte{0xBB, 0xFF, 0xFF, 0xBA, 0xFF, 0xFF, 0xB9, 0x80, 0x00, 0xBE, 0x0F, 0x00, 0xBF, 0x0F, 0x00}
+
+// xorObfuscation is the XOR obfuscation of a password: the key and the
+// verifier stored in the FILEPASS record, and the 16 bytes the record bodies
+// are combined with.
+type xorObfuscation struct {
+	key      uint16
+	verifier uint16
+	array    [16]byte
+}
+
+// newXORObfuscation returns the XOR obfuscation of password, or false if
+// password is not 1 to 15 characters of one byte.
+func newXORObfuscation(password string) (*xorObfuscation, bool) {
+	var chars []byte
+	for _, r := range password {
+		if r > 0xFF {
+			return nil, false
+		}
+		chars = append(chars, byte(r))
+	}
+	if len(chars) == 0 || len(chars) > 15 {
+		return nil, false
+	}
+
+	// The key is a CRC of the 7-bit characters, last first
+	x := &xorObfuscation{verifier: passwordHash(string(chars))}
+	base, end := uint16(0x8000), uint16(0xFFFF)
+	for i := len(chars) - 1; i >= 0; i-- {
+		c := chars[i] & 0x7F
+		for range 8 {
+			if base = bits.RotateLeft16(base, 1); base&1 != 0 {
+				base ^= 0x1020
+			}
+			if c&1 != 0 {
+				x.key ^= base
+			}
+			c >>= 1
+			if end = bits.RotateLeft16(end, 1); end&1 != 0 {
+				end ^= 0x1020
+			}
+		}
+	}
+	x.key ^= end
+
+	copy(x.array[:], chars)
+	copy(x.array[len(chars):], xorPadding[:])
+	for i := range x.array {
+		x.array[i] = bits.RotateLeft8(x.array[i]^byte(x.key>>(8*(i&1))), 2)
+	}
+	return x, true
+}
+
+// encrypt obfuscates b, the part of the body of a record of recSize bytes at
+// position pos of the stream, in place. It is a recordCrypter.
+func (x *xorObfuscation) encrypt(b []byte, pos, recSize int) {
+	for i := range b {
+		b[i] = bits.RotateLeft8(b[i], 5) ^ x.array[(pos+recSize+i)%16]
+	}
+}
+
+// decrypt reverses encrypt. It is a recordCrypter.
+func (x *xorObfuscation) decrypt(b []byte, pos, recSize int) {
+	for i := range b {
+		b[i] = bits.RotateLeft8(b[i]^x.array[(pos+recSize+i)%16], 3)
+	}
+}
@@ -0,0 +1,357 @@
+package xls
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cryptData returns two sheets of data long enough for many RC4 blocks.
+func cryptData() (first, second [][]interface{}) {
+	for i := range 300 {
+		first = append(first, []interface{}{fmt.Sprintf("name %d", i), float64(i) * 1.5, i%2 == 0})
+	}
+	second = [][]interface{}{{"second", 2}}
+	return first, second
+}
+
+// encryptedFile returns a two-sheet workbook of cryptData with filePass
+// inserted after the BOF of the globals and the stream encrypted with keys.
+func encryptedFile(t *testing.T, filePass []byte, keys func(block uint32) []byte) []byte {
+	t.Helper()
+	first, second := cryptData()
+	w := New()
+	w.Write(first)
+	s, _ := w.AddSheet("Second")
+	s.Write(second)
+	stream, err := w.WorkbookStream()
+	if err != nil {
+		t.Fatalf("WorkbookStream() failed: %v", err)
+	}
+
+	bofSize := 4 + int(binary.LittleEndian.Uint16(stream[2:]))
+	record := binary.LittleEndian.AppendUint16(binary.LittleEndian.AppendUint16(nil, recTypeFILEPASS), uint16(len(filePass)))
+	record = append(record, filePass...)
+	stream = append(stream[:bofSize:bofSize], append(record, stream[bofSize:]...)...)
+	r := &recordReader{data: stream}
+	for {
+		recType, data, _, err := r.next()
+		if err != nil {
+			break
+		}
+		if recType == recTypeBOUNDSHEET {
+			binary.LittleEndian.PutUint32(data, binary.LittleEndian.Uint32(data)+uint32(len(record)))
+		}
+	}
+	if keys != nil {
+		if err := cryptRecords(stream, (&rc4Stream{key: keys}).crypt); err != nil {
+			t.Fatalf("cryptRecords() failed: %v", err)
+		}
+	}
+
+	var file bytes.Buffer
+	if err := WriteCFB(&file, stream); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+	return file.Bytes()
+}
+
+// rc4FilePass returns the body of an Office 97 RC4 FILEPASS record and the
+// keys it describes.
+func rc4FilePass(password string) ([]byte, func(uint32) []byte) {
+	salt, verifier := bytes.Repeat([]byte{0x5A}, 16), []byte("0123456789abcdef")
+	keys := rc4Keys(password, salt)
+	hash := md5.Sum(verifier)
+	enc := append(bytes.Clone(verifier), hash[:]...)
+	c, _ := rc4.NewCipher(keys(0))
+	c.XORKeyStream(enc, enc)
+
+	data := []byte{1, 0, 1, 0, 1, 0}
+	data = append(data, salt...)
+	return append(data, enc...), keys
+}
+
+// cryptoAPIFilePass returns the body of a CryptoAPI RC4 FILEPASS record
+// with keys of keyBits, 0 for the 40-bit default, and the keys it describes.
+func cryptoAPIFilePass(password string, keyBits int) ([]byte, func(uint32) []byte) {
+	salt, verifier := bytes.Repeat([]byte{0xA5}, 16), []byte("fedcba9876543210")
+	keys := cryptoAPIKeys(password, salt, max(keyBits, 40))
+	hash := sha1.Sum(verifier)
+	enc := append(bytes.Clone(verifier), hash[:]...)
+	c, _ := rc4.NewCipher(keys(0))
+	c.XORKeyStream(enc, enc)
+
+	le := binary.LittleEndian
+	data := []byte{1, 0, 4, 0, 2, 0}
+	data = le.AppendUint32(data, 0x04)
+	data = le.AppendUint32(data, 34)
+	for _, v := range []uint32{0x04, 0, 0x6801, 0x8004, uint32(keyBits), 1, 0, 0} {
+		data = le.AppendUint32(data, v)
+	}
+	data = append(data, 0, 0) // Empty CSP name
+	data = le.AppendUint32(data, 16)
+	data = append(data, salt...)
+	data = append(data, enc[:16]...)
+	data = le.AppendUint32(data, 20)
+	return append(data, enc[16:]...), keys
+}
+
+func TestOpenFileWithPassword(t *testing.T) {
+	first, second := cryptData()
+	rc4Data, rc4Keys := rc4FilePass("secret")
+	api128, api128Keys := cryptoAPIFilePass("secret", 128)
+	api40, api40Keys := cryptoAPIFilePass("secret", 0)
+	files := map[string][]byte{
+		"RC4":           encryptedFile(t, rc4Data, rc4Keys),
+		"CryptoAPI 128": encryptedFile(t, api128, api128Keys),
+		"CryptoAPI 40":  encryptedFile(t, api40, api40Keys),
+	}
+	dir := t.TempDir()
+	for name, file := range files {
+		path := filepath.Join(dir, name+".xls")
+		if err := os.WriteFile(path, file, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		wb, err := OpenFileWithPassword(path, "secret")
+		if err != nil {
+			t.Fatalf("%s: OpenFileWithPassword() failed: %v", name, err)
+		}
+		sheets := wb.Sheets()
+		if len(sheets) != 2 || sheets[1].Name != "Second" {
+			t.Fatalf("%s: expected two sheets, got %d", name, len(sheets))
+		}
+		rows := sheets[0].Rows()
+		if len(rows) != len(first) {
+			t.Fatalf("%s: expected %d rows, got %d", name, len(first), len(rows))
+		}
+		for i, row := range rows {
+			if row[0].Value != first[i][0] || row[1].Value != first[i][1] || row[2].Value != first[i][2] {
+				t.Fatalf("%s: row %d: expected %v, got %v", name, i, first[i], row)
+			}
+		}
+		if got := sheets[1].Cell(0, 1).Value; got != float64(2) {
+			t.Errorf("%s: expected %v in the second sheet, got %v", name, second[0][1], got)
+		}
+
+		for _, open := range []func() (*Workbook, error){
+			func() (*Workbook, error) { return OpenFileWithPassword(path, "Secret") },
+			func() (*Workbook, error) { return OpenFile(path) },
+		} {
+			if _, err := open(); !errors.Is(err, ErrWrongPassword) {
+				t.Errorf("%s: expected ErrWrongPassword, got %v", name, err)
+			}
+		}
+	}
+}
+
+// The keys and encrypted verifiers were computed from [MS-OFFCRYPTO]
+// 2.3.6.2 and 2.3.5.2 by a separate implementation, not with rc4Keys,
+// cryptoAPIKeys and crypto/rc4. Its RC4 gives the published answer for the
+// key "Key" and plaintext "Plaintext", BBF316E8D940AF0AD3. The salt is the
+// bytes 0x00 to 0x0F, the verifier 0x10 to 0x1F, the password "password".
+func TestRC4KnownAnswers(t *testing.T) {
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	le := binary.LittleEndian
+	cryptoAPI := func(keyBits uint32, verifier string) []byte {
+		enc, _ := hex.DecodeString(verifier)
+		data := []byte{1, 0, 2, 0, 2, 0}
+		data = le.AppendUint32(data, 0x04)
+		data = le.AppendUint32(data, 34)
+		for _, v := range []uint32{0x04, 0, 0x6801, 0x8004, keyBits, 1, 0, 0} {
+			data = le.AppendUint32(data, v)
+		}
+		data = append(data, 0, 0)
+		data = le.AppendUint32(data, 16)
+		data = append(data, salt...)
+		data = append(data, enc[:16]...)
+		data = le.AppendUint32(data, 20)
+		return append(data, enc[16:]...)
+	}
+	office97, _ := hex.DecodeString("010001000100" + "000102030405060708090a0b0c0d0e0f" +
+		"0467c1fcedec5020bd3253fa0f8a18ff0cc6e5eaa1001489dfd93c5e29024295")
+
+	tests := []struct {
+		name     string
+		filePass []byte
+		keys     func(block uint32) []byte
+		key0     string
+	}{
+		{"RC4", office97, rc4Keys("password", salt), "d17084ef80dfaade760e9be2fe9b8d19"},
+		{"CryptoAPI 40", cryptoAPI(40, "e05d9fc926cb77ebb1008350c850b341961108c7e587d830a7feb753a3440c341b4dda12"),
+			cryptoAPIKeys("password", salt, 40), "c25141865f0000000000000000000000"},
+		{"CryptoAPI 128", cryptoAPI(128, "c1ecc68427ba1587c8093c435b6a903df736420c5dd715fa1763eab17ae37281c6ac57ab"),
+			cryptoAPIKeys("password", salt, 128), "c25141865f8d11622c27586270440db4"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(tt.keys(0)); got != tt.key0 {
+			t.Errorf("%s: expected the key of block 0 to be %s, got %s", tt.name, tt.key0, got)
+		}
+		if _, err := filePassDecrypter(tt.filePass, "password"); err != nil {
+			t.Errorf("%s: expected the verifier to match, got %v", tt.name, err)
+		}
+		if _, err := filePassDecrypter(tt.filePass, "Password"); !errors.Is(err, ErrWrongPassword) {
+			t.Errorf("%s: expected ErrWrongPassword, got %v", tt.name, err)
+		}
+	}
+
+	// Bytes 1020 to 1027 of the stream take the last four bytes of the key
+	// stream of block 0 and the first four of block 1
+	if got := hex.EncodeToString(rc4Keys("password", salt)(1)); got != "0d3ec553cde81b8881f25b9ef8b1bb61" {
+		t.Errorf("Expected the key of block 1 to be 0d3ec553cde81b8881f25b9ef8b1bb61, got %s", got)
+	}
+	b, _ := hex.DecodeString("ffb6ac7e72275190")
+	(&rc4Stream{key: rc4Keys("password", salt)}).crypt(b, 1020, len(b))
+	if string(b) != "ABCDEFGH" {
+		t.Errorf("Expected %q across the block boundary, got %q", "ABCDEFGH", b)
+	}
+}
+
+func TestOpenFileDefaultPassword(t *testing.T) {
+	filePass, keys := rc4FilePass(defaultPassword)
+	wb, err := openWorkbook(encryptedFile(t, filePass, keys))
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	if got := wb.Sheets()[0].Cell(299, 0).Value; got != "name 299" {
+		t.Errorf("Expected the last name, got %v", got)
+	}
+}
+
+func TestOpenFileUnsupportedEncryption(t *testing.T) {
+	for name, filePass := range map[string][]byte{
+		"type 2":   {2, 0, 0x34, 0x12, 0x78, 0x56},
+		"RC4 v3.3": {1, 0, 3, 0, 3, 0},
+	} {
+		_, err := openWorkbookWithPassword(encryptedFile(t, filePass, nil), "secret", DefaultLimits())
+		if !errors.Is(err, ErrUnsupportedEncryption) {
+			t.Errorf("%s: expected ErrUnsupportedEncryption, got %v", name, err)
+		}
+	}
+
+	if _, err := openWorkbookWithPassword(encryptedFile(t, []byte{1, 0, 1, 0, 1, 0}, nil), "secret", DefaultLimits()); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat for a short FILEPASS record, got %v", err)
+	}
+}
+
+func TestOpenFileWithPasswordPlain(t *testing.T) {
+	var buf bytes.Buffer
+	w := New()
+	w.Write([][]interface{}{{"plain"}})
+	w.WriteTo(&buf)
+	wb, err := openWorkbookWithPassword(buf.Bytes(), "secret", DefaultLimits())
+	if err != nil || wb.Sheets()[0].Cell(0, 0).Value != "plain" {
+		t.Errorf("Expected an unencrypted file read as is, got %v", err)
+	}
+}
+
+func TestWithLegacyXORPassword(t *testing.T) {
+	first, _ := cryptData()
+	w := New(WithLegacyXORPassword("hunter2"), WithPostWriteVerification())
+	w.Write(first)
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), stringToUTF16LE("name 299")) || bytes.Contains(buf.Bytes(), []byte("name 299")) {
+		t.Error("Expected the strings obfuscated")
+	}
+
+	path := filepath.Join(t.TempDir(), "xor.xls")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wb, err := OpenFileWithPassword(path, "hunter2")
+	if err != nil {
+		t.Fatalf("OpenFileWithPassword() failed: %v", err)
+	}
+	for i, row := range wb.Sheets()[0].Rows() {
+		if row[0].Value != first[i][0] || row[1].Value != first[i][1] || row[2].Value != first[i][2] {
+			t.Fatalf("Row %d: expected %v, got %v", i, first[i], row)
+		}
+	}
+	for _, password := range []string{"hunter3", "Hunter2", ""} {
+		if _, err := OpenFileWithPassword(path, password); !errors.Is(err, ErrWrongPassword) {
+			t.Errorf("Password %q: expected ErrWrongPassword, got %v", password, err)
+		}
+	}
+	if _, err := OpenFile(path); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("OpenFile(): expected ErrWrongPassword, got %v", err)
+	}
+}
+
+func TestWithLegacyXORPasswordInvalid(t *testing.T) {
+	for _, password := range []string{"sixteen chars!!!", "pässword"} {
+		w := New(WithLegacyXORPassword(password))
+		w.Write([][]interface{}{{1}})
+		if _, err := w.WriteTo(&bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "not 1 to 15 ASCII characters") {
+			t.Errorf("Password %q: expected an error, got %v", password, err)
+		}
+	}
+}
+
+func TestXORObfuscation(t *testing.T) {
+	x, ok := newXORObfuscation("password")
+	if !ok || x.verifier != passwordHash("password") {
+		t.Fatalf("Expected the verifier of sheet protection, got %#x", x.verifier)
+	}
+	data := []byte("The quick brown fox jumps over the lazy dog")
+	got := bytes.Clone(data)
+	x.encrypt(got, 100, len(data))
+	if bytes.Equal(got, data) {
+		t.Fatal("Expected encrypt to change the data")
+	}
+	x.decrypt(got, 100, len(data))
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected decrypt to reverse encrypt, got %q", got)
+	}
+}
+
+// The keys and arrays were computed with the InitialCode and XorMatrix
+// tables of [MS-OFFCRYPTO] 2.3.7.2 rather than the shift register of
+// newXORObfuscation, and the array rotated by 2 bits as Excel does. The
+// verifiers of "test" and "password" are the published hashes of sheet
+// protection.
+func TestXORObfuscationKnownAnswers(t *testing.T) {
+	tests := []struct {
+		password string
+		key      uint16
+		verifier uint16
+		array    string
+	}{
+		{"abc", 0x514A, 0xCC1A, "accca4abd6bac3bad6a32b45d37929bb"},
+		{"test", 0x1FC6, 0xCBEB, "cae9d6adf583e496e483fd7e1b86277c"},
+		{"password", 0x147A, 0x83AF, "28d5249d34ed20c107af16ba16af0f52"},
+		{"VelvetSweatshop", 0xB359, 0x9A0A, "3c5bd417f01f2813f04bb403c473a420"},
+	}
+	for _, tt := range tests {
+		x, ok := newXORObfuscation(tt.password)
+		if !ok {
+			t.Fatalf("%q: expected a valid password", tt.password)
+		}
+		if x.key != tt.key || x.verifier != tt.verifier {
+			t.Errorf("%q: expected key %#04x and verifier %#04x, got %#04x and %#04x", tt.password, tt.key, tt.verifier, x.key, x.verifier)
+		}
+		if got := hex.EncodeToString(x.array[:]); got != tt.array {
+			t.Errorf("%q: expected array %s, got %s", tt.password, tt.array, got)
+		}
+	}
+
+	// Each byte is rotated left by 5 bits, then combined with the array at
+	// the stream position plus the record size
+	x, _ := newXORObfuscation("password")
+	got := []byte{1, 2, 3, 4, 5, 6}
+	x.encrypt(got, 100, 6)
+	if hex.EncodeToString(got) != "36fa762faf92" {
+		t.Errorf("Expected encrypted bytes 36fa762faf92, got %x", got)
+	}
+}
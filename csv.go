@@ -0,0 +1,124 @@
+package xls
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVOption is a functional option for configuring ConvertToCSV.
+type CSVOption func(*csvConfig)
+
+type csvConfig struct {
+	delimiter rune
+	rfc3339   bool
+	location  *time.Location
+}
+
+// WithDelimiter sets the field delimiter. The default is a comma.
+func WithDelimiter(delimiter rune) CSVOption {
+	return func(c *csvConfig) {
+		c.delimiter = delimiter
+	}
+}
+
+// WithRFC3339Dates renders date cells as RFC 3339 timestamps instead of
+// applying their number format.
+func WithRFC3339Dates() CSVOption {
+	return func(c *csvConfig) {
+		c.rfc3339 = true
+	}
+}
+
+// WithLocation sets the time zone that date cells are interpreted in when
+// rendered as RFC 3339 timestamps. The default is UTC.
+func WithLocation(loc *time.Location) CSVOption {
+	return func(c *csvConfig) {
+		c.location = loc
+	}
+}
+
+// ConvertToCSV reads the named sheet of the XLS file at xlsPath and writes it
+// to w as CSV. An empty sheet name selects the first sheet.
+//
+// Numbers are written in their shortest exact form, dates through their number
+// format (or as RFC 3339 with WithRFC3339Dates), booleans as TRUE or FALSE,
+// errors as their #NAME text and formulas as their cached result. Empty rows
+// are kept; rows are not padded to a common width.
+func ConvertToCSV(xlsPath, sheet string, w io.Writer, opts ...CSVOption) error {
+	cfg := &csvConfig{delimiter: ',', location: time.UTC}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	wb, err := OpenFile(xlsPath)
+	if err != nil {
+		return err
+	}
+	if sheet == "" {
+		sheets := wb.Sheets()
+		if len(sheets) == 0 {
+			return fmt.Errorf("%w: workbook has no worksheets", ErrSheetNotFound)
+		}
+		sheet = sheets[0].Name
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = cfg.delimiter
+
+	next := 0
+	var record []string
+	err = wb.ForEachRow(sheet, func(rowIndex int, cells []Cell) error {
+		for ; next < rowIndex; next++ {
+			if err := cw.Write(nil); err != nil {
+				return err
+			}
+		}
+		next = rowIndex + 1
+
+		record = record[:0]
+		for _, cell := range cells {
+			record = append(record, cfg.cellText(cell))
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// cellText renders a cell value as CSV field text.
+func (c *csvConfig) cellText(cell Cell) string {
+	if cell.IsDate() {
+		if t, err := cell.Time(c.location); err == nil {
+			if c.rfc3339 {
+				return t.Format(time.RFC3339)
+			}
+			return formatDate(t, cell.Value.(float64), cell.FormatString)
+		}
+	}
+
+	switch v := cell.Value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case CellError:
+		return v.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,77 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func writeCSVFixture(t *testing.T, filename string) {
+	t.Helper()
+
+	data := [][]interface{}{
+		{"Name", "Amount", "Paid", "Date"},
+		{"Alice, Inc.", 1234.5, true, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)},
+		{`Say "hi"`, 100, false, time.Date(2024, time.March, 5, 13, 4, 5, 0, time.UTC)},
+		{},
+		{"Total", FormulaCell{Expr: "SUM(B2:B3)", Cached: 1334.5}, CellErrorNA},
+	}
+	if err := WriteToFile(filename, data, WithSheetName("Data")); err != nil {
+		t.Fatalf("WriteToFile() failed: %v", err)
+	}
+}
+
+func TestConvertToCSV(t *testing.T) {
+	tmpFile := "test_csv.xls"
+	defer os.Remove(tmpFile)
+	writeCSVFixture(t, tmpFile)
+
+	tests := []struct {
+		name  string
+		sheet string
+		opts  []CSVOption
+		want  string
+	}{
+		{
+			name: "default",
+			want: "Name,Amount,Paid,Date\n" +
+				"\"Alice, Inc.\",1234.5,TRUE,2024-03-05\n" +
+				"\"Say \"\"hi\"\"\",100,FALSE,2024-03-05 13:04:05\n" +
+				"\n" +
+				"Total,1334.5,#N/A\n",
+		},
+		{
+			name:  "tab delimited RFC 3339",
+			sheet: "Data",
+			opts:  []CSVOption{WithDelimiter('\t'), WithRFC3339Dates()},
+			want: "Name\tAmount\tPaid\tDate\n" +
+				"Alice, Inc.\t1234.5\tTRUE\t2024-03-05T00:00:00Z\n" +
+				"\"Say \"\"hi\"\"\"\t100\tFALSE\t2024-03-05T13:04:05Z\n" +
+				"\n" +
+				"Total\t1334.5\t#N/A\n",
+		},
+	}
+
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		if err := ConvertToCSV(tmpFile, tt.sheet, buf, tt.opts...); err != nil {
+			t.Fatalf("%s: ConvertToCSV() failed: %v", tt.name, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestConvertToCSVSheetNotFound(t *testing.T) {
+	tmpFile := "test_csv_missing.xls"
+	defer os.Remove(tmpFile)
+	writeCSVFixture(t, tmpFile)
+
+	err := ConvertToCSV(tmpFile, "Missing", new(bytes.Buffer))
+	if !errors.Is(err, ErrSheetNotFound) {
+		t.Errorf("Expected ErrSheetNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDate is returned when a value cannot be converted to or from an
+// Excel date serial number.
+var ErrInvalidDate = errors.New("xls: invalid date")
+
+var (
+	// Serial 0 in the 1900 date system; serial 1 is 1900-01-01.
+	epoch1900 = time.Date(1899, time.December, 31, 0, 0, 0, 0, time.UTC)
+	// Serial 0 in the 1904 date system.
+	epoch1904 = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// First date after the non-existent 1900-02-29 (serial 61).
+	march1900 = time.Date(1900, time.March, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// timeToSerial converts the wall clock of t into an Excel date serial number.
+//
+// In the 1900 date system Excel treats 1900 as a leap year, so serials from
+// 61 onwards (1900-03-01 and later) are one day ahead of the real day count.
+func timeToSerial(t time.Time, date1904 bool) (float64, error) {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+
+	epoch := epoch1900
+	if date1904 {
+		epoch = epoch1904
+	}
+	if wall.Before(epoch) {
+		return 0, fmt.Errorf("%w: %s is before %s", ErrInvalidDate, t.Format("2006-01-02"), epoch.Format("2006-01-02"))
+	}
+
+	serial := float64(wall.Sub(epoch)) / float64(24*time.Hour)
+	if !date1904 && !wall.Before(march1900) {
+		serial++
+	}
+	return serial, nil
+}
+
+// serialToTime converts an Excel date serial number into a time with the same
+// wall clock in loc. Fractions of a day are rounded to the nearest millisecond.
+func serialToTime(serial float64, date1904 bool, loc *time.Location) (time.Time, error) {
+	if math.IsNaN(serial) || math.IsInf(serial, 0) || serial < 0 || serial > 2958465 {
+		return time.Time{}, fmt.Errorf("%w: serial %v out of range", ErrInvalidDate, serial)
+	}
+
+	epoch := epoch1900
+	if date1904 {
+		epoch = epoch1904
+	} else if serial >= 61 {
+		serial--
+	} else if serial >= 60 {
+		return time.Time{}, fmt.Errorf("%w: serial 60 is 1900-02-29, which does not exist", ErrInvalidDate)
+	}
+
+	ms := math.Round(serial * 24 * 60 * 60 * 1000)
+	wall := epoch.Add(time.Duration(ms) * time.Millisecond)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc), nil
+}
+
+// isBuiltinDateFormat reports whether a built-in format index is a date or time format.
+func isBuiltinDateFormat(index uint16) bool {
+	return index >= 14 && index <= 22 || index >= 45 && index <= 47
+}
+
+// isDateFormat reports whether a number format displays a date or time. It
+// looks for date/time tokens outside quoted text, escapes and bracketed
+// sections in the first format section.
+func isDateFormat(format string) bool {
+	if strings.EqualFold(format, "General") {
+		return false
+	}
+
+	for i := 0; i < len(format); i++ {
+		switch c := format[i]; c {
+		case ';':
+			return false
+		case '"':
+			for i++; i < len(format) && format[i] != '"'; i++ {
+			}
+		case '\\', '_', '*':
+			i++ // Escaped literal, padding or fill character
+		case '[':
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				return false
+			}
+			// Elapsed time such as [h] or [mm]; colors and conditions are skipped
+			section := strings.ToLower(format[i+1 : i+end])
+			if section != "" && strings.Trim(section, "hms") == "" {
+				return true
+			}
+			i += end
+		case 'y', 'Y', 'm', 'M', 'd', 'D', 'h', 'H', 's', 'S':
+			return true
+		}
+	}
+	return false
+}
+
+// IsDate reports whether the cell holds a number displayed as a date or time,
+// judging by its number format.
+func (c Cell) IsDate() bool {
+	if _, ok := c.Value.(float64); !ok {
+		return false
+	}
+	if c.Kind != KindNumber && c.Kind != KindFormula {
+		return false
+	}
+	return isBuiltinDateFormat(c.formatIndex) || isDateFormat(c.FormatString)
+}
+
+// Time converts the cell's numeric value into a time.Time using the
+// workbook's date system. The wall clock stored in the file is interpreted in
+// loc (UTC if nil).
+func (c Cell) Time(loc *time.Location) (time.Time, error) {
+	v, ok := c.Value.(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: cell is %s, not a number", ErrInvalidDate, c.Kind)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return serialToTime(v, c.date1904, loc)
+}
+
+var (
+	monthNames = [...]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	dayNames   = [...]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+// formatDate renders t, whose serial number is serial, with the first section
+// of an Excel date/time number format. Characters that are not date/time
+// tokens are copied unchanged.
+func formatDate(t time.Time, serial float64, format string) string {
+	var b strings.Builder
+
+	upper := strings.ToUpper(format)
+	twelveHour := strings.Contains(upper, "AM/PM") || strings.Contains(upper, "A/P")
+	afterHour := false // A following m means minutes
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch c {
+		case ';':
+			return b.String()
+		case '"':
+			for i++; i < len(format) && format[i] != '"'; i++ {
+				b.WriteByte(format[i])
+			}
+			continue
+		case '\\':
+			if i+1 < len(format) {
+				i++
+				b.WriteByte(format[i])
+			}
+			continue
+		case '_':
+			i++ // Padding the width of the next character
+			b.WriteByte(' ')
+			continue
+		case '*':
+			i++ // Fill character
+			continue
+		case '[':
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				b.WriteString(format[i:])
+				return b.String()
+			}
+			switch section := strings.ToLower(format[i+1 : i+end]); {
+			case section != "" && strings.Trim(section, "h") == "":
+				fmt.Fprintf(&b, "%0*d", len(section), int64(serial*24))
+				afterHour = true
+			case section != "" && strings.Trim(section, "m") == "":
+				fmt.Fprintf(&b, "%0*d", len(section), int64(serial*24*60))
+			case section != "" && strings.Trim(section, "s") == "":
+				fmt.Fprintf(&b, "%0*d", len(section), int64(serial*24*60*60))
+			}
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(upper[i:], "AM/PM") {
+			if t.Hour() < 12 {
+				b.WriteString("AM")
+			} else {
+				b.WriteString("PM")
+			}
+			i += 4
+			continue
+		}
+		if strings.HasPrefix(upper[i:], "A/P") {
+			if t.Hour() < 12 {
+				b.WriteByte('A')
+			} else {
+				b.WriteByte('P')
+			}
+			i += 2
+			continue
+		}
+
+		lower := c | 0x20
+		if !strings.ContainsRune("ymdhs", rune(lower)) {
+			b.WriteByte(c)
+			continue
+		}
+		n := 1
+		for i+n < len(format) && format[i+n]|0x20 == lower {
+			n++
+		}
+		i += n - 1
+
+		switch lower {
+		case 'y':
+			if n <= 2 {
+				fmt.Fprintf(&b, "%02d", t.Year()%100)
+			} else {
+				fmt.Fprintf(&b, "%04d", t.Year())
+			}
+			afterHour = false
+		case 'm':
+			if n <= 2 && (afterHour || secondsFollow(format[i+1:])) {
+				fmt.Fprintf(&b, "%0*d", n, t.Minute())
+			} else {
+				switch n {
+				case 1, 2:
+					fmt.Fprintf(&b, "%0*d", n, int(t.Month()))
+				case 3:
+					b.WriteString(monthNames[t.Month()-1][:3])
+				case 5:
+					b.WriteString(monthNames[t.Month()-1][:1])
+				default:
+					b.WriteString(monthNames[t.Month()-1])
+				}
+			}
+			afterHour = false
+		case 'd':
+			switch n {
+			case 1, 2:
+				fmt.Fprintf(&b, "%0*d", n, t.Day())
+			case 3:
+				b.WriteString(dayNames[t.Weekday()][:3])
+			default:
+				b.WriteString(dayNames[t.Weekday()])
+			}
+			afterHour = false
+		case 'h':
+			hour := t.Hour()
+			if twelveHour {
+				if hour = hour % 12; hour == 0 {
+					hour = 12
+				}
+			}
+			fmt.Fprintf(&b, "%0*d", min(n, 2), hour)
+			afterHour = true
+		case 's':
+			fmt.Fprintf(&b, "%0*d", min(n, 2), t.Second())
+			// Fractions of a second such as ss.00
+			if i+2 < len(format) && format[i+1] == '.' && format[i+2] == '0' {
+				digits := 0
+				for i+2+digits < len(format) && format[i+2+digits] == '0' && digits < 3 {
+					digits++
+				}
+				frac := t.Nanosecond() / int(time.Millisecond)
+				for d := 3; d > digits; d-- {
+					frac /= 10
+				}
+				fmt.Fprintf(&b, ".%0*d", digits, frac)
+				i += 1 + digits
+			}
+			afterHour = false
+		}
+	}
+	return b.String()
+}
+
+// secondsFollow reports whether the next date/time token in format is s,
+// which makes a preceding m mean minutes.
+func secondsFollow(format string) bool {
+	for i := 0; i < len(format); i++ {
+		switch format[i] | 0x20 {
+		case 's':
+			return true
+		case 'y', 'm', 'd', 'h':
+			return false
+		}
+	}
+	return false
+}
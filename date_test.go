@@ -0,0 +1,189 @@
+package xls
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeToSerial(t *testing.T) {
+	tests := []struct {
+		t        time.Time
+		date1904 bool
+		want     float64
+	}{
+		{time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), false, 1},
+		{time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC), false, 59},
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), false, 61},
+		{time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), false, 45444},
+		{time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), false, 45444.5},
+		{time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC), true, 0},
+		{time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), true, 43982},
+	}
+
+	for _, tt := range tests {
+		got, err := timeToSerial(tt.t, tt.date1904)
+		if err != nil {
+			t.Errorf("timeToSerial(%v, %v) failed: %v", tt.t, tt.date1904, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("timeToSerial(%v, %v) = %v, expected %v", tt.t, tt.date1904, got, tt.want)
+		}
+	}
+
+	if _, err := timeToSerial(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC), false); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("Expected ErrInvalidDate for date before 1900, got %v", err)
+	}
+}
+
+func TestSerialToTime(t *testing.T) {
+	tests := []struct {
+		serial   float64
+		date1904 bool
+		want     time.Time
+	}{
+		{1, false, time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{59, false, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{61, false, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{45444.75, false, time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)},
+		{0.5, false, time.Date(1899, 12, 31, 12, 0, 0, 0, time.UTC)},
+		{43982, true, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := serialToTime(tt.serial, tt.date1904, time.UTC)
+		if err != nil {
+			t.Errorf("serialToTime(%v, %v) failed: %v", tt.serial, tt.date1904, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("serialToTime(%v, %v) = %v, expected %v", tt.serial, tt.date1904, got, tt.want)
+		}
+	}
+
+	for _, serial := range []float64{60, 60.5, -1, 3e6} {
+		if _, err := serialToTime(serial, false, time.UTC); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("Expected ErrInvalidDate for serial %v, got %v", serial, err)
+		}
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   bool
+	}{
+		{"General", false},
+		{"0.00", false},
+		{"#,##0", false},
+		{"0.00E+00", false},
+		{"@", false},
+		{"yyyy-mm-dd", true},
+		{"m/d/yy", true},
+		{"h:mm:ss AM/PM", true},
+		{"[h]:mm:ss", true},
+		{"[Red]0.00", false},
+		{"[$-409]d-mmm-yy", true},
+		{`0.00" days"`, false},
+		{`\d0`, false},
+		{"_(* #,##0_)", false},
+		{"0;[Red]yyyy", false},
+	}
+
+	for _, tt := range tests {
+		if got := isDateFormat(tt.format); got != tt.want {
+			t.Errorf("isDateFormat(%q) = %v, expected %v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDateRoundTrip(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	times := []time.Time{
+		time.Date(2024, 6, 1, 0, 0, 0, 0, tokyo),
+		time.Date(2024, 6, 1, 23, 59, 59, 0, tokyo),
+		time.Date(1900, 2, 28, 6, 30, 0, 0, tokyo),
+		time.Date(1999, 12, 31, 12, 0, 0, 0, tokyo),
+	}
+
+	w := New()
+	defer w.Close()
+
+	data := [][]interface{}{{"Date"}}
+	for _, tm := range times {
+		data = append(data, []interface{}{tm})
+	}
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	sheet := writeTestWorkbook(t, w).Sheets()[0]
+
+	if sheet.Cell(0, 0).IsDate() {
+		t.Error("Text cell reported as date")
+	}
+	for i, want := range times {
+		cell := sheet.Cell(i+1, 0)
+		if !cell.IsDate() {
+			t.Errorf("Row %d: expected date cell, format '%s'", i+1, cell.FormatString)
+		}
+		got, err := cell.Time(tokyo)
+		if err != nil {
+			t.Errorf("Row %d: Time() failed: %v", i+1, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("Row %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+
+	if f := sheet.Cell(1, 0).FormatString; f != "yyyy-mm-dd" {
+		t.Errorf("Expected date format for midnight value, got '%s'", f)
+	}
+	if f := sheet.Cell(2, 0).FormatString; f != "yyyy-mm-dd hh:mm:ss" {
+		t.Errorf("Expected date-time format, got '%s'", f)
+	}
+}
+
+func TestCellTimeNotNumber(t *testing.T) {
+	cell := Cell{Kind: KindText, Value: "2024-06-01"}
+	if _, err := cell.Time(nil); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("Expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func TestCellIsDateBuiltinFormat(t *testing.T) {
+	cell := Cell{Kind: KindNumber, Value: 45444.0, formatIndex: 14}
+	if !cell.IsDate() {
+		t.Error("Expected built-in format 14 to be a date format")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tm := time.Date(2024, time.March, 5, 13, 4, 5, 250*int(time.Millisecond), time.UTC)
+	serial, err := timeToSerial(tm, false)
+	if err != nil {
+		t.Fatalf("timeToSerial() failed: %v", err)
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"m/d/yy", "3/5/24"},
+		{"yyyy-mm-dd hh:mm:ss", "2024-03-05 13:04:05"},
+		{"d-mmm-yy", "5-Mar-24"},
+		{"dddd, mmmm d", "Tuesday, March 5"},
+		{"h:mm AM/PM", "1:04 PM"},
+		{"mm:ss.0", "04:05.2"},
+		{`yyyy"年"m"月"d"日"`, "2024年3月5日"},
+		{"[$-409]yyyy/mm/dd;@", "2024/03/05"},
+		{"[h]:mm", "1088557:04"},
+	}
+	for _, tt := range tests {
+		if got := formatDate(tm, serial, tt.format); got != tt.want {
+			t.Errorf("formatDate(%q) = %q, expected %q", tt.format, got, tt.want)
+		}
+	}
+}
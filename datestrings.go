@@ -0,0 +1,124 @@
+package xls
+
+import "time"
+
+// isoDateLayouts are the layouts WithDateStringDetection tries by default:
+// ISO 8601 dates and times, which read the same in every locale. Fractional
+// seconds are accepted after the seconds of any of them.
+var isoDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// WithDateStringDetection makes Write and SetCell write string cells that
+// match one of the layouts, in the form of time.Parse, as dates, with the
+// built-in date or date-time format. Without layouts, ISO 8601 dates and
+// times are detected, such as "2024-06-01", "2024-06-01 12:30" and
+// "2024-06-01T12:30:00Z". Layouts that read differently by locale, such as
+// "01/02/2006", are only tried when given.
+//
+// The whole string must match, so "2024.06.01" or "2024-06-01 note" stay
+// text, as do dates before 1900, which a cell cannot hold. Timestamps with
+// a zone are written at the wall clock they show, as dates carry no zone.
+// The header rows of WithHeaderRows, the first row under WithSchema and the
+// columns the schema declares are left as they are: declare a column
+// ColumnText to keep its date strings as text.
+func WithDateStringDetection(layouts ...string) Option {
+	return func(w *Writer) {
+		if len(layouts) == 0 {
+			layouts = isoDateLayouts
+		}
+		w.dateLayouts = layouts
+	}
+}
+
+// detectDates returns data with its date strings converted to dates,
+// copying the rows that change.
+func (s *SheetWriter) detectDates(data [][]interface{}, owned bool) ([][]interface{}, bool) {
+	var header []interface{}
+	if len(data) > 0 {
+		header = data[0]
+	}
+	first, declared := s.dateScope(header)
+	out := data
+	for r := first; r < len(data); r++ {
+		row := data[r]
+		var detected []interface{}
+		for c, cell := range row {
+			if declared[c] {
+				continue
+			}
+			value, ok := s.w.detectDate(cell)
+			if !ok {
+				continue
+			}
+			if detected == nil {
+				detected = append([]interface{}(nil), row...)
+			}
+			detected[c] = value
+		}
+		if detected == nil {
+			continue
+		}
+		if !owned {
+			out, owned = append([][]interface{}(nil), data...), true
+		}
+		out[r] = detected
+	}
+	return out, owned
+}
+
+// detectsDates reports whether SetCell detects a date string at the cell,
+// under the header of the current data.
+func (s *SheetWriter) detectsDates(row, col int) bool {
+	var header []interface{}
+	if len(s.data) > 0 {
+		header = s.data[0]
+	}
+	first, declared := s.dateScope(header)
+	return row >= first && !declared[col]
+}
+
+// dateScope returns the first row date strings are detected in and the
+// columns the schema declares by their header, where they are not.
+func (s *SheetWriter) dateScope(header []interface{}) (int, map[int]bool) {
+	first := s.w.headerRows
+	if s.w.schema == nil {
+		return first, nil
+	}
+	declared := make(map[int]bool, len(s.w.schema))
+	for _, spec := range s.w.schema {
+		if col := headerIndex(header, spec.Name); col >= 0 {
+			declared[col] = true
+		}
+	}
+	return max(first, 1), declared
+}
+
+// detectDate returns a string cell that matches one of the date layouts as
+// a date, and false for the cells that are left as they are. Styled cells
+// keep their style.
+func (w *Writer) detectDate(cell interface{}) (interface{}, bool) {
+	switch v := cell.(type) {
+	case StyledCell:
+		value, ok := w.detectDate(v.Value)
+		v.Value = value
+		return v, ok
+	case string:
+		for _, layout := range w.dateLayouts {
+			t, err := time.Parse(layout, v)
+			if err != nil {
+				continue
+			}
+			if _, err := timeToSerial(t, false); err != nil {
+				break
+			}
+			return t, true
+		}
+	}
+	return cell, false
+}
@@ -0,0 +1,124 @@
+package xls
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		layouts []string
+		want    time.Time // Zero for strings left as text
+	}{
+		{"2024-06-01", nil, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-06-01 12:30", nil, time.Date(2024, time.June, 1, 12, 30, 0, 0, time.UTC)},
+		{"2024-06-01T12:30:45", nil, time.Date(2024, time.June, 1, 12, 30, 45, 0, time.UTC)},
+		{"2024-06-01 12:30:45.5", nil, time.Date(2024, time.June, 1, 12, 30, 45, 5e8, time.UTC)},
+		{"2024-06-01T12:30:00Z", nil, time.Date(2024, time.June, 1, 12, 30, 0, 0, time.UTC)},
+		{"2024-06-01T12:30:00+09:00", nil, time.Date(2024, time.June, 1, 12, 30, 0, 0, time.FixedZone("", 9*3600))},
+		{"2024-06-01T23:15:00.250-05:00", nil, time.Date(2024, time.June, 1, 23, 15, 0, 25e7, time.FixedZone("", -5*3600))},
+
+		// Near misses
+		{"2024.06.01", nil, time.Time{}},
+		{"2024-6-1", nil, time.Time{}},
+		{"20240601", nil, time.Time{}},
+		{"2024-13-01", nil, time.Time{}},
+		{"2024-02-30", nil, time.Time{}},
+		{"2024-06-01 note", nil, time.Time{}},
+		{" 2024-06-01", nil, time.Time{}},
+		{"v2024-06-01", nil, time.Time{}},
+		{"2024-06-01T12:30:00+0900", nil, time.Time{}},
+		{"1.2.3", nil, time.Time{}},
+		{"1899-12-30", nil, time.Time{}},
+
+		// Ambiguous layouts only when given
+		{"01/02/2024", nil, time.Time{}},
+		{"01/02/2024", []string{"01/02/2006"}, time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"01/02/2024", []string{"02/01/2006"}, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-06-01", []string{"02/01/2006"}, time.Time{}},
+		{"2024.06.01", []string{"2006.01.02"}, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		w := New(WithDateStringDetection(tt.layouts...))
+		got, ok := w.detectDate(tt.in)
+		if tt.want.IsZero() {
+			if ok || got != tt.in {
+				t.Errorf("%q with %q: expected text, got %v", tt.in, tt.layouts, got)
+			}
+			continue
+		}
+		if got, isTime := got.(time.Time); !ok || !isTime || !got.Equal(tt.want) || got.Format(time.RFC3339Nano) != tt.want.Format(time.RFC3339Nano) {
+			t.Errorf("%q with %q: expected %v, got %v", tt.in, tt.layouts, tt.want, got)
+		}
+	}
+}
+
+func TestWithDateStringDetection(t *testing.T) {
+	data := [][]interface{}{
+		{"Day", "At", "Version", "Code"},
+		{"2024-06-01", "2024-06-01T12:30:00+09:00", "2024.06.01", "2024-06-01"},
+		{Styled("2024-06-02", Style{Font: Font{Bold: true}}), "2024-06-01T12:30:00Z", "1.2.3", "2024-06-02"},
+	}
+	schema := ColumnSchema{{Name: "Code", Type: ColumnText}}
+	w := New(WithDateStringDetection(), WithSchema(schema))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	w.SetCell(3, 0, "2024-06-03")
+	w.SetCell(3, 3, "2024-06-03")
+	w.SetCell(0, 4, "2024-06-04")
+	sheet := readBack(t, w)
+
+	dates := []struct {
+		row, col int
+		want     time.Time
+	}{
+		{1, 0, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{1, 1, time.Date(2024, time.June, 1, 12, 30, 0, 0, time.UTC)},
+		{2, 0, time.Date(2024, time.June, 2, 0, 0, 0, 0, time.UTC)},
+		{2, 1, time.Date(2024, time.June, 1, 12, 30, 0, 0, time.UTC)},
+		{3, 0, time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, d := range dates {
+		cell := sheet.Cell(d.row, d.col)
+		got, err := cell.Time(time.UTC)
+		if !cell.IsDate() || err != nil || !got.Equal(d.want) {
+			t.Errorf("Cell (%d, %d): expected date %v, got %v", d.row, d.col, d.want, cell.Value)
+		}
+	}
+	if sheet.Cell(1, 0).xf != xfDate || sheet.Cell(1, 1).xf != xfDateTime {
+		t.Error("Expected a date format for a date and a date-time format for a timestamp")
+	}
+	if st := cellStyleOf(w, sheet.Cell(2, 0)); !st.Font.Bold || st.NumberFormat != "yyyy-mm-dd" {
+		t.Errorf("Expected the styled date to keep its style with a date format, got %+v", st)
+	}
+
+	texts := []struct {
+		row, col int
+		want     string
+	}{
+		{0, 0, "Day"},
+		{0, 4, "2024-06-04"},
+		{1, 2, "2024.06.01"},
+		{2, 2, "1.2.3"},
+		{1, 3, "2024-06-01"},
+		{3, 3, "2024-06-03"},
+	}
+	for _, c := range texts {
+		if got := sheet.Cell(c.row, c.col); got.Kind != KindText || got.Value != c.want {
+			t.Errorf("Cell (%d, %d): expected text %q, got %v", c.row, c.col, c.want, got.Value)
+		}
+	}
+	if data[1][0] != "2024-06-01" {
+		t.Error("Expected the data passed to Write to be left as it is")
+	}
+
+	// Header rows are left as they are
+	w = New(WithDateStringDetection(), WithHeaderRows(2))
+	w.Write([][]interface{}{{"2024-06-01"}, {"2024-06-02"}, {"2024-06-03"}})
+	sheet = readBack(t, w)
+	if sheet.Cell(1, 0).Kind != KindText || !sheet.Cell(2, 0).IsDate() {
+		t.Errorf("Expected dates below the header rows only, got %v and %v", sheet.Cell(1, 0).Value, sheet.Cell(2, 0).Value)
+	}
+}
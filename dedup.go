@@ -0,0 +1,99 @@
+package xls
+
+const (
+	// autoTuneSample is how many strings WithAutoTuning samples from the
+	// start of the data.
+	autoTuneSample = 10000
+
+	// autoTuneUnique is the share of unique strings of the sample from
+	// which WithAutoTuning turns string deduplication off.
+	autoTuneUnique = 0.9
+)
+
+// WithoutStringDeduplication writes every string cell as a string of its
+// own in the shared string table, in the order the cells are written,
+// instead of looking each string up to share one entry among the cells
+// that hold it. The file is still valid, as readers do not expect the
+// strings of the table to be unique, and is larger when strings repeat.
+// It saves the memory of the lookup for data whose strings rarely repeat,
+// such as URLs or UUIDs.
+func WithoutStringDeduplication() Option {
+	return func(w *Writer) {
+		w.noDedup = true
+	}
+}
+
+// WithAutoTuning lets the Writer choose settings from a sample of the data
+// on each write. It turns string deduplication off, as
+// WithoutStringDeduplication does, when at least 90% of the first 10,000
+// strings of the data are unique. The choice only depends on the data, so
+// the same data is always written the same way.
+func WithAutoTuning() Option {
+	return func(w *Writer) {
+		w.autoTune = true
+	}
+}
+
+// dedupStrings reports whether the shared string table of a write
+// deduplicates its strings.
+func (w *Writer) dedupStrings() bool {
+	if w.noDedup {
+		return false
+	}
+	if !w.autoTune {
+		return true
+	}
+
+	seen := make(map[string]struct{})
+	sampled := 0
+	for _, s := range w.sheets {
+		s.eachText(func(_, _ int, str string) {
+			if sampled < autoTuneSample {
+				seen[str] = struct{}{}
+				sampled++
+			}
+		})
+		if sampled == autoTuneSample {
+			break
+		}
+	}
+	return sampled == 0 || float64(len(seen)) < autoTuneUnique*float64(sampled)
+}
+
+// appendString is addString without deduplication: every occurrence of s
+// is a string of its own, at the next index. Once the table is filled, the
+// indices are handed out again, in the same order, from next.
+func (sst *sharedStringTable) appendString(s string) int {
+	if sst.filled {
+		index := sst.next
+		sst.next++
+		return index
+	}
+	index := sst.uniqueCount
+	sst.totalCount++
+	sst.uniqueCount++
+	if sst.spilled != nil {
+		sst.spilled.append(s)
+		return index
+	}
+	sst.strings = append(sst.strings, s)
+	if sst.uniqueCount == sst.spillAt {
+		// Without a temporary file the table stays in memory
+		if sst.spill() != nil {
+			sst.spillAt = 0
+		}
+	}
+	return index
+}
+
+// forSheet returns the table the worker writing sheet i of a filled table
+// adds its strings to: sst itself, or, without deduplication, a copy that
+// hands out the indices fill gave the strings of the sheet.
+func (sst *sharedStringTable) forSheet(i int) *sharedStringTable {
+	if !sst.noDedup {
+		return sst
+	}
+	c := *sst
+	c.next = sst.sheetStarts[i]
+	return &c
+}
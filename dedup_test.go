@@ -0,0 +1,133 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// sstCounts returns the total and unique string counts of the SST of file.
+func sstCounts(t *testing.T, file []byte) (total, unique int) {
+	t.Helper()
+	stream, err := workbookStream(file)
+	if err != nil {
+		t.Fatalf("workbookStream() failed: %v", err)
+	}
+	records, err := readAllRecords(stream)
+	if err != nil {
+		t.Fatalf("readAllRecords() failed: %v", err)
+	}
+	sst := recordsOfType(records, recTypeSST)
+	if len(sst) != 1 {
+		t.Fatalf("Expected one SST record, got %d", len(sst))
+	}
+	return int(binary.LittleEndian.Uint32(sst[0].Data[0:4])), int(binary.LittleEndian.Uint32(sst[0].Data[4:8]))
+}
+
+func TestWithoutStringDeduplication(t *testing.T) {
+	want := writeSpillWorkbook(t, WithoutStringDeduplication(), WithConcurrency(1), WithSSTSpillThreshold(-1))
+	total, unique := sstCounts(t, want)
+	if total != 3*2000*4 || unique != total {
+		t.Errorf("Expected %d strings, all unique, got %d of %d", 3*2000*4, unique, total)
+	}
+	if _, dedup := sstCounts(t, writeSpillWorkbook(t)); dedup >= unique {
+		t.Errorf("Expected fewer strings with deduplication, got %d", dedup)
+	}
+
+	wb, err := openWorkbook(want)
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	for i, data := range spillData() {
+		sheet := wb.Sheets()[i]
+		for r, row := range data {
+			for c, value := range row {
+				got := sheet.Cell(r, c).Value
+				if s, ok := value.(string); ok && got != s || !ok && got != float64(value.(int)) {
+					t.Fatalf("Sheet %d, cell (%d, %d): expected %v, got %v", i, r, c, value, got)
+				}
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"concurrent", []Option{WithConcurrency(3)}},
+		{"forced", []Option{WithLowMemorySST()}},
+		{"threshold", []Option{WithSSTSpillThreshold(1000)}},
+		{"concurrent-spilled", []Option{WithLowMemorySST(), WithConcurrency(3)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+			got := writeSpillWorkbook(t, append(tt.opts, WithoutStringDeduplication())...)
+			if !bytes.Equal(got, want) {
+				t.Error("Expected the same file as the sequential write in memory")
+			}
+		})
+	}
+
+	hybrid := writeSpillWorkbook(t, WithoutStringDeduplication(), WithHybridStrings(2))
+	if total, unique := sstCounts(t, hybrid); unique != total || total >= 3*2000*4 {
+		t.Errorf("Expected fewer unique strings with hybrid strings, got %d of %d", unique, total)
+	}
+}
+
+func TestWithAutoTuning(t *testing.T) {
+	write := func(data [][]interface{}, opts ...Option) (total, unique int) {
+		w := New(opts...)
+		if err := w.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		return sstCounts(t, buf.Bytes())
+	}
+
+	var unique, repeated [][]interface{}
+	for r := 0; r < autoTuneSample+100; r++ {
+		unique = append(unique, []interface{}{fmt.Sprintf("https://example.com/%d", r)})
+		repeated = append(repeated, []interface{}{fmt.Sprintf("value %d", r%5)})
+	}
+	// Every string of the first sample is unique, then one repeats
+	unique[autoTuneSample] = []interface{}{"https://example.com/0"}
+
+	if total, n := write(unique, WithAutoTuning()); n != total {
+		t.Errorf("Unique data: expected deduplication off, got %d unique of %d", n, total)
+	}
+	if total, n := write(unique); n != total-1 {
+		t.Errorf("Unique data without auto-tuning: expected %d unique, got %d", total-1, n)
+	}
+	if _, n := write(repeated, WithAutoTuning()); n != 5 {
+		t.Errorf("Repeated data: expected 5 unique strings, got %d", n)
+	}
+}
+
+func TestStringTableForSheet(t *testing.T) {
+	w := New(WithoutStringDeduplication())
+	w.Write([][]interface{}{{"a", "b"}, {"a"}})
+	s, _ := w.AddSheet("Sheet2")
+	s.Write([][]interface{}{{"c", "a"}})
+
+	sst := w.newStringTable()
+	sst.fill(w)
+	if sst.uniqueCount != 5 || sst.totalCount != 5 {
+		t.Fatalf("Expected 5 strings, all unique, got %d of %d", sst.uniqueCount, sst.totalCount)
+	}
+	// Each sheet hands out its own indices, whichever is written first
+	second, first := sst.forSheet(1), sst.forSheet(0)
+	for i, want := range []int{3, 4} {
+		if got := second.addString("x"); got != want {
+			t.Errorf("Sheet2, string %d: expected index %d, got %d", i, want, got)
+		}
+	}
+	for i, want := range []int{0, 1, 2} {
+		if got := first.addString("x"); got != want {
+			t.Errorf("Sheet1, string %d: expected index %d, got %d", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package xls
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// IgnoreSheets are the names of the sheets left out of the comparison.
+	IgnoreSheets []string
+	// IgnoreColumns are the zero-based columns left out of the comparison
+	// in every sheet.
+	IgnoreColumns []int
+	// Tolerance is the largest difference between two numbers, including
+	// the cached results of formulas, that still counts as equal.
+	Tolerance float64
+	// Formats also compares the number formats of cells with equal values.
+	Formats bool
+}
+
+// DiffKind is the kind of difference a CellDiff reports.
+type DiffKind int
+
+const (
+	// DiffValue reports cells of a different kind, value or formula.
+	DiffValue DiffKind = iota
+	// DiffFormat reports cells with equal values but different number
+	// formats. It is only reported with DiffOptions.Formats.
+	DiffFormat
+	// DiffSheetOnlyInA reports a sheet of the first workbook that the
+	// second does not have.
+	DiffSheetOnlyInA
+	// DiffSheetOnlyInB reports a sheet of the second workbook that the
+	// first does not have.
+	DiffSheetOnlyInB
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffValue:
+		return "value"
+	case DiffFormat:
+		return "format"
+	case DiffSheetOnlyInA:
+		return "sheet only in A"
+	case DiffSheetOnlyInB:
+		return "sheet only in B"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// CellDiff is a difference between two workbooks: a cell, with its value
+// in each, or a whole sheet found in only one of them, for which Row and
+// Col are -1 and A and B are blank.
+type CellDiff struct {
+	CellRef
+	Kind DiffKind
+	A, B Cell
+}
+
+func (d CellDiff) String() string {
+	switch d.Kind {
+	case DiffSheetOnlyInA, DiffSheetOnlyInB:
+		return fmt.Sprintf("sheet %q: %s", d.Sheet, d.Kind)
+	case DiffFormat:
+		return fmt.Sprintf("%s: format %q != %q", d.CellRef, d.A.FormatString, d.B.FormatString)
+	}
+	return fmt.Sprintf("%s: %s != %s", d.CellRef, diffText(d.A), diffText(d.B))
+}
+
+// diffText describes a cell of a CellDiff.
+func diffText(c Cell) string {
+	switch c.Kind {
+	case KindBlank:
+		return "(blank)"
+	case KindText:
+		return fmt.Sprintf("%q", c.Value)
+	case KindFormula:
+		return fmt.Sprintf("=%s (%v)", c.Formula, c.FormattedValue())
+	}
+	return c.FormattedValue()
+}
+
+// Diff compares two XLS files of aSize and bSize bytes cell by cell and
+// returns their differences: the sheets of a in order, then the sheets only
+// b has, and the cells of each sheet in row and column order. Sheets are
+// matched by name. Cells are compared by kind and value, formulas by their
+// expression and cached result, and with DiffOptions.Formats by number
+// format as well.
+func Diff(a, b io.ReaderAt, aSize, bSize int64, opts DiffOptions) ([]CellDiff, error) {
+	wbA, err := readWorkbookAt(a, aSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the first workbook: %w", err)
+	}
+	wbB, err := readWorkbookAt(b, bSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the second workbook: %w", err)
+	}
+
+	var diffs []CellDiff
+	for _, sa := range wbA.Sheets() {
+		if slices.Contains(opts.IgnoreSheets, sa.Name) {
+			continue
+		}
+		sb, err := wbB.Sheet(sa.Name)
+		if err != nil {
+			diffs = append(diffs, sheetDiff(sa.Name, DiffSheetOnlyInA))
+			continue
+		}
+		diffs = append(diffs, diffSheet(sa, sb, opts)...)
+	}
+	for _, sb := range wbB.Sheets() {
+		if slices.Contains(opts.IgnoreSheets, sb.Name) {
+			continue
+		}
+		if _, err := wbA.Sheet(sb.Name); err != nil {
+			diffs = append(diffs, sheetDiff(sb.Name, DiffSheetOnlyInB))
+		}
+	}
+	return diffs, nil
+}
+
+// readWorkbookAt opens the XLS file of size bytes read from r.
+func readWorkbookAt(r io.ReaderAt, size int64) (*Workbook, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	return openWorkbook(data)
+}
+
+func sheetDiff(name string, kind DiffKind) CellDiff {
+	return CellDiff{CellRef: CellRef{Sheet: name, Row: -1, Col: -1}, Kind: kind}
+}
+
+// diffSheet compares two sheets of the same name.
+func diffSheet(a, b *Sheet, opts DiffOptions) []CellDiff {
+	var diffs []CellDiff
+	rowsA, rowsB := a.Rows(), b.Rows()
+	for row := range max(len(rowsA), len(rowsB)) {
+		cols := 0
+		if row < len(rowsA) {
+			cols = len(rowsA[row])
+		}
+		if row < len(rowsB) {
+			cols = max(cols, len(rowsB[row]))
+		}
+		for col := range cols {
+			if slices.Contains(opts.IgnoreColumns, col) {
+				continue
+			}
+			ca, cb := a.Cell(row, col), b.Cell(row, col)
+			kind, ok := diffCells(ca, cb, opts)
+			if ok {
+				continue
+			}
+			diffs = append(diffs, CellDiff{CellRef: CellRef{Sheet: a.Name, Row: row, Col: col}, Kind: kind, A: ca, B: cb})
+		}
+	}
+	return diffs
+}
+
+// diffCells compares two cells, returning true if they are equal and else
+// the kind of their difference.
+func diffCells(a, b Cell, opts DiffOptions) (DiffKind, bool) {
+	if a.Kind != b.Kind || a.Formula != b.Formula || !diffValuesEqual(a.Value, b.Value, opts.Tolerance) {
+		return DiffValue, false
+	}
+	if opts.Formats && a.Kind != KindBlank && a.FormatString != b.FormatString {
+		return DiffFormat, false
+	}
+	return 0, true
+}
+
+// diffValuesEqual compares two cell values, numbers within tolerance.
+func diffValuesEqual(a, b interface{}, tolerance float64) bool {
+	fa, okA := a.(float64)
+	fb, okB := b.(float64)
+	if okA && okB {
+		return fa == fb || math.Abs(fa-fb) <= tolerance
+	}
+	return a == b
+}
@@ -0,0 +1,115 @@
+package xls
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// diffWorkbook writes sheets, each a name and its data, to a file.
+func diffWorkbook(t *testing.T, sheets ...interface{}) *bytes.Reader {
+	t.Helper()
+	w := New(WithSheetName(sheets[0].(string)))
+	for i := 0; i < len(sheets); i += 2 {
+		s := w.sheets[0]
+		if i > 0 {
+			var err error
+			if s, err = w.AddSheet(sheets[i].(string)); err != nil {
+				t.Fatalf("AddSheet() failed: %v", err)
+			}
+		}
+		if err := s.Write(sheets[i+1].([][]interface{})); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	var file bytes.Buffer
+	if _, err := w.WriteTo(&file); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	return bytes.NewReader(file.Bytes())
+}
+
+func diffStrings(diffs []CellDiff) string {
+	var lines []string
+	for _, d := range diffs {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiff(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	a := diffWorkbook(t,
+		"Data", [][]interface{}{
+			{"name", "total", "when", "id"},
+			{"a", 1.0, day, 1},
+			{"b", 2.0, 45413, 2},
+			{"c", FormulaCell{Expr: "B2+B3", Cached: 3.0}, true},
+		},
+		"Old", [][]interface{}{{"x"}},
+		"Skipped", [][]interface{}{{"x"}},
+	)
+	b := diffWorkbook(t,
+		"Data", [][]interface{}{
+			{"name", "total", "when", "id"},
+			{"a", 1.0000001, 45413, 10},
+			{"b", "2", day, 20},
+			{"c", FormulaCell{Expr: "B2*B3", Cached: 3.0}, true, "new"},
+		},
+		"Skipped", [][]interface{}{{"y"}},
+		"New", [][]interface{}{{"x"}},
+	)
+
+	for _, tc := range []struct {
+		name string
+		opts DiffOptions
+		want string
+	}{
+		{"values", DiffOptions{IgnoreSheets: []string{"Skipped"}}, `Data!B2: 1 != 1.0000001
+Data!D2: 1 != 10
+Data!B3: 2 != "2"
+Data!D3: 2 != 20
+Data!B4: =B2+B3 (3) != =B2*B3 (3)
+Data!D4: (blank) != "new"
+sheet "Old": sheet only in A
+sheet "New": sheet only in B`},
+		{"tolerance and columns", DiffOptions{IgnoreSheets: []string{"Skipped"}, IgnoreColumns: []int{3}, Tolerance: 1e-3}, `Data!B3: 2 != "2"
+Data!B4: =B2+B3 (3) != =B2*B3 (3)
+sheet "Old": sheet only in A
+sheet "New": sheet only in B`},
+		{"formats", DiffOptions{IgnoreColumns: []int{3}, Tolerance: 1e-3, Formats: true}, `Data!C2: format "yyyy-mm-dd" != "General"
+Data!B3: 2 != "2"
+Data!C3: format "General" != "yyyy-mm-dd"
+Data!B4: =B2+B3 (3) != =B2*B3 (3)
+sheet "Old": sheet only in A
+Skipped!A1: "x" != "y"
+sheet "New": sheet only in B`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diffs, err := Diff(a, b, a.Size(), b.Size(), tc.opts)
+			if err != nil {
+				t.Fatalf("Diff() failed: %v", err)
+			}
+			if got := diffStrings(diffs); got != tc.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+
+	diffs, err := Diff(a, a, a.Size(), a.Size(), DiffOptions{Formats: true})
+	if err != nil || len(diffs) != 0 {
+		t.Errorf("Expected no differences with itself, got %v, %v", diffs, err)
+	}
+}
+
+func TestDiffInvalidFile(t *testing.T) {
+	a := diffWorkbook(t, "Sheet1", [][]interface{}{{1}})
+	b := strings.NewReader("not an XLS file")
+	if _, err := Diff(a, b, a.Size(), b.Size(), DiffOptions{}); err == nil || !strings.Contains(err.Error(), "second workbook") {
+		t.Errorf("Expected an error for the second workbook, got %v", err)
+	}
+	if _, err := Diff(a, a, a.Size()/2, a.Size(), DiffOptions{}); err == nil || !strings.Contains(err.Error(), "first workbook") {
+		t.Errorf("Expected an error for a truncated first workbook, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package xls
+
+import "strings"
+
+// formulaTriggers are the leading characters that make a spreadsheet
+// application read text as a formula when the cell is edited, or when the
+// file is saved as CSV and opened again.
+const formulaTriggers = "=+-@\t\r"
+
+// WithFormulaEscaping makes Write and SetCell prefix text that starts with
+// =, +, -, @, a tab or a carriage return with a single quote, so that
+// untrusted strings such as "=HYPERLINK(...)" cannot turn into formulas
+// once the file is edited or exported to CSV. The quote is part of the
+// text, as Excel shows it. FormulaCell values, strings written as numbers
+// by WithAutoNumberConversion and values other than strings are not
+// changed. It is off by default.
+func WithFormulaEscaping() Option {
+	return WithFormulaEscapePrefix("'")
+}
+
+// WithFormulaEscapePrefix is WithFormulaEscaping with another prefix, such
+// as a space or a zero-width space. An empty prefix turns escaping off.
+func WithFormulaEscapePrefix(prefix string) Option {
+	return func(w *Writer) {
+		w.escapePrefix = prefix
+	}
+}
+
+// escapeFormulas returns data with the text that could be read as a
+// formula escaped, copying the rows that change. owned reports whether the
+// outer slice of data is already a copy.
+func (s *SheetWriter) escapeFormulas(data [][]interface{}, owned bool) ([][]interface{}, bool) {
+	out := data
+	for r, row := range data {
+		var escaped []interface{}
+		for c, cell := range row {
+			value, ok := s.w.escapeFormula(cell)
+			if !ok {
+				continue
+			}
+			if escaped == nil {
+				escaped = append([]interface{}(nil), row...)
+			}
+			escaped[c] = value
+		}
+		if escaped == nil {
+			continue
+		}
+		if !owned {
+			out, owned = append([][]interface{}(nil), data...), true
+		}
+		out[r] = escaped
+	}
+	return out, owned
+}
+
+// escapeFormula returns a string cell with the escape prefix added, and
+// false for the cells that are left as they are. Styled cells keep their
+// style.
+func (w *Writer) escapeFormula(cell interface{}) (interface{}, bool) {
+	if sc, ok := cell.(StyledCell); ok {
+		v, escaped := w.escapeFormula(sc.Value)
+		sc.Value = v
+		return sc, escaped
+	}
+	str, ok := cell.(string)
+	if !ok || str == "" || !strings.ContainsRune(formulaTriggers, rune(str[0])) {
+		return cell, false
+	}
+	if _, ok := w.numberText(str); ok {
+		return cell, false
+	}
+	return w.escapePrefix + str, true
+}
@@ -0,0 +1,60 @@
+package xls
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFormulaEscaping(t *testing.T) {
+	bold := Style{Font: Font{Bold: true}}
+	formula := FormulaCell{Expr: "SUM(A1:A2)"}
+	data := [][]interface{}{
+		{"=1+1", "+1", "-A1", "@SUM(A1)", "\tx", "\rx"},
+		{"plain", "a=b", "", -5, formula, Styled("=cmd", bold)},
+	}
+	w := New(WithFormulaEscaping())
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	want := [][]interface{}{
+		{"'=1+1", "'+1", "'-A1", "'@SUM(A1)", "'\tx", "'\rx"},
+		{"plain", "a=b", "", -5, formula, Styled("'=cmd", bold)},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if data[0][0] != "=1+1" {
+		t.Error("Expected the data passed to Write unchanged")
+	}
+
+	w.SetCell(2, 0, "=HYPERLINK(\"http://x\")")
+	if got := w.sheets[0].data[2][0]; got != "'=HYPERLINK(\"http://x\")" {
+		t.Errorf("Expected SetCell to escape the value, got %q", got)
+	}
+
+	if _, err := w.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+}
+
+func TestFormulaEscapePrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []interface{}
+	}{
+		{"off", nil, []interface{}{"=1", "-5", "-x"}},
+		{"prefix", []Option{WithFormulaEscapePrefix(" ")}, []interface{}{" =1", " -5", " -x"}},
+		{"numbers", []Option{WithFormulaEscaping(), WithAutoNumberConversion()}, []interface{}{"'=1", "-5", "'-x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(tt.opts...)
+			w.Write([][]interface{}{{"=1", "-5", "-x"}})
+			if got := w.sheets[0].data[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 
@@ -16,6 +17,9 @@ func main() {
 
 	// Example 3: Using Writer for more control
 	writerExample()
+
+	// Example 4: Multipart upload in chunks
+	chunksExample()
 }
 
 func simpleExample() {
@@ -79,3 +83,47 @@ func writerExample() {
 
 	fmt.Println("  Created: sales.xls")
 }
+
+// multipartUpload stands in for an object storage multipart upload, such as
+// S3's UploadPart and CompleteMultipartUpload calls.
+type multipartUpload struct {
+	parts map[int][]byte
+}
+
+func (u *multipartUpload) UploadPart(part int, data []byte) error {
+	u.parts[part] = bytes.Clone(data)
+	return nil
+}
+
+func (u *multipartUpload) Complete() []byte {
+	var object []byte
+	for part := 1; part <= len(u.parts); part++ {
+		object = append(object, u.parts[part]...)
+	}
+	return object
+}
+
+func chunksExample() {
+	fmt.Println("Example 4: Multipart upload in chunks")
+
+	data := [][]interface{}{{"ID", "Value"}}
+	for i := 1; i <= 1000; i++ {
+		data = append(data, []interface{}{i, fmt.Sprintf("item %d", i)})
+	}
+
+	writer := xls.New()
+	defer writer.Close()
+
+	if err := writer.Write(data); err != nil {
+		log.Fatalf("Failed to write data: %v", err)
+	}
+
+	// S3 parts must be at least 5 MiB except the last; a small size is used
+	// here to show several parts
+	upload := &multipartUpload{parts: make(map[int][]byte)}
+	if err := writer.WriteChunks(8*1024, upload.UploadPart); err != nil {
+		log.Fatalf("Failed to upload: %v", err)
+	}
+
+	fmt.Printf("  Uploaded %d bytes in %d parts\n", len(upload.Complete()), len(upload.parts))
+}
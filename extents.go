@@ -0,0 +1,122 @@
+package xls
+
+import "sort"
+
+// rowExtent is a row that gets a ROW record and the columns its cell
+// records cover. firstCol == lastCol for a row without cells.
+type rowExtent struct {
+	row               int
+	firstCol, lastCol int // lastCol is one past the last cell
+}
+
+// sheetExtents describes where a sheet has cell records. DIMENSIONS is the
+// bounding box of all cells and each ROW record the extent of its own
+// cells, both derived from this one computation so they cannot disagree.
+type sheetExtents struct {
+	rows []rowExtent // In ascending row order
+
+	// Bounding box of the cells, with the last row and column exclusive.
+	// All zero for a sheet without cells.
+	firstRow, lastRow int
+	firstCol, lastCol int
+}
+
+// sheetExtents computes the extents of the cell records writeRowsAndCells
+// writes: the rows with cells and styled rows, each spanning its first to
+// last written cell. Empty rows without a row style get no ROW record.
+// Styles must have been prepared.
+func (s *SheetWriter) sheetExtents() sheetExtents {
+	blanks := s.styledBlankColumns()
+
+	// Rows with data, followed by the rows beyond it that carry a row style
+	// or styled blank cells, of cell or range styles
+	n := s.rowCount()
+	rows := make([]int, 0, n)
+	for rowIndex := range n {
+		rows = append(rows, rowIndex)
+	}
+	var beyond []int
+	for _, rowIndex := range s.styledRows() {
+		if rowIndex >= n {
+			beyond = append(beyond, rowIndex)
+		}
+	}
+	for rowIndex := range blanks {
+		if _, styled := s.rowStyles[rowIndex]; rowIndex >= n && !styled {
+			beyond = append(beyond, rowIndex)
+		}
+	}
+	for _, rowIndex := range s.ranges.rowsFrom(n) {
+		_, styled := s.rowStyles[rowIndex]
+		if _, blank := blanks[rowIndex]; !styled && !blank {
+			beyond = append(beyond, rowIndex)
+		}
+	}
+	sort.Ints(beyond)
+	rows = append(rows, beyond...)
+
+	var ext sheetExtents
+	used := false
+	for _, rowIndex := range rows {
+		width := s.rowWidth(rowIndex)
+		if cols := blanks[rowIndex]; len(cols) > 0 {
+			width = max(width, cols[len(cols)-1]+1)
+		}
+		width = max(width, s.ranges.width(rowIndex))
+
+		re := rowExtent{row: rowIndex, firstCol: -1}
+		for col := 0; col < width; col++ {
+			if s.writesCellAt(rowIndex, col) {
+				if re.firstCol < 0 {
+					re.firstCol = col
+				}
+				re.lastCol = col + 1
+			}
+		}
+
+		if re.firstCol < 0 {
+			if _, styled := s.rowStyles[rowIndex]; !styled && s.rowLevels[rowIndex] == 0 {
+				continue
+			}
+			re.firstCol = 0
+		} else if !used {
+			used = true
+			ext.firstRow, ext.firstCol, ext.lastCol = rowIndex, re.firstCol, re.lastCol
+		}
+		if re.lastCol > re.firstCol {
+			ext.lastRow = rowIndex + 1
+			ext.firstCol = min(ext.firstCol, re.firstCol)
+			ext.lastCol = max(ext.lastCol, re.lastCol)
+		}
+		ext.rows = append(ext.rows, re)
+	}
+	return ext
+}
+
+// writesCellAt reports whether writeRowsAndCells writes a record for the
+// cell of the data at row and col.
+func (s *SheetWriter) writesCellAt(row, col int) bool {
+	if s.cols == nil {
+		return s.writesCell(row, col, s.cellAt(row, col))
+	}
+	if s.cols.present(row, col) {
+		return true
+	}
+	_, ok := s.blankXF(row, col, nil)
+	return ok
+}
+
+// writesCell reports whether writeRowsAndCells writes a record for the cell.
+func (s *SheetWriter) writesCell(row, col int, value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+	case StyledCell:
+		if v.Value != nil {
+			return true
+		}
+	default:
+		return true
+	}
+	_, ok := s.blankXF(row, col, value)
+	return ok
+}
@@ -0,0 +1,148 @@
+package xls
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// checkExtents parses the DIMENSIONS, ROW and cell records of the written
+// sheets and reports cells outside their ROW extent, and DIMENSIONS records
+// that are not the bounding box of their sheet's cells. It returns those of
+// the last sheet.
+func checkExtents(t *testing.T, records []Record) (dims [4]int, rows map[int][2]int) {
+	t.Helper()
+	for start := 0; start < len(records); {
+		end := start + 1
+		for end < len(records) && !(records[end].Type == recTypeBOF && binary.LittleEndian.Uint16(records[end].Data[2:4]) == bofWorksheet) {
+			end++
+		}
+		dims, rows = checkSheetExtents(t, records[start:end])
+		start = end
+	}
+	return dims, rows
+}
+
+// checkSheetExtents is checkExtents for the records of one sheet.
+func checkSheetExtents(t *testing.T, records []Record) (dims [4]int, rows map[int][2]int) {
+	t.Helper()
+	rows = make(map[int][2]int)
+	var cells [][2]int
+	for _, rec := range records {
+		d := rec.Data
+		switch rec.Type {
+		case recTypeDIMENSIONS:
+			dims = [4]int{int(binary.LittleEndian.Uint32(d[0:4])), int(binary.LittleEndian.Uint32(d[4:8])), int(binary.LittleEndian.Uint16(d[8:10])), int(binary.LittleEndian.Uint16(d[10:12]))}
+		case recTypeROW:
+			rows[int(binary.LittleEndian.Uint16(d[0:2]))] = [2]int{int(binary.LittleEndian.Uint16(d[2:4])), int(binary.LittleEndian.Uint16(d[4:6]))}
+		case recTypeLABELSST, recTypeLABEL, recTypeRSTRING, recTypeNUMBER, recTypeBOOLERR, recTypeFORMULA, recTypeBLANK:
+			cells = append(cells, [2]int{int(binary.LittleEndian.Uint16(d[0:2])), int(binary.LittleEndian.Uint16(d[2:4]))})
+		case recTypeMULBLANK:
+			row := int(binary.LittleEndian.Uint16(d[0:2]))
+			first := int(binary.LittleEndian.Uint16(d[2:4]))
+			last := int(binary.LittleEndian.Uint16(d[len(d)-2:]))
+			for col := first; col <= last; col++ {
+				cells = append(cells, [2]int{row, col})
+			}
+		}
+	}
+
+	var box [4]int
+	for i, c := range cells {
+		if i == 0 {
+			box = [4]int{c[0], c[0] + 1, c[1], c[1] + 1}
+		}
+		box = [4]int{min(box[0], c[0]), max(box[1], c[0]+1), min(box[2], c[1]), max(box[3], c[1]+1)}
+
+		extent, ok := rows[c[0]]
+		if !ok {
+			t.Errorf("Cell %v has no ROW record", c)
+		} else if c[1] < extent[0] || c[1] >= extent[1] {
+			t.Errorf("Cell %v is outside its ROW extent %v", c, extent)
+		}
+	}
+	if dims != box {
+		t.Errorf("Expected DIMENSIONS %v to be the bounding box of the cells %v", dims, box)
+	}
+	return dims, rows
+}
+
+func TestExtentsRagged(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.SetCellStyle(6, 4, Style{Fill: ColorYellow})
+	w.SetRowStyle(8, Style{Font: Font{Bold: true}})
+	w.Write([][]interface{}{
+		{"a"},
+		{"a", "b", "c", nil, nil},
+		{},
+		{nil, nil},
+		{1, nil, 3},
+		{},
+	})
+
+	dims, rows := checkExtents(t, writtenRecords(t, w))
+	if want := [4]int{0, 7, 0, 5}; dims != want {
+		t.Errorf("Expected DIMENSIONS %v, got %v", want, dims)
+	}
+	want := map[int]int{0: 1, 1: 3, 4: 3, 6: 5, 8: 0}
+	if len(rows) != len(want) {
+		t.Errorf("Expected ROW records for rows %v, got %v", want, rows)
+	}
+	for row, lastCol := range want {
+		if got, ok := rows[row]; !ok || got[1] != lastCol {
+			t.Errorf("Row %d: expected last column %d, got %v", row, lastCol, got)
+		}
+	}
+	if rows, cols := w.Dimensions("Sheet1"); rows != 7 || cols != 5 {
+		t.Errorf("Expected Dimensions() to match DIMENSIONS, got %dx%d", rows, cols)
+	}
+}
+
+func TestExtentsEmpty(t *testing.T) {
+	for name, data := range map[string][][]interface{}{
+		"no rows":    nil,
+		"empty rows": {{}, {nil, nil}, {}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := New()
+			defer w.Close()
+			w.Write(data)
+			dims, rows := checkExtents(t, writtenRecords(t, w))
+			if dims != [4]int{} || len(rows) != 0 {
+				t.Errorf("Expected empty DIMENSIONS and no ROW records, got %v and %v", dims, rows)
+			}
+		})
+	}
+}
+
+func TestExtentsGoldens(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.build()
+			defer w.Close()
+			checkExtents(t, writtenRecords(t, w))
+		})
+	}
+}
+
+func TestRowFirstColumn(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.SetCellStyle(2, 1, Style{Fill: ColorYellow})
+	w.Write([][]interface{}{
+		{nil, nil, "x"},
+		{"a", nil, "b"},
+		{nil, nil, nil, 4},
+	})
+
+	dims, rows := checkExtents(t, writtenRecords(t, w))
+	want := map[int][2]int{0: {2, 3}, 1: {0, 3}, 2: {1, 4}}
+	for row, extent := range want {
+		if got := rows[row]; got != extent {
+			t.Errorf("Row %d: expected columns %v, got %v", row, extent, got)
+		}
+	}
+	if wantDims := [4]int{0, 3, 0, 4}; dims != wantDims {
+		t.Errorf("Expected DIMENSIONS %v, got %v", wantDims, dims)
+	}
+}
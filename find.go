@@ -0,0 +1,216 @@
+package xls
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CellRef identifies a cell of the written data by sheet and zero-based row
+// and column.
+type CellRef struct {
+	Sheet    string
+	Row, Col int
+}
+
+// String returns the reference in A1 notation with its sheet, such as
+// Sheet1!B3 or 'Q1 Sales'!A1.
+func (r CellRef) String() string {
+	sheet := r.Sheet
+	for _, c := range sheet {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			sheet = "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+			break
+		}
+	}
+	return sheet + "!" + columnName(r.Col) + strconv.Itoa(r.Row+1)
+}
+
+// FindOptions configures how Find and Replace compare strings. Values other
+// than strings always match by value.
+type FindOptions struct {
+	CaseInsensitive bool
+	// Substring matches text cells containing the string instead of only
+	// those equal to it.
+	Substring bool
+}
+
+// Find returns the cells of all sheets whose value matches value, in sheet,
+// row and column order. See SheetWriter.Find.
+func (w *Writer) Find(value interface{}, opts ...FindOptions) []CellRef {
+	var refs []CellRef
+	for _, s := range w.sheets {
+		refs = append(refs, s.Find(value, opts...)...)
+	}
+	return refs
+}
+
+// Replace replaces the matching values in all sheets and returns the number
+// of cells changed. See SheetWriter.Replace.
+func (w *Writer) Replace(old, new interface{}, opts ...FindOptions) int {
+	n := 0
+	for _, s := range w.sheets {
+		n += s.Replace(old, new, opts...)
+	}
+	return n
+}
+
+// Find returns the cells of the sheet whose value matches value, in row and
+// column order. A string matches the cells written as text, as set by opts;
+// a number matches numeric cells of any type with the same value, so 3
+// matches both int(3) and 3.0; other values match cells equal to them.
+// Styles are ignored, and formulas match only as FormulaCell values.
+func (s *SheetWriter) Find(value interface{}, opts ...FindOptions) []CellRef {
+	m := newMatcher(value, opts)
+	var refs []CellRef
+	for row, cells := range s.rowsData() {
+		for col, cell := range cells {
+			if m.match(cell) {
+				refs = append(refs, CellRef{Sheet: s.name, Row: row, Col: col})
+			}
+		}
+	}
+	return refs
+}
+
+// Replace sets the cells matching old, as Find matches them, to new and
+// returns the number of cells changed. Styled cells keep their style.
+//
+// With the Substring option, a cell whose whole text matches becomes new, so
+// a "{{TOTAL}}" placeholder can be replaced by a number, while a longer text
+// has each occurrence replaced by the text of new. The slices passed to
+// Write are not modified.
+func (s *SheetWriter) Replace(old, new interface{}, opts ...FindOptions) int {
+	m := newMatcher(old, opts)
+	n := 0
+	s.materialize()
+	data, copied := s.data, false
+	for row, cells := range s.data {
+		var replaced []interface{}
+		for col, cell := range cells {
+			value, ok := m.replace(cell, new)
+			if !ok {
+				continue
+			}
+			if replaced == nil {
+				replaced = append([]interface{}(nil), cells...)
+			}
+			if sc, ok := cell.(StyledCell); ok {
+				value = StyledCell{Value: value, Style: sc.Style}
+			}
+			replaced[col] = value
+			n++
+		}
+		if replaced == nil {
+			continue
+		}
+		if !copied {
+			data, copied = append([][]interface{}(nil), s.data...), true
+		}
+		data[row] = replaced
+	}
+	s.data = data
+	return n
+}
+
+// matcher compares cell values against the value searched for.
+type matcher struct {
+	value    interface{}
+	text     string
+	isText   bool
+	number   float64
+	isNumber bool
+	opts     FindOptions
+}
+
+func newMatcher(value interface{}, opts []FindOptions) *matcher {
+	m := &matcher{value: value}
+	if len(opts) > 0 {
+		m.opts = opts[len(opts)-1]
+	}
+	m.text, m.isText = value.(string)
+	m.number, m.isNumber = toFloat64(value)
+	return m
+}
+
+func (m *matcher) match(cell interface{}) bool {
+	if sc, ok := cell.(StyledCell); ok {
+		cell = sc.Value
+	}
+	switch {
+	case m.isText:
+		text, ok := sstString(cell)
+		return ok && m.matchText(text)
+	case m.isNumber:
+		number, ok := toFloat64(cell)
+		return ok && number == m.number
+	}
+	return reflect.DeepEqual(cell, m.value)
+}
+
+func (m *matcher) matchText(text string) bool {
+	switch {
+	case m.opts.Substring && m.opts.CaseInsensitive:
+		_, n := replaceFold(text, m.text, "")
+		return n > 0
+	case m.opts.Substring:
+		return m.text != "" && strings.Contains(text, m.text)
+	case m.opts.CaseInsensitive:
+		return strings.EqualFold(text, m.text)
+	}
+	return text == m.text
+}
+
+// replace returns the value of cell with old replaced by new, or false if
+// cell does not match.
+func (m *matcher) replace(cell, new interface{}) (interface{}, bool) {
+	if !m.match(cell) {
+		return nil, false
+	}
+	if !m.isText || !m.opts.Substring {
+		return new, true
+	}
+	if sc, ok := cell.(StyledCell); ok {
+		cell = sc.Value
+	}
+	text, _ := sstString(cell)
+	if text == m.text || m.opts.CaseInsensitive && strings.EqualFold(text, m.text) {
+		return new, true
+	}
+	replacement, ok := new.(string)
+	if !ok {
+		replacement = fmt.Sprintf("%v", new)
+	}
+	if m.opts.CaseInsensitive {
+		text, _ = replaceFold(text, m.text, replacement)
+		return text, true
+	}
+	return strings.ReplaceAll(text, m.text, replacement), true
+}
+
+// replaceFold replaces the case-insensitive occurrences of old in s and
+// returns the result and their number. An empty old matches nothing.
+func replaceFold(s, old, new string) (string, int) {
+	if old == "" {
+		return s, 0
+	}
+	var b strings.Builder
+	n := 0
+	i := 0
+	for i+len(old) <= len(s) {
+		if strings.EqualFold(s[i:i+len(old)], old) {
+			b.WriteString(new)
+			i += len(old)
+			n++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+	}
+	b.WriteString(s[i:])
+	return b.String(), n
+}
@@ -0,0 +1,128 @@
+package xls
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.Write([][]interface{}{
+		{"Customer", "{{CUSTOMER}}"},
+		{"Qty", 3, 3.0, "3"},
+		{"Paid", true, Styled("customer", Style{Font: Font{Bold: true}})},
+	})
+	detail, _ := w.AddSheet("Q1 Detail")
+	detail.Write([][]interface{}{{"Dear {{customer}},", int64(3)}})
+
+	tests := []struct {
+		name  string
+		value interface{}
+		opts  []FindOptions
+		want  []CellRef
+	}{
+		{"exact", "Customer", nil, []CellRef{{"Sheet1", 0, 0}}},
+		{"case-insensitive", "customer", []FindOptions{{CaseInsensitive: true}}, []CellRef{{"Sheet1", 0, 0}, {"Sheet1", 2, 2}}},
+		{"substring", "{{CUSTOMER}}", []FindOptions{{Substring: true}}, []CellRef{{"Sheet1", 0, 1}}},
+		{"substring case-insensitive", "{{CUSTOMER}}", []FindOptions{{Substring: true, CaseInsensitive: true}}, []CellRef{{"Sheet1", 0, 1}, {"Q1 Detail", 0, 0}}},
+		{"number", 3, nil, []CellRef{{"Sheet1", 1, 1}, {"Sheet1", 1, 2}, {"Q1 Detail", 0, 1}}},
+		{"bool", true, nil, []CellRef{{"Sheet1", 2, 1}}},
+		{"no match", "missing", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Find(tt.value, tt.opts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	w := New()
+	defer w.Close()
+	bold := Style{Font: Font{Bold: true}}
+	data := [][]interface{}{
+		{"Invoice for {{CUSTOMER}}", nil},
+		{"Customer", Styled("{{CUSTOMER}}", bold)},
+		{"Total", "{{TOTAL}}"},
+		{"Rate", 0.5, 0.5},
+	}
+	w.Write(data)
+
+	if n := w.Replace("{{CUSTOMER}}", "Acme", FindOptions{Substring: true}); n != 2 {
+		t.Errorf("Expected 2 placeholders replaced, got %d", n)
+	}
+	if n := w.Replace("{{TOTAL}}", 120.5, FindOptions{Substring: true}); n != 1 {
+		t.Errorf("Expected 1 total replaced, got %d", n)
+	}
+	if n := w.Replace(0.5, 0.25); n != 2 {
+		t.Errorf("Expected 2 numbers replaced, got %d", n)
+	}
+	if n := w.Replace("{{CUSTOMER}}", "Acme"); n != 0 {
+		t.Errorf("Expected no placeholders left, got %d", n)
+	}
+
+	want := [][]interface{}{
+		{"Invoice for Acme", nil},
+		{"Customer", Styled("Acme", bold)},
+		{"Total", 120.5},
+		{"Rate", 0.25, 0.25},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if data[0][0] != "Invoice for {{CUSTOMER}}" || data[3][1] != 0.5 {
+		t.Errorf("Expected the written slices unchanged, got %v", data)
+	}
+
+	// The SST is built at save time, so it holds only the new strings
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Sheet1", want); err != nil {
+		t.Errorf("Replaced data does not read back: %v", err)
+	}
+	sst := recordsOfType(writtenRecords(t, w), recTypeSST)
+	if bytes.Contains(sst[0].Data, []byte("{{")) {
+		t.Error("Expected no placeholder left in the SST")
+	}
+}
+
+func TestReplaceFold(t *testing.T) {
+	tests := []struct {
+		s, old, new string
+		want        string
+		n           int
+	}{
+		{"Hello hello HELLO", "hello", "bye", "bye bye bye", 3},
+		{"Grüße GRÜSSE", "grüße", "x", "x GRÜSSE", 1},
+		{"abc", "", "x", "abc", 0},
+		{"ab", "abc", "x", "ab", 0},
+	}
+	for _, tt := range tests {
+		got, n := replaceFold(tt.s, tt.old, tt.new)
+		if got != tt.want || n != tt.n {
+			t.Errorf("replaceFold(%q, %q, %q) = %q, %d; expected %q, %d", tt.s, tt.old, tt.new, got, n, tt.want, tt.n)
+		}
+	}
+}
+
+func TestCellRefString(t *testing.T) {
+	tests := []struct {
+		ref  CellRef
+		want string
+	}{
+		{CellRef{"Sheet1", 2, 1}, "Sheet1!B3"},
+		{CellRef{"Q1 Sales", 0, 27}, "'Q1 Sales'!AB1"},
+		{CellRef{"Bob's", 9, 0}, "'Bob''s'!A10"},
+	}
+	for _, tt := range tests {
+		if got := tt.ref.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,949 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// FormulaCell is a cell value holding a formula.
+//
+// Expr is the formula text in A1 notation, with or without the leading "=".
+// Cached is the optional pre-computed result (a number, string, bool or
+// CellError) stored alongside the formula so that viewers which do not
+// recalculate still display the right value. When Cached is nil, the formula
+// is written with a zero result and flagged to be recalculated on load. The
+// flag does not mark the result as missing, so Excel files flag formulas
+// with results too, and the reader returns the zero result as the number 0.
+type FormulaCell struct {
+	Expr   string
+	Cached interface{}
+}
+
+// CellError is an Excel error value such as #DIV/0!.
+type CellError uint8
+
+// Excel error values
+const (
+	CellErrorNull  CellError = 0x00 // #NULL!
+	CellErrorDiv0  CellError = 0x07 // #DIV/0!
+	CellErrorValue CellError = 0x0F // #VALUE!
+	CellErrorRef   CellError = 0x17 // #REF!
+	CellErrorName  CellError = 0x1D // #NAME?
+	CellErrorNum   CellError = 0x24 // #NUM!
+	CellErrorNA    CellError = 0x2A // #N/A
+)
+
+var cellErrorNames = map[CellError]string{
+	CellErrorNull:  "#NULL!",
+	CellErrorDiv0:  "#DIV/0!",
+	CellErrorValue: "#VALUE!",
+	CellErrorRef:   "#REF!",
+	CellErrorName:  "#NAME?",
+	CellErrorNum:   "#NUM!",
+	CellErrorNA:    "#N/A",
+}
+
+// String returns the error text as displayed by Excel.
+func (e CellError) String() string {
+	if s, ok := cellErrorNames[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("#ERR%d", uint8(e))
+}
+
+// Formula tokens (ptg) in BIFF8 RPN form
+const (
+	ptgAdd      = 0x03
+	ptgSub      = 0x04
+	ptgMul      = 0x05
+	ptgDiv      = 0x06
+	ptgPower    = 0x07
+	ptgConcat   = 0x08
+	ptgLT       = 0x09
+	ptgLE       = 0x0A
+	ptgEQ       = 0x0B
+	ptgGE       = 0x0C
+	ptgGT       = 0x0D
+	ptgNE       = 0x0E
+	ptgUplus    = 0x12
+	ptgUminus   = 0x13
+	ptgPercent  = 0x14
+	ptgParen    = 0x15
+	ptgMissArg  = 0x16
+	ptgStr      = 0x17
+	ptgErr      = 0x1C
+	ptgBool     = 0x1D
+	ptgInt      = 0x1E
+	ptgNum      = 0x1F
+	ptgFuncV    = 0x41
+	ptgFuncVarV = 0x42
+	ptgRefR     = 0x24
+	ptgAreaR    = 0x25
+	ptgRefV     = 0x44
+	ptgAreaV    = 0x45
+)
+
+// formulaFunc describes a built-in worksheet function.
+type formulaFunc struct {
+	index   uint16
+	minArgs int
+	maxArgs int
+}
+
+// formulaFuncs maps function names to their BIFF8 function table entries.
+var formulaFuncs = map[string]formulaFunc{
+	"COUNT":       {0, 0, 30},
+	"IF":          {1, 2, 3},
+	"ISNA":        {2, 1, 1},
+	"ISERROR":     {3, 1, 1},
+	"SUM":         {4, 0, 30},
+	"AVERAGE":     {5, 1, 30},
+	"MIN":         {6, 1, 30},
+	"MAX":         {7, 1, 30},
+	"ROW":         {8, 0, 1},
+	"COLUMN":      {9, 0, 1},
+	"NA":          {10, 0, 0},
+	"STDEV":       {12, 1, 30},
+	"PI":          {19, 0, 0},
+	"SQRT":        {20, 1, 1},
+	"EXP":         {21, 1, 1},
+	"LN":          {22, 1, 1},
+	"LOG10":       {23, 1, 1},
+	"ABS":         {24, 1, 1},
+	"INT":         {25, 1, 1},
+	"SIGN":        {26, 1, 1},
+	"ROUND":       {27, 2, 2},
+	"INDEX":       {29, 2, 4},
+	"MID":         {31, 3, 3},
+	"LEN":         {32, 1, 1},
+	"VALUE":       {33, 1, 1},
+	"AND":         {36, 1, 30},
+	"OR":          {37, 1, 30},
+	"NOT":         {38, 1, 1},
+	"MOD":         {39, 2, 2},
+	"TEXT":        {48, 2, 2},
+	"DATE":        {65, 3, 3},
+	"TIME":        {66, 3, 3},
+	"DAY":         {67, 1, 1},
+	"MONTH":       {68, 1, 1},
+	"YEAR":        {69, 1, 1},
+	"WEEKDAY":     {70, 1, 2},
+	"HOUR":        {71, 1, 1},
+	"MINUTE":      {72, 1, 1},
+	"SECOND":      {73, 1, 1},
+	"NOW":         {74, 0, 0},
+	"CHOOSE":      {100, 2, 30},
+	"HLOOKUP":     {101, 3, 4},
+	"VLOOKUP":     {102, 3, 4},
+	"LOWER":       {112, 1, 1},
+	"UPPER":       {113, 1, 1},
+	"LEFT":        {115, 1, 2},
+	"RIGHT":       {116, 1, 2},
+	"EXACT":       {117, 2, 2},
+	"TRIM":        {118, 1, 1},
+	"SUBSTITUTE":  {120, 3, 4},
+	"FIND":        {124, 2, 3},
+	"ISTEXT":      {127, 1, 1},
+	"ISNUMBER":    {128, 1, 1},
+	"ISBLANK":     {129, 1, 1},
+	"COUNTA":      {169, 0, 30},
+	"PRODUCT":     {183, 0, 30},
+	"ROUNDUP":     {212, 2, 2},
+	"ROUNDDOWN":   {213, 2, 2},
+	"TODAY":       {221, 0, 0},
+	"MEDIAN":      {227, 1, 30},
+	"CONCATENATE": {336, 0, 30},
+	"POWER":       {337, 2, 2},
+	"SUBTOTAL":    {344, 2, 30},
+	"SUMIF":       {345, 2, 3},
+	"COUNTIF":     {346, 2, 2},
+	"COUNTBLANK":  {347, 1, 1},
+}
+
+var formulaErrorLiterals = map[string]CellError{
+	"#NULL!":  CellErrorNull,
+	"#DIV/0!": CellErrorDiv0,
+	"#VALUE!": CellErrorValue,
+	"#REF!":   CellErrorRef,
+	"#NAME?":  CellErrorName,
+	"#NUM!":   CellErrorNum,
+	"#N/A":    CellErrorNA,
+}
+
+// compileFormula compiles an A1-style formula into BIFF8 RPN tokens.
+func compileFormula(expr string) ([]byte, error) {
+	p := &formulaParser{src: strings.TrimPrefix(strings.TrimSpace(expr), "=")}
+	if strings.TrimSpace(p.src) == "" {
+		return nil, fmt.Errorf("empty formula")
+	}
+	if err := p.parseComparison(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.src[p.pos:], p.pos)
+	}
+	if len(p.out) > math.MaxUint16 {
+		return nil, fmt.Errorf("formula too long")
+	}
+	return p.out, nil
+}
+
+// formulaParser is a recursive descent parser emitting RPN tokens.
+type formulaParser struct {
+	src       string
+	pos       int
+	out       []byte
+	funcDepth int
+}
+
+func (p *formulaParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *formulaParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *formulaParser) parseComparison() error {
+	if err := p.parseConcat(); err != nil {
+		return err
+	}
+	for {
+		p.skipSpace()
+		var op byte
+		rest := p.src[p.pos:]
+		switch {
+		case strings.HasPrefix(rest, "<>"):
+			op, p.pos = ptgNE, p.pos+2
+		case strings.HasPrefix(rest, "<="):
+			op, p.pos = ptgLE, p.pos+2
+		case strings.HasPrefix(rest, ">="):
+			op, p.pos = ptgGE, p.pos+2
+		case strings.HasPrefix(rest, "<"):
+			op, p.pos = ptgLT, p.pos+1
+		case strings.HasPrefix(rest, ">"):
+			op, p.pos = ptgGT, p.pos+1
+		case strings.HasPrefix(rest, "="):
+			op, p.pos = ptgEQ, p.pos+1
+		default:
+			return nil
+		}
+		if err := p.parseConcat(); err != nil {
+			return err
+		}
+		p.out = append(p.out, op)
+	}
+}
+
+func (p *formulaParser) parseConcat() error {
+	if err := p.parseAdditive(); err != nil {
+		return err
+	}
+	for p.peek() == '&' {
+		p.pos++
+		if err := p.parseAdditive(); err != nil {
+			return err
+		}
+		p.out = append(p.out, ptgConcat)
+	}
+	return nil
+}
+
+func (p *formulaParser) parseAdditive() error {
+	if err := p.parseMultiplicative(); err != nil {
+		return err
+	}
+	for {
+		var op byte
+		switch p.peek() {
+		case '+':
+			op = ptgAdd
+		case '-':
+			op = ptgSub
+		default:
+			return nil
+		}
+		p.pos++
+		if err := p.parseMultiplicative(); err != nil {
+			return err
+		}
+		p.out = append(p.out, op)
+	}
+}
+
+func (p *formulaParser) parseMultiplicative() error {
+	if err := p.parsePower(); err != nil {
+		return err
+	}
+	for {
+		var op byte
+		switch p.peek() {
+		case '*':
+			op = ptgMul
+		case '/':
+			op = ptgDiv
+		default:
+			return nil
+		}
+		p.pos++
+		if err := p.parsePower(); err != nil {
+			return err
+		}
+		p.out = append(p.out, op)
+	}
+}
+
+func (p *formulaParser) parsePower() error {
+	if err := p.parsePercent(); err != nil {
+		return err
+	}
+	for p.peek() == '^' {
+		p.pos++
+		if err := p.parsePercent(); err != nil {
+			return err
+		}
+		p.out = append(p.out, ptgPower)
+	}
+	return nil
+}
+
+func (p *formulaParser) parsePercent() error {
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+	for p.peek() == '%' {
+		p.pos++
+		p.out = append(p.out, ptgPercent)
+	}
+	return nil
+}
+
+func (p *formulaParser) parseUnary() error {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+		p.out = append(p.out, ptgUminus)
+		return nil
+	case '+':
+		p.pos++
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+		p.out = append(p.out, ptgUplus)
+		return nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *formulaParser) parsePrimary() error {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return fmt.Errorf("unexpected end of formula")
+	case c == '(':
+		p.pos++
+		if err := p.parseComparison(); err != nil {
+			return err
+		}
+		if p.peek() != ')' {
+			return fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		p.pos++
+		p.out = append(p.out, ptgParen)
+		return nil
+	case c == '"':
+		return p.parseString()
+	case c == '#':
+		return p.parseErrorLiteral()
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c == '$' || isFormulaNameChar(c):
+		return p.parseName()
+	}
+	return fmt.Errorf("unexpected %q at position %d", c, p.pos)
+}
+
+func (p *formulaParser) parseString() error {
+	p.pos++ // opening quote
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return fmt.Errorf("unterminated string literal")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		if c == '"' {
+			if p.pos < len(p.src) && p.src[p.pos] == '"' {
+				sb.WriteByte('"')
+				p.pos++
+				continue
+			}
+			break
+		}
+		sb.WriteByte(c)
+	}
+
+	units := utf16.Encode([]rune(sb.String()))
+	if len(units) > 255 {
+		return fmt.Errorf("string literal longer than 255 characters")
+	}
+	p.out = append(p.out, ptgStr, byte(len(units)), 0x01)
+	for _, u := range units {
+		p.out = binary.LittleEndian.AppendUint16(p.out, u)
+	}
+	return nil
+}
+
+func (p *formulaParser) parseErrorLiteral() error {
+	rest := strings.ToUpper(p.src[p.pos:])
+	for text, code := range formulaErrorLiterals {
+		if strings.HasPrefix(rest, text) {
+			p.pos += len(text)
+			p.out = append(p.out, ptgErr, byte(code))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown error literal at position %d", p.pos)
+}
+
+func (p *formulaParser) parseNumber() error {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+	}
+
+	v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", p.src[start:p.pos])
+	}
+	if v == math.Trunc(v) && v >= 0 && v <= math.MaxUint16 {
+		p.out = append(p.out, ptgInt)
+		p.out = binary.LittleEndian.AppendUint16(p.out, uint16(v))
+		return nil
+	}
+	p.out = append(p.out, ptgNum)
+	p.out = binary.LittleEndian.AppendUint64(p.out, math.Float64bits(v))
+	return nil
+}
+
+func isFormulaNameChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+func (p *formulaParser) parseName() error {
+	start := p.pos
+	for p.pos < len(p.src) && (isFormulaNameChar(p.src[p.pos]) || p.src[p.pos] == '$') {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+
+	if p.peek() == '(' {
+		p.pos++
+		return p.parseFunction(strings.ToUpper(name))
+	}
+
+	if ref, ok := parseCellRef(name); ok {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ':' {
+			p.pos++
+			p.skipSpace()
+			start := p.pos
+			for p.pos < len(p.src) && (isFormulaNameChar(p.src[p.pos]) || p.src[p.pos] == '$') {
+				p.pos++
+			}
+			last, ok := parseCellRef(p.src[start:p.pos])
+			if !ok {
+				return fmt.Errorf("invalid range end %q", p.src[start:p.pos])
+			}
+			p.emitArea(ref, last)
+			return nil
+		}
+		p.emitRef(ref)
+		return nil
+	}
+
+	switch strings.ToUpper(name) {
+	case "TRUE":
+		p.out = append(p.out, ptgBool, 1)
+		return nil
+	case "FALSE":
+		p.out = append(p.out, ptgBool, 0)
+		return nil
+	}
+	return fmt.Errorf("unsupported name %q", name)
+}
+
+func (p *formulaParser) parseFunction(name string) error {
+	fn, ok := formulaFuncs[name]
+	if !ok {
+		return fmt.Errorf("unsupported function %s", name)
+	}
+
+	p.funcDepth++
+	defer func() { p.funcDepth-- }()
+
+	argc := 0
+	if p.peek() == ')' {
+		p.pos++
+	} else {
+		for {
+			if c := p.peek(); c == ',' || c == ')' {
+				p.out = append(p.out, ptgMissArg)
+			} else if err := p.parseComparison(); err != nil {
+				return err
+			}
+			argc++
+
+			c := p.peek()
+			p.pos++
+			if c == ')' {
+				break
+			}
+			if c != ',' {
+				return fmt.Errorf("expected ',' or ')' in %s arguments", name)
+			}
+		}
+	}
+
+	if argc < fn.minArgs || argc > fn.maxArgs {
+		return fmt.Errorf("%s takes %d to %d arguments, got %d", name, fn.minArgs, fn.maxArgs, argc)
+	}
+
+	if fn.minArgs == fn.maxArgs {
+		p.out = append(p.out, ptgFuncV)
+		p.out = binary.LittleEndian.AppendUint16(p.out, fn.index)
+	} else {
+		p.out = append(p.out, ptgFuncVarV, byte(argc))
+		p.out = binary.LittleEndian.AppendUint16(p.out, fn.index)
+	}
+	return nil
+}
+
+// refColumn encodes a column with its relative flags as used by ptgRef/ptgArea.
+func refColumn(ref cellRef) uint16 {
+	col := ref.col
+	if !ref.absRow {
+		col |= 0x8000
+	}
+	if !ref.absCol {
+		col |= 0x4000
+	}
+	return col
+}
+
+func (p *formulaParser) emitRef(ref cellRef) {
+	// Function arguments take references; elsewhere a value is expected.
+	ptg := byte(ptgRefV)
+	if p.funcDepth > 0 {
+		ptg = ptgRefR
+	}
+	p.out = append(p.out, ptg)
+	p.out = binary.LittleEndian.AppendUint16(p.out, ref.row)
+	p.out = binary.LittleEndian.AppendUint16(p.out, refColumn(ref))
+}
+
+func (p *formulaParser) emitArea(first, last cellRef) {
+	ptg := byte(ptgAreaV)
+	if p.funcDepth > 0 {
+		ptg = ptgAreaR
+	}
+	p.out = append(p.out, ptg)
+	p.out = binary.LittleEndian.AppendUint16(p.out, first.row)
+	p.out = binary.LittleEndian.AppendUint16(p.out, last.row)
+	p.out = binary.LittleEndian.AppendUint16(p.out, refColumn(first))
+	p.out = binary.LittleEndian.AppendUint16(p.out, refColumn(last))
+}
+
+// cellRef is a parsed A1-style reference component.
+type cellRef struct {
+	row    uint16
+	col    uint16
+	absRow bool
+	absCol bool
+}
+
+// parseCellRef parses an A1-style reference such as "B3" or "$B$3".
+func parseCellRef(s string) (cellRef, bool) {
+	var ref cellRef
+	i := 0
+	if i < len(s) && s[i] == '$' {
+		ref.absCol = true
+		i++
+	}
+	colStart := i
+	col := 0
+	for i < len(s) && (s[i] >= 'A' && s[i] <= 'Z' || s[i] >= 'a' && s[i] <= 'z') {
+		c := s[i]
+		if c >= 'a' {
+			c -= 'a' - 'A'
+		}
+		col = col*26 + int(c-'A'+1)
+		i++
+	}
+	if i == colStart || i-colStart > 2 || col > 256 {
+		return ref, false
+	}
+	if i < len(s) && s[i] == '$' {
+		ref.absRow = true
+		i++
+	}
+	rowStart := i
+	row := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		row = row*10 + int(s[i]-'0')
+		if row > 65536 {
+			return ref, false
+		}
+		i++
+	}
+	if i == rowStart || i != len(s) || row == 0 {
+		return ref, false
+	}
+
+	ref.row = uint16(row - 1)
+	ref.col = uint16(col - 1)
+	return ref, true
+}
+
+// formulaResult encodes a cached formula result into the 8-byte result field
+// of a FORMULA record. For string results, the returned string must be written
+// in a STRING record following the FORMULA record.
+func formulaResult(cached interface{}) (result [8]byte, str *string, err error) {
+	switch v := cached.(type) {
+	case string:
+		if v == "" {
+			result[0] = 0x03 // Empty string
+		} else {
+			result[0] = 0x00 // String follows in a STRING record
+			str = &v
+		}
+		result[6], result[7] = 0xFF, 0xFF
+	case bool:
+		result[0] = 0x01
+		if v {
+			result[2] = 1
+		}
+		result[6], result[7] = 0xFF, 0xFF
+	case CellError:
+		result[0] = 0x02
+		result[2] = byte(v)
+		result[6], result[7] = 0xFF, 0xFF
+	default:
+		f, ok := toFloat64(cached)
+		if !ok {
+			return result, nil, fmt.Errorf("unsupported cached value type %T", cached)
+		}
+		binary.LittleEndian.PutUint64(result[:], math.Float64bits(f))
+	}
+	return result, str, nil
+}
+
+// toFloat64 converts Go numeric types to float64.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// formulaFuncNames maps function table indices back to their names.
+var formulaFuncNames = func() map[uint16]string {
+	names := make(map[uint16]string, len(formulaFuncs))
+	for name, fn := range formulaFuncs {
+		names[fn.index] = name
+	}
+	return names
+}()
+
+var formulaOperators = map[byte]string{
+	ptgAdd:    "+",
+	ptgSub:    "-",
+	ptgMul:    "*",
+	ptgDiv:    "/",
+	ptgPower:  "^",
+	ptgConcat: "&",
+	ptgLT:     "<",
+	ptgLE:     "<=",
+	ptgEQ:     "=",
+	ptgGE:     ">=",
+	ptgGT:     ">",
+	ptgNE:     "<>",
+}
+
+// columnName returns the letters of a zero-based column index ("A", "AB").
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// formatRef renders a ptgRef style row/column pair in A1 notation.
+func formatRef(row, col uint16) string {
+	s := ""
+	if col&0x4000 == 0 {
+		s += "$"
+	}
+	s += columnName(int(col & 0x00FF))
+	if col&0x8000 == 0 {
+		s += "$"
+	}
+	return s + strconv.Itoa(int(row)+1)
+}
+
+// decompileFormula renders BIFF8 RPN tokens back into A1-style text. This is
+// best effort: tokens referring to other sheets, names or shared formulas are
+// reported as errors.
+func decompileFormula(rgce []byte) (string, error) {
+	var stack []string
+	pop := func() (string, error) {
+		if len(stack) == 0 {
+			return "", fmt.Errorf("formula token stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v, nil
+	}
+	need := func(pos, n int) error {
+		if pos+n > len(rgce) {
+			return fmt.Errorf("truncated formula token at position %d", pos-1)
+		}
+		return nil
+	}
+
+	for pos := 0; pos < len(rgce); {
+		ptg := rgce[pos]
+		pos++
+
+		if op, ok := formulaOperators[ptg]; ok {
+			b, err := pop()
+			if err != nil {
+				return "", err
+			}
+			a, err := pop()
+			if err != nil {
+				return "", err
+			}
+			stack = append(stack, a+op+b)
+			continue
+		}
+
+		switch ptg {
+		case ptgUplus, ptgUminus, ptgPercent, ptgParen:
+			a, err := pop()
+			if err != nil {
+				return "", err
+			}
+			switch ptg {
+			case ptgUplus:
+				a = "+" + a
+			case ptgUminus:
+				a = "-" + a
+			case ptgPercent:
+				a += "%"
+			case ptgParen:
+				a = "(" + a + ")"
+			}
+			stack = append(stack, a)
+		case ptgMissArg:
+			stack = append(stack, "")
+		case ptgStr:
+			if err := need(pos, 2); err != nil {
+				return "", err
+			}
+			cch, flags := int(rgce[pos]), rgce[pos+1]
+			pos += 2
+			var s string
+			if flags&0x01 != 0 {
+				if err := need(pos, cch*2); err != nil {
+					return "", err
+				}
+				units := make([]uint16, cch)
+				for i := range units {
+					units[i] = binary.LittleEndian.Uint16(rgce[pos+i*2:])
+				}
+				s = string(utf16.Decode(units))
+				pos += cch * 2
+			} else {
+				if err := need(pos, cch); err != nil {
+					return "", err
+				}
+				s = latin1String(rgce[pos : pos+cch])
+				pos += cch
+			}
+			stack = append(stack, `"`+strings.ReplaceAll(s, `"`, `""`)+`"`)
+		case 0x19: // ptgAttr
+			if err := need(pos, 3); err != nil {
+				return "", err
+			}
+			grbit := rgce[pos]
+			data := binary.LittleEndian.Uint16(rgce[pos+1:])
+			pos += 3
+			if grbit&0x04 != 0 { // tAttrChoose carries a jump table
+				if err := need(pos, (int(data)+1)*2); err != nil {
+					return "", err
+				}
+				pos += (int(data) + 1) * 2
+			}
+			if grbit&0x10 != 0 { // tAttrSum
+				a, err := pop()
+				if err != nil {
+					return "", err
+				}
+				stack = append(stack, "SUM("+a+")")
+			}
+		case ptgErr:
+			if err := need(pos, 1); err != nil {
+				return "", err
+			}
+			stack = append(stack, CellError(rgce[pos]).String())
+			pos++
+		case ptgBool:
+			if err := need(pos, 1); err != nil {
+				return "", err
+			}
+			if rgce[pos] != 0 {
+				stack = append(stack, "TRUE")
+			} else {
+				stack = append(stack, "FALSE")
+			}
+			pos++
+		case ptgInt:
+			if err := need(pos, 2); err != nil {
+				return "", err
+			}
+			stack = append(stack, strconv.Itoa(int(binary.LittleEndian.Uint16(rgce[pos:]))))
+			pos += 2
+		case ptgNum:
+			if err := need(pos, 8); err != nil {
+				return "", err
+			}
+			v := math.Float64frombits(binary.LittleEndian.Uint64(rgce[pos:]))
+			stack = append(stack, strconv.FormatFloat(v, 'G', -1, 64))
+			pos += 8
+		case 0x21, ptgFuncV, 0x61, 0x22, ptgFuncVarV, 0x62:
+			var argc int
+			var index uint16
+			fixed := ptg&0x1F == 0x01
+			if fixed {
+				if err := need(pos, 2); err != nil {
+					return "", err
+				}
+				index = binary.LittleEndian.Uint16(rgce[pos:])
+				pos += 2
+			} else {
+				if err := need(pos, 3); err != nil {
+					return "", err
+				}
+				argc = int(rgce[pos])
+				index = binary.LittleEndian.Uint16(rgce[pos+1:]) & 0x7FFF
+				pos += 3
+			}
+			name, ok := formulaFuncNames[index]
+			if !ok {
+				return "", fmt.Errorf("unsupported function index %d", index)
+			}
+			if fixed {
+				argc = formulaFuncs[name].minArgs
+			}
+			if argc > len(stack) {
+				return "", fmt.Errorf("formula token stack underflow")
+			}
+			args := strings.Join(stack[len(stack)-argc:], ",")
+			stack = append(stack[:len(stack)-argc], name+"("+args+")")
+		case ptgRefR, ptgRefV, 0x64:
+			if err := need(pos, 4); err != nil {
+				return "", err
+			}
+			row := binary.LittleEndian.Uint16(rgce[pos:])
+			col := binary.LittleEndian.Uint16(rgce[pos+2:])
+			stack = append(stack, formatRef(row, col))
+			pos += 4
+		case ptgAreaR, ptgAreaV, 0x65:
+			if err := need(pos, 8); err != nil {
+				return "", err
+			}
+			firstRow := binary.LittleEndian.Uint16(rgce[pos:])
+			lastRow := binary.LittleEndian.Uint16(rgce[pos+2:])
+			firstCol := binary.LittleEndian.Uint16(rgce[pos+4:])
+			lastCol := binary.LittleEndian.Uint16(rgce[pos+6:])
+			stack = append(stack, formatRef(firstRow, firstCol)+":"+formatRef(lastRow, lastCol))
+			pos += 8
+		case 0x2A, 0x4A, 0x6A: // ptgRefErr
+			if err := need(pos, 4); err != nil {
+				return "", err
+			}
+			stack = append(stack, CellErrorRef.String())
+			pos += 4
+		case 0x2B, 0x4B, 0x6B: // ptgAreaErr
+			if err := need(pos, 8); err != nil {
+				return "", err
+			}
+			stack = append(stack, CellErrorRef.String())
+			pos += 8
+		default:
+			return "", fmt.Errorf("unsupported formula token 0x%02X", ptg)
+		}
+	}
+
+	if len(stack) != 1 {
+		return "", fmt.Errorf("malformed formula token stream")
+	}
+	return stack[0], nil
+}
+
+// latin1String decodes 8-bit compressed BIFF characters.
+func latin1String(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
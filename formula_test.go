@@ -0,0 +1,202 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+type testRecord struct {
+	recType uint16
+	data    []byte
+}
+
+// parseTestRecords splits a BIFF8 stream into records.
+func parseTestRecords(t *testing.T, stream []byte) []testRecord {
+	t.Helper()
+
+	var records []testRecord
+	for pos := 0; pos < len(stream); {
+		if pos+4 > len(stream) {
+			t.Fatalf("Truncated record header at offset %d", pos)
+		}
+		recType := binary.LittleEndian.Uint16(stream[pos:])
+		size := int(binary.LittleEndian.Uint16(stream[pos+2:]))
+		if pos+4+size > len(stream) {
+			t.Fatalf("Truncated record 0x%04X at offset %d", recType, pos)
+		}
+		records = append(records, testRecord{recType: recType, data: stream[pos+4 : pos+4+size]})
+		pos += 4 + size
+	}
+	return records
+}
+
+func TestCompileFormula(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"SUM(A1:A3)", "25" + "0000" + "0200" + "00c0" + "00c0" + "42" + "01" + "0400"},
+		{"=A1+1", "44" + "0000" + "00c0" + "1e0100" + "03"},
+		{"$B$2*2", "44" + "0100" + "0100" + "1e0200" + "05"},
+		{"-2^2", "1e0200" + "13" + "1e0200" + "07"},
+		{"(1+2)*3", "1e0100" + "1e0200" + "03" + "15" + "1e0300" + "05"},
+		{`"a"&"b"`, "17" + "0101" + "6100" + "17" + "0101" + "6200" + "08"},
+		{"A1<>TRUE", "44" + "0000" + "00c0" + "1d01" + "0e"},
+		{"ROUND(A1,2)", "24" + "0000" + "00c0" + "1e0200" + "41" + "1b00"},
+		{"IF(A1,,#N/A)", "24" + "0000" + "00c0" + "16" + "1c2a" + "42" + "03" + "0100"},
+		{"50%", "1e3200" + "14"},
+	}
+
+	for _, tt := range tests {
+		got, err := compileFormula(tt.expr)
+		if err != nil {
+			t.Errorf("compileFormula(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("compileFormula(%q) = %x, expected %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileFormulaNumber(t *testing.T) {
+	got, err := compileFormula("2.5")
+	if err != nil {
+		t.Fatalf("compileFormula() failed: %v", err)
+	}
+	if len(got) != 9 || got[0] != ptgNum {
+		t.Fatalf("Expected ptgNum token, got %x", got)
+	}
+	if v := math.Float64frombits(binary.LittleEndian.Uint64(got[1:])); v != 2.5 {
+		t.Errorf("Expected 2.5, got %v", v)
+	}
+}
+
+func TestCompileFormulaErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		"=",
+		"SUM(",
+		"FOO(1)",
+		"A1:",
+		"ROUND(1)",
+		`"abc`,
+		"1+",
+		"IW1",
+		"A65537",
+		"MyName",
+		"1 2",
+	}
+
+	for _, expr := range invalid {
+		if _, err := compileFormula(expr); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
+
+func TestWriteFormulaCachedValues(t *testing.T) {
+	w := New()
+	defer w.Close()
+
+	data := [][]interface{}{
+		{1, 2, 3},
+		{
+			FormulaCell{Expr: "SUM(A1:C1)"},
+			FormulaCell{Expr: "SUM(A1:C1)", Cached: 6},
+			FormulaCell{Expr: `"x"&A1`, Cached: "x1"},
+			FormulaCell{Expr: "A1>0", Cached: true},
+			FormulaCell{Expr: "1/0", Cached: CellErrorDiv0},
+			FormulaCell{Expr: `""`, Cached: ""},
+		},
+	}
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := w.writeBIFF8(buf); err != nil {
+		t.Fatalf("writeBIFF8() failed: %v", err)
+	}
+
+	records := parseTestRecords(t, buf.Bytes())
+	var formulas []testRecord
+	for i, rec := range records {
+		if rec.recType != recTypeFORMULA {
+			continue
+		}
+		formulas = append(formulas, rec)
+
+		col := binary.LittleEndian.Uint16(rec.data[2:4])
+		next := records[i+1]
+		if col == 2 {
+			if next.recType != recTypeSTRING {
+				t.Fatalf("Expected STRING record after string formula, got 0x%04X", next.recType)
+			}
+			if got := hex.EncodeToString(next.data); got != "020001"+"7800"+"3100" {
+				t.Errorf("Unexpected STRING record body %s", got)
+			}
+		} else if next.recType == recTypeSTRING {
+			t.Errorf("Unexpected STRING record after formula in column %d", col)
+		}
+	}
+
+	if len(formulas) != 6 {
+		t.Fatalf("Expected 6 FORMULA records, got %d", len(formulas))
+	}
+
+	tests := []struct {
+		result string
+		flags  uint16
+	}{
+		{"0000000000000000", 0x0001},
+		{hex.EncodeToString(binary.LittleEndian.AppendUint64(nil, math.Float64bits(6))), 0},
+		{"000000000000ffff", 0},
+		{"010001000000ffff", 0},
+		{"020007000000ffff", 0},
+		{"030000000000ffff", 0},
+	}
+	for i, tt := range tests {
+		rec := formulas[i]
+		if got := hex.EncodeToString(rec.data[6:14]); got != tt.result {
+			t.Errorf("Formula %d: expected result %s, got %s", i, tt.result, got)
+		}
+		if got := binary.LittleEndian.Uint16(rec.data[14:16]); got != tt.flags {
+			t.Errorf("Formula %d: expected flags 0x%04X, got 0x%04X", i, tt.flags, got)
+		}
+		cce := int(binary.LittleEndian.Uint16(rec.data[20:22]))
+		if 22+cce != len(rec.data) {
+			t.Errorf("Formula %d: expected %d bytes of tokens, got %d", i, cce, len(rec.data)-22)
+		}
+	}
+
+	// Without a cached result the formula reads back as 0
+	if got := readBack(t, w).Cell(1, 0); got.Kind != KindFormula || got.Value != 0.0 {
+		t.Errorf("Expected a formula reading back as 0, got %v %v", got.Kind, got.Value)
+	}
+}
+
+func TestWriteInvalidFormula(t *testing.T) {
+	w := New()
+	defer w.Close()
+
+	if err := w.Write([][]interface{}{{FormulaCell{Expr: "NOPE(1)"}}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	if err := w.writeBIFF8(new(bytes.Buffer)); err == nil {
+		t.Error("Expected error for unsupported function")
+	}
+}
+
+func TestCellErrorString(t *testing.T) {
+	if s := CellErrorDiv0.String(); s != "#DIV/0!" {
+		t.Errorf("Expected '#DIV/0!', got '%s'", s)
+	}
+	if s := CellErrorNA.String(); s != "#N/A" {
+		t.Errorf("Expected '#N/A', got '%s'", s)
+	}
+}
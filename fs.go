@@ -0,0 +1,116 @@
+package xls
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+)
+
+// WriteFS is a file system SaveToFS writes to, such as an in-memory file
+// system in tests. A thin wrapper adapts afero, whose Create returns an
+// afero.File, or any other file system abstraction.
+//
+// If the file system also has a Rename(oldname, newname string) error
+// method, files are written under a temporary name next to the target and
+// renamed into place, so that readers never see a partial file. A
+// Remove(name string) error method is then used to clean up the temporary
+// file after a failure.
+type WriteFS interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// renameFS is a WriteFS that can replace files atomically.
+type renameFS interface {
+	WriteFS
+	Rename(oldname, newname string) error
+}
+
+// removeFS is a WriteFS that can delete files.
+type removeFS interface {
+	Remove(name string) error
+}
+
+// osFS is the WriteFS of SaveAs: the operating system's file system.
+type osFS struct{}
+
+func (osFS) Create(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+func (osFS) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// SaveToFS writes the XLS file to name in fsys. See WriteFS.
+//
+// With WithLowMemorySST, the Workbook stream is written to a temporary file
+// and copied from it, as by WriteChunks, instead of being built in memory,
+// unless an option that needs the whole stream at once is set.
+func (w *Writer) SaveToFS(fsys WriteFS, name string) error {
+	if w.lowMemorySST && w.streamsFromFile() {
+		f, err := w.spillFile()
+		if err != nil {
+			return err
+		}
+		defer f.close()
+		if err := saveStream(fsys, name, f.writeTo); err != nil {
+			return err
+		}
+		return f.err
+	}
+
+	data, err := w.build()
+	if data == nil {
+		return err
+	}
+	if saveErr := saveFile(fsys, name, data); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// saveFile writes data to name in fsys, through a temporary file renamed
+// into place if fsys can rename files.
+func saveFile(fsys WriteFS, name string, data []byte) error {
+	return saveStream(fsys, name, func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	})
+}
+
+// saveStream writes the file that write writes to name in fsys, as
+// saveFile does.
+func saveStream(fsys WriteFS, name string, write func(out io.Writer) error) error {
+	target := name
+	renamer, atomic := fsys.(renameFS)
+	if atomic {
+		target = fmt.Sprintf("%s.%08x.tmp", name, rand.Uint32())
+	}
+
+	file, err := fsys.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to write file: %w", err)
+	} else if atomic {
+		if err = renamer.Rename(target, name); err != nil {
+			err = fmt.Errorf("failed to rename file: %w", err)
+		}
+	}
+	if err != nil && atomic {
+		if remover, ok := fsys.(removeFS); ok {
+			remover.Remove(target)
+		}
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memFS is an in-memory WriteFS. A file appears when it is closed.
+type memFS struct {
+	files      map[string][]byte
+	failWrites bool
+	creates    []string
+	removals   []string
+}
+
+type memFile struct {
+	fs   *memFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.fs.failWrites {
+		return 0, errors.New("disk full")
+	}
+	return f.buf.Write(p)
+}
+
+func (f *memFile) Close() error {
+	f.fs.files[f.name] = f.buf.Bytes()
+	return nil
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: make(map[string][]byte)}
+}
+
+func (m *memFS) Create(name string) (io.WriteCloser, error) {
+	m.creates = append(m.creates, name)
+	return &memFile{fs: m, name: name}, nil
+}
+
+// renamingMemFS is a memFS that can rename and remove files.
+type renamingMemFS struct {
+	*memFS
+}
+
+func (m renamingMemFS) Rename(oldname, newname string) error {
+	data, ok := m.files[oldname]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(m.files, oldname)
+	m.files[newname] = data
+	return nil
+}
+
+func (m renamingMemFS) Remove(name string) error {
+	m.removals = append(m.removals, name)
+	delete(m.files, name)
+	return nil
+}
+
+func fsTestWriter() *Writer {
+	w := New(WithSheetName("Data"))
+	w.Write([][]interface{}{{"name", "qty"}, {"apple", 3}, {"pear", 1.5}})
+	return w
+}
+
+func TestSaveToFS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.xls")
+	if err := fsTestWriter().SaveAs(path); err != nil {
+		t.Fatalf("SaveAs() failed: %v", err)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected SaveAs to leave only its file, got %d entries", len(entries))
+	}
+
+	plain := newMemFS()
+	if err := fsTestWriter().SaveToFS(plain, "out.xls"); err != nil {
+		t.Fatalf("SaveToFS() failed: %v", err)
+	}
+	if !bytes.Equal(plain.files["out.xls"], want) {
+		t.Error("Expected SaveToFS to write the bytes of SaveAs")
+	}
+	if len(plain.creates) != 1 || plain.creates[0] != "out.xls" {
+		t.Errorf("Expected the file created in place without Rename, got %v", plain.creates)
+	}
+
+	renaming := renamingMemFS{newMemFS()}
+	if err := fsTestWriter().SaveToFS(renaming, "out.xls"); err != nil {
+		t.Fatalf("SaveToFS() failed: %v", err)
+	}
+	if len(renaming.files) != 1 || !bytes.Equal(renaming.files["out.xls"], want) {
+		t.Errorf("Expected only out.xls with the bytes of SaveAs, got %d files", len(renaming.files))
+	}
+	if len(renaming.creates) != 1 || !strings.HasPrefix(renaming.creates[0], "out.xls.") {
+		t.Errorf("Expected a temporary file renamed into place, got %v", renaming.creates)
+	}
+}
+
+func TestSaveToFSFailure(t *testing.T) {
+	fsys := renamingMemFS{newMemFS()}
+	fsys.files["out.xls"] = []byte("previous")
+	fsys.failWrites = true
+	err := fsTestWriter().SaveToFS(fsys, "out.xls")
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Expected the write error, got %v", err)
+	}
+	if string(fsys.files["out.xls"]) != "previous" {
+		t.Error("Expected a failed save to keep the previous file")
+	}
+	if len(fsys.files) != 1 || len(fsys.removals) != 1 || fsys.removals[0] != fsys.creates[0] {
+		t.Errorf("Expected the temporary file %v removed, got removals %v", fsys.creates, fsys.removals)
+	}
+}
+
+func TestSaveToFSLowMemorySST(t *testing.T) {
+	want := newMemFS()
+	if err := fsTestWriter().SaveToFS(want, "out.xls"); err != nil {
+		t.Fatalf("SaveToFS() failed: %v", err)
+	}
+
+	// The stream goes through a temporary file, removed once it is copied
+	for _, failWrites := range []bool{false, true} {
+		dir := t.TempDir()
+		w := New(WithSheetName("Data"), WithLowMemorySST(), WithTempDir(dir))
+		w.Write([][]interface{}{{"name", "qty"}, {"apple", 3}, {"pear", 1.5}})
+		fsys := renamingMemFS{newMemFS()}
+		fsys.failWrites = failWrites
+		err := w.SaveToFS(fsys, "out.xls")
+		if failWrites {
+			if err == nil || !strings.Contains(err.Error(), "disk full") {
+				t.Errorf("Expected the write error, got %v", err)
+			}
+		} else if err != nil {
+			t.Fatalf("SaveToFS() failed: %v", err)
+		} else if !bytes.Equal(fsys.files["out.xls"], want.files["out.xls"]) {
+			t.Error("Expected the bytes of the file built in memory")
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("Expected no temporary file left, got %d", len(entries))
+		}
+	}
+}
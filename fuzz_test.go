@@ -0,0 +1,173 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fuzzSeedWorkbooks returns writer-generated workbooks covering every cell type.
+func fuzzSeedWorkbooks(f *testing.F) [][]byte {
+	f.Helper()
+
+	inputs := [][][]interface{}{
+		{},
+		{{"a", 1, 2.5, true}},
+		{
+			{"Name", "Date", "Total"},
+			{"日本語", time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC), FormulaCell{Expr: `IF(A1="x",SUM(B1:B3),"y")`, Cached: "y"}},
+			{nil, CellErrorDiv0, FormulaCell{Expr: "1/0", Cached: CellErrorDiv0}},
+		},
+	}
+
+	var files [][]byte
+	for _, data := range inputs {
+		w := New()
+		if err := w.Write(data); err != nil {
+			f.Fatalf("Write() failed: %v", err)
+		}
+		buf := new(bytes.Buffer)
+		if _, err := w.WriteTo(buf); err != nil {
+			f.Fatalf("WriteTo() failed: %v", err)
+		}
+		files = append(files, buf.Bytes())
+	}
+
+	fixtures, _ := filepath.Glob(filepath.Join("testdata", "*.xls"))
+	for _, path := range fixtures {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatalf("Failed to read %s: %v", path, err)
+		}
+		files = append(files, data)
+	}
+	return files
+}
+
+func FuzzOpenCFB(f *testing.F) {
+	for _, file := range fuzzSeedWorkbooks(f) {
+		f.Add(file)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cfb, err := ReadCFB(data)
+		if err != nil {
+			return
+		}
+		for _, e := range cfb.Entries() {
+			cfb.Chain(e)
+			if e.ObjectType == CFBObjectStream {
+				cfb.Stream(e.Name)
+			}
+		}
+	})
+}
+
+// FuzzReadWorkbook mutates the workbook stream rather than the container so
+// that the fuzzer spends its time in the BIFF8 parser.
+func FuzzReadWorkbook(f *testing.F) {
+	for _, file := range fuzzSeedWorkbooks(f) {
+		cfb, err := ReadCFB(file)
+		if err != nil {
+			f.Fatalf("ReadCFB() failed: %v", err)
+		}
+		stream, err := cfb.Stream("Workbook")
+		if err != nil {
+			f.Fatalf("Stream() failed: %v", err)
+		}
+		f.Add(stream)
+	}
+
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		file := new(bytes.Buffer)
+		if err := WriteCFB(file, stream); err != nil {
+			t.Fatalf("WriteCFB() failed: %v", err)
+		}
+		wb, err := openWorkbook(file.Bytes())
+		if err != nil {
+			return
+		}
+		for _, sheet := range wb.Sheets() {
+			for _, row := range sheet.Rows() {
+				for _, cell := range row {
+					if cell.IsDate() {
+						cell.Time(nil)
+					}
+				}
+			}
+			err := wb.ForEachRow(sheet.Name, func(int, []Cell) error { return nil })
+			if err != nil {
+				t.Fatalf("ForEachRow() failed after a successful open: %v", err)
+			}
+		}
+	})
+}
+
+func TestReadFileTooComplex(t *testing.T) {
+	bof := testRecord{recTypeBOF, le(0x0600, 0x0020, 0, 0, uint32(0), uint32(6))}
+	var nested []testRecord
+	for i := 0; i <= maxSubstreamDepth; i++ {
+		nested = append(nested, bof)
+	}
+
+	tests := []struct {
+		name    string
+		globals []testRecord
+		cells   []testRecord
+	}{
+		{"SST with 4 billion strings", []testRecord{{recTypeSST, le(uint32(0xFFFFFFFF), uint32(0xFFFFFFFF))}}, nil},
+		{"deeply nested substreams", nil, nested},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openWorkbook(buildFixture(t, tt.globals, tt.cells))
+			if !errors.Is(err, ErrFileTooComplex) {
+				t.Errorf("Expected ErrFileTooComplex, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadEmbeddedChart(t *testing.T) {
+	cells := []testRecord{
+		{recTypeNUMBER, le(0, 0, 0, 1.0)},
+		{recTypeBOF, le(0x0600, 0x0020, 0, 0, uint32(0), uint32(6))},
+		{recTypeNUMBER, le(5, 5, 0, 9.0)}, // Belongs to the chart
+		{recTypeEOF, nil},
+		{recTypeNUMBER, le(1, 0, 0, 2.0)},
+	}
+	wb, err := openWorkbook(buildFixture(t, nil, cells))
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+
+	sheet := wb.Sheets()[0]
+	if v := sheet.Cell(1, 0).Value; v != 2.0 {
+		t.Errorf("Expected cell after the chart to be read, got %v", v)
+	}
+	if cell := sheet.Cell(5, 5); cell.Kind != KindBlank {
+		t.Errorf("Expected chart records to be skipped, got %v", cell.Value)
+	}
+}
+
+func TestReadCFBChainCycle(t *testing.T) {
+	file := new(bytes.Buffer)
+	if err := WriteCFB(file, make([]byte, 4096)); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+	data := file.Bytes()
+
+	// Point the directory sector at itself in the FAT
+	fatSector := binary.LittleEndian.Uint32(data[76:80])
+	dirSector := binary.LittleEndian.Uint32(data[48:52])
+	fat := data[(fatSector+1)*cfbSectorSize:]
+	binary.LittleEndian.PutUint32(fat[dirSector*4:], dirSector)
+
+	if _, err := ReadCFB(data); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat, got %v", err)
+	}
+}
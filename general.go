@@ -0,0 +1,134 @@
+package xls
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GeneralNumbers is how a GeneralNumberPolicy writes the numbers it
+// applies to.
+type GeneralNumbers int
+
+const (
+	// GeneralKeep writes numbers with the General format, which displays
+	// large and small magnitudes in scientific notation, such as 1E+21.
+	GeneralKeep GeneralNumbers = iota
+	// GeneralFixed gives numbers a fixed-decimal format with the digits of
+	// their shortest representation, such as 0.0000001234.
+	GeneralFixed
+	// GeneralText writes numbers as text of their shortest fixed-decimal
+	// representation.
+	GeneralText
+)
+
+// The General format displays a number in at most generalWidth characters.
+// In scientific notation that leaves generalScientificDigits significant
+// digits, as in 1.23457E-07; in fixed notation, a number below 1 keeps
+// generalWidth-2 decimals after its "0.".
+const (
+	generalWidth            = 11
+	generalScientificDigits = 6
+)
+
+// defaultGeneralLarge is the magnitude from which the integer part of a
+// number takes more than generalWidth characters, so that General switches
+// to scientific notation.
+const defaultGeneralLarge = 1e11
+
+// generalScientific reports whether the General format displays abs, a
+// magnitude other than zero, in scientific notation: when its integer part
+// is too long, or when the decimals that fit in generalWidth characters
+// keep fewer of its significant digits than scientific notation would.
+// 0.0000001234 keeps 3 digits as 0.000000123, so it shows as 1.234E-07.
+func generalScientific(abs float64) bool {
+	if abs >= defaultGeneralLarge {
+		return true
+	}
+	if abs >= 1 {
+		return false
+	}
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(abs, 'e', -1, 64), "e")
+	digits := len(strings.Replace(mantissa, ".", "", 1))
+	e, _ := strconv.Atoi(exp)
+	zeros := -e - 1 // Zeros after the decimal point
+	return generalWidth-2-zeros < min(digits, generalScientificDigits)
+}
+
+// GeneralNumberPolicy selects how numbers in cells with the General format
+// are written when their magnitude is at least Large or, other than zero,
+// below Small. A zero Large means 1e11, from which General switches to
+// scientific notation. A zero Small means the numbers below 1 that General
+// displays in scientific notation, such as 0.0000001234 shown as
+// 1.234E-07: those whose significant digits do not fit in 11 characters.
+type GeneralNumberPolicy struct {
+	Mode         GeneralNumbers
+	Large, Small float64
+}
+
+// WithGeneralNumberPolicy sets how numbers that the General format would
+// display in scientific notation are written: as they are, with a
+// fixed-decimal format, or as text. It applies to each number cell, and
+// string converted with WithNumberConversion, whose style has no number
+// format, by the magnitude of its value. Dates and formulas are not
+// changed.
+func WithGeneralNumberPolicy(p GeneralNumberPolicy) Option {
+	return func(w *Writer) {
+		if p.Large == 0 {
+			p.Large = defaultGeneralLarge
+		}
+		w.general = p
+	}
+}
+
+// applies reports whether the policy changes how f is written.
+func (p GeneralNumberPolicy) applies(f float64) bool {
+	abs := math.Abs(f)
+	if p.Mode == GeneralKeep || math.IsInf(f, 0) || abs == 0 {
+		return false
+	}
+	if p.Small == 0 {
+		return abs >= p.Large || abs < 1 && generalScientific(abs)
+	}
+	return abs >= p.Large || abs < p.Small
+}
+
+// generalNumber returns the number of a cell the General number policy
+// applies to, or false for other cells.
+func (s *SheetWriter) generalNumber(row, col int, value interface{}) (float64, bool) {
+	if s.w.general.Mode == GeneralKeep {
+		return 0, false
+	}
+	value, st, _ := s.resolveStyle(row, col, value)
+	if st.NumberFormat != "" && st.NumberFormat != "General" {
+		return 0, false
+	}
+	f, ok := s.w.numberText(value)
+	if !ok {
+		f, ok = toFloat64(value)
+	}
+	return f, ok && s.w.general.applies(f)
+}
+
+// generalText returns the text a cell is written as under GeneralText, or
+// false if it is not.
+func (s *SheetWriter) generalText(row, col int, value interface{}) (string, bool) {
+	if s.w.general.Mode != GeneralText {
+		return "", false
+	}
+	f, ok := s.generalNumber(row, col, value)
+	if !ok {
+		return "", false
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), true
+}
+
+// fixedFormat returns the number format that displays f with the decimals
+// of its shortest representation, at most 30 as Excel allows.
+func fixedFormat(f float64) string {
+	_, decimals, _ := strings.Cut(strconv.FormatFloat(f, 'f', -1, 64), ".")
+	if decimals == "" {
+		return "0"
+	}
+	return "0." + strings.Repeat("0", min(len(decimals), 30))
+}
@@ -0,0 +1,161 @@
+package xls
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGeneralNumberPolicyApplies(t *testing.T) {
+	w := New(WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralFixed}))
+	for _, tc := range []struct {
+		f    float64
+		want bool
+	}{
+		{1e11, true},
+		{99999999999, false},
+		{-1e11, true},
+		{1e-9, false},
+		{9.99e-10, true},
+		{1.234e-7, true},
+		{-1.234e-7, true},
+		{1.2e-7, false},
+		{0.1234567891234, false},
+		{-9.99e-10, true},
+		{0, false},
+		{1, false},
+	} {
+		if got := w.general.applies(tc.f); got != tc.want {
+			t.Errorf("applies(%g): expected %v, got %v", tc.f, tc.want, got)
+		}
+	}
+
+	custom := New(WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralText, Large: 1000, Small: 0.01}))
+	for _, tc := range []struct {
+		f    float64
+		want bool
+	}{
+		{1000, true},
+		{999.99, false},
+		{0.01, false},
+		{0.0099, true},
+	} {
+		if got := custom.general.applies(tc.f); got != tc.want {
+			t.Errorf("custom applies(%g): expected %v, got %v", tc.f, tc.want, got)
+		}
+	}
+	if New().general.applies(1e21) {
+		t.Error("Expected GeneralKeep to apply to no number")
+	}
+}
+
+func TestFixedFormat(t *testing.T) {
+	for _, tc := range []struct {
+		f    float64
+		want string
+	}{
+		{1e21, "0"},
+		{123456789012.5, "0.0"},
+		{1.234e-7, "0.0000000000"},
+		{1e-40, "0.000000000000000000000000000000"},
+	} {
+		if got := fixedFormat(tc.f); got != tc.want {
+			t.Errorf("fixedFormat(%g): expected %q, got %q", tc.f, tc.want, got)
+		}
+	}
+}
+
+func TestWithGeneralNumberPolicyFixed(t *testing.T) {
+	data := [][]interface{}{
+		{1e21, 1.234e-10, 99999999999, 0, Styled(1e12, Style{NumberFormat: "0.00E+00"})},
+		{"1e12", Styled(2e-10, Style{Font: Font{Bold: true}})},
+	}
+	w := New(WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralFixed}), WithNumberConversion(func(string) bool { return true }))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	file, err := w.build()
+	if err != nil {
+		t.Fatalf("build() failed: %v", err)
+	}
+	wb, err := openWorkbook(file)
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	sheet := wb.Sheets()[0]
+	for _, tc := range []struct {
+		row, col int
+		format   string
+		value    float64
+	}{
+		{0, 0, "0", 1e21},
+		{0, 1, "0.0000000000000", 1.234e-10},
+		{0, 2, "General", 99999999999},
+		{0, 3, "General", 0},
+		{0, 4, "0.00E+00", 1e12},
+		{1, 0, "0", 1e12},
+		{1, 1, "0.0000000000", 2e-10},
+	} {
+		c := sheet.Cell(tc.row, tc.col)
+		if c.Kind != KindNumber || c.Value != tc.value {
+			t.Errorf("Cell (%d, %d): expected the number %g, got %s %v", tc.row, tc.col, tc.value, c.Kind, c.Value)
+		}
+		if c.FormatString != tc.format {
+			t.Errorf("Cell (%d, %d): expected the format %q, got %q", tc.row, tc.col, tc.format, c.FormatString)
+		}
+	}
+	for col, want := range []string{"1000000000000000000000", "0.0000000001234"} {
+		if got := sheet.Cell(0, col).FormattedValue(); got != want {
+			t.Errorf("Column %d: expected %q, got %q", col, want, got)
+		}
+	}
+}
+
+func TestWithGeneralNumberPolicyText(t *testing.T) {
+	data := [][]interface{}{
+		{1e21, -3e-12, 12.5, Styled(1e15, Style{NumberFormat: "0"})},
+	}
+	w := New(WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralText}))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	file, err := w.build()
+	if err != nil {
+		t.Fatalf("build() failed: %v", err)
+	}
+	wb, err := openWorkbook(file)
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	sheet := wb.Sheets()[0]
+	want := []Cell{
+		{Kind: KindText, Value: "1000000000000000000000"},
+		{Kind: KindText, Value: "-0.000000000003"},
+		{Kind: KindNumber, Value: 12.5},
+		{Kind: KindNumber, Value: 1e15},
+	}
+	for col, w := range want {
+		if got := sheet.Cell(0, col); got.Kind != w.Kind || got.Value != w.Value {
+			t.Errorf("Column %d: expected %s %v, got %s %v", col, w.Kind, w.Value, got.Kind, got.Value)
+		}
+	}
+}
+
+func TestWithGeneralNumberPolicyKeep(t *testing.T) {
+	data := [][]interface{}{{1e21, 1.234e-7, "x"}}
+	var a, b bytes.Buffer
+	for buf, opts := range map[*bytes.Buffer][]Option{
+		&a: nil,
+		&b: {WithGeneralNumberPolicy(GeneralNumberPolicy{Mode: GeneralKeep})},
+	} {
+		w := New(opts...)
+		if err := w.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		if _, err := w.WriteTo(buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Expected GeneralKeep to write the same file as the default")
+	}
+}
@@ -0,0 +1,251 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goldenCases are canonical workbooks whose bytes are pinned by files in
+// testdata/golden. Run "go test -run TestGolden -update" after an
+// intentional change to the output and review the record diff first.
+var goldenCases = []struct {
+	name  string
+	build func() *Writer
+}{
+	{"empty", func() *Writer {
+		return New()
+	}},
+	{"typed", func() *Writer {
+		w := New(WithSheetName("Types"))
+		w.Write([][]interface{}{
+			{"string", "日本語", "", nil},
+			{"int", 42, int64(-7), uint8(255)},
+			{"float", 3.14, float32(0.5), 1e-10},
+			{"bool", true, false},
+			{"date", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, time.February, 29, 18, 30, 0, 0, time.UTC)},
+			{"error", CellErrorDiv0, CellErrorNA},
+			{"formula", FormulaCell{Expr: "SUM(B2:D2)", Cached: 290}, FormulaCell{Expr: `IF(B4,"yes","no")`, Cached: "yes"}, FormulaCell{Expr: "B3*2"}},
+			{"other", struct{ A, B int }{1, 2}},
+		})
+		return w
+	}},
+	{"large", func() *Writer {
+		// Large enough to need several FAT sectors
+		data := make([][]interface{}, 1000)
+		for i := range data {
+			data[i] = []interface{}{fmt.Sprintf("Item %d", i%50), i, float64(i) / 8, i%3 == 0, time.Date(2024, time.January, 1+i%28, 0, 0, 0, 0, time.UTC)}
+		}
+		w := New(WithSheetName("Large"))
+		w.Write(data)
+		return w
+	}},
+	{"identity", func() *Writer {
+		w := New(WithBOFIdentity(0x2775, 0x07CD))
+		w.Write([][]interface{}{{"build", 10101}})
+		return w
+	}},
+	{"styled", func() *Writer {
+		w := New(WithSheetName("Styled"))
+		header := Style{Font: Font{Bold: true, Color: ColorWhite}, Fill: ColorNavy, HAlign: HAlignCenter, Border: Border{Bottom: BorderThin}}
+		for col := 0; col < 3; col++ {
+			w.SetCellStyle(0, col, header)
+		}
+		w.SetColStyle(1, Style{NumberFormat: "#,##0.00", HAlign: HAlignRight})
+		w.SetColStyle(2, Style{NumberFormat: "d-mmm-yy", Font: Font{Name: "Courier New", Size: 9, Italic: true}})
+		w.Write([][]interface{}{
+			{"Item", "Price", "Sold"},
+			{"Apple", 1.5, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+			{"Wrapped\ntext", 1234.5, nil},
+		})
+		w.SetCellStyle(2, 0, Style{WrapText: true, VAlign: VAlignTop})
+		return w
+	}},
+	{"sheets", func() *Writer {
+		w := New(WithSheetName("Summary"))
+		w.Write([][]interface{}{{"Region", "Total"}, {"North", 120}, {"South", 80}})
+		w.SetRowStyle(0, Style{Font: Font{Bold: true}})
+		w.FormatAsTable(0, 2, 0, 1, TableStyle{AutoFilter: true})
+		summary, _ := w.Sheet("Summary")
+		summary.FreezePanes(1, 0)
+		summary.SetColWidth(0, 16)
+
+		detail, _ := w.AddSheet("Detail", WithZoom(85), WithProtection("secret"),
+			WithPrintSetup(PrintSetup{Landscape: true, PaperSize: 9, FitToWidth: 1}))
+		detail.Write([][]interface{}{{"Region", "Month", "Sales"}, {"North", "Jan", 70}, {"North", "Feb", 50}})
+		detail.FormatAsTable(0, 2, 0, 2, TableStyle{AutoFilter: true})
+		return w
+	}},
+	{"default-width", func() *Writer {
+		w := New(WithDefaultColumnWidth(12.5))
+		w.SetColStyle(1, Style{NumberFormat: "0.00"})
+		sheet, _ := w.Sheet("Sheet1")
+		sheet.SetColWidth(2, 30)
+		w.Write([][]interface{}{{"Name", 1.5, "Wide"}, {"Other", 2.25, "Column"}})
+		return w
+	}},
+	{"rich-text", func() *Writer {
+		// Repeated rich text goes to the SST, the rest to RSTRING records
+		w := New(WithHybridStrings(2))
+		status := RichText{Text: "Status: late", Runs: []TextRun{{Start: 8, Font: Font{Bold: true, Color: ColorRed}}}}
+		w.Write([][]interface{}{
+			{"Order", "Status"},
+			{RichText{Text: "A-100 (rush)", Runs: []TextRun{{Start: 6, Font: Font{Italic: true}}}}, status},
+			{RichText{Text: "B-200 日本", Runs: []TextRun{{Start: 0, Font: Font{Name: "Courier New"}}, {Start: 6, Font: Font{Bold: true}}}}, status},
+		})
+		return w
+	}},
+	{"profile-excel97", func() *Writer { return profileWorkbook(ProfileExcel97) }},
+	{"profile-poi", func() *Writer { return profileWorkbook(ProfilePOI) }},
+	{"profile-strict", func() *Writer { return profileWorkbook(ProfileStrict, WithMinimalRecords()) }},
+	{"theme-default", func() *Writer { return themeWorkbook(ThemeDefault) }},
+	{"theme-modern", func() *Writer { return themeWorkbook(ThemeModern) }},
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.build()
+			defer w.Close()
+
+			got := new(bytes.Buffer)
+			if _, err := w.WriteTo(got); err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+
+			path := filepath.Join("testdata", "golden", tc.name+".xls")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(want, got.Bytes()) {
+				t.Errorf("Output differs from %s:\n%s", path, diffXLS(want, got.Bytes()))
+			}
+		})
+	}
+}
+
+// diffXLS describes how two XLS files differ, record by record where
+// possible, so that golden file failures are readable.
+func diffXLS(want, got []byte) string {
+	wantStream, err := workbookStream(want)
+	if err != nil {
+		return fmt.Sprintf("cannot read golden file: %v", err)
+	}
+	gotStream, err := workbookStream(got)
+	if err != nil {
+		return fmt.Sprintf("cannot read output: %v", err)
+	}
+
+	wantRecords, wantErr := readAllRecords(wantStream)
+	gotRecords, gotErr := readAllRecords(gotStream)
+
+	const maxDiffs = 5
+	var b strings.Builder
+	diffs := 0
+	for i := 0; i < max(len(wantRecords), len(gotRecords)); i++ {
+		var w, g *Record
+		if i < len(wantRecords) {
+			w = &wantRecords[i]
+		}
+		if i < len(gotRecords) {
+			g = &gotRecords[i]
+		}
+		if w != nil && g != nil && w.Type == g.Type && bytes.Equal(w.Data, g.Data) {
+			continue
+		}
+		if diffs++; diffs > maxDiffs {
+			continue
+		}
+		fmt.Fprintf(&b, "record %d:\n  want %s\n  got  %s\n", i, describeRecord(w), describeRecord(g))
+	}
+	if diffs > maxDiffs {
+		fmt.Fprintf(&b, "... %d more differing records\n", diffs-maxDiffs)
+	}
+	fmt.Fprintf(&b, "%d records in golden file, %d in output\n", len(wantRecords), len(gotRecords))
+	if wantErr != nil || gotErr != nil {
+		fmt.Fprintf(&b, "record errors: golden %v, output %v\n", wantErr, gotErr)
+	}
+
+	if diffs == 0 {
+		b.Reset()
+		for i := 0; i < min(len(want), len(got)); i++ {
+			if want[i] != got[i] {
+				fmt.Fprintf(&b, "workbook records are identical; the CFB container differs at byte %d\n", i)
+				break
+			}
+		}
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "workbook records are identical; the files are %d and %d bytes long\n", len(want), len(got))
+		}
+	}
+	return b.String()
+}
+
+func workbookStream(file []byte) ([]byte, error) {
+	cfb, err := ReadCFB(file)
+	if err != nil {
+		return nil, err
+	}
+	return cfb.Stream("Workbook")
+}
+
+// readAllRecords reads records up to the trailing zero padding.
+func readAllRecords(stream []byte) ([]Record, error) {
+	var records []Record
+	r := NewRecordReader(stream)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF || err == nil && rec.Type == 0 && len(bytes.Trim(stream[rec.Offset:], "\x00")) == 0 {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+func describeRecord(rec *Record) string {
+	if rec == nil {
+		return "(none)"
+	}
+	body := hex.EncodeToString(rec.Data)
+	if len(body) > 64 {
+		body = body[:64] + "..."
+	}
+	return fmt.Sprintf("%s (0x%04X) at offset %d, %d bytes: %s", RecordName(rec.Type), rec.Type, rec.Offset, len(rec.Data), body)
+}
+
+func TestDiffXLS(t *testing.T) {
+	build := func(data [][]interface{}) []byte {
+		w := New()
+		w.Write(data)
+		buf := new(bytes.Buffer)
+		if _, err := w.WriteTo(buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	diff := diffXLS(build([][]interface{}{{1.0}}), build([][]interface{}{{2.0}}))
+	if !strings.Contains(diff, "NUMBER (0x0203)") || strings.Contains(diff, "record errors") {
+		t.Errorf("Expected the NUMBER record in the diff, got:\n%s", diff)
+	}
+}
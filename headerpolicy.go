@@ -0,0 +1,120 @@
+package xls
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// HeaderPolicy selects how WithHeaderSanitization rewrites the headers of
+// WriteStructs and WriteMaps. The steps run in the order of the fields.
+type HeaderPolicy struct {
+	// NormalizeSpace replaces control characters and each run of white
+	// space, such as line breaks, tabs and no-break spaces, with a single
+	// space, and trims both ends.
+	NormalizeSpace bool
+
+	// TitleCase upper-cases the first letter of each word, leaving the
+	// other letters as they are, so "unit price" becomes "Unit Price" and
+	// "ID" stays "ID".
+	TitleCase bool
+
+	// NameEmpty names the empty headers "Column N", where N is the
+	// one-based column number, so every column of the header row has a
+	// name.
+	NameEmpty bool
+
+	// MaxLength shortens headers to at most MaxLength characters, counted
+	// as for Limits.MaxCellChars. 0 leaves them whole.
+	MaxLength int
+
+	// Deduplicate suffixes a header equal to an earlier one, ignoring case,
+	// with " (2)", " (3)" and so on, skipping the names already taken and
+	// shortening the header so the suffix fits within MaxLength. Empty
+	// headers are left empty.
+	Deduplicate bool
+}
+
+// WithHeaderSanitization rewrites the header row WriteStructs and WriteMaps
+// produce, headers from WithColumnMapping included, following policy, for
+// headers that collide, such as "Amount" from both sides of a join, or
+// hold characters that trip pivot tables and other readers. Write and the
+// other methods that take the header row from the caller are not changed.
+func WithHeaderSanitization(policy HeaderPolicy) RecordOption {
+	return func(c *recordConfig) {
+		c.headers = &policy
+	}
+}
+
+// apply returns headers rewritten by the policy, as a new slice.
+func (p *HeaderPolicy) apply(headers []string) []string {
+	out := make([]string, len(headers))
+	title := cases.Title(language.Und, cases.NoLower)
+	for c, h := range headers {
+		if p.NormalizeSpace {
+			h = strings.TrimSpace(collapseSpace(strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) {
+					return ' '
+				}
+				return r
+			}, h)))
+		}
+		if p.TitleCase {
+			h = title.String(h)
+		}
+		if p.NameEmpty && h == "" {
+			h = "Column " + strconv.Itoa(c+1)
+		}
+		out[c] = p.shorten(h, 0)
+	}
+	if !p.Deduplicate {
+		return out
+	}
+
+	taken := make(map[string]bool, len(out))
+	for _, h := range out {
+		taken[strings.ToLower(h)] = true
+	}
+	seen := make(map[string]bool, len(out))
+	for c, h := range out {
+		key := strings.ToLower(h)
+		if h == "" || !seen[key] {
+			seen[key] = true
+			continue
+		}
+		for n := 2; ; n++ {
+			suffix := " (" + strconv.Itoa(n) + ")"
+			name := p.shorten(h, textLength(suffix)) + suffix
+			if key := strings.ToLower(name); !taken[key] {
+				taken[key], seen[key] = true, true
+				out[c] = name
+				break
+			}
+		}
+	}
+	return out
+}
+
+// shorten returns h cut to MaxLength characters less reserve, dropping
+// trailing spaces left by the cut when NormalizeSpace is set.
+func (p *HeaderPolicy) shorten(h string, reserve int) string {
+	limit := p.MaxLength - reserve
+	if p.MaxLength <= 0 || textLength(h) <= limit {
+		return h
+	}
+	n := 0
+	for i, r := range h {
+		n += textLength(string(r))
+		if n > limit {
+			h = h[:i]
+			break
+		}
+	}
+	if p.NormalizeSpace {
+		h = strings.TrimRightFunc(h, unicode.IsSpace)
+	}
+	return h
+}
@@ -0,0 +1,83 @@
+package xls
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeaderPolicy(t *testing.T) {
+	long := strings.Repeat("Quarterly revenue ", 20)
+	tests := []struct {
+		name    string
+		policy  HeaderPolicy
+		headers []string
+		want    []string
+	}{
+		{"unchanged", HeaderPolicy{}, []string{" Amount", "Amount", ""}, []string{" Amount", "Amount", ""}},
+		{"collisions", HeaderPolicy{Deduplicate: true},
+			[]string{"Amount", "ID", "amount", "Amount (2)", "Amount", "ID"},
+			[]string{"Amount", "ID", "amount (3)", "Amount (2)", "Amount (4)", "ID (2)"}},
+		{"white space", HeaderPolicy{NormalizeSpace: true, Deduplicate: true},
+			[]string{"Unit Price", " Unit  price\t", "Line\r\nTotal", "Note\x00"},
+			[]string{"Unit Price", "Unit price (2)", "Line Total", "Note"}},
+		{"title case", HeaderPolicy{TitleCase: true}, []string{"unit price", "ID", "customerID"}, []string{"Unit Price", "ID", "CustomerID"}},
+		{"empty", HeaderPolicy{NormalizeSpace: true, Deduplicate: true}, []string{"", " ", "\t"}, []string{"", "", ""}},
+		{"named empty", HeaderPolicy{NormalizeSpace: true, NameEmpty: true, Deduplicate: true},
+			[]string{"Name", " ", "Column 2", ""},
+			[]string{"Name", "Column 2", "Column 2 (2)", "Column 4"}},
+		{"long", HeaderPolicy{NormalizeSpace: true, MaxLength: 20, Deduplicate: true},
+			[]string{long, long, "Quarterly revenue Q1", "€" + strings.Repeat("\U0001F600", 12)},
+			[]string{"Quarterly revenue Qu", "Quarterly revenu (2)", "Quarterly revenue Q1", "€" + strings.Repeat("\U0001F600", 9)}},
+		{"long with room", HeaderPolicy{MaxLength: 20, Deduplicate: true},
+			[]string{"Total", "Total"}, []string{"Total", "Total (2)"}},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.apply(tt.headers); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestWithHeaderSanitization(t *testing.T) {
+	policy := WithHeaderSanitization(HeaderPolicy{NormalizeSpace: true, TitleCase: true, NameEmpty: true, Deduplicate: true})
+
+	w := New()
+	err := w.WriteMaps([]map[string]interface{}{
+		{"amount": 1, " amount ": 2, "": "x", "note\n": "rush"},
+	}, policy)
+	if err != nil {
+		t.Fatalf("WriteMaps() failed: %v", err)
+	}
+	want := [][]interface{}{
+		{"Column 1", "Amount", "Amount (2)", "Note"},
+		{"x", 2, 1, "rush"},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Headers of the mapping are sanitized, the blank columns included
+	err = w.WriteStructs(mappingOrders(), policy, WithColumnMapping([]ColumnMap{
+		{SourceKey: "Amount", Index: 0},
+		{SourceKey: "Customer", Header: "amount", Index: 1},
+		{SourceKey: "ID", Index: 3},
+	}))
+	if err != nil {
+		t.Fatalf("WriteStructs() failed: %v", err)
+	}
+	if got, want := w.sheets[0].data[0], []interface{}{"Amount", "Amount (2)", "Column 3", "ID"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the header %v, got %v", want, got)
+	}
+	if got, want := w.sheets[0].data[1], []interface{}{12.5, "Acme", nil, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the first row %v, got %v", want, got)
+	}
+
+	// Write is left alone
+	if err := w.Write([][]interface{}{{"amount", "amount"}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if got := w.sheets[0].data[0]; !reflect.DeepEqual(got, []interface{}{"amount", "amount"}) {
+		t.Errorf("Expected Write to keep its header, got %v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is returned, by a *LimitError, when a workbook written or
+// read exceeds its Limits.
+var ErrLimitExceeded = errors.New("xls: limit exceeded")
+
+// Limits bounds the workbooks a Writer writes and the reader reads. The
+// defaults, those of DefaultLimits, are the limits of the BIFF8 format and
+// the reader's hard caps; setting a field tightens its limit, so that, for
+// example, an upload endpoint can refuse files of more than 20 MB or five
+// sheets. A field of zero, or above its default, takes the default.
+type Limits struct {
+	MaxRows          int   // Rows of a sheet
+	MaxCols          int   // Columns of a sheet
+	MaxCellChars     int   // Characters of a cell text, in UTF-16 code units
+	MaxUniqueStrings int   // Strings of the shared string table
+	MaxSheets        int   // Sheets of a workbook
+	MaxFileBytes     int64 // Size of the compound file
+}
+
+// DefaultLimits returns the limits of the BIFF8 format: 65,536 rows, 256
+// columns and 32,767 characters a cell, and the reader's caps of 16M shared
+// strings, 16,384 sheets and files of 8 GiB.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxRows:          maxRow + 1,
+		MaxCols:          maxColumn + 1,
+		MaxCellChars:     maxTextLength,
+		MaxUniqueStrings: maxSSTStrings,
+		MaxSheets:        maxSheets,
+		MaxFileBytes:     maxSectors * cfbSectorSize,
+	}
+}
+
+// resolve returns l with its unset or too large fields at their default.
+func (l Limits) resolve() Limits {
+	d := DefaultLimits()
+	pick := func(v, def int) int {
+		if v <= 0 || v > def {
+			return def
+		}
+		return v
+	}
+	l.MaxRows = pick(l.MaxRows, d.MaxRows)
+	l.MaxCols = pick(l.MaxCols, d.MaxCols)
+	l.MaxCellChars = pick(l.MaxCellChars, d.MaxCellChars)
+	l.MaxUniqueStrings = pick(l.MaxUniqueStrings, d.MaxUniqueStrings)
+	l.MaxSheets = pick(l.MaxSheets, d.MaxSheets)
+	if l.MaxFileBytes <= 0 || l.MaxFileBytes > d.MaxFileBytes {
+		l.MaxFileBytes = d.MaxFileBytes
+	}
+	return l
+}
+
+// WithLimits tightens the limits of the workbooks the Writer writes.
+// Writing a workbook that exceeds them fails with a *LimitError.
+func WithLimits(l Limits) Option {
+	return func(w *Writer) {
+		w.limits = l.resolve()
+	}
+}
+
+// ReadOption configures how a workbook is opened.
+type ReadOption func(*readOptions)
+
+// readOptions are the settings the ReadOptions of an open function set.
+type readOptions struct {
+	limits Limits
+}
+
+// newReadOptions applies opts to the default settings.
+func newReadOptions(opts []ReadOption) readOptions {
+	o := readOptions{limits: DefaultLimits()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithReadLimits tightens the limits of the workbooks that are read. Files
+// that exceed them fail to open, or, for the rows, columns and text of a
+// sheet read on demand, to read, with a *LimitError.
+func WithReadLimits(l Limits) ReadOption {
+	return func(o *readOptions) {
+		o.limits = l.resolve()
+	}
+}
+
+// LimitError reports a workbook exceeding one of its Limits. It wraps
+// ErrLimitExceeded, and ErrOutOfRange when writing or ErrFileTooComplex
+// when reading, so that the errors of the BIFF8 limits match those of the
+// limits that were tightened.
+type LimitError struct {
+	Limit string // The field of Limits, such as "MaxRows"
+	Value int64  // The count or size of the workbook
+	Max   int64  // The limit
+	Read  bool   // Whether the workbook was read, rather than written
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %d %s, more than %s %d", ErrLimitExceeded, e.Value, limitUnits[e.Limit], e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() []error {
+	if e.Read {
+		return []error{ErrLimitExceeded, ErrFileTooComplex}
+	}
+	return []error{ErrLimitExceeded, ErrOutOfRange}
+}
+
+// limitUnits names what each limit counts, for the error messages.
+var limitUnits = map[string]string{
+	"MaxRows":          "rows",
+	"MaxCols":          "columns",
+	"MaxCellChars":     "characters",
+	"MaxUniqueStrings": "unique strings",
+	"MaxSheets":        "sheets",
+	"MaxFileBytes":     "bytes",
+}
+
+// check returns a *LimitError if value exceeds most, the limit named limit.
+func check[T int | int64](limit string, value, most T, read bool) error {
+	if value <= most {
+		return nil
+	}
+	return &LimitError{Limit: limit, Value: int64(value), Max: int64(most), Read: read}
+}
+
+// checkText checks the text of the cell at row, col.
+func (l Limits) checkText(s string, row, col int, read bool) error {
+	// Every character takes at least one byte
+	if len(s) <= l.MaxCellChars {
+		return nil
+	}
+	if err := check("MaxCellChars", textLength(s), l.MaxCellChars, read); err != nil {
+		return fmt.Errorf("text at row %d, column %d: %w", row, col, err)
+	}
+	return nil
+}
+
+// checkCell checks the position of the cell at row, col.
+func (l Limits) checkCell(row, col int, read bool) error {
+	if err := check("MaxRows", row+1, l.MaxRows, read); err != nil {
+		return fmt.Errorf("cell (%d, %d): %w", row, col, err)
+	}
+	if err := check("MaxCols", col+1, l.MaxCols, read); err != nil {
+		return fmt.Errorf("cell (%d, %d): %w", row, col, err)
+	}
+	return nil
+}
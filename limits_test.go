@@ -0,0 +1,138 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// limitsWorkbook writes a workbook of three sheets of three rows and three
+// columns, holding three unique strings, the longest of six characters.
+func limitsWorkbook(t *testing.T, opts ...Option) ([]byte, error) {
+	t.Helper()
+	data := [][]interface{}{
+		{"abcdef", "x", "y"},
+		{1.0, 2.0, 3.0},
+		{4.0, 5.0, 6.0},
+	}
+	w := New(opts...)
+	if err := w.Write(data); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"Second", "Third"} {
+		s, err := w.AddSheet(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readLimits opens file with limits and reads its first sheet.
+func readLimits(file []byte, limits Limits) error {
+	wb, err := OpenReader(bytes.NewReader(file), int64(len(file)), WithReadLimits(limits))
+	if err != nil {
+		return err
+	}
+	return wb.ForEachRow(wb.Sheets()[0].Name, func(int, []Cell) error { return nil })
+}
+
+func TestLimits(t *testing.T) {
+	file, err := limitsWorkbook(t)
+	if err != nil {
+		t.Fatalf("limitsWorkbook() failed: %v", err)
+	}
+
+	tests := []struct {
+		limit  string
+		limits Limits
+	}{
+		{"MaxRows", Limits{MaxRows: 2}},
+		{"MaxCols", Limits{MaxCols: 2}},
+		{"MaxCellChars", Limits{MaxCellChars: 5}},
+		{"MaxUniqueStrings", Limits{MaxUniqueStrings: 2}},
+		{"MaxSheets", Limits{MaxSheets: 2}},
+		{"MaxFileBytes", Limits{MaxFileBytes: int64(len(file) - 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			for _, side := range []struct {
+				name string
+				err  error
+				kind error
+			}{
+				{"write", func() error { _, err := limitsWorkbook(t, WithLimits(tt.limits)); return err }(), ErrOutOfRange},
+				{"read", readLimits(file, tt.limits), ErrFileTooComplex},
+			} {
+				var limitErr *LimitError
+				if !errors.As(side.err, &limitErr) || limitErr.Limit != tt.limit {
+					t.Errorf("%s: expected a %s LimitError, got %v", side.name, tt.limit, side.err)
+					continue
+				}
+				if !errors.Is(side.err, ErrLimitExceeded) || !errors.Is(side.err, side.kind) {
+					t.Errorf("%s: expected ErrLimitExceeded and %v, got %v", side.name, side.kind, side.err)
+				}
+			}
+		})
+	}
+
+	// The workbook fits limits it reaches exactly
+	exact := Limits{MaxRows: 3, MaxCols: 3, MaxCellChars: 6, MaxUniqueStrings: 3, MaxSheets: 3, MaxFileBytes: int64(len(file))}
+	if _, err := limitsWorkbook(t, WithLimits(exact)); err != nil {
+		t.Errorf("Expected the workbook written within its limits, got %v", err)
+	}
+	if err := readLimits(file, exact); err != nil {
+		t.Errorf("Expected the workbook read within its limits, got %v", err)
+	}
+}
+
+func TestLimitsOpenFile(t *testing.T) {
+	file, err := limitsWorkbook(t)
+	if err != nil {
+		t.Fatalf("limitsWorkbook() failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "limits.xls")
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	small := WithReadLimits(Limits{MaxFileBytes: 1024})
+	if _, err := OpenFile(path, small); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("OpenFile: expected ErrLimitExceeded, got %v", err)
+	}
+	if _, err := OpenFileWithPassword(path, "secret", small); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("OpenFileWithPassword: expected ErrLimitExceeded, got %v", err)
+	}
+	if _, _, err := OpenFileRecover(path, small); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("OpenFileRecover: expected ErrLimitExceeded, got %v", err)
+	}
+	if _, _, err := OpenFileRecover(path, WithReadLimits(Limits{MaxSheets: 1})); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("OpenFileRecover: expected the sheets not salvaged past the limit, got %v", err)
+	}
+	if _, err := OpenFile(path); err != nil {
+		t.Errorf("OpenFile() failed: %v", err)
+	}
+}
+
+func TestLimitsResolve(t *testing.T) {
+	got := Limits{MaxRows: 1 << 20, MaxCols: 10, MaxFileBytes: -1}.resolve()
+	want := DefaultLimits()
+	want.MaxCols = 10
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	err := &LimitError{Limit: "MaxSheets", Value: 6, Max: 5, Read: true}
+	if msg := err.Error(); msg != "xls: limit exceeded: 6 sheets, more than MaxSheets 5" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+}
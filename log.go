@@ -0,0 +1,40 @@
+package xls
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// logWritten logs the CFB layout of a written file and a summary of the
+// records in its workbook stream.
+func (w *Writer) logWritten(streamSize, fileSize int) {
+	p := cfbLayout(streamSize, w.cfbSectorSize())
+	w.logger.Debug("xls: CFB sectors allocated",
+		"data", p.dataSectors, "fat", p.fatSectors, "difat", p.difatSectors, "directory", p.dirSectors)
+
+	types := make([]int, 0, len(w.recordCounts))
+	for recType := range w.recordCounts {
+		types = append(types, int(recType))
+	}
+	sort.Ints(types)
+
+	total := 0
+	counts := make([]any, 0, len(types))
+	for _, recType := range types {
+		name := RecordName(uint16(recType))
+		if name == "" {
+			name = fmt.Sprintf("0x%04X", recType)
+		}
+		count := w.recordCounts[uint16(recType)]
+		counts = append(counts, slog.Int(name, count))
+		total += count
+	}
+
+	w.logger.LogAttrs(context.Background(), slog.LevelDebug, "xls: workbook written",
+		slog.Int("stream_size", streamSize),
+		slog.Int("file_size", fileSize),
+		slog.Int("record_count", total),
+		slog.Group("records", counts...))
+}
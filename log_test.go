@@ -0,0 +1,60 @@
+package xls
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	w := New(WithLogger(logger), WithSheetName("Data"))
+	defer w.Close()
+	w.Write([][]interface{}{
+		{"a", "b", "a"},
+		{1, 2, 3},
+	})
+	w.Stats()
+	if logs.Len() != 0 {
+		t.Errorf("Expected Stats() not to log, got %q", logs.String())
+	}
+	if _, err := w.WriteTo(io.Discard); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	out := logs.String()
+	for _, want := range []string{
+		`msg="xls: SST built" strings=3 unique=2`,
+		`msg="xls: globals written" offset=0`,
+		`msg="xls: sheet written" sheet=Data start=`,
+		`msg="xls: CFB sectors allocated" data=8 fat=1 difat=0 directory=1`,
+		`msg="xls: workbook written"`,
+		`records.LABELSST=3`,
+		`records.NUMBER=3`,
+		`records.ROW=2`,
+		`records.BOF=2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", want, out)
+		}
+	}
+	if w.recordCounts != nil {
+		t.Error("Expected record counting to stop after the write")
+	}
+}
+
+func TestWithoutLogger(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.Write([][]interface{}{{"a", 1}})
+	if _, err := w.WriteTo(io.Discard); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if w.recordCounts != nil {
+		t.Error("Expected no record counting without a logger")
+	}
+}
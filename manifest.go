@@ -0,0 +1,199 @@
+package xls
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// manifestStream is the name of the stream WithEmbeddedManifest adds to the
+// compound file.
+const manifestStream = "_Manifest"
+
+// modulePath is the module path of the package, to find its version in the
+// build information.
+const modulePath = "github.com/tkuchiki/go-xls"
+
+// Manifest describes what a file written by a Writer contains, for tools
+// that check files without opening them in a spreadsheet application. It
+// is meant to be serialized with encoding/json.
+type Manifest struct {
+	Generator   string          `json:"generator"`    // Module path of the package
+	Version     string          `json:"version"`      // Module version, "(devel)" if unknown
+	GeneratedAt time.Time       `json:"generated_at"` // When the manifest was made
+	Styles      int             `json:"styles"`       // XF records of the styles used, past the built-in ones
+	Sheets      []SheetManifest `json:"sheets"`
+}
+
+// SheetManifest describes a sheet of a Manifest.
+type SheetManifest struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"` // One past the last used row, as in Dimensions
+	Cols int    `json:"cols"` // One past the last used column, as in Dimensions
+
+	// Headers holds the text of the last of the rows set with
+	// WithHeaderRows, the one naming the columns; nil without header rows.
+	Headers []string `json:"headers,omitempty"`
+
+	// Cells counts the cells written by kind, by the names of CellKind:
+	// "Text", "Number", "Bool", "Error", "Formula", and "Blank" for styled
+	// cells without a value. Dates are numbers.
+	Cells map[string]int `json:"cells"`
+}
+
+// WithEmbeddedManifest stores the Manifest of each file written in the
+// compound file, as a stream named "_Manifest" next to the Workbook stream,
+// holding its JSON. Excel and other readers ignore streams they do not
+// know; ReadManifest reads it back. As the manifest records when it was
+// made, files written with this option differ from one write to the next.
+// WorkbookStream returns the Workbook stream alone, without it.
+func WithEmbeddedManifest() Option {
+	return func(w *Writer) {
+		w.embedManifest = true
+	}
+}
+
+// Manifest returns the Manifest of the file the Writer would write now,
+// with the sheets of WithSheetSplitting. Like Stats, it does not serialize
+// the data.
+func (w *Writer) Manifest() Manifest {
+	if done, err := w.beginWrite(); err == nil {
+		defer done()
+	}
+	// An invalid style position fails the write; ignore it here
+	_ = w.prepareStyles()
+	return w.manifest(time.Now())
+}
+
+// ReadManifest returns the manifest stored with WithEmbeddedManifest in the
+// XLS file data.
+func ReadManifest(data []byte) (*Manifest, error) {
+	cfb, err := ReadCFB(data)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := cfb.Stream(manifestStream)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(stream, m); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidFormat, err)
+	}
+	return m, nil
+}
+
+// manifest returns the Manifest of the sheets of the Writer, made at now,
+// once their styles are prepared.
+func (w *Writer) manifest(now time.Time) Manifest {
+	m := Manifest{
+		Generator:   modulePath,
+		Version:     moduleVersion(),
+		GeneratedAt: now.UTC(),
+		Styles:      len(w.styles.xfs),
+		Sheets:      make([]SheetManifest, 0, len(w.sheets)),
+	}
+	for _, s := range w.sheets {
+		m.Sheets = append(m.Sheets, s.manifest())
+	}
+	return m
+}
+
+// manifest returns the SheetManifest of the sheet.
+func (s *SheetWriter) manifest() SheetManifest {
+	ext := s.sheetExtents()
+	sm := SheetManifest{Name: s.name, Rows: ext.lastRow, Cols: ext.lastCol, Cells: make(map[string]int)}
+	data := s.rowsData()
+	if n := s.w.headerRows; n > 0 && n <= len(data) {
+		row := data[n-1]
+		sm.Headers = make([]string, len(row))
+		for c, cell := range row {
+			sm.Headers[c] = s.headerText(n-1, c, cell)
+		}
+	}
+	for r, row := range data {
+		for c, cell := range row {
+			if cell != nil {
+				sm.Cells[s.writtenKind(r, c, cell).String()]++
+			}
+		}
+	}
+	for _, re := range ext.rows {
+		for c := re.firstCol; c < re.lastCol; c++ {
+			if !s.hasValue(re.row, c) && s.writesCellAt(re.row, c) {
+				sm.Cells[KindBlank.String()]++
+			}
+		}
+	}
+	return sm
+}
+
+// headerText returns the text of a header cell: its text as written, or the
+// value rendered as text.
+func (s *SheetWriter) headerText(row, col int, value interface{}) string {
+	if str, ok := s.textValue(row, col, value); ok {
+		return str
+	}
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	return renderValue(value, s.w.byteEncoding)
+}
+
+// writtenKind returns the kind of record writeCell writes for a value other
+// than nil, as the reader reports it.
+func (s *SheetWriter) writtenKind(row, col int, value interface{}) CellKind {
+	if _, ok := s.textValue(row, col, value); ok {
+		return KindText
+	}
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	switch v := value.(type) {
+	case bool:
+		if _, ok := s.boolNumber(col, v); ok {
+			return KindNumber
+		}
+		return KindBool
+	case CellError:
+		return KindError
+	case FormulaCell:
+		return KindFormula
+	}
+	return KindNumber
+}
+
+// moduleVersion returns the version of the package in the build
+// information of the program, "(devel)" if it is not known.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// manifestStreams returns the streams written next to the Workbook stream:
+// the manifest with WithEmbeddedManifest, none otherwise.
+func (w *Writer) manifestStreams() ([]NamedStream, error) {
+	if !w.embedManifest {
+		return nil, nil
+	}
+	data, err := json.Marshal(w.manifest(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the manifest: %w", err)
+	}
+	return []NamedStream{{Name: manifestStream, Data: data}}, nil
+}
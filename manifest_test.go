@@ -0,0 +1,151 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// manifestWriter returns a Writer with a cell of every kind on two sheets.
+func manifestWriter(opts ...Option) *Writer {
+	w := New(append([]Option{WithHeaderRows(1)}, opts...)...)
+	w.Write([][]interface{}{
+		{"Name", 2024, Styled("Total", Style{Font: Font{Bold: true}})},
+		{"a", 1.5, FormulaCell{Expr: "B2*2", Cached: 3.0}},
+		{"b", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), CellErrorDiv0},
+		{nil, true},
+	})
+	w.SetCellStyle(5, 1, Style{Fill: ColorRed})
+	s, _ := w.AddSheet("Empty")
+	s.SetColWidth(0, 20)
+	return w
+}
+
+func TestManifest(t *testing.T) {
+	w := manifestWriter()
+	before := time.Now().UTC()
+	m := w.Manifest()
+	if m.GeneratedAt.Before(before.Add(-time.Second)) || m.GeneratedAt.Location() != time.UTC {
+		t.Errorf("Expected the time of the call in UTC, got %v", m.GeneratedAt)
+	}
+	if m.Generator != modulePath || m.Version == "" {
+		t.Errorf("Expected the package and its version, got %q %q", m.Generator, m.Version)
+	}
+	if m.Styles != 2 {
+		t.Errorf("Expected 2 styles, got %d", m.Styles)
+	}
+	want := []SheetManifest{
+		{
+			Name: "Sheet1", Rows: 6, Cols: 3,
+			Headers: []string{"Name", "2024", "Total"},
+			Cells:   map[string]int{"Text": 4, "Number": 3, "Bool": 1, "Error": 1, "Formula": 1, "Blank": 1},
+		},
+		{Name: "Empty", Cells: map[string]int{}},
+	}
+	if !reflect.DeepEqual(m.Sheets, want) {
+		t.Errorf("Expected sheets %+v, got %+v", want, m.Sheets)
+	}
+
+	// The manifest survives JSON
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	var got Manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("Expected %+v after JSON, got %+v", m, got)
+	}
+
+	if headers := New().Manifest().Sheets[0].Headers; headers != nil {
+		t.Errorf("Expected no headers without WithHeaderRows, got %q", headers)
+	}
+}
+
+func TestManifestCellsMatchFile(t *testing.T) {
+	w := manifestWriter(WithBoolRendering(BoolNumeric()), WithNumberConversion(func(string) bool { return true }))
+	w.SetCell(4, 2, "42")
+	counts := w.Manifest().Sheets[0].Cells
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	read := make(map[string]int)
+	for _, row := range wb.Sheets()[0].Rows() {
+		for _, c := range row {
+			if c.Kind != KindBlank || c.xf != 0 {
+				read[c.Kind.String()]++
+			}
+		}
+	}
+	if !maps.Equal(counts, read) {
+		t.Errorf("Expected the cells of the file %v, got %v", read, counts)
+	}
+}
+
+func TestWithEmbeddedManifest(t *testing.T) {
+	w := manifestWriter(WithEmbeddedManifest())
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	got, err := ReadManifest(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadManifest() failed: %v", err)
+	}
+	want := w.Manifest()
+	if got.GeneratedAt.IsZero() {
+		t.Error("Expected the time of the write")
+	}
+	got.GeneratedAt = want.GeneratedAt
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Expected %+v, got %+v", want, *got)
+	}
+
+	cfb, err := ReadCFB(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+	var names []string
+	for _, e := range cfb.Entries() {
+		if e.ObjectType == CFBObjectStream {
+			names = append(names, e.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"Workbook", manifestStream}) {
+		t.Errorf("Expected the Workbook and manifest streams, got %q", names)
+	}
+
+	// Readers of the workbook ignore the stream
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	plain := readBack(t, manifestWriter())
+	if sheet := wb.Sheets()[0]; !reflect.DeepEqual(sheet.Rows(), plain.Rows()) {
+		t.Error("Expected the cells of the file written without the manifest")
+	}
+	plainCFB, err := ReadCFB(writtenBytes(t, manifestWriter()))
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+	workbook, _ := cfb.Stream("Workbook")
+	if stream, _ := plainCFB.Stream("Workbook"); !bytes.Equal(workbook, stream) {
+		t.Error("Expected the Workbook stream to be unchanged")
+	}
+
+	if _, err := ReadManifest(writtenBytes(t, manifestWriter())); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat without a manifest, got %v", err)
+	}
+}
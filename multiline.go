@@ -0,0 +1,47 @@
+package xls
+
+import "strings"
+
+// byteOrderMark is the UTF-8 byte order mark some CSV exports leave at the
+// start of their first field.
+const byteOrderMark = "\uFEFF"
+
+// WithAutoWrapMultiline sets whether string cells with line breaks are
+// given the wrap flag, so Excel shows them on several lines instead of as
+// one line with boxes for the breaks. It is on by default; a cell style
+// with WrapText set wraps whatever this option says. Either way, the CRLF
+// and CR line breaks of string cells are written as LF, the break Excel
+// expects, and a leading byte order mark is dropped.
+func WithAutoWrapMultiline(on bool) Option {
+	return func(w *Writer) {
+		w.noAutoWrap = !on
+	}
+}
+
+// cleanText returns the text of a string cell as it is written: without a
+// leading byte order mark, and with CRLF and CR line breaks turned into LF.
+// A leading 0xFF byte, written as U+FFFD anyway, is U+FFFD, as unmarked
+// makes it.
+func cleanText(s string) string {
+	s = unmarked(strings.TrimPrefix(s, byteOrderMark))
+	if strings.IndexByte(s, '\r') < 0 {
+		return s
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
+
+// wrapsText reports whether a cell value, without its StyledCell, is a
+// string or rich text with a line break that WithAutoWrapMultiline wraps.
+func (w *Writer) wrapsText(value interface{}) bool {
+	if w.noAutoWrap {
+		return false
+	}
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	if rt, ok := value.(RichText); ok {
+		value = rt.Text
+	}
+	str, ok := value.(string)
+	return ok && strings.ContainsAny(str, "\r\n")
+}
@@ -0,0 +1,88 @@
+package xls
+
+import (
+	"bytes"
+	"testing"
+)
+
+// wraps reports whether the cell at (row, col) of records has an XF with
+// the wrap flag.
+func wraps(records []Record, row, col int) bool {
+	xf, ok := cellXFs(records)[[2]int{row, col}]
+	return ok && recordsOfType(records, recTypeXF)[xf].Data[6]&0x08 != 0
+}
+
+func TestMultilineText(t *testing.T) {
+	bold := Style{Font: Font{Bold: true}}
+	data := [][]interface{}{
+		{"Line 1\r\nLine 2", "a\rb", "\uFEFFID", Styled("x\ny", bold), "plain", Text("t\r\n")},
+	}
+	w := New(WithPostWriteVerification())
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	records := writtenRecords(t, w)
+
+	sst := recordsOfType(records, recTypeSST)[0].Data
+	for _, str := range []string{"Line 1\nLine 2", "a\nb", "ID", "x\ny", "t\n"} {
+		if !bytes.Contains(sst, utf16le(str)) {
+			t.Errorf("Expected %q in the SST", str)
+		}
+	}
+	for _, str := range []string{"\r\n", "a\rb", "\uFEFF"} {
+		if bytes.Contains(sst, utf16le(str)) {
+			t.Errorf("Expected no %q in the SST", str)
+		}
+	}
+	if total, unique := sstCounts(t, writtenBytes(t, w)); total != 6 || unique != 6 {
+		t.Errorf("Expected 6 strings, got %d of %d", unique, total)
+	}
+
+	for col, want := range []bool{true, true, false, true, false, true} {
+		if got := wraps(records, 0, col); got != want {
+			t.Errorf("Column %d: expected wrap %v, got %v", col, want, got)
+		}
+	}
+	if st := w.styles.xfs[cellXFs(records)[[2]int{0, 3}]-firstCustomXF]; !st.Font.Bold {
+		t.Errorf("Expected the styled cell to keep its style, got %+v", st)
+	}
+	if got := readBack(t, w).Cell(0, 0).Value; got != "Line 1\nLine 2" {
+		t.Errorf("Expected LF line breaks, got %q", got)
+	}
+
+	// The wrap flag can be left off
+	off := New(WithAutoWrapMultiline(false))
+	off.Write(data)
+	records = writtenRecords(t, off)
+	for col := range data[0] {
+		if wraps(records, 0, col) {
+			t.Errorf("Column %d: expected no wrap flag", col)
+		}
+	}
+	if got := readBack(t, off).Cell(0, 1).Value; got != "a\nb" {
+		t.Errorf("Expected LF line breaks without the wrap flag, got %q", got)
+	}
+}
+
+func TestMultilineColumns(t *testing.T) {
+	w := New(WithPostWriteVerification())
+	w.PredeclareStrings([]string{"Total\r\nAmount", "a\r\nb", "c"})
+	err := w.WriteColumns([]string{"Total\nAmount"}, []Column{
+		StringColumn{Values: []string{"a\r\nb", "\uFEFFc"}},
+	})
+	if err != nil {
+		t.Fatalf("WriteColumns() failed: %v", err)
+	}
+	records := writtenRecords(t, w)
+	for row, want := range []bool{true, true, false} {
+		if got := wraps(records, row, 0); got != want {
+			t.Errorf("Row %d: expected wrap %v, got %v", row, want, got)
+		}
+	}
+	sheet := readBack(t, w)
+	for row, want := range []string{"Total\nAmount", "a\nb", "c"} {
+		if got := sheet.Cell(row, 0).Value; got != want {
+			t.Errorf("Row %d: expected %q, got %q", row, want, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package xls
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormForm is a Unicode normalization form for NormOptions.
+type NormForm int
+
+// Unicode normalization forms
+const (
+	NormNone NormForm = iota // Leave the code points as they are
+	NormNFC                  // Canonical composition, joining letters and their combining marks
+	NormNFKC                 // Compatibility composition, also folding ligatures and full-width forms
+)
+
+// NormOptions selects how WithStringNormalization rewrites strings. The
+// steps run in the order of the fields.
+type NormOptions struct {
+	// ReplaceNBSP replaces no-break spaces (U+00A0, U+2007 and U+202F) with
+	// plain spaces.
+	ReplaceNBSP bool
+
+	// Form normalizes the code points to NFC or NFKC.
+	Form NormForm
+
+	// CollapseInnerWhitespace replaces each run of white space, such as
+	// tabs, newlines and repeated spaces, with a single space.
+	CollapseInnerWhitespace bool
+
+	// TrimSpace removes leading and trailing white space.
+	TrimSpace bool
+
+	// KeepText leaves Text values as they are, so a string wrapped in Text
+	// is written exactly as given.
+	KeepText bool
+}
+
+// WithStringNormalization makes Write, SetCell and computed columns
+// normalize string cells, including styled ones, before they are stored,
+// so strings that only differ in white space or Unicode form are written
+// as one and share an entry of the shared string table. Normalization
+// runs before WithFormulaEscaping and number conversion, so "  42 " with
+// TrimSpace converts to a number. FormulaCell values and values other
+// than strings and Text are not changed.
+func WithStringNormalization(opts NormOptions) Option {
+	return func(w *Writer) {
+		w.norm = &opts
+	}
+}
+
+// normalizeStrings returns data with its strings normalized, copying the
+// rows that change. The outer slice of the result is always a copy when it
+// differs from data.
+func (s *SheetWriter) normalizeStrings(data [][]interface{}) ([][]interface{}, bool) {
+	out, owned := data, false
+	for r, row := range data {
+		var normalized []interface{}
+		for c, cell := range row {
+			value, ok := s.w.normalizeCell(cell)
+			if !ok {
+				continue
+			}
+			if normalized == nil {
+				normalized = append([]interface{}(nil), row...)
+			}
+			normalized[c] = value
+		}
+		if normalized == nil {
+			continue
+		}
+		if !owned {
+			out, owned = append([][]interface{}(nil), data...), true
+		}
+		out[r] = normalized
+	}
+	return out, owned
+}
+
+// normalizeCell returns a string or Text cell normalized, and false for
+// the cells that are left as they are. Styled cells keep their style.
+func (w *Writer) normalizeCell(cell interface{}) (interface{}, bool) {
+	switch v := cell.(type) {
+	case StyledCell:
+		value, ok := w.normalizeCell(v.Value)
+		v.Value = value
+		return v, ok
+	case string:
+		str := w.norm.apply(v)
+		return str, str != v
+	case Text:
+		if w.norm.KeepText {
+			return cell, false
+		}
+		str := w.norm.apply(string(v))
+		return Text(str), str != string(v)
+	}
+	return cell, false
+}
+
+// apply returns s normalized by the options.
+func (o *NormOptions) apply(s string) string {
+	if o.ReplaceNBSP {
+		s = strings.Map(func(r rune) rune {
+			switch r {
+			case '\u00a0', '\u2007', '\u202f':
+				return ' '
+			}
+			return r
+		}, s)
+	}
+	switch o.Form {
+	case NormNFC:
+		s = norm.NFC.String(s)
+	case NormNFKC:
+		s = norm.NFKC.String(s)
+	}
+	if o.CollapseInnerWhitespace {
+		s = collapseSpace(s)
+	}
+	if o.TrimSpace {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// collapseSpace replaces each run of white space in s with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package xls
+
+import "testing"
+
+func TestNormOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts NormOptions
+		in   string
+		want string
+	}{
+		{"none", NormOptions{}, " a b\t", " a b\t"},
+		{"nbsp", NormOptions{ReplaceNBSP: true}, "a\u00a0b\u2007c\u202fd", "a b c d"},
+		{"nfc", NormOptions{Form: NormNFC}, "Cafe\u0301", "Caf\u00e9"},
+		{"nfc keeps ligatures", NormOptions{Form: NormNFC}, "\ufb01le", "\ufb01le"},
+		{"nfkc", NormOptions{Form: NormNFKC}, "\ufb01le \uff21", "file A"},
+		{"collapse", NormOptions{CollapseInnerWhitespace: true}, "\ta  b\n\nc ", " a b c "},
+		{"trim", NormOptions{TrimSpace: true}, " \ta  b\t\t", "a  b"},
+		{"all", NormOptions{ReplaceNBSP: true, Form: NormNFC, CollapseInnerWhitespace: true, TrimSpace: true}, "\u00a0Jose\u0301 \u00a0 Garci\u0301a\t", "Jos\u00e9 Garc\u00eda"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.apply(tt.in); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithStringNormalization(t *testing.T) {
+	data := [][]interface{}{
+		{"Tokyo", "Tokyo\u00a0", "Tokyo\t", "  Tokyo"},
+		{"Caf\u00e9", "Cafe\u0301", Styled("Cafe\u0301 ", Style{Font: Font{Bold: true}}), Text("Cafe\u0301")},
+		{FormulaCell{Expr: `"a"&"b"`, Cached: "ab "}, 1.5, " 42 ", nil},
+	}
+	opts := NormOptions{ReplaceNBSP: true, Form: NormNFC, TrimSpace: true}
+
+	file := func(opts ...Option) []byte {
+		w := New(opts...)
+		if err := w.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		return writtenBytes(t, w)
+	}
+	if _, unique := sstCounts(t, file()); unique != 8 {
+		t.Errorf("Expected 8 unique strings without normalization, got %d", unique)
+	}
+	if _, unique := sstCounts(t, file(WithStringNormalization(opts))); unique != 3 {
+		t.Errorf("Expected 3 unique strings once normalized, got %d", unique)
+	}
+	keep := opts
+	keep.KeepText = true
+	if _, unique := sstCounts(t, file(WithStringNormalization(keep))); unique != 4 {
+		t.Errorf("Expected the Text value kept apart, got %d unique strings", unique)
+	}
+
+	w := New(WithStringNormalization(opts), WithAutoNumberConversion())
+	w.Write(data)
+	w.SetCell(3, 0, "Tokyo  ")
+	sheet := readBack(t, w)
+	for _, c := range []struct {
+		row, col int
+		want     interface{}
+	}{
+		{0, 1, "Tokyo"},
+		{1, 1, "Caf\u00e9"},
+		{1, 2, "Caf\u00e9"},
+		{2, 2, 42.0},
+		{3, 0, "Tokyo"},
+	} {
+		if got := sheet.Cell(c.row, c.col).Value; got != c.want {
+			t.Errorf("Cell (%d, %d): expected %v, got %v", c.row, c.col, c.want, got)
+		}
+	}
+	if !cellStyleOf(w, sheet.Cell(1, 2)).Font.Bold {
+		t.Error("Expected the styled cell to keep its style")
+	}
+	if data[0][1] != "Tokyo\u00a0" {
+		t.Error("Expected the data passed to Write to be left as it is")
+	}
+}
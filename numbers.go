@@ -0,0 +1,331 @@
+package xls
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxConvertibleDigits is the most digits a string converted to a number by
+// IsConvertibleNumber can have: Excel keeps 15 significant digits, so longer
+// digit strings, such as card and account numbers, would lose digits.
+const maxConvertibleDigits = 15
+
+// WithAutoNumberConversion writes string cells that IsConvertibleNumber
+// accepts as numbers, so Excel does not mark them as numbers stored as text.
+// Styled string cells keep their style. It is off by default.
+func WithAutoNumberConversion() Option {
+	return WithNumberConversion(IsConvertibleNumber)
+}
+
+// WithNumberConversion writes the string cells for which convert returns
+// true as numbers. convert is only called with strings that
+// strconv.ParseFloat parses to a finite number, after WithNumberLocale
+// replaced their separators; other strings stay text. Use it to keep
+// columns of identifiers as text, or to widen the rules of
+// IsConvertibleNumber.
+func WithNumberConversion(convert func(s string) bool) Option {
+	return func(w *Writer) {
+		w.convert = convert
+	}
+}
+
+// IsConvertibleNumber reports whether s is a plain decimal number that is
+// safe to store as a number: an optional minus sign, digits and an optional
+// decimal point. It rejects strings that are more likely identifiers than
+// quantities, so that they stay text:
+//
+//   - a leading zero before another digit, as in ZIP codes ("01234")
+//   - a leading plus sign, as in phone numbers ("+15551234")
+//   - more than 15 digits, which Excel cannot hold exactly
+//   - exponents ("1E5"), which part numbers and gene names look like
+//   - spaces, thousands separators, and the infinities, NaN and
+//     hexadecimal forms strconv.ParseFloat also accepts
+func IsConvertibleNumber(s string) bool {
+	digits, intDigits, point := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '-' && i == 0:
+		case c == '.' && !point:
+			point = true
+		case '0' <= c && c <= '9':
+			digits++
+			if !point {
+				intDigits++
+				if intDigits == 2 && s[i-1] == '0' {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+	return digits > 0 && digits <= maxConvertibleDigits
+}
+
+// numberText returns the number a string cell is written as, if number
+// conversion is enabled and accepts it.
+func (w *Writer) numberText(value interface{}) (float64, bool) {
+	if w.convert == nil {
+		return 0, false
+	}
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	if w.locale != nil {
+		if s, ok = w.locale.normalize(s); !ok {
+			return 0, false
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) || !w.convert(s) {
+		return 0, false
+	}
+	return f, true
+}
+
+// textValue is sstString for a cell of the sheet: strings converted to
+// numbers are not text, and the numbers and bools of renderedText are.
+func (s *SheetWriter) textValue(row, col int, value interface{}) (string, bool) {
+	if str, ok := s.renderedText(row, col, value); ok {
+		return str, true
+	}
+	if _, ok := s.w.numberText(value); ok {
+		return "", false
+	}
+	return sstString(value)
+}
+
+// cellText is textValue for a cell writeCell writes: it also returns the
+// error that fails the cell, for text longer than the Limits allow, a value
+// of another type under WithStrictTypes, a string not declared with
+// PredeclareStrings or rich text with runs out of order. Rich text with
+// runs is returned as its key, which richKey makes.
+func (s *SheetWriter) cellText(row, col int, value interface{}) (string, bool, error) {
+	str, ok := s.renderedText(row, col, value)
+	if !ok {
+		if _, ok := s.w.numberText(value); ok {
+			return "", false, nil
+		}
+		if str, ok = sstString(value); !ok {
+			return "", false, nil
+		}
+		plain := value
+		if sc, styled := plain.(StyledCell); styled {
+			plain = sc.Value
+		}
+		switch plain.(type) {
+		case string, RichText:
+		default:
+			if s.w.strict {
+				return "", false, fmt.Errorf("%w: %T at row %d, column %d", ErrUnsupportedCellType, plain, row, col)
+			}
+		}
+	}
+	str = unmarked(str)
+	if err := s.w.limits.checkText(str, row, col, false); err != nil {
+		return "", false, err
+	}
+	if s.w.dict != nil {
+		if err := s.w.dict.check(str, row, col); err != nil {
+			return "", false, err
+		}
+		if err := s.w.dict.checkRich(value, row, col); err != nil {
+			return "", false, err
+		}
+	}
+	if rt, rich := richText(value); rich {
+		runs, err := s.richRuns(row, col, value, rt)
+		if err != nil {
+			return "", false, fmt.Errorf("%w at row %d, column %d", err, row, col)
+		}
+		if len(runs) > 0 {
+			str = richKey(str, runs)
+		}
+	}
+	return str, true, nil
+}
+
+// convertedData returns the data of the sheet with the string cells
+// converted to numbers, the numbers and bools of renderedText converted to
+// text and the bools of BoolNumeric to numbers, as they are written,
+// copying only the rows that change, for verification.
+func (s *SheetWriter) convertedData() [][]interface{} {
+	data := s.rowsData()
+	if s.w.convert == nil && s.w.general.Mode != GeneralText && s.w.bools.mode <= boolNative && s.boolColumns == nil && s.w.byteEncoding == BytesList {
+		return data
+	}
+	var converted [][]interface{}
+	for r, row := range data {
+		var out []interface{}
+		for c, cell := range row {
+			var value interface{}
+			if str, ok := s.renderedText(r, c, cell); ok {
+				value = str
+			} else if f, ok := s.boolNumber(c, cell); ok {
+				value = f
+			} else if f, ok := s.w.numberText(cell); ok {
+				value = f
+			} else {
+				continue
+			}
+			if out == nil {
+				out = append([]interface{}(nil), row...)
+			}
+			if sc, styled := cell.(StyledCell); styled {
+				sc.Value = value
+				out[c] = sc
+			} else {
+				out[c] = value
+			}
+		}
+		if out == nil {
+			continue
+		}
+		if converted == nil {
+			converted = append([][]interface{}(nil), data...)
+		}
+		converted[r] = out
+	}
+	if converted == nil {
+		return data
+	}
+	return converted
+}
+
+// numberLocale holds the separators of numbers written as text in a locale.
+type numberLocale struct {
+	decimal, group rune
+}
+
+// numberLocales maps language tags, and the base languages that stand for
+// their usual regions, to their separators.
+var numberLocales = map[string]numberLocale{
+	"en":    {'.', ','},
+	"ja":    {'.', ','},
+	"ko":    {'.', ','},
+	"zh":    {'.', ','},
+	"es-mx": {'.', ','},
+	"de":    {',', '.'},
+	"es":    {',', '.'},
+	"it":    {',', '.'},
+	"nl":    {',', '.'},
+	"pt":    {',', '.'},
+	"id":    {',', '.'},
+	"tr":    {',', '.'},
+	"da":    {',', '.'},
+	"de-ch": {'.', '\''},
+	"fr":    {',', ' '},
+	"sv":    {',', ' '},
+	"nb":    {',', ' '},
+	"fi":    {',', ' '},
+	"pl":    {',', ' '},
+	"cs":    {',', ' '},
+	"sk":    {',', ' '},
+	"hu":    {',', ' '},
+	"ru":    {',', ' '},
+	"uk":    {',', ' '},
+}
+
+// WithNumberLocale makes number conversion read strings with the decimal
+// and group separators of the language tag lang, such as "de-DE" for
+// "1.234,56" or "fr-FR" for "1 234,56". Separators are applied strictly:
+// "1,234" is 1.234 in de-DE and 1234 in en-US, and a group separator must be
+// followed by exactly three digits. A tag that is not known fails the
+// write. It has no effect without WithAutoNumberConversion or
+// WithNumberConversion.
+func WithNumberLocale(lang string) Option {
+	return func(w *Writer) {
+		tag := strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+		loc, ok := numberLocales[tag]
+		if !ok {
+			base, _, _ := strings.Cut(tag, "-")
+			loc, ok = numberLocales[base]
+		}
+		if !ok {
+			w.locale, w.localeErr = nil, fmt.Errorf("unknown number locale %q", lang)
+			return
+		}
+		w.locale, w.localeErr = &loc, nil
+	}
+}
+
+// WithNumberSeparators is WithNumberLocale with explicit separators. A
+// space group separator also matches the no-break spaces U+00A0 and U+202F.
+func WithNumberSeparators(decimal, group rune) Option {
+	return func(w *Writer) {
+		if decimal == group || unicode.IsDigit(decimal) || unicode.IsDigit(group) || decimal == '-' || group == '-' {
+			w.locale, w.localeErr = nil, fmt.Errorf("invalid number separators %q and %q", decimal, group)
+			return
+		}
+		w.locale, w.localeErr = &numberLocale{decimal, group}, nil
+	}
+}
+
+func (l *numberLocale) isGroup(r rune) bool {
+	return r == l.group || l.group == ' ' && (r == '\u00A0' || r == '\u202F')
+}
+
+// normalize returns s with the separators of the locale replaced by those
+// strconv.ParseFloat reads, or false if s uses them in a way the locale
+// does not: a decimal or group separator after the decimal separator, a
+// group of other than three digits, or a period or comma that is neither.
+func (l *numberLocale) normalize(s string) (string, bool) {
+	for _, r := range s {
+		if (r == '.' || r == ',') && r != l.decimal && !l.isGroup(r) {
+			return "", false
+		}
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, hasDecimal := strings.Cut(s, string(l.decimal))
+	if strings.ContainsFunc(frac, func(r rune) bool { return r == l.decimal || l.isGroup(r) }) {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.Grow(len(sign) + len(s))
+	b.WriteString(sign)
+	groups := 0
+	for {
+		i := strings.IndexFunc(intPart, l.isGroup)
+		if i < 0 {
+			break
+		}
+		group := intPart[:i]
+		if groups == 0 && (len(group) < 1 || len(group) > 3) || groups > 0 && len(group) != 3 || !isDigits(group) {
+			return "", false
+		}
+		b.WriteString(group)
+		_, size := utf8.DecodeRuneInString(intPart[i:])
+		intPart = intPart[i+size:]
+		groups++
+	}
+	if groups > 0 && (len(intPart) != 3 || !isDigits(intPart)) {
+		return "", false
+	}
+	b.WriteString(intPart)
+	if hasDecimal {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String(), true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package xls
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsConvertibleNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		// Numbers
+		{"0", true},
+		{"42", true},
+		{"-42", true},
+		{"3.14", true},
+		{"-0.5", true},
+		{"0.001", true},
+		{".5", true},
+		{"10.", true},
+		{"123456789012345", true},
+		{"1234567890.12345", true},
+
+		// Leading zeros: ZIP codes and zero-padded IDs
+		{"01234", false},
+		{"00", false},
+		{"-012", false},
+		{"007.5", false},
+
+		// Leading plus: phone numbers
+		{"+1", false},
+		{"+15551234567", false},
+
+		// More than 15 digits: card and account numbers
+		{"4111111111111111", false},
+		{"1234567890.123456", false},
+		{"0.0000000000000001", false},
+
+		// Exponents
+		{"1e5", false},
+		{"1E5", false},
+		{"2E10", false},
+
+		// Forms strconv accepts but Excel does not read as plain numbers
+		{"Inf", false},
+		{"-Infinity", false},
+		{"NaN", false},
+		{"0x1Fp0", false},
+		{"1_000", false},
+
+		// Not numbers
+		{"", false},
+		{"-", false},
+		{".", false},
+		{"-.", false},
+		{"1.2.3", false},
+		{"1-2", false},
+		{"1,000", false},
+		{" 42", false},
+		{"42 ", false},
+		{"$5", false},
+		{"5%", false},
+		{"１２", false}, // Fullwidth digits
+	}
+	for _, tt := range tests {
+		if got := IsConvertibleNumber(tt.s); got != tt.want {
+			t.Errorf("IsConvertibleNumber(%q): expected %v, got %v", tt.s, tt.want, got)
+		}
+	}
+}
+
+func TestAutoNumberConversion(t *testing.T) {
+	bold := Style{Font: Font{Bold: true}}
+	data := [][]interface{}{
+		{"qty", "zip", "price"},
+		{"3", "01234", Styled("1.5", bold)},
+		{"-4", "94103", "n/a"},
+	}
+	w := New(WithAutoNumberConversion(), WithPostWriteVerification())
+	defer w.Close()
+	w.Write(data)
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to read the file: %v", err)
+	}
+	s := wb.Sheets()[0]
+	for _, tt := range []struct {
+		row, col int
+		want     interface{}
+	}{
+		{1, 0, 3.0},
+		{1, 1, "01234"},
+		{1, 2, 1.5},
+		{2, 0, -4.0},
+		{2, 1, 94103.0},
+		{2, 2, "n/a"},
+	} {
+		if got := s.Cell(tt.row, tt.col).Value; got != tt.want {
+			t.Errorf("Cell(%d, %d): expected %#v, got %#v", tt.row, tt.col, tt.want, got)
+		}
+	}
+	if c := s.Cell(1, 2); c.xf < firstCustomXF {
+		t.Error("Expected the converted styled cell to keep its style")
+	}
+	if st := w.Stats(); st.Strings != 5 {
+		t.Errorf("Expected only the text in the SST, got %d strings", st.Strings)
+	}
+	if data[1][0] != "3" {
+		t.Error("Expected the data to be left unchanged")
+	}
+}
+
+func TestNumberConversionPredicate(t *testing.T) {
+	// IDs starting with 9 stay text; "Inf" never converts
+	w := New(WithNumberConversion(func(s string) bool {
+		return !strings.HasPrefix(s, "9")
+	}))
+	defer w.Close()
+	w.Write([][]interface{}{{"1e3", "912", "Inf", "0x10"}})
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, _ := openWorkbook(buf.Bytes())
+	want := []interface{}{1000.0, "912", "Inf", "0x10"}
+	for c, v := range want {
+		if got := wb.Sheets()[0].Cell(0, c).Value; got != v {
+			t.Errorf("Cell(0, %d): expected %#v, got %#v", c, v, got)
+		}
+	}
+}
+
+func TestNumberConversionConcurrent(t *testing.T) {
+	build := func(n int) []byte {
+		w := New(WithAutoNumberConversion(), WithHybridStrings(2), WithConcurrency(n))
+		w.Write([][]interface{}{{"1", "a", "2"}, {"a", "b", "1"}})
+		s, _ := w.AddSheet("Other")
+		s.Write([][]interface{}{{"b", "3", "c"}})
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	if !bytes.Equal(build(1), build(2)) {
+		t.Error("Expected the same file written concurrently")
+	}
+}
+
+func TestNumberConversionWarnings(t *testing.T) {
+	var warnings []Warning
+	w := New(WithAutoNumberConversion(), WithDataWarnings(func(warning Warning) {
+		warnings = append(warnings, warning)
+	}))
+	defer w.Close()
+	w.Write([][]interface{}{{"42", "007"}})
+	w.WriteTo(&bytes.Buffer{})
+	want := Warning{Kind: WarningNumberAsText, Cell: CellRef{Sheet: "Sheet1", Col: 1}, Count: 1}
+	if len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("Expected only %v, got %v", want, warnings)
+	}
+}
+
+func TestNumberLocale(t *testing.T) {
+	tests := []struct {
+		lang string
+		s    string
+		want interface{}
+	}{
+		{"de-DE", "1.234,56", 1234.56},
+		{"de-DE", "1,5", 1.5},
+		{"de-DE", "-0,25", -0.25},
+		{"de-DE", "1.234", 1234.0},
+		{"de-DE", "1,234", 1.234},
+		{"de-DE", "1.234.567", 1234567.0},
+		{"de-DE", "1.5", "1.5"},
+		{"de-DE", "12.34", "12.34"},
+		{"de-DE", "1.234,5.6", "1.234,5.6"},
+		{"de-DE", "1234.567,8", "1234.567,8"},
+		{"de-DE", "01,5", "01,5"},
+		{"de-AT", "2,5", 2.5},
+
+		{"fr-FR", "1 234,56", 1234.56},
+		{"fr-FR", "1\u00A0234,56", 1234.56},
+		{"fr-FR", "1\u202F234\u202F567", 1234567.0},
+		{"fr-FR", "1,5", 1.5},
+		{"fr-FR", "1.5", "1.5"},
+		{"fr-FR", "1 23,4", "1 23,4"},
+		{"fr-FR", "1  234", "1  234"},
+
+		{"en-US", "1,234.56", 1234.56},
+		{"en-US", "1,234", 1234.0},
+		{"en-US", "1.5", 1.5},
+		{"en-US", "1,5", "1,5"},
+		{"en-US", "1.234,56", "1.234,56"},
+		{"en-US", ",234", ",234"},
+		{"en-US", "1,234,", "1,234,"},
+		{"en_GB", "12,345,678.9", 12345678.9},
+
+		{"de-CH", "1'234.50", 1234.5},
+	}
+	for _, tt := range tests {
+		w := New(WithAutoNumberConversion(), WithNumberLocale(tt.lang))
+		w.Write([][]interface{}{{tt.s}})
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		wb, _ := openWorkbook(buf.Bytes())
+		if got := wb.Sheets()[0].Cell(0, 0).Value; got != tt.want {
+			t.Errorf("%s %q: expected %#v, got %#v", tt.lang, tt.s, tt.want, got)
+		}
+	}
+}
+
+func TestNumberSeparators(t *testing.T) {
+	w := New(WithAutoNumberConversion(), WithNumberSeparators(',', '_'), WithPostWriteVerification())
+	w.Write([][]interface{}{{"1_234,5", "1.5"}})
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, _ := openWorkbook(buf.Bytes())
+	if got := wb.Sheets()[0].Cell(0, 0).Value; got != 1234.5 {
+		t.Errorf("Expected 1234.5, got %#v", got)
+	}
+	if got := wb.Sheets()[0].Cell(0, 1).Value; got != "1.5" {
+		t.Errorf("Expected \"1.5\" kept as text, got %#v", got)
+	}
+
+	for _, opt := range []Option{WithNumberLocale("xx-YY"), WithNumberSeparators('.', '.'), WithNumberSeparators('1', ',')} {
+		w := New(WithAutoNumberConversion(), opt)
+		w.Write([][]interface{}{{"1"}})
+		if _, err := w.WriteTo(&bytes.Buffer{}); err == nil {
+			t.Error("Expected an error for an invalid number locale")
+		}
+	}
+}
@@ -0,0 +1,815 @@
+package xls
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormattedValue returns the cell's value as Excel displays it with the
+// cell's number format: numbers are rendered with the format's sections,
+// digit placeholders, separators, percent, exponent and fraction tokens and
+// literal text, dates and times with their date/time tokens, and text with
+// the text section. Colors are ignored and padding renders as one space; the
+// column width plays no part. Booleans render as TRUE and FALSE, errors by
+// name and blank cells as the empty string.
+func (c Cell) FormattedValue() string {
+	format := c.FormatString
+	if format == "" {
+		format = "General"
+	}
+	switch v := c.Value.(type) {
+	case float64:
+		return formatNumber(v, format, c.date1904)
+	case string:
+		return formatText(v, format)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case CellError:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+// splitSections splits a number format into its sections at the semicolons
+// outside quoted text, escapes and brackets.
+func splitSections(format string) []string {
+	var sections []string
+	start := 0
+	for i := 0; i < len(format); i++ {
+		switch format[i] {
+		case '"':
+			for i++; i < len(format) && format[i] != '"'; i++ {
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			if end := strings.IndexByte(format[i:], ']'); end >= 0 {
+				i += end
+			}
+		case ';':
+			sections = append(sections, format[start:i])
+			start = i + 1
+		}
+	}
+	return append(sections, format[start:])
+}
+
+// sectionCondition returns the condition of a section, such as [>=100], if it
+// has one.
+func sectionCondition(section string) (op string, value float64, ok bool) {
+	for i := 0; i < len(section); i++ {
+		switch section[i] {
+		case '"':
+			for i++; i < len(section) && section[i] != '"'; i++ {
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			end := strings.IndexByte(section[i:], ']')
+			if end < 0 {
+				return "", 0, false
+			}
+			cond := section[i+1 : i+end]
+			n := 0
+			for n < len(cond) && n < 2 && strings.IndexByte("<>=", cond[n]) >= 0 {
+				n++
+			}
+			if n > 0 {
+				v, err := strconv.ParseFloat(strings.TrimSpace(cond[n:]), 64)
+				return cond[:n], v, err == nil
+			}
+			i += end
+		}
+	}
+	return "", 0, false
+}
+
+// matches reports whether v satisfies a section condition.
+func matches(v float64, op string, value float64) bool {
+	switch op {
+	case "<":
+		return v < value
+	case "<=", "=<":
+		return v <= value
+	case ">":
+		return v > value
+	case ">=", "=>":
+		return v >= value
+	case "=":
+		return v == value
+	case "<>":
+		return v != value
+	}
+	return false
+}
+
+// numberSection picks the section of a format that displays v. It reports
+// whether the section supplies its own sign, in which case v is rendered
+// without a minus sign.
+func numberSection(v float64, sections []string) (string, bool) {
+	// A text section does not display numbers
+	if n := len(sections); n > 1 && n < 4 && strings.Contains(sections[n-1], "@") && !strings.ContainsAny(sections[n-1], "0#?") {
+		sections = sections[:n-1]
+	}
+
+	if op, value, ok := sectionCondition(sections[0]); ok {
+		if matches(v, op, value) {
+			return sections[0], false
+		}
+		if len(sections) == 1 {
+			return "General", false
+		}
+		if op, value, ok := sectionCondition(sections[1]); ok {
+			if matches(v, op, value) {
+				return sections[1], false
+			}
+			if len(sections) > 2 {
+				return sections[2], false
+			}
+			return "General", false
+		}
+		if len(sections) == 2 || matches(v, "<", 0) {
+			return sections[1], v < 0
+		}
+		return sections[2], false
+	}
+
+	switch {
+	case len(sections) == 1 || v > 0:
+		return sections[0], false
+	case v < 0:
+		return sections[1], true
+	case len(sections) > 2:
+		return sections[2], false
+	default:
+		return sections[0], false
+	}
+}
+
+// formatText renders text with the text section of a format: the fourth
+// section, or the only one when it holds @. Other formats show text as is.
+func formatText(s, format string) string {
+	sections := splitSections(format)
+	section := ""
+	switch {
+	case len(sections) == 4:
+		section = sections[3]
+	case strings.Contains(sections[len(sections)-1], "@"):
+		section = sections[len(sections)-1]
+	default:
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(section); i++ {
+		if section[i] == '@' {
+			b.WriteString(s)
+			continue
+		}
+		i = writeLiteral(&b, section, i)
+	}
+	return b.String()
+}
+
+// writeLiteral writes the literal at section[i]: quoted text, an escaped
+// character, padding, or any other character; fills, colors and conditions
+// write nothing, and a currency tag such as [$€-407] writes its symbol. It
+// returns the index of the literal's last byte.
+func writeLiteral(b *strings.Builder, section string, i int) int {
+	switch section[i] {
+	case '"':
+		end := strings.IndexByte(section[i+1:], '"')
+		if end < 0 {
+			b.WriteString(section[i+1:])
+			return len(section) - 1
+		}
+		b.WriteString(section[i+1 : i+1+end])
+		return i + 1 + end
+	case '\\':
+		if i+1 < len(section) {
+			b.WriteString(nextRune(section[i+1:]))
+			return i + len(nextRune(section[i+1:]))
+		}
+	case '_':
+		if i+1 < len(section) {
+			b.WriteByte(' ')
+			return i + len(nextRune(section[i+1:]))
+		}
+	case '*':
+		if i+1 < len(section) {
+			return i + len(nextRune(section[i+1:]))
+		}
+	case '[':
+		end := strings.IndexByte(section[i:], ']')
+		if end < 0 {
+			return len(section) - 1
+		}
+		if tag := section[i+1 : i+end]; strings.HasPrefix(tag, "$") {
+			symbol, _, _ := strings.Cut(tag[1:], "-")
+			b.WriteString(symbol)
+		}
+		return i + end
+	default:
+		r := nextRune(section[i:])
+		b.WriteString(r)
+		return i + len(r) - 1
+	}
+	return i
+}
+
+// nextRune returns the UTF-8 encoding of the first character of s.
+func nextRune(s string) string {
+	for i := range s {
+		if i > 0 {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// formatNumber renders v with a number format.
+func formatNumber(v float64, format string, date1904 bool) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return CellErrorNum.String()
+	}
+	section, signed := numberSection(v, splitSections(format))
+	if signed {
+		v = -v
+	}
+
+	// A text section shows numbers as General
+	if strings.Contains(section, "@") && !strings.ContainsAny(section, "0#?") {
+		section = "General"
+	}
+	if isDateFormat(section) {
+		t, err := serialToTime(v, date1904, time.UTC)
+		if err != nil {
+			return formatGeneral(v)
+		}
+		return formatDate(t, v, section)
+	}
+	return formatDigits(v, section)
+}
+
+// formatGeneral renders v as the General format does in a standard width
+// column: up to 11 characters, switching to scientific notation for very
+// large and very small magnitudes.
+func formatGeneral(v float64) string {
+	abs := math.Abs(v)
+	if abs == 0 {
+		return "0"
+	}
+	sign := ""
+	if v < 0 {
+		sign = "-"
+	}
+	if generalScientific(abs) {
+		s := strconv.FormatFloat(abs, 'E', generalScientificDigits-1, 64)
+		mantissa, exp, _ := strings.Cut(s, "E")
+		if strings.Contains(mantissa, ".") {
+			mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+		}
+		if len(exp) == 2 {
+			exp = exp[:1] + "0" + exp[1:]
+		}
+		return sign + mantissa + "E" + exp
+	}
+
+	s := strconv.FormatFloat(abs, 'f', -1, 64)
+	if len(s) > generalWidth {
+		intDigits := len(strconv.FormatFloat(math.Floor(abs), 'f', 0, 64))
+		s = strconv.FormatFloat(abs, 'f', max(0, generalWidth-1-intDigits), 64)
+		if strings.Contains(s, ".") {
+			s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+		}
+	}
+	return sign + s
+}
+
+// numberPattern is a number section split into its parts. Each part is a
+// sequence of literals and digit placeholders (0, # and ?).
+type numberPattern struct {
+	prefix   string // Literals before the first placeholder
+	intPart  []numberToken
+	point    bool
+	fracPart []numberToken
+	expSign  byte // + or - after E, or 0 without an exponent
+	expPart  []numberToken
+	numer    []numberToken // Fractions: numerator and denominator
+	denom    []numberToken
+	fraction bool
+	suffix   string // Literals after the last placeholder
+	grouping bool   // Thousands separators
+	scale    int    // Powers of 1000 from trailing commas
+	percent  int
+	general  bool
+}
+
+// numberToken is a digit placeholder, or a literal when text is set.
+type numberToken struct {
+	placeholder byte
+	text        string
+}
+
+// parseNumberSection splits a number section into a numberPattern.
+func parseNumberSection(section string) numberPattern {
+	var p numberPattern
+	var tokens []numberToken
+	for i := 0; i < len(section); i++ {
+		c := section[i]
+		switch {
+		case strings.IndexByte("0#?./,", c) >= 0:
+			tokens = append(tokens, numberToken{placeholder: c})
+		case (c == 'E' || c == 'e') && i+1 < len(section) && (section[i+1] == '+' || section[i+1] == '-'):
+			// The exponent is kept as its sign
+			i++
+			tokens = append(tokens, numberToken{placeholder: section[i]})
+		case c == '%':
+			p.percent++
+			tokens = append(tokens, numberToken{text: "%"})
+		case (c == 'G' || c == 'g') && strings.EqualFold(section[i:min(i+7, len(section))], "General"):
+			p.general = true
+			tokens = append(tokens, numberToken{placeholder: 'G'})
+			i += 6
+		default:
+			var b strings.Builder
+			i = writeLiteral(&b, section, i)
+			if b.Len() > 0 {
+				tokens = append(tokens, numberToken{text: b.String()})
+			}
+		}
+	}
+
+	isDigit := func(t numberToken) bool { return t.text == "" && strings.IndexByte("0#?", t.placeholder) >= 0 }
+	first, last := -1, -1
+	for i, t := range tokens {
+		if isDigit(t) || t.placeholder == 'G' {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		// No placeholders: the section is all literals
+		for _, t := range tokens {
+			p.prefix += tokenText(t)
+		}
+		return p
+	}
+	// A fixed denominator, as in # ?/8, is literal digits after the slash
+	if last+1 < len(tokens) && tokens[last+1].placeholder == '/' {
+		for last++; last+1 < len(tokens) && tokens[last+1].text != "" && strings.Trim(tokens[last+1].text, "0123456789") == ""; last++ {
+		}
+	}
+	// A leading decimal point, as in .00, belongs to the number
+	if first > 0 && tokens[first-1].placeholder == '.' {
+		first--
+	}
+	for _, t := range tokens[:first] {
+		p.prefix += tokenText(t)
+	}
+	// Commas right after the last placeholder scale by 1000; a decimal point
+	// directly after the digits still belongs to the number
+	end := last + 1
+	for end < len(tokens) && (tokens[end].placeholder == ',' || tokens[end].placeholder == '.') {
+		if tokens[end].placeholder == ',' {
+			p.scale++
+		} else {
+			p.point = true
+		}
+		end++
+	}
+	for _, t := range tokens[end:] {
+		p.suffix += tokenText(t)
+	}
+
+	body := tokens[first : last+1]
+	if p.general {
+		return p
+	}
+
+	if slash := indexPlaceholder(body, '/'); slash >= 0 {
+		p.fraction = true
+		// The numerator is the run of placeholders before the slash, and the
+		// integer part, if any, is separated from it by literals
+		start := slash
+		for start > 0 && isDigit(body[start-1]) {
+			start--
+		}
+		for _, t := range body[slash+1:] {
+			if isDigit(t) || t.text != "" && strings.Trim(t.text, "0123456789") == "" {
+				p.denom = append(p.denom, t)
+			}
+		}
+		// Literals between the integer part and the numerator, such as the
+		// space of "# ?/?", are kept in front of the numerator
+		intEnd := start
+		for intEnd > 0 && !isDigit(body[intEnd-1]) {
+			intEnd--
+		}
+		p.intPart, p.numer = body[:intEnd], body[intEnd:slash]
+		return p
+	}
+
+	part := &p.intPart
+	for _, t := range body {
+		switch {
+		case t.placeholder == '.' && !p.point && p.expSign == 0:
+			p.point = true
+			part = &p.fracPart
+		case t.placeholder == '+' || t.placeholder == '-':
+			p.expSign = t.placeholder
+			part = &p.expPart
+		case t.placeholder == ',' && part == &p.intPart:
+			p.grouping = true
+		case t.placeholder == ',':
+		default:
+			*part = append(*part, t)
+		}
+	}
+	return p
+}
+
+// tokenText returns the text of a token outside the digits of a number.
+func tokenText(t numberToken) string {
+	if t.text != "" || t.placeholder == ',' {
+		return t.text
+	}
+	return string(t.placeholder)
+}
+
+// indexPlaceholder returns the index of the first token that is the given
+// placeholder, or -1.
+func indexPlaceholder(tokens []numberToken, placeholder byte) int {
+	for i, t := range tokens {
+		if t.text == "" && t.placeholder == placeholder {
+			return i
+		}
+	}
+	return -1
+}
+
+// countPlaceholders returns the number of digit placeholders in tokens.
+func countPlaceholders(tokens []numberToken) int {
+	n := 0
+	for _, t := range tokens {
+		if t.text == "" && strings.IndexByte("0#?", t.placeholder) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// formatDigits renders v with a number section that is not a date format.
+func formatDigits(v float64, section string) string {
+	p := parseNumberSection(section)
+	negative := v < 0
+	v = math.Abs(v)
+	for range p.percent {
+		v *= 100
+	}
+	for range p.scale {
+		v /= 1000
+	}
+	// Excel keeps 15 significant digits
+	v, _ = strconv.ParseFloat(strconv.FormatFloat(v, 'g', 15, 64), 64)
+
+	var number string
+	switch {
+	case p.general:
+		number = formatGeneral(v)
+	case p.fraction:
+		number = p.formatFraction(v)
+	case p.expSign != 0:
+		number = p.formatScientific(v)
+	case p.intPart == nil && p.fracPart == nil:
+		number = ""
+	default:
+		number = p.formatFixed(v)
+	}
+
+	if negative && strings.ContainsAny(number, "123456789") {
+		return "-" + p.prefix + number + p.suffix
+	}
+	return p.prefix + number + p.suffix
+}
+
+// formatFixed renders v with the integer and decimal parts of the pattern.
+func (p numberPattern) formatFixed(v float64) string {
+	s := roundDecimal(strconv.FormatFloat(v, 'f', -1, 64), countPlaceholders(p.fracPart))
+	intDigits, fracDigits, _ := strings.Cut(s, ".")
+	if intDigits == "0" {
+		intDigits = ""
+	}
+
+	var b strings.Builder
+	b.WriteString(p.fillInteger(intDigits))
+	if p.point {
+		b.WriteByte('.')
+	}
+	b.WriteString(fillDecimals(p.fracPart, fracDigits))
+	return b.String()
+}
+
+// fillInteger fills the integer placeholders with digits from the right; the
+// leftmost placeholder takes any digits left over. Unused 0 placeholders
+// show 0, ? placeholders a space and # placeholders nothing.
+func (p numberPattern) fillInteger(digits string) string {
+	if p.grouping {
+		if pad := countZeros(p.intPart) - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		var b strings.Builder
+		for i := range len(digits) {
+			if i > 0 && (len(digits)-i)%3 == 0 {
+				b.WriteByte(',')
+			}
+			b.WriteByte(digits[i])
+		}
+		return b.String()
+	}
+
+	n := countPlaceholders(p.intPart)
+	if n == 0 {
+		return digits
+	}
+	out := make([]string, len(p.intPart))
+	pos := len(digits)
+	seen := 0
+	for i := len(p.intPart) - 1; i >= 0; i-- {
+		t := p.intPart[i]
+		if t.text != "" || strings.IndexByte("0#?", t.placeholder) < 0 {
+			out[i] = tokenText(t)
+			continue
+		}
+		seen++
+		switch {
+		case seen == n:
+			out[i] = digits[:pos]
+			if pos == 0 {
+				out[i] = emptyDigit(t.placeholder)
+			}
+			pos = 0
+		case pos > 0:
+			out[i] = digits[pos-1 : pos]
+			pos--
+		default:
+			out[i] = emptyDigit(t.placeholder)
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// fillDecimals fills the decimal placeholders with digits from the left.
+// Trailing zeros show as nothing for # and as a space for ?.
+func fillDecimals(tokens []numberToken, digits string) string {
+	var placeholders []byte
+	for _, t := range tokens {
+		if t.text == "" && strings.IndexByte("0#?", t.placeholder) >= 0 {
+			placeholders = append(placeholders, t.placeholder)
+		}
+	}
+	shown := []byte(digits)
+	for i := len(shown) - 1; i >= 0 && shown[i] == '0' && placeholders[i] != '0'; i-- {
+		if placeholders[i] == '?' {
+			shown[i] = ' '
+		} else {
+			shown[i] = 0
+		}
+	}
+
+	var b strings.Builder
+	d := 0
+	for _, t := range tokens {
+		if t.text != "" || strings.IndexByte("0#?", t.placeholder) < 0 {
+			b.WriteString(tokenText(t))
+			continue
+		}
+		if shown[d] != 0 {
+			b.WriteByte(shown[d])
+		}
+		d++
+	}
+	return b.String()
+}
+
+// countZeros returns the number of 0 placeholders in tokens.
+func countZeros(tokens []numberToken) int {
+	n := 0
+	for _, t := range tokens {
+		if t.text == "" && t.placeholder == '0' {
+			n++
+		}
+	}
+	return n
+}
+
+// emptyDigit returns what a placeholder shows without a digit.
+func emptyDigit(placeholder byte) string {
+	switch placeholder {
+	case '0':
+		return "0"
+	case '?':
+		return " "
+	}
+	return ""
+}
+
+// formatScientific renders v in scientific notation. The mantissa has as
+// many integer digits as the pattern has 0 placeholders; with # placeholders
+// the exponent is a multiple of their count, as in ##0.0E+0.
+func (p numberPattern) formatScientific(v float64) string {
+	decimals := countPlaceholders(p.fracPart)
+	n := max(countPlaceholders(p.intPart), 1)
+	engineering := indexPlaceholder(p.intPart, '#') >= 0
+
+	// The exponent puts as many digits before the point as the pattern has
+	// 0 placeholders, or a multiple of the placeholders with #. Rounding
+	// the mantissa can carry into another digit.
+	digits := strconv.FormatFloat(v, 'f', -1, 64)
+	exp, intWidth := 0, max(countZeros(p.intPart), 1)
+	if v != 0 {
+		exp, _ = strconv.Atoi(strings.SplitN(strconv.FormatFloat(v, 'e', -1, 64), "e", 2)[1])
+		if engineering {
+			exp, intWidth = int(math.Floor(float64(exp)/float64(n)))*n, n
+		} else {
+			exp -= intWidth - 1
+		}
+	}
+	s := roundDecimal(shiftPoint(digits, -exp), decimals)
+	if intDigits, _, _ := strings.Cut(s, "."); v != 0 && len(intDigits) > intWidth {
+		if engineering {
+			exp += n
+		} else {
+			exp++
+		}
+		s = roundDecimal(shiftPoint(digits, -exp), decimals)
+	}
+	intDigits, fracDigits, _ := strings.Cut(s, ".")
+	if intDigits == "0" && v != 0 {
+		intDigits = ""
+	}
+
+	var b strings.Builder
+	b.WriteString(p.fillInteger(intDigits))
+	if p.point {
+		b.WriteByte('.')
+	}
+	b.WriteString(fillDecimals(p.fracPart, fracDigits))
+	b.WriteByte('E')
+	switch {
+	case exp < 0:
+		b.WriteByte('-')
+	case p.expSign == '+':
+		b.WriteByte('+')
+	}
+	expDigits := strconv.Itoa(max(exp, -exp))
+	if pad := countZeros(p.expPart) - len(expDigits); pad > 0 {
+		expDigits = strings.Repeat("0", pad) + expDigits
+	}
+	b.WriteString(expDigits)
+	return b.String()
+}
+
+// formatFraction renders v as a fraction with an optional integer part. The
+// denominator is fixed when the pattern spells it out, and otherwise is the
+// best one with as many digits as the denominator has placeholders.
+func (p numberPattern) formatFraction(v float64) string {
+	whole := 0.0
+	rest := v
+	hasInt := countPlaceholders(p.intPart) > 0
+	if hasInt {
+		whole = math.Floor(v)
+		rest = v - whole
+	}
+
+	var numer, denom int
+	fixed := ""
+	for _, t := range p.denom {
+		fixed += t.text
+	}
+	if fixed != "" {
+		denom, _ = strconv.Atoi(fixed)
+		denom = max(denom, 1)
+		numer = int(math.Round(rest * float64(denom)))
+	} else {
+		numer, denom = bestFraction(rest, int(math.Pow10(countPlaceholders(p.denom)))-1)
+	}
+	if hasInt && numer == denom {
+		whole++
+		numer = 0
+	}
+
+	var b strings.Builder
+	intDigits := ""
+	switch {
+	case whole != 0:
+		intDigits = strconv.FormatFloat(whole, 'f', 0, 64)
+	case numer == 0:
+		intDigits = "0"
+	}
+	if hasInt {
+		b.WriteString(p.fillInteger(intDigits))
+	}
+	if hasInt && numer == 0 {
+		// A whole number shows blanks in place of the fraction
+		width := len(numberText(p.numer, "")) + 1 + max(len(fixed), countPlaceholders(p.denom))
+		b.WriteString(strings.Repeat(" ", width))
+		return b.String()
+	}
+	b.WriteString(numberText(p.numer, strconv.Itoa(numer)))
+	b.WriteByte('/')
+	d := strconv.Itoa(denom)
+	if fixed == "" {
+		if pad := countPlaceholders(p.denom) - len(d); pad > 0 {
+			d += strings.Repeat(" ", pad)
+		}
+	}
+	b.WriteString(d)
+	return b.String()
+}
+
+// numberText writes digits into the placeholders of tokens, right aligned,
+// keeping the literals.
+func numberText(tokens []numberToken, digits string) string {
+	p := numberPattern{intPart: tokens}
+	if countPlaceholders(tokens) == 0 {
+		var b strings.Builder
+		for _, t := range tokens {
+			b.WriteString(tokenText(t))
+		}
+		return b.String() + digits
+	}
+	return p.fillInteger(digits)
+}
+
+// bestFraction returns the fraction closest to v, in [0, 1], with a
+// denominator of at most maxDenom.
+func bestFraction(v float64, maxDenom int) (int, int) {
+	maxDenom = max(maxDenom, 1)
+	bestN, bestD := 0, 1
+	bestErr := math.Abs(v)
+	for d := 1; d <= maxDenom; d++ {
+		n := int(math.Round(v * float64(d)))
+		if err := math.Abs(v - float64(n)/float64(d)); err < bestErr-1e-12 {
+			bestN, bestD, bestErr = n, d, err
+		}
+	}
+	return bestN, bestD
+}
+
+// shiftPoint moves the decimal point of a non-negative decimal number n
+// places to the right, or to the left when n is negative.
+func shiftPoint(s string, n int) string {
+	intDigits, fracDigits, _ := strings.Cut(s, ".")
+	digits := intDigits + fracDigits
+	point := len(intDigits) + n
+	switch {
+	case point <= 0:
+		s = "0." + strings.Repeat("0", -point) + digits
+	case point >= len(digits):
+		s = digits + strings.Repeat("0", point-len(digits))
+	default:
+		s = digits[:point] + "." + digits[point:]
+	}
+	s = strings.TrimLeft(s, "0")
+	if s == "" || s[0] == '.' {
+		s = "0" + s
+	}
+	return s
+}
+
+// roundDecimal rounds a non-negative decimal number to the given number of
+// decimals, halves away from zero as Excel does.
+func roundDecimal(s string, decimals int) string {
+	intDigits, fracDigits, _ := strings.Cut(s, ".")
+	if len(fracDigits) <= decimals {
+		fracDigits += strings.Repeat("0", decimals-len(fracDigits))
+	} else {
+		up := fracDigits[decimals] >= '5'
+		digits := []byte(intDigits + fracDigits[:decimals])
+		for i := len(digits) - 1; up && i >= 0; i-- {
+			if digits[i] == '9' {
+				digits[i] = '0'
+			} else {
+				digits[i]++
+				up = false
+			}
+		}
+		if up {
+			digits = append([]byte{'1'}, digits...)
+		}
+		intDigits, fracDigits = string(digits[:len(digits)-decimals]), string(digits[len(digits)-decimals:])
+	}
+	if decimals == 0 {
+		return intDigits
+	}
+	return intDigits + "." + fracDigits
+}
@@ -0,0 +1,178 @@
+package xls
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		value  float64
+		format string
+		want   string
+	}{
+		// General
+		{0, "General", "0"},
+		{1234.5, "General", "1234.5"},
+		{-1.5, "General", "-1.5"},
+		{1.0 / 3, "General", "0.333333333"},
+		{0.1 + 0.2, "General", "0.3"},
+		{12345678901, "General", "12345678901"},
+		{123456789012, "General", "1.23457E+11"},
+		{1e-10, "General", "1E-10"},
+		{1.234e-7, "General", "1.234E-07"},
+		{-1.234e-7, "General", "-1.234E-07"},
+		{1.2e-7, "General", "0.00000012"},
+		{1e-9, "General", "0.000000001"},
+		{1.23456789e-5, "General", "1.23457E-05"},
+		{0.1234567891234, "General", "0.123456789"},
+		{12, `General" kg"`, "12 kg"},
+
+		// Built-in number formats
+		{1234.5, builtinFormats[1], "1235"},
+		{2.5, builtinFormats[1], "3"},
+		{-2.5, builtinFormats[1], "-3"},
+		{0.4, builtinFormats[1], "0"},
+		{3.14159, builtinFormats[2], "3.14"},
+		{2.675, builtinFormats[2], "2.68"},
+		{1234567.891, builtinFormats[3], "1,234,568"},
+		{999.5, builtinFormats[3], "1,000"},
+		{0, builtinFormats[3], "0"},
+		{1234.5, builtinFormats[4], "1,234.50"},
+		{-1234.5, builtinFormats[4], "-1,234.50"},
+		{1234, builtinFormats[5], "$1,234 "},
+		{-1234, builtinFormats[5], "($1,234)"},
+		{0, builtinFormats[5], "$0 "},
+		{-1234, builtinFormats[6], "($1,234)"},
+		{1234.5, builtinFormats[7], "$1,234.50 "},
+		{-0.5, builtinFormats[8], "($0.50)"},
+		{0.125, builtinFormats[9], "13%"},
+		{0.12345, builtinFormats[10], "12.35%"},
+		{-0.5, builtinFormats[10], "-50.00%"},
+		{12345, builtinFormats[11], "1.23E+04"},
+		{0.000123, builtinFormats[11], "1.23E-04"},
+		{99999, builtinFormats[11], "1.00E+05"},
+		{-12345, builtinFormats[11], "-1.23E+04"},
+		{0, builtinFormats[11], "0.00E+00"},
+		{1.5, builtinFormats[12], "1 1/2"},
+		{0.75, builtinFormats[12], " 3/4"},
+		{3, builtinFormats[12], "3    "},
+		{-2.25, builtinFormats[12], "-2 1/4"},
+		{3.14159265, builtinFormats[13], "3 14/99"},
+		{0.5, builtinFormats[13], "  1/2 "},
+		{1234, builtinFormats[37], "1,234 "},
+		{-1234, builtinFormats[38], "(1,234)"},
+		{-1234.5, builtinFormats[39], "(1,234.50)"},
+		{1234, builtinFormats[41], " 1,234 "},
+		{-1234, builtinFormats[41], " (1,234)"},
+		{0, builtinFormats[41], " - "},
+		{0, builtinFormats[43], " -   "},
+		{1234.5, builtinFormats[44], " $1,234.50 "},
+		{12345, builtinFormats[48], "12.3E+3"},
+		{1234567, builtinFormats[48], "1.2E+6"},
+		{0.0012, builtinFormats[48], "1.2E-3"},
+		{999999, builtinFormats[48], "1.0E+6"},
+		{12, builtinFormats[49], "12"},
+
+		// Built-in date and time formats
+		{45000, builtinFormats[14], "3/15/23"},
+		{45000, builtinFormats[15], "15-Mar-23"},
+		{45000, builtinFormats[16], "15-Mar"},
+		{45000, builtinFormats[17], "Mar-23"},
+		{45000.75, builtinFormats[18], "6:00 PM"},
+		{45000.75, builtinFormats[19], "6:00:00 PM"},
+		{0.5, builtinFormats[20], "12:00"},
+		{0.25, builtinFormats[21], "6:00:00"},
+		{45000.5, builtinFormats[22], "3/15/23 12:00"},
+		{90.0 / 86400, builtinFormats[45], "01:30"},
+		{1.5, builtinFormats[46], "36:00:00"},
+		{90.5 / 86400, builtinFormats[47], "01:30.5"},
+
+		// Formats the writer generates
+		{45000, "yyyy-mm-dd", "2023-03-15"},
+		{45000.5, "yyyy-mm-dd hh:mm:ss", "2023-03-15 12:00:00"},
+		{0.05, "0.0%", "5.0%"},
+
+		// Custom formats
+		{0.5, "#.##", ".5"},
+		{1, "#.##", "1."},
+		{1.5, "0.0#", "1.5"},
+		{12.5, ".00", "12.50"},
+		{1.5, "?.??", "1.5 "},
+		{7, "000", "007"},
+		{123456789, "000-00-0000", "123-45-6789"},
+		{1234567, "#,##0,", "1,235"},
+		{1234567890, `0.0,,"M"`, "1234.6M"},
+		{12, `0 "items"`, "12 items"},
+		{12, `\$0`, "$12"},
+		{12, `[$€-407]0.00`, "€12.00"},
+		{2.3, "# ?/8", "2 2/8"},
+		{5, "0;(0);zero", "5"},
+		{-5, "0;(0);zero", "(5)"},
+		{0, "0;(0);zero", "zero"},
+		{-5, "0;[Red]0", "5"},
+		{150, `[>100]"big";[<0]"negative";0`, "big"},
+		{-1, `[>100]"big";[<0]"negative";0`, "negative"},
+		{50, `[>100]"big";[<0]"negative";0`, "50"},
+		{5, `[Blue]0;[Red]-0`, "5"},
+		{-5, `[Blue]0;[Red]-0`, "-5"},
+		{-1, "yyyy-mm-dd", "-1"},
+	}
+	for _, tt := range tests {
+		if got := formatNumber(tt.value, tt.format, false); got != tt.want {
+			t.Errorf("formatNumber(%v, %q) = %q, want %q", tt.value, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	tests := []struct {
+		value  string
+		format string
+		want   string
+	}{
+		{"abc", "General", "abc"},
+		{"abc", "@", "abc"},
+		{"abc", "0.00", "abc"},
+		{"abc", `"Name: "@`, "Name: abc"},
+		{"abc", `0;-0;0;"<"@">"`, "<abc>"},
+		{"abc", builtinFormats[41], " abc "},
+	}
+	for _, tt := range tests {
+		if got := formatText(tt.value, tt.format); got != tt.want {
+			t.Errorf("formatText(%q, %q) = %q, want %q", tt.value, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormattedValue(t *testing.T) {
+	w := New()
+	w.Write([][]interface{}{{"text", 1234.5, true, time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)}})
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	want := []string{"text", "1234.5", "TRUE", "2023-03-15"}
+	for i, cell := range wb.Sheets()[0].Rows()[0] {
+		if got := cell.FormattedValue(); got != want[i] {
+			t.Errorf("Column %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+
+	for cell, want := range map[*Cell]string{
+		{Kind: KindBlank}:                                            "",
+		{Kind: KindError, Value: CellErrorDiv0}:                      "#DIV/0!",
+		{Kind: KindNumber, Value: 0.25, FormatString: "0%"}:          "25%",
+		{Kind: KindNumber, Value: 1.234e-7, FormatString: "General"}: "1.234E-07",
+		{Kind: KindFormula, Value: 2.0 / 3, FormatString: "0.00"}:    "0.67",
+	} {
+		if got := cell.FormattedValue(); got != want {
+			t.Errorf("%+v: expected %q, got %q", *cell, want, got)
+		}
+	}
+}
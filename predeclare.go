@@ -0,0 +1,113 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrUndeclaredString is returned for a string cell that is not among the
+// strings declared with PredeclareStrings.
+var ErrUndeclaredString = errors.New("xls: undeclared string")
+
+// stringDict holds the strings declared with PredeclareStrings, in the
+// order of the shared string table.
+type stringDict struct {
+	strings []string
+	index   map[string]int
+}
+
+// PredeclareStrings declares the strings of the workbook up front, for data
+// whose strings come from a known set, such as the values of a status
+// column. The shared string table is built from them at once, in the order
+// they are declared, so a write needs no pass over the cells to collect
+// the strings before the sheets are written, however many are written
+// concurrently.
+//
+// Once strings are declared, Write, SetCell and InsertRow fail with
+// ErrUndeclaredString for a cell written as a string, headers included,
+// that is not among them, leaving the sheet unchanged; strings that only
+// appear when the sheet is written, such as those of WriteColumns or of
+// summary rows, fail the write instead. Further calls add strings; a
+// string declared again keeps its place. The table holds exactly the
+// declared strings, so WithHybridStrings, WithoutStringDeduplication and
+// WithLowMemorySST do not apply to it, and RichText with runs fails with
+// ErrUndeclaredString. A string longer than a cell holds,
+// or more strings than the table holds, fail with a *LimitError.
+func (w *Writer) PredeclareStrings(strs []string) error {
+	d := w.dict
+	if d == nil {
+		d = &stringDict{index: make(map[string]int)}
+	}
+	added := make(map[string]bool)
+	for _, str := range strs {
+		str = cleanText(str)
+		if _, declared := d.index[str]; declared || added[str] {
+			continue
+		}
+		if err := check("MaxCellChars", textLength(str), w.limits.MaxCellChars, false); err != nil {
+			return fmt.Errorf("declared string %.20q: %w", str, err)
+		}
+		added[str] = true
+	}
+	if err := check("MaxUniqueStrings", len(d.strings)+len(added), w.limits.MaxUniqueStrings, false); err != nil {
+		return err
+	}
+	for _, str := range strs {
+		str = cleanText(str)
+		if _, declared := d.index[str]; !declared {
+			d.index[str] = len(d.strings)
+			d.strings = append(d.strings, str)
+		}
+	}
+	w.dict = d
+	return nil
+}
+
+// check returns ErrUndeclaredString for a string that is not declared.
+func (d *stringDict) check(str string, row, col int) error {
+	if _, ok := d.index[str]; ok {
+		return nil
+	}
+	return fmt.Errorf("%w: %q at row %d, column %d", ErrUndeclaredString, str, row, col)
+}
+
+// checkRich returns ErrUndeclaredString for rich text with runs, which a
+// table of the declared plain strings cannot hold.
+func (d *stringDict) checkRich(value interface{}, row, col int) error {
+	if rt, ok := richText(value); ok && len(rt.Runs) > 0 {
+		return fmt.Errorf("%w: rich text %q at row %d, column %d", ErrUndeclaredString, rt.Text, row, col)
+	}
+	return nil
+}
+
+// checkDeclared checks that the strings of the cells of data, from row
+// first on, are declared.
+func (s *SheetWriter) checkDeclared(data [][]interface{}, first int) error {
+	for r, row := range data {
+		for c, cell := range row {
+			if str, ok := s.textValue(first+r, c, cell); ok {
+				if err := s.w.dict.check(str, first+r, c); err != nil {
+					return err
+				}
+				if err := s.w.dict.checkRich(cell, first+r, c); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// declaredTable returns the shared string table of the declared strings,
+// filled, so it only looks strings up and counts the cells that refer to
+// them.
+func (d *stringDict) declaredTable() *sharedStringTable {
+	return &sharedStringTable{
+		strings:     d.strings,
+		stringMap:   d.index,
+		uniqueCount: len(d.strings),
+		filled:      true,
+		refs:        new(atomic.Int64),
+	}
+}
@@ -0,0 +1,129 @@
+package xls
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// statusRows returns a header and n rows of ids and statuses.
+func statusRows(n int) [][]interface{} {
+	statuses := []string{"open", "closed", "pending"}
+	rows := [][]interface{}{{"ID", "Status"}}
+	for i := range n {
+		rows = append(rows, []interface{}{i, statuses[i%len(statuses)]})
+	}
+	return rows
+}
+
+func TestPredeclareStrings(t *testing.T) {
+	write := func(opts ...Option) (*Writer, []byte) {
+		w := New(opts...)
+		if err := w.PredeclareStrings([]string{"ID", "Status", "open", "open", "closed"}); err != nil {
+			t.Fatalf("PredeclareStrings() failed: %v", err)
+		}
+		if err := w.PredeclareStrings([]string{"closed", "pending", "archived"}); err != nil {
+			t.Fatalf("PredeclareStrings() failed: %v", err)
+		}
+		w.Write(statusRows(3000))
+		for _, name := range []string{"Two", "Three"} {
+			s, _ := w.AddSheet(name)
+			if err := s.Write(statusRows(2000)); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+		}
+		return w, writtenBytes(t, w)
+	}
+
+	w, file := write(WithConcurrency(1), WithPostWriteVerification())
+	want := []string{"ID", "Status", "open", "closed", "pending", "archived"}
+	if !reflect.DeepEqual(w.dict.strings, want) {
+		t.Errorf("Expected the declared strings %q, got %q", want, w.dict.strings)
+	}
+	total, unique := sstCounts(t, file)
+	if cells := 3*2 + 3000 + 2*2000; total != cells || unique != len(want) {
+		t.Errorf("Expected %d strings of %d cells, got %d of %d", len(want), cells, unique, total)
+	}
+
+	wb, err := openWorkbook(file)
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	plain := New()
+	plain.Write(statusRows(2000))
+	if got, want := wb.Sheets()[2].Rows(), readBack(t, plain).Rows(); !reflect.DeepEqual(got, want) {
+		t.Error("Expected the cells of the file written without declared strings")
+	}
+
+	// Sheets written concurrently give the same file
+	if _, concurrent := write(WithConcurrency(3)); !slices.Equal(concurrent, file) {
+		t.Error("Expected the same file with sheets written concurrently")
+	}
+
+	// The declared table applies over the options that shape the table
+	for _, opt := range []Option{WithHybridStrings(2), WithoutStringDeduplication(), WithLowMemorySST()} {
+		if _, got := write(opt, WithConcurrency(1)); !slices.Equal(got, file) {
+			t.Error("Expected the declared table to be written as it is")
+		}
+	}
+}
+
+func TestPredeclareStringsUndeclared(t *testing.T) {
+	w := New()
+	w.PredeclareStrings([]string{"ID", "Status", "open", "closed", "pending"})
+	if err := w.Write(statusRows(10)); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	rows := statusRows(10)
+	rows[7][1] = "reopened"
+	err := w.Write(rows)
+	if !errors.Is(err, ErrUndeclaredString) || !strings.Contains(err.Error(), `"reopened" at row 7, column 1`) {
+		t.Errorf("Expected ErrUndeclaredString naming the string, got %v", err)
+	}
+	if err := w.SetCell(3, 1, "reopened"); !errors.Is(err, ErrUndeclaredString) {
+		t.Errorf("Expected SetCell() to fail with ErrUndeclaredString, got %v", err)
+	}
+	if err := w.InsertRow(2, []interface{}{99, Styled("reopened", Style{Font: Font{Bold: true}})}); !errors.Is(err, ErrUndeclaredString) {
+		t.Errorf("Expected InsertRow() to fail with ErrUndeclaredString, got %v", err)
+	}
+	if sheet := readBack(t, w); sheet.Cell(7, 1).Value != "open" || len(sheet.Rows()) != 11 {
+		t.Error("Expected the sheet to be left unchanged")
+	}
+
+	// Declared strings, numbers and converted strings are accepted
+	conv := New(WithAutoNumberConversion())
+	conv.PredeclareStrings([]string{"open"})
+	if err := conv.Write([][]interface{}{{"open", 1.5, "42", true, nil}}); err != nil {
+		t.Errorf("Expected only strings to be checked, got %v", err)
+	}
+
+	// Strings that reach the table when the sheet is written fail the write
+	w.WriteColumns([]string{"ID", "Status"}, []Column{
+		Float64Column{Values: []float64{1, 2}},
+		StringColumn{Values: []string{"open", "reopened"}},
+	})
+	if _, err := w.WriteTo(new(strings.Builder)); !errors.Is(err, ErrUndeclaredString) {
+		t.Errorf("Expected the write to fail with ErrUndeclaredString, got %v", err)
+	}
+	collect := New(WithErrorCollection(0))
+	collect.PredeclareStrings([]string{"ID", "Status", "open"})
+	collect.WriteColumns([]string{"ID", "Status"}, []Column{
+		Float64Column{Values: []float64{1, 2}},
+		StringColumn{Values: []string{"open", "reopened"}},
+	})
+	var collected *WriteErrors
+	if _, err := collect.WriteTo(new(strings.Builder)); !errors.As(err, &collected) || len(collected.Cells) != 1 || collected.Cells[0].Cell.Row != 2 {
+		t.Errorf("Expected the undeclared cell to be collected, got %v", err)
+	}
+
+	if err := New(WithLimits(Limits{MaxCellChars: 4})).PredeclareStrings([]string{"open", "closed"}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected a string longer than a cell to fail, got %v", err)
+	}
+	limited := New(WithLimits(Limits{MaxUniqueStrings: 2}))
+	if err := limited.PredeclareStrings([]string{"a", "b", "a", "c"}); !errors.Is(err, ErrLimitExceeded) || limited.dict != nil {
+		t.Errorf("Expected too many strings to fail without declaring any, got %v", err)
+	}
+}
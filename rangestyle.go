@@ -0,0 +1,223 @@
+package xls
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// SetRangeStyle sets the style of an inclusive, zero-based range of cells of
+// the first sheet. See SheetWriter.SetRangeStyle.
+func (w *Writer) SetRangeStyle(firstRow, lastRow, firstCol, lastCol int, st Style) error {
+	return w.sheets[0].SetRangeStyle(firstRow, lastRow, firstCol, lastCol, st)
+}
+
+// SetRangeStyle sets the style of the inclusive, zero-based range of cells,
+// such as a band of rows or a bordered block. The range is kept as one
+// rectangle, whatever its size, and resolved when the sheet is written:
+// where ranges overlap, the range set last wins. Cell styles set with
+// SetCellStyle take precedence over range styles, which take precedence
+// over column and row styles. As with SetCellStyle, a cell of the range
+// without a value is written as a blank cell carrying the style.
+func (s *SheetWriter) SetRangeStyle(firstRow, lastRow, firstCol, lastCol int, st Style) error {
+	if firstRow < 0 || firstCol < 0 || lastRow > maxRow || lastCol > maxColumn || firstRow > lastRow || firstCol > lastCol {
+		return fmt.Errorf("%w: style range rows %d-%d, columns %d-%d", ErrOutOfRange, firstRow, lastRow, firstCol, lastCol)
+	}
+	s.ranges.add(styledRange{cellRange{firstRow, lastRow, firstCol, lastCol}, st})
+	return nil
+}
+
+// styledRange is a range of cells with a style, set with SetRangeStyle.
+type styledRange struct {
+	cellRange
+	style Style
+}
+
+// rangeStyles holds the styled ranges of a sheet in the order they were
+// set. Lookups sweep the rows in ascending order, keeping the ranges that
+// cover the current row, so each costs a scan of those ranges only; a
+// lookup of an earlier row starts the sweep over.
+type rangeStyles struct {
+	list []styledRange
+
+	byFirst []int // Indices of list by first row, nil once list changes
+	next    int   // Next index of byFirst to activate
+	row     int   // Row of active
+	active  []int // Indices of the ranges covering row, ascending
+}
+
+func (rs *rangeStyles) add(r styledRange) {
+	rs.list = append(rs.list, r)
+	rs.byFirst = nil
+}
+
+// set replaces the ranges with list.
+func (rs *rangeStyles) set(list []styledRange) {
+	*rs = rangeStyles{list: list}
+}
+
+// clone returns a copy of the ranges with a sweep of its own.
+func (rs *rangeStyles) clone() rangeStyles {
+	return rangeStyles{list: slices.Clone(rs.list)}
+}
+
+// seek moves the sweep to row.
+func (rs *rangeStyles) seek(row int) {
+	if rs.byFirst == nil || row < rs.row {
+		rs.byFirst = make([]int, len(rs.list))
+		for i := range rs.byFirst {
+			rs.byFirst[i] = i
+		}
+		sort.SliceStable(rs.byFirst, func(i, j int) bool {
+			return rs.list[rs.byFirst[i]].firstRow < rs.list[rs.byFirst[j]].firstRow
+		})
+		rs.next, rs.active = 0, rs.active[:0]
+	}
+	rs.row = row
+
+	active := rs.active[:0]
+	for _, i := range rs.active {
+		if rs.list[i].lastRow >= row {
+			active = append(active, i)
+		}
+	}
+	added := false
+	for ; rs.next < len(rs.byFirst) && rs.list[rs.byFirst[rs.next]].firstRow <= row; rs.next++ {
+		if i := rs.byFirst[rs.next]; rs.list[i].lastRow >= row {
+			active, added = append(active, i), true
+		}
+	}
+	if added {
+		slices.Sort(active)
+	}
+	rs.active = active
+}
+
+// style returns the style of the last range set that holds the cell.
+func (rs *rangeStyles) style(row, col int) (Style, bool) {
+	if len(rs.list) == 0 {
+		return Style{}, false
+	}
+	rs.seek(row)
+	for i := len(rs.active) - 1; i >= 0; i-- {
+		if r := &rs.list[rs.active[i]]; r.firstCol <= col && col <= r.lastCol {
+			return r.style, true
+		}
+	}
+	return Style{}, false
+}
+
+// width returns one past the last column of the ranges holding cells of
+// row, 0 for none.
+func (rs *rangeStyles) width(row int) int {
+	if len(rs.list) == 0 {
+		return 0
+	}
+	rs.seek(row)
+	width := 0
+	for _, i := range rs.active {
+		width = max(width, rs.list[i].lastCol+1)
+	}
+	return width
+}
+
+// rowsFrom returns the rows from the given row on that hold cells of a
+// range, in ascending order.
+func (rs *rangeStyles) rowsFrom(from int) []int {
+	var rows []int
+	last := from - 1
+	for _, r := range slices.SortedFunc(slices.Values(rs.list), func(a, b styledRange) int {
+		return a.firstRow - b.firstRow
+	}) {
+		for row := max(r.firstRow, last+1, from); row <= r.lastRow; row++ {
+			rows = append(rows, row)
+		}
+		last = max(last, r.lastRow)
+	}
+	return rows
+}
+
+// shift moves the rows of the ranges from index on by delta rows, as
+// shiftRows does; with a negative delta, the rows from index+delta up to
+// index are the deleted ones. A range spanning index grows or shrinks, and
+// one left without rows is dropped. Rows moved past the last row are cut.
+func (rs *rangeStyles) shift(index, delta int) {
+	gone := min(index, index+delta) // First deleted row, index for none
+	list := rs.list[:0]
+	for _, r := range rs.list {
+		switch {
+		case r.firstRow >= index:
+			r.firstRow += delta
+		case r.firstRow >= gone:
+			r.firstRow = gone
+		}
+		switch {
+		case r.lastRow >= index:
+			r.lastRow = min(r.lastRow+delta, maxRow)
+		case r.lastRow >= gone:
+			r.lastRow = gone - 1
+		}
+		if r.firstRow <= r.lastRow {
+			list = append(list, r)
+		}
+	}
+	rs.set(list)
+}
+
+// extendCol extends the ranges whose last column is col-1 to col, as a new
+// column takes the styles of its left neighbour.
+func (rs *rangeStyles) extendCol(col int) {
+	for i := range rs.list {
+		if r := &rs.list[i]; r.lastCol == col-1 {
+			r.lastCol = col
+		}
+	}
+	rs.byFirst = nil
+}
+
+// rowRange returns the ranges cut to the rows first to last, inclusive,
+// moved by delta rows.
+func (rs *rangeStyles) rowRange(first, last, delta int) []styledRange {
+	var out []styledRange
+	for _, r := range rs.list {
+		r.firstRow, r.lastRow = max(r.firstRow, first), min(r.lastRow, last)
+		if r.firstRow <= r.lastRow {
+			r.firstRow += delta
+			r.lastRow += delta
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ownStyle returns the style set on the cell itself, with SetCellStyle or
+// SetRangeStyle.
+func (s *SheetWriter) ownStyle(row, col int) (Style, bool) {
+	if st, ok := s.cellStyles[[2]int{row, col}]; ok {
+		return st, true
+	}
+	return s.ranges.style(row, col)
+}
+
+// rangeBlanks returns the number of blank cells written for range styles
+// alone: cells of a range without a value or a cell style.
+func (s *SheetWriter) rangeBlanks() int {
+	if len(s.ranges.list) == 0 {
+		return 0
+	}
+	n := 0
+	for _, re := range s.sheetExtents().rows {
+		for col := re.firstCol; col < re.lastCol; col++ {
+			if s.hasValue(re.row, col) {
+				continue
+			}
+			if _, ok := s.cellStyles[[2]int{re.row, col}]; ok {
+				continue
+			}
+			if _, ok := s.ranges.style(re.row, col); ok {
+				n++
+			}
+		}
+	}
+	return n
+}
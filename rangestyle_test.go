@@ -0,0 +1,159 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// cellStyleOf returns the style of a cell read back from the file w wrote.
+func cellStyleOf(w *Writer, c Cell) Style {
+	if c.xf < firstCustomXF {
+		return Style{}
+	}
+	return w.styles.xfs[int(c.xf)-firstCustomXF]
+}
+
+func TestSetRangeStyle(t *testing.T) {
+	red := Style{Fill: ColorRed}
+	blue := Style{Fill: ColorBlue, Font: Font{Bold: true}}
+	green := Style{Fill: ColorGreen}
+	cell := Style{Border: Border{Top: BorderThick}}
+	column := Style{NumberFormat: "0.00"}
+
+	ranges := []struct {
+		firstRow, lastRow, firstCol, lastCol int
+		style                                Style
+	}{
+		{0, 3, 0, 3, red},
+		{2, 7, 2, 5, blue},
+		{3, 3, 0, 6, green}, // A band across both
+	}
+	w := New()
+	data := make([][]interface{}, 6)
+	for r := range data {
+		data[r] = []interface{}{r, fmt.Sprint(r), 1.5, nil}
+	}
+	w.Write(data)
+	w.SetColStyle(1, column)
+	for _, r := range ranges {
+		if err := w.SetRangeStyle(r.firstRow, r.lastRow, r.firstCol, r.lastCol, r.style); err != nil {
+			t.Fatalf("SetRangeStyle() failed: %v", err)
+		}
+	}
+	w.SetCellStyle(3, 3, cell)
+	if n := len(w.sheets[0].ranges.list); n != len(ranges) {
+		t.Errorf("Expected %d ranges stored, got %d", len(ranges), n)
+	}
+
+	// The style each cell resolves to, cell by cell: its cell style, else
+	// the last range holding it, else its column style
+	want := func(row, col int) (Style, bool) {
+		if row == 3 && col == 3 {
+			return cell, true
+		}
+		for i := len(ranges) - 1; i >= 0; i-- {
+			r := ranges[i]
+			if r.firstRow <= row && row <= r.lastRow && r.firstCol <= col && col <= r.lastCol {
+				return r.style, true
+			}
+		}
+		if col == 1 {
+			return column, true
+		}
+		return Style{}, false
+	}
+
+	sheet := readBack(t, w)
+	for row := 0; row < 10; row++ {
+		for col := 0; col < 8; col++ {
+			c := sheet.Cell(row, col)
+			st, styled := want(row, col)
+			hasValue := row < len(data) && col < 3
+			switch {
+			case styled && (hasValue || col != 1):
+				if got := cellStyleOf(w, c); got != st {
+					t.Errorf("Cell (%d, %d): expected style %+v, got %+v", row, col, st, got)
+				}
+			case !hasValue && c.xf != 0:
+				t.Errorf("Cell (%d, %d): expected no cell, got XF %d", row, col, c.xf)
+			}
+		}
+	}
+	if rows, cols := w.Dimensions("Sheet1"); rows != 8 || cols != 7 {
+		t.Errorf("Expected dimensions 8 x 7 with the range blanks, got %d x %d", rows, cols)
+	}
+
+	for _, r := range [][4]int{{-1, 0, 0, 0}, {0, maxRow + 1, 0, 0}, {0, 0, 0, maxColumn + 1}, {2, 1, 0, 0}, {0, 0, 3, 2}} {
+		if err := w.SetRangeStyle(r[0], r[1], r[2], r[3], red); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Range %v: expected ErrOutOfRange, got %v", r, err)
+		}
+	}
+}
+
+func TestRangeStylesShift(t *testing.T) {
+	tests := []struct {
+		name         string
+		index, delta int
+		want         []cellRange
+	}{
+		{"insert above", 0, 1, []cellRange{{3, 5, 0, 0}, {6, 6, 0, 0}, {maxRow, maxRow, 0, 0}}},
+		{"insert inside", 3, 1, []cellRange{{2, 5, 0, 0}, {6, 6, 0, 0}, {maxRow, maxRow, 0, 0}}},
+		{"delete first row", 3, -1, []cellRange{{2, 3, 0, 0}, {4, 4, 0, 0}, {maxRow - 2, maxRow - 1, 0, 0}}},
+		{"delete last row", 5, -1, []cellRange{{2, 3, 0, 0}, {4, 4, 0, 0}, {maxRow - 2, maxRow - 1, 0, 0}}},
+		{"delete single row", 6, -1, []cellRange{{2, 4, 0, 0}, {maxRow - 2, maxRow - 1, 0, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rs rangeStyles
+			for _, r := range []cellRange{{2, 4, 0, 0}, {5, 5, 0, 0}, {maxRow - 1, maxRow, 0, 0}} {
+				rs.add(styledRange{cellRange: r})
+			}
+			rs.shift(tt.index, tt.delta)
+			var got []cellRange
+			for _, r := range rs.list {
+				got = append(got, r.cellRange)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRangeStyleRows(t *testing.T) {
+	bold := Style{Font: Font{Bold: true}}
+	w := New()
+	w.Write([][]interface{}{{"a"}, {"b"}, {"c"}, {"d"}})
+	w.SetRangeStyle(1, 2, 0, 0, bold)
+	if err := w.InsertRow(2, []interface{}{"new"}); err != nil {
+		t.Fatalf("InsertRow() failed: %v", err)
+	}
+	if err := w.DeleteRow(0); err != nil {
+		t.Fatalf("DeleteRow() failed: %v", err)
+	}
+	sheet := readBack(t, w)
+	for row, want := range []Style{bold, bold, bold, {}} {
+		if got := cellStyleOf(w, sheet.Cell(row, 0)); got != want {
+			t.Errorf("Row %d: expected style %+v, got %+v", row, want, got)
+		}
+	}
+
+	// Each part of a split sheet keeps the ranges of its header and rows
+	s := New().sheets[0]
+	s.ranges.add(styledRange{cellRange{0, 5, 0, 1}, bold})
+	s.data = make([][]interface{}, 7)
+	parts := s.split(1, 3)
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d", len(parts))
+	}
+	for i, want := range [][]cellRange{{{0, 0, 0, 1}, {1, 3, 0, 1}}, {{0, 0, 0, 1}, {1, 2, 0, 1}}} {
+		var got []cellRange
+		for _, r := range parts[i].ranges.list {
+			got = append(got, r.cellRange)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Part %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxRecordSize is the largest record body BIFF8 allows.
+const maxRecordSize = 8224
+
+// ErrRecordTooLarge is returned for a raw record body over 8224 bytes.
+var ErrRecordTooLarge = errors.New("xls: record body exceeds 8224 bytes")
+
+// rawRecord is a record the caller appends verbatim.
+type rawRecord struct {
+	recType uint16
+	data    []byte
+}
+
+// AppendRawGlobalsRecord appends a record to the workbook globals substream,
+// written as is just before its EOF record, after every record the Writer
+// writes itself. Records are written in the order they were appended.
+//
+// It is an escape hatch for records the package does not model. The body is
+// copied but not otherwise checked: a record that does not belong in the
+// globals, or whose body is invalid, produces a file Excel may reject.
+func (w *Writer) AppendRawGlobalsRecord(recType uint16, body []byte) error {
+	rec, err := newRawRecord(recType, body)
+	if err != nil {
+		return err
+	}
+	w.rawGlobals = append(w.rawGlobals, rec)
+	return nil
+}
+
+// AppendRawWorksheetRecord appends a record to the worksheet substream of
+// the first sheet. See SheetWriter.AppendRawWorksheetRecord.
+func (w *Writer) AppendRawWorksheetRecord(recType uint16, body []byte) error {
+	return w.sheets[0].AppendRawWorksheetRecord(recType, body)
+}
+
+// AppendRawWorksheetRecord appends a record to the worksheet substream,
+// written as is just before its EOF record, after the window records.
+// The caveats of Writer.AppendRawGlobalsRecord apply.
+func (s *SheetWriter) AppendRawWorksheetRecord(recType uint16, body []byte) error {
+	rec, err := newRawRecord(recType, body)
+	if err != nil {
+		return err
+	}
+	s.raw = append(s.raw, rec)
+	return nil
+}
+
+func newRawRecord(recType uint16, body []byte) (rawRecord, error) {
+	if recType == recTypeBOF || recType == recTypeEOF {
+		return rawRecord{}, fmt.Errorf("xls: a raw %s record would break the substream", RecordName(recType))
+	}
+	if len(body) > maxRecordSize {
+		return rawRecord{}, fmt.Errorf("%w: record 0x%04X of %d bytes", ErrRecordTooLarge, recType, len(body))
+	}
+	return rawRecord{recType: recType, data: append([]byte(nil), body...)}, nil
+}
+
+func (w *Writer) writeRawRecords(writer io.Writer, records []rawRecord) error {
+	for _, rec := range records {
+		if err := w.writeRecord(writer, rec.recType, rec.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestAppendRawRecords(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := [][]interface{}{{"a", 1}}
+	w.Write(data)
+	w.SetColStyle(0, Style{Font: Font{Bold: true}})
+	w.FormatAsTable(0, 0, 0, 1, TableStyle{AutoFilter: true})
+
+	const recUSESELFS, recPRINTSIZE = 0x0160, 0x0033
+	if err := w.AppendRawGlobalsRecord(recUSESELFS, []byte{0x01, 0x00}); err != nil {
+		t.Fatalf("AppendRawGlobalsRecord() failed: %v", err)
+	}
+	printSize := []byte{0x03, 0x00}
+	if err := w.AppendRawWorksheetRecord(recPRINTSIZE, printSize); err != nil {
+		t.Fatalf("AppendRawWorksheetRecord() failed: %v", err)
+	}
+	printSize[0] = 0xFF // The body is copied
+
+	records := writtenRecords(t, w)
+	var eofs []int
+	for i, rec := range records {
+		if rec.Type == recTypeEOF {
+			eofs = append(eofs, i)
+		}
+	}
+	if len(eofs) != 2 {
+		t.Fatalf("Expected 2 EOF records, got %d", len(eofs))
+	}
+	if rec := records[eofs[0]-1]; rec.Type != recUSESELFS || !bytes.Equal(rec.Data, []byte{0x01, 0x00}) {
+		t.Errorf("Expected the raw globals record just before the globals EOF, got 0x%04X % X", rec.Type, rec.Data)
+	}
+	if rec := records[eofs[1]-1]; rec.Type != recPRINTSIZE || !bytes.Equal(rec.Data, []byte{0x03, 0x00}) {
+		t.Errorf("Expected the raw worksheet record just before the worksheet EOF, got 0x%04X % X", rec.Type, rec.Data)
+	}
+	if rec := records[eofs[1]-2]; rec.Type != recTypeWINDOW2 {
+		t.Errorf("Expected the raw worksheet record after WINDOW2, got %s", RecordName(rec.Type))
+	}
+
+	// The BOUNDSHEET offset accounts for the raw globals record
+	boundSheet := recordsOfType(records, recTypeBOUNDSHEET)[0]
+	offset := 0
+	for _, rec := range records[:eofs[0]+1] {
+		offset += 4 + len(rec.Data)
+	}
+	if got := binary.LittleEndian.Uint32(boundSheet.Data[0:4]); int(got) != offset {
+		t.Errorf("Expected the worksheet at offset %d, got %d", offset, got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Sheet1", data); err != nil {
+		t.Errorf("File with raw records does not read back: %v", err)
+	}
+}
+
+func TestAppendRawRecordInvalid(t *testing.T) {
+	w := New()
+	defer w.Close()
+	if err := w.AppendRawWorksheetRecord(0x0033, make([]byte, maxRecordSize+1)); !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("Expected ErrRecordTooLarge, got %v", err)
+	}
+	if err := w.AppendRawGlobalsRecord(0x0033, make([]byte, maxRecordSize)); err != nil {
+		t.Errorf("Expected a record of %d bytes to be accepted, got %v", maxRecordSize, err)
+	}
+	for _, recType := range []uint16{recTypeBOF, recTypeEOF} {
+		if err := w.AppendRawGlobalsRecord(recType, nil); err == nil {
+			t.Errorf("Expected an error for a raw %s record", RecordName(recType))
+		}
+	}
+}
@@ -0,0 +1,1228 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+var (
+	// ErrInvalidFormat is returned when a file is not a well-formed XLS file.
+	ErrInvalidFormat = errors.New("xls: invalid file format")
+	// ErrUnsupportedVersion is returned for workbooks older than BIFF8.
+	ErrUnsupportedVersion = errors.New("xls: unsupported BIFF version")
+	// ErrSheetNotFound is returned when a sheet name does not exist.
+	ErrSheetNotFound = errors.New("xls: sheet not found")
+	// ErrFileTooComplex is returned when a file exceeds the reader's hard
+	// caps on sectors, strings, sheets or substream nesting.
+	ErrFileTooComplex = errors.New("xls: file too complex")
+	// ErrStop can be returned from a ForEachRow callback to stop iterating
+	// without reporting an error.
+	ErrStop = errors.New("xls: stop iteration")
+)
+
+// Hard caps protecting the reader from hostile files
+const (
+	maxSectors        = 1 << 24 // 8 GiB in 512-byte sectors
+	maxSSTStrings     = 1 << 24
+	maxSheets         = 1 << 14
+	maxSubstreamDepth = 4 // Embedded chart substreams inside a worksheet
+)
+
+// CellKind identifies the type of a cell read from a workbook.
+type CellKind int
+
+// Cell kinds
+const (
+	KindBlank CellKind = iota
+	KindText
+	KindNumber
+	KindBool
+	KindError
+	KindFormula
+)
+
+var cellKindNames = [...]string{"Blank", "Text", "Number", "Bool", "Error", "Formula"}
+
+// String returns the name of the kind.
+func (k CellKind) String() string {
+	if k >= 0 && int(k) < len(cellKindNames) {
+		return cellKindNames[k]
+	}
+	return fmt.Sprintf("CellKind(%d)", int(k))
+}
+
+// Cell is a cell read from a workbook.
+//
+// Value holds a string for KindText, a float64 for KindNumber, a bool for
+// KindBool and a CellError for KindError. For KindFormula, Value holds the
+// cached result (any of the above, or nil if the file carries none, as for
+// a string result without its STRING record) and Formula holds the
+// expression decompiled on a best-effort basis; it is empty when the formula
+// uses constructs the decompiler does not understand. A FormulaCell written
+// without Cached reads back as 0. Runs returns the formatting runs of rich
+// text.
+type Cell struct {
+	Kind         CellKind
+	Value        interface{}
+	FormatString string
+	Formula      string
+
+	formatIndex uint16
+	xf          uint16
+	date1904    bool
+	runs        *[]TextRun // Of rich text, shared by the cells of a string
+}
+
+// Workbook is an XLS workbook opened for reading.
+//
+// A Workbook and its sheets are safe for concurrent use by multiple
+// goroutines, such as one per sheet: the decoded workbook state is not
+// changed once the workbook is opened, and the cells and layout of a sheet
+// are decoded once, on first access, however many goroutines ask for
+// them. The slices and maps the accessors return are shared, and must not
+// be modified. Clone returns a workbook whose sheets decode their own
+// copies.
+type Workbook struct {
+	stream    []byte      // The Workbook stream, when held in memory
+	src       io.ReaderAt // Otherwise, to read it on demand
+	size      int         // Of the stream
+	sheets    []*Sheet
+	sst       []string
+	sstRuns   map[int]*[]TextRun // Of the rich text of the SST, by index
+	fonts     []Font             // Of the FONT records, as templateFont decodes them
+	formats   map[uint16]string
+	xfFormats []uint16
+	dateMode  uint16
+	codePage  codePage // Of the 8-bit strings, from the CODEPAGE record
+	salvage   *salvage // Set by OpenFileRecover
+	limits    Limits   // Set with WithReadLimits
+
+	otherSheets []boundSheet // Sheets that are not worksheets
+	offsets     []int        // Of the substreams of the sheets, in order
+}
+
+// Sheet is a worksheet of a Workbook. Its cells are decoded on first access.
+type Sheet struct {
+	Name string
+
+	wb         *Workbook
+	offset     uint32
+	size       int // Of the substream, up to the next one
+	visibility SheetVisibility
+	once       sync.Once
+	rows       [][]Cell
+	err        error // That ended the reading of rows, if any
+
+	infoOnce sync.Once
+	info     *sheetLayout // Layout records, read on first access
+}
+
+// errEncrypted is returned by parseGlobals for an encrypted stream read on
+// demand, which is decrypted in memory instead.
+var errEncrypted = errors.New("xls: encrypted stream")
+
+// OpenFile opens and parses the XLS file at path, read into memory. Its
+// sheets are checked at once, so a malformed record fails OpenFile rather
+// than a later access. Files encrypted with a password to open need
+// OpenFileWithPassword.
+func OpenFile(path string, opts ...ReadOption) (*Workbook, error) {
+	limits := newReadOptions(opts).limits
+	data, err := readFile(path, limits)
+	if err != nil {
+		return nil, err
+	}
+	return openWorkbookWithPassword(data, defaultPassword, limits)
+}
+
+// readFile reads the file at path into memory, unless it is larger than
+// limits allow.
+func readFile(path string, limits Limits) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := check("MaxFileBytes", info.Size(), limits.MaxFileBytes, true); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// OpenReader opens the XLS file of size bytes read from r, such as a
+// memory-mapped file, a file in a zip archive or an object store read by
+// range. The file is read on demand: opening it reads the compound file
+// header and directory and the workbook globals, and each sheet is read
+// when it is first accessed, so r must stay readable while the workbook
+// is used. Encrypted files, which are decrypted with the password Excel
+// uses for files without one, are read at once.
+//
+// The records of a sheet are checked as the sheet is read: the cells of a
+// sheet end at a malformed record or a failed read, whose error ForEachRow
+// and Sheet.Err return.
+func OpenReader(r io.ReaderAt, size int64, opts ...ReadOption) (*Workbook, error) {
+	limits := newReadOptions(opts).limits
+	if err := check("MaxFileBytes", size, limits.MaxFileBytes, true); err != nil {
+		return nil, err
+	}
+	cfb, err := readCFB(r, size)
+	if err != nil {
+		return nil, err
+	}
+	src, streamSize, err := cfb.streamAt("Workbook")
+	if err != nil {
+		return nil, missingWorkbook(cfb, err)
+	}
+
+	wb := &Workbook{src: src, size: int(streamSize), formats: make(map[uint16]string), limits: limits}
+	bounds, err := wb.parseGlobals()
+	if errors.Is(err, errEncrypted) {
+		stream := make([]byte, streamSize)
+		if read, err := src.ReadAt(stream, 0); read < len(stream) {
+			return nil, fmt.Errorf("failed to read the workbook stream: %w", err)
+		}
+		return parseWorkbook(stream, defaultPassword, nil, limits)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := wb.addSheets(bounds, false); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// openWorkbook parses a complete XLS file held in memory, decrypting it
+// with the password Excel uses for files without one if it is encrypted.
+func openWorkbook(data []byte) (*Workbook, error) {
+	return openWorkbookWithPassword(data, defaultPassword, DefaultLimits())
+}
+
+// openWorkbookWithPassword parses a complete XLS file held in memory,
+// decrypting it with password if it is encrypted.
+func openWorkbookWithPassword(data []byte, password string, limits Limits) (*Workbook, error) {
+	cfb, err := ReadCFB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := cfb.Stream("Workbook")
+	if err != nil {
+		return nil, missingWorkbook(cfb, err)
+	}
+	return parseWorkbook(stream, password, nil, limits)
+}
+
+// missingWorkbook returns the error for a compound file whose Workbook
+// stream could not be read.
+func missingWorkbook(cfb *CFBFile, err error) error {
+	if _, bookErr := cfb.streamEntry("Book"); bookErr == nil {
+		return fmt.Errorf("%w: BIFF5 workbooks are not supported", ErrUnsupportedVersion)
+	}
+	return err
+}
+
+// parseWorkbook parses a Workbook stream, decrypting it with password if
+// it is encrypted. With a salvage, the problems it reads past are reported
+// to it.
+func parseWorkbook(stream []byte, password string, salvage *salvage, limits Limits) (*Workbook, error) {
+	stream, err := decryptStream(stream, password)
+	if err != nil {
+		return nil, err
+	}
+
+	wb := &Workbook{stream: stream, size: len(stream), formats: make(map[uint16]string), salvage: salvage, limits: limits}
+	bounds, err := wb.parseGlobals()
+	if err != nil {
+		return nil, err
+	}
+	if err := wb.addSheets(bounds, true); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// addSheets adds the worksheets of bounds, and keeps the other sheets
+// apart. With validate, their substreams are checked at once.
+func (wb *Workbook) addSheets(bounds []boundSheet, validate bool) error {
+	for _, b := range bounds {
+		wb.offsets = append(wb.offsets, int(b.offset))
+	}
+	slices.Sort(wb.offsets)
+	wb.offsets = slices.Compact(wb.offsets)
+
+	for _, b := range bounds {
+		if b.sheetType != sheetTypeWorksheet {
+			// Chart, macro and VB module sheets are not read
+			wb.otherSheets = append(wb.otherSheets, b)
+			continue
+		}
+		// Validate the substream now so that later accesses cannot fail,
+		// but keep no cells until they are asked for
+		if validate {
+			if err := wb.walkSheet(b.offset, func(int, int, Cell) error { return nil }); err != nil {
+				if wb.salvage == nil || errors.Is(err, ErrFileTooComplex) {
+					return fmt.Errorf("failed to read sheet %q: %w", b.name, err)
+				}
+				wb.salvage.report(int(b.offset), fmt.Errorf("sheet %q skipped: %w", b.name, err))
+				continue
+			}
+		}
+		wb.sheets = append(wb.sheets, &Sheet{
+			Name:       b.name,
+			wb:         wb,
+			offset:     b.offset,
+			size:       max(wb.substreamEnd(int(b.offset))-int(b.offset), 0),
+			visibility: b.visibility,
+		})
+	}
+	return nil
+}
+
+// substreamEnd returns the offset at which the substream at offset ends:
+// that of the next sheet's, or the end of the stream.
+func (wb *Workbook) substreamEnd(offset int) int {
+	i, found := slices.BinarySearch(wb.offsets, offset)
+	if found {
+		i++
+	}
+	if i < len(wb.offsets) {
+		return min(wb.offsets[i], wb.size)
+	}
+	return wb.size
+}
+
+// records returns a reader of the records of the Workbook stream from
+// offset. Those of a stream read on demand are read up to the end of the
+// substream at offset, unless a record runs past it.
+func (wb *Workbook) records(offset uint32) *recordReader {
+	if wb.src != nil {
+		return &recordReader{src: wb.src, size: wb.size, limit: wb.substreamEnd(int(offset)), pos: int(offset)}
+	}
+	return &recordReader{data: wb.stream, pos: int(offset)}
+}
+
+// Clone returns a workbook reading the same file whose sheets keep their
+// own decoded cells and layout, for goroutines that should not share
+// them: those of a clone are released when the clone is no longer used.
+// The file and the decoded workbook state, which are never modified, are
+// shared.
+func (wb *Workbook) Clone() *Workbook {
+	clone := *wb
+	clone.sheets = make([]*Sheet, len(wb.sheets))
+	for i, s := range wb.sheets {
+		clone.sheets[i] = &Sheet{Name: s.Name, wb: &clone, offset: s.offset, size: s.size, visibility: s.visibility}
+	}
+	return &clone
+}
+
+// Sheets returns the worksheets in workbook order.
+func (wb *Workbook) Sheets() []*Sheet {
+	return wb.sheets
+}
+
+// Sheet returns the worksheet with the given name.
+func (wb *Workbook) Sheet(name string) (*Sheet, error) {
+	for _, s := range wb.sheets {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrSheetNotFound, name)
+}
+
+// ForEachRow calls fn for each row of the named sheet that holds at least one
+// cell, in the order the rows appear in the file. Rows are decoded one at a
+// time and are not kept, so memory use does not grow with the sheet size; the
+// cells slice is reused and must not be retained after fn returns. Gaps are
+// filled with blank cells as in Rows.
+//
+// Iteration stops at the first error returned by fn, which ForEachRow
+// returns, unless it is ErrStop, in which case ForEachRow returns nil.
+func (wb *Workbook) ForEachRow(sheet string, fn func(rowIndex int, cells []Cell) error) error {
+	s, err := wb.Sheet(sheet)
+	if err != nil {
+		return err
+	}
+
+	current := -1
+	var cells []Cell
+	flush := func() error {
+		if current < 0 || len(cells) == 0 {
+			return nil
+		}
+		return fn(current, cells)
+	}
+
+	err = wb.walkSheet(s.offset, func(row, col int, cell Cell) error {
+		if row != current {
+			if err := flush(); err != nil {
+				return err
+			}
+			current, cells = row, cells[:0]
+		}
+		for len(cells) <= col {
+			cells = append(cells, Cell{Kind: KindBlank})
+		}
+		cells[col] = cell
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if errors.Is(err, ErrStop) {
+		return nil
+	}
+	return err
+}
+
+// load decodes the cells of the sheet once. The substream was validated
+// when the workbook was opened, unless it is read on demand: its cells then
+// end at a malformed record or a failed read, whose error is kept for Err.
+func (s *Sheet) load() {
+	s.once.Do(func() {
+		err := s.wb.walkSheet(s.offset, func(row, col int, cell Cell) error {
+			s.setCell(row, col, cell)
+			return nil
+		})
+		if err != nil {
+			s.err = fmt.Errorf("failed to read sheet %q: %w", s.Name, err)
+		}
+	})
+}
+
+// Err returns the error that cut the cells or layout of the sheet short,
+// reading them first if they were not read yet, or nil if they were read
+// whole. Only the sheets of a workbook opened with OpenReader are read on
+// access and can fail: Rows, Cell and the layout methods then return what
+// was read before the error.
+func (s *Sheet) Err() error {
+	s.load()
+	if s.err != nil {
+		return s.err
+	}
+	return s.layout().err
+}
+
+// Cell returns the cell at the zero-based row and column. Cells outside the
+// used area are returned as blank cells.
+func (s *Sheet) Cell(row, col int) Cell {
+	s.load()
+	if row < 0 || row >= len(s.rows) || col < 0 || col >= len(s.rows[row]) {
+		return Cell{Kind: KindBlank}
+	}
+	return s.rows[row][col]
+}
+
+// Rows returns all rows of the sheet. Rows may have different lengths; gaps
+// are filled with blank cells. The returned slice must not be modified.
+func (s *Sheet) Rows() [][]Cell {
+	s.load()
+	return s.rows
+}
+
+func (s *Sheet) setCell(row, col int, cell Cell) {
+	for len(s.rows) <= row {
+		s.rows = append(s.rows, nil)
+	}
+	for len(s.rows[row]) <= col {
+		s.rows[row] = append(s.rows[row], Cell{Kind: KindBlank})
+	}
+	s.rows[row][col] = cell
+}
+
+// boundSheet is a BOUNDSHEET entry of the workbook globals.
+type boundSheet struct {
+	name       string
+	offset     uint32
+	visibility SheetVisibility
+	sheetType  byte
+}
+
+// readWindow is the number of bytes a recordReader reads at a time from a
+// stream read on demand.
+const readWindow = 4096
+
+// recordReader iterates over the records of a BIFF8 stream: one held in
+// data, or one of size bytes read on demand from src, a window of which,
+// from base, data then holds. Windows do not extend past limit.
+type recordReader struct {
+	data []byte
+	pos  int
+
+	src   io.ReaderAt
+	size  int
+	limit int
+	base  int
+}
+
+// len returns the size of the stream.
+func (r *recordReader) len() int {
+	if r.src == nil {
+		return len(r.data)
+	}
+	return r.size
+}
+
+// span returns the n bytes of the stream at offset, which must lie in it.
+// A stream read on demand gets a new window when they are not in the
+// current one, so that the records returned before stay valid.
+func (r *recordReader) span(offset, n int) ([]byte, error) {
+	if offset < r.base || offset+n > r.base+len(r.data) {
+		window := make([]byte, min(max(n, min(readWindow, r.limit-offset)), r.size-offset))
+		if read, err := r.src.ReadAt(window, int64(offset)); read < len(window) {
+			return nil, fmt.Errorf("failed to read the workbook stream at offset %d: %w", offset, err)
+		}
+		r.data, r.base = window, offset
+	}
+	return r.data[offset-r.base : offset-r.base+n], nil
+}
+
+// next returns the next record and its offset in the stream, or io.EOF when
+// the stream is exhausted.
+func (r *recordReader) next() (uint16, []byte, int, error) {
+	if r.pos >= r.len() {
+		return 0, nil, r.pos, io.EOF
+	}
+	offset := r.pos
+	if offset+4 > r.len() {
+		return 0, nil, offset, fmt.Errorf("%w: truncated record header at offset %d", ErrInvalidFormat, offset)
+	}
+	header, err := r.span(offset, 4)
+	if err != nil {
+		return 0, nil, offset, err
+	}
+	recType := binary.LittleEndian.Uint16(header)
+	size := int(binary.LittleEndian.Uint16(header[2:]))
+	if offset+4+size > r.len() {
+		return 0, nil, offset, fmt.Errorf("%w: record 0x%04X at offset %d runs past end of stream", ErrInvalidFormat, recType, offset)
+	}
+	record, err := r.span(offset, 4+size)
+	if err != nil {
+		return 0, nil, offset, err
+	}
+	r.pos = offset + 4 + size
+	return recType, record[4:], offset, nil
+}
+
+// peek returns the type of the next record, if the stream holds one more.
+func (r *recordReader) peek() (uint16, bool) {
+	if r.pos+4 > r.len() {
+		return 0, false
+	}
+	header, err := r.span(r.pos, 4)
+	if err != nil {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint16(header), true
+}
+
+// recordError reports a malformed record body.
+func recordError(recType uint16, offset int) error {
+	return fmt.Errorf("%w: malformed record 0x%04X at offset %d", ErrInvalidFormat, recType, offset)
+}
+
+// stringError reports a string of a record that could not be read: in a
+// code page without an encoding, or malformed.
+func stringError(err error, recType uint16, offset int) error {
+	if errors.Is(err, ErrUnsupportedCodePage) {
+		return fmt.Errorf("%w in record 0x%04X at offset %d", err, recType, offset)
+	}
+	return recordError(recType, offset)
+}
+
+func (wb *Workbook) parseGlobals() ([]boundSheet, error) {
+	r := wb.records(0)
+
+	recType, data, offset, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if recType != recTypeBOF || len(data) < 4 {
+		return nil, fmt.Errorf("%w: workbook stream does not start with BOF", ErrInvalidFormat)
+	}
+	if binary.LittleEndian.Uint16(data[0:2]) != biffVersion {
+		return nil, ErrUnsupportedVersion
+	}
+
+	var bounds []boundSheet
+	for {
+		recType, data, offset, err = r.next()
+		if err == io.EOF {
+			err = fmt.Errorf("%w: missing EOF in workbook globals", ErrInvalidFormat)
+		}
+		if err != nil {
+			if wb.salvage == nil {
+				return nil, err
+			}
+			wb.salvage.report(offset, err)
+			return bounds, nil
+		}
+
+		var malformed error
+		switch recType {
+		case recTypeEOF:
+			return bounds, nil
+		case recTypeFILEPASS:
+			if wb.src != nil {
+				return nil, errEncrypted
+			}
+		case recTypeDATEMODE:
+			if len(data) < 2 {
+				malformed = recordError(recType, offset)
+				break
+			}
+			wb.dateMode = binary.LittleEndian.Uint16(data)
+		case recTypeCODEPAGE:
+			if len(data) < 2 {
+				malformed = recordError(recType, offset)
+				break
+			}
+			wb.codePage = newCodePage(binary.LittleEndian.Uint16(data))
+		case recTypeFONT:
+			f, err := templateFont(data)
+			if err != nil {
+				malformed = stringError(err, recType, offset)
+				break
+			}
+			wb.fonts = append(wb.fonts, f)
+		case recTypeFORMAT:
+			if len(data) < 2 {
+				malformed = recordError(recType, offset)
+				break
+			}
+			s, _, err := readCodePageString(data[2:], 2, wb.codePage)
+			if err != nil {
+				malformed = stringError(err, recType, offset)
+				break
+			}
+			wb.formats[binary.LittleEndian.Uint16(data)] = s
+		case recTypeXF:
+			if len(data) < 4 {
+				malformed = recordError(recType, offset)
+				break
+			}
+			wb.xfFormats = append(wb.xfFormats, binary.LittleEndian.Uint16(data[2:4]))
+		case recTypeSST:
+			// The SST continues in any CONTINUE records that follow it
+			segments := [][]byte{data}
+			for next, ok := r.peek(); ok && next == recTypeCONTINUE; next, ok = r.peek() {
+				_, cont, _, err := r.next()
+				if err != nil {
+					if wb.salvage == nil {
+						return nil, err
+					}
+					break // Reported as the end of the globals
+				}
+				segments = append(segments, cont)
+			}
+			if err := wb.parseSST(segments); err != nil {
+				if errors.Is(err, ErrFileTooComplex) {
+					return nil, err
+				}
+				if errors.Is(err, ErrUnsupportedCodePage) {
+					malformed = stringError(err, recType, offset)
+				} else {
+					malformed = fmt.Errorf("%w: SST record at offset %d: %v", ErrInvalidFormat, offset, err)
+				}
+			}
+		case recTypeBOUNDSHEET:
+			if len(data) < 6 {
+				malformed = recordError(recType, offset)
+				break
+			}
+			if err := check("MaxSheets", len(bounds)+1, wb.limits.MaxSheets, true); err != nil {
+				return nil, err
+			}
+			name, _, err := readCodePageString(data[6:], 1, wb.codePage)
+			if err != nil {
+				malformed = stringError(err, recType, offset)
+				break
+			}
+			bounds = append(bounds, boundSheet{
+				name:       name,
+				offset:     binary.LittleEndian.Uint32(data[0:4]),
+				visibility: SheetVisibility(data[4] & 0x03),
+				sheetType:  data[5],
+			})
+		}
+
+		if err := wb.readPast(offset, malformed); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// formulaString reads the string of a STRING record, continued in any
+// CONTINUE records that follow it when it is longer than a record holds.
+func (wb *Workbook) formulaString(r *recordReader, data []byte) (string, error) {
+	segments := [][]byte{data}
+	for next, ok := r.peek(); ok && next == recTypeCONTINUE; next, ok = r.peek() {
+		_, cont, _, err := r.next()
+		if err != nil {
+			break // Reported as the next record
+		}
+		segments = append(segments, cont)
+	}
+	if len(segments) == 1 {
+		s, _, err := readCodePageString(data, 2, wb.codePage)
+		return s, err
+	}
+	return (&continueReader{segments: segments, codePage: wb.codePage}).unicodeString()
+}
+
+func (wb *Workbook) parseSST(segments [][]byte) error {
+	r := &continueReader{segments: segments, codePage: wb.codePage}
+	header, err := r.bytes(8)
+	if err != nil {
+		return fmt.Errorf("truncated header")
+	}
+	unique := binary.LittleEndian.Uint32(header[4:8])
+	if err := check("MaxUniqueStrings", int64(unique), int64(wb.limits.MaxUniqueStrings), true); err != nil {
+		return fmt.Errorf("SST: %w", err)
+	}
+	for i := 0; i < int(unique); i++ {
+		s, runs, err := r.richString()
+		if err != nil {
+			return fmt.Errorf("entry %d of %d: %w", i, unique, err)
+		}
+		if runs := wb.textRuns(s, runs); runs != nil {
+			if wb.sstRuns == nil {
+				wb.sstRuns = make(map[int]*[]TextRun)
+			}
+			wb.sstRuns[i] = runs
+		}
+		wb.sst = append(wb.sst, s)
+	}
+	return nil
+}
+
+// continueReader reads data split across a record and its CONTINUE records.
+type continueReader struct {
+	segments [][]byte
+	seg      int
+	pos      int
+	codePage codePage // Of the 8-bit characters
+}
+
+// advance moves to the next segment when the current one is exhausted and
+// reports whether a segment boundary was crossed.
+func (r *continueReader) advance() (bool, error) {
+	if r.seg < len(r.segments) && r.pos < len(r.segments[r.seg]) {
+		return false, nil
+	}
+	for r.seg < len(r.segments) && r.pos >= len(r.segments[r.seg]) {
+		r.seg++
+		r.pos = 0
+	}
+	if r.seg >= len(r.segments) {
+		return false, io.ErrUnexpectedEOF
+	}
+	return true, nil
+}
+
+// bytes reads n raw bytes, which may span segment boundaries.
+func (r *continueReader) bytes(n int) ([]byte, error) {
+	out := make([]byte, 0, min(n, 8224))
+	for len(out) < n {
+		if _, err := r.advance(); err != nil {
+			return nil, err
+		}
+		chunk := r.segments[r.seg][r.pos:]
+		if len(chunk) > n-len(out) {
+			chunk = chunk[:n-len(out)]
+		}
+		out = append(out, chunk...)
+		r.pos += len(chunk)
+	}
+	return out, nil
+}
+
+// skip discards n bytes, which may span segment boundaries.
+func (r *continueReader) skip(n int) error {
+	for n > 0 {
+		if _, err := r.advance(); err != nil {
+			return err
+		}
+		step := min(n, len(r.segments[r.seg])-r.pos)
+		r.pos += step
+		n -= step
+	}
+	return nil
+}
+
+// unicodeString reads an XLUnicodeRichExtendedString. When the character
+// data is split by a CONTINUE record, the continuation starts with a new
+// option byte telling whether the remaining characters are compressed.
+func (r *continueReader) unicodeString() (string, error) {
+	s, _, err := r.richString()
+	return s, err
+}
+
+// richString is unicodeString that also returns the formatting runs of
+// rich text, as they are stored.
+func (r *continueReader) richString() (string, []byte, error) {
+	header, err := r.bytes(3)
+	if err != nil {
+		return "", nil, err
+	}
+	cch := int(binary.LittleEndian.Uint16(header))
+	flags := header[2]
+
+	runs, ext := 0, 0
+	if flags&0x08 != 0 {
+		b, err := r.bytes(2)
+		if err != nil {
+			return "", nil, err
+		}
+		runs = int(binary.LittleEndian.Uint16(b))
+	}
+	if flags&0x04 != 0 {
+		b, err := r.bytes(4)
+		if err != nil {
+			return "", nil, err
+		}
+		ext = int(binary.LittleEndian.Uint32(b))
+	}
+
+	// The characters are decoded a run of 8-bit or UTF-16 characters at a
+	// time, since those of a code page may take several bytes
+	var s strings.Builder
+	var units []uint16
+	var chars []byte
+	flush := func() error {
+		if len(units) > 0 {
+			s.WriteString(string(utf16.Decode(units)))
+			units = units[:0]
+		}
+		if len(chars) > 0 {
+			decoded, err := r.codePage.decode(chars)
+			if err != nil {
+				return err
+			}
+			s.WriteString(decoded)
+			chars = chars[:0]
+		}
+		return nil
+	}
+	highByte := flags&0x01 != 0
+	for n := 0; n < cch; n++ {
+		crossed, err := r.advance()
+		if err != nil {
+			return "", nil, err
+		}
+		if crossed {
+			// A surrogate pair or multibyte character may be split
+			// across the records, so runs are only ended by a change
+			// of width
+			if wide := r.segments[r.seg][r.pos]&0x01 != 0; wide != highByte {
+				if err := flush(); err != nil {
+					return "", nil, err
+				}
+				highByte = wide
+			}
+			r.pos++
+			if _, err := r.advance(); err != nil {
+				return "", nil, err
+			}
+		}
+		seg := r.segments[r.seg]
+		if highByte {
+			if r.pos+2 > len(seg) {
+				return "", nil, fmt.Errorf("character split across CONTINUE records")
+			}
+			units = append(units, binary.LittleEndian.Uint16(seg[r.pos:]))
+			r.pos += 2
+		} else {
+			chars = append(chars, seg[r.pos])
+			r.pos++
+		}
+	}
+	if err := flush(); err != nil {
+		return "", nil, err
+	}
+
+	var rgRun []byte
+	if runs > 0 {
+		if rgRun, err = r.bytes(runs * 4); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := r.skip(ext); err != nil {
+		return "", nil, err
+	}
+	return s.String(), rgRun, nil
+}
+
+// readUnicodeString decodes a BIFF8 unicode string whose character count is
+// stored in lenSize (1 or 2) bytes. It returns the string and the number of
+// bytes consumed, including rich text runs and extended data.
+func readUnicodeString(b []byte, lenSize int) (string, int, error) {
+	return readCodePageString(b, lenSize, codePage{})
+}
+
+// readCodePageString is readUnicodeString with 8-bit characters in a code
+// page.
+func readCodePageString(b []byte, lenSize int, cp codePage) (string, int, error) {
+	if len(b) < lenSize+1 {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	var cch int
+	if lenSize == 1 {
+		cch = int(b[0])
+	} else {
+		cch = int(binary.LittleEndian.Uint16(b))
+	}
+	flags := b[lenSize]
+	pos := lenSize + 1
+
+	runs, ext := 0, 0
+	if flags&0x08 != 0 {
+		if len(b) < pos+2 {
+			return "", 0, io.ErrUnexpectedEOF
+		}
+		runs = int(binary.LittleEndian.Uint16(b[pos:]))
+		pos += 2
+	}
+	if flags&0x04 != 0 {
+		if len(b) < pos+4 {
+			return "", 0, io.ErrUnexpectedEOF
+		}
+		ext = int(binary.LittleEndian.Uint32(b[pos:]))
+		pos += 4
+	}
+
+	var s string
+	if flags&0x01 != 0 {
+		if len(b) < pos+cch*2 {
+			return "", 0, io.ErrUnexpectedEOF
+		}
+		units := make([]uint16, cch)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(b[pos+i*2:])
+		}
+		s = string(utf16.Decode(units))
+		pos += cch * 2
+	} else {
+		if len(b) < pos+cch {
+			return "", 0, io.ErrUnexpectedEOF
+		}
+		var err error
+		if s, err = cp.decode(b[pos : pos+cch]); err != nil {
+			return "", 0, err
+		}
+		pos += cch
+	}
+
+	pos += runs*4 + ext
+	if pos > len(b) {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	return s, pos, nil
+}
+
+// newCell creates a cell with the number format of the XF index resolved.
+func (wb *Workbook) newCell(kind CellKind, value interface{}, ixfe uint16) Cell {
+	cell := Cell{Kind: kind, Value: value, xf: ixfe, date1904: wb.dateMode == 1}
+	if int(ixfe) < len(wb.xfFormats) {
+		cell.formatIndex = wb.xfFormats[ixfe]
+		if s, ok := wb.formats[cell.formatIndex]; ok {
+			cell.FormatString = s
+		} else {
+			cell.FormatString = builtinFormats[cell.formatIndex]
+		}
+	}
+	return cell
+}
+
+// walkSheet decodes the worksheet substream at offset and calls visit for
+// each cell in stream order. An error returned by visit is returned as is.
+func (wb *Workbook) walkSheet(offset uint32, visit func(row, col int, cell Cell) error) error {
+	if int64(offset) >= int64(wb.size) {
+		return fmt.Errorf("%w: sheet offset %d beyond end of stream", ErrInvalidFormat, offset)
+	}
+	r := wb.records(offset)
+
+	recType, data, recOffset, err := r.next()
+	if err != nil {
+		return err
+	}
+	if recType != recTypeBOF || len(data) < 4 || binary.LittleEndian.Uint16(data[2:4]) != bofWorksheet {
+		return fmt.Errorf("%w: no worksheet BOF at offset %d", ErrInvalidFormat, recOffset)
+	}
+
+	// Cells beyond the limits of the workbook end the sheet
+	visitCell := visit
+	visit = func(row, col int, cell Cell) error {
+		if err := wb.limits.checkCell(row, col, true); err != nil {
+			return err
+		}
+		if s, ok := cell.Value.(string); ok {
+			if err := wb.limits.checkText(s, row, col, true); err != nil {
+				return err
+			}
+		}
+		return visitCell(row, col, cell)
+	}
+
+	// A FORMULA record with a string result is followed by a STRING record
+	var pendingRow, pendingCol int
+	var pending *Cell
+	depth := 0
+
+	for {
+		recType, data, recOffset, err = r.next()
+		if err == io.EOF {
+			err = fmt.Errorf("%w: missing EOF in worksheet", ErrInvalidFormat)
+		}
+		if err != nil {
+			if err := wb.readPast(recOffset, err); err != nil {
+				return err
+			}
+			// Keep a formula cut off from its STRING record without a value
+			if pending != nil {
+				return visit(pendingRow, pendingCol, *pending)
+			}
+			return nil
+		}
+
+		if pending != nil {
+			// Shared formula, array and table records may sit between
+			// a FORMULA record and its STRING record
+			if recType == recTypeSHRFMLA || recType == recTypeARRAY || recType == recTypeTABLE {
+				continue
+			}
+			if recType == recTypeSTRING {
+				s, err := wb.formulaString(r, data)
+				if err != nil {
+					if err := wb.readPast(recOffset, stringError(err, recType, recOffset)); err != nil {
+						return err
+					}
+				} else {
+					pending.Value = s
+				}
+			}
+			if err := visit(pendingRow, pendingCol, *pending); err != nil {
+				return err
+			}
+			pending = nil
+			if recType == recTypeSTRING {
+				continue
+			}
+		}
+
+		// Embedded charts nest BOF ... EOF substreams in the worksheet
+		if recType == recTypeBOF {
+			if depth++; depth > maxSubstreamDepth {
+				return fmt.Errorf("%w: substreams nested deeper than %d at offset %d", ErrFileTooComplex, maxSubstreamDepth, recOffset)
+			}
+			continue
+		}
+		if depth > 0 {
+			if recType == recTypeEOF {
+				depth--
+			}
+			continue
+		}
+
+		var malformed error
+		switch recType {
+		case recTypeEOF:
+			return nil
+		case recTypeNUMBER:
+			if len(data) < 14 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			v := math.Float64frombits(binary.LittleEndian.Uint64(data[6:14]))
+			if err := visit(row, col, wb.newCell(KindNumber, v, ixfe)); err != nil {
+				return err
+			}
+		case recTypeRK:
+			if len(data) < 10 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			v := decodeRK(binary.LittleEndian.Uint32(data[6:10]))
+			if err := visit(row, col, wb.newCell(KindNumber, v, ixfe)); err != nil {
+				return err
+			}
+		case recTypeMULRK:
+			if len(data) < 6 || (len(data)-6)%6 != 0 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, first, _ := cellHeader(data)
+			last := int(binary.LittleEndian.Uint16(data[len(data)-2:]))
+			count := (len(data) - 6) / 6
+			if last-first+1 != count {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			for i := 0; i < count; i++ {
+				item := data[4+i*6:]
+				ixfe := binary.LittleEndian.Uint16(item[0:2])
+				v := decodeRK(binary.LittleEndian.Uint32(item[2:6]))
+				if err := visit(row, first+i, wb.newCell(KindNumber, v, ixfe)); err != nil {
+					return err
+				}
+			}
+		case recTypeBLANK:
+			if len(data) < 6 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			if err := visit(row, col, wb.newCell(KindBlank, nil, ixfe)); err != nil {
+				return err
+			}
+		case recTypeMULBLANK:
+			if len(data) < 6 || len(data)%2 != 0 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, first, _ := cellHeader(data)
+			last := int(binary.LittleEndian.Uint16(data[len(data)-2:]))
+			count := (len(data) - 6) / 2
+			if last-first+1 != count {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			for i := 0; i < count; i++ {
+				ixfe := binary.LittleEndian.Uint16(data[4+i*2:])
+				if err := visit(row, first+i, wb.newCell(KindBlank, nil, ixfe)); err != nil {
+					return err
+				}
+			}
+		case recTypeLABEL, recTypeRSTRING:
+			if len(data) < 6 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			s, n, err := readCodePageString(data[6:], 2, wb.codePage)
+			if err != nil {
+				malformed = stringError(err, recType, recOffset)
+				break
+			}
+			cell := wb.newCell(KindText, s, ixfe)
+			if runs := data[6+n:]; recType == recTypeRSTRING && len(runs) >= 2 {
+				// The formatting runs follow the string; those cut short
+				// are dropped
+				count := int(binary.LittleEndian.Uint16(runs))
+				runs = runs[2:]
+				cell.runs = wb.textRuns(s, runs[:min(4*count, len(runs)&^3)])
+			}
+			if err := visit(row, col, cell); err != nil {
+				return err
+			}
+		case recTypeLABELSST:
+			if len(data) < 10 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			index := binary.LittleEndian.Uint32(data[6:10])
+			if int64(index) >= int64(len(wb.sst)) {
+				malformed = fmt.Errorf("%w: SST index %d out of range at offset %d", ErrInvalidFormat, index, recOffset)
+				break
+			}
+			cell := wb.newCell(KindText, wb.sst[index], ixfe)
+			cell.runs = wb.sstRuns[int(index)]
+			if err := visit(row, col, cell); err != nil {
+				return err
+			}
+		case recTypeBOOLERR:
+			if len(data) < 8 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			cell := wb.newCell(KindBool, data[6] != 0, ixfe)
+			if data[7] != 0 {
+				cell.Kind, cell.Value = KindError, CellError(data[6])
+			}
+			if err := visit(row, col, cell); err != nil {
+				return err
+			}
+		case recTypeFORMULA:
+			if len(data) < 22 {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			row, col, ixfe := cellHeader(data)
+			cell := wb.newCell(KindFormula, nil, ixfe)
+
+			cce := int(binary.LittleEndian.Uint16(data[20:22]))
+			if 22+cce > len(data) {
+				malformed = recordError(recType, recOffset)
+				break
+			}
+			if expr, err := decompileFormula(data[22 : 22+cce]); err == nil {
+				cell.Formula = expr
+			}
+
+			isString := false
+			if data[12] == 0xFF && data[13] == 0xFF {
+				switch data[6] {
+				case 0x00:
+					isString = true
+				case 0x01:
+					cell.Value = data[8] != 0
+				case 0x02:
+					cell.Value = CellError(data[8])
+				case 0x03:
+					cell.Value = ""
+				}
+			} else {
+				cell.Value = math.Float64frombits(binary.LittleEndian.Uint64(data[6:14]))
+			}
+
+			if isString {
+				pending, pendingRow, pendingCol = &cell, row, col
+			} else {
+				if err := visit(row, col, cell); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := wb.readPast(recOffset, malformed); err != nil {
+			return err
+		}
+	}
+}
+
+// cellHeader decodes the row, column and XF index common to cell records.
+func cellHeader(data []byte) (int, int, uint16) {
+	return int(binary.LittleEndian.Uint16(data[0:2])),
+		int(binary.LittleEndian.Uint16(data[2:4])),
+		binary.LittleEndian.Uint16(data[4:6])
+}
+
+// decodeRK decodes an RK number: a 30-bit integer or the high 30 bits of a
+// float64, optionally multiplied by 100.
+func decodeRK(rk uint32) float64 {
+	var v float64
+	if rk&0x02 != 0 {
+		v = float64(int32(rk) >> 2)
+	} else {
+		v = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		v /= 100
+	}
+	return v
+}
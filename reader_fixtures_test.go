@@ -0,0 +1,317 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update files in testdata")
+
+// le encodes values in little-endian order. Ints are written as uint16.
+func le(parts ...interface{}) []byte {
+	var b []byte
+	for _, p := range parts {
+		switch v := p.(type) {
+		case byte:
+			b = append(b, v)
+		case int:
+			b = binary.LittleEndian.AppendUint16(b, uint16(v))
+		case uint16:
+			b = binary.LittleEndian.AppendUint16(b, v)
+		case uint32:
+			b = binary.LittleEndian.AppendUint32(b, v)
+		case float64:
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		case []byte:
+			b = append(b, v...)
+		case string:
+			b = append(b, v...)
+		default:
+			panic("unsupported fixture value")
+		}
+	}
+	return b
+}
+
+func utf16le(s string) []byte {
+	return stringToUTF16LE(s)
+}
+
+func rkInt(v int32) uint32 {
+	return uint32(v<<2) | 0x02
+}
+
+// buildFixture assembles a single-sheet workbook the way Excel lays it out:
+// globals with the given extra records, one BOUNDSHEET, then the sheet.
+func buildFixture(t *testing.T, globals, cells []testRecord) []byte {
+	t.Helper()
+
+	bof := func(subType int) testRecord {
+		return testRecord{recTypeBOF, le(0x0600, subType, 0x0DBB, 0x07CC, uint32(0), uint32(6))}
+	}
+	xfs := []testRecord{
+		{recTypeXF, le(0, 0, 0, make([]byte, 14))},    // XF 0: General
+		{recTypeXF, le(0, 14, 0, make([]byte, 14))},   // XF 1: built-in date
+		{recTypeXF, le(0, 0xA4, 0, make([]byte, 14))}, // XF 2: custom percent
+	}
+	format := testRecord{recTypeFORMAT, le(0xA4, 4, byte(0), "0.0%")}
+
+	var head []testRecord
+	head = append(head, bof(bofWorkbook), format)
+	head = append(head, xfs...)
+	head = append(head, globals...)
+
+	var stream bytes.Buffer
+	for _, rec := range head {
+		stream.Write(le(rec.recType, len(rec.data), rec.data))
+	}
+	name := "Sheet1"
+	sheetOffset := stream.Len() + 4 + 8 + len(name) + 4
+	stream.Write(le(recTypeBOUNDSHEET, 8+len(name), uint32(sheetOffset), byte(0), byte(0), byte(len(name)), byte(0), name))
+	stream.Write(le(recTypeEOF, 0))
+
+	sheet := append([]testRecord{bof(bofWorksheet)}, cells...)
+	sheet = append(sheet, testRecord{recTypeEOF, nil})
+	for _, rec := range sheet {
+		stream.Write(le(rec.recType, len(rec.data), rec.data))
+	}
+
+	file := new(bytes.Buffer)
+	if err := WriteCFB(file, stream.Bytes()); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+	return file.Bytes()
+}
+
+var readerFixtures = []struct {
+	name    string
+	globals []testRecord
+	cells   []testRecord
+	want    [][]interface{}
+	kinds   [][]CellKind
+}{
+	{
+		name: "rk_mulrk.xls",
+		cells: []testRecord{
+			{recTypeRK, le(0, 0, 0, rkInt(42))},
+			{recTypeRK, le(0, 1, 0, rkInt(1234)|0x01)},
+			{recTypeRK, le(0, 2, 0, uint32(0x3FF80000))},
+			{recTypeRK, le(0, 3, 0, rkInt(-7))},
+			{recTypeMULRK, le(1, 0, 0, rkInt(1), 1, rkInt(45444), 0, rkInt(3), 2)},
+		},
+		want: [][]interface{}{
+			{42.0, 12.34, 1.5, -7.0},
+			{1.0, 45444.0, 3.0},
+		},
+	},
+	{
+		name: "labels.xls",
+		cells: []testRecord{
+			{recTypeLABEL, le(0, 0, 0, 7, byte(0), []byte("Latin \xe9"))},
+			{recTypeLABEL, le(0, 1, 0, 3, byte(1), utf16le("日本語"))},
+			{recTypeRSTRING, le(1, 0, 0, 4, byte(0), "Rich", 2, 0, 0, 2, 1)},
+		},
+		want: [][]interface{}{
+			{"Latin é", "日本語"},
+			{"Rich"},
+		},
+	},
+	{
+		name: "blanks.xls",
+		cells: []testRecord{
+			{recTypeBLANK, le(0, 1, 1)},
+			{recTypeMULBLANK, le(1, 0, 0, 1, 2, 2)},
+			{recTypeNUMBER, le(1, 3, 2, 0.25)},
+		},
+		want: [][]interface{}{
+			{nil, nil},
+			{nil, nil, nil, 0.25},
+		},
+		kinds: [][]CellKind{
+			{KindBlank, KindBlank},
+			{KindBlank, KindBlank, KindBlank, KindNumber},
+		},
+	},
+	{
+		name: "sst_continue.xls",
+		globals: []testRecord{
+			{recTypeSST, le(uint32(4), uint32(3),
+				5, byte(0), "alpha",
+				6, byte(0), "abc")},
+			// Remaining characters switch to UTF-16 after the boundary
+			{recTypeCONTINUE, le(byte(1), utf16le("δεζ"),
+				4, byte(0x08), 1, "bold", uint16(0))},
+			// The rich text run itself spans the boundary
+			{recTypeCONTINUE, le(uint16(1))},
+		},
+		cells: []testRecord{
+			{recTypeLABELSST, le(0, 0, 0, uint32(0))},
+			{recTypeLABELSST, le(0, 1, 0, uint32(1))},
+			{recTypeLABELSST, le(0, 2, 0, uint32(2))},
+			{recTypeLABELSST, le(1, 0, 0, uint32(1))},
+		},
+		want: [][]interface{}{
+			{"alpha", "abcδεζ", "bold"},
+			{"abcδεζ"},
+		},
+	},
+	{
+		name: "cp932.xls",
+		globals: []testRecord{
+			{recTypeCODEPAGE, le(932)},
+			{recTypeSST, le(uint32(2), uint32(2),
+				4, byte(0), []byte("\x94\x84\x8f\xe3"),
+				4, byte(0), []byte("\x93\x8c\x8b"))},
+			// A double-byte character spans the boundary
+			{recTypeCONTINUE, le(byte(0), []byte("\x9e"))},
+		},
+		cells: []testRecord{
+			{recTypeLABELSST, le(0, 0, 0, uint32(0))},
+			{recTypeLABELSST, le(0, 1, 0, uint32(1))},
+			{recTypeLABEL, le(0, 2, 0, 3, byte(0), []byte("\xb1\xb2\xb3"))},
+		},
+		want: [][]interface{}{
+			{"売上", "東京", "ｱｲｳ"},
+		},
+	},
+	{
+		name: "cp1251.xls",
+		globals: []testRecord{
+			{recTypeCODEPAGE, le(1251)},
+			{recTypeSST, le(uint32(1), uint32(1), 6, byte(0), []byte("\xcf\xf0\xe8\xe2\xe5\xf2"))},
+		},
+		cells: []testRecord{
+			{recTypeLABELSST, le(0, 0, 0, uint32(0))},
+			{recTypeLABEL, le(0, 1, 0, 6, byte(0), []byte("\xcc\xee\xf1\xea\xe2\xe0"))},
+			{recTypeLABEL, le(0, 2, 0, 3, byte(1), utf16le("Ёж!"))},
+		},
+		want: [][]interface{}{
+			{"Привет", "Москва", "Ёж!"},
+		},
+	},
+	{
+		name: "formulas.xls",
+		cells: []testRecord{
+			{recTypeFORMULA, le(0, 0, 0, []byte{0, 0, 0, 0, 0, 0, 0xFF, 0xFF}, 0, uint32(0), 3, byte(ptgStr), byte(1), byte(0), "x")},
+			{recTypeSHRFMLA, le(0, 0, byte(0), byte(0), 0, byte(0), byte(1), 0)},
+			{recTypeSTRING, le(5, byte(0), "hello")},
+			// Shared formula reference (ptgExp) cannot be decompiled
+			{recTypeFORMULA, le(0, 1, 0, 7.0, 0, uint32(0), 5, byte(0x01), 0, 0)},
+			{recTypeFORMULA, le(0, 2, 0, []byte{1, 0, 1, 0, 0, 0, 0xFF, 0xFF}, 0, uint32(0), 2, byte(ptgBool), byte(1))},
+		},
+		want: [][]interface{}{
+			{"hello", 7.0, true},
+		},
+	},
+}
+
+func TestReadFixtures(t *testing.T) {
+	for _, fx := range readerFixtures {
+		t.Run(fx.name, func(t *testing.T) {
+			path := filepath.Join("testdata", fx.name)
+			if *update {
+				if err := os.WriteFile(path, buildFixture(t, fx.globals, fx.cells), 0o644); err != nil {
+					t.Fatalf("Failed to write fixture: %v", err)
+				}
+			}
+
+			wb, err := OpenFile(path)
+			if err != nil {
+				t.Fatalf("OpenFile() failed: %v", err)
+			}
+			rows := wb.Sheets()[0].Rows()
+			if len(rows) != len(fx.want) {
+				t.Fatalf("Expected %d rows, got %d", len(fx.want), len(rows))
+			}
+			for r, want := range fx.want {
+				if len(rows[r]) != len(want) {
+					t.Errorf("Row %d: expected %d cells, got %d", r, len(want), len(rows[r]))
+					continue
+				}
+				for c, v := range want {
+					if rows[r][c].Value != v {
+						t.Errorf("Cell(%d, %d): expected %v, got %v", r, c, v, rows[r][c].Value)
+					}
+					if fx.kinds != nil && rows[r][c].Kind != fx.kinds[r][c] {
+						t.Errorf("Cell(%d, %d): expected kind %s, got %s", r, c, fx.kinds[r][c], rows[r][c].Kind)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadFixtureFormats(t *testing.T) {
+	wb, err := OpenFile(filepath.Join("testdata", "blanks.xls"))
+	if err != nil {
+		t.Fatalf("OpenFile() failed: %v", err)
+	}
+	sheet := wb.Sheets()[0]
+
+	if f := sheet.Cell(0, 1).FormatString; f != "m/d/yy" {
+		t.Errorf("Expected built-in date format on blank cell, got '%s'", f)
+	}
+	if f := sheet.Cell(1, 3).FormatString; f != "0.0%" {
+		t.Errorf("Expected custom format '0.0%%', got '%s'", f)
+	}
+
+	wb, err = OpenFile(filepath.Join("testdata", "rk_mulrk.xls"))
+	if err != nil {
+		t.Fatalf("OpenFile() failed: %v", err)
+	}
+	if !wb.Sheets()[0].Cell(1, 1).IsDate() {
+		t.Error("Expected MULRK cell with date XF to be a date")
+	}
+}
+
+func TestReadMalformedRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		globals []testRecord
+		cells   []testRecord
+	}{
+		{"short RK", nil, []testRecord{{recTypeRK, le(0, 0, 0)}}},
+		{"MULRK column mismatch", nil, []testRecord{{recTypeMULRK, le(0, 0, 0, rkInt(1), 5)}}},
+		{"MULBLANK column mismatch", nil, []testRecord{{recTypeMULBLANK, le(0, 0, 0, 0, 9)}}},
+		{"SST index out of range", nil, []testRecord{{recTypeLABELSST, le(0, 0, 0, uint32(3))}}},
+		{"truncated LABEL", nil, []testRecord{{recTypeLABEL, le(0, 0, 0, 50, byte(0), "abc")}}},
+		{"short FORMULA", nil, []testRecord{{recTypeFORMULA, le(0, 0, 0, 1.0)}}},
+		{"SST count too large", []testRecord{{recTypeSST, le(uint32(9), uint32(9), 1, byte(0), "a")}}, nil},
+		{"SST split character", []testRecord{
+			{recTypeSST, le(uint32(1), uint32(1), 2, byte(1), byte(0x61))},
+			{recTypeCONTINUE, le(byte(1), byte(0), byte(0x62), byte(0))},
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openWorkbook(buildFixture(t, tt.globals, tt.cells))
+			if !errors.Is(err, ErrInvalidFormat) {
+				t.Fatalf("Expected ErrInvalidFormat, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "offset") {
+				t.Errorf("Expected error to include the stream offset, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadTruncatedStream(t *testing.T) {
+	data := buildFixture(t, nil, []testRecord{{recTypeNUMBER, le(0, 0, 0, 1.0)}})
+
+	// Declare a record length running past the padded stream
+	stream := bytes.Index(data, le(recTypeNUMBER, 14))
+	binary.LittleEndian.PutUint16(data[stream+2:], 0xFFFF)
+
+	_, err := openWorkbook(data)
+	if !errors.Is(err, ErrInvalidFormat) || !strings.Contains(err.Error(), "offset") {
+		t.Errorf("Expected ErrInvalidFormat with offset, got %v", err)
+	}
+}
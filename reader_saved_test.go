@@ -0,0 +1,187 @@
+package xls
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// savedGrid is the values of the cells of a sheet of a saved file, by row.
+type savedGrid struct {
+	sheet string
+	rows  [][]interface{}
+}
+
+// savedFiles are workbooks saved by Excel and LibreOffice, kept in
+// testdata/saved and made as testdata/saved/README.md describes. Unlike the
+// fixtures built by the tests, they hold what the applications write:
+// their record layouts, the SST split as they split it, the summary
+// information streams. A file that is not checked in fails the test.
+var savedFiles = []struct {
+	name  string
+	grids []savedGrid
+	check func(t *testing.T, wb *Workbook)
+}{
+	{
+		name:  "excel_cells.xls",
+		grids: savedCellGrids,
+		check: checkSavedCells,
+	},
+	{
+		name:  "libreoffice_cells.xls",
+		grids: savedCellGrids,
+		check: checkSavedCells,
+	},
+	{
+		name:  "excel_layout.xls",
+		check: checkSavedLayout,
+	},
+}
+
+// savedCellGrids is the content of the cells files: integers and decimals,
+// which Excel keeps in RK and MULRK records, a date, and strings long
+// enough for the SST to continue in CONTINUE records.
+var savedCellGrids = []savedGrid{
+	{"Numbers", [][]interface{}{
+		{42.0, 12.34, 1.5, -7.0},
+		{1.0, 45444.0, 3.0},
+	}},
+	{"Strings", [][]interface{}{
+		{"alpha", "日本語", "Latin é"},
+		{strings.Repeat("abcdefghij", 1000)},
+		{"alpha"},
+	}},
+}
+
+func checkSavedCells(t *testing.T, wb *Workbook) {
+	s, err := wb.Sheet("Numbers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Cell(1, 1).IsDate() {
+		t.Errorf("Expected B2 to be a date, got format %q", s.Cell(1, 1).FormatString)
+	}
+}
+
+func TestReadSavedFiles(t *testing.T) {
+	for _, sf := range savedFiles {
+		t.Run(sf.name, func(t *testing.T) {
+			path := filepath.Join("testdata", "saved", sf.name)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("%s is missing: save it as testdata/saved/README.md describes", path)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Files the applications saved carry summary information,
+			// which this package never writes
+			cfb, err := ReadCFB(data)
+			if err != nil {
+				t.Fatalf("ReadCFB() failed: %v", err)
+			}
+			if _, err := cfb.Stream("\x05SummaryInformation"); err != nil {
+				t.Errorf("Expected a SummaryInformation stream, as applications write: %v", err)
+			}
+
+			wb, err := OpenFile(path)
+			if err != nil {
+				t.Fatalf("OpenFile() failed: %v", err)
+			}
+			for _, g := range sf.grids {
+				s, err := wb.Sheet(g.sheet)
+				if err != nil {
+					t.Errorf("Sheet(%q) failed: %v", g.sheet, err)
+					continue
+				}
+				rows := s.Rows()
+				if len(rows) != len(g.rows) {
+					t.Errorf("%s: expected %d rows, got %d", g.sheet, len(g.rows), len(rows))
+					continue
+				}
+				for r, want := range g.rows {
+					if len(rows[r]) != len(want) {
+						t.Errorf("%s row %d: expected %d cells, got %d", g.sheet, r, len(want), len(rows[r]))
+						continue
+					}
+					for c, v := range want {
+						if rows[r][c].Value != v {
+							t.Errorf("%s cell (%d, %d): expected %v, got %v", g.sheet, r, c, v, rows[r][c].Value)
+						}
+					}
+				}
+			}
+			if sf.check != nil {
+				sf.check(t, wb)
+			}
+		})
+	}
+}
+
+// checkSavedLayout checks the merged cells, column widths, row heights and
+// hyperlinks of excel_layout.xls, the cells of the links and the sheet one
+// links to. The merged
+// ranges may hold blank cells, so the sheet has no grid. Excel stores the
+// widths with the padding of the default font, so they are checked to
+// within a character.
+func checkSavedLayout(t *testing.T, wb *Workbook) {
+	s, err := wb.Sheet("Layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cell, want := range map[[2]int]string{{0, 0}: "Site", {1, 1}: "File", {2, 2}: "Here", {3, 0}: "Mail"} {
+		if got := s.Cell(cell[0], cell[1]).Value; got != want {
+			t.Errorf("Cell %v: expected %q, got %v", cell, want, got)
+		}
+	}
+
+	wantMerged := []Range{{5, 6, 0, 3}, {9, 11, 2, 3}}
+	if got := s.MergedRanges(); !reflect.DeepEqual(got, wantMerged) {
+		t.Errorf("Expected merged ranges %v, got %v", wantMerged, got)
+	}
+
+	widths := s.ColWidths()
+	for col, want := range map[int]float64{0: 20, 2: 5.5, 3: 5.5, 4: 5.5, 5: 0} {
+		if got, ok := widths[col]; !ok || math.Abs(got-want) >= 1 {
+			t.Errorf("Column %d: expected a width of about %v, got %v", col, want, got)
+		}
+	}
+	heights := s.RowHeights()
+	for row, want := range map[int]float64{0: 30, 2: 0} {
+		if got, ok := heights[row]; !ok || got != want {
+			t.Errorf("Row %d: expected a height of %v, got %v", row, want, got)
+		}
+	}
+
+	// Excel writes a URL moniker for web and mailto links, a file moniker
+	// for files and a location alone for places in the workbook
+	want := map[Range]Hyperlink{
+		{0, 0, 0, 0}: {URL: "https://example.com/a"},
+		{1, 1, 1, 1}: {URL: "data.xls"},
+		{2, 2, 2, 2}: {Location: "Sheet2!A1", Tooltip: "Go there"},
+		{3, 3, 0, 0}: {URL: "mailto:sales@example.com"},
+	}
+	if _, err := wb.Sheet("Sheet2"); err != nil {
+		t.Errorf("Expected the sheet the link of C3 goes to: %v", err)
+	}
+	links := s.Hyperlinks()
+	if len(links) != len(want) {
+		t.Errorf("Expected %d hyperlinks, got %+v", len(want), links)
+	}
+	for _, link := range links {
+		w, ok := want[link.Range]
+		if !ok {
+			t.Errorf("Unexpected hyperlink %+v", link)
+			continue
+		}
+		if link.URL != w.URL || link.Location != w.Location || link.Tooltip != w.Tooltip {
+			t.Errorf("Hyperlink %v: expected %+v, got %+v", link.Range, w, link)
+		}
+	}
+}
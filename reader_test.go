@@ -0,0 +1,560 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTestWorkbook serializes the writer and opens the result with the reader.
+func writeTestWorkbook(t *testing.T, w *Writer) *Workbook {
+	t.Helper()
+
+	stream := new(bytes.Buffer)
+	if err := w.writeBIFF8(stream); err != nil {
+		t.Fatalf("writeBIFF8() failed: %v", err)
+	}
+	file := new(bytes.Buffer)
+	if err := WriteCFB(file, stream.Bytes()); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+
+	wb, err := openWorkbook(file.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	return wb
+}
+
+func TestReadRoundTrip(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.SetSheetName("Data")
+
+	data := [][]interface{}{
+		{"Name", "Age", "Active"},
+		{"Alice", 30, true},
+		{"Bob", 2.5, false},
+		{"Alice", CellErrorNA},
+	}
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	wb := writeTestWorkbook(t, w)
+
+	if len(wb.Sheets()) != 1 {
+		t.Fatalf("Expected 1 sheet, got %d", len(wb.Sheets()))
+	}
+	sheet, err := wb.Sheet("Data")
+	if err != nil {
+		t.Fatalf("Sheet() failed: %v", err)
+	}
+
+	tests := []struct {
+		row, col int
+		kind     CellKind
+		value    interface{}
+	}{
+		{0, 0, KindText, "Name"},
+		{1, 0, KindText, "Alice"},
+		{1, 1, KindNumber, 30.0},
+		{1, 2, KindBool, true},
+		{2, 1, KindNumber, 2.5},
+		{2, 2, KindBool, false},
+		{3, 0, KindText, "Alice"},
+		{3, 1, KindError, CellErrorNA},
+		{3, 2, KindBlank, nil},
+		{10, 10, KindBlank, nil},
+	}
+	for _, tt := range tests {
+		cell := sheet.Cell(tt.row, tt.col)
+		if cell.Kind != tt.kind {
+			t.Errorf("Cell(%d, %d): expected kind %s, got %s", tt.row, tt.col, tt.kind, cell.Kind)
+		}
+		if cell.Value != tt.value {
+			t.Errorf("Cell(%d, %d): expected value %v, got %v", tt.row, tt.col, tt.value, cell.Value)
+		}
+	}
+
+	if f := sheet.Cell(1, 1).FormatString; f != "General" {
+		t.Errorf("Expected format 'General', got '%s'", f)
+	}
+
+	rows := sheet.Rows()
+	if len(rows) != 4 {
+		t.Fatalf("Expected 4 rows, got %d", len(rows))
+	}
+	if len(rows[3]) != 2 {
+		t.Errorf("Expected 2 cells in last row, got %d", len(rows[3]))
+	}
+}
+
+func TestReadFormulaCachedValues(t *testing.T) {
+	w := New()
+	defer w.Close()
+
+	data := [][]interface{}{
+		{1, 2},
+		{
+			FormulaCell{Expr: "SUM(A1:B1)", Cached: 3},
+			FormulaCell{Expr: `"n="&A1`, Cached: "n=1"},
+			FormulaCell{Expr: "A1>B1", Cached: false},
+			FormulaCell{Expr: "A1/0", Cached: CellErrorDiv0},
+			FormulaCell{Expr: "ROUND($A$1*1.5,0)"},
+		},
+	}
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	sheet := writeTestWorkbook(t, w).Sheets()[0]
+
+	tests := []struct {
+		col     int
+		formula string
+		value   interface{}
+	}{
+		{0, "SUM(A1:B1)", 3.0},
+		{1, `"n="&A1`, "n=1"},
+		{2, "A1>B1", false},
+		{3, "A1/0", CellErrorDiv0},
+		{4, "ROUND($A$1*1.5,0)", 0.0},
+	}
+	for _, tt := range tests {
+		cell := sheet.Cell(1, tt.col)
+		if cell.Kind != KindFormula {
+			t.Errorf("Column %d: expected kind Formula, got %s", tt.col, cell.Kind)
+		}
+		if cell.Formula != tt.formula {
+			t.Errorf("Column %d: expected formula '%s', got '%s'", tt.col, tt.formula, cell.Formula)
+		}
+		if cell.Value != tt.value {
+			t.Errorf("Column %d: expected cached value %v, got %v", tt.col, tt.value, cell.Value)
+		}
+	}
+}
+
+func TestReadSheetNotFound(t *testing.T) {
+	w := New()
+	defer w.Close()
+
+	wb := writeTestWorkbook(t, w)
+	if _, err := wb.Sheet("Missing"); !errors.Is(err, ErrSheetNotFound) {
+		t.Errorf("Expected ErrSheetNotFound, got %v", err)
+	}
+}
+
+func TestForEachRow(t *testing.T) {
+	w := New()
+	defer w.Close()
+
+	data := [][]interface{}{
+		{"a", 1},
+		{},
+		{nil, nil, "c"},
+		{FormulaCell{Expr: `"x"&A1`, Cached: "xa"}},
+	}
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	wb := writeTestWorkbook(t, w)
+
+	var rows []int
+	var values []interface{}
+	err := wb.ForEachRow("Sheet1", func(rowIndex int, cells []Cell) error {
+		rows = append(rows, rowIndex)
+		values = append(values, cells[len(cells)-1].Value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachRow() failed: %v", err)
+	}
+
+	wantRows := []int{0, 2, 3}
+	wantValues := []interface{}{1.0, "c", "xa"}
+	if len(rows) != len(wantRows) {
+		t.Fatalf("Expected rows %v, got %v", wantRows, rows)
+	}
+	for i := range wantRows {
+		if rows[i] != wantRows[i] || values[i] != wantValues[i] {
+			t.Errorf("Row %d: expected %d/%v, got %d/%v", i, wantRows[i], wantValues[i], rows[i], values[i])
+		}
+	}
+}
+
+func TestForEachRowStop(t *testing.T) {
+	w := New()
+	defer w.Close()
+
+	if err := w.Write([][]interface{}{{1}, {2}, {3}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	wb := writeTestWorkbook(t, w)
+
+	calls := 0
+	err := wb.ForEachRow("Sheet1", func(rowIndex int, cells []Cell) error {
+		calls++
+		return ErrStop
+	})
+	if err != nil {
+		t.Errorf("Expected nil error for ErrStop, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+
+	errBoom := errors.New("boom")
+	err = wb.ForEachRow("Sheet1", func(rowIndex int, cells []Cell) error {
+		if rowIndex == 1 {
+			return errBoom
+		}
+		return nil
+	})
+	if err != errBoom {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+
+	if err := wb.ForEachRow("Missing", nil); !errors.Is(err, ErrSheetNotFound) {
+		t.Errorf("Expected ErrSheetNotFound, got %v", err)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	tmpFile := "test_open.xls"
+	defer os.Remove(tmpFile)
+
+	data := [][]interface{}{
+		{"こんにちは", 1},
+	}
+	if err := WriteToFile(tmpFile, data); err != nil {
+		t.Fatalf("WriteToFile() failed: %v", err)
+	}
+
+	wb, err := OpenFile(tmpFile)
+	if err != nil {
+		t.Fatalf("OpenFile() failed: %v", err)
+	}
+	if v := wb.Sheets()[0].Cell(0, 0).Value; v != "こんにちは" {
+		t.Errorf("Expected 'こんにちは', got '%v'", v)
+	}
+}
+
+// countingReaderAt counts the bytes read from a file, and records the
+// ranges read.
+type countingReaderAt struct {
+	r      io.ReaderAt
+	read   int
+	ranges [][2]int // Start and end of each read
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read += n
+	c.ranges = append(c.ranges, [2]int{int(off), int(off) + n})
+	return n, err
+}
+
+func TestOpenReader(t *testing.T) {
+	// A small first sheet, then large ones of numbers
+	small := make([][]interface{}, 500)
+	for r := range small {
+		small[r] = []interface{}{fmt.Sprintf("item %d", r), float64(r) + 0.5}
+	}
+	w := New(WithSheetName("Small"))
+	if err := w.Write(small); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	large := make([][]interface{}, 10000)
+	for r := range large {
+		large[r] = []interface{}{float64(r) + 0.5, 1.5, 2.5, 3.5, 4.5}
+	}
+	for _, name := range []string{"Large 1", "Large 2", "Large 3"} {
+		s, err := w.AddSheet(name)
+		if err != nil {
+			t.Fatalf("AddSheet() failed: %v", err)
+		}
+		if err := s.Write(large); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	r := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	wb, err := OpenReader(r, int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenReader() failed: %v", err)
+	}
+	sheet := wb.Sheets()[0]
+	if got := sheet.Cell(499, 0).Value; got != "item 499" {
+		t.Errorf("Expected the last row of the small sheet, got %v", got)
+	}
+
+	// The globals and the small sheet lie at the start of the Workbook
+	// stream, the first sector of the file; the header, a directory sector
+	// and two sectors of the FAT are needed as well
+	needed := int(wb.Sheets()[1].offset) + 4*cfbSectorSize
+	if r.read > 2*needed {
+		t.Errorf("Expected at most %d bytes read for %d needed, got %d of %d", 2*needed, needed, r.read, buf.Len())
+	}
+
+	if got := wb.Sheets()[3].Cell(9999, 0).Value; got != 9999.5 {
+		t.Errorf("Expected the last row of a large sheet, got %v", got)
+	}
+}
+
+func TestOpenReaderSheetRange(t *testing.T) {
+	data := make([][]interface{}, 1000)
+	for r := range data {
+		data[r] = []interface{}{float64(r), "cell", 1.5}
+	}
+	w := New(WithSheetName("Sheet 0"))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	for i := 1; i < 10; i++ {
+		s, err := w.AddSheet(fmt.Sprintf("Sheet %d", i))
+		if err != nil {
+			t.Fatalf("AddSheet() failed: %v", err)
+		}
+		if err := s.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	file := buf.Bytes()
+
+	r := &countingReaderAt{r: bytes.NewReader(file)}
+	wb, err := OpenReader(r, int64(len(file)))
+	if err != nil {
+		t.Fatalf("OpenReader() failed: %v", err)
+	}
+	if len(wb.Sheets()) != 10 {
+		t.Fatalf("Expected 10 sheets, got %d", len(wb.Sheets()))
+	}
+	r.ranges = nil
+
+	sheet := wb.Sheets()[6]
+	if rows := sheet.Rows(); len(rows) != 1000 || rows[999][0].Value != 999.0 {
+		t.Fatalf("Expected the 1000 rows of the sheet, got %d", len(rows))
+	}
+
+	// The Workbook stream is written from the first sector on, so the
+	// sheet lies at its stream offset past the header; the FAT sectors
+	// that chain its sectors may be read as well
+	start := cfbSectorSize + int(sheet.offset)
+	end := start + sheet.size
+	var fat [][2]int
+	for i := 0; i < 109; i++ {
+		sector := binary.LittleEndian.Uint32(file[76+4*i:])
+		if sector == cfbFreeSector {
+			break
+		}
+		at := (int(sector) + 1) * cfbSectorSize
+		fat = append(fat, [2]int{at, at + cfbSectorSize})
+	}
+	if len(r.ranges) == 0 {
+		t.Fatal("Expected the sheet read on first access")
+	}
+	for _, rg := range r.ranges {
+		if rg[0] >= start && rg[1] <= end {
+			continue
+		}
+		if slices.ContainsFunc(fat, func(f [2]int) bool { return rg[0] >= f[0] && rg[1] <= f[1] }) {
+			continue
+		}
+		t.Errorf("Read %v outside of the sheet at %v", rg, [2]int{start, end})
+	}
+}
+
+func TestWriteCFBLargeStream(t *testing.T) {
+	// 100 KB needs several FAT sectors; 8 MB needs DIFAT sectors as well
+	for _, size := range []int{100 << 10, 8 << 20} {
+		stream := make([]byte, size)
+		for i := range stream {
+			stream[i] = byte(i * 7)
+		}
+
+		file := new(bytes.Buffer)
+		if err := WriteCFB(file, stream); err != nil {
+			t.Fatalf("WriteCFB(%d bytes) failed: %v", size, err)
+		}
+		cfb, err := ReadCFB(file.Bytes())
+		if err != nil {
+			t.Fatalf("ReadCFB(%d bytes) failed: %v", size, err)
+		}
+		got, err := cfb.Stream("Workbook")
+		if err != nil {
+			t.Fatalf("Stream(%d bytes) failed: %v", size, err)
+		}
+		if !bytes.Equal(got, stream) {
+			t.Errorf("Stream of %d bytes did not round-trip", size)
+		}
+	}
+}
+
+func TestOpenInvalidFile(t *testing.T) {
+	if _, err := openWorkbook([]byte("not an xls file")); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat, got %v", err)
+	}
+	if _, err := openWorkbook(make([]byte, 1024)); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat, got %v", err)
+	}
+}
+
+func TestCellKindString(t *testing.T) {
+	if s := KindFormula.String(); s != "Formula" {
+		t.Errorf("Expected 'Formula', got '%s'", s)
+	}
+}
+
+// benchmarkFile writes a sheet of the maximum BIFF8 height (65536 rows of 8
+// numbers) and returns the path.
+func benchmarkFile(b *testing.B) string {
+	b.Helper()
+
+	data := make([][]interface{}, 65536)
+	for i := range data {
+		row := make([]interface{}, 8)
+		for j := range row {
+			row[j] = float64(i*8 + j)
+		}
+		data[i] = row
+	}
+
+	path := filepath.Join(b.TempDir(), "bench.xls")
+	if err := WriteToFile(path, data); err != nil {
+		b.Fatalf("WriteToFile() failed: %v", err)
+	}
+	return path
+}
+
+func BenchmarkSheetRows(b *testing.B) {
+	path := benchmarkFile(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wb, err := OpenFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sum := 0.0
+		for _, row := range wb.Sheets()[0].Rows() {
+			sum += row[0].Value.(float64)
+		}
+	}
+}
+
+func BenchmarkForEachRow(b *testing.B) {
+	path := benchmarkFile(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wb, err := OpenFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sum := 0.0
+		err = wb.ForEachRow("Sheet1", func(rowIndex int, cells []Cell) error {
+			sum += cells[0].Value.(float64)
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// failingReaderAt fails the reads past offset from.
+type failingReaderAt struct {
+	r    io.ReaderAt
+	from int64
+}
+
+var errReadFailed = errors.New("read failed")
+
+func (f *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.from {
+		return 0, errReadFailed
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestOpenReaderSheetErrors(t *testing.T) {
+	// A malformed RK between two good cells
+	file := buildFixture(t, nil, []testRecord{
+		{recTypeNUMBER, le(0, 0, 0, 1.0)},
+		{recTypeRK, le(1, 0, 0)},
+		{recTypeNUMBER, le(2, 0, 0, 3.0)},
+	})
+	wb, err := OpenReader(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("OpenReader() failed: %v", err)
+	}
+	sheet := wb.Sheets()[0]
+	if rows := sheet.Rows(); len(rows) != 1 || rows[0][0].Value != 1.0 {
+		t.Errorf("Expected the cells before the malformed record, got %v", rows)
+	}
+	if err := sheet.Err(); !errors.Is(err, ErrInvalidFormat) || !strings.Contains(err.Error(), "malformed record 0x027E") {
+		t.Errorf("Expected the malformed record from Err(), got %v", err)
+	}
+	if err := sheet.Err(); err == nil {
+		t.Error("Expected the error to stay after the first call")
+	}
+
+	// OpenFile checks the sheets at once
+	path := filepath.Join(t.TempDir(), "malformed.xls")
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFile(path); !errors.Is(err, ErrInvalidFormat) || !strings.Contains(err.Error(), `failed to read sheet "Sheet1"`) {
+		t.Errorf("Expected OpenFile() to fail on the malformed record, got %v", err)
+	}
+
+	// A reader failing once the workbook is open
+	data := make([][]interface{}, 5000)
+	for r := range data {
+		data[r] = []interface{}{float64(r), "cell"}
+	}
+	w := New()
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	r := &failingReaderAt{r: bytes.NewReader(buf.Bytes()), from: int64(buf.Len())}
+	wb, err = OpenReader(r, int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenReader() failed: %v", err)
+	}
+	r.from = int64(buf.Len() / 2)
+	sheet = wb.Sheets()[0]
+	if rows := sheet.Rows(); len(rows) >= len(data) {
+		t.Errorf("Expected the rows to end at the failed read, got %d", len(rows))
+	}
+	if err := sheet.Err(); !errors.Is(err, errReadFailed) {
+		t.Errorf("Expected the read error from Err(), got %v", err)
+	}
+
+	// A sheet read whole has no error
+	if err := readBack(t, w).Err(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package xls
+
+// Record is a BIFF8 record read from a workbook stream.
+type Record struct {
+	Type   uint16
+	Offset int // Offset of the record header in the stream
+	Data   []byte
+}
+
+// RecordReader iterates over the records of a BIFF8 workbook stream, such as
+// the "Workbook" stream returned by CFBFile.Stream.
+type RecordReader struct {
+	r recordReader
+}
+
+// NewRecordReader returns a RecordReader for stream.
+func NewRecordReader(stream []byte) *RecordReader {
+	return &RecordReader{r: recordReader{data: stream}}
+}
+
+// Next returns the next record, or io.EOF when the stream is exhausted.
+// Record.Data aliases the stream.
+func (r *RecordReader) Next() (Record, error) {
+	recType, data, offset, err := r.r.next()
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{Type: recType, Offset: offset, Data: data}, nil
+}
+
+// Seek moves the reader to offset, such as a BOUNDSHEET stream position.
+func (r *RecordReader) Seek(offset int) {
+	r.r.pos = offset
+}
+
+var recordNames = map[uint16]string{
+	recTypeBOF:              "BOF",
+	recTypeEOF:              "EOF",
+	recTypeDIMENSIONS:       "DIMENSIONS",
+	recTypeROW:              "ROW",
+	recTypeLABEL:            "LABEL",
+	recTypeNUMBER:           "NUMBER",
+	recTypeBOOLERR:          "BOOLERR",
+	recTypeFORMULA:          "FORMULA",
+	recTypeSTRING:           "STRING",
+	recTypeBLANK:            "BLANK",
+	recTypeMULBLANK:         "MULBLANK",
+	recTypeRK:               "RK",
+	recTypeMULRK:            "MULRK",
+	recTypeRSTRING:          "RSTRING",
+	recTypeSHRFMLA:          "SHRFMLA",
+	recTypeARRAY:            "ARRAY",
+	recTypeTABLE:            "TABLE",
+	recTypeCONTINUE:         "CONTINUE",
+	recTypeSST:              "SST",
+	recTypeEXTSST:           "EXTSST",
+	recTypeLABELSST:         "LABELSST",
+	recTypeCODEPAGE:         "CODEPAGE",
+	recTypeFONT:             "FONT",
+	recTypeFORMAT:           "FORMAT",
+	recTypeXF:               "XF",
+	recTypeSTYLE:            "STYLE",
+	recTypeBOUNDSHEET:       "BOUNDSHEET",
+	recTypeWINDOW1:          "WINDOW1",
+	recTypeWINDOW2:          "WINDOW2",
+	recTypeDEFAULTROWHEIGHT: "DEFAULTROWHEIGHT",
+	recTypeDEFCOLWIDTH:      "DEFCOLWIDTH",
+	recTypeWSBOOL:           "WSBOOL",
+	recTypeBOOKBOOL:         "BOOKBOOL",
+	recTypeINTERFACEHDR:     "INTERFACEHDR",
+	recTypeMMS:              "MMS",
+	recTypeINTERFACEEND:     "INTERFACEEND",
+	recTypeWRITEACCESS:      "WRITEACCESS",
+	recTypeDATEMODE:         "DATEMODE",
+	recTypePRECISION:        "PRECISION",
+	recTypeREFRESHALL:       "REFRESHALL",
+	recTypeCALCMODE:         "CALCMODE",
+	recTypeCALCCOUNT:        "CALCCOUNT",
+	recTypeREFMODE:          "REFMODE",
+	recTypeITERATION:        "ITERATION",
+	recTypeDELTA:            "DELTA",
+	recTypeSAVERECALC:       "SAVERECALC",
+	recTypePRINTHEADERS:     "PRINTHEADERS",
+	recTypePRINTGRIDLINES:   "PRINTGRIDLINES",
+	recTypePROTECT:          "PROTECT",
+	recTypePASSWORD:         "PASSWORD",
+	recTypeBACKUP:           "BACKUP",
+	recTypeHIDEOBJ:          "HIDEOBJ",
+	recTypeWINDOWPROTECT:    "WINDOWPROTECT",
+	recTypeDSF:              "DSF",
+	recTypePROT4REV:         "PROT4REV",
+	recTypePASSWORDREV4:     "PASSWORDREV4",
+	recTypeFNGROUPCOUNT:     "TABID", // 0x013D is TABID in the specification
+	recTypeUSESELFS:         "USESELFS",
+	recTypeUNKNOWN9C:        "FNGROUPCOUNT",
+	recTypeLEFTMARGIN:       "LEFTMARGIN",
+	recTypeRIGHTMARGIN:      "RIGHTMARGIN",
+	recTypeTOPMARGIN:        "TOPMARGIN",
+	recTypeBOTTOMMARGIN:     "BOTTOMMARGIN",
+	recTypeHCENTER:          "HCENTER",
+	recTypeVCENTER:          "VCENTER",
+	recTypeSETUP:            "SETUP",
+	recTypeGRIDSET:          "GRIDSET",
+	recTypeGUTS:             "GUTS",
+	recTypeOBJPROTECT:       "OBJPROTECT",
+	recTypeSCENPROTECT:      "SCENPROTECT",
+	recTypeHBREAK:           "HORIZONTALPAGEBREAKS",
+	recTypeVBREAK:           "VERTICALPAGEBREAKS",
+	recTypeHEADER:           "HEADER",
+	recTypeFOOTER:           "FOOTER",
+	recTypeCOLINFO:          "COLINFO",
+	recTypeSUPBOOK:          "SUPBOOK",
+	recTypeEXTERNSHEET:      "EXTERNSHEET",
+	recTypeNAME:             "NAME",
+	recTypeAUTOFILTERINFO:   "AUTOFILTERINFO",
+	recTypeRECALCID:         "RECALCID",
+	recTypePANE:             "PANE",
+	recTypeSELECTION:        "SELECTION",
+	recTypeSCL:              "SCL",
+	recTypeSTANDARDWIDTH:    "STANDARDWIDTH",
+
+	// Records this package does not write but commonly finds in files
+	0x002F: "FILEPASS",
+	0x00E5: "MERGEDCELLS",
+	0x01B8: "HLINK",
+	0x0092: "PALETTE",
+	0x00EB: "MSODRAWINGGROUP",
+	0x00EC: "MSODRAWING",
+	0x005D: "OBJ",
+	0x01B6: "TXO",
+	0x001C: "NOTE",
+	0x00D3: "OBPROJ",
+	0x020B: "INDEX",
+	0x00D7: "DBCELL",
+	0x01B0: "CONDFMT",
+	0x01B1: "CF",
+	0x01BE: "DV",
+	0x01B2: "DVAL",
+	0x0863: "BOOKEXT",
+	0x088C: "COMPAT12",
+	0x0896: "THEME",
+	0x089A: "MTRSETTINGS",
+	0x0892: "STYLEEXT",
+	0x087C: "XFCRC",
+	0x087D: "XFEXT",
+	0x0867: "FEATHDR",
+	0x0868: "FEAT",
+}
+
+// RecordName returns the specification name of a BIFF8 record type, or an
+// empty string if the type is not known.
+func RecordName(recType uint16) string {
+	return recordNames[recType]
+}
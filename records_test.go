@@ -0,0 +1,79 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRecordReader(t *testing.T) {
+	w := New()
+	defer w.Close()
+	if err := w.Write([][]interface{}{{"a", 1}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	stream := new(bytes.Buffer)
+	if err := w.writeBIFF8(stream); err != nil {
+		t.Fatalf("writeBIFF8() failed: %v", err)
+	}
+
+	r := NewRecordReader(stream.Bytes())
+	var names []string
+	offset := 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		if rec.Offset != offset {
+			t.Errorf("Expected offset %d, got %d", offset, rec.Offset)
+		}
+		offset += 4 + len(rec.Data)
+		names = append(names, RecordName(rec.Type))
+	}
+
+	if offset != stream.Len() {
+		t.Errorf("Expected to read %d bytes, got %d", stream.Len(), offset)
+	}
+	if names[0] != "BOF" || names[len(names)-1] != "EOF" {
+		t.Errorf("Expected BOF ... EOF, got %s ... %s", names[0], names[len(names)-1])
+	}
+	if RecordName(0xFFFF) != "" {
+		t.Errorf("Expected empty name for unknown record, got %q", RecordName(0xFFFF))
+	}
+
+	r = NewRecordReader([]byte{0x09, 0x08, 0x10})
+	if _, err := r.Next(); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat, got %v", err)
+	}
+}
+
+func TestCFBChain(t *testing.T) {
+	file := new(bytes.Buffer)
+	if err := WriteCFB(file, make([]byte, 5000)); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+	cfb, err := ReadCFB(file.Bytes())
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+
+	entries := cfb.Entries()
+	if len(entries) < 2 || entries[1].Name != "Workbook" || entries[1].ObjectType != CFBObjectStream {
+		t.Fatalf("Unexpected directory entries %+v", entries)
+	}
+	chain, mini, err := cfb.Chain(entries[1])
+	if err != nil {
+		t.Fatalf("Chain() failed: %v", err)
+	}
+	if mini {
+		t.Error("Expected regular sectors")
+	}
+	if len(chain) != 10 || chain[0] != 0 || chain[9] != 9 {
+		t.Errorf("Expected sectors 0-9, got %v", chain)
+	}
+}
@@ -0,0 +1,84 @@
+package xls
+
+import (
+	"fmt"
+)
+
+// Problem is an issue OpenFileRecover read past: where in the Workbook
+// stream it was found and what was wrong.
+type Problem struct {
+	Offset      int
+	Description string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("offset %d: %s", p.Offset, p.Description)
+}
+
+// salvage collects the problems of a workbook opened with OpenFileRecover.
+type salvage struct {
+	problems []Problem
+	done     bool // The workbook is open, and its problems known
+}
+
+// report records a problem, unless the workbook is already open: its
+// sheets are read again when their cells are first accessed.
+func (s *salvage) report(offset int, err error) {
+	if !s.done {
+		s.problems = append(s.problems, Problem{Offset: offset, Description: err.Error()})
+	}
+}
+
+// readPast returns err, or, for a workbook opened with OpenFileRecover,
+// reports it and returns nil so that reading goes on.
+func (wb *Workbook) readPast(offset int, err error) error {
+	if err == nil || wb.salvage == nil {
+		return err
+	}
+	wb.salvage.report(offset, err)
+	return nil
+}
+
+// OpenFileRecover opens the XLS file at path as OpenFile does, but reads
+// past the problems of files that were cut off or slightly corrupted: a
+// Workbook stream whose sector chain is broken or ends early, records
+// running past the end of the stream, missing EOF records, malformed
+// records, which are skipped, and shared string tables holding fewer
+// strings than they declare. It returns the cells it could decode, with the
+// problems in the order they were found.
+//
+// Files it cannot make sense of still fail: those whose compound file
+// header, FAT or directory cannot be read, without a Workbook stream, or
+// whose stream does not start with a BOF record.
+func OpenFileRecover(path string, opts ...ReadOption) (*Workbook, []Problem, error) {
+	limits := newReadOptions(opts).limits
+	data, err := readFile(path, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	return openWorkbookRecover(data, limits)
+}
+
+// openWorkbookRecover parses an XLS file held in memory as OpenFileRecover
+// does.
+func openWorkbookRecover(data []byte, limits Limits) (*Workbook, []Problem, error) {
+	cfb, err := ReadCFB(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &salvage{}
+	stream, err := cfb.salvageStream("Workbook")
+	if err != nil {
+		if len(stream) == 0 {
+			return nil, nil, missingWorkbook(cfb, err)
+		}
+		s.report(len(stream), err)
+	}
+	wb, err := parseWorkbook(stream, defaultPassword, s, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.done = true
+	return wb, s.problems, nil
+}
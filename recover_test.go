@@ -0,0 +1,207 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// recoverData is a sheet large enough for a Workbook stream of regular
+// sectors.
+func recoverData() [][]interface{} {
+	data := make([][]interface{}, 300)
+	for r := range data {
+		data[r] = []interface{}{float64(r) + 0.5, "row", float64(r) * 2}
+	}
+	return data
+}
+
+func TestOpenFileRecoverTruncatedStream(t *testing.T) {
+	w := New()
+	if err := w.Write(recoverData()); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	stream, err := w.WorkbookStream()
+	if err != nil {
+		t.Fatalf("WorkbookStream() failed: %v", err)
+	}
+	records, err := readAllRecords(stream)
+	if err != nil {
+		t.Fatalf("readAllRecords() failed: %v", err)
+	}
+
+	// Cut the stream in the middle of the first cell of row 200
+	cut := -1
+	for _, rec := range records {
+		if rec.Type == recTypeNUMBER && binary.LittleEndian.Uint16(rec.Data) == 200 {
+			cut = rec.Offset + 8
+			break
+		}
+	}
+	if cut < 0 {
+		t.Fatal("No NUMBER record for row 200")
+	}
+	var file bytes.Buffer
+	if err := WriteCFB(&file, stream[:cut]); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+
+	if _, err := openWorkbook(file.Bytes()); err == nil {
+		t.Fatal("Expected the truncated file to fail to open")
+	}
+	wb, problems, err := openWorkbookRecover(file.Bytes(), DefaultLimits())
+	if err != nil {
+		t.Fatalf("openWorkbookRecover() failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Offset != cut-8 || !strings.Contains(problems[0].Description, "runs past end of stream") {
+		t.Errorf("Expected the cut record reported, got %v", problems)
+	}
+
+	sheet := wb.Sheets()[0]
+	if rows := sheet.Rows(); len(rows) != 200 {
+		t.Errorf("Expected the 200 rows before the cut, got %d", len(rows))
+	}
+	if got := sheet.Cell(199, 2).Value; got != 398.0 {
+		t.Errorf("Expected the last cell before the cut, got %v", got)
+	}
+}
+
+func TestOpenFileRecoverBrokenChain(t *testing.T) {
+	var buf bytes.Buffer
+	w := New()
+	if err := w.Write(recoverData()); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	// End the chain of the Workbook stream before its final sector
+	cfb, err := ReadCFB(data)
+	if err != nil {
+		t.Fatalf("ReadCFB() failed: %v", err)
+	}
+	chain, _, err := cfb.Chain(cfb.Entries()[1])
+	if err != nil {
+		t.Fatalf("Chain() failed: %v", err)
+	}
+	fat := (int(binary.LittleEndian.Uint32(data[76:80])) + 1) * cfb.SectorSize()
+	binary.LittleEndian.PutUint32(data[fat+4*int(chain[len(chain)-2]):], cfbEndOfChain)
+
+	if _, err := openWorkbook(data); err == nil {
+		t.Fatal("Expected the broken chain to fail to open")
+	}
+	wb, problems, err := openWorkbookRecover(data, DefaultLimits())
+	if err != nil {
+		t.Fatalf("openWorkbookRecover() failed: %v", err)
+	}
+	if len(problems) == 0 || !strings.Contains(problems[0].Description, "shorter than its declared size") {
+		t.Errorf("Expected the short stream reported first, got %v", problems)
+	}
+	if got := wb.Sheets()[0].Cell(10, 0).Value; got != 10.5 {
+		t.Errorf("Expected the cells before the lost sector, got %v", got)
+	}
+}
+
+func TestOpenFileRecoverRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		globals  []testRecord
+		cells    []testRecord
+		problems []string
+		want     []interface{}
+	}{
+		{
+			name:  "malformed cell",
+			cells: []testRecord{{recTypeRK, le(0, 0, 0)}, {recTypeNUMBER, le(0, 1, 0, 2.0)}},
+			// The malformed RK is skipped
+			problems: []string{"malformed record 0x027E"},
+			want:     []interface{}{nil, 2.0},
+		},
+		{
+			name: "SST count mismatch",
+			globals: []testRecord{
+				{recTypeSST, le(uint32(3), uint32(3), 1, byte(0), "a", 1, byte(0), "b")},
+			},
+			cells: []testRecord{
+				{recTypeLABELSST, le(0, 0, 0, uint32(0))},
+				{recTypeLABELSST, le(0, 1, 0, uint32(1))},
+				{recTypeLABELSST, le(0, 2, 0, uint32(2))},
+			},
+			problems: []string{"entry 2 of 3", "SST index 2 out of range"},
+			want:     []interface{}{"a", "b"},
+		},
+		{
+			name: "malformed global",
+			globals: []testRecord{
+				{recTypeFORMAT, le(0xA5)},
+			},
+			cells:    []testRecord{{recTypeNUMBER, le(0, 0, 0, 1.0)}},
+			problems: []string{"malformed record 0x041E"},
+			want:     []interface{}{1.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wb, problems, err := openWorkbookRecover(buildFixture(t, tt.globals, tt.cells), DefaultLimits())
+			if err != nil {
+				t.Fatalf("openWorkbookRecover() failed: %v", err)
+			}
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.problems), problems)
+			}
+			for i, want := range tt.problems {
+				if !strings.Contains(problems[i].Description, want) {
+					t.Errorf("Problem %d: expected %q, got %v", i, want, problems[i])
+				}
+			}
+			sheet := wb.Sheets()[0]
+			for c, want := range tt.want {
+				if got := sheet.Cell(0, c).Value; got != want {
+					t.Errorf("Cell (0, %d): expected %v, got %v", c, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenFileRecoverMissingEOF(t *testing.T) {
+	w := New()
+	if err := w.Write(recoverData()); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	stream, err := w.WorkbookStream()
+	if err != nil {
+		t.Fatalf("WorkbookStream() failed: %v", err)
+	}
+	// Drop the EOF of the sheet, the last record of the stream
+	var file bytes.Buffer
+	if err := WriteCFB(&file, stream[:len(stream)-4]); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+
+	wb, problems, err := openWorkbookRecover(file.Bytes(), DefaultLimits())
+	if err != nil {
+		t.Fatalf("openWorkbookRecover() failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Offset != len(stream)-4 || !strings.Contains(problems[0].Description, "missing EOF in worksheet") {
+		t.Errorf("Expected the missing EOF reported, got %v", problems)
+	}
+	if got := wb.Sheets()[0].Cell(299, 2).Value; got != 598.0 {
+		t.Errorf("Expected every cell read, got %v", got)
+	}
+}
+
+func TestOpenFileRecoverHardFailure(t *testing.T) {
+	data := buildFixture(t, nil, nil)
+	copy(data, "not a CFB")
+	if _, _, err := openWorkbookRecover(data, DefaultLimits()); err == nil {
+		t.Error("Expected an unreadable header to fail")
+	}
+	if _, _, err := OpenFileRecover("testdata/does-not-exist.xls"); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,153 @@
+package xls
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+// continuation is how the body of a record type continues in CONTINUE
+// records once it is longer than maxRecordSize.
+type continuation int
+
+const (
+	// continueNever: the record is never split, since readers take the
+	// record alone, as for XF or BOUNDSHEET
+	continueNever continuation = iota
+
+	// continueString: the body ends in a UTF-16 or 8-bit string whose
+	// flags byte is at stringFlagsAt; each CONTINUE record starts with the
+	// flags byte again and holds whole characters, as for STRING
+	continueString
+
+	// continuePieces: the writer of the record splits it itself, at the
+	// places the record type needs, and writes each piece, of at most
+	// maxSize bytes, on its own, as sstWriter does for SST
+	continuePieces
+)
+
+// stringFlagsAt is the offset of the flags byte of the string of a
+// continueString body, after its 2-byte character count.
+const stringFlagsAt = 2
+
+// recordPolicy bounds the body of a record type.
+type recordPolicy struct {
+	maxSize   int // Most bytes of the body, over all its records
+	continued continuation
+}
+
+// defaultRecordPolicy applies to the record types recordPolicies does not
+// list, including raw records: one record of at most maxRecordSize bytes.
+var defaultRecordPolicy = recordPolicy{maxSize: maxRecordSize}
+
+// recordPolicies are the policies of the record types whose bodies vary in
+// size or may continue. writeRecord enforces them, and Verify checks the
+// records of the files it reads against them.
+var recordPolicies = map[uint16]recordPolicy{
+	recTypeXF:         {maxSize: 20},
+	recTypeBOUNDSHEET: {maxSize: 8 + 4*maxSheetNameLength}, // Offset, state, type, name of up to two UTF-16 units a rune
+	recTypeFONT:       {maxSize: 16 + 2*maxShortText},      // Fixed part, name
+	recTypeFORMAT:     {maxSize: 5 + 2*maxShortText},       // Index, format
+	recTypeHEADER:     {maxSize: 3 + 2*maxShortText},
+	recTypeFOOTER:     {maxSize: 3 + 2*maxShortText},
+	recTypeDIMENSIONS: {maxSize: 14},
+	recTypeROW:        {maxSize: 16},
+	recTypeLABEL:      {maxSize: 9 + 2*maxLabelChars},  // Cell, string
+	recTypeRSTRING:    {maxSize: 11 + 6*maxLabelChars}, // Cell, string, a run a character
+	recTypeLABELSST:   {maxSize: 10},
+	recTypeNUMBER:     {maxSize: 14},
+	recTypeBLANK:      {maxSize: 6},
+	recTypeBOOLERR:    {maxSize: 8},
+	recTypeMULBLANK:   {maxSize: 6 + 2*(maxColumn+1)},    // Row, columns, XFs
+	recTypeEXTSST:     {maxSize: 2 + 8*maxExtSSTBuckets}, // Strings per bucket, buckets
+	recTypeSTRING:     {maxSize: 3 + 2*maxTextLength, continued: continueString},
+	recTypeSST:        {maxSize: maxRecordSize, continued: continuePieces},
+	recTypeCONTINUE:   {maxSize: maxRecordSize},
+}
+
+// recordPolicyOf returns the policy of a record type.
+func recordPolicyOf(recType uint16) recordPolicy {
+	if p, ok := recordPolicies[recType]; ok {
+		return p
+	}
+	return defaultRecordPolicy
+}
+
+// writeContinued writes a continueString body longer than maxRecordSize as
+// a record and the CONTINUE records its characters continue in.
+func (w *Writer) writeContinued(writer io.Writer, recType uint16, data []byte) error {
+	flags := data[stringFlagsAt]
+	width := 1
+	if flags&0x01 != 0 {
+		width = 2
+	}
+	head := stringFlagsAt + 1
+	n := head + (maxRecordSize-head)/width*width
+	if err := w.putRecord(writer, recType, data[:n]); err != nil {
+		return err
+	}
+	piece := make([]byte, 0, maxRecordSize)
+	for rest := data[n:]; len(rest) > 0; {
+		n := min(len(rest), (maxRecordSize-1)/width*width)
+		piece = append(append(piece[:0], flags), rest[:n]...)
+		if err := w.putRecord(writer, recTypeCONTINUE, piece); err != nil {
+			return err
+		}
+		rest = rest[n:]
+	}
+	return nil
+}
+
+// checkWorkbookRecords checks the records of the Workbook stream of cfb
+// against their policies.
+func checkWorkbookRecords(cfb *CFBFile) error {
+	stream, err := cfb.Stream("Workbook")
+	if err != nil {
+		return err
+	}
+	return checkRecordPolicies(stream)
+}
+
+// checkRecordPolicies checks the records of a workbook stream against
+// their policies: every record within maxRecordSize, CONTINUE records only
+// after records that continue, and the bodies within their most bytes. The
+// check ends at the EOF record followed by nothing but padding.
+func checkRecordPolicies(stream []byte) error {
+	r := recordReader{data: stream}
+	var prev uint16
+	var policy recordPolicy
+	size := 0
+	for {
+		recType, data, offset, err := r.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(data) > maxRecordSize {
+			return fmt.Errorf("%s record at offset %d: %d bytes, more than %d", RecordName(recType), offset, len(data), maxRecordSize)
+		}
+		if recType == recTypeCONTINUE {
+			if prev == 0 || policy.continued == continueNever {
+				return fmt.Errorf("CONTINUE record at offset %d after a %s record, which does not continue", offset, RecordName(prev))
+			}
+			if policy.continued == continueString && (len(data) == 0 || data[0]&^0x01 != 0) {
+				return fmt.Errorf("CONTINUE record at offset %d of a %s record: no flags byte", offset, RecordName(prev))
+			}
+			size += len(data)
+			if policy.continued == continueString {
+				size-- // The repeated flags byte
+			}
+		} else {
+			prev, policy, size = recType, recordPolicyOf(recType), len(data)
+		}
+		if policy.continued != continuePieces && size > policy.maxSize {
+			return fmt.Errorf("%s record at offset %d: %d bytes, more than %d", RecordName(prev), offset, size, policy.maxSize)
+		}
+		// The stream may be padded with zeros past the last EOF record
+		if recType == recTypeEOF && !slices.ContainsFunc(stream[r.pos:], func(b byte) bool { return b != 0 }) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecordPolicies(t *testing.T) {
+	for recType, p := range recordPolicies {
+		name := RecordName(recType)
+		if p.continued != continueString && p.maxSize > maxRecordSize {
+			t.Errorf("%s: %d bytes do not fit in a record that is not continued", name, p.maxSize)
+		}
+
+		body := make([]byte, p.maxSize)
+		if p.continued == continueString {
+			body[stringFlagsAt] = 0x01
+		}
+		var buf bytes.Buffer
+		if err := New().writeRecord(&buf, recType, body); err != nil {
+			t.Errorf("%s: writeRecord() of %d bytes failed: %v", name, p.maxSize, err)
+		}
+		records, err := readAllRecords(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: readAllRecords() failed: %v", name, err)
+		}
+		size := 0
+		for i, rec := range records {
+			if len(rec.Data) > maxRecordSize {
+				t.Errorf("%s: record %d of %d bytes", name, i, len(rec.Data))
+			}
+			size += len(rec.Data)
+		}
+		if want := p.maxSize + len(records) - 1; size != want || (len(records) > 1) != (p.maxSize > maxRecordSize) {
+			t.Errorf("%s: expected %d bytes, got %d in %d records", name, want, size, len(records))
+		}
+		// A CONTINUE record stands only after the record it continues
+		if err := checkRecordPolicies(buf.Bytes()); err != nil && recType != recTypeCONTINUE {
+			t.Errorf("%s: checkRecordPolicies() failed: %v", name, err)
+		}
+
+		if err := New().writeRecord(new(bytes.Buffer), recType, make([]byte, p.maxSize+1)); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("%s: expected ErrOutOfRange for %d bytes, got %v", name, p.maxSize+1, err)
+		}
+	}
+
+	if err := New().writeRecord(new(bytes.Buffer), 0x0893, make([]byte, maxRecordSize+1)); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange for a record type without a policy, got %v", err)
+	}
+}
+
+func TestLongFormulaString(t *testing.T) {
+	long := strings.Repeat("a", 4109) + "\U0001F600" + strings.Repeat("b", maxTextLength-4111)
+	data := [][]interface{}{
+		{FormulaCell{Expr: `"x"`, Cached: long}},
+		{FormulaCell{Expr: `"y"`, Cached: strings.Repeat("y", 5000)}, "after"},
+	}
+	w := New(WithPostWriteVerification())
+	w.Write(data)
+	file := writtenBytes(t, w)
+
+	stream, err := workbookStream(file)
+	if err != nil {
+		t.Fatalf("workbookStream() failed: %v", err)
+	}
+	records, err := readAllRecords(stream)
+	if err != nil {
+		t.Fatalf("readAllRecords() failed: %v", err)
+	}
+	var continues []Record
+	for i, rec := range records {
+		if rec.Type == recTypeSTRING {
+			for _, c := range records[i+1:] {
+				if c.Type != recTypeCONTINUE {
+					break
+				}
+				continues = append(continues, c)
+			}
+			break
+		}
+	}
+	if len(continues) != 7 {
+		t.Fatalf("Expected the string to continue in 7 records, got %d", len(continues))
+	}
+	for _, c := range continues {
+		if c.Data[0] != 0x01 || len(c.Data)%2 != 1 {
+			t.Errorf("Expected a flags byte and whole characters, got %d bytes from 0x%02X", len(c.Data), c.Data[0])
+		}
+	}
+
+	sheet := readBack(t, w)
+	if got := sheet.Cell(0, 0).Value; got != long {
+		t.Errorf("Expected the string of %d characters, got %d", len(long), len(got.(string)))
+	}
+	if got := sheet.Cell(1, 1).Value; got != "after" {
+		t.Errorf("Expected the cells after the string, got %v", got)
+	}
+}
+
+func TestCheckRecordPolicies(t *testing.T) {
+	rec := func(recType uint16, data []byte) []byte {
+		return le(recType, len(data), data)
+	}
+	eof := rec(recTypeEOF, nil)
+	tests := []struct {
+		name   string
+		stream []byte
+		want   string // Part of the error, "" for none
+	}{
+		{"valid", bytes.Join([][]byte{
+			rec(recTypeXF, make([]byte, 20)),
+			rec(recTypeSST, make([]byte, maxRecordSize)),
+			rec(recTypeCONTINUE, make([]byte, 100)),
+			rec(recTypeSTRING, le(3, byte(1), "a\x00b\x00")),
+			rec(recTypeCONTINUE, le(byte(0), "c")),
+			eof, make([]byte, 100),
+		}, nil), ""},
+		{"long XF", rec(recTypeXF, make([]byte, 21)), "XF record at offset 0: 21 bytes, more than 20"},
+		{"continued XF", append(rec(recTypeXF, make([]byte, 20)), rec(recTypeCONTINUE, make([]byte, 4))...), "after a XF record, which does not continue"},
+		{"continued BOUNDSHEET", append(rec(recTypeBOUNDSHEET, make([]byte, 12)), rec(recTypeCONTINUE, nil)...), "after a BOUNDSHEET record"},
+		{"first CONTINUE", rec(recTypeCONTINUE, nil), "CONTINUE record at offset 0"},
+		{"no flags byte", append(rec(recTypeSTRING, le(3, byte(1), "a\x00")), rec(recTypeCONTINUE, le(uint16('b'), uint16('c')))...), "no flags byte"},
+		{"record past the EOF", append(eof, rec(recTypeCONTINUE, nil)...), "after a EOF record"},
+	}
+	for _, tt := range tests {
+		err := checkRecordPolicies(tt.stream)
+		if tt.want == "" && err != nil {
+			t.Errorf("%s: checkRecordPolicies() failed: %v", tt.name, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%s: expected an error with %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Verify checks the records of the file
+	file := buildFixture(t, []testRecord{{recTypeCONTINUE, make([]byte, 4)}}, []testRecord{{recTypeNUMBER, le(0, 0, 0, 1.5)}})
+	if err := Verify(file, "Sheet1", [][]interface{}{{1.5}}); !errors.Is(err, ErrVerificationFailed) || !strings.Contains(err.Error(), "CONTINUE") {
+		t.Errorf("Expected Verify() to fail on the CONTINUE record, got %v", err)
+	}
+	if err := Verify(buildFixture(t, nil, []testRecord{{recTypeNUMBER, le(0, 0, 0, 1.5)}}), "Sheet1", [][]interface{}{{1.5}}); err != nil {
+		t.Errorf("Verify() failed: %v", err)
+	}
+}
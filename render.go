@@ -0,0 +1,98 @@
+package xls
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ByteEncoding is how the Writer writes []byte cells as text.
+type ByteEncoding int
+
+const (
+	// BytesList writes the bytes as any other slice, as a list of
+	// numbers such as "104, 105".
+	BytesList ByteEncoding = iota
+	// BytesBase64 writes the bytes in standard base64 with padding, such
+	// as "aGk=".
+	BytesBase64
+	// BytesHex writes the bytes in lowercase hexadecimal, such as "6869".
+	BytesHex
+)
+
+// WithByteEncoding sets how []byte cells are written as text: as a list of
+// numbers, in base64 or in hexadecimal. Under WithStrictTypes, []byte cells
+// are only written with BytesBase64 or BytesHex.
+func WithByteEncoding(e ByteEncoding) Option {
+	return func(w *Writer) {
+		w.byteEncoding = e
+	}
+}
+
+// byteText returns the text a byte slice cell is written as under
+// WithByteEncoding, or false for other cells and BytesList.
+func (w *Writer) byteText(value interface{}) (string, bool) {
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	if w.byteEncoding == BytesList || !isByteSlice(reflect.ValueOf(value)) {
+		return "", false
+	}
+	return renderValue(value, w.byteEncoding), true
+}
+
+// isByteSlice reports whether rv is a slice of bytes, such as []byte.
+func isByteSlice(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8
+}
+
+// renderValue returns the text a value of a type the Writer has no cell for
+// is written as, by the rules of FromInterface, with byte slices encoded
+// as enc says.
+func renderValue(v interface{}, enc ByteEncoding) string {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return ""
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.Slice, reflect.Array:
+		if isByteSlice(rv) && enc != BytesList {
+			if enc == BytesHex {
+				return hex.EncodeToString(rv.Bytes())
+			}
+			return base64.StdEncoding.EncodeToString(rv.Bytes())
+		}
+		parts := make([]string, rv.Len())
+		for i := range parts {
+			parts[i] = renderValue(rv.Index(i).Interface(), enc)
+		}
+		return strings.Join(parts, ", ")
+	case reflect.Pointer:
+		return renderValue(rv.Elem().Interface(), enc)
+	}
+	return fmt.Sprintf("%+v", v)
+}
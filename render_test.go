@@ -0,0 +1,93 @@
+package xls
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+type renderPoint struct {
+	X, Y float64
+}
+
+type renderName string
+
+func TestRenderValue(t *testing.T) {
+	one := 1
+	var nilPoint *renderPoint
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{nilPoint, ""},
+		{renderName("named"), "named"},
+		{errors.New("failed"), "failed"},
+		{time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), "2024-03-01 00:00:00 +0000 UTC"},
+		{net.IPv4(10, 0, 0, 1), "10.0.0.1"},
+		{[]float64{0.1, 1e-7, 1.5e21, math.Inf(1)}, "0.1, 1e-07, 1.5e+21, +Inf"},
+		{[]float32{0.1}, "0.1"},
+		{[3]int{1, -2, 3}, "1, -2, 3"},
+		{[]interface{}{"a", nil, true, uint8(7)}, "a, , true, 7"},
+		{[][]int{{1, 2}, {3}}, "1, 2, 3"},
+		{[]byte("hi"), "104, 105"},
+		{[]string{}, ""},
+		{&one, "1"},
+		{renderPoint{0.1, 2}, "{X:0.1 Y:2}"},
+		{&renderPoint{0.5, 1}, "{X:0.5 Y:1}"},
+		{map[string]int{"b": 2, "a": 1}, "map[a:1 b:2]"},
+		{complex(1, 2), "(1+2i)"},
+	}
+	for _, tt := range tests {
+		if got := renderValue(tt.value, BytesList); got != tt.want {
+			t.Errorf("renderValue(%#v): expected %q, got %q", tt.value, tt.want, got)
+		}
+	}
+
+	for _, tt := range []struct {
+		enc  ByteEncoding
+		want string
+	}{
+		{BytesList, "104, 105"},
+		{BytesBase64, "aGk="},
+		{BytesHex, "6869"},
+	} {
+		if got := renderValue([]byte("hi"), tt.enc); got != tt.want {
+			t.Errorf("Encoding %d: expected %q, got %q", tt.enc, tt.want, got)
+		}
+	}
+}
+
+func TestWithByteEncoding(t *testing.T) {
+	data := [][]interface{}{{[]byte("hi"), Styled([]byte{0xFF}, Style{Font: Font{Bold: true}}), []int{1, 2}}}
+	for _, tt := range []struct {
+		enc  ByteEncoding
+		want []string
+	}{
+		{BytesList, []string{"104, 105", "255", "1, 2"}},
+		{BytesBase64, []string{"aGk=", "/w==", "1, 2"}},
+		{BytesHex, []string{"6869", "ff", "1, 2"}},
+	} {
+		w := New(WithByteEncoding(tt.enc), WithPostWriteVerification())
+		if err := w.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		sheet := readBack(t, w)
+		for c, want := range tt.want {
+			if got := sheet.Cell(0, c).Value; got != want {
+				t.Errorf("Encoding %d, cell (0, %d): expected %q, got %v", tt.enc, c, want, got)
+			}
+		}
+	}
+
+	// Encoded bytes are text the Writer handles under WithStrictTypes
+	w := New(WithStrictTypes(), WithByteEncoding(BytesHex))
+	if err := w.Write([][]interface{}{{[]byte("hi")}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if got := readBack(t, w).Cell(0, 0).Value; got != "6869" {
+		t.Errorf("Expected hex under WithStrictTypes, got %v", got)
+	}
+}
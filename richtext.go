@@ -0,0 +1,194 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// RichText is a text cell value with formatting runs: parts of the text
+// shown in fonts of their own. A run starts at a rune of Text and lasts up
+// to the next run or the end of the text; the text before the first run
+// has the font of the cell. The font of a run is merged over that of the
+// cell as a StyledCell style is over the cell style, so a run only sets
+// what it changes, and it is written as a FONT record like the fonts of
+// styles.
+//
+// Rich text written inline, the strings WithHybridStrings keeps out of the
+// shared string table, is an RSTRING record: a LABEL record followed by the
+// runs. Otherwise it is a shared string with its runs in the SST. Rich text
+// without runs is written as a plain string. The reader returns the runs
+// with Cell.Runs.
+type RichText struct {
+	Text string
+	Runs []TextRun
+}
+
+// TextRun is a formatting run of a RichText.
+type TextRun struct {
+	Start int // Index of the rune of the text it starts at
+	Font  Font
+}
+
+func (RichText) cellValue() {}
+
+// String returns the text.
+func (rt RichText) String() string {
+	return rt.Text
+}
+
+// richText returns the RichText a cell value is, also inside a StyledCell.
+func richText(value interface{}) (RichText, bool) {
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	rt, ok := value.(RichText)
+	return rt, ok
+}
+
+// positions returns the UTF-16 position of the start of each run in the
+// text as cleanText writes it, or -1 for a run that starts past its end,
+// such as on a dropped byte order mark. Runs must start at increasing
+// runes of the text.
+func (rt RichText) positions() ([]int, error) {
+	runes := []rune(rt.Text)
+	before := make([]int, len(runes)+1) // Units before each rune
+	for i, r := range runes {
+		before[i+1] = before[i]
+		switch {
+		case i == 0 && r == '\uFEFF':
+		case r == '\n' && i > 0 && runes[i-1] == '\r':
+		default:
+			before[i+1] += utf16.RuneLen(r)
+		}
+	}
+
+	positions := make([]int, len(rt.Runs))
+	for i, run := range rt.Runs {
+		if run.Start < 0 || run.Start >= len(runes) || i > 0 && run.Start <= rt.Runs[i-1].Start {
+			return nil, fmt.Errorf("%w: rich text run %d starts at rune %d of %d, not after the previous run", ErrOutOfRange, i, run.Start, len(runes))
+		}
+		positions[i] = before[run.Start]
+		if positions[i] >= before[len(runes)] {
+			positions[i] = -1
+		}
+	}
+	return positions, nil
+}
+
+// richRuns returns the runs of a rich text cell as written: the position
+// and FONT index of each, with its font merged over the font of the cell.
+// Runs that start at the same character once the text is cleaned keep the
+// last. prepareStyles adds the fonts to the style table before the sheets
+// are written, so that writing them only looks the fonts up.
+func (s *SheetWriter) richRuns(row, col int, value interface{}, rt RichText) ([]byte, error) {
+	positions, err := rt.positions()
+	if err != nil {
+		return nil, err
+	}
+	_, st, _ := s.resolveStyle(row, col, value)
+	var runs []byte
+	for i, run := range rt.Runs {
+		if positions[i] < 0 {
+			continue
+		}
+		font := s.w.styles.font(Style{Font: run.Font}.over(st).Font)
+		if n := len(runs); n > 0 && int(binary.LittleEndian.Uint16(runs[n-4:])) == positions[i] {
+			runs = runs[:n-4]
+		}
+		runs = binary.LittleEndian.AppendUint16(runs, uint16(positions[i]))
+		runs = binary.LittleEndian.AppendUint16(runs, font)
+	}
+	return runs, nil
+}
+
+// richMarker starts the key that stands for rich text among the strings
+// of a write, from cellText to the LABEL and SST records: the marker, the
+// number of runs, the runs as written and the text. No plain string starts
+// with it, as unmarked replaces the byte, which is invalid UTF-8 written as
+// U+FFFD anyway, with U+FFFD.
+const richMarker = "\xff"
+
+// richKey returns the key of a text with runs.
+func richKey(text string, runs []byte) string {
+	var b strings.Builder
+	b.Grow(3 + len(runs) + len(text))
+	b.WriteString(richMarker)
+	b.WriteByte(byte(len(runs) / 4))
+	b.WriteByte(byte(len(runs) / 4 >> 8))
+	b.Write(runs)
+	b.WriteString(text)
+	return b.String()
+}
+
+// splitRichKey returns the text and runs of a rich text key, and s itself
+// and false for a plain string.
+func splitRichKey(s string) (text string, runs []byte, rich bool) {
+	if len(s) < 3 || s[0] != richMarker[0] {
+		return s, nil, false
+	}
+	n := 3 + 4*(int(s[1])|int(s[2])<<8)
+	return s[n:], []byte(s[3:n]), true
+}
+
+// unmarked returns s with a leading richMarker byte replaced by U+FFFD.
+func unmarked(s string) string {
+	if strings.HasPrefix(s, richMarker) {
+		return "\uFFFD" + s[len(richMarker):]
+	}
+	return s
+}
+
+// Runs returns the formatting runs of a text cell with rich text, read
+// from an RSTRING record or the shared string table, or nil. The fonts are
+// as the FONT records of the file give them, with the defaults of Font,
+// Arial and 10 points, left zero. The slice is shared by the cells of the
+// same string and must not be modified.
+func (c Cell) Runs() []TextRun {
+	if c.runs == nil {
+		return nil
+	}
+	return *c.runs
+}
+
+// textRuns decodes the runs of a string read from a file, pairs of a
+// UTF-16 position and a FONT index, into TextRuns, or returns nil if there
+// are none.
+func (wb *Workbook) textRuns(text string, runs []byte) *[]TextRun {
+	if len(runs) < 4 {
+		return nil
+	}
+	out := make([]TextRun, 0, len(runs)/4)
+	start, units := 0, 0
+	rest := text
+	for ; len(runs) >= 4; runs = runs[4:] {
+		ich := int(binary.LittleEndian.Uint16(runs))
+		if ich < units {
+			start, units, rest = 0, 0, text
+		}
+		for units < ich && rest != "" {
+			r, size := utf8.DecodeRuneInString(rest)
+			units += utf16.RuneLen(r)
+			rest = rest[size:]
+			start++
+		}
+		out = append(out, TextRun{Start: start, Font: wb.font(binary.LittleEndian.Uint16(runs[2:]))})
+	}
+	return &out
+}
+
+// font returns the font of a FONT index, or the default font for an index
+// with no FONT record.
+func (wb *Workbook) font(index uint16) Font {
+	// Font index 4 is never written, so the FONT records skip it
+	i := int(index)
+	if i >= 4 {
+		i--
+	}
+	if i < len(wb.fonts) {
+		return wb.fonts[i]
+	}
+	return Font{}
+}
@@ -0,0 +1,209 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRichTextRoundTrip(t *testing.T) {
+	bold := Font{Bold: true}
+	courier := Font{Name: "Courier New", Italic: true}
+	data := [][]interface{}{
+		{RichText{Text: "Total: 42 units", Runs: []TextRun{{Start: 7, Font: bold}, {Start: 9, Font: courier}}}, "Total: 42 units"},
+		{Styled(RichText{Text: "αβγ 𝄞x", Runs: []TextRun{{Start: 5, Font: bold}}}, Style{Font: Font{Italic: true, Color: ColorRed}})},
+		{RichText{Text: "no runs"}},
+	}
+	want := [][][]TextRun{
+		{{{Start: 7, Font: bold}, {Start: 9, Font: courier}}, nil},
+		{{{Start: 5, Font: Font{Bold: true, Italic: true, Color: ColorRed}}}},
+		{nil},
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		// Records of the rich text cells
+		rstring, labelSST int
+	}{
+		{"shared strings", nil, 0, 4},
+		{"inline", []Option{WithHybridStrings(2)}, 2, 0},
+		{"spilled", []Option{WithLowMemorySST(), WithSSTSpillThreshold(1)}, 0, 4},
+		{"no deduplication", []Option{WithoutStringDeduplication()}, 0, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(append(tt.opts, WithPostWriteVerification())...)
+			defer w.Close()
+			if err := w.Write(data); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			file := writtenBytes(t, w)
+
+			stream, err := workbookStream(file)
+			if err != nil {
+				t.Fatalf("workbookStream() failed: %v", err)
+			}
+			records, err := readAllRecords(stream)
+			if err != nil {
+				t.Fatalf("readAllRecords() failed: %v", err)
+			}
+			counts := make(map[uint16]int)
+			for _, rec := range records {
+				counts[rec.Type]++
+			}
+			if counts[recTypeRSTRING] != tt.rstring || counts[recTypeLABELSST] != tt.labelSST {
+				t.Errorf("Expected %d RSTRING and %d LABELSST records, got %d and %d", tt.rstring, tt.labelSST, counts[recTypeRSTRING], counts[recTypeLABELSST])
+			}
+
+			wb, err := openWorkbook(file)
+			if err != nil {
+				t.Fatalf("openWorkbook() failed: %v", err)
+			}
+			sheet := wb.Sheets()[0]
+			for r, row := range want {
+				for c, runs := range row {
+					cell := sheet.Cell(r, c)
+					if text, _ := sstString(data[r][c]); cell.Kind != KindText || cell.Value != text {
+						t.Errorf("Cell(%d, %d): expected the text back, got %s %v", r, c, cell.Kind, cell.Value)
+					}
+					if got := cell.Runs(); !reflect.DeepEqual(got, runs) {
+						t.Errorf("Cell(%d, %d).Runs() = %+v, expected %+v", r, c, got, runs)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestRichTextContinue checks rich text whose characters and runs continue
+// across CONTINUE records of the SST.
+func TestRichTextContinue(t *testing.T) {
+	text := strings.Repeat("abcdefghij", 1000)
+	var runs []TextRun
+	for start := 0; start < len(text); start += 5 {
+		run := TextRun{Start: start}
+		if start%10 == 0 {
+			run.Font.Bold = true
+		}
+		runs = append(runs, run)
+	}
+
+	w := New()
+	if err := w.Write([][]interface{}{{"before", RichText{Text: text, Runs: runs}, "after"}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	sheet := readBack(t, w)
+	if got := sheet.Cell(0, 1).Value; got != text {
+		t.Errorf("Expected the text of %d characters back, got %d", len(text), len(got.(string)))
+	}
+	if got := sheet.Cell(0, 1).Runs(); !reflect.DeepEqual(got, runs) {
+		t.Errorf("Expected %d runs back, got %d", len(runs), len(got))
+	}
+	if got := sheet.Cell(0, 2).Value; got != "after" {
+		t.Errorf("Expected the next string after the rich text, got %v", got)
+	}
+}
+
+func TestRichTextPositions(t *testing.T) {
+	tests := []struct {
+		text   string
+		starts []int
+		want   []int
+	}{
+		{"plain", []int{0, 2}, []int{0, 2}},
+		{"a𝄞b", []int{1, 2}, []int{1, 3}},
+		// The byte order mark is dropped and CRLF is written as LF
+		{"\uFEFFa\r\nb", []int{0, 1, 3, 4}, []int{0, 0, 2, 2}},
+		{"x\r\n", []int{1, 2}, []int{1, -1}},
+	}
+	for _, tt := range tests {
+		rt := RichText{Text: tt.text}
+		for _, start := range tt.starts {
+			rt.Runs = append(rt.Runs, TextRun{Start: start})
+		}
+		got, err := rt.positions()
+		if err != nil {
+			t.Errorf("positions(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("positions(%q) = %v, expected %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRichTextErrors(t *testing.T) {
+	for _, runs := range [][]TextRun{{{Start: 3}}, {{Start: -1}}, {{Start: 1}, {Start: 0}}} {
+		w := New()
+		w.Write([][]interface{}{{RichText{Text: "abc", Runs: runs}}})
+		if _, err := w.WriteTo(new(bytes.Buffer)); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Runs %v: expected an out of range error, got %v", runs, err)
+		}
+	}
+
+	w := New()
+	if err := w.PredeclareStrings([]string{"abc"}); err != nil {
+		t.Fatalf("PredeclareStrings() failed: %v", err)
+	}
+	if err := w.Write([][]interface{}{{RichText{Text: "abc", Runs: []TextRun{{Start: 1}}}}}); !errors.Is(err, ErrUndeclaredString) {
+		t.Errorf("Expected an undeclared string error, got %v", err)
+	}
+	if err := w.Write([][]interface{}{{RichText{Text: "abc"}}}); err != nil {
+		t.Errorf("Rich text without runs is a plain string, got %v", err)
+	}
+}
+
+// TestReadRichText reads the runs of rich text in the SST and in an
+// RSTRING record of a hand-built workbook.
+func TestReadRichText(t *testing.T) {
+	font := func(weight, grbit int, name string) testRecord {
+		return testRecord{recTypeFONT, le(200, grbit, 0x7FFF, weight, 0, byte(0), byte(0), byte(1), byte(0), byte(len(name)), byte(0), name)}
+	}
+	globals := []testRecord{
+		font(400, 0, "Arial"),
+		font(400, 0, "Arial"),
+		font(400, 0, "Arial"),
+		font(400, 0, "Arial"),
+		// Font index 4 is skipped: these are fonts 5 and 6
+		font(700, 0, "Arial"),
+		font(400, 0x02, "Courier New"),
+		{recTypeSST, le(uint32(2), uint32(2),
+			5, byte(0x08), 2, "Hello", 1, 5, 3, 6,
+			4, byte(0), "Nope")},
+	}
+	cells := []testRecord{
+		{recTypeLABELSST, le(0, 0, 0, uint32(0))},
+		{recTypeLABELSST, le(0, 1, 0, uint32(1))},
+		{recTypeRSTRING, le(1, 0, 0, 4, byte(0), "Rich", 2, 0, 5, 2, 6)},
+		{recTypeLABEL, le(1, 1, 0, 5, byte(0), "Plain")},
+	}
+	wb, err := openWorkbook(buildFixture(t, globals, cells))
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+
+	bold, courier := Font{Bold: true}, Font{Name: "Courier New", Italic: true}
+	tests := []struct {
+		row, col int
+		text     string
+		runs     []TextRun
+	}{
+		{0, 0, "Hello", []TextRun{{Start: 1, Font: bold}, {Start: 3, Font: courier}}},
+		{0, 1, "Nope", nil},
+		{1, 0, "Rich", []TextRun{{Start: 0, Font: bold}, {Start: 2, Font: courier}}},
+		{1, 1, "Plain", nil},
+	}
+	sheet := wb.Sheets()[0]
+	for _, tt := range tests {
+		cell := sheet.Cell(tt.row, tt.col)
+		if cell.Value != tt.text {
+			t.Errorf("Cell(%d, %d): expected %q, got %v", tt.row, tt.col, tt.text, cell.Value)
+		}
+		if got := cell.Runs(); !reflect.DeepEqual(got, tt.runs) {
+			t.Errorf("Cell(%d, %d).Runs() = %+v, expected %+v", tt.row, tt.col, got, tt.runs)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRowOutOfRange is returned by InsertRow and DeleteRow for an index
+// outside the sheet's data.
+var ErrRowOutOfRange = errors.New("xls: row out of range")
+
+// InsertRow inserts a row into the data of the first sheet. See
+// SheetWriter.InsertRow.
+func (w *Writer) InsertRow(index int, cells []interface{}) error {
+	return w.sheets[0].InsertRow(index, cells)
+}
+
+// DeleteRow deletes a row from the data of the first sheet. See
+// SheetWriter.DeleteRow.
+func (w *Writer) DeleteRow(index int) error {
+	return w.sheets[0].DeleteRow(index)
+}
+
+// InsertRow inserts cells as the zero-based row index of the data, shifting
+// the rows from index down by one. index may be the number of rows to
+// append a row.
+//
+// Row and cell styles, outline levels and the AutoFilter move with their
+// rows; an AutoFilter range gains the row if it is inserted below its
+// header row. Column styles and widths, frozen panes and formulas are left
+// as they are, so formula references to shifted rows are not rewritten.
+func (s *SheetWriter) InsertRow(index int, cells []interface{}) error {
+	s.materialize()
+	if index < 0 || index > len(s.data) || len(s.data) > maxRow {
+		return fmt.Errorf("%w: cannot insert row %d into %d rows", ErrRowOutOfRange, index, len(s.data))
+	}
+
+	if plain, ok := plainRow(cells); ok {
+		cells = plain
+	}
+	if s.w.dict != nil {
+		if err := s.checkDeclared([][]interface{}{cells}, index); err != nil {
+			return err
+		}
+	}
+
+	// Build a new slice so that the caller's slice passed to Write is not
+	// modified
+	data := make([][]interface{}, 0, len(s.data)+1)
+	data = append(data, s.data[:index]...)
+	data = append(data, cells)
+	s.data = append(data, s.data[index:]...)
+
+	s.shiftRows(index, 1)
+	if r := s.autoFilter; r != nil {
+		switch {
+		case index <= r.firstRow:
+			r.firstRow++
+			r.lastRow++
+		case index <= r.lastRow:
+			r.lastRow++
+		}
+		if r.lastRow > maxRow {
+			r.lastRow = maxRow
+		}
+	}
+	return nil
+}
+
+// DeleteRow deletes the zero-based row index of the data, shifting the rows
+// below it up by one.
+//
+// The styles and outline level of the row are dropped and those of the rows
+// below move up with them. An AutoFilter range spanning the row shrinks by one row and is
+// dropped once no row is left. As with InsertRow, formulas are not
+// rewritten.
+func (s *SheetWriter) DeleteRow(index int) error {
+	s.materialize()
+	if index < 0 || index >= len(s.data) {
+		return fmt.Errorf("%w: cannot delete row %d of %d rows", ErrRowOutOfRange, index, len(s.data))
+	}
+
+	data := make([][]interface{}, 0, len(s.data)-1)
+	data = append(data, s.data[:index]...)
+	s.data = append(data, s.data[index+1:]...)
+
+	for key := range s.cellStyles {
+		if key[0] == index {
+			delete(s.cellStyles, key)
+		}
+	}
+	delete(s.rowStyles, index)
+	delete(s.rowLevels, index)
+	s.shiftRows(index+1, -1)
+	if r := s.autoFilter; r != nil {
+		switch {
+		case index < r.firstRow:
+			r.firstRow--
+			r.lastRow--
+		case index <= r.lastRow:
+			r.lastRow--
+		}
+		if r.lastRow < r.firstRow {
+			s.autoFilter = nil
+		}
+	}
+	return nil
+}
+
+// shiftRows moves the row and cell styles and outline levels of the rows
+// from index on by delta rows. Styles moved past the last row Excel supports are dropped.
+func (s *SheetWriter) shiftRows(index, delta int) {
+	if len(s.cellStyles) > 0 {
+		cellStyles := make(map[[2]int]Style, len(s.cellStyles))
+		for key, st := range s.cellStyles {
+			if key[0] >= index {
+				if key[0]+delta > maxRow {
+					continue
+				}
+				key[0] += delta
+			}
+			cellStyles[key] = st
+		}
+		s.cellStyles = cellStyles
+	}
+	s.ranges.shift(index, delta)
+	s.rowStyles = shiftRowMap(s.rowStyles, index, delta)
+	s.rowLevels = shiftRowMap(s.rowLevels, index, delta)
+}
+
+// shiftRowMap returns m with the rows from index on moved by delta rows,
+// dropping those moved past the last row.
+func shiftRowMap[V any](m map[int]V, index, delta int) map[int]V {
+	if len(m) == 0 {
+		return m
+	}
+	shifted := make(map[int]V, len(m))
+	for row, v := range m {
+		if row >= index {
+			if row+delta > maxRow {
+				continue
+			}
+			row += delta
+		}
+		shifted[row] = v
+	}
+	return shifted
+}
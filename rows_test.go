@@ -0,0 +1,128 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInsertRow(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := [][]interface{}{
+		{"Name", "Qty"},
+		{"Apple", 3},
+		{"Banana", 6},
+	}
+	w.Write(data)
+	w.FormatAsTable(0, 2, 0, 1, TableStyle{Header: Style{Font: Font{Bold: true}}, AutoFilter: true})
+	w.SetRowStyle(2, Style{Fill: ColorYellow})
+
+	if err := w.InsertRow(2, []interface{}{"Avocado", 4}); err != nil {
+		t.Fatalf("InsertRow() failed: %v", err)
+	}
+	if err := w.InsertRow(0, []interface{}{"Fruit"}); err != nil {
+		t.Fatalf("InsertRow() failed: %v", err)
+	}
+	if err := w.InsertRow(5, nil); err != nil {
+		t.Fatalf("InsertRow() at the end failed: %v", err)
+	}
+
+	want := [][]interface{}{
+		{"Fruit"},
+		{"Name", "Qty"},
+		{"Apple", 3},
+		{"Avocado", 4},
+		{"Banana", 6},
+		nil,
+	}
+	s := w.sheets[0]
+	if !reflect.DeepEqual(s.data, want) {
+		t.Errorf("Expected data %v, got %v", want, s.data)
+	}
+	if len(data) != 3 || data[2][0] != "Banana" {
+		t.Errorf("Expected the written slice unchanged, got %v", data)
+	}
+	if !s.cellStyles[[2]int{1, 0}].Font.Bold || s.cellStyles[[2]int{0, 0}].Font.Bold {
+		t.Error("Expected the header cell styles to move down a row")
+	}
+	if _, ok := s.rowStyles[4]; !ok || len(s.rowStyles) != 1 {
+		t.Errorf("Expected the row style on row 4, got %v", s.rowStyles)
+	}
+	if r := *s.autoFilter; r != (cellRange{firstRow: 1, lastRow: 4, firstCol: 0, lastCol: 1}) {
+		t.Errorf("Expected the AutoFilter to cover rows 1-4, got %+v", r)
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Sheet1", want); err != nil {
+		t.Errorf("Inserted rows do not read back: %v", err)
+	}
+}
+
+func TestDeleteRow(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.Write([][]interface{}{
+		{"Name", "Qty"},
+		{"Apple", 3},
+		{"Banana", 6},
+		{"Total", FormulaCell{Expr: "SUM(B2:B3)", Cached: 9}},
+	})
+	w.FormatAsTable(0, 2, 0, 1, TableStyle{AutoFilter: true})
+	w.SetRowStyle(1, Style{Fill: ColorYellow})
+	w.SetCellStyle(3, 1, Style{Font: Font{Bold: true}})
+
+	if err := w.DeleteRow(1); err != nil {
+		t.Fatalf("DeleteRow() failed: %v", err)
+	}
+
+	s := w.sheets[0]
+	if len(s.data) != 3 || s.data[1][0] != "Banana" {
+		t.Errorf("Expected Apple deleted, got %v", s.data)
+	}
+	if len(s.rowStyles) != 0 {
+		t.Errorf("Expected the deleted row's style dropped, got %v", s.rowStyles)
+	}
+	if !s.cellStyles[[2]int{2, 1}].Font.Bold {
+		t.Error("Expected the total cell style to move up a row")
+	}
+	if r := *s.autoFilter; r.firstRow != 0 || r.lastRow != 1 {
+		t.Errorf("Expected the AutoFilter to shrink to rows 0-1, got %+v", r)
+	}
+	// Formulas are not rewritten
+	if f := s.data[2][1].(FormulaCell); f.Expr != "SUM(B2:B3)" {
+		t.Errorf("Expected the formula unchanged, got %q", f.Expr)
+	}
+
+	w.DeleteRow(0)
+	w.DeleteRow(0)
+	if s.autoFilter != nil {
+		t.Errorf("Expected the AutoFilter dropped with its last row, got %+v", *s.autoFilter)
+	}
+}
+
+func TestRowIndexOutOfRange(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.Write([][]interface{}{{"a"}, {"b"}})
+
+	if err := w.InsertRow(-1, nil); !errors.Is(err, ErrRowOutOfRange) {
+		t.Errorf("InsertRow(-1): expected ErrRowOutOfRange, got %v", err)
+	}
+	if err := w.InsertRow(3, nil); !errors.Is(err, ErrRowOutOfRange) {
+		t.Errorf("InsertRow(3): expected ErrRowOutOfRange, got %v", err)
+	}
+	if err := w.DeleteRow(2); !errors.Is(err, ErrRowOutOfRange) {
+		t.Errorf("DeleteRow(2): expected ErrRowOutOfRange, got %v", err)
+	}
+	if err := w.DeleteRow(-1); !errors.Is(err, ErrRowOutOfRange) {
+		t.Errorf("DeleteRow(-1): expected ErrRowOutOfRange, got %v", err)
+	}
+	if len(w.sheets[0].data) != 2 {
+		t.Errorf("Expected the data unchanged after errors, got %v", w.sheets[0].data)
+	}
+}
@@ -0,0 +1,293 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMissingColumn is returned by Write when the header lacks a column
+	// that the schema marks as required.
+	ErrMissingColumn = errors.New("xls: required column missing")
+	// ErrCoercionFailed is returned by Write, with WithStrictTypes, for a
+	// value that cannot be converted to the type of its column.
+	ErrCoercionFailed = errors.New("xls: value does not match column type")
+)
+
+// ColumnType is the type the values of a column are converted to.
+type ColumnType int
+
+const (
+	ColumnText ColumnType = iota
+	ColumnNumber
+	ColumnDate
+	ColumnBool
+	ColumnCurrency
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case ColumnText:
+		return "text"
+	case ColumnNumber:
+		return "number"
+	case ColumnDate:
+		return "date"
+	case ColumnBool:
+		return "bool"
+	case ColumnCurrency:
+		return "currency"
+	}
+	return fmt.Sprintf("ColumnType(%d)", int(t))
+}
+
+// ColumnSpec declares the type of the column with a header.
+type ColumnSpec struct {
+	// Name is the header of the column in the first row.
+	Name string
+	Type ColumnType
+	// Format is the number format of the column, such as "#,##0.00" or
+	// "yyyy-mm-dd". Without one, numbers and currencies get the
+	// NumberFormat and CurrencyFormat of the theme and dates its date
+	// format.
+	Format string
+	// Required makes Write fail if the first row has no such header.
+	Required bool
+	// Bools is how the bools of the column are written, in place of the
+	// rendering of WithBoolRendering. The zero value keeps that one.
+	Bools BoolRendering
+}
+
+// ColumnSchema declares the columns of the data passed to Write, by header.
+type ColumnSchema []ColumnSpec
+
+// WithSchema makes Write convert the values of the columns the schema
+// declares, found by their header in the first row, instead of writing them
+// as they are:
+//
+//   - ColumnText: values are written as their text
+//   - ColumnNumber: numeric strings, such as "1234.5" or, with
+//     WithNumberLocale, "1.234,5", become numbers
+//   - ColumnCurrency: as ColumnNumber, with the CurrencyFormat of the
+//     theme unless the column has a Format
+//   - ColumnDate: ISO 8601 and RFC 3339 strings, such as "2024-03-01" or
+//     "2024-03-01 10:30:00", become dates, in UTC
+//   - ColumnBool: "true", "false", "yes", "no", "1", "0" and the numbers 1
+//     and 0 become booleans
+//
+// Empty strings become blank cells in non-text columns, and formulas are
+// kept. A value that cannot be converted is written as text and reported
+// as a WarningCoercionFailed to WithDataWarnings, or fails Write with
+// ErrCoercionFailed under WithStrictTypes. A required column missing from
+// the header fails Write with ErrMissingColumn. Format is set as the number
+// format of the column style. The data passed to Write is not modified.
+func WithSchema(schema ColumnSchema) Option {
+	return func(w *Writer) {
+		w.schema = schema
+	}
+}
+
+// dateInputLayouts are the layouts strings are parsed with in ColumnDate
+// columns.
+var dateInputLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006/01/02",
+	"2006/01/02 15:04:05",
+}
+
+// applySchema returns data with the values of the columns of the schema
+// converted, copying the rows that change, and the warnings for the values
+// that could not be. It also reports whether the outer slice was copied.
+func (s *SheetWriter) applySchema(data [][]interface{}) ([][]interface{}, bool, []Warning, error) {
+	var header []interface{}
+	if len(data) > 0 {
+		header = data[0]
+	}
+	var columns []schemaColumn
+	for _, spec := range s.w.schema {
+		col := headerIndex(header, spec.Name)
+		if col < 0 {
+			if spec.Required {
+				return nil, false, nil, fmt.Errorf("%w: %q", ErrMissingColumn, spec.Name)
+			}
+			continue
+		}
+		if !slices.ContainsFunc(columns, func(c schemaColumn) bool { return c.col == col }) {
+			columns = append(columns, schemaColumn{col, spec})
+		}
+	}
+	slices.SortFunc(columns, func(a, b schemaColumn) int { return a.col - b.col })
+	s.boolColumns = nil
+	for _, column := range columns {
+		if column.Bools.mode != boolDefault {
+			if s.boolColumns == nil {
+				s.boolColumns = make(map[int]BoolRendering)
+			}
+			s.boolColumns[column.col] = column.Bools
+		}
+	}
+
+	var warnings []Warning
+	out := data
+	owned := false
+	for r := 1; r < len(data); r++ {
+		row := data[r]
+		var coerced []interface{}
+		for _, column := range columns {
+			c := column.col
+			if c >= len(row) {
+				continue
+			}
+			value, changed, ok := s.w.coerce(row[c], column.Type)
+			if !ok {
+				if s.w.strict {
+					return nil, false, nil, fmt.Errorf("%w: %#v at row %d, column %d does not convert to %s", ErrCoercionFailed, row[c], r, c, column.Type)
+				}
+				warnings = append(warnings, Warning{Kind: WarningCoercionFailed, Cell: CellRef{Sheet: s.name, Row: r, Col: c}, Count: 1})
+				value, changed, _ = s.w.coerce(row[c], ColumnText)
+			}
+			if !changed {
+				continue
+			}
+			if coerced == nil {
+				coerced = append([]interface{}(nil), row...)
+			}
+			coerced[c] = value
+		}
+		if coerced == nil {
+			continue
+		}
+		if !owned {
+			out, owned = append([][]interface{}(nil), data...), true
+		}
+		out[r] = coerced
+	}
+
+	for _, column := range columns {
+		format := column.Format
+		if format == "" {
+			switch column.Type {
+			case ColumnNumber:
+				format = s.w.theme.NumberFormat
+			case ColumnCurrency:
+				format = s.w.theme.CurrencyFormat
+			}
+		}
+		if format == "" {
+			continue
+		}
+		if st := s.colStyles[column.col]; st.NumberFormat == "" {
+			st.NumberFormat = format
+			s.SetColStyle(column.col, st)
+		}
+	}
+	return out, owned, warnings, nil
+}
+
+// headerIndex returns the column of the header cell holding name, or -1.
+func headerIndex(header []interface{}, name string) int {
+	return slices.IndexFunc(header, func(cell interface{}) bool {
+		if sc, ok := cell.(StyledCell); ok {
+			cell = sc.Value
+		}
+		return cell == name
+	})
+}
+
+// schemaColumn is a column of the data declared in a schema.
+type schemaColumn struct {
+	col int
+	ColumnSpec
+}
+
+// coerce converts value to a value of the column type, and reports whether
+// the value changed and whether it could be converted. Styled cells keep
+// their style.
+func (w *Writer) coerce(value interface{}, t ColumnType) (interface{}, bool, bool) {
+	if sc, ok := value.(StyledCell); ok {
+		v, changed, ok := w.coerce(sc.Value, t)
+		sc.Value = v
+		return sc, changed, ok
+	}
+	if _, ok := value.(FormulaCell); ok || value == nil {
+		return value, false, true
+	}
+	str, isString := value.(string)
+	if isString && t != ColumnText && strings.TrimSpace(str) == "" {
+		return nil, true, true
+	}
+
+	switch t {
+	case ColumnText:
+		switch v := value.(type) {
+		case string:
+			return v, false, true
+		case time.Time:
+			if hasTimeOfDay(v) {
+				return v.Format("2006-01-02 15:04:05"), true, true
+			}
+			return v.Format("2006-01-02"), true, true
+		}
+		return fmt.Sprint(value), true, true
+	case ColumnNumber, ColumnCurrency:
+		if _, ok := toFloat64(value); ok {
+			return value, false, true
+		}
+		if !isString {
+			return value, false, false
+		}
+		text := strings.TrimSpace(str)
+		if w.locale != nil {
+			var ok bool
+			if text, ok = w.locale.normalize(text); !ok {
+				return value, false, false
+			}
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return value, false, false
+		}
+		return f, true, true
+	case ColumnDate:
+		if _, ok := value.(time.Time); ok {
+			return value, false, true
+		}
+		if !isString {
+			return value, false, false
+		}
+		for _, layout := range dateInputLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(str)); err == nil {
+				return t, true, true
+			}
+		}
+		return value, false, false
+	case ColumnBool:
+		if _, ok := value.(bool); ok {
+			return value, false, true
+		}
+		if f, ok := toFloat64(value); ok && (f == 0 || f == 1) {
+			return f == 1, true, true
+		}
+		if !isString {
+			return value, false, false
+		}
+		switch strings.ToLower(strings.TrimSpace(str)) {
+		case "true", "yes", "1":
+			return true, true, true
+		case "false", "no", "0":
+			return false, true, true
+		}
+		return value, false, false
+	}
+	return value, false, true
+}
@@ -0,0 +1,163 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoerce(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	bold := Style{Font: Font{Bold: true}}
+	w := New()
+	tests := []struct {
+		value interface{}
+		typ   ColumnType
+		want  interface{}
+		ok    bool
+	}{
+		{"abc", ColumnText, "abc", true},
+		{42, ColumnText, "42", true},
+		{1.5, ColumnText, "1.5", true},
+		{true, ColumnText, "true", true},
+		{day, ColumnText, "2024-03-01", true},
+		{day.Add(90 * time.Minute), ColumnText, "2024-03-01 01:30:00", true},
+		{"", ColumnText, "", true},
+
+		{"1234.5", ColumnNumber, 1234.5, true},
+		{" -7 ", ColumnNumber, -7.0, true},
+		{42, ColumnNumber, 42, true},
+		{"", ColumnNumber, nil, true},
+		{"1,234", ColumnNumber, "1,234", false},
+		{"NaN", ColumnNumber, "NaN", false},
+		{true, ColumnNumber, true, false},
+
+		{"2024-03-01", ColumnDate, day, true},
+		{"2024-03-01 01:30:00", ColumnDate, day.Add(90 * time.Minute), true},
+		{"2024-03-01T01:30:00Z", ColumnDate, day.Add(90 * time.Minute), true},
+		{"2024/03/01", ColumnDate, day, true},
+		{day, ColumnDate, day, true},
+		{"03/01/2024", ColumnDate, "03/01/2024", false},
+		{"2024-02-30", ColumnDate, "2024-02-30", false},
+		{45000, ColumnDate, 45000, false},
+
+		{"true", ColumnBool, true, true},
+		{"No", ColumnBool, false, true},
+		{"1", ColumnBool, true, true},
+		{0, ColumnBool, false, true},
+		{true, ColumnBool, true, true},
+		{"maybe", ColumnBool, "maybe", false},
+		{2, ColumnBool, 2, false},
+
+		{nil, ColumnNumber, nil, true},
+		{FormulaCell{Expr: "A1+1"}, ColumnDate, FormulaCell{Expr: "A1+1"}, true},
+		{Styled("3", bold), ColumnNumber, Styled(3.0, bold), true},
+	}
+	for _, tt := range tests {
+		got, _, ok := w.coerce(tt.value, tt.typ)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.ok {
+			t.Errorf("coerce(%#v, %s): expected %#v, %v, got %#v, %v", tt.value, tt.typ, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := ColumnSchema{
+		{Name: "Date", Type: ColumnDate, Required: true},
+		{Name: "Amount", Type: ColumnNumber, Format: "#,##0.00", Required: true},
+		{Name: "Paid", Type: ColumnBool},
+		{Name: "Code", Type: ColumnText},
+		{Name: "Missing", Type: ColumnNumber},
+	}
+	data := [][]interface{}{
+		{"Code", "Date", "Amount", "Paid", "Note"},
+		{1001, "2024-03-01", "1234.5", "yes", "12"},
+		{"A-7", "2024-03-02", "n/a", "0", nil},
+		{1003},
+	}
+	var warnings []Warning
+	w := New(WithSchema(schema), WithDataWarnings(func(warning Warning) {
+		warnings = append(warnings, warning)
+	}))
+	defer w.Close()
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	want := [][]interface{}{
+		{"Code", "Date", "Amount", "Paid", "Note"},
+		{"1001", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1234.5, true, "12"},
+		{"A-7", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), "n/a", false, nil},
+		{"1003"},
+	}
+	if !reflect.DeepEqual(w.sheets[0].data, want) {
+		t.Errorf("Expected %v, got %v", want, w.sheets[0].data)
+	}
+	if data[1][0] != 1001 || data[1][2] != "1234.5" {
+		t.Error("Expected the data passed to Write to be left unchanged")
+	}
+	if st := w.sheets[0].colStyles[2]; st.NumberFormat != "#,##0.00" {
+		t.Errorf("Expected the column format set, got %+v", st)
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wantWarning := Warning{Kind: WarningCoercionFailed, Cell: CellRef{Sheet: "Sheet1", Row: 2, Col: 2}, Count: 1}
+	if len(warnings) == 0 || warnings[0] != wantWarning {
+		t.Errorf("Expected %v first, got %v", wantWarning, warnings)
+	}
+	wb, _ := openWorkbook(buf.Bytes())
+	s := wb.Sheets()[0]
+	if c := s.Cell(1, 1); !c.IsDate() {
+		t.Errorf("Expected a date, got %v in %q", c.Value, c.FormatString)
+	}
+	if c := s.Cell(1, 2); c.Value != 1234.5 || c.FormatString != "#,##0.00" {
+		t.Errorf("Expected 1234.5 in the column format, got %v in %q", c.Value, c.FormatString)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	schema := ColumnSchema{{Name: "Amount", Type: ColumnNumber, Required: true}}
+
+	w := New(WithSchema(schema))
+	if err := w.Write([][]interface{}{{"Total"}, {1}}); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("Expected ErrMissingColumn, got %v", err)
+	}
+	if err := w.Write(nil); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("Expected ErrMissingColumn for no header, got %v", err)
+	}
+
+	w = New(WithSchema(schema), WithStrictTypes())
+	w.Write([][]interface{}{{"Amount"}, {1}})
+	err := w.Write([][]interface{}{{"Amount"}, {"12"}, {"twelve"}})
+	if !errors.Is(err, ErrCoercionFailed) {
+		t.Fatalf("Expected ErrCoercionFailed, got %v", err)
+	}
+	if want := `xls: value does not match column type: "twelve" at row 2, column 0 does not convert to number`; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if !reflect.DeepEqual(w.sheets[0].data, [][]interface{}{{"Amount"}, {1}}) {
+		t.Errorf("Expected a failed Write to keep the previous data, got %v", w.sheets[0].data)
+	}
+}
+
+func TestSchemaWriteToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.xls")
+	schema := ColumnSchema{{Name: "Betrag", Type: ColumnNumber}}
+	err := WriteToFile(path, [][]interface{}{{"Betrag"}, {"1.234,56"}}, WithSchema(schema), WithNumberLocale("de-DE"))
+	if err != nil {
+		t.Fatalf("WriteToFile() failed: %v", err)
+	}
+	wb, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() failed: %v", err)
+	}
+	if got := wb.Sheets()[0].Cell(1, 0).Value; got != 1234.56 {
+		t.Errorf("Expected 1234.56, got %#v", got)
+	}
+}
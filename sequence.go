@@ -0,0 +1,270 @@
+package xls
+
+import (
+	"bytes"
+	"io"
+	"slices"
+)
+
+// recordWriter is one step of a substream: the records it is named after,
+// as RecordName names them, which write appends to the substream if when,
+// when set, allows it.
+type recordWriter struct {
+	name     string
+	optional bool // Left out with WithMinimalRecords; see optional
+	when     func(w *Writer) bool
+	write    func(w *Writer, st *substream) error
+}
+
+// substream is the state a sequence of recordWriters is written with.
+type substream struct {
+	buf streamBuffer
+	sst *sharedStringTable
+
+	// Workbook globals
+	names        *bytes.Buffer // NAME records, then the raw globals
+	sstPos       int           // Position of the SST record, for EXTSST
+	extSST       []extSSTEntry // The EXTSST buckets, if the profile writes EXTSST
+	extSSTBucket int           // Strings per EXTSST bucket, 0 without EXTSST
+	boundSheets  []int         // Position of the offset of each BOUNDSHEET
+
+	// Worksheets
+	sheet       *SheetWriter
+	ext         sheetExtents
+	index       int // Position of the INDEX record
+	defColWidth int // Position of DEFCOLWIDTH, for the INDEX record
+}
+
+// record returns the step of a record that only depends on the Writer.
+func record(name string, write func(*Writer, io.Writer) error) recordWriter {
+	return recordWriter{name: name, write: func(w *Writer, st *substream) error {
+		return write(w, st.buf)
+	}}
+}
+
+// optionalRecord is record for a record that only restates a default.
+func optionalRecord(name string, write func(*Writer, io.Writer) error) recordWriter {
+	r := record(name, write)
+	r.optional = true
+	return r
+}
+
+// sheetRecord returns the step of a worksheet record that depends on the
+// sheet.
+func sheetRecord(name string, write func(*Writer, io.Writer, *SheetWriter) error) recordWriter {
+	return recordWriter{name: name, write: func(w *Writer, st *substream) error {
+		return write(w, st.buf, st.sheet)
+	}}
+}
+
+// globalsRecords is the workbook globals substream up to the styles, in
+// the order of the [MS-XLS] globals grammar. The records after them are
+// ordered by the profile; see globalsSequence.
+var globalsRecords = []recordWriter{
+	{name: "BOF", write: func(w *Writer, st *substream) error {
+		return w.writeBOF(st.buf, bofWorkbook)
+	}},
+	record("FILEPASS", (*Writer).writeFilePass),
+	optionalRecord("INTERFACEHDR", (*Writer).writeInterfaceHdr),
+	optionalRecord("MMS", (*Writer).writeMMS),
+	optionalRecord("INTERFACEEND", (*Writer).writeInterfaceEnd),
+	optionalRecord("WRITEACCESS", (*Writer).writeWriteAccess),
+	record("CODEPAGE", (*Writer).writeCodePage),
+	record("DSF", (*Writer).writeDSF),
+	record("TABID", (*Writer).writeFnGroupCount),
+	optionalRecord("FNGROUPCOUNT", (*Writer).writeUnknown9C),
+	record("WINDOWPROTECT", (*Writer).writeWindowProtect),
+	record("PROTECT", (*Writer).writeProtect),
+	optionalRecord("OBJPROTECT", (*Writer).writeObjProtect),
+	record("PASSWORD", (*Writer).writePassword),
+	record("PROT4REV", (*Writer).writeProt4Rev),
+	record("PASSWORDREV4", (*Writer).writePasswordRev4),
+	optionalRecord("BACKUP", (*Writer).writeBackup),
+	optionalRecord("HIDEOBJ", (*Writer).writeHideObj),
+	record("WINDOW1", (*Writer).writeWindow1),
+	record("DATEMODE", (*Writer).writeDateMode),
+	record("PRECISION", (*Writer).writePrecision),
+	optionalRecord("REFRESHALL", (*Writer).writeRefreshAll),
+	optionalRecord("BOOKBOOL", (*Writer).writeBookBool),
+	record("FONT", func(w *Writer, writer io.Writer) error {
+		// BIFF8 requires 7 default font records
+		for range 7 {
+			if err := w.writeDefaultFont(writer); err != nil {
+				return err
+			}
+		}
+		return w.writeStyleFonts(writer)
+	}),
+	record("FORMAT", func(w *Writer, writer io.Writer) error {
+		for _, f := range []struct {
+			index  uint16
+			format string
+		}{
+			{fmtDate, "yyyy-mm-dd"},
+			{fmtDateTime, "yyyy-mm-dd hh:mm:ss"},
+		} {
+			if err := w.writeFormat(writer, f.index, f.format); err != nil {
+				return err
+			}
+		}
+		return w.writeStyleFormats(writer)
+	}),
+	record("XF", func(w *Writer, writer io.Writer) error {
+		// First 16 XF records are style XF
+		for range 16 {
+			if err := w.writeXF(writer, true, 6, fmtGeneral); err != nil {
+				return err
+			}
+		}
+		// Cell XF records
+		for _, xf := range [][2]uint16{{6, fmtGeneral}, {7, fmtGeneral}, {6, fmtDate}, {6, fmtDateTime}} {
+			if err := w.writeXF(writer, false, xf[0], xf[1]); err != nil {
+				return err
+			}
+		}
+		return w.writeStyleXFs(writer)
+	}),
+	record("STYLE", (*Writer).writeDefaultStyle),
+	record("USESELFS", (*Writer).writeUseSelfs),
+	record("RECALCID", (*Writer).writeRecalcID),
+}
+
+// globalsTail is the records of each part of the end of the workbook
+// globals, which the profile orders.
+var globalsTail = [...][]recordWriter{
+	globalsSST: {
+		{name: "SST", write: func(w *Writer, st *substream) error {
+			st.sstPos = st.buf.Len()
+			if w.spec().extSST {
+				st.extSSTBucket = extSSTBucket(st.sst.uniqueCount)
+			}
+			var err error
+			st.extSST, err = w.writeSST(st.buf, st.sst, st.extSSTBucket)
+			return err
+		}},
+		{name: "EXTSST", when: func(w *Writer) bool { return w.spec().extSST }, write: func(w *Writer, st *substream) error {
+			return w.writeExtSST(st.buf, st.sstPos, st.extSSTBucket, st.extSST)
+		}},
+	},
+	globalsBoundSheets: {
+		{name: "BOUNDSHEET", write: func(w *Writer, st *substream) error {
+			// The worksheets are not placed yet: writeBIFF8 patches their
+			// offsets in once the globals are written
+			for _, s := range w.sheets {
+				st.boundSheets = append(st.boundSheets, st.buf.Len()+4)
+				if err := w.writeBoundSheet(st.buf, 0, s.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	},
+	globalsNames: {
+		{name: "NAME", write: func(w *Writer, st *substream) error {
+			_, err := st.buf.Write(st.names.Bytes())
+			return err
+		}},
+	},
+}
+
+// globalsSequence returns the workbook globals substream of the Writer's
+// profile.
+func (w *Writer) globalsSequence() []recordWriter {
+	seq := slices.Clone(globalsRecords)
+	for _, part := range w.spec().tail {
+		seq = append(seq, globalsTail[part]...)
+	}
+	return append(seq, record("EOF", (*Writer).writeEOF))
+}
+
+// worksheetRecords is the worksheet substream. Records follow the order of
+// the [MS-XLS] worksheet substream grammar: calculation settings, sheet
+// globals, page setup, protection, columns, AutoFilter, DIMENSIONS, the
+// cell table and the window.
+var worksheetRecords = []recordWriter{
+	{name: "BOF", write: func(w *Writer, st *substream) error {
+		return w.writeBOF(st.buf, bofWorksheet)
+	}},
+	{name: "INDEX", when: func(w *Writer) bool { return w.spec().index }, write: func(w *Writer, st *substream) error {
+		st.index = st.buf.Len()
+		return w.writeIndex(st.buf, st.ext.rows)
+	}},
+	record("CALCMODE", (*Writer).writeCalcMode),
+	record("CALCCOUNT", (*Writer).writeCalcCount),
+	record("REFMODE", (*Writer).writeRefMode),
+	record("ITERATION", (*Writer).writeIteration),
+	record("DELTA", (*Writer).writeDelta),
+	record("SAVERECALC", (*Writer).writeSaveRecalc),
+	record("PRINTHEADERS", (*Writer).writePrintHeaders),
+	sheetRecord("PRINTGRIDLINES", (*Writer).writePrintGridlines),
+	record("GRIDSET", (*Writer).writeGridSet),
+	sheetRecord("GUTS", (*Writer).writeGuts),
+	record("DEFAULTROWHEIGHT", (*Writer).writeDefaultRowHeight),
+	sheetRecord("WSBOOL", (*Writer).writeWSBool),
+	optionalRecord("HORIZONTALPAGEBREAKS", (*Writer).writeHBreak),
+	optionalRecord("VERTICALPAGEBREAKS", (*Writer).writeVBreak),
+	optionalRecord("HEADER", (*Writer).writeHeader),
+	optionalRecord("FOOTER", (*Writer).writeFooter),
+	optionalRecord("HCENTER", (*Writer).writeHCenter),
+	optionalRecord("VCENTER", (*Writer).writeVCenter),
+	optionalRecord("LEFTMARGIN", (*Writer).writeLeftMargin),
+	optionalRecord("RIGHTMARGIN", (*Writer).writeRightMargin),
+	optionalRecord("TOPMARGIN", (*Writer).writeTopMargin),
+	optionalRecord("BOTTOMMARGIN", (*Writer).writeBottomMargin),
+	sheetRecord("SETUP", (*Writer).writeSetup),
+	// WINDOWPROTECT belongs to the workbook globals only
+	sheetRecord("PROTECT", (*Writer).writeSheetProtection),
+	{name: "DEFCOLWIDTH", write: func(w *Writer, st *substream) error {
+		st.defColWidth = st.buf.Len()
+		return w.writeDefColWidth(st.buf)
+	}},
+	sheetRecord("COLINFO", (*Writer).writeColInfo),
+	sheetRecord("AUTOFILTERINFO", (*Writer).writeAutoFilterInfo),
+	// DIMENSIONS must come before ROW records
+	{name: "DIMENSIONS", write: func(w *Writer, st *substream) error {
+		return w.writeDimensions(st.buf, st.ext)
+	}},
+	{name: "ROW", write: func(w *Writer, st *substream) error {
+		dbCells, err := w.writeRowsAndCells(st.buf, st.sheet, st.sst, st.ext)
+		if err != nil {
+			return err
+		}
+		if w.spec().index {
+			return patchIndex(st.buf, st.index, st.defColWidth, dbCells)
+		}
+		return nil
+	}},
+	// The window block (WINDOW2, then SCL, PANE and SELECTION if present)
+	// follows the cell table
+	sheetRecord("WINDOW2", (*Writer).writeWindow2),
+	sheetRecord("SCL", (*Writer).writeSCL),
+	sheetRecord("PANE", (*Writer).writePane),
+	{name: "STANDARDWIDTH", when: func(w *Writer) bool { return w.defColWidth != 0 }, write: func(w *Writer, st *substream) error {
+		return w.writeStandardWidth(st.buf)
+	}},
+	{name: "raw", write: func(w *Writer, st *substream) error {
+		return w.writeRawRecords(st.buf, st.sheet.raw)
+	}},
+	record("EOF", (*Writer).writeEOF),
+}
+
+// writeSequence runs the steps of seq that apply to the Writer, in order.
+func (w *Writer) writeSequence(seq []recordWriter, st *substream) error {
+	for _, r := range seq {
+		if !w.applies(r) {
+			continue
+		}
+		if err := r.write(w, st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applies reports whether the Writer writes the step r.
+func (w *Writer) applies(r recordWriter) bool {
+	if r.optional && w.omitsOptional() {
+		return false
+	}
+	return r.when == nil || r.when(w)
+}
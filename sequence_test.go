@@ -0,0 +1,192 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sequenceNames returns the names of the steps of seq the Writer writes.
+func sequenceNames(w *Writer, seq []recordWriter) []string {
+	var names []string
+	for _, r := range seq {
+		if w.applies(r) {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+func TestRecordSequence(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		globals string // The steps after RECALCID
+		sheet   string // The steps after BOF up to CALCMODE
+		minimal bool
+	}{
+		{"default", nil, "SST BOUNDSHEET NAME EOF", "CALCMODE", false},
+		{"minimal", []Option{WithMinimalRecords()}, "SST BOUNDSHEET NAME EOF", "CALCMODE", true},
+		{"excel97", []Option{WithCompatibility(ProfileExcel97)}, "BOUNDSHEET NAME SST EXTSST EOF", "INDEX CALCMODE", false},
+		{"poi", []Option{WithCompatibility(ProfilePOI)}, "BOUNDSHEET NAME SST EOF", "INDEX CALCMODE", false},
+		{"strict", []Option{WithCompatibility(ProfileStrict), WithMinimalRecords()}, "BOUNDSHEET NAME SST EXTSST EOF", "INDEX CALCMODE", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := New(tc.opts...)
+			globals := sequenceNames(w, w.globalsSequence())
+			if globals[0] != "BOF" {
+				t.Errorf("Expected the globals to start with BOF, got %s", globals[0])
+			}
+			if got := strings.Join(globals[slices.Index(globals, "RECALCID")+1:], " "); got != tc.globals {
+				t.Errorf("Expected globals tail %q, got %q", tc.globals, got)
+			}
+			if got := slices.Contains(globals, "WRITEACCESS"); got == tc.minimal {
+				t.Errorf("Expected WRITEACCESS %v, got %v", !tc.minimal, got)
+			}
+
+			sheet := sequenceNames(w, worksheetRecords)
+			if got := strings.Join(sheet[1:slices.Index(sheet, "CALCMODE")+1], " "); got != tc.sheet {
+				t.Errorf("Expected worksheet head %q, got %q", tc.sheet, got)
+			}
+			if got := slices.Contains(sheet, "HEADER"); got == tc.minimal {
+				t.Errorf("Expected HEADER %v, got %v", !tc.minimal, got)
+			}
+			if sheet[len(sheet)-1] != "EOF" {
+				t.Errorf("Expected the worksheet to end with EOF, got %s", sheet[len(sheet)-1])
+			}
+		})
+	}
+}
+
+// stepRecords runs seq as writeSequence does and returns the names of the
+// records each step wrote.
+func stepRecords(t *testing.T, w *Writer, seq []recordWriter, st *substream) map[string][]string {
+	t.Helper()
+	written := make(map[string][]string)
+	for _, r := range seq {
+		if !w.applies(r) {
+			continue
+		}
+		start := st.buf.Len()
+		if err := r.write(w, st); err != nil {
+			t.Fatalf("%s failed: %v", r.name, err)
+		}
+		records, err := readAllRecords(st.buf.(bufferAt).Bytes()[start:])
+		if err != nil {
+			t.Fatalf("Failed to read the records of %s: %v", r.name, err)
+		}
+		for _, rec := range records {
+			written[r.name] = append(written[r.name], RecordName(rec.Type))
+		}
+	}
+	return written
+}
+
+// TestRecordSequenceWritten checks that each step only writes the records
+// it is named after, or those that belong to them.
+func TestRecordSequenceWritten(t *testing.T) {
+	others := map[string][]string{
+		"PROTECT": {"SCENPROTECT", "OBJPROTECT", "PASSWORD"},
+		"NAME":    {"SUPBOOK", "EXTERNSHEET"},
+		"ROW":     {"LABELSST", "NUMBER", "RK", "BOOLERR", "BLANK", "MULBLANK", "DBCELL"},
+	}
+	for _, p := range []Profile{ProfileDefault, ProfileExcel97} {
+		t.Run(p.String(), func(t *testing.T) {
+			w := profileWorkbook(p)
+			if err := w.prepareStyles(); err != nil {
+				t.Fatalf("prepareStyles() failed: %v", err)
+			}
+			sst := w.newStringTable()
+			defer sst.close()
+
+			for _, s := range w.sheets {
+				st := &substream{buf: bufferAt{new(bytes.Buffer)}, sst: sst, sheet: s, ext: s.sheetExtents()}
+				for name, records := range stepRecords(t, w, worksheetRecords, st) {
+					for _, rec := range records {
+						if rec != name && !slices.Contains(others[name], rec) {
+							t.Errorf("Sheet %q: step %s wrote %s", s.name, name, rec)
+						}
+					}
+				}
+			}
+
+			names := new(bytes.Buffer)
+			if err := w.writeAutoFilterNames(names); err != nil {
+				t.Fatalf("writeAutoFilterNames() failed: %v", err)
+			}
+			st := &substream{buf: bufferAt{new(bytes.Buffer)}, sst: sst, names: names}
+			written := stepRecords(t, w, w.globalsSequence(), st)
+			for name, records := range written {
+				for _, rec := range records {
+					if rec != name && !slices.Contains(others[name], rec) {
+						t.Errorf("Globals: step %s wrote %s", name, rec)
+					}
+				}
+			}
+			if len(written["NAME"]) == 0 {
+				t.Error("Expected NAME records")
+			}
+		})
+	}
+}
+
+// TestBoundSheetOffsets adds a record to the end of the workbook globals,
+// which the worksheet offsets of the BOUNDSHEET records must account for.
+func TestBoundSheetOffsets(t *testing.T) {
+	saved := globalsTail[globalsNames]
+	t.Cleanup(func() { globalsTail[globalsNames] = saved })
+	globalsTail[globalsNames] = append(slices.Clone(saved), record("COUNTRY", func(w *Writer, writer io.Writer) error {
+		return w.writeRecord(writer, 0x008C, []byte{0x01, 0x00, 0x51, 0x00})
+	}))
+
+	for _, p := range []Profile{ProfileDefault, ProfileExcel97} {
+		t.Run(p.String(), func(t *testing.T) {
+			w := profileWorkbook(p)
+			stream, err := w.WorkbookStream()
+			if err != nil {
+				t.Fatalf("WorkbookStream() failed: %v", err)
+			}
+			records, err := readAllRecords(stream)
+			if err != nil {
+				t.Fatalf("Failed to read records: %v", err)
+			}
+			at := make(map[int]Record)
+			var offsets []int
+			for _, rec := range records {
+				at[rec.Offset] = rec
+				switch rec.Type {
+				case 0x008C:
+					if len(offsets) == 0 {
+						t.Error("Expected COUNTRY after the BOUNDSHEET records")
+					}
+				case recTypeBOUNDSHEET:
+					offsets = append(offsets, int(binary.LittleEndian.Uint32(rec.Data)))
+				}
+			}
+			if len(offsets) != 2 {
+				t.Fatalf("Expected 2 BOUNDSHEET records, got %d", len(offsets))
+			}
+			for i, offset := range offsets {
+				bof, ok := at[offset]
+				if !ok || bof.Type != recTypeBOF || binary.LittleEndian.Uint16(bof.Data[2:4]) != bofWorksheet {
+					t.Errorf("Sheet %d: expected a worksheet BOF at offset %d", i, offset)
+				}
+			}
+
+			var file bytes.Buffer
+			if _, err := w.WriteTo(&file); err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+			wb, err := openWorkbook(file.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to open the workbook: %v", err)
+			}
+			if got := wb.Sheets()[0].Cell(1, 0).FormattedValue(); got != "name 0" {
+				t.Errorf("Expected A2 %q, got %q", "name 0", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package xls
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServeXLS writes data as an XLS file download in response to r. It sets
+// Content-Type to application/vnd.ms-excel, Content-Length, and a
+// Content-Disposition naming the file filename, with an RFC 5987 encoded
+// form for names that are not plain ASCII. HEAD requests get the headers
+// only, and range requests are served as by http.ServeContent.
+//
+// The compound file header records the size of the workbook stream, so the
+// file is written before anything is sent: to a temporary file, as for
+// WriteChunks, and served from it, so large downloads are not held in
+// memory and their length is always known. With the options that need the
+// whole stream at once, it is built in memory as for WriteTo. An error
+// building the file is returned before any header is written, leaving the
+// response to the caller; the *WriteErrors of WithErrorCollection is
+// returned once the file is served.
+func ServeXLS(w http.ResponseWriter, r *http.Request, filename string, data [][]interface{}, opts ...Option) error {
+	writer := New(opts...)
+	defer writer.Close()
+	if err := writer.Write(data); err != nil {
+		return err
+	}
+
+	var content io.ReadSeeker
+	var err error
+	if writer.streamsFromFile() {
+		f, spillErr := writer.spillFile()
+		if spillErr != nil {
+			return spillErr
+		}
+		defer f.close()
+		if content, err = f.container(); err != nil {
+			return fmt.Errorf("failed to write CFB container: %w", err)
+		}
+		err = f.err
+	} else {
+		var file []byte
+		if file, err = writer.build(); file == nil {
+			return err
+		}
+		content = bytes.NewReader(file)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/vnd.ms-excel")
+	h.Set("Content-Disposition", contentDisposition(filename))
+	h.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, filename, time.Time{}, content)
+	return err
+}
+
+// container writes the XLS file after the Workbook stream in the temporary
+// file and returns a reader of it.
+func (f *spilledFile) container() (*io.SectionReader, error) {
+	base := int64(f.sheets + f.globals)
+	if err := f.writeTo(io.NewOffsetWriter(f.file, base)); err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(f.file, base, int64(f.size)), nil
+}
+
+// contentDisposition returns an attachment Content-Disposition for filename.
+// Names that are not printable ASCII get an ASCII fallback with the other
+// characters replaced by underscores, followed by the exact name encoded
+// as in RFC 5987.
+func contentDisposition(filename string) string {
+	var fallback strings.Builder
+	plain := true
+	for _, c := range filename {
+		switch {
+		case c == '"' || c == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(c)
+		case c >= 0x20 && c < 0x7F:
+			fallback.WriteRune(c)
+		default:
+			fallback.WriteByte('_')
+			plain = false
+		}
+	}
+	header := `attachment; filename="` + fallback.String() + `"`
+	if plain {
+		return header
+	}
+	return header + "; filename*=UTF-8''" + rfc5987Escape(filename)
+}
+
+// rfc5987Escape percent-encodes s, as UTF-8, except for the attr-char
+// characters of RFC 5987.
+func rfc5987Escape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0F])
+	}
+	return b.String()
+}
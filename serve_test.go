@@ -0,0 +1,130 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestServeXLS(t *testing.T) {
+	data := [][]interface{}{{"Name", "Qty"}, {"Apple", 3}}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	if err := ServeXLS(rec, req, "report.xls", data); err != nil {
+		t.Fatalf("ServeXLS() failed: %v", err)
+	}
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/vnd.ms-excel" {
+		t.Errorf("Expected the XLS content type, got %q", ct)
+	}
+	if cd := res.Header.Get("Content-Disposition"); cd != `attachment; filename="report.xls"` {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+	body := rec.Body.Bytes()
+	if cl := res.Header.Get("Content-Length"); cl != strconv.Itoa(len(body)) {
+		t.Errorf("Expected Content-Length %d, got %q", len(body), cl)
+	}
+	if err := Verify(body, "Sheet1", data); err != nil {
+		t.Errorf("Served file does not read back: %v", err)
+	}
+
+	// HEAD gets the same headers without the body
+	head := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodHead, "/report", nil)
+	if err := ServeXLS(head, req, "report.xls", data); err != nil {
+		t.Fatalf("ServeXLS() for HEAD failed: %v", err)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("Expected no body for HEAD, got %d bytes", head.Body.Len())
+	}
+	if cl := head.Result().Header.Get("Content-Length"); cl != strconv.Itoa(len(body)) {
+		t.Errorf("Expected Content-Length %d for HEAD, got %q", len(body), cl)
+	}
+
+	named := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/report", nil)
+	if err := ServeXLS(named, req, "売上.xls", data); err != nil {
+		t.Fatalf("ServeXLS() failed: %v", err)
+	}
+	want := `attachment; filename="__.xls"; filename*=UTF-8''%E5%A3%B2%E4%B8%8A.xls`
+	if cd := named.Result().Header.Get("Content-Disposition"); cd != want {
+		t.Errorf("Expected Content-Disposition %q, got %q", want, cd)
+	}
+}
+
+func TestServeXLSError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	err := ServeXLS(rec, req, "report.xls", [][]interface{}{{struct{}{}}}, WithStrictTypes())
+	if !errors.Is(err, ErrUnsupportedCellType) {
+		t.Errorf("Expected ErrUnsupportedCellType, got %v", err)
+	}
+	if len(rec.Header()) != 0 || rec.Body.Len() != 0 {
+		t.Errorf("Expected nothing written on error, got headers %v", rec.Header())
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"report.xls", `attachment; filename="report.xls"`},
+		{`say "hi".xls`, `attachment; filename="say \"hi\".xls"`},
+		{"売上 2026.xls", `attachment; filename="__ 2026.xls"; filename*=UTF-8''%E5%A3%B2%E4%B8%8A%202026.xls`},
+		{"Résumé.xls", `attachment; filename="R_sum_.xls"; filename*=UTF-8''R%C3%A9sum%C3%A9.xls`},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition(tt.filename); got != tt.want {
+			t.Errorf("contentDisposition(%q):\nexpected %s\ngot      %s", tt.filename, tt.want, got)
+		}
+	}
+}
+
+func TestServeXLSFromFile(t *testing.T) {
+	data := make([][]interface{}, 2000)
+	for i := range data {
+		data[i] = []interface{}{"item", i, float64(i) / 3}
+	}
+	dir := t.TempDir()
+	w := New(WithTempDir(dir))
+	defer w.Close()
+	w.Write(data)
+	want := writtenBytes(t, w)
+
+	// Served from the temporary file, which is removed afterwards; with
+	// verification, built in memory
+	for _, opts := range [][]Option{{WithTempDir(dir)}, {WithTempDir(dir), WithPostWriteVerification()}} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/report", nil)
+		if err := ServeXLS(rec, req, "report.xls", data, opts...); err != nil {
+			t.Fatalf("ServeXLS() failed: %v", err)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), want) {
+			t.Errorf("Expected the file WriteTo writes, got %d bytes of %d", rec.Body.Len(), len(want))
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("Expected the temporary files to be removed, got %d", len(entries))
+		}
+	}
+
+	// Ranges of the file
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set("Range", "bytes=1000-1999")
+	if err := ServeXLS(rec, req, "report.xls", data, WithTempDir(dir)); err != nil {
+		t.Fatalf("ServeXLS() failed: %v", err)
+	}
+	if rec.Code != http.StatusPartialContent || !bytes.Equal(rec.Body.Bytes(), want[1000:2000]) {
+		t.Errorf("Expected bytes 1000-1999 with status 206, got %d bytes with status %d", rec.Body.Len(), rec.Code)
+	}
+}
@@ -0,0 +1,646 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"strings"
+)
+
+// ErrInvalidSheetName is returned for a sheet name Excel does not accept:
+// empty, longer than 31 characters, containing any of []:*?/\ or already
+// used by another sheet of the workbook (compared case-insensitively).
+var ErrInvalidSheetName = errors.New("xls: invalid sheet name")
+
+// maxSheetNameLength is the longest sheet name Excel accepts.
+const maxSheetNameLength = 31
+
+// SheetWriter is one worksheet of a Writer. It holds the sheet's data,
+// styles and view, print and protection settings; everything shared by the
+// sheets, such as the strings and the style records, stays with the Writer.
+//
+// The Writer methods that set data and styles act on its first sheet.
+type SheetWriter struct {
+	w         *Writer
+	name      string
+	data      [][]interface{}
+	dataOwned bool        // data is a copy SetCell may modify in place
+	cols      *columnData // Set with WriteColumns instead of data
+
+	// Values WithSchema could not convert in the last Write
+	schemaWarnings []Warning
+	// Rendering of the bools of the columns whose ColumnSpec sets one, from
+	// the last Write
+	boolColumns map[int]BoolRendering
+
+	cellStyles map[[2]int]Style
+	ranges     rangeStyles // Set with SetRangeStyle
+	colStyles  map[int]Style
+	rowStyles  map[int]Style
+	rowLevels  map[int]int // Outline levels, set by WithGroupSubtotals
+	colWidths  map[int]float64
+	autoFilter *cellRange
+	raw        []rawRecord
+
+	freezeRows, freezeCols int
+	zoom                   int // Percent, 0 for the default of 100
+	protected              bool
+	password               string
+	verifier               uint16 // PASSWORD hash kept from a template
+	print                  *PrintSetup
+}
+
+// SheetOption is a functional option for configuring a sheet added with
+// AddSheet. Each option has an equivalent SheetWriter method.
+type SheetOption func(*SheetWriter)
+
+// PrintSetup configures how a sheet prints.
+type PrintSetup struct {
+	Landscape bool
+	// PaperSize is the Excel paper size code, such as 1 for Letter and 9
+	// for A4. Zero keeps Letter.
+	PaperSize int
+	// Scale is the print scale in percent, from 10 to 400. Zero keeps 100.
+	Scale int
+	// FitToWidth and FitToHeight fit the sheet on that many pages across and
+	// down instead of scaling it. Zero in both turns fitting off; zero in one
+	// leaves that direction unconstrained.
+	FitToWidth, FitToHeight int
+	Gridlines               bool // Prints the cell gridlines
+}
+
+func newSheet(w *Writer, name string) *SheetWriter {
+	return &SheetWriter{w: w, name: name}
+}
+
+// AddSheet appends a sheet named name to the workbook and returns it. The
+// Writer starts with one sheet, named by WithSheetName, so the first
+// AddSheet creates the second sheet.
+func (w *Writer) AddSheet(name string, opts ...SheetOption) (*SheetWriter, error) {
+	if err := w.checkSheetName(name, nil); err != nil {
+		return nil, err
+	}
+	s := newSheet(w, name)
+	for _, opt := range opts {
+		opt(s)
+	}
+	w.sheets = append(w.sheets, s)
+	return s, nil
+}
+
+// CopySheet appends a sheet named dstName holding a copy of the sheet named
+// srcName: its data, styles, AutoFilter, column widths, view, print and
+// protection settings and raw records. The copy is deep, so later changes
+// to either sheet, including to the data rows, do not affect the other.
+// Formulas are copied verbatim; their references are relative to whichever
+// sheet they are on.
+func (w *Writer) CopySheet(srcName, dstName string) error {
+	src, err := w.Sheet(srcName)
+	if err != nil {
+		return err
+	}
+	if err := w.checkSheetName(dstName, nil); err != nil {
+		return err
+	}
+	w.sheets = append(w.sheets, src.clone(dstName))
+	return nil
+}
+
+// clone returns a deep copy of the sheet named name.
+func (s *SheetWriter) clone(name string) *SheetWriter {
+	c := *s
+	c.name, c.dataOwned = name, true
+	if s.data != nil {
+		c.data = make([][]interface{}, len(s.data))
+		for i, row := range s.data {
+			if row != nil {
+				c.data[i] = append(make([]interface{}, 0, len(row)), row...)
+			}
+		}
+	}
+	c.cellStyles = maps.Clone(s.cellStyles)
+	c.ranges = s.ranges.clone()
+	c.colStyles = maps.Clone(s.colStyles)
+	c.rowStyles = maps.Clone(s.rowStyles)
+	c.rowLevels = maps.Clone(s.rowLevels)
+	c.colWidths = maps.Clone(s.colWidths)
+	if s.autoFilter != nil {
+		r := *s.autoFilter
+		c.autoFilter = &r
+	}
+	if s.print != nil {
+		p := *s.print
+		c.print = &p
+	}
+	c.raw = nil
+	for _, rec := range s.raw {
+		c.raw = append(c.raw, rawRecord{recType: rec.recType, data: append([]byte(nil), rec.data...)})
+	}
+	return &c
+}
+
+// Sheet returns the sheet named name.
+func (w *Writer) Sheet(name string) (*SheetWriter, error) {
+	for _, s := range w.sheets {
+		if s.name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrSheetNotFound, name)
+}
+
+// checkSheetName validates name for a sheet of w other than self. See
+// ErrInvalidSheetName and ErrReservedSheetName.
+func (w *Writer) checkSheetName(name string, self *SheetWriter) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidSheetName)
+	}
+	if n := len([]rune(name)); n > maxSheetNameLength {
+		return fmt.Errorf("%w: %q is %d characters, more than %d", ErrInvalidSheetName, name, n, maxSheetNameLength)
+	}
+	if i := strings.IndexAny(name, `[]:*?/\`); i >= 0 {
+		return fmt.Errorf("%w: %q contains %q", ErrInvalidSheetName, name, name[i])
+	}
+	if err := w.checkRiskySheetName(name); err != nil {
+		return err
+	}
+	for _, s := range w.sheets {
+		if s != self && strings.EqualFold(s.name, name) {
+			return fmt.Errorf("%w: %q is already used", ErrInvalidSheetName, name)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the sheet.
+func (s *SheetWriter) Name() string {
+	return s.name
+}
+
+// Write sets the data of the sheet. With WithSchema, the values of the
+// declared columns are converted first, with WithDateStringDetection the
+// date strings of the other columns next, with WithCellConverter every cell
+// then goes through the converter, and with WithFormulaEscaping text that
+// could be read as a formula is escaped last. With WithMetadataColumn, the
+// hidden column is appended to the result, with WithGroupSubtotals, the
+// subtotal rows are inserted into it, and with WithSummaryRow, the summary
+// row is appended to it.
+func (s *SheetWriter) Write(data [][]interface{}) error {
+	owned := false
+	if s.w.norm != nil {
+		data, owned = s.normalizeStrings(data)
+	}
+	data, owned = plainValues(data, owned)
+	var warnings []Warning
+	if s.w.schema != nil {
+		var err error
+		if data, owned, warnings, err = s.applySchema(data); err != nil {
+			return err
+		}
+	}
+	if s.w.dateLayouts != nil {
+		data, owned = s.detectDates(data, owned)
+	}
+	if s.w.converter != nil {
+		var err error
+		if data, owned, err = s.convertCells(data, owned); err != nil {
+			return err
+		}
+	}
+	if s.w.escapePrefix != "" {
+		data, owned = s.escapeFormulas(data, owned)
+	}
+	if s.w.dict != nil {
+		if err := s.checkDeclared(data, 0); err != nil {
+			return err
+		}
+	}
+	s.data, s.dataOwned, s.schemaWarnings = data, owned, warnings
+	s.cols = nil
+	s.rowLevels = nil
+	if s.w.metadata != nil {
+		if err := s.addMetadataColumn(s.w.metadata); err != nil {
+			return err
+		}
+	}
+	if s.w.subtotals != nil {
+		if err := s.addSubtotals(s.w.subtotals); err != nil {
+			return err
+		}
+	}
+	if s.w.summary != nil {
+		return s.AddSummaryRow(s.w.summary)
+	}
+	return nil
+}
+
+// SetCell sets the value of a cell of the first sheet. See
+// SheetWriter.SetCell.
+func (w *Writer) SetCell(row, col int, value interface{}) error {
+	return w.sheets[0].SetCell(row, col, value)
+}
+
+// SetCellRef sets the value of a cell of the first sheet by its A1-style
+// reference. See SheetWriter.SetCellRef.
+func (w *Writer) SetCellRef(ref string, value interface{}) error {
+	return w.sheets[0].SetCellRef(ref, value)
+}
+
+// SetCell sets the value of the cell at the zero-based row and column,
+// extending the data as needed. The cell keeps any style set with
+// SetCellStyle, so a cell of a template keeps its formatting. The slices
+// passed to Write are not modified.
+func (s *SheetWriter) SetCell(row, col int, value interface{}) error {
+	if _, _, err := cellIndex(row, col); err != nil {
+		return err
+	}
+	s.materialize()
+	if s.w.norm != nil {
+		value, _ = s.w.normalizeCell(value)
+	}
+	value, _ = plainValue(value)
+	if s.w.dateLayouts != nil && s.detectsDates(row, col) {
+		value, _ = s.w.detectDate(value)
+	}
+	if s.w.converter != nil {
+		var err error
+		if value, _, err = s.w.convertCell(row, col, value); err != nil {
+			return err
+		}
+	}
+	if s.w.escapePrefix != "" {
+		value, _ = s.w.escapeFormula(value)
+	}
+	if s.w.dict != nil {
+		if str, ok := s.textValue(row, col, value); ok {
+			if err := s.w.dict.check(str, row, col); err != nil {
+				return err
+			}
+			if err := s.w.dict.checkRich(value, row, col); err != nil {
+				return err
+			}
+		}
+	}
+	if !s.dataOwned {
+		s.data, s.dataOwned = append([][]interface{}(nil), s.data...), true
+	}
+	for len(s.data) <= row {
+		s.data = append(s.data, nil)
+	}
+	cells := make([]interface{}, max(len(s.data[row]), col+1))
+	copy(cells, s.data[row])
+	cells[col] = value
+	s.data[row] = cells
+	return nil
+}
+
+// SetCellRef sets the value of the cell at an A1-style reference such as
+// "B3" or "$B$3". See SetCell.
+func (s *SheetWriter) SetCellRef(ref string, value interface{}) error {
+	r, ok := parseCellRef(ref)
+	if !ok {
+		return fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return s.SetCell(int(r.row), int(r.col), value)
+}
+
+// WithFreezePanes freezes the first rows and columns of the sheet.
+func WithFreezePanes(rows, cols int) SheetOption {
+	return func(s *SheetWriter) {
+		s.FreezePanes(rows, cols)
+	}
+}
+
+// FreezePanes keeps the first rows and columns of the sheet in view while
+// scrolling, typically FreezePanes(1, 0) for a header row. Zero for both
+// unfreezes the sheet.
+func (s *SheetWriter) FreezePanes(rows, cols int) {
+	s.freezeRows, s.freezeCols = rows, cols
+}
+
+// WithColWidth sets the width of a column of the sheet.
+func WithColWidth(col int, width float64) SheetOption {
+	return func(s *SheetWriter) {
+		s.SetColWidth(col, width)
+	}
+}
+
+// SetColWidth sets the width of a zero-based column in characters of the
+// default font, from 0 (hidden) to 255.
+func (s *SheetWriter) SetColWidth(col int, width float64) {
+	if s.colWidths == nil {
+		s.colWidths = make(map[int]float64)
+	}
+	s.colWidths[col] = width
+}
+
+// WithZoom sets the zoom of the sheet.
+func WithZoom(percent int) SheetOption {
+	return func(s *SheetWriter) {
+		s.SetZoom(percent)
+	}
+}
+
+// SetZoom sets the magnification the sheet opens with, from 10 to 400
+// percent. Zero keeps the default of 100.
+func (s *SheetWriter) SetZoom(percent int) {
+	s.zoom = percent
+}
+
+// WithProtection protects the sheet.
+func WithProtection(password string) SheetOption {
+	return func(s *SheetWriter) {
+		s.Protect(password)
+	}
+}
+
+// Protect protects the cells, objects and scenarios of the sheet against
+// changes, with a password of up to 15 characters or none if it is empty.
+// The password only stops users of spreadsheet applications; it does not
+// encrypt anything.
+func (s *SheetWriter) Protect(password string) {
+	s.protected, s.password, s.verifier = true, password, 0
+}
+
+// WithPrintSetup sets the print setup of the sheet.
+func WithPrintSetup(p PrintSetup) SheetOption {
+	return func(s *SheetWriter) {
+		s.SetPrintSetup(p)
+	}
+}
+
+// SetPrintSetup sets how the sheet prints.
+func (s *SheetWriter) SetPrintSetup(p PrintSetup) {
+	s.print = &p
+}
+
+// checkSettings validates the view, print and protection settings.
+func (s *SheetWriter) checkSettings() error {
+	if s.freezeRows < 0 || s.freezeRows > maxRow || s.freezeCols < 0 || s.freezeCols > maxColumn {
+		return fmt.Errorf("sheet %q: %w: frozen panes %d rows, %d columns", s.name, ErrOutOfRange, s.freezeRows, s.freezeCols)
+	}
+	for col, width := range s.colWidths {
+		if col < 0 || col > maxColumn {
+			return fmt.Errorf("sheet %q: %w: column width index %d", s.name, ErrOutOfRange, col)
+		}
+		if width < 0 || width > 255 || math.IsNaN(width) {
+			return fmt.Errorf("sheet %q: %w: column %d width %g", s.name, ErrOutOfRange, col, width)
+		}
+	}
+	if s.zoom != 0 && (s.zoom < 10 || s.zoom > 400) {
+		return fmt.Errorf("sheet %q: %w: zoom %d%%", s.name, ErrOutOfRange, s.zoom)
+	}
+	if n := len([]rune(s.password)); n > 15 {
+		return fmt.Errorf("sheet %q: %w: password is %d characters, more than 15", s.name, ErrOutOfRange, n)
+	}
+	if p := s.print; p != nil {
+		if p.PaperSize < 0 || p.PaperSize > 0xFFFF {
+			return fmt.Errorf("sheet %q: %w: paper size %d", s.name, ErrOutOfRange, p.PaperSize)
+		}
+		if p.Scale != 0 && (p.Scale < 10 || p.Scale > 400) {
+			return fmt.Errorf("sheet %q: %w: print scale %d%%", s.name, ErrOutOfRange, p.Scale)
+		}
+		if p.FitToWidth < 0 || p.FitToWidth > 0x7FFF || p.FitToHeight < 0 || p.FitToHeight > 0x7FFF {
+			return fmt.Errorf("sheet %q: %w: fit to %dx%d pages", s.name, ErrOutOfRange, p.FitToWidth, p.FitToHeight)
+		}
+	}
+	return nil
+}
+
+// fitToPage reports whether the sheet prints fitted to a number of pages.
+func (s *SheetWriter) fitToPage() bool {
+	return s.print != nil && (s.print.FitToWidth > 0 || s.print.FitToHeight > 0)
+}
+
+func (w *Writer) writePrintGridlines(writer io.Writer, s *SheetWriter) error {
+	data := make([]byte, 2)
+	if s.print != nil && s.print.Gridlines {
+		binary.LittleEndian.PutUint16(data[0:2], 1)
+	}
+	return w.writeRecord(writer, recTypePRINTGRIDLINES, data)
+}
+
+func (w *Writer) writeWSBool(writer io.Writer, s *SheetWriter) error {
+	grbit := uint16(0x04C1)
+	if s.fitToPage() {
+		grbit |= 0x0100 // fFitToPage
+	}
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data[0:2], grbit)
+	return w.writeRecord(writer, recTypeWSBOOL, data)
+}
+
+func (w *Writer) writeSetup(writer io.Writer, s *SheetWriter) error {
+	data := make([]byte, 34)
+	p := s.print
+	if p == nil {
+		binary.LittleEndian.PutUint16(data[0:2], 1)
+		binary.LittleEndian.PutUint16(data[2:4], 100)
+		binary.LittleEndian.PutUint16(data[4:6], 1)
+		binary.LittleEndian.PutUint16(data[6:8], 1)
+		binary.LittleEndian.PutUint16(data[8:10], 1)
+		binary.LittleEndian.PutUint16(data[10:12], 0x0000)
+		binary.LittleEndian.PutUint16(data[12:14], 600)
+		binary.LittleEndian.PutUint16(data[14:16], 600)
+		binary.LittleEndian.PutUint16(data[16:18], 1)
+		return w.writeRecord(writer, recTypeSETUP, data)
+	}
+
+	paper, scale := p.PaperSize, p.Scale
+	if paper == 0 {
+		paper = 1 // Letter
+	}
+	if scale == 0 {
+		scale = 100
+	}
+	grbit := uint16(0)
+	if !p.Landscape {
+		grbit |= 0x0002 // fPortrait
+	}
+	binary.LittleEndian.PutUint16(data[0:2], uint16(paper))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(scale))
+	binary.LittleEndian.PutUint16(data[4:6], 1) // First page number
+	binary.LittleEndian.PutUint16(data[6:8], uint16(p.FitToWidth))
+	binary.LittleEndian.PutUint16(data[8:10], uint16(p.FitToHeight))
+	binary.LittleEndian.PutUint16(data[10:12], grbit)
+	binary.LittleEndian.PutUint16(data[12:14], 600)                   // Horizontal DPI
+	binary.LittleEndian.PutUint16(data[14:16], 600)                   // Vertical DPI
+	binary.LittleEndian.PutUint64(data[16:24], math.Float64bits(0.5)) // Header margin in inches
+	binary.LittleEndian.PutUint64(data[24:32], math.Float64bits(0.5)) // Footer margin in inches
+	binary.LittleEndian.PutUint16(data[32:34], 1)                     // Copies
+	return w.writeRecord(writer, recTypeSETUP, data)
+}
+
+// writeSheetProtection writes the protection block of the sheet. An
+// unprotected sheet only restates the default, which minimal mode omits.
+func (w *Writer) writeSheetProtection(writer io.Writer, s *SheetWriter) error {
+	if !s.protected {
+		for _, write := range []func(io.Writer) error{w.writeProtect, w.writeScenProtect, w.writeObjProtect, w.writePassword} {
+			if err := w.optional(writer, write); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	on := []byte{0x01, 0x00}
+	for _, recType := range []uint16{recTypePROTECT, recTypeSCENPROTECT, recTypeOBJPROTECT} {
+		if err := w.writeRecord(writer, recType, on); err != nil {
+			return err
+		}
+	}
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data[0:2], s.passwordVerifier())
+	return w.writeRecord(writer, recTypePASSWORD, data)
+}
+
+// passwordVerifier returns the PASSWORD hash of a protected sheet.
+func (s *SheetWriter) passwordVerifier() uint16 {
+	if s.verifier != 0 {
+		return s.verifier
+	}
+	return passwordHash(s.password)
+}
+
+// passwordHash returns the 16-bit verifier of a sheet protection password,
+// or 0 for no password.
+func passwordHash(password string) uint16 {
+	if password == "" {
+		return 0
+	}
+	var hash uint16
+	for i, c := range []byte(password) {
+		v := uint32(c) << (i + 1)
+		hash ^= uint16(v&0x7FFF | v>>15&0x7FFF)
+	}
+	return hash ^ uint16(len(password)) ^ 0xCE4B
+}
+
+// writeColInfo writes COLINFO records for the columns with a style or a
+// width. Adjacent columns with the same XF and width share a record.
+func (w *Writer) writeColInfo(writer io.Writer, s *SheetWriter) error {
+	for _, c := range s.colInfos() {
+		data := make([]byte, 12)
+		binary.LittleEndian.PutUint16(data[0:2], uint16(c.first))
+		binary.LittleEndian.PutUint16(data[2:4], uint16(c.last))
+		binary.LittleEndian.PutUint16(data[4:6], c.width)
+		binary.LittleEndian.PutUint16(data[6:8], c.xf)
+		if c.width == 0 {
+			binary.LittleEndian.PutUint16(data[8:10], 0x0001) // fHidden
+		}
+		if err := w.writeRecord(writer, recTypeCOLINFO, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// colInfo is a run of adjacent columns sharing a COLINFO record.
+type colInfo struct {
+	first, last int
+	width       uint16 // In 1/256 of a character
+	xf          uint16
+}
+
+// colInfos returns the COLINFO runs of the sheet. Styles must have been
+// prepared.
+func (s *SheetWriter) colInfos() []colInfo {
+	var infos []colInfo
+	for col := 0; col <= maxColumn; col++ {
+		st, styled := s.colStyles[col]
+		width, sized := s.colWidths[col]
+		if !styled && !sized {
+			continue
+		}
+
+		c := colInfo{first: col, last: col, width: s.w.colWidth()}
+		if styled {
+			c.xf = s.w.styles.xf(st)
+		}
+		if sized {
+			c.width = uint16(math.Round(width * 256))
+		}
+		if n := len(infos); n > 0 && infos[n-1].last == col-1 && infos[n-1].width == c.width && infos[n-1].xf == c.xf {
+			infos[n-1].last = col
+			continue
+		}
+		infos = append(infos, c)
+	}
+	return infos
+}
+
+func (w *Writer) writeWindow2(writer io.Writer, s *SheetWriter) error {
+	grbit := uint16(0x00B6)
+	if s == w.sheets[0] {
+		grbit |= 0x0600 // fSelected and fPaged: the sheet is the active one
+	}
+	if s.freezeRows > 0 || s.freezeCols > 0 {
+		grbit |= 0x0008 | 0x0100 // fFrozen, fFrozenNoSplit
+	}
+
+	data := make([]byte, 18)
+	binary.LittleEndian.PutUint16(data[0:2], grbit)
+	binary.LittleEndian.PutUint16(data[2:4], 0)
+	binary.LittleEndian.PutUint16(data[4:6], 0)
+	binary.LittleEndian.PutUint16(data[6:8], 0x0040)
+	binary.LittleEndian.PutUint16(data[8:10], 0)
+	binary.LittleEndian.PutUint16(data[10:12], 0)
+	binary.LittleEndian.PutUint16(data[12:14], uint16(s.zoom)) // Zoom in percent, 0 for 100
+	binary.LittleEndian.PutUint16(data[14:16], 0)
+	binary.LittleEndian.PutUint16(data[16:18], 0)
+	return w.writeRecord(writer, recTypeWINDOW2, data)
+}
+
+// writeSCL writes the zoom of a sheet with a zoom set.
+func (w *Writer) writeSCL(writer io.Writer, s *SheetWriter) error {
+	if s.zoom == 0 {
+		return nil
+	}
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(s.zoom))
+	binary.LittleEndian.PutUint16(data[2:4], 100)
+	return w.writeRecord(writer, recTypeSCL, data)
+}
+
+// writePane writes the PANE record of a sheet with frozen panes and a
+// SELECTION record placing the cursor in its scrolling pane.
+func (w *Writer) writePane(writer io.Writer, s *SheetWriter) error {
+	if s.freezeRows == 0 && s.freezeCols == 0 {
+		return nil
+	}
+
+	var pane byte
+	switch {
+	case s.freezeRows > 0 && s.freezeCols > 0:
+		pane = 0 // Bottom right
+	case s.freezeRows > 0:
+		pane = 2 // Bottom left
+	default:
+		pane = 1 // Top right
+	}
+
+	data := make([]byte, 10)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(s.freezeCols)) // Columns left of the split
+	binary.LittleEndian.PutUint16(data[2:4], uint16(s.freezeRows)) // Rows above the split
+	binary.LittleEndian.PutUint16(data[4:6], uint16(s.freezeRows)) // First visible row of the bottom pane
+	binary.LittleEndian.PutUint16(data[6:8], uint16(s.freezeCols)) // First visible column of the right pane
+	data[8] = pane                                                 // Active pane
+	if err := w.writeRecord(writer, recTypePANE, data); err != nil {
+		return err
+	}
+
+	row, col := uint16(s.freezeRows), byte(s.freezeCols)
+	sel := make([]byte, 15)
+	sel[0] = pane
+	binary.LittleEndian.PutUint16(sel[1:3], row)         // Active cell row
+	binary.LittleEndian.PutUint16(sel[3:5], uint16(col)) // Active cell column
+	binary.LittleEndian.PutUint16(sel[5:7], 0)           // Index of the active cell's range
+	binary.LittleEndian.PutUint16(sel[7:9], 1)           // One selected range
+	binary.LittleEndian.PutUint16(sel[9:11], row)
+	binary.LittleEndian.PutUint16(sel[11:13], row)
+	sel[13], sel[14] = col, col
+	return w.writeRecord(writer, recTypeSELECTION, sel)
+}
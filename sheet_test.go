@@ -0,0 +1,460 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// substreams splits written records into the globals and one slice per
+// worksheet, each from its BOF to its EOF.
+func substreams(records []Record) [][]Record {
+	var out [][]Record
+	start := 0
+	for i, rec := range records {
+		if rec.Type == recTypeEOF {
+			out = append(out, records[start:i+1])
+			start = i + 1
+		}
+	}
+	return out
+}
+
+func TestAddSheet(t *testing.T) {
+	w := New(WithSheetName("Report"))
+	defer w.Close()
+	report := [][]interface{}{
+		{"Name", "Amount"},
+		{"Alice", 12.5},
+		{"Bob", 7},
+	}
+	plain := [][]interface{}{{"Note"}, {"Bob"}}
+	w.Write(report)
+	w.SetRowStyle(0, Style{Font: Font{Bold: true}})
+	w.SetColStyle(1, Style{NumberFormat: "#,##0.00"})
+	first, err := w.Sheet("Report")
+	if err != nil {
+		t.Fatalf("Sheet() failed: %v", err)
+	}
+	first.FreezePanes(1, 0)
+
+	second, err := w.AddSheet("Plain")
+	if err != nil {
+		t.Fatalf("AddSheet() failed: %v", err)
+	}
+	second.Write(plain)
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Report", report); err != nil {
+		t.Errorf("First sheet does not read back: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Plain", plain); err != nil {
+		t.Errorf("Second sheet does not read back: %v", err)
+	}
+
+	streams := substreams(writtenRecords(t, w))
+	if len(streams) != 3 {
+		t.Fatalf("Expected the globals and 2 worksheets, got %d substreams", len(streams))
+	}
+	if n := len(recordsOfType(streams[0], recTypeBOUNDSHEET)); n != 2 {
+		t.Errorf("Expected 2 BOUNDSHEET records, got %d", n)
+	}
+	sst := recordsOfType(streams[0], recTypeSST)[0].Data
+	if unique := binary.LittleEndian.Uint32(sst[4:8]); unique != 5 {
+		t.Errorf("Expected the sheets to share 5 strings, got %d", unique)
+	}
+
+	window := binary.LittleEndian.Uint16(recordsOfType(streams[1], recTypeWINDOW2)[0].Data)
+	if window&0x0608 != 0x0608 {
+		t.Errorf("Expected the first sheet selected and frozen, got WINDOW2 flags 0x%04X", window)
+	}
+	if len(recordsOfType(streams[1], recTypePANE)) != 1 || len(recordsOfType(streams[1], recTypeCOLINFO)) != 1 {
+		t.Errorf("Expected a PANE and a COLINFO record in the first sheet")
+	}
+
+	window = binary.LittleEndian.Uint16(recordsOfType(streams[2], recTypeWINDOW2)[0].Data)
+	if window != 0x00B6 {
+		t.Errorf("Expected a plain, unselected second sheet, got WINDOW2 flags 0x%04X", window)
+	}
+	for _, recType := range []uint16{recTypePANE, recTypeSCL, recTypeCOLINFO} {
+		if n := len(recordsOfType(streams[2], recType)); n != 0 {
+			t.Errorf("Expected no %s record in the second sheet, got %d", RecordName(recType), n)
+		}
+	}
+	for _, rec := range recordsOfType(streams[2], recTypeROW) {
+		if flags := binary.LittleEndian.Uint32(rec.Data[12:16]); flags&0x80 != 0 {
+			t.Errorf("Expected the row style to stay on the first sheet, got ROW flags 0x%08X", flags)
+		}
+	}
+}
+
+func TestAddSheetInvalidName(t *testing.T) {
+	w := New()
+	defer w.Close()
+	for _, name := range []string{"", "sheet1", "a/b", "[x]", "What?", "0123456789012345678901234567890123"} {
+		if _, err := w.AddSheet(name); !errors.Is(err, ErrInvalidSheetName) {
+			t.Errorf("AddSheet(%q): expected ErrInvalidSheetName, got %v", name, err)
+		}
+	}
+	if _, err := w.AddSheet("日本語のシート"); err != nil {
+		t.Errorf("AddSheet() failed for a valid name: %v", err)
+	}
+
+	w.SetSheetName("日本語のシート")
+	if _, err := w.WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrInvalidSheetName) {
+		t.Errorf("Expected ErrInvalidSheetName for a renamed duplicate, got %v", err)
+	}
+}
+
+// TestSheetWriterAliases checks that the Writer methods only act on the
+// first sheet: the same calls on a SheetWriter, including one added with
+// AddSheet, write the same worksheet substream.
+func TestSheetWriterAliases(t *testing.T) {
+	data := [][]interface{}{{"Name", "Amount"}, {"Alice", 12.5}, {"Bob", 7}}
+	style := Style{Font: Font{Bold: true}}
+
+	viaWriter := New(WithSheetName("Data"))
+	viaWriter.Write(data)
+	viaWriter.SetRowStyle(0, style)
+	viaWriter.SetCellStyle(2, 1, style)
+	viaWriter.FormatAsTable(0, 2, 0, 1, TableStyle{AutoFilter: true})
+
+	apply := func(s *SheetWriter) {
+		s.Write(data)
+		s.SetRowStyle(0, style)
+		s.SetCellStyle(2, 1, style)
+		s.FormatAsTable(0, 2, 0, 1, TableStyle{AutoFilter: true})
+	}
+	viaHandle := New(WithSheetName("Data"))
+	first, err := viaHandle.Sheet("Data")
+	if err != nil {
+		t.Fatalf("Sheet() failed: %v", err)
+	}
+	apply(first)
+
+	want, err := viaWriter.WorkbookStream()
+	if err != nil {
+		t.Fatalf("WorkbookStream() failed: %v", err)
+	}
+	got, err := viaHandle.WorkbookStream()
+	if err != nil {
+		t.Fatalf("WorkbookStream() failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected the same stream through the SheetWriter of the first sheet")
+	}
+
+	// The same sheet second, after an empty one
+	added := New(WithSheetName("Empty"))
+	second, err := added.AddSheet("Data")
+	if err != nil {
+		t.Fatalf("AddSheet() failed: %v", err)
+	}
+	apply(second)
+	wantSheet := substreams(writtenRecords(t, viaWriter))[1]
+	gotSheet := substreams(writtenRecords(t, added))[2]
+	if len(gotSheet) != len(wantSheet) {
+		t.Fatalf("Expected %d worksheet records, got %d", len(wantSheet), len(gotSheet))
+	}
+	for i := range wantSheet {
+		if gotSheet[i].Type != wantSheet[i].Type || !bytes.Equal(gotSheet[i].Data, wantSheet[i].Data) {
+			// Only the selection differs: the added sheet is not the active one
+			if wantSheet[i].Type == recTypeWINDOW2 {
+				continue
+			}
+			t.Errorf("Record %d: expected %s, got %s", i, describeRecord(&wantSheet[i]), describeRecord(&gotSheet[i]))
+		}
+	}
+}
+
+func TestFreezePanes(t *testing.T) {
+	tests := []struct {
+		rows, cols int
+		pane       byte
+	}{
+		{1, 0, 2},
+		{0, 2, 1},
+		{3, 1, 0},
+	}
+	for _, tt := range tests {
+		w := New()
+		s, _ := w.AddSheet("Data", WithFreezePanes(tt.rows, tt.cols))
+		s.Write([][]interface{}{{"a", "b", "c"}, {1, 2, 3}})
+
+		sheet := substreams(writtenRecords(t, w))[2]
+		panes := recordsOfType(sheet, recTypePANE)
+		if len(panes) != 1 {
+			t.Fatalf("Freeze %dx%d: expected 1 PANE record, got %d", tt.rows, tt.cols, len(panes))
+		}
+		d := panes[0].Data
+		x, y := binary.LittleEndian.Uint16(d[0:2]), binary.LittleEndian.Uint16(d[2:4])
+		if int(x) != tt.cols || int(y) != tt.rows || d[8] != tt.pane {
+			t.Errorf("Freeze %dx%d: expected split at %d,%d in pane %d, got %d,%d in pane %d", tt.rows, tt.cols, tt.cols, tt.rows, tt.pane, x, y, d[8])
+		}
+		sel := recordsOfType(sheet, recTypeSELECTION)
+		if len(sel) != 1 || sel[0].Data[0] != tt.pane {
+			t.Errorf("Freeze %dx%d: expected a SELECTION in pane %d", tt.rows, tt.cols, tt.pane)
+		}
+		w.Close()
+	}
+}
+
+func TestSetColWidth(t *testing.T) {
+	w := New()
+	defer w.Close()
+	s, _ := w.AddSheet("Data", WithColWidth(0, 20), WithColWidth(1, 20))
+	s.SetColWidth(3, 0)
+	s.SetColWidth(4, 12.5)
+	s.SetColStyle(4, Style{Font: Font{Bold: true}})
+	s.SetColStyle(5, Style{Font: Font{Bold: true}})
+
+	infos := recordsOfType(substreams(writtenRecords(t, w))[2], recTypeCOLINFO)
+	want := [][5]uint16{
+		{0, 1, 20 * 256, 0, 0},
+		{3, 3, 0, 0, 1},
+		{4, 4, 12.5 * 256, firstCustomXF, 0},
+		{5, 5, defaultColWidth, firstCustomXF, 0},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("Expected %d COLINFO records, got %d", len(want), len(infos))
+	}
+	for i, rec := range infos {
+		var got [5]uint16
+		for j := range got {
+			got[j] = binary.LittleEndian.Uint16(rec.Data[j*2:])
+		}
+		if got != want[i] {
+			t.Errorf("COLINFO %d: expected first, last, width, xf, flags %v, got %v", i, want[i], got)
+		}
+	}
+}
+
+func TestSetZoom(t *testing.T) {
+	w := New()
+	defer w.Close()
+	s, _ := w.AddSheet("Data", WithZoom(150))
+	s.Write([][]interface{}{{1}})
+
+	sheet := substreams(writtenRecords(t, w))[2]
+	scl := recordsOfType(sheet, recTypeSCL)
+	if len(scl) != 1 || binary.LittleEndian.Uint16(scl[0].Data[0:2]) != 150 || binary.LittleEndian.Uint16(scl[0].Data[2:4]) != 100 {
+		t.Errorf("Expected an SCL record of 150/100, got %v", scl)
+	}
+	if zoom := binary.LittleEndian.Uint16(recordsOfType(sheet, recTypeWINDOW2)[0].Data[12:14]); zoom != 150 {
+		t.Errorf("Expected WINDOW2 zoom 150, got %d", zoom)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	if got := passwordHash("secret"); got != 0xDAA7 {
+		t.Errorf("Expected hash 0xDAA7, got 0x%04X", got)
+	}
+
+	for _, opts := range [][]Option{nil, {WithMinimalRecords()}} {
+		w := New(opts...)
+		w.AddSheet("Locked", WithProtection("secret"))
+		streams := substreams(writtenRecords(t, w))
+		for _, recType := range []uint16{recTypePROTECT, recTypeSCENPROTECT, recTypeOBJPROTECT} {
+			recs := recordsOfType(streams[2], recType)
+			if len(recs) != 1 || binary.LittleEndian.Uint16(recs[0].Data) != 1 {
+				t.Errorf("Expected %s set in the protected sheet, got %v", RecordName(recType), recs)
+			}
+		}
+		if pw := recordsOfType(streams[2], recTypePASSWORD); len(pw) != 1 || binary.LittleEndian.Uint16(pw[0].Data) != 0xDAA7 {
+			t.Errorf("Expected the password hash, got %v", pw)
+		}
+		for _, rec := range recordsOfType(streams[1], recTypePROTECT) {
+			if binary.LittleEndian.Uint16(rec.Data) != 0 {
+				t.Error("Expected the first sheet to stay unprotected")
+			}
+		}
+		w.Close()
+	}
+}
+
+func TestSetPrintSetup(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.AddSheet("Print", WithPrintSetup(PrintSetup{Landscape: true, PaperSize: 9, FitToWidth: 1, Gridlines: true}))
+
+	streams := substreams(writtenRecords(t, w))
+	setup := recordsOfType(streams[2], recTypeSETUP)[0].Data
+	if paper := binary.LittleEndian.Uint16(setup[0:2]); paper != 9 {
+		t.Errorf("Expected paper size 9, got %d", paper)
+	}
+	if scale := binary.LittleEndian.Uint16(setup[2:4]); scale != 100 {
+		t.Errorf("Expected scale 100, got %d", scale)
+	}
+	if fit := [2]uint16{binary.LittleEndian.Uint16(setup[6:8]), binary.LittleEndian.Uint16(setup[8:10])}; fit != [2]uint16{1, 0} {
+		t.Errorf("Expected fit to 1 page wide, got %v", fit)
+	}
+	if grbit := binary.LittleEndian.Uint16(setup[10:12]); grbit&0x0002 != 0 {
+		t.Errorf("Expected landscape, got flags 0x%04X", grbit)
+	}
+	if margin := math.Float64frombits(binary.LittleEndian.Uint64(setup[16:24])); margin != 0.5 {
+		t.Errorf("Expected a header margin of 0.5, got %g", margin)
+	}
+	if wsBool := binary.LittleEndian.Uint16(recordsOfType(streams[2], recTypeWSBOOL)[0].Data); wsBool&0x0100 == 0 {
+		t.Errorf("Expected fFitToPage in WSBOOL, got 0x%04X", wsBool)
+	}
+	if grid := recordsOfType(streams[2], recTypePRINTGRIDLINES)[0].Data; binary.LittleEndian.Uint16(grid) != 1 {
+		t.Error("Expected gridlines to print")
+	}
+	if grid := recordsOfType(streams[1], recTypePRINTGRIDLINES)[0].Data; binary.LittleEndian.Uint16(grid) != 0 {
+		t.Error("Expected the first sheet to keep the default print setup")
+	}
+}
+
+func TestSheetSettingsOutOfRange(t *testing.T) {
+	for name, opt := range map[string]SheetOption{
+		"freeze":   WithFreezePanes(0, 256),
+		"width":    WithColWidth(0, 256),
+		"column":   WithColWidth(256, 10),
+		"zoom":     WithZoom(5),
+		"password": WithProtection("0123456789abcdef"),
+		"scale":    WithPrintSetup(PrintSetup{Scale: 500}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := New()
+			defer w.Close()
+			w.AddSheet("Data", opt)
+			if _, err := w.WriteTo(&bytes.Buffer{}); err == nil {
+				t.Error("Expected an error for a setting out of range")
+			}
+		})
+	}
+}
+
+func TestCopySheet(t *testing.T) {
+	w := New(WithSheetName("Template"))
+	defer w.Close()
+	template := [][]interface{}{
+		{"Day", "Sales", "Total"},
+		{1, 100, FormulaCell{Expr: "SUM(B2:B3)", Cached: 300}},
+		{2, 200, nil},
+	}
+	w.Write(template)
+	w.SetColStyle(1, Style{NumberFormat: "#,##0"})
+	w.FormatAsTable(0, 2, 0, 2, TableStyle{AutoFilter: true})
+	src, _ := w.Sheet("Template")
+	src.SetColWidth(0, 6)
+	src.FreezePanes(1, 0)
+	src.SetPrintSetup(PrintSetup{Landscape: true})
+	src.AppendRawWorksheetRecord(0x0033, []byte{0x03, 0x00})
+
+	for _, month := range []string{"Jan", "Feb"} {
+		if err := w.CopySheet("Template", month); err != nil {
+			t.Fatalf("CopySheet() failed: %v", err)
+		}
+	}
+	if err := w.CopySheet("Missing", "Mar"); !errors.Is(err, ErrSheetNotFound) {
+		t.Errorf("Expected ErrSheetNotFound, got %v", err)
+	}
+	if err := w.CopySheet("Template", "jan"); !errors.Is(err, ErrInvalidSheetName) {
+		t.Errorf("Expected ErrInvalidSheetName for a duplicate name, got %v", err)
+	}
+
+	// Changes to a copy do not reach the template or the other copy
+	jan, _ := w.Sheet("Jan")
+	jan.data[1][1] = 999
+	jan.SetColStyle(1, Style{Font: Font{Bold: true}})
+	jan.SetColWidth(0, 30)
+	jan.print.Landscape = false
+	jan.autoFilter.lastRow = 1
+	jan.raw[0].data[0] = 0x04
+	if template[1][1] != 100 {
+		t.Errorf("Expected the template data unchanged, got %v", template[1][1])
+	}
+	for _, name := range []string{"Template", "Feb"} {
+		s, _ := w.Sheet(name)
+		if s.data[1][1] != 100 || s.colStyles[1].Font.Bold || s.colWidths[0] != 6 ||
+			!s.print.Landscape || s.autoFilter.lastRow != 2 || s.raw[0].data[0] != 0x03 {
+			t.Errorf("Sheet %q was changed through a copy", name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	for _, name := range []string{"Template", "Feb"} {
+		if err := Verify(buf.Bytes(), name, template); err != nil {
+			t.Errorf("Sheet %q does not read back: %v", name, err)
+		}
+	}
+
+	// Each BOUNDSHEET points at the BOF of its sheet
+	records := writtenRecords(t, w)
+	offsets := make(map[int]bool)
+	offset := 0
+	for _, rec := range records {
+		if rec.Type == recTypeBOF {
+			offsets[offset] = true
+		}
+		offset += 4 + len(rec.Data)
+	}
+	boundSheets := recordsOfType(records, recTypeBOUNDSHEET)
+	if len(boundSheets) != 3 {
+		t.Fatalf("Expected 3 BOUNDSHEET records, got %d", len(boundSheets))
+	}
+	for _, rec := range boundSheets {
+		if pos := int(binary.LittleEndian.Uint32(rec.Data[0:4])); pos == 0 || !offsets[pos] {
+			t.Errorf("BOUNDSHEET offset %d does not point at a worksheet BOF", pos)
+		}
+	}
+	if n := len(recordsOfType(records, recTypeNAME)); n != 3 {
+		t.Errorf("Expected an AutoFilter name per sheet, got %d", n)
+	}
+}
+
+func TestSetCell(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := [][]interface{}{{"a", "b"}}
+	w.Write(data)
+	w.SetCellStyle(2, 3, Style{NumberFormat: "0.00"})
+
+	if err := w.SetCell(0, 1, "B"); err != nil {
+		t.Fatalf("SetCell() failed: %v", err)
+	}
+	if err := w.SetCellRef("d3", 1.5); err != nil {
+		t.Fatalf("SetCellRef() failed: %v", err)
+	}
+	want := [][]interface{}{{"a", "B"}, nil, {nil, nil, nil, 1.5}}
+	if !reflect.DeepEqual(w.sheets[0].data, want) {
+		t.Errorf("Expected %v, got %v", want, w.sheets[0].data)
+	}
+	if data[0][1] != "b" {
+		t.Errorf("Expected the written slice unchanged, got %v", data)
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to read the output: %v", err)
+	}
+	if c := wb.Sheets()[0].Cell(2, 3); c.Value != 1.5 || c.FormatString != "0.00" {
+		t.Errorf("Expected the cell to keep its style, got %v in %q", c.Value, c.FormatString)
+	}
+
+	for _, ref := range []string{"", "B", "3", "B0", "IW1", "A65537", "Sheet1!A1"} {
+		if err := w.SetCellRef(ref, 1); err == nil {
+			t.Errorf("SetCellRef(%q): expected an error", ref)
+		}
+	}
+	if err := w.SetCell(-1, 0, 1); err == nil {
+		t.Error("SetCell(-1, 0): expected an error")
+	}
+	if err := w.SetCell(0, maxColumn+1, 1); err == nil {
+		t.Error("SetCell(0, 256): expected an error")
+	}
+}
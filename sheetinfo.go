@@ -0,0 +1,324 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Range is an inclusive range of zero-based cells of a sheet.
+type Range struct {
+	FirstRow, LastRow, FirstCol, LastCol int
+}
+
+// Hyperlink is a hyperlink of a sheet read from an HLINK record.
+type Hyperlink struct {
+	Range
+	// URL is the target of the link: a URL, or the path of a file. It is
+	// empty for links to a place in the workbook.
+	URL string
+	// Location is the place in the target, such as Sheet2!A1, or the
+	// fragment of a URL.
+	Location string
+	Display  string // The text shown for the link, if the file sets one
+	Tooltip  string
+}
+
+// SheetVisibility is whether a sheet is shown in the workbook's tabs.
+type SheetVisibility int
+
+// Sheet visibilities, from the BOUNDSHEET record
+const (
+	SheetVisible SheetVisibility = iota
+	SheetHidden
+	// SheetVeryHidden sheets can only be shown again from VBA.
+	SheetVeryHidden
+)
+
+var sheetVisibilityNames = [...]string{"Visible", "Hidden", "VeryHidden"}
+
+// String returns the name of the visibility.
+func (v SheetVisibility) String() string {
+	if v >= 0 && int(v) < len(sheetVisibilityNames) {
+		return sheetVisibilityNames[v]
+	}
+	return fmt.Sprintf("SheetVisibility(%d)", int(v))
+}
+
+// SheetInfo describes a worksheet from the workbook globals, without
+// reading its cells.
+type SheetInfo struct {
+	Name       string
+	Visibility SheetVisibility
+	// Size is the approximate size of the sheet in the Workbook stream, in
+	// bytes: up to the next sheet, or the end of the stream.
+	Size int
+}
+
+// SheetInfo returns the name, visibility and size of the worksheets, in
+// the order of Sheets. No sheet is read.
+func (wb *Workbook) SheetInfo() []SheetInfo {
+	infos := make([]SheetInfo, len(wb.sheets))
+	for i, s := range wb.sheets {
+		infos[i] = SheetInfo{Name: s.Name, Visibility: s.visibility, Size: s.size}
+	}
+	return infos
+}
+
+// sheetLayout holds the layout records of a sheet.
+type sheetLayout struct {
+	merged     []Range
+	colWidths  map[int]float64
+	rowHeights map[int]float64
+	links      []Hyperlink
+	err        error // That stopped the reading of the records, if any
+}
+
+// MergedRanges returns the merged cell ranges of the sheet, from its
+// MERGEDCELLS records.
+func (s *Sheet) MergedRanges() []Range {
+	return s.layout().merged
+}
+
+// ColWidths returns the widths of the columns that have a COLINFO record, in
+// characters of the default font, by zero-based column. Hidden columns have
+// width 0.
+func (s *Sheet) ColWidths() map[int]float64 {
+	return s.layout().colWidths
+}
+
+// RowHeights returns the heights of the rows that have a custom height or
+// are hidden, in points, by zero-based row. Hidden rows have height 0.
+func (s *Sheet) RowHeights() map[int]float64 {
+	return s.layout().rowHeights
+}
+
+// Hyperlinks returns the hyperlinks of the sheet, from its HLINK and
+// HLINKTOOLTIP records.
+func (s *Sheet) Hyperlinks() []Hyperlink {
+	return s.layout().links
+}
+
+// layout reads the layout records of the sheet once. Records it cannot
+// decode are skipped.
+func (s *Sheet) layout() *sheetLayout {
+	s.infoOnce.Do(func() {
+		info := &sheetLayout{
+			merged:     []Range{},
+			colWidths:  make(map[int]float64),
+			rowHeights: make(map[int]float64),
+			links:      []Hyperlink{},
+		}
+		s.info = info
+
+		// Reading stops at a truncated record, which opening the
+		// workbook found unless the sheet is read on demand
+		r := s.wb.records(s.offset)
+		depth := 0
+		for {
+			recType, data, _, err := r.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				info.err = fmt.Errorf("failed to read the layout of sheet %q: %w", s.Name, err)
+				return
+			}
+			if recType == recTypeBOF {
+				depth++
+				continue
+			}
+			if recType == recTypeEOF {
+				if depth--; depth == 0 {
+					return
+				}
+				continue
+			}
+			if depth > 1 {
+				continue
+			}
+
+			le := binary.LittleEndian
+			switch recType {
+			case recTypeMERGEDCELLS:
+				if len(data) < 2 {
+					continue
+				}
+				for i, n := 0, int(le.Uint16(data)); i < n && 2+8*(i+1) <= len(data); i++ {
+					info.merged = append(info.merged, readRef8(data[2+8*i:]))
+				}
+			case recTypeCOLINFO:
+				if len(data) < 10 {
+					continue
+				}
+				width := float64(le.Uint16(data[4:6])) / 256
+				if le.Uint16(data[8:10])&0x0001 != 0 {
+					width = 0
+				}
+				for col := int(le.Uint16(data[0:2])); col <= min(int(le.Uint16(data[2:4])), maxColumn); col++ {
+					info.colWidths[col] = width
+				}
+			case recTypeROW:
+				if len(data) < 16 {
+					continue
+				}
+				options := le.Uint16(data[12:14])
+				switch {
+				case options&0x0020 != 0: // fDyZero: the row is hidden
+					info.rowHeights[int(le.Uint16(data[0:2]))] = 0
+				case options&0x0040 != 0: // fUnsynced: the height is set
+					info.rowHeights[int(le.Uint16(data[0:2]))] = float64(le.Uint16(data[6:8])&0x7FFF) / 20
+				}
+			case recTypeHLINK:
+				if link, ok := readHyperlink(data); ok {
+					info.links = append(info.links, link)
+				}
+			case recTypeHLINKTOOLTIP:
+				if len(data) < 10 || len(info.links) == 0 {
+					continue
+				}
+				info.links[len(info.links)-1].Tooltip = utf16String(data[10:])
+			}
+		}
+	})
+	return s.info
+}
+
+// readRef8 reads a Ref8 structure: first and last row, first and last
+// column.
+func readRef8(b []byte) Range {
+	return Range{
+		FirstRow: int(binary.LittleEndian.Uint16(b[0:2])),
+		LastRow:  int(binary.LittleEndian.Uint16(b[2:4])),
+		FirstCol: int(binary.LittleEndian.Uint16(b[4:6])),
+		LastCol:  int(binary.LittleEndian.Uint16(b[6:8])),
+	}
+}
+
+// Hyperlink object flags
+const (
+	hlinkHasMoniker     = 0x0001
+	hlinkHasLocation    = 0x0008
+	hlinkHasDisplayName = 0x0010
+	hlinkHasFrameName   = 0x0080
+	hlinkMonikerIsText  = 0x0100
+)
+
+// Moniker class IDs, as stored
+var (
+	urlMonikerCLSID  = []byte{0xE0, 0xC9, 0xEA, 0x79, 0xF9, 0xBA, 0xCE, 0x11, 0x8C, 0x82, 0x00, 0xAA, 0x00, 0x4B, 0xA9, 0x0B}
+	fileMonikerCLSID = []byte{0x03, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+)
+
+// readHyperlink decodes the body of an HLINK record: a Ref8, a class ID and
+// a hyperlink object holding, as its flags say, the display name, a target
+// frame, the target as text or as a URL or file moniker, and the location.
+func readHyperlink(data []byte) (Hyperlink, bool) {
+	if len(data) < 32 {
+		return Hyperlink{}, false
+	}
+	link := Hyperlink{Range: readRef8(data)}
+	flags := binary.LittleEndian.Uint32(data[28:32])
+	b := data[32:]
+
+	// str reads a HyperlinkString: a count of UTF-16 code units, with the
+	// terminating null, and the code units
+	ok := true
+	str := func() string {
+		if len(b) < 4 {
+			ok = false
+			return ""
+		}
+		n := 2 * int(binary.LittleEndian.Uint32(b))
+		if n < 0 || len(b) < 4+n {
+			ok = false
+			return ""
+		}
+		s := utf16String(b[4 : 4+n])
+		b = b[4+n:]
+		return s
+	}
+
+	if flags&hlinkHasDisplayName != 0 {
+		link.Display = str()
+	}
+	if flags&hlinkHasFrameName != 0 {
+		str()
+	}
+	if flags&hlinkHasMoniker != 0 {
+		if flags&hlinkMonikerIsText != 0 {
+			link.URL = str()
+		} else if link.URL, ok = readMoniker(&b); !ok {
+			return Hyperlink{}, false
+		}
+	}
+	if flags&hlinkHasLocation != 0 {
+		link.Location = str()
+	}
+	return link, ok
+}
+
+// readMoniker reads a URL or file moniker from the start of *b, advancing
+// it, and returns its target.
+func readMoniker(b *[]byte) (string, bool) {
+	data := *b
+	if len(data) < 20 {
+		return "", false
+	}
+	clsid, data := data[:16], data[16:]
+	le := binary.LittleEndian
+	switch {
+	case bytes.Equal(clsid, urlMonikerCLSID):
+		n := int(le.Uint32(data))
+		if n < 0 || len(data) < 4+n {
+			return "", false
+		}
+		*b = data[4+n:]
+		return utf16String(data[4 : 4+n]), true
+
+	case bytes.Equal(clsid, fileMonikerCLSID):
+		// Parent directory count, the 8-bit path, then a fixed tail and,
+		// if present, the UTF-16 path
+		if len(data) < 6 {
+			return "", false
+		}
+		up, n := int(le.Uint16(data)), int(le.Uint32(data[2:]))
+		if n < 0 || len(data) < 6+n+24+4 {
+			return "", false
+		}
+		path := string(bytes.TrimRight(data[6:6+n], "\x00"))
+		data = data[6+n+24:]
+		if size := int(le.Uint32(data)); size > 0 {
+			if size < 6 || len(data) < 4+size {
+				return "", false
+			}
+			wide := int(le.Uint32(data[4:]))
+			if wide < 0 || 10+wide > 4+size {
+				return "", false
+			}
+			path = utf16String(data[10 : 10+wide])
+			data = data[4+size:]
+		} else {
+			data = data[4:]
+		}
+		*b = data
+		return strings.Repeat(`..\`, up) + path, true
+	}
+	return "", false
+}
+
+// utf16String decodes UTF-16LE text up to the first null code unit.
+func utf16String(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,151 @@
+package xls
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var hlinkCLSID = []byte{0xD0, 0xC9, 0xEA, 0x79, 0xF9, 0xBA, 0xCE, 0x11, 0x8C, 0x82, 0x00, 0xAA, 0x00, 0x4B, 0xA9, 0x0B}
+
+// hlinkString encodes a HyperlinkString: the count of UTF-16 code units
+// with the terminating null, then the code units.
+func hlinkString(s string) []byte {
+	units := utf16le(s + "\x00")
+	return le(uint32(len(units)/2), units)
+}
+
+// layoutRecords are the worksheet records of testdata/layout.xls, laid out
+// as Excel saves them. TestReadSavedFiles checks the same records in a file
+// Excel saved, testdata/saved/excel_layout.xls.
+var layoutRecords = []testRecord{
+	{recTypeCOLINFO, le(0, 0, 20*256, 0, 0, 0)},
+	{recTypeCOLINFO, le(2, 4, 5*256+128, 0, 0, 0)},
+	{recTypeCOLINFO, le(5, 5, 10*256, 0, 0x0001, 0)},
+	{recTypeROW, le(0, 0, 3, 600, 0, 0, 0x0140, 0x000F)}, // 30pt, custom height
+	{recTypeROW, le(1, 0, 3, 255, 0, 0, 0x0100, 0x000F)}, // Default height
+	{recTypeROW, le(2, 0, 3, 255, 0, 0, 0x0120, 0x000F)}, // Hidden
+	{recTypeLABEL, le(0, 0, 0, 4, byte(0), "Site")},
+	{recTypeLABEL, le(1, 1, 0, 4, byte(0), "File")},
+	{recTypeLABEL, le(2, 2, 0, 4, byte(0), "Here")},
+	{recTypeMERGEDCELLS, le(2, 0, 1, 0, 3, 4, 6, 2, 3)},
+	{recTypeMERGEDCELLS, le(1, 10, 12, 0, 0)},
+	{recTypeHLINK, le(0, 0, 0, 0, hlinkCLSID, uint32(2), uint32(0x17), hlinkString("Site"),
+		urlMonikerCLSID, uint32(len(utf16le("https://example.com/a\x00"))), utf16le("https://example.com/a\x00"))},
+	{recTypeHLINK, le(1, 1, 1, 1, hlinkCLSID, uint32(2), uint32(0x15), hlinkString("File"),
+		fileMonikerCLSID, 1, uint32(9), "data.xls\x00", 0xFFFF, 0xDEAD, make([]byte, 20), uint32(0))},
+	{recTypeHLINK, le(2, 2, 2, 2, hlinkCLSID, uint32(2), uint32(0x1C), hlinkString("Here"), hlinkString("Sheet2!A1"))},
+	{recTypeHLINKTOOLTIP, le(recTypeHLINKTOOLTIP, 2, 2, 2, 2, utf16le("Go there\x00"))},
+}
+
+func TestSheetLayout(t *testing.T) {
+	path := filepath.Join("testdata", "layout.xls")
+	if *update {
+		if err := os.WriteFile(path, buildFixture(t, nil, layoutRecords), 0o644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+	}
+	wb, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() failed: %v", err)
+	}
+	s := wb.Sheets()[0]
+
+	wantMerged := []Range{{0, 1, 0, 3}, {4, 6, 2, 3}, {10, 12, 0, 0}}
+	if got := s.MergedRanges(); !reflect.DeepEqual(got, wantMerged) {
+		t.Errorf("Expected merged ranges %v, got %v", wantMerged, got)
+	}
+	wantWidths := map[int]float64{0: 20, 2: 5.5, 3: 5.5, 4: 5.5, 5: 0}
+	if got := s.ColWidths(); !reflect.DeepEqual(got, wantWidths) {
+		t.Errorf("Expected column widths %v, got %v", wantWidths, got)
+	}
+	wantHeights := map[int]float64{0: 30, 2: 0}
+	if got := s.RowHeights(); !reflect.DeepEqual(got, wantHeights) {
+		t.Errorf("Expected row heights %v, got %v", wantHeights, got)
+	}
+	wantLinks := []Hyperlink{
+		{Range: Range{0, 0, 0, 0}, URL: "https://example.com/a", Display: "Site"},
+		{Range: Range{1, 1, 1, 1}, URL: `..\data.xls`, Display: "File"},
+		{Range: Range{2, 2, 2, 2}, Location: "Sheet2!A1", Display: "Here", Tooltip: "Go there"},
+	}
+	if got := s.Hyperlinks(); !reflect.DeepEqual(got, wantLinks) {
+		t.Errorf("Expected hyperlinks %+v, got %+v", wantLinks, got)
+	}
+	if got := s.Cell(2, 2).Value; got != "Here" {
+		t.Errorf("Expected the cells read as well, got %v", got)
+	}
+}
+
+func TestSheetLayoutEmpty(t *testing.T) {
+	w := New()
+	w.Write([][]interface{}{{"a", 1}})
+	w.sheets[0].SetColWidth(1, 12.5)
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	s := wb.Sheets()[0]
+	if got := s.MergedRanges(); got == nil || len(got) != 0 {
+		t.Errorf("Expected no merged ranges, got %#v", got)
+	}
+	if got := s.Hyperlinks(); got == nil || len(got) != 0 {
+		t.Errorf("Expected no hyperlinks, got %#v", got)
+	}
+	if got := s.RowHeights(); got == nil || len(got) != 0 {
+		t.Errorf("Expected no row heights, got %#v", got)
+	}
+	if got := s.ColWidths(); got[1] != 12.5 {
+		t.Errorf("Expected column 1 12.5 wide, got %v", got)
+	}
+}
+
+func TestWorkbookSheetInfo(t *testing.T) {
+	data := buildFixture(t, nil, []testRecord{{recTypeNUMBER, le(0, 0, 0, 1.0)}})
+	// The visibility byte of the BOUNDSHEET record precedes the sheet type,
+	// name length and flags
+	i := bytes.Index(data, []byte("Sheet1"))
+	data[i-4] = byte(SheetVeryHidden)
+
+	wb, err := openWorkbook(data)
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	// The last sheet runs to the end of the stream, padded by WriteCFB
+	want := []SheetInfo{{Name: "Sheet1", Visibility: SheetVeryHidden, Size: wb.size - int(wb.Sheets()[0].offset)}}
+	if got := wb.SheetInfo(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := SheetVisibility(7).String(); got != "SheetVisibility(7)" {
+		t.Errorf("Expected an unknown visibility named by value, got %q", got)
+	}
+
+	w := New()
+	if err := w.Write([][]interface{}{{"a"}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if _, err := w.AddSheet("Second"); err != nil {
+		t.Fatalf("AddSheet() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err = OpenReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenReader() failed: %v", err)
+	}
+	infos := wb.SheetInfo()
+	if len(infos) != 2 || infos[0].Visibility != SheetVisible || infos[1].Name != "Second" {
+		t.Fatalf("Expected two visible sheets, got %v", infos)
+	}
+	sheets := wb.Sheets()
+	if infos[0].Size != int(sheets[1].offset-sheets[0].offset) || infos[1].Size != wb.size-int(sheets[1].offset) {
+		t.Errorf("Expected the sizes of the substreams, got %v", infos)
+	}
+}
@@ -0,0 +1,113 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrReservedSheetName is returned for a sheet name Excel reserves, such as
+// "History", or that reads as a cell reference, such as "A1", "ZZ100" or
+// "R1C1", which formulas referring to the sheet would confuse with a cell.
+// WithAllowRiskySheetNames accepts them.
+var ErrReservedSheetName = errors.New("xls: reserved sheet name")
+
+// reservedSheetNames are the names Excel keeps for itself, in lower case.
+// "History" holds the change history of shared workbooks.
+var reservedSheetNames = []string{"history"}
+
+// Limits of the cell references of current Excel versions, XFD1048576,
+// which are the ones a sheet name is read as
+const (
+	maxRefColumn = 16384
+	maxRefRow    = 1048576
+)
+
+// WithAllowRiskySheetNames accepts sheet names that Excel reserves or that
+// read as cell references, rejected by default with ErrReservedSheetName.
+func WithAllowRiskySheetNames() Option {
+	return func(w *Writer) {
+		w.riskySheetNames = true
+	}
+}
+
+// checkRiskySheetName returns ErrReservedSheetName for a reserved name or
+// one that reads as a cell reference, unless WithAllowRiskySheetNames is
+// set.
+func (w *Writer) checkRiskySheetName(name string) error {
+	if w.riskySheetNames {
+		return nil
+	}
+	for _, reserved := range reservedSheetNames {
+		if strings.EqualFold(name, reserved) {
+			return fmt.Errorf("%w: %q is reserved by Excel", ErrReservedSheetName, name)
+		}
+	}
+	if isA1Ref(name) || isR1C1Ref(name) {
+		return fmt.Errorf("%w: %q reads as a cell reference", ErrReservedSheetName, name)
+	}
+	return nil
+}
+
+// isA1Ref reports whether s is an A1-style reference without $, such as
+// "B3" or "xfd1048576", within the limits of current Excel versions.
+func isA1Ref(s string) bool {
+	i, col := 0, 0
+	for i < len(s) && i < 3 && isASCIILetter(s[i]) {
+		col = col*26 + int(s[i]|0x20-'a'+1)
+		i++
+	}
+	if i == 0 || col > maxRefColumn {
+		return false
+	}
+	row, ok := refNumber(s[i:])
+	return ok && row >= 1 && row <= maxRefRow
+}
+
+// isR1C1Ref reports whether s is an R1C1-style reference without brackets:
+// "R1C1", a row "R1", a column "C1", or "R", "C" and "RC", which R1C1
+// formulas read as the current row, column and cell.
+func isR1C1Ref(s string) bool {
+	s = strings.ToUpper(s)
+	if rest, ok := strings.CutPrefix(s, "R"); ok {
+		digits := strings.IndexByte(rest, 'C')
+		if digits < 0 {
+			digits = len(rest)
+		}
+		if digits > 0 {
+			if _, ok := refNumber(rest[:digits]); !ok {
+				return false
+			}
+		}
+		s = rest[digits:]
+		if s == "" {
+			return true
+		}
+	}
+	rest, ok := strings.CutPrefix(s, "C")
+	if !ok {
+		return false
+	}
+	_, ok = refNumber(rest)
+	return ok || rest == ""
+}
+
+// refNumber parses the digits of a reference, at most seven, enough for any
+// row or column.
+func refNumber(s string) (int, bool) {
+	if s == "" || len(s) > 7 {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
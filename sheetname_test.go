@@ -0,0 +1,93 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCellReferenceSheetNames(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		a1, r1c1 bool
+	}{
+		{"A1", true, false},
+		{"zz100", true, false},
+		{"ZZ100", true, false},
+		{"XFD1048576", true, false},
+		{"XFE1", false, false},
+		{"A1048577", false, false},
+		{"A0", false, false}, // Rows start at 1
+		{"ABCD1", false, false},
+		{"JAN2024", true, false},
+		{"R1C1", false, true},
+		{"r12c3", false, true},
+		{"R1", true, true},
+		{"C7", true, true},
+		{"R", false, true},
+		{"C", false, true},
+		{"RC", false, true},
+		{"R1C", false, true},
+		{"Rabbit", false, false},
+		{"Cat", false, false},
+		{"Sheet1", false, false},
+		{"A1 B", false, false},
+		{"1A", false, false},
+	} {
+		if got := isA1Ref(tc.name); got != tc.a1 {
+			t.Errorf("isA1Ref(%q): expected %v, got %v", tc.name, tc.a1, got)
+		}
+		if got := isR1C1Ref(tc.name); got != tc.r1c1 {
+			t.Errorf("isR1C1Ref(%q): expected %v, got %v", tc.name, tc.r1c1, got)
+		}
+	}
+}
+
+func TestReservedSheetName(t *testing.T) {
+	w := New()
+	for _, name := range []string{"A1", "ZZ100", "R1C1", "History", "history", "RC"} {
+		if _, err := w.AddSheet(name); !errors.Is(err, ErrReservedSheetName) {
+			t.Errorf("AddSheet(%q): expected ErrReservedSheetName, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"Histories", "Sheet2", "A1 notes", "Totals"} {
+		if _, err := w.AddSheet(name); err != nil {
+			t.Errorf("AddSheet(%q) failed: %v", name, err)
+		}
+	}
+
+	w.SetSheetName("HISTORY")
+	if _, err := w.WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrReservedSheetName) {
+		t.Errorf("Expected ErrReservedSheetName for a renamed sheet, got %v", err)
+	}
+	if _, err := New(WithSheetName("B2")).WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrReservedSheetName) {
+		t.Errorf("Expected ErrReservedSheetName from WithSheetName, got %v", err)
+	}
+}
+
+func TestWithAllowRiskySheetNames(t *testing.T) {
+	w := New(WithAllowRiskySheetNames(), WithSheetName("History"))
+	for _, name := range []string{"A1", "R1C1"} {
+		if _, err := w.AddSheet(name); err != nil {
+			t.Errorf("AddSheet(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := w.AddSheet("a1"); !errors.Is(err, ErrInvalidSheetName) {
+		t.Errorf("Expected ErrInvalidSheetName for a duplicate, got %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	var names []string
+	for _, s := range wb.Sheets() {
+		names = append(names, s.Name)
+	}
+	if len(names) != 3 || names[0] != "History" || names[1] != "A1" || names[2] != "R1C1" {
+		t.Errorf("Expected the sheets History, A1 and R1C1, got %v", names)
+	}
+}
@@ -0,0 +1,329 @@
+package xls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// defaultSSTSpillThreshold is the number of unique strings from which the
+// SST is moved to a temporary file, unless set with WithSSTSpillThreshold.
+const defaultSSTSpillThreshold = 1 << 20
+
+const (
+	// spillBufferSize is how many string bytes are kept before they are
+	// written to the temporary file, and the size of the reads and writes
+	// of the SST record.
+	spillBufferSize = 64 << 10
+
+	// maxHotStrings bounds the strings seen more than once that are kept in
+	// memory, so repeated strings are not read back from the file.
+	maxHotStrings = 1 << 16
+)
+
+// spilledStrings holds the unique strings of an SST in a temporary file, in
+// the order they were added. Only a hash and the file offset of each string
+// stay in memory; a string whose hash matches is read back to compare it.
+type spilledStrings struct {
+	file    *os.File
+	written int64   // Bytes in the file
+	pending []byte  // Bytes not yet written to the file
+	offsets []int64 // Start of each string; a string ends where the next starts
+	size    int     // Size of the strings encoded in the SST record
+	err     error   // First error writing or reading the file
+
+	seed   maphash.Seed
+	byHash map[uint64]int32
+	// Strings whose hash was already taken by another string
+	collided map[string]int
+	// Strings seen more than once, up to maxHotStrings
+	hot map[string]int
+
+	scratch []byte
+}
+
+// newSpilledStrings creates the temporary file of a table in dir, or in
+// os.TempDir if it is "". The file has a unique name and is created
+// exclusively.
+func newSpilledStrings(dir string) (*spilledStrings, error) {
+	file, err := os.CreateTemp(dir, "xls-sst-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SST file: %w", err)
+	}
+	return &spilledStrings{
+		file:     file,
+		pending:  make([]byte, 0, spillBufferSize),
+		seed:     maphash.MakeSeed(),
+		byHash:   make(map[uint64]int32),
+		collided: make(map[string]int),
+		hot:      make(map[string]int),
+	}, nil
+}
+
+// index returns the index of s, or -1 if s has not been added. If shared
+// is set the strings are looked up concurrently and the scratch buffer is
+// not used.
+func (sp *spilledStrings) index(s string, h uint64, shared bool) int {
+	if index, ok := sp.hot[s]; ok {
+		return index
+	}
+	index, ok := sp.byHash[h]
+	if !ok {
+		return -1
+	}
+	if sp.equal(int(index), s, shared) {
+		return int(index)
+	}
+	if index, ok := sp.collided[s]; ok {
+		return index
+	}
+	return -1
+}
+
+// add appends s, with hash h, as the string of the given index.
+func (sp *spilledStrings) add(s string, h uint64, index int) {
+	if _, taken := sp.byHash[h]; taken {
+		sp.collided[s] = index
+	} else {
+		sp.byHash[h] = int32(index)
+	}
+	sp.append(s)
+}
+
+// append appends s without making it found by index, for a table without
+// deduplication.
+func (sp *spilledStrings) append(s string) {
+	sp.offsets = append(sp.offsets, sp.end())
+	sp.pending = append(sp.pending, s...)
+	sp.size += sstStringSize(s)
+	if len(sp.pending) >= spillBufferSize {
+		sp.flush()
+	}
+}
+
+// remember keeps s, found at index, in memory if there is room.
+func (sp *spilledStrings) remember(s string, index int) {
+	if len(sp.hot) < maxHotStrings {
+		sp.hot[s] = index
+	}
+}
+
+func (sp *spilledStrings) end() int64 {
+	return sp.written + int64(len(sp.pending))
+}
+
+func (sp *spilledStrings) flush() {
+	if sp.err == nil {
+		_, sp.err = sp.file.Write(sp.pending)
+	}
+	sp.written += int64(len(sp.pending))
+	sp.pending = sp.pending[:0]
+}
+
+// equal reports whether the string of the given index is s.
+func (sp *spilledStrings) equal(index int, s string, shared bool) bool {
+	start, end := sp.offsets[index], sp.end()
+	if index+1 < len(sp.offsets) {
+		end = sp.offsets[index+1]
+	}
+	if end-start != int64(len(s)) {
+		return false
+	}
+	if start >= sp.written {
+		return string(sp.pending[start-sp.written:end-sp.written]) == s
+	}
+	var b []byte
+	if shared {
+		b = make([]byte, len(s))
+	} else {
+		if cap(sp.scratch) < len(s) {
+			sp.scratch = make([]byte, len(s))
+		}
+		b = sp.scratch[:len(s)]
+	}
+	if _, err := sp.file.ReadAt(b, start); err != nil {
+		if sp.err == nil {
+			sp.err = fmt.Errorf("failed to read SST file: %w", err)
+		}
+		return false
+	}
+	return string(b) == s
+}
+
+// writeTo adds the strings to the SST record of sw.
+func (sp *spilledStrings) writeTo(sw *sstWriter) error {
+	if len(sp.pending) > 0 {
+		sp.flush()
+	}
+	if sp.err != nil {
+		return sp.err
+	}
+	r := bufio.NewReaderSize(io.NewSectionReader(sp.file, 0, sp.written), spillBufferSize)
+	var str, out []byte
+	for i, start := range sp.offsets {
+		end := sp.written
+		if i+1 < len(sp.offsets) {
+			end = sp.offsets[i+1]
+		}
+		if n := int(end - start); cap(str) < n {
+			str = make([]byte, n)
+		} else {
+			str = str[:n]
+		}
+		if _, err := io.ReadFull(r, str); err != nil {
+			return fmt.Errorf("failed to read SST file: %w", err)
+		}
+		out = appendSSTString(out[:0], string(str))
+		if err := sw.add(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close removes the temporary file, returning its name if it could not be
+// removed.
+func (sp *spilledStrings) close() (string, error) {
+	sp.file.Close()
+	if err := os.Remove(sp.file.Name()); err != nil {
+		return sp.file.Name(), err
+	}
+	return "", nil
+}
+
+// sstStringSize returns the size of s encoded by appendSSTString.
+func sstStringSize(s string) int {
+	size := 3
+	s, runs, rich := splitRichKey(s)
+	if rich {
+		size += 2 + len(runs)
+	}
+	for _, r := range s {
+		if r >= 0x10000 {
+			size += 4
+		} else {
+			size += 2
+		}
+	}
+	return size
+}
+
+// spillAt returns the number of unique strings from which the SST of a
+// write is moved to a file, or 0 if it stays in memory.
+func (w *Writer) spillAt() int {
+	switch {
+	case w.sstSpillThreshold == 0:
+		return defaultSSTSpillThreshold
+	case w.sstSpillThreshold < 0:
+		return 0
+	}
+	return w.sstSpillThreshold
+}
+
+// spill moves the strings of the table to a temporary file, where the
+// strings added later go too.
+func (sst *sharedStringTable) spill() error {
+	sp, err := newSpilledStrings(sst.tempDir)
+	if err != nil {
+		return err
+	}
+	for i, s := range sst.strings {
+		if sst.noDedup {
+			sp.append(s)
+		} else {
+			sp.add(s, maphash.String(sp.seed, s), i)
+		}
+	}
+	sst.spilled = sp
+	sst.strings, sst.stringMap = nil, nil
+	return nil
+}
+
+// addSpilled is addString for a table whose strings are in a file.
+func (sst *sharedStringTable) addSpilled(s string) int {
+	sp := sst.spilled
+	h := maphash.String(sp.seed, s)
+	index := sp.index(s, h, sst.filled)
+	if sst.filled {
+		return index
+	}
+	sst.totalCount++
+	if index < 0 {
+		index = sst.uniqueCount
+		sp.add(s, h, index)
+		sst.uniqueCount++
+	} else {
+		sp.remember(s, index)
+	}
+	return index
+}
+
+// WithLowMemorySST keeps the unique strings of the shared string table in a
+// temporary file, in os.TempDir or the directory set with WithTempDir,
+// instead of in memory from the start of each write. Only a hash and an
+// offset of each string stay in memory. It suits exports with millions of
+// unique strings, such as logs, and costs a read of the file for strings
+// that repeat. The file is removed when the write ends, whether it
+// succeeds, fails or panics. SaveAs and SaveToFS also write the Workbook
+// stream to a temporary file, as WriteChunks does.
+func WithLowMemorySST() Option {
+	return func(w *Writer) {
+		w.lowMemorySST = true
+	}
+}
+
+// WithSSTSpillThreshold sets the number of unique strings from which the
+// shared string table is moved to a temporary file during a write, as with
+// WithLowMemorySST: 1,048,576 by default, or with 0. A negative threshold
+// keeps it in memory. If the temporary file cannot be created, the table
+// stays in memory.
+func WithSSTSpillThreshold(n int) Option {
+	return func(w *Writer) {
+		w.sstSpillThreshold = n
+	}
+}
+
+// WithTempDir sets the directory of the temporary files of WithLowMemorySST,
+// WithSSTSpillThreshold, WriteChunks and ServeXLS, such as a scratch volume
+// larger than /tmp. It is os.TempDir by default, or with "".
+func WithTempDir(dir string) Option {
+	return func(w *Writer) {
+		w.tempDir = dir
+	}
+}
+
+// tempFiles holds the temporary files of a Writer that a write could not
+// remove, for Close. The copies of a Writer made for SaveAsFiles share it.
+type tempFiles struct {
+	mu     sync.Mutex
+	leaked []string
+}
+
+// leak records a file that could not be removed.
+func (t *tempFiles) leak(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.leaked = append(t.leaked, name)
+}
+
+// remove removes the leaked files, returning an error for each one that is
+// still there.
+func (t *tempFiles) remove() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var errs []error
+	var left []string
+	for _, name := range t.leaked {
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("xls: temporary file %s not removed: %w", name, err))
+			left = append(left, name)
+		}
+	}
+	t.leaked = left
+	return errors.Join(errs...)
+}
@@ -0,0 +1,244 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// spillData returns sheets of strings that fill several file buffers: unique
+// strings, repeated ones, and strings with non-ASCII and non-BMP characters.
+func spillData() [][][]interface{} {
+	sheets := make([][][]interface{}, 3)
+	for i := range sheets {
+		for r := 0; r < 2000; r++ {
+			sheets[i] = append(sheets[i], []interface{}{
+				fmt.Sprintf("unique string %d-%d", i, r),
+				fmt.Sprintf("repeated %d", r%50),
+				fmt.Sprintf("売上 %d 😀", r%700),
+				r,
+				"",
+			})
+		}
+	}
+	return sheets
+}
+
+func writeSpillWorkbook(t *testing.T, opts ...Option) []byte {
+	t.Helper()
+	w := New(opts...)
+	defer w.Close()
+	for i, data := range spillData() {
+		s := w.sheets[0]
+		if i > 0 {
+			s, _ = w.AddSheet(fmt.Sprintf("Sheet%d", i+1))
+		}
+		s.Write(data)
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLowMemorySST(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	want := writeSpillWorkbook(t, WithSSTSpillThreshold(-1))
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"forced", []Option{WithLowMemorySST()}},
+		{"threshold", []Option{WithSSTSpillThreshold(1000)}},
+		{"concurrent", []Option{WithLowMemorySST(), WithConcurrency(3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeSpillWorkbook(t, tt.opts...); !bytes.Equal(got, want) {
+				t.Error("Expected the same file as with the SST in memory")
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("Expected the SST file removed, found %d files", len(entries))
+			}
+		})
+	}
+
+	hybrid := writeSpillWorkbook(t, WithHybridStrings(2), WithSSTSpillThreshold(-1))
+	if got := writeSpillWorkbook(t, WithHybridStrings(2), WithLowMemorySST()); !bytes.Equal(got, hybrid) {
+		t.Error("Expected the same file with hybrid strings")
+	}
+}
+
+func TestLowMemorySSTWithoutTempDir(t *testing.T) {
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+
+	want := writeSpillWorkbook(t, WithSSTSpillThreshold(-1))
+	if got := writeSpillWorkbook(t, WithSSTSpillThreshold(10)); !bytes.Equal(got, want) {
+		t.Error("Expected the SST kept in memory when no file can be created")
+	}
+
+	w := New(WithLowMemorySST())
+	w.Write([][]interface{}{{"a"}})
+	if _, err := w.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Error("Expected an error creating the SST file with WithLowMemorySST")
+	}
+}
+
+// spillFiles returns the names of the SST files in dir.
+func spillFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "xls-sst-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestWithTempDir(t *testing.T) {
+	dir, tmp := t.TempDir(), t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	data := [][]interface{}{{"a", "b", "1"}}
+
+	// The number conversion runs in the middle of the write
+	var during []string
+	convert := func(string) bool {
+		during = spillFiles(t, dir)
+		return true
+	}
+	w := New(WithLowMemorySST(), WithTempDir(dir), WithNumberConversion(convert))
+	w.Write(data)
+	if _, err := w.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if len(during) != 1 || len(spillFiles(t, tmp)) != 0 {
+		t.Errorf("Expected the SST file in the temporary directory, found %v", during)
+	}
+	if files := spillFiles(t, dir); len(files) != 0 {
+		t.Errorf("Expected the SST file removed, found %v", files)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+
+	// A write failing after the SST file is created
+	w = New(WithLowMemorySST(), WithTempDir(dir), WithStrictTypes())
+	w.Write([][]interface{}{{"a", "b"}, {struct{}{}}})
+	if err := w.SaveAs(filepath.Join(dir, "out.xls")); !errors.Is(err, ErrUnsupportedCellType) {
+		t.Errorf("Expected ErrUnsupportedCellType, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the temporary directory empty after a failed save, found %d files", len(entries))
+	}
+
+	// A write panicking after the SST file is created
+	w = New(WithLowMemorySST(), WithTempDir(dir), WithNumberConversion(func(string) bool { panic("boom") }))
+	w.Write(data)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic of the conversion")
+			}
+		}()
+		w.WriteTo(&bytes.Buffer{})
+	}()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the temporary directory empty after a panic, found %d files", len(entries))
+	}
+}
+
+func TestCloseLeakedTempFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// Replace the SST file with a directory that cannot be removed while it
+	// holds a file
+	var blocker string
+	convert := func(string) bool {
+		files := spillFiles(t, dir)
+		if len(files) == 1 && blocker == "" {
+			os.Remove(files[0])
+			os.Mkdir(files[0], 0o755)
+			blocker = filepath.Join(files[0], "blocker")
+			os.WriteFile(blocker, nil, 0o644)
+		}
+		return true
+	}
+	w := New(WithLowMemorySST(), WithTempDir(dir), WithNumberConversion(convert))
+	w.Write([][]interface{}{{"a", "1"}})
+	if _, err := w.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if blocker == "" {
+		t.Fatal("Expected an SST file during the write")
+	}
+	err := w.Close()
+	if err == nil || !strings.Contains(err.Error(), filepath.Dir(blocker)) {
+		t.Errorf("Expected Close to report the leaked file, got %v", err)
+	}
+
+	os.Remove(blocker)
+	if err := w.Close(); err != nil {
+		t.Errorf("Expected Close to remove the leaked file, got %v", err)
+	}
+	if files := spillFiles(t, dir); len(files) != 0 {
+		t.Errorf("Expected no SST file left, found %v", files)
+	}
+}
+
+func TestSpilledStringsCollision(t *testing.T) {
+	sp, err := newSpilledStrings("")
+	if err != nil {
+		t.Fatalf("newSpilledStrings() failed: %v", err)
+	}
+	defer sp.close()
+
+	// Strings with the same hash, one of them already in the file
+	sp.add("first", 42, 0)
+	sp.flush()
+	sp.add("second", 42, 1)
+	sp.add("third", 7, 2)
+	for i, s := range []string{"first", "second", "third"} {
+		h := uint64(42)
+		if s == "third" {
+			h = 7
+		}
+		for _, shared := range []bool{false, true} {
+			if got := sp.index(s, h, shared); got != i {
+				t.Errorf("index(%q, shared %v): expected %d, got %d", s, shared, i, got)
+			}
+		}
+	}
+	if got := sp.index("fourth", 42, false); got != -1 {
+		t.Errorf("Expected -1 for a string not added, got %d", got)
+	}
+
+	var buf bytes.Buffer
+	sw := &sstWriter{w: New(), out: &buf, body: make([]byte, 0, maxRecordSize)}
+	if err := sp.writeTo(sw); err != nil {
+		t.Fatalf("writeTo() failed: %v", err)
+	}
+	if err := sw.flush(); err != nil {
+		t.Fatalf("flush() failed: %v", err)
+	}
+	var want []byte
+	for _, s := range []string{"first", "second", "third"} {
+		want = appendSSTString(want, s)
+	}
+	if got := buf.Bytes()[4:]; !bytes.Equal(got, want) || sp.size != len(want) {
+		t.Errorf("Expected the strings encoded in order (%d bytes), got %d bytes", sp.size, len(got))
+	}
+}
+
+func TestSSTStringSize(t *testing.T) {
+	for _, s := range []string{"", "abc", "売上", "😀x", "\xff\xfe"} {
+		if got, want := sstStringSize(s), len(appendSSTString(nil, s)); got != want {
+			t.Errorf("sstStringSize(%q): expected %d, got %d", s, want, got)
+		}
+	}
+}
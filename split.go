@@ -0,0 +1,253 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// WithHeaderRows sets how many rows at the top of the data are headers,
+// which SaveAsFiles and WriteToFiles repeat at the top of every file,
+// WithSheetSplitting at the top of every continuation sheet, and summary
+// rows leave out.
+func WithHeaderRows(n int) Option {
+	return func(w *Writer) {
+		w.headerRows = n
+	}
+}
+
+// WithRowsPerFile sets the most rows, header rows included, that
+// SaveAsFiles and WriteToFiles put in one file. It defaults to 65,536, the
+// most a BIFF8 sheet can hold, which is also the largest value allowed.
+func WithRowsPerFile(n int) Option {
+	return func(w *Writer) {
+		w.rowsPerFile = n
+	}
+}
+
+// WriteToFiles writes data to as many XLS files as it takes to stay within
+// the rows of a sheet, or the limit set with WithRowsPerFile, and returns
+// their names. See Writer.SaveAsFiles.
+func WriteToFiles(pattern string, data [][]interface{}, opts ...Option) ([]string, error) {
+	w := New(opts...)
+	defer w.Close()
+
+	if err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	return w.SaveAsFiles(pattern)
+}
+
+// SaveAsFiles writes the sheet of a one-sheet workbook to numbered files,
+// each holding the header rows set with WithHeaderRows followed by the next
+// rows of the data, up to the limit set with WithRowsPerFile. The file
+// names are pattern formatted with the file number, starting at 1, so
+// pattern must hold one %d verb, as in "report_%03d.xls". Data that fits in
+// one file is written to file 1.
+//
+// Every file gets the column styles and widths, the view, print and
+// protection settings of the sheet, and the styles of the rows and cells
+// it holds; an AutoFilter that starts in the header rows covers the rows of
+// each file. SaveAsFiles returns the names of the files written so far with
+// the first error; with WithErrorCollection, the files with failed cells
+// are written and their *WriteErrors returned together.
+func (w *Writer) SaveAsFiles(pattern string) ([]string, error) {
+	if len(w.sheets) != 1 {
+		return nil, fmt.Errorf("xls: SaveAsFiles needs a workbook of one sheet, got %d", len(w.sheets))
+	}
+	if err := checkFilePattern(pattern); err != nil {
+		return nil, err
+	}
+	perFile := w.rowsPerFile
+	if perFile == 0 {
+		perFile = maxRow + 1
+	}
+	if err := checkSplit(w.headerRows, perFile); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	var cellErrs []error
+	for i, part := range w.sheets[0].split(w.headerRows, perFile-w.headerRows) {
+		fw := *w
+		fw.sheets = []*SheetWriter{part}
+		part.w = &fw
+		name := fmt.Sprintf(pattern, i+1)
+		if err := fw.SaveAs(name); err != nil {
+			var collected *WriteErrors
+			if !errors.As(err, &collected) || collected.Aborted {
+				return files, fmt.Errorf("file %s: %w", name, err)
+			}
+			cellErrs = append(cellErrs, fmt.Errorf("file %s: %w", name, err))
+		}
+		files = append(files, name)
+	}
+	return files, errors.Join(cellErrs...)
+}
+
+// checkFilePattern checks that a file name pattern formats one integer.
+func checkFilePattern(pattern string) error {
+	one, two := fmt.Sprintf(pattern, 1), fmt.Sprintf(pattern, 2)
+	if one == two || strings.Contains(one, "%!") {
+		return fmt.Errorf("xls: file pattern %q needs one %%d verb", pattern)
+	}
+	return nil
+}
+
+// checkSplit checks the header rows and the rows per part of a split.
+func checkSplit(headerRows, rowsPerPart int) error {
+	if headerRows < 0 {
+		return fmt.Errorf("%w: %d header rows", ErrOutOfRange, headerRows)
+	}
+	if rowsPerPart <= headerRows || rowsPerPart > maxRow+1 {
+		return fmt.Errorf("%w: %d rows per part with %d header rows, need more than %d and at most %d",
+			ErrOutOfRange, rowsPerPart, headerRows, headerRows, maxRow+1)
+	}
+	return nil
+}
+
+// split returns the sheet cut into parts of the header rows followed by up
+// to perPart rows of the rest, at least one. Each part keeps the settings
+// of the sheet, the styles of the header rows and those of its own rows;
+// styled rows beyond the data go to the last part.
+func (s *SheetWriter) split(header, perPart int) []*SheetWriter {
+	data := s.rowsData()
+	header = min(header, len(data))
+	n := max(1, (len(data)-header+perPart-1)/perPart)
+
+	// part returns the part of a row of the sheet, -1 for a header row, and
+	// its row in that part
+	part := func(row int) (int, int) {
+		if row < header {
+			return -1, row
+		}
+		p := min((row-header)/perPart, n-1)
+		return p, row - p*perPart
+	}
+
+	parts := make([]*SheetWriter, n)
+	for i := range parts {
+		p := *s
+		first, last := header+i*perPart, min(header+(i+1)*perPart, len(data))
+		p.data = append(data[:header:header], data[first:last]...)
+		p.dataOwned, p.cols = true, nil
+		p.schemaWarnings = nil
+		p.colStyles = maps.Clone(s.colStyles)
+		p.colWidths = maps.Clone(s.colWidths)
+		p.cellStyles, p.rowStyles, p.rowLevels = nil, nil, nil
+		// Range styles beyond the data go to the last part, as styled rows do
+		end := first + perPart - 1
+		if i == n-1 {
+			end = maxRow + i*perPart
+		}
+		p.ranges.set(append(s.ranges.rowRange(0, header-1, 0), s.ranges.rowRange(first, end, -i*perPart)...))
+		if f := s.autoFilter; f != nil {
+			p.autoFilter = nil
+			if f.firstRow < header {
+				r := *f
+				if r.lastRow >= header {
+					r.lastRow = max(len(p.data)-1, header-1)
+				}
+				p.autoFilter = &r
+			}
+		}
+		parts[i] = &p
+	}
+
+	for key, st := range s.cellStyles {
+		i, row := part(key[0])
+		for j, p := range parts {
+			if i == j || i < 0 {
+				if p.cellStyles == nil {
+					p.cellStyles = make(map[[2]int]Style)
+				}
+				p.cellStyles[[2]int{row, key[1]}] = st
+			}
+		}
+	}
+	for r, st := range s.rowStyles {
+		i, row := part(r)
+		for j, p := range parts {
+			if i == j || i < 0 {
+				if p.rowStyles == nil {
+					p.rowStyles = make(map[int]Style)
+				}
+				p.rowStyles[row] = st
+			}
+		}
+	}
+	for r, level := range s.rowLevels {
+		if i, row := part(r); i >= 0 {
+			if parts[i].rowLevels == nil {
+				parts[i].rowLevels = make(map[int]int)
+			}
+			parts[i].rowLevels[row] = level
+		}
+	}
+	return parts
+}
+
+// sheetSplit holds the settings of WithSheetSplitting.
+type sheetSplit struct {
+	rows    int
+	pattern string
+}
+
+// WithSheetSplitting moves the rows of a sheet beyond rowsPerSheet, header
+// rows included, to continuation sheets added after it, each starting with
+// the header rows set with WithHeaderRows. The continuation sheets are
+// named namePattern formatted with the name of the sheet and their number,
+// starting at 2, so with the default pattern "%s (%d)" the sheet "Data"
+// continues on "Data (2)", "Data (3)" and so on. They get the settings and styles of the
+// sheet as the files of SaveAsFiles do. rowsPerSheet is at most 65,536; an
+// invalid setting or continuation sheet name fails the write. The sheets of
+// the Writer are not changed.
+func WithSheetSplitting(rowsPerSheet int, namePattern string) Option {
+	return func(w *Writer) {
+		if namePattern == "" {
+			namePattern = "%s (%d)"
+		}
+		w.sheetSplit = &sheetSplit{rows: rowsPerSheet, pattern: namePattern}
+	}
+}
+
+// splitSheets returns the sheets of the workbook with those longer than the
+// rows per sheet of WithSheetSplitting followed by their continuation
+// sheets.
+func (w *Writer) splitSheets() ([]*SheetWriter, error) {
+	split := w.sheetSplit
+	if err := checkSplit(w.headerRows, split.rows); err != nil {
+		return nil, err
+	}
+	two, three := fmt.Sprintf(split.pattern, "Sheet", 2), fmt.Sprintf(split.pattern, "Sheet", 3)
+	if two == three || strings.Contains(two, "%!") {
+		return nil, fmt.Errorf("xls: sheet name pattern %q needs a %%s and a %%d verb", split.pattern)
+	}
+
+	// Continuation sheets are checked against all the names so far
+	orig := w.sheets
+	defer func() { w.sheets = orig }()
+	w.sheets = slices.Clone(orig)
+
+	var sheets []*SheetWriter
+	for _, s := range orig {
+		if s.rowCount() <= split.rows {
+			sheets = append(sheets, s)
+			continue
+		}
+		parts := s.split(w.headerRows, split.rows-w.headerRows)
+		sheets = append(sheets, parts[0])
+		for i, p := range parts[1:] {
+			p.name = fmt.Sprintf(split.pattern, s.name, i+2)
+			if err := w.checkSheetName(p.name, nil); err != nil {
+				return nil, fmt.Errorf("sheet %q: %w", s.name, err)
+			}
+			w.sheets = append(w.sheets, p)
+			sheets = append(sheets, p)
+		}
+	}
+	return sheets, nil
+}
@@ -0,0 +1,215 @@
+package xls
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func splitData(rows int) [][]interface{} {
+	data := [][]interface{}{{"ID", "Name"}}
+	for i := 1; i <= rows; i++ {
+		data = append(data, []interface{}{i, float64(i) / 2})
+	}
+	return data
+}
+
+func TestWriteToFiles(t *testing.T) {
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "report_%03d.xls")
+	files, err := WriteToFiles(pattern, splitData(140000), WithHeaderRows(1))
+	if err != nil {
+		t.Fatalf("WriteToFiles() failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "report_001.xls"),
+		filepath.Join(dir, "report_002.xls"),
+		filepath.Join(dir, "report_003.xls"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("Expected files %v, got %v", want, files)
+	}
+
+	next := 1.0
+	for i, file := range files {
+		wb, err := OpenFile(file)
+		if err != nil {
+			t.Fatalf("OpenFile(%s) failed: %v", file, err)
+		}
+		rows := wb.Sheets()[0].Rows()
+		if wantRows := []int{65536, 65536, 8931}[i]; len(rows) != wantRows {
+			t.Errorf("%s: expected %d rows, got %d", file, wantRows, len(rows))
+		}
+		if rows[0][0].Value != "ID" || rows[0][1].Value != "Name" {
+			t.Errorf("%s: expected the header row, got %v", file, rows[0])
+		}
+		for _, row := range rows[1:] {
+			if row[0].Value != next {
+				t.Fatalf("%s: expected row %v, got %v", file, next, row[0].Value)
+			}
+			next++
+		}
+	}
+	if next != 140001 {
+		t.Errorf("Expected all 140000 rows written, got %v", next-1)
+	}
+}
+
+func TestSaveAsFilesStyles(t *testing.T) {
+	header := Style{Font: Font{Bold: true}}
+	money := Style{NumberFormat: "#,##0.00"}
+	red := Style{Fill: ColorRed}
+
+	w := New(WithHeaderRows(1), WithRowsPerFile(4))
+	w.Write(splitData(7))
+	w.SetRowStyle(0, header)
+	w.SetColStyle(0, money)
+	w.sheets[0].SetColWidth(1, 30)
+	w.SetCellStyle(5, 1, red) // Row 2 of the second file
+	w.sheets[0].FreezePanes(1, 0)
+
+	files, err := w.SaveAsFiles(filepath.Join(t.TempDir(), "part%d.xls"))
+	if err != nil {
+		t.Fatalf("SaveAsFiles() failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %v", files)
+	}
+	for i, file := range files {
+		tw, err := OpenTemplate(file)
+		if err != nil {
+			t.Fatalf("OpenTemplate(%s) failed: %v", file, err)
+		}
+		s := tw.sheets[0]
+		if got := s.data[0][0]; got != "ID" {
+			t.Errorf("%s: expected the header row, got %v", file, s.data[0])
+		}
+		if wantRows := []int{4, 4, 2}[i]; len(s.data) != wantRows {
+			t.Errorf("%s: expected %d rows, got %d", file, wantRows, len(s.data))
+		}
+		if st := s.rowStyles[0]; !st.Font.Bold {
+			t.Errorf("%s: expected the header row style, got %+v", file, st)
+		}
+		if st := s.colStyles[0]; st.NumberFormat != money.NumberFormat {
+			t.Errorf("%s: expected the column style, got %+v", file, st)
+		}
+		if s.colWidths[1] != 30 || s.freezeRows != 1 {
+			t.Errorf("%s: expected the column width and frozen header, got %v and %d", file, s.colWidths[1], s.freezeRows)
+		}
+		_, styled := s.cellStyles[[2]int{2, 1}]
+		if styled != (i == 1) {
+			t.Errorf("%s: expected the cell style only in the second file, got %v", file, styled)
+		}
+	}
+}
+
+func TestSaveAsFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []Option
+		want    string
+	}{
+		{"no verb", "report.xls", nil, "needs one %d verb"},
+		{"string verb", "report_%s.xls", nil, "needs one %d verb"},
+		{"too many rows", "r%d.xls", []Option{WithRowsPerFile(65537)}, "65537 rows per part"},
+		{"headers only", "r%d.xls", []Option{WithHeaderRows(2), WithRowsPerFile(2)}, "2 rows per part with 2 header rows"},
+		{"negative headers", "r%d.xls", []Option{WithHeaderRows(-1)}, "-1 header rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := WriteToFiles(filepath.Join(dir, tt.pattern), splitData(3), tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	w := New()
+	w.AddSheet("Other")
+	if _, err := w.SaveAsFiles(filepath.Join(dir, "r%d.xls")); err == nil {
+		t.Error("Expected an error for a workbook of two sheets")
+	}
+}
+
+func TestSaveAsFilesSmall(t *testing.T) {
+	files, err := WriteToFiles(filepath.Join(t.TempDir(), "r%d.xls"), splitData(3), WithHeaderRows(1))
+	if err != nil || len(files) != 1 || !strings.HasSuffix(files[0], "r1.xls") {
+		t.Errorf("Expected one file, got %v, %v", files, err)
+	}
+}
+
+func TestSheetSplitting(t *testing.T) {
+	w := New(WithSheetName("Data"), WithHeaderRows(1), WithSheetSplitting(65536, ""))
+	w.Write(splitData(199999))
+	w.SetRowStyle(0, Style{Font: Font{Bold: true}})
+	small, _ := w.AddSheet("Summary")
+	small.Write([][]interface{}{{"Total", 199999}})
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if len(w.sheets) != 2 || len(w.sheets[0].data) != 200000 {
+		t.Errorf("Expected the sheets of the Writer unchanged, got %d sheets", len(w.sheets))
+	}
+
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	var names []string
+	for _, s := range wb.Sheets() {
+		names = append(names, s.Name)
+	}
+	if want := []string{"Data", "Data (2)", "Data (3)", "Data (4)", "Summary"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected sheets %v, got %v", want, names)
+	}
+	next := 1.0
+	for i, s := range wb.Sheets()[:4] {
+		rows := s.Rows()
+		if wantRows := []int{65536, 65536, 65536, 3395}[i]; len(rows) != wantRows {
+			t.Errorf("%s: expected %d rows, got %d", s.Name, wantRows, len(rows))
+		}
+		if rows[0][0].Value != "ID" {
+			t.Errorf("%s: expected the header row, got %v", s.Name, rows[0])
+		}
+		for _, row := range rows[1:] {
+			if row[0].Value != next {
+				t.Fatalf("%s: expected row %v, got %v", s.Name, next, row[0].Value)
+			}
+			next++
+		}
+	}
+	if next != 200000 {
+		t.Errorf("Expected all 199999 rows written, got %v", next-1)
+	}
+}
+
+func TestSheetSplittingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"too many rows", []Option{WithSheetSplitting(70000, "")}, "70000 rows per part"},
+		{"headers only", []Option{WithHeaderRows(3), WithSheetSplitting(3, "")}, "3 rows per part with 3 header rows"},
+		{"no number", []Option{WithSheetSplitting(2, "%s more")}, "needs a %s and a %d verb"},
+		{"used name", []Option{WithSheetSplitting(2, "")}, `"Sheet1 (3)" is already used`},
+		{"long name", []Option{WithSheetSplitting(2, "%s continued on sheet number %d")}, "more than 31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(tt.opts...)
+			w.Write(splitData(5))
+			w.AddSheet("Sheet1 (3)")
+			_, err := w.WriteTo(&bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package xls
+
+import (
+	"bytes"
+	"unicode/utf16"
+)
+
+// Stats describes the workbook a Writer currently holds.
+type Stats struct {
+	Sheets  int
+	Rows    int // Rows of data, including empty ones
+	Cells   int // Cells with a value
+	Strings int // Distinct strings in the shared string table
+	// EstimatedSize is the expected size of the XLS file in bytes, usually
+	// within a few percent of what SaveAs writes.
+	EstimatedSize int64
+}
+
+// Record sizes, including the 4-byte record header
+const (
+	rowRecordSize      = 4 + 16
+	labelSSTRecordSize = 4 + 10
+	numberRecordSize   = 4 + 14
+	boolErrRecordSize  = 4 + 8
+	blankRecordSize    = 4 + 6
+	xfRecordSize       = 4 + 20
+	colInfoRecordSize  = 4 + 12
+)
+
+// Stats returns statistics about the data held by the Writer. It does not
+// serialize the data; the size estimate adds up the records each row, cell,
+// string and style needs on top of the records every workbook has.
+func (w *Writer) Stats() Stats {
+	st := Stats{Sheets: len(w.sheets)}
+
+	// An invalid style position fails the write; ignore it here
+	_ = w.prepareStyles()
+
+	size := w.fixedRecordsSize()
+	for _, f := range w.styles.fonts {
+		size += 4 + 14 + len(encodeText(f.Name, 1, w.maxCompressed()))
+	}
+	for _, format := range w.styles.formats {
+		size += 4 + 5 + len(format)
+	}
+	size += xfRecordSize * len(w.styles.xfs)
+
+	sst := w.newStringTable()
+	for _, s := range w.sheets {
+		data := s.rowsData()
+		st.Rows += len(data)
+		size += colInfoRecordSize * (len(s.colInfos()) - len(s.bare(w).colInfos()))
+		size += rowRecordSize * len(s.sheetExtents().rows)
+
+		for r, row := range data {
+			for c, cell := range row {
+				if str, ok := s.renderedText(r, c, cell); ok {
+					cell = str
+				}
+				if sc, ok := cell.(StyledCell); ok {
+					cell = sc.Value
+				}
+				if cell == nil {
+					continue
+				}
+				if f, ok := w.numberText(cell); ok {
+					cell = f
+				}
+				st.Cells++
+				if str, ok := sstString(cell); ok {
+					if sst.inline(str) {
+						size += 4 + 6 + len(encodeText(str, 2, w.maxCompressed()))
+						continue
+					}
+					sst.addString(str)
+				}
+				size += cellRecordSize(cell)
+			}
+		}
+		for key := range s.cellStyles {
+			if !s.hasValue(key[0], key[1]) {
+				size += blankRecordSize
+			}
+		}
+		size += blankRecordSize * s.rangeBlanks()
+	}
+	for _, str := range sst.strings {
+		size += 3 + 2*len(utf16.Encode([]rune(str)))
+	}
+
+	st.Strings = sst.uniqueCount
+	st.EstimatedSize = int64(cfbFileSize(size, w.cfbSectorSize()))
+	return st
+}
+
+// Dimensions returns the extent of the used cells of the named sheet, as
+// written in its DIMENSIONS record: one past the last row and one past the
+// last column with a value or a styled blank cell. It returns zeros if the
+// sheet has no used cells or the Writer has no sheet of that name.
+func (w *Writer) Dimensions(sheet string) (rows, cols int) {
+	s, err := w.Sheet(sheet)
+	if err != nil {
+		return 0, 0
+	}
+	// An invalid style position fails the write; ignore it here
+	_ = w.prepareStyles()
+	ext := s.sheetExtents()
+	return ext.lastRow, ext.lastCol
+}
+
+// fixedRecordsSize measures the workbook stream of a workbook with the same
+// settings and sheets but no data or styles, which holds the records every
+// workbook has.
+func (w *Writer) fixedRecordsSize() int {
+	empty := *w
+	empty.logger, empty.recordCounts = nil, nil
+	empty.sheets = make([]*SheetWriter, len(w.sheets))
+	for i, s := range w.sheets {
+		empty.sheets[i] = s.bare(&empty)
+	}
+	var buf bytes.Buffer
+	if err := empty.writeBIFF8(&buf); err != nil {
+		return 0
+	}
+	return buf.Len()
+}
+
+// bare returns a copy of the sheet for w without its data and styles.
+func (s *SheetWriter) bare(w *Writer) *SheetWriter {
+	c := *s
+	c.w = w
+	c.data, c.cols = nil, nil
+	c.cellStyles, c.colStyles, c.rowStyles, c.rowLevels = nil, nil, nil, nil
+	c.ranges = rangeStyles{}
+	return &c
+}
+
+// cellRecordSize returns the size of the records writeCell writes for value.
+func cellRecordSize(value interface{}) int {
+	switch v := value.(type) {
+	case bool, CellError:
+		return boolErrRecordSize
+	case FormulaCell:
+		rgce, err := compileFormula(v.Expr)
+		if err != nil {
+			return 0
+		}
+		size := 4 + 22 + len(rgce)
+		if s, ok := v.Cached.(string); ok {
+			size += 4 + 3 + 2*len(utf16.Encode([]rune(s)))
+		}
+		return size
+	}
+	if _, ok := sstString(value); ok {
+		return labelSSTRecordSize
+	}
+	return numberRecordSize
+}
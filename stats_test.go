@@ -0,0 +1,115 @@
+package xls
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.Write([][]interface{}{
+		{"Name", "Age", nil},
+		{"Alice", 30, "Name"},
+		{},
+	})
+
+	st := w.Stats()
+	want := Stats{Sheets: 1, Rows: 3, Cells: 5, Strings: 3}
+	st.EstimatedSize = 0
+	if st != want {
+		t.Errorf("Expected %+v, got %+v", want, st)
+	}
+
+	if rows, cols := w.Dimensions("Sheet1"); rows != 2 || cols != 3 {
+		t.Errorf("Expected dimensions 2x3 without the empty last row, got %dx%d", rows, cols)
+	}
+	if rows, cols := w.Dimensions("Other"); rows != 0 || cols != 0 {
+		t.Errorf("Expected no dimensions for an unknown sheet, got %dx%d", rows, cols)
+	}
+}
+
+func TestStatsEstimatedSize(t *testing.T) {
+	large := make([][]interface{}, 5000)
+	for i := range large {
+		large[i] = []interface{}{fmt.Sprintf("Item %d", i%100), i, float64(i) / 3, i%2 == 0, time.Date(2024, 1, 1+i%28, 0, 0, 0, 0, time.UTC)}
+	}
+	formulas := [][]interface{}{
+		{1, 2, FormulaCell{Expr: "A1+B1", Cached: 3}},
+		{"x", FormulaCell{Expr: `IF(A1>0,"pos","neg")`, Cached: "pos"}, CellErrorNA},
+	}
+
+	tests := []struct {
+		name  string
+		build func() *Writer
+	}{
+		{"empty", func() *Writer { return New() }},
+		{"small", func() *Writer {
+			w := New(WithSheetName("日本語"))
+			w.Write([][]interface{}{{"日本語", "text"}, {1, 2.5}})
+			return w
+		}},
+		{"formulas", func() *Writer {
+			w := New()
+			w.Write(formulas)
+			return w
+		}},
+		{"large", func() *Writer {
+			w := New()
+			w.Write(large)
+			return w
+		}},
+		{"hybrid", func() *Writer {
+			w := New(WithHybridStrings(2))
+			w.Write(append(large[:len(large):len(large)], []interface{}{"unique", "日本語"}))
+			return w
+		}},
+		{"sheets", func() *Writer {
+			w := New()
+			w.Write(formulas)
+			w.SetColStyle(0, Style{Font: Font{Bold: true}})
+			first, _ := w.Sheet("Sheet1")
+			first.SetColWidth(0, 20)
+			first.SetColWidth(1, 20)
+			s, _ := w.AddSheet("Large", WithFreezePanes(1, 1), WithZoom(80), WithProtection("x"), WithColWidth(3, 12))
+			s.Write(large)
+			s.SetColStyle(4, Style{NumberFormat: "d-mmm"})
+			s.FormatAsTable(0, 3, 0, 4, TableStyle{AutoFilter: true})
+			return w
+		}},
+		{"styled", func() *Writer {
+			w := New()
+			w.Write(large)
+			w.SetColStyle(1, Style{NumberFormat: "#,##0"})
+			w.SetRowStyle(0, Style{Font: Font{Name: "Courier New", Bold: true}})
+			w.SetCellStyle(6000, 0, Style{Fill: ColorRed})
+			return w
+		}},
+		{"ranges", func() *Writer {
+			w := New()
+			w.Write(large)
+			w.SetRangeStyle(0, 4999, 0, 4, Style{Fill: ColorLightYellow})
+			w.SetRangeStyle(100, 199, 3, 6, Style{Fill: ColorRed})
+			return w
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.build()
+			defer w.Close()
+			estimate := w.Stats().EstimatedSize
+
+			var buf bytes.Buffer
+			n, err := w.WriteTo(&buf)
+			if err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+			if diff := math.Abs(float64(estimate-n)) / float64(n); diff > 0.05 {
+				t.Errorf("Estimated %d bytes, wrote %d (%.1f%% off)", estimate, n, diff*100)
+			}
+		})
+	}
+}
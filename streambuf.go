@@ -0,0 +1,105 @@
+package xls
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+// streamBuffer is what a substream is written to: a buffer, or the
+// temporary file of WriteChunks. Records written earlier, such as INDEX and
+// BOUNDSHEET, are patched at their positions once the positions they hold
+// are known.
+type streamBuffer interface {
+	io.Writer
+	io.ReaderAt
+	io.WriterAt
+	Len() int // Bytes written
+}
+
+// bufferAt is a streamBuffer in memory. WriteAt only overwrites bytes
+// already written.
+type bufferAt struct {
+	*bytes.Buffer
+}
+
+func (b bufferAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(b.Len()) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.Bytes()[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > int64(b.Len()) {
+		return 0, io.ErrShortWrite
+	}
+	return copy(b.Bytes()[off:], p), nil
+}
+
+// spillStream is a streamBuffer in a temporary file, written through a
+// buffer that is flushed before the file is read or patched.
+type spillStream struct {
+	file *os.File
+	w    *bufio.Writer
+	n    int
+}
+
+// newSpillStream returns a stream writing to file, which must be empty.
+func newSpillStream(file *os.File) *spillStream {
+	return &spillStream{file: file, w: bufio.NewWriterSize(file, spillBufferSize)}
+}
+
+func (s *spillStream) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.n += n
+	return n, err
+}
+
+func (s *spillStream) ReadAt(p []byte, off int64) (int, error) {
+	if err := s.w.Flush(); err != nil {
+		return 0, err
+	}
+	return s.file.ReadAt(p, off)
+}
+
+func (s *spillStream) WriteAt(p []byte, off int64) (int, error) {
+	if err := s.w.Flush(); err != nil {
+		return 0, err
+	}
+	return s.file.WriteAt(p, off)
+}
+
+func (s *spillStream) Len() int {
+	return s.n
+}
+
+// Flush writes the buffered bytes to the file.
+func (s *spillStream) Flush() error {
+	return s.w.Flush()
+}
+
+// sectionStream is the part of a streamBuffer from base on, as a stream of
+// its own: the substreams of WriteChunks share one file, and the positions
+// in each are relative to its start.
+type sectionStream struct {
+	streamBuffer
+	base int
+}
+
+func (s sectionStream) ReadAt(p []byte, off int64) (int, error) {
+	return s.streamBuffer.ReadAt(p, off+int64(s.base))
+}
+
+func (s sectionStream) WriteAt(p []byte, off int64) (int, error) {
+	return s.streamBuffer.WriteAt(p, off+int64(s.base))
+}
+
+func (s sectionStream) Len() int {
+	return s.streamBuffer.Len() - s.base
+}
@@ -0,0 +1,51 @@
+package xls
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestStreamBuffers(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "stream-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for name, buf := range map[string]streamBuffer{
+		"memory": bufferAt{new(bytes.Buffer)},
+		"file":   newSpillStream(file),
+	} {
+		buf.Write([]byte("head"))
+		section := sectionStream{buf, buf.Len()}
+		section.Write([]byte("0123456789"))
+		if section.Len() != 10 || buf.Len() != 14 {
+			t.Errorf("%s: expected 10 bytes in the section of 14, got %d of %d", name, section.Len(), buf.Len())
+		}
+
+		// Patched and read back relative to the section, before and after
+		// more writes
+		if _, err := section.WriteAt([]byte("ab"), 2); err != nil {
+			t.Fatalf("%s: WriteAt() failed: %v", name, err)
+		}
+		section.Write([]byte("tail"))
+		got := make([]byte, 6)
+		if _, err := section.ReadAt(got, 0); err != nil || string(got) != "01ab45" {
+			t.Errorf("%s: expected %q, got %q (%v)", name, "01ab45", got, err)
+		}
+		all := make([]byte, 18)
+		if _, err := buf.ReadAt(all, 0); err != nil || string(all) != "head01ab456789tail" {
+			t.Errorf("%s: expected the whole stream, got %q (%v)", name, all, err)
+		}
+		if _, err := buf.ReadAt(got, 16); err != io.EOF {
+			t.Errorf("%s: expected io.EOF reading past the end, got %v", name, err)
+		}
+	}
+
+	// A buffer is only patched where it was written
+	if _, err := (bufferAt{bytes.NewBufferString("abc")}).WriteAt([]byte("xy"), 2); err == nil {
+		t.Error("Expected an error patching past the end of a buffer")
+	}
+}
@@ -0,0 +1,274 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidMapping is returned by WriteStructs and WriteMaps for a column
+// mapping with a negative or repeated column index.
+var ErrInvalidMapping = errors.New("xls: invalid column mapping")
+
+// CellMarshaler is implemented by struct field types that choose how
+// WriteStructs writes them: MarshalCell returns the cell value and a style,
+// merged over the column and row styles as Styled does. A zero Style
+// leaves the cell unstyled. An error fails WriteStructs.
+type CellMarshaler interface {
+	MarshalCell() (interface{}, Style, error)
+}
+
+// RecordOption is a functional option for configuring WriteStructs and
+// WriteMaps.
+type RecordOption func(*recordConfig)
+
+type recordConfig struct {
+	mapping []ColumnMap
+	headers *HeaderPolicy
+}
+
+// ColumnMap places a struct field or map key in a column of the sheet.
+type ColumnMap struct {
+	// SourceKey is the field name, its xls tag name, or the map key.
+	SourceKey string
+	// Header is the header of the column; SourceKey if empty.
+	Header string
+	// Index is the zero-based column.
+	Index int
+}
+
+// WithColumnMapping selects, renames and orders the columns written by
+// WriteStructs and WriteMaps. Fields and keys without a mapping are
+// dropped, and columns between the mapped indices are left blank. A source
+// that does not exist gives a blank column, or an ErrMissingColumn error
+// with WithStrictTypes.
+func WithColumnMapping(mapping []ColumnMap) RecordOption {
+	return func(c *recordConfig) {
+		c.mapping = mapping
+	}
+}
+
+// WriteStructs sets the data of the first sheet from a slice of structs.
+// See SheetWriter.WriteStructs.
+func (w *Writer) WriteStructs(rows interface{}, opts ...RecordOption) error {
+	return w.sheets[0].WriteStructs(rows, opts...)
+}
+
+// WriteMaps sets the data of the first sheet from a slice of maps. See
+// SheetWriter.WriteMaps.
+func (w *Writer) WriteMaps(rows []map[string]interface{}, opts ...RecordOption) error {
+	return w.sheets[0].WriteMaps(rows, opts...)
+}
+
+// WriteStructs sets the data of the sheet from rows, a slice of structs or
+// of pointers to structs, with a header row of the field names followed by
+// a row per element. The exported fields are written in declaration order,
+// with those of embedded structs in place; a field tag such as
+// `xls:"Unit Price"` renames a column and `xls:"-"` leaves it out. Nil
+// pointers are blank cells, and other pointers are written as the value
+// they point to. Fields implementing CellMarshaler, directly or through a
+// pointer, are written as MarshalCell returns. The header cells get the
+// Header style of the theme. The data then goes through Write.
+func (s *SheetWriter) WriteStructs(rows interface{}, opts ...RecordOption) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("%w: WriteStructs needs a slice of structs, got %T", ErrUnsupportedCellType, rows)
+	}
+	typ := v.Type().Elem()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: WriteStructs needs a slice of structs, got %T", ErrUnsupportedCellType, rows)
+	}
+
+	var keys []string
+	var fields [][]int
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("xls"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		keys = append(keys, name)
+		fields = append(fields, f.Index)
+	}
+
+	records := make([][]interface{}, v.Len())
+	for i := range records {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		record := make([]interface{}, len(fields))
+		for j, index := range fields {
+			field, err := elem.FieldByIndexErr(index)
+			if err != nil {
+				continue // Field of a nil embedded pointer
+			}
+			value, err := marshalField(field)
+			if err != nil {
+				return fmt.Errorf("row %d, field %s: %w", i, keys[j], err)
+			}
+			record[j] = value
+		}
+		records[i] = record
+	}
+	return s.writeRecords(keys, records, opts)
+}
+
+// WriteMaps sets the data of the sheet from rows, with a header row of the
+// keys, in sorted order, followed by a row per map. A key missing from a
+// map is a blank cell. The header cells get the Header style of the theme.
+// The data then goes through Write.
+func (s *SheetWriter) WriteMaps(rows []map[string]interface{}, opts ...RecordOption) error {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	records := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		record := make([]interface{}, len(keys))
+		for j, key := range keys {
+			record[j] = row[key]
+		}
+		records[i] = record
+	}
+	return s.writeRecords(keys, records, opts)
+}
+
+// writeRecords writes records, whose values are in the order of keys, under
+// a header row, applying the column mapping and header policy of opts.
+func (s *SheetWriter) writeRecords(keys []string, records [][]interface{}, opts []RecordOption) error {
+	cfg := &recordConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// columns[c] is the index in keys of the source of column c, or -1
+	headers := keys
+	columns := make([]int, len(keys))
+	for i := range columns {
+		columns[i] = i
+	}
+	if cfg.mapping != nil {
+		width := 0
+		for _, m := range cfg.mapping {
+			if m.Index < 0 {
+				return fmt.Errorf("%w: negative index %d for %q", ErrInvalidMapping, m.Index, m.SourceKey)
+			}
+			width = max(width, m.Index+1)
+		}
+		headers = make([]string, width)
+		columns = slices.Repeat([]int{-1}, width)
+		mapped := make([]bool, width)
+		for _, m := range cfg.mapping {
+			if mapped[m.Index] {
+				return fmt.Errorf("%w: index %d mapped twice", ErrInvalidMapping, m.Index)
+			}
+			mapped[m.Index] = true
+			headers[m.Index] = m.Header
+			if m.Header == "" {
+				headers[m.Index] = m.SourceKey
+			}
+			columns[m.Index] = slices.Index(keys, m.SourceKey)
+			if columns[m.Index] < 0 && s.w.strict {
+				return fmt.Errorf("%w: no field or key %q", ErrMissingColumn, m.SourceKey)
+			}
+		}
+	}
+
+	if cfg.headers != nil {
+		headers = cfg.headers.apply(headers)
+	}
+
+	data := make([][]interface{}, 0, len(records)+1)
+	header := make([]interface{}, len(headers))
+	for c, h := range headers {
+		if h != "" {
+			header[c] = h
+		}
+		if s.w.theme.Header != (Style{}) {
+			header[c] = Styled(header[c], s.w.theme.Header)
+		}
+	}
+	data = append(data, header)
+	for _, record := range records {
+		row := make([]interface{}, len(columns))
+		if record != nil {
+			for c, source := range columns {
+				if source >= 0 {
+					row[c] = record[source]
+				}
+			}
+		}
+		data = append(data, row)
+	}
+	return s.Write(data)
+}
+
+// fieldValue returns the cell value of a struct field: nil for a nil
+// pointer or interface, and the value pointed to for other pointers.
+func fieldValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+// marshalField is fieldValue for WriteStructs: a CellMarshaler, or a
+// pointer to one, is written as MarshalCell returns.
+func marshalField(v reflect.Value) (interface{}, error) {
+	for {
+		isPointer := v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface
+		if isPointer && v.IsNil() {
+			return nil, nil
+		}
+		if m, ok := cellMarshaler(v); ok {
+			value, st, err := m.MarshalCell()
+			if err != nil || st == (Style{}) {
+				return value, err
+			}
+			return Styled(value, st), nil
+		}
+		if !isPointer {
+			return v.Interface(), nil
+		}
+		v = v.Elem()
+	}
+}
+
+// cellMarshaler returns v as a CellMarshaler, or its address if only the
+// pointer implements it.
+func cellMarshaler(v reflect.Value) (CellMarshaler, bool) {
+	if m, ok := v.Interface().(CellMarshaler); ok {
+		return m, true
+	}
+	if v.CanAddr() {
+		m, ok := v.Addr().Interface().(CellMarshaler)
+		return m, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,196 @@
+package xls
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type mappingBase struct {
+	ID int
+}
+
+type mappingOrder struct {
+	mappingBase
+	Customer string
+	Total    float64 `xls:"Amount"`
+	Note     *string
+	Secret   string `xls:"-"`
+	internal int
+}
+
+func mappingOrders() []mappingOrder {
+	note := "rush"
+	return []mappingOrder{
+		{mappingBase{1}, "Acme", 12.5, &note, "x", 0},
+		{mappingBase{2}, "Globex", 7, nil, "y", 0},
+	}
+}
+
+func TestWriteStructs(t *testing.T) {
+	w := New()
+	if err := w.WriteStructs(mappingOrders()); err != nil {
+		t.Fatalf("WriteStructs() failed: %v", err)
+	}
+	want := [][]interface{}{
+		{"ID", "Customer", "Amount", "Note"},
+		{1, "Acme", 12.5, "rush"},
+		{2, "Globex", 7.0, nil},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	orders := mappingOrders()
+	if err := w.WriteStructs([]*mappingOrder{&orders[0], nil}); err != nil {
+		t.Fatalf("WriteStructs() with pointers failed: %v", err)
+	}
+	if got := w.sheets[0].data[2]; !reflect.DeepEqual(got, make([]interface{}, 4)) {
+		t.Errorf("Expected a blank row for a nil element, got %v", got)
+	}
+
+	for _, rows := range []interface{}{mappingOrders()[0], []int{1}, nil} {
+		if err := w.WriteStructs(rows); !errors.Is(err, ErrUnsupportedCellType) {
+			t.Errorf("WriteStructs(%T): expected ErrUnsupportedCellType, got %v", rows, err)
+		}
+	}
+}
+
+// status flags overdue values in red.
+type status string
+
+func (s status) MarshalCell() (interface{}, Style, error) {
+	switch s {
+	case "overdue":
+		return "OVERDUE", Style{Fill: ColorRed}, nil
+	case "bad":
+		return nil, Style{}, errors.New("bad status")
+	}
+	return string(s), Style{}, nil
+}
+
+// cents is a CellMarshaler through its pointer.
+type cents int
+
+func (c *cents) MarshalCell() (interface{}, Style, error) {
+	return float64(*c) / 100, Style{NumberFormat: "#,##0.00"}, nil
+}
+
+type invoice struct {
+	Status status
+	Amount cents
+	Prev   *status
+}
+
+func TestWriteStructsCellMarshaler(t *testing.T) {
+	paid := status("paid")
+	w := New()
+	err := w.WriteStructs([]invoice{
+		{"overdue", 1250, &paid},
+		{"paid", 99, nil},
+	})
+	if err != nil {
+		t.Fatalf("WriteStructs() failed: %v", err)
+	}
+	money := Style{NumberFormat: "#,##0.00"}
+	want := [][]interface{}{
+		{"Status", "Amount", "Prev"},
+		{Styled("OVERDUE", Style{Fill: ColorRed}), Styled(12.5, money), "paid"},
+		{"paid", Styled(0.99, money), nil},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	err = w.WriteStructs([]invoice{{Status: "paid"}, {Status: "bad"}})
+	if err == nil || !strings.Contains(err.Error(), "row 1, field Status: bad status") {
+		t.Errorf("Expected the MarshalCell error with its position, got %v", err)
+	}
+}
+
+func TestWriteMaps(t *testing.T) {
+	w := New()
+	rows := []map[string]interface{}{
+		{"name": "Acme", "total": 12.5},
+		{"name": "Globex", "region": "EU"},
+	}
+	if err := w.WriteMaps(rows); err != nil {
+		t.Fatalf("WriteMaps() failed: %v", err)
+	}
+	want := [][]interface{}{
+		{"name", "region", "total"},
+		{"Acme", nil, 12.5},
+		{"Globex", "EU", nil},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestColumnMapping(t *testing.T) {
+	partnerA := []ColumnMap{
+		{SourceKey: "Amount", Header: "Total (EUR)", Index: 0},
+		{SourceKey: "ID", Header: "Order No", Index: 1},
+	}
+	partnerB := []ColumnMap{
+		{SourceKey: "Customer", Index: 0},
+		{SourceKey: "ID", Header: "Ref", Index: 3},
+		{SourceKey: "Region", Index: 2},
+	}
+
+	w := New()
+	b, _ := w.AddSheet("B")
+	if err := w.WriteStructs(mappingOrders(), WithColumnMapping(partnerA)); err != nil {
+		t.Fatalf("WriteStructs() with mapping A failed: %v", err)
+	}
+	if err := b.WriteStructs(mappingOrders(), WithColumnMapping(partnerB)); err != nil {
+		t.Fatalf("WriteStructs() with mapping B failed: %v", err)
+	}
+
+	wantA := [][]interface{}{
+		{"Total (EUR)", "Order No"},
+		{12.5, 1},
+		{7.0, 2},
+	}
+	wantB := [][]interface{}{
+		{"Customer", nil, "Region", "Ref"},
+		{"Acme", nil, nil, 1},
+		{"Globex", nil, nil, 2},
+	}
+	if got := w.sheets[0].data; !reflect.DeepEqual(got, wantA) {
+		t.Errorf("Mapping A: expected %v, got %v", wantA, got)
+	}
+	if got := b.data; !reflect.DeepEqual(got, wantB) {
+		t.Errorf("Mapping B: expected %v, got %v", wantB, got)
+	}
+
+	rows := []map[string]interface{}{{"sku": "X-1", "qty": 3}}
+	if err := w.WriteMaps(rows, WithColumnMapping([]ColumnMap{{SourceKey: "qty", Header: "Quantity", Index: 0}})); err != nil {
+		t.Fatalf("WriteMaps() with mapping failed: %v", err)
+	}
+	if got, want := w.sheets[0].data, [][]interface{}{{"Quantity"}, {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteMaps() with mapping: expected %v, got %v", want, got)
+	}
+}
+
+func TestColumnMappingErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		mapping []ColumnMap
+		want    error
+	}{
+		{"duplicate index", nil, []ColumnMap{{SourceKey: "ID", Index: 1}, {SourceKey: "Customer", Index: 1}}, ErrInvalidMapping},
+		{"negative index", nil, []ColumnMap{{SourceKey: "ID", Index: -1}}, ErrInvalidMapping},
+		{"missing source, strict", []Option{WithStrictTypes()}, []ColumnMap{{SourceKey: "Region", Index: 0}}, ErrMissingColumn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(tt.opts...)
+			if err := w.WriteStructs(mappingOrders(), WithColumnMapping(tt.mapping)); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,707 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Style describes the formatting of a cell. The zero value is the default
+// style: General number format, the font of the theme, no fill and no
+// borders. Styles are comparable and can be used as map keys.
+type Style struct {
+	// NumberFormat is an Excel number format such as "#,##0.00" or
+	// "yyyy-mm-dd". Empty means General.
+	NumberFormat string
+	Font         Font
+	HAlign       HAlign
+	VAlign       VAlign
+	WrapText     bool
+	// Fill is a solid background color. ColorAuto means no fill.
+	Fill   Color
+	Border Border
+}
+
+// Font describes the font of a cell.
+type Font struct {
+	Name      string  // Empty means that of the theme, Arial by default
+	Size      float64 // In points; 0 means that of the theme, 10 by default
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Strikeout bool
+	Color     Color
+}
+
+// Border describes the borders around a cell.
+type Border struct {
+	Top, Bottom, Left, Right BorderStyle
+	Color                    Color
+}
+
+// BorderStyle is the line style of a border.
+type BorderStyle uint8
+
+// Border styles
+const (
+	BorderNone BorderStyle = iota
+	BorderThin
+	BorderMedium
+	BorderDashed
+	BorderDotted
+	BorderThick
+	BorderDouble
+	BorderHair
+)
+
+// HAlign is the horizontal alignment of a cell.
+type HAlign uint8
+
+// Horizontal alignments
+const (
+	HAlignGeneral HAlign = iota
+	HAlignLeft
+	HAlignCenter
+	HAlignRight
+	HAlignFill
+	HAlignJustify
+)
+
+// VAlign is the vertical alignment of a cell.
+type VAlign uint8
+
+// Vertical alignments
+const (
+	VAlignBottom VAlign = iota
+	VAlignTop
+	VAlignCenter
+	VAlignJustify
+)
+
+// Color is an index into the default BIFF8 color palette.
+type Color uint16
+
+// Colors of the default palette
+const (
+	ColorAuto        Color = 0
+	ColorBlack       Color = 8
+	ColorWhite       Color = 9
+	ColorRed         Color = 10
+	ColorBrightGreen Color = 11
+	ColorBlue        Color = 12
+	ColorYellow      Color = 13
+	ColorMagenta     Color = 14
+	ColorCyan        Color = 15
+	ColorDarkRed     Color = 16
+	ColorGreen       Color = 17
+	ColorNavy        Color = 18
+	ColorOlive       Color = 19
+	ColorPurple      Color = 20
+	ColorTeal        Color = 21
+	ColorSilver      Color = 22
+	ColorGray        Color = 23
+	ColorSkyBlue     Color = 40
+	ColorLightCyan   Color = 41
+	ColorLightGreen  Color = 42
+	ColorLightYellow Color = 43
+	ColorPaleBlue    Color = 44
+	ColorRose        Color = 45
+	ColorLavender    Color = 46
+	ColorTan         Color = 47
+	ColorLightBlue   Color = 48
+	ColorAqua        Color = 49
+	ColorLime        Color = 50
+	ColorGold        Color = 51
+	ColorLightOrange Color = 52
+	ColorOrange      Color = 53
+	ColorBlueGray    Color = 54
+	ColorGray40      Color = 55
+)
+
+const (
+	firstCustomFont   = 8    // After the default fonts 0-3 and 5-7; index 4 does not exist
+	firstCustomFormat = 0xA6 // After fmtDate and fmtDateTime
+	firstCustomXF     = 20   // After the 16 style XFs and 4 cell XFs
+	defaultColWidth   = 2340 // 8.43 characters in 1/256 of a character
+	colorSystemText   = 0x40 // Default foreground palette index
+	colorSystemBack   = 0x41 // Default background palette index
+	fontColorAuto     = 0x7FFF
+	maxColumn         = 0xFF
+	maxRow            = 0xFFFF
+)
+
+// styleTable assigns FONT, FORMAT and XF indices to the styles used by a
+// workbook.
+type styleTable struct {
+	fonts     []Font
+	fontIndex map[Font]uint16
+	formats   []string
+	formatIdx map[string]uint16
+	xfs       []Style
+	xfIndex   map[Style]uint16
+
+	// defaultFont is FONT 0, the font of styles without a font name or
+	// size: 10pt Arial unless a Theme sets another
+	defaultFont Font
+
+	// The records of the styles that come from a StyleSet, which take the
+	// first indices
+	prepared styleRecords
+}
+
+func newStyleTable() *styleTable {
+	return &styleTable{
+		fontIndex:   make(map[Font]uint16),
+		formatIdx:   make(map[string]uint16),
+		xfIndex:     make(map[Style]uint16),
+		defaultFont: ThemeDefault.font(),
+	}
+}
+
+// builtinFormatIndex maps built-in format strings to their indices.
+var builtinFormatIndex = func() map[string]uint16 {
+	m := make(map[string]uint16, len(builtinFormats))
+	for index, format := range builtinFormats {
+		m[format] = index
+	}
+	return m
+}()
+
+// xf returns the XF index for s, adding it to the table if needed.
+func (t *styleTable) xf(s Style) uint16 {
+	if index, ok := t.xfIndex[s]; ok {
+		return index
+	}
+	t.font(s.Font)
+	t.format(s.NumberFormat)
+	index := uint16(firstCustomXF + len(t.xfs))
+	t.xfs = append(t.xfs, s)
+	t.xfIndex[s] = index
+	return index
+}
+
+func (t *styleTable) font(f Font) uint16 {
+	if f.Name == "" {
+		f.Name = t.defaultFont.Name
+	}
+	if f.Size == 0 {
+		f.Size = t.defaultFont.Size
+	}
+	if f == t.defaultFont {
+		return 0
+	}
+	if index, ok := t.fontIndex[f]; ok {
+		return index
+	}
+	index := uint16(firstCustomFont + len(t.fonts))
+	t.fonts = append(t.fonts, f)
+	t.fontIndex[f] = index
+	return index
+}
+
+func (t *styleTable) format(s string) uint16 {
+	switch s {
+	case "":
+		return 0
+	case "yyyy-mm-dd":
+		return fmtDate
+	case "yyyy-mm-dd hh:mm:ss":
+		return fmtDateTime
+	}
+	if index, ok := builtinFormatIndex[s]; ok {
+		return index
+	}
+	if index, ok := t.formatIdx[s]; ok {
+		return index
+	}
+	index := uint16(firstCustomFormat + len(t.formats))
+	t.formats = append(t.formats, s)
+	t.formatIdx[s] = index
+	return index
+}
+
+// StyledCell is a cell value with its own style. The style is merged over
+// the style the cell has otherwise, from SetCellStyle, SetColStyle or
+// SetRowStyle: its fields that are set replace those of that style, so
+// Styled(v, Style{Fill: ColorRed}) keeps the number format of the column.
+// A nil Value writes a blank cell that carries the style.
+type StyledCell struct {
+	Value interface{}
+	Style Style
+}
+
+// Styled returns value with style s, for use in the data passed to Write.
+func Styled(value interface{}, s Style) StyledCell {
+	return StyledCell{Value: value, Style: s}
+}
+
+// SetCellStyle sets the style of the cell at the zero-based row and column
+// of the first sheet. See SheetWriter.SetCellStyle.
+func (w *Writer) SetCellStyle(row, col int, st Style) {
+	w.sheets[0].SetCellStyle(row, col, st)
+}
+
+// SetRowStyle sets the default style of a zero-based row of the first
+// sheet. See SheetWriter.SetRowStyle.
+func (w *Writer) SetRowStyle(row int, st Style) {
+	w.sheets[0].SetRowStyle(row, st)
+}
+
+// SetColStyle sets the default style of a zero-based column of the first
+// sheet. See SheetWriter.SetColStyle.
+func (w *Writer) SetColStyle(col int, st Style) {
+	w.sheets[0].SetColStyle(col, st)
+}
+
+// SetCellStyle sets the style of the cell at the zero-based row and column.
+// It takes precedence over column and row styles. A cell without a value is
+// written as a blank cell carrying the style.
+func (s *SheetWriter) SetCellStyle(row, col int, st Style) {
+	if s.cellStyles == nil {
+		s.cellStyles = make(map[[2]int]Style)
+	}
+	s.cellStyles[[2]int{row, col}] = st
+}
+
+// SetRowStyle sets the default style of a zero-based row. It applies to
+// written cells without a cell or column style and, through the ROW record,
+// to cells users fill in later.
+func (s *SheetWriter) SetRowStyle(row int, st Style) {
+	if s.rowStyles == nil {
+		s.rowStyles = make(map[int]Style)
+	}
+	s.rowStyles[row] = st
+}
+
+// SetColStyle sets the default style of a zero-based column. It applies to
+// written cells without a cell style, taking precedence over row styles,
+// and, through the COLINFO
+// record, to cells users fill in later.
+func (s *SheetWriter) SetColStyle(col int, st Style) {
+	if s.colStyles == nil {
+		s.colStyles = make(map[int]Style)
+	}
+	s.colStyles[col] = st
+}
+
+// cellStyle resolves the style of a cell: its own style, else that of its
+// range, else its column's, else its row's.
+func (s *SheetWriter) cellStyle(row, col int) (Style, bool) {
+	if st, ok := s.ownStyle(row, col); ok {
+		return st, true
+	}
+	if st, ok := s.colStyles[col]; ok {
+		return st, true
+	}
+	if st, ok := s.rowStyles[row]; ok {
+		return st, true
+	}
+	return Style{}, false
+}
+
+// blankXF returns the XF index of an empty cell with an explicit style, or
+// false if the cell has no value and is not written.
+func (s *SheetWriter) blankXF(row, col int, value interface{}) (uint16, bool) {
+	switch v := value.(type) {
+	case nil:
+		if st, ok := s.ownStyle(row, col); ok {
+			return s.w.styles.xf(st), true
+		}
+	case StyledCell:
+		if v.Value == nil {
+			_, st, _ := s.resolveStyle(row, col, v)
+			return s.w.styles.xf(st), true
+		}
+	}
+	return 0, false
+}
+
+// styledBlankColumns returns, per row, the sorted columns with a cell style.
+func (s *SheetWriter) styledBlankColumns() map[int][]int {
+	cols := make(map[int][]int)
+	for key := range s.cellStyles {
+		cols[key[0]] = append(cols[key[0]], key[1])
+	}
+	for _, c := range cols {
+		sort.Ints(c)
+	}
+	return cols
+}
+
+// rowXF returns the XF index of a styled row.
+func (s *SheetWriter) rowXF(row int) (uint16, bool) {
+	st, ok := s.rowStyles[row]
+	if !ok {
+		return 0, false
+	}
+	return s.w.styles.xf(st), true
+}
+
+// resolveStyle returns the value of a cell without its StyledCell, and its
+// style: that of cellStyle, with the style of a StyledCell merged over it.
+// It returns false for a cell without a style.
+func (s *SheetWriter) resolveStyle(row, col int, value interface{}) (interface{}, Style, bool) {
+	st, ok := s.cellStyle(row, col)
+	if sc, styled := value.(StyledCell); styled {
+		return sc.Value, sc.Style.over(st), true
+	}
+	return value, st, ok
+}
+
+// over returns base with the fields set in st replacing its own. Boolean
+// fields can only be turned on.
+func (st Style) over(base Style) Style {
+	if st.NumberFormat != "" {
+		base.NumberFormat = st.NumberFormat
+	}
+	f := st.Font
+	if f.Name != "" {
+		base.Font.Name = f.Name
+	}
+	if f.Size != 0 {
+		base.Font.Size = f.Size
+	}
+	base.Font.Bold = base.Font.Bold || f.Bold
+	base.Font.Italic = base.Font.Italic || f.Italic
+	base.Font.Underline = base.Font.Underline || f.Underline
+	base.Font.Strikeout = base.Font.Strikeout || f.Strikeout
+	if f.Color != ColorAuto {
+		base.Font.Color = f.Color
+	}
+	if st.HAlign != HAlignGeneral {
+		base.HAlign = st.HAlign
+	}
+	if st.VAlign != VAlignBottom {
+		base.VAlign = st.VAlign
+	}
+	base.WrapText = base.WrapText || st.WrapText
+	if st.Fill != ColorAuto {
+		base.Fill = st.Fill
+	}
+	b := st.Border
+	if b.Top != BorderNone {
+		base.Border.Top = b.Top
+	}
+	if b.Bottom != BorderNone {
+		base.Border.Bottom = b.Bottom
+	}
+	if b.Left != BorderNone {
+		base.Border.Left = b.Left
+	}
+	if b.Right != BorderNone {
+		base.Border.Right = b.Right
+	}
+	if b.Color != ColorAuto {
+		base.Border.Color = b.Color
+	}
+	return base
+}
+
+// cellXF returns the XF index of a cell. Dates without a number format in
+// their style are given the date format of the theme, numbers the fixed-decimal format of
+// GeneralFixed and text with line breaks the wrap flag.
+func (s *SheetWriter) cellXF(row, col int, value interface{}) uint16 {
+	if f, ok := s.generalNumber(row, col, value); ok && s.w.general.Mode == GeneralFixed {
+		_, st, _ := s.resolveStyle(row, col, value)
+		st.NumberFormat = fixedFormat(f)
+		return s.w.styles.xf(st)
+	}
+	value, st, ok := s.resolveStyle(row, col, value)
+	if s.w.wrapsText(value) {
+		st.WrapText, ok = true, true
+	}
+	t, isTime := value.(time.Time)
+	if !ok {
+		if !isTime {
+			return 0
+		}
+		if s.w.theme.builtinDates() {
+			return dateXF(t)
+		}
+	}
+	if isTime && st.NumberFormat == "" {
+		st.NumberFormat = s.w.theme.dateFormat(t)
+	}
+	return s.w.styles.xf(st)
+}
+
+// dateXF returns the built-in XF for a date, with a time-of-day format only
+// if the time has one.
+func dateXF(t time.Time) uint16 {
+	if hasTimeOfDay(t) {
+		return xfDateTime
+	}
+	return xfDate
+}
+
+func hasTimeOfDay(t time.Time) bool {
+	return t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 || t.Nanosecond() != 0
+}
+
+// prepareStyles validates style positions and assigns XF indices to every
+// style the sheets use, in a deterministic order after the styles of the
+// StyleSet, before the globals that declare them are written.
+func (w *Writer) prepareStyles() error {
+	w.styles = newStyleTable()
+	if w.styleSet != nil {
+		w.styles = w.styleSet.table(w.maxCompressed())
+	}
+	w.styles.defaultFont = w.theme.font()
+	for _, s := range w.sheets {
+		if err := s.prepareStyles(); err != nil {
+			return fmt.Errorf("sheet %q: %w", s.name, err)
+		}
+	}
+	if n := firstCustomXF + len(w.styles.xfs); n > maxXFs {
+		return fmt.Errorf("%w: %d XF records, more than %d", ErrOutOfRange, n, maxXFs)
+	}
+	for _, st := range w.styles.xfs {
+		if err := checkStyleText(st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareStyles validates the style positions of the sheet and registers its
+// styles: column styles by column, row styles by row, styles in the data,
+// range styles in the order they were set, then cell styles by position.
+func (s *SheetWriter) prepareStyles() error {
+	cells := s.styledCells()
+	for _, key := range cells {
+		if key[0] < 0 || key[0] > maxRow || key[1] < 0 || key[1] > maxColumn {
+			return fmt.Errorf("%w: cell style position (%d, %d)", ErrOutOfRange, key[0], key[1])
+		}
+	}
+	for _, col := range s.styledColumns() {
+		if col < 0 || col > maxColumn {
+			return fmt.Errorf("%w: column style index %d", ErrOutOfRange, col)
+		}
+		s.w.styles.xf(s.colStyles[col])
+	}
+	for _, row := range s.styledRows() {
+		if row < 0 || row > maxRow {
+			return fmt.Errorf("%w: row style index %d", ErrOutOfRange, row)
+		}
+		s.w.styles.xf(s.rowStyles[row])
+	}
+
+	if s.cols != nil {
+		s.prepareColumnStyles()
+	}
+	for rowIndex, row := range s.data {
+		for colIndex, cell := range row {
+			s.cellXF(rowIndex, colIndex, cell)
+			if rt, ok := richText(cell); ok {
+				// Errors fail the cell when it is written
+				_, _ = s.richRuns(rowIndex, colIndex, cell, rt)
+			}
+		}
+	}
+	for _, r := range s.ranges.list {
+		s.w.styles.xf(r.style)
+	}
+	for _, key := range cells {
+		s.w.styles.xf(s.cellStyles[key])
+	}
+	return nil
+}
+
+// styledCells returns the positions with a cell style in (row, col) order.
+func (s *SheetWriter) styledCells() [][2]int {
+	keys := make([][2]int, 0, len(s.cellStyles))
+	for key := range s.cellStyles {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+func (s *SheetWriter) styledColumns() []int {
+	cols := make([]int, 0, len(s.colStyles))
+	for col := range s.colStyles {
+		cols = append(cols, col)
+	}
+	sort.Ints(cols)
+	return cols
+}
+
+func (s *SheetWriter) styledRows() []int {
+	rows := make([]int, 0, len(s.rowStyles))
+	for row := range s.rowStyles {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+// writeBlanks writes a run of empty styled cells starting at col, as a
+// BLANK record for one cell and a MULBLANK record for several.
+func (w *Writer) writeBlanks(writer io.Writer, row rowIdx, col colIdx, xfs []uint16) error {
+	if len(xfs) == 1 {
+		data := make([]byte, 6)
+		binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+		binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+		binary.LittleEndian.PutUint16(data[4:6], xfs[0])
+		return w.writeRecord(writer, recTypeBLANK, data)
+	}
+
+	data := make([]byte, 6+len(xfs)*2)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	for i, xf := range xfs {
+		binary.LittleEndian.PutUint16(data[4+i*2:], xf)
+	}
+	binary.LittleEndian.PutUint16(data[len(data)-2:], uint16(col)+uint16(len(xfs))-1)
+	return w.writeRecord(writer, recTypeMULBLANK, data)
+}
+
+// writeStyleFonts writes the FONT records of the styles, copying those a
+// StyleSet prepared.
+func (w *Writer) writeStyleFonts(writer io.Writer) error {
+	for i, f := range w.styles.fonts {
+		if i < len(w.styles.prepared.fonts) {
+			if err := w.writeRecord(writer, recTypeFONT, w.styles.prepared.fonts[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.writeFont(writer, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeFont(writer io.Writer, f Font) error {
+	return w.writeRecord(writer, recTypeFONT, fontRecord(f, w.maxCompressed()))
+}
+
+// fontRecord returns the body of a FONT record for f, with the characters
+// of its name up to maxCompressed written as 8-bit characters.
+func fontRecord(f Font, maxCompressed rune) []byte {
+	name := encodeText(f.Name, 1, maxCompressed)
+
+	data := make([]byte, 14+len(name))
+	binary.LittleEndian.PutUint16(data[0:2], uint16(math.Round(f.Size*20))) // Height in 1/20 points
+	grbit := uint16(0)
+	if f.Italic {
+		grbit |= 0x0002
+	}
+	if f.Strikeout {
+		grbit |= 0x0008
+	}
+	binary.LittleEndian.PutUint16(data[2:4], grbit)
+	color := uint16(f.Color)
+	if f.Color == ColorAuto {
+		color = fontColorAuto
+	}
+	binary.LittleEndian.PutUint16(data[4:6], color)
+	weight := uint16(400)
+	if f.Bold {
+		weight = 700
+	}
+	binary.LittleEndian.PutUint16(data[6:8], weight)
+	if f.Underline {
+		data[10] = 1 // Single underline
+	}
+	data[12] = 1 // Character set (1 = default)
+	copy(data[14:], name)
+	return data
+}
+
+// writeStyleFormats writes the FORMAT records of the styles, copying those
+// a StyleSet prepared.
+func (w *Writer) writeStyleFormats(writer io.Writer) error {
+	for i, s := range w.styles.formats {
+		if i < len(w.styles.prepared.formats) {
+			if err := w.writeRecord(writer, recTypeFORMAT, w.styles.prepared.formats[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.writeFormat(writer, uint16(firstCustomFormat+i), s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStyleXFs writes the cell XF records of the styles, copying those a
+// StyleSet prepared.
+func (w *Writer) writeStyleXFs(writer io.Writer) error {
+	for i, s := range w.styles.xfs {
+		if i < len(w.styles.prepared.xfs) {
+			if err := w.writeRecord(writer, recTypeXF, w.styles.prepared.xfs[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.writeStyleXF(writer, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStyleXF writes a cell XF record for s.
+func (w *Writer) writeStyleXF(writer io.Writer, s Style) error {
+	return w.writeRecord(writer, recTypeXF, w.styles.xfRecord(s))
+}
+
+// xfRecord returns the body of a cell XF record for s, whose font and
+// number format are in t.
+func (t *styleTable) xfRecord(s Style) []byte {
+	data := make([]byte, 20)
+	binary.LittleEndian.PutUint16(data[0:2], t.font(s.Font))
+	binary.LittleEndian.PutUint16(data[2:4], t.format(s.NumberFormat))
+	binary.LittleEndian.PutUint16(data[4:6], 0x0001) // Locked, parent style XF #0
+
+	vertical := [...]byte{2, 0, 1, 3}[s.VAlign&0x03]
+	align := byte(s.HAlign&0x07) | vertical<<4
+	if s.WrapText {
+		align |= 0x08
+	}
+	data[6] = align
+	data[9] = 0xFC // Number format, font, alignment, border, fill and protection are set
+
+	borderColor := uint32(s.Border.Color)
+	if s.Border.Color == ColorAuto {
+		borderColor = colorSystemText
+	}
+	lines := uint32(s.Border.Left&0x0F) | uint32(s.Border.Right&0x0F)<<4 |
+		uint32(s.Border.Top&0x0F)<<8 | uint32(s.Border.Bottom&0x0F)<<12
+	if s.Border.Left != BorderNone {
+		lines |= borderColor << 16
+	}
+	if s.Border.Right != BorderNone {
+		lines |= borderColor << 23
+	}
+	binary.LittleEndian.PutUint32(data[10:14], lines)
+
+	var topBottom uint32
+	if s.Border.Top != BorderNone {
+		topBottom |= borderColor
+	}
+	if s.Border.Bottom != BorderNone {
+		topBottom |= borderColor << 7
+	}
+	fore, back := uint16(colorSystemText), uint16(colorSystemBack)
+	if s.Fill != ColorAuto {
+		topBottom |= 1 << 26 // Solid fill pattern
+		fore = uint16(s.Fill)
+		back = colorSystemBack
+	}
+	binary.LittleEndian.PutUint32(data[14:18], topBottom)
+	binary.LittleEndian.PutUint16(data[18:20], fore&0x7F|(back&0x7F)<<7)
+
+	return data
+}
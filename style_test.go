@@ -0,0 +1,428 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writtenRecords writes w and returns the records of its workbook stream.
+func writtenRecords(t *testing.T, w *Writer) []Record {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	stream, err := workbookStream(buf.Bytes())
+	if err != nil {
+		t.Fatalf("workbookStream() failed: %v", err)
+	}
+	records, err := readAllRecords(stream)
+	if err != nil {
+		t.Fatalf("readAllRecords() failed: %v", err)
+	}
+	return records
+}
+
+// cellXFs maps the (row, col) of every cell record to its XF index.
+func cellXFs(records []Record) map[[2]int]uint16 {
+	xfs := make(map[[2]int]uint16)
+	for _, rec := range records {
+		switch rec.Type {
+		case recTypeLABELSST, recTypeNUMBER, recTypeBOOLERR, recTypeFORMULA, recTypeBLANK:
+			row := int(binary.LittleEndian.Uint16(rec.Data[0:2]))
+			col := int(binary.LittleEndian.Uint16(rec.Data[2:4]))
+			xfs[[2]int{row, col}] = binary.LittleEndian.Uint16(rec.Data[4:6])
+		}
+	}
+	return xfs
+}
+
+func recordsOfType(records []Record, recType uint16) []Record {
+	var out []Record
+	for _, rec := range records {
+		if rec.Type == recType {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func TestSetColStyle(t *testing.T) {
+	w := New()
+	defer w.Close()
+	money := Style{NumberFormat: "#,##0.00", Font: Font{Bold: true}}
+	w.SetColStyle(1, money)
+	w.SetColStyle(2, money)
+	w.Write([][]interface{}{
+		{"Item", 1234.5, 2},
+		{"Other", 10, 20},
+	})
+
+	records := writtenRecords(t, w)
+
+	colInfos := recordsOfType(records, recTypeCOLINFO)
+	if len(colInfos) != 1 {
+		t.Fatalf("Expected 1 COLINFO record for adjacent columns with one style, got %d", len(colInfos))
+	}
+	data := colInfos[0].Data
+	first := binary.LittleEndian.Uint16(data[0:2])
+	last := binary.LittleEndian.Uint16(data[2:4])
+	xf := binary.LittleEndian.Uint16(data[6:8])
+	if first != 1 || last != 2 || xf != firstCustomXF {
+		t.Errorf("Expected COLINFO columns 1-2 with XF %d, got %d-%d with XF %d", firstCustomXF, first, last, xf)
+	}
+	if width := binary.LittleEndian.Uint16(data[4:6]); width != defaultColWidth {
+		t.Errorf("Expected default width %d, got %d", defaultColWidth, width)
+	}
+
+	if n := len(recordsOfType(records, recTypeXF)); n != firstCustomXF+1 {
+		t.Errorf("Expected %d XF records, got %d", firstCustomXF+1, n)
+	}
+	if n := len(recordsOfType(records, recTypeFONT)); n != 8 {
+		t.Errorf("Expected 8 FONT records, got %d", n)
+	}
+
+	xfs := cellXFs(records)
+	for key, want := range map[[2]int]uint16{{0, 0}: 0, {0, 1}: firstCustomXF, {1, 2}: firstCustomXF} {
+		if got := xfs[key]; got != want {
+			t.Errorf("Cell %v: expected XF %d, got %d", key, want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	w.WriteTo(&buf)
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	s, _ := wb.Sheet("Sheet1")
+	if got := s.Cell(0, 1).FormatString; got != "#,##0.00" {
+		t.Errorf("Expected format %q, got %q", "#,##0.00", got)
+	}
+}
+
+func TestStylePrecedence(t *testing.T) {
+	w := New()
+	defer w.Close()
+	col := Style{Fill: ColorLightYellow}
+	cell := Style{Font: Font{Italic: true}}
+	w.SetColStyle(0, col)
+	w.SetCellStyle(1, 0, cell)
+	w.SetCellStyle(0, 1, cell)
+	w.Write([][]interface{}{
+		{"a", "b", "c"},
+		{"d", "e", "f"},
+	})
+
+	xfs := cellXFs(writtenRecords(t, w))
+	colXF, cellXF := uint16(firstCustomXF), uint16(firstCustomXF+1)
+	want := map[[2]int]uint16{
+		{0, 0}: colXF,  // Column style
+		{1, 0}: cellXF, // Cell style overrides the column style
+		{0, 1}: cellXF, // Cell style
+		{1, 1}: 0,      // Default
+	}
+	for key, xf := range want {
+		if got := xfs[key]; got != xf {
+			t.Errorf("Cell %v: expected XF %d, got %d", key, xf, got)
+		}
+	}
+}
+
+func TestStyledDates(t *testing.T) {
+	w := New()
+	defer w.Close()
+	w.SetColStyle(0, Style{Font: Font{Bold: true}})
+	w.SetColStyle(1, Style{NumberFormat: "d-mmm-yy"})
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	w.Write([][]interface{}{{day, day}})
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	s, _ := wb.Sheet("Sheet1")
+	if cell := s.Cell(0, 0); !cell.IsDate() || cell.FormatString != "yyyy-mm-dd" {
+		t.Errorf("Expected a styled date without a number format to keep yyyy-mm-dd, got %q", cell.FormatString)
+	}
+	if cell := s.Cell(0, 1); !cell.IsDate() || cell.FormatString != "d-mmm-yy" {
+		t.Errorf("Expected the style's number format, got %q", cell.FormatString)
+	}
+}
+
+// TestBuiltinFormats checks that built-in number formats are referenced
+// by index without FORMAT records and custom ones are numbered from 164.
+func TestBuiltinFormats(t *testing.T) {
+	formats := []string{"", "General", "0%", "0.00%", "m/d/yy", "m/d/yy h:mm", "0.0%", "yyyy-mm-dd"}
+	want := []uint16{0, 0, 9, 10, 14, 22, 166, 164}
+	w := New()
+	defer w.Close()
+	row := make([]interface{}, len(formats))
+	for col, format := range formats {
+		w.SetColStyle(col, Style{NumberFormat: format, Font: Font{Italic: true}})
+		row[col] = 0.5
+	}
+	w.Write([][]interface{}{row, {time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}})
+
+	records := writtenRecords(t, w)
+	customs := make(map[uint16]string)
+	for _, rec := range recordsOfType(records, recTypeFORMAT) {
+		index := binary.LittleEndian.Uint16(rec.Data[0:2])
+		if index < 164 {
+			t.Errorf("Expected no FORMAT record for the built-in format %d", index)
+		}
+		customs[index] = string(rec.Data[5:])
+	}
+	wantCustoms := map[uint16]string{164: "yyyy-mm-dd", 165: "yyyy-mm-dd hh:mm:ss", 166: "0.0%"}
+	if fmt.Sprint(customs) != fmt.Sprint(wantCustoms) {
+		t.Errorf("Expected the FORMAT records %v, got %v", wantCustoms, customs)
+	}
+
+	xfs := recordsOfType(records, recTypeXF)
+	ifmt := func(cell [2]int) uint16 {
+		return binary.LittleEndian.Uint16(xfs[cellXFs(records)[cell]].Data[2:4])
+	}
+	for col, index := range want {
+		if got := ifmt([2]int{0, col}); got != index {
+			t.Errorf("Format %q: expected the XF format index %d, got %d", formats[col], index, got)
+		}
+	}
+	if got := ifmt([2]int{1, 0}); got != fmtDate {
+		t.Errorf("Expected a date to use format %d, got %d", fmtDate, got)
+	}
+	for i := range 16 {
+		if got := binary.LittleEndian.Uint16(xfs[i].Data[2:4]); got != 0 {
+			t.Errorf("Style XF %d: expected General, got format %d", i, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	s, _ := wb.Sheet("Sheet1")
+	for col, format := range formats {
+		if format == "" {
+			format = "General"
+		}
+		cell := s.Cell(0, col)
+		if cell.FormatString != format {
+			t.Errorf("Column %d: expected the format %q, got %q", col, format, cell.FormatString)
+		}
+		if isDate := col == 4 || col == 5 || col == 7; cell.IsDate() != isDate {
+			t.Errorf("Column %d: expected IsDate() %v", col, isDate)
+		}
+	}
+}
+
+func TestStyledCellMerge(t *testing.T) {
+	money := Style{NumberFormat: "#,##0.00", HAlign: HAlignRight, Font: Font{Bold: true}}
+	w := New()
+	defer w.Close()
+	w.SetColStyle(1, money)
+	w.SetRowStyle(1, Style{Font: Font{Italic: true}})
+	red := Style{Fill: ColorRed, Border: Border{Top: BorderThin}}
+	w.Write([][]interface{}{
+		{"a", Styled(1.5, red)},
+		{Styled("b", red), Styled(nil, Style{Font: Font{Color: ColorRed}})},
+	})
+
+	records := writtenRecords(t, w)
+	xfs := cellXFs(records)
+	want := map[[2]int]Style{
+		{0, 1}: {NumberFormat: "#,##0.00", HAlign: HAlignRight, Font: Font{Bold: true}, Fill: ColorRed, Border: Border{Top: BorderThin}},
+		{1, 0}: {Font: Font{Italic: true}, Fill: ColorRed, Border: Border{Top: BorderThin}},
+		{1, 1}: {NumberFormat: "#,##0.00", HAlign: HAlignRight, Font: Font{Bold: true, Color: ColorRed}},
+	}
+	for cell, st := range want {
+		if got := w.styles.xfs[xfs[cell]-firstCustomXF]; got != st {
+			t.Errorf("Cell %v: expected %+v, got %+v", cell, st, got)
+		}
+	}
+}
+
+func TestStyleOver(t *testing.T) {
+	base := Style{
+		NumberFormat: "0.00", Font: Font{Name: "Courier New", Size: 9, Bold: true, Color: ColorBlue},
+		HAlign: HAlignCenter, VAlign: VAlignTop, WrapText: true, Fill: ColorYellow,
+		Border: Border{Top: BorderThin, Left: BorderThin, Color: ColorBlue},
+	}
+	if got := (Style{}).over(base); got != base {
+		t.Errorf("Expected an empty style to keep %+v, got %+v", base, got)
+	}
+	st := Style{
+		NumberFormat: "0%", Font: Font{Size: 12, Italic: true, Color: ColorRed},
+		HAlign: HAlignLeft, Fill: ColorRed, Border: Border{Left: BorderThick, Bottom: BorderThin},
+	}
+	want := Style{
+		NumberFormat: "0%", Font: Font{Name: "Courier New", Size: 12, Bold: true, Italic: true, Color: ColorRed},
+		HAlign: HAlignLeft, VAlign: VAlignTop, WrapText: true, Fill: ColorRed,
+		Border: Border{Top: BorderThin, Left: BorderThick, Bottom: BorderThin, Color: ColorBlue},
+	}
+	if got := st.over(base); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if got := base.over(Style{}); got != base {
+		t.Errorf("Expected %+v over an empty style, got %+v", base, got)
+	}
+}
+
+// TestWriteToFileStyled writes a styled cell without the Writer API.
+func TestWriteToFileStyled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "red.xls")
+	data := [][]interface{}{
+		{"invoice", "status"},
+		{1042, Styled("overdue", Style{Fill: ColorRed})},
+	}
+	if err := WriteToFile(path, data); err != nil {
+		t.Fatalf("WriteToFile() failed: %v", err)
+	}
+	w, err := OpenTemplate(path)
+	if err != nil {
+		t.Fatalf("OpenTemplate() failed: %v", err)
+	}
+	defer w.Close()
+	styles := w.sheets[0].cellStyles
+	if st := styles[[2]int{1, 1}]; st.Fill != ColorRed {
+		t.Errorf("Expected a red cell, got %+v", st)
+	}
+	if len(styles) != 1 {
+		t.Errorf("Expected one styled cell, got %v", styles)
+	}
+}
+
+func TestStyleOutOfRange(t *testing.T) {
+	for name, setup := range map[string]func(w *Writer){
+		"column": func(w *Writer) { w.SetColStyle(256, Style{}) },
+		"cell":   func(w *Writer) { w.SetCellStyle(65536, 0, Style{}) },
+		"row":    func(w *Writer) { w.SetRowStyle(-1, Style{}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := New()
+			defer w.Close()
+			setup(w)
+			var buf bytes.Buffer
+			if _, err := w.WriteTo(&buf); err == nil {
+				t.Error("Expected an error for a style out of range")
+			}
+		})
+	}
+}
+
+func TestSetRowStyle(t *testing.T) {
+	w := New()
+	defer w.Close()
+	header := Style{Font: Font{Bold: true}, Fill: ColorSilver}
+	w.SetRowStyle(0, header)
+	w.SetRowStyle(5, header)
+	w.SetColStyle(1, Style{NumberFormat: "0.00"})
+	w.SetCellStyle(0, 2, Style{Font: Font{Italic: true}})
+	w.Write([][]interface{}{
+		{"Name", "Score", "Note"},
+		{"Alice", 9.5, "ok"},
+	})
+
+	records := writtenRecords(t, w)
+	rowXF, colXF, cellXF := uint16(firstCustomXF+1), uint16(firstCustomXF), uint16(firstCustomXF+2)
+
+	rows := make(map[uint16]Record)
+	for _, rec := range recordsOfType(records, recTypeROW) {
+		rows[binary.LittleEndian.Uint16(rec.Data[0:2])] = rec
+	}
+	for row, want := range map[uint16]uint32{
+		0: uint32(rowXF)<<16 | 0x0180,
+		1: 0x000F0000,
+		5: uint32(rowXF)<<16 | 0x0180,
+	} {
+		rec, ok := rows[row]
+		if !ok {
+			t.Errorf("Expected a ROW record for row %d", row)
+			continue
+		}
+		flags := binary.LittleEndian.Uint32(rec.Data[12:16])
+		if flags != want {
+			t.Errorf("Row %d: expected flags 0x%08X, got 0x%08X", row, want, flags)
+		}
+		if xf := uint16(flags>>16) & 0x0FFF; flags&0x80 != 0 && xf != rowXF {
+			t.Errorf("Row %d: expected ixfe %d, got %d", row, rowXF, xf)
+		}
+	}
+	if colMac := binary.LittleEndian.Uint16(rows[5].Data[4:6]); colMac != 0 {
+		t.Errorf("Expected an empty styled row, got last column %d", colMac)
+	}
+
+	xfs := cellXFs(records)
+	want := map[[2]int]uint16{
+		{0, 0}: rowXF,  // Row style
+		{0, 1}: colXF,  // Column style overrides the row style
+		{0, 2}: cellXF, // Cell style overrides the row style
+		{1, 0}: 0,      // Default
+		{1, 1}: colXF,  // Column style
+	}
+	for key, xf := range want {
+		if got := xfs[key]; got != xf {
+			t.Errorf("Cell %v: expected XF %d, got %d", key, xf, got)
+		}
+	}
+}
+
+func TestStyledBlankCells(t *testing.T) {
+	w := New()
+	defer w.Close()
+	yellow := Style{Fill: ColorLightYellow}
+	gray := Style{Fill: ColorGray40}
+	w.SetCellStyle(0, 4, gray)
+	w.SetCellStyle(3, 1, gray)
+	w.Write([][]interface{}{
+		{"a", nil, "c"},
+		{Styled(nil, yellow), Styled(nil, yellow), Styled(nil, gray), nil, Styled("x", yellow)},
+	})
+
+	records := writtenRecords(t, w)
+	yellowXF, grayXF := uint16(firstCustomXF), uint16(firstCustomXF+1)
+
+	blanks := recordsOfType(records, recTypeBLANK)
+	if len(blanks) != 2 {
+		t.Fatalf("Expected 2 BLANK records, got %d", len(blanks))
+	}
+	for i, want := range [][3]uint16{{0, 4, grayXF}, {3, 1, grayXF}} {
+		d := blanks[i].Data
+		got := [3]uint16{binary.LittleEndian.Uint16(d[0:2]), binary.LittleEndian.Uint16(d[2:4]), binary.LittleEndian.Uint16(d[4:6])}
+		if got != want {
+			t.Errorf("BLANK %d: expected row, col, xf %v, got %v", i, want, got)
+		}
+	}
+
+	mulBlanks := recordsOfType(records, recTypeMULBLANK)
+	if len(mulBlanks) != 1 {
+		t.Fatalf("Expected 1 MULBLANK record, got %d", len(mulBlanks))
+	}
+	d := mulBlanks[0].Data
+	want := []uint16{1, 0, yellowXF, yellowXF, grayXF, 2}
+	for i, v := range want {
+		if got := binary.LittleEndian.Uint16(d[i*2:]); got != v {
+			t.Errorf("MULBLANK field %d: expected %d, got %d", i, v, got)
+		}
+	}
+	if xfs := cellXFs(records); xfs[[2]int{1, 4}] != yellowXF {
+		t.Errorf("Expected the styled value to use XF %d, got %d", yellowXF, xfs[[2]int{1, 4}])
+	}
+
+	var buf bytes.Buffer
+	w.WriteTo(&buf)
+	if err := Verify(buf.Bytes(), "Sheet1", [][]interface{}{{"a", nil, "c"}, {nil, nil, nil, nil, "x"}}); err != nil {
+		t.Errorf("Styled blanks do not read back as blank: %v", err)
+	}
+}
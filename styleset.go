@@ -0,0 +1,117 @@
+package xls
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// StyleSet is a set of styles shared by Writers, such as a pool of Writers
+// producing many files with the same few styles. Its styles are prepared
+// once, including their FONT, FORMAT and XF records, and take the same XF
+// indices in every file written with WithStyleSet, so their handles are
+// valid across those Writers. A StyleSet is safe for concurrent use; it is
+// meant to be filled before it is attached and only read afterwards.
+type StyleSet struct {
+	mu     sync.RWMutex
+	styles *styleTable
+
+	// The records of the styles, with strings compressed up to 0xFF and up
+	// to 0x7F, as written without and with a code page
+	records [2]styleRecords
+}
+
+// styleRecords holds the bodies of the FONT, FORMAT and XF records of the
+// fonts, formats and styles of a styleTable, in order.
+type styleRecords struct {
+	fonts, formats, xfs [][]byte
+}
+
+// StyleHandle identifies a style of a StyleSet. It is the index of the XF
+// record of the style in every file written with the set.
+type StyleHandle uint16
+
+// NewStyleSet returns an empty StyleSet.
+func NewStyleSet() *StyleSet {
+	return &StyleSet{styles: newStyleTable()}
+}
+
+// Add adds st to the set, if it is not in it yet, and returns its handle.
+// Styles with text longer than Excel allows fail the writes of the Writers
+// using the set, as they would when used directly.
+func (set *StyleSet) Add(st Style) StyleHandle {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	t := set.styles
+	h := StyleHandle(t.xf(st))
+	for i, maxCompressed := range [...]rune{0xFF, 0x7F} {
+		r := &set.records[i]
+		for _, f := range t.fonts[len(r.fonts):] {
+			r.fonts = append(r.fonts, fontRecord(f, maxCompressed))
+		}
+		for j := len(r.formats); j < len(t.formats); j++ {
+			r.formats = append(r.formats, formatRecord(uint16(firstCustomFormat+j), t.formats[j], maxCompressed))
+		}
+		for _, s := range t.xfs[len(r.xfs):] {
+			r.xfs = append(r.xfs, t.xfRecord(s))
+		}
+	}
+	return h
+}
+
+// Style returns the style of h, or the default style if h is not a handle
+// of the set.
+func (set *StyleSet) Style(h StyleHandle) Style {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	i := int(h) - firstCustomXF
+	if i < 0 || i >= len(set.styles.xfs) {
+		return Style{}
+	}
+	return set.styles.xfs[i]
+}
+
+// Styled returns value with the style of h, for use in the data passed to
+// Write.
+func (set *StyleSet) Styled(value interface{}, h StyleHandle) StyledCell {
+	return Styled(value, set.Style(h))
+}
+
+// Len returns the number of styles in the set.
+func (set *StyleSet) Len() int {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	return len(set.styles.xfs)
+}
+
+// WithStyleSet attaches a StyleSet to the Writer. Every style of the set is
+// written in each file, first, at the XF index of its handle, from records
+// the set prepared once; the styles the Writer uses otherwise follow. A
+// style of the data equal to one of the set shares its XF record.
+func WithStyleSet(set *StyleSet) Option {
+	return func(w *Writer) {
+		w.styleSet = set
+	}
+}
+
+// table returns a copy of the style table of the set, for a Writer to add
+// its own styles to, with the records of the set for strings compressed up
+// to maxCompressed.
+func (set *StyleSet) table(maxCompressed rune) *styleTable {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	t := set.styles
+	records := set.records[0]
+	if maxCompressed != 0xFF {
+		records = set.records[1]
+	}
+	return &styleTable{
+		fonts:     slices.Clone(t.fonts),
+		fontIndex: maps.Clone(t.fontIndex),
+		formats:   slices.Clone(t.formats),
+		formatIdx: maps.Clone(t.formatIdx),
+		xfs:       slices.Clone(t.xfs),
+		xfIndex:   maps.Clone(t.xfIndex),
+		prepared:  records,
+	}
+}
@@ -0,0 +1,110 @@
+package xls
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	setHeader = Style{Font: Font{Bold: true, Name: "Sérif"}, Fill: ColorLightYellow}
+	setMoney  = Style{NumberFormat: "#,##0.00 €"}
+	setDate   = Style{NumberFormat: "yyyy-mm-dd", Border: Border{Bottom: BorderThin}}
+)
+
+func newTestStyleSet() (*StyleSet, [3]StyleHandle) {
+	set := NewStyleSet()
+	return set, [3]StyleHandle{set.Add(setHeader), set.Add(setMoney), set.Add(setDate)}
+}
+
+func TestStyleSet(t *testing.T) {
+	set, h := newTestStyleSet()
+	if h != [3]StyleHandle{firstCustomXF, firstCustomXF + 1, firstCustomXF + 2} {
+		t.Errorf("Expected handles from %d, got %v", firstCustomXF, h)
+	}
+	if again := set.Add(setMoney); again != h[1] || set.Len() != 3 {
+		t.Errorf("Expected the handle of the style already added, got %d of %d styles", again, set.Len())
+	}
+	if set.Style(h[2]) != setDate || set.Style(0) != (Style{}) || set.Style(firstCustomXF+3) != (Style{}) {
+		t.Error("Expected Style to return the style of a handle and the default otherwise")
+	}
+
+	// The file is the one written without the set, with its styles first
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"UTF-16", nil},
+		{"code page", []Option{WithCodePage(1252)}},
+	} {
+		opts := tt.opts
+		with := New(append(opts, WithStyleSet(set))...)
+		with.Write([][]interface{}{{set.Styled("Total", h[0]), set.Styled(9.5, h[1]), set.Styled(date, h[2])}})
+		without := New(opts...)
+		without.Write([][]interface{}{{Styled("Total", setHeader), Styled(9.5, setMoney), Styled(date, setDate)}})
+		var got, want bytes.Buffer
+		if _, err := with.WriteTo(&got); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		if _, err := without.WriteTo(&want); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%s: expected the file written without the set", tt.name)
+		}
+	}
+
+	// Styles of the Writer follow those of the set, used or not
+	w := New(WithStyleSet(set))
+	w.Write([][]interface{}{{Styled(1, Style{Fill: ColorRed}), set.Styled(2, h[2])}})
+	sheet := readBack(t, w)
+	if got := sheet.Cell(0, 0).xf; got != firstCustomXF+3 {
+		t.Errorf("Expected the Writer's style at XF %d, got %d", firstCustomXF+3, got)
+	}
+	if got := sheet.Cell(0, 1).xf; got != uint16(h[2]) {
+		t.Errorf("Expected the set's style at XF %d, got %d", h[2], got)
+	}
+	if got := sheet.Cell(0, 1).FormatString; got != "yyyy-mm-dd" {
+		t.Errorf("Expected the set's number format, got %q", got)
+	}
+}
+
+func TestStyleSetConcurrentWriters(t *testing.T) {
+	set, h := newTestStyleSet()
+	write := func(i int) []byte {
+		w := New(WithStyleSet(set))
+		w.Write([][]interface{}{
+			{set.Styled("Name", h[0]), set.Styled("Amount", h[0])},
+			{fmt.Sprintf("row %d", i), set.Styled(float64(i), h[1])},
+		})
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Errorf("WriteTo() failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	files := make([][]byte, 16)
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Go(func() {
+			files[i] = write(i)
+		})
+	}
+	wg.Wait()
+	for i, file := range files {
+		if !bytes.Equal(file, write(i)) {
+			t.Errorf("Writer %d: expected the file written alone", i)
+		}
+		wb, err := openWorkbook(file)
+		if err != nil {
+			t.Fatalf("openWorkbook() failed: %v", err)
+		}
+		if got := wb.Sheets()[0].Cell(1, 1).xf; got != uint16(h[1]) {
+			t.Errorf("Writer %d: expected XF %d, got %d", i, h[1], got)
+		}
+	}
+}
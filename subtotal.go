@@ -0,0 +1,204 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrUnsortedGroups is returned by Write with WithGroupSubtotals for data
+// whose rows of a group are not together, unless WithGroupSorting is given.
+var ErrUnsortedGroups = errors.New("xls: rows not sorted by group")
+
+// SubtotalOption is a functional option for configuring WithGroupSubtotals.
+type SubtotalOption func(*subtotals)
+
+// subtotals holds the settings of WithGroupSubtotals.
+type subtotals struct {
+	groupCol int
+	sumCols  []int
+	formulas bool
+	sort     bool
+}
+
+// Outline levels of the rows of WithGroupSubtotals
+const (
+	subtotalLevel = 1
+	detailLevel   = 2
+)
+
+// WithSubtotalFormulas makes subtotal and grand total cells SUBTOTAL
+// formulas over their rows, with the sums cached, instead of the sums
+// alone. The grand total's formulas skip the subtotals in their range, so
+// they stay right when rows are collapsed or filtered.
+func WithSubtotalFormulas() SubtotalOption {
+	return func(c *subtotals) {
+		c.formulas = true
+	}
+}
+
+// WithGroupSorting sorts the data rows by the group column, as Excel sorts
+// cells and keeping the order of the rows of a group, instead of returning
+// ErrUnsortedGroups for data that is not sorted.
+func WithGroupSorting() SubtotalOption {
+	return func(c *subtotals) {
+		c.sort = true
+	}
+}
+
+// WithGroupSubtotals makes Write insert a subtotal row after each run of
+// data rows with the same value in the zero-based groupCol, those after the
+// header rows set with WithHeaderRows, and append a grand total row, with
+// the sums of the columns sumCols.
+//
+// As with Excel's Subtotal command, the group column of a subtotal row
+// reads "East Total" for the group "East" and that of the last row "Grand
+// Total". Subtotal rows are bold, and the grand total is styled as a
+// summary row, see WithSummaryStyle; WithSummaryRow would count the
+// subtotals twice. The rows are outlined, so that each group can be
+// collapsed to its subtotal and the whole data to the grand total.
+func WithGroupSubtotals(groupCol int, sumCols []int, opts ...SubtotalOption) Option {
+	cfg := &subtotals{groupCol: groupCol, sumCols: slices.Clone(sumCols)}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(w *Writer) {
+		w.subtotals = cfg
+	}
+}
+
+// subtotalGroup is a run of data rows with the same group value.
+type subtotalGroup struct {
+	first, last int // Rows of the data, last exclusive
+	label       string
+}
+
+// addSubtotals inserts the subtotal and grand total rows of cfg into the
+// data and outlines its rows.
+func (s *SheetWriter) addSubtotals(cfg *subtotals) error {
+	width := 0
+	for _, col := range append([]int{cfg.groupCol}, cfg.sumCols...) {
+		if _, _, err := cellIndex(0, col); err != nil {
+			return err
+		}
+		width = max(width, col+1)
+	}
+	first := min(s.w.headerRows, len(s.data))
+	if first == len(s.data) {
+		return nil
+	}
+
+	keys := make([]crosstabKey, len(s.data)-first)
+	labels := make([]interface{}, len(keys))
+	for i, row := range s.data[first:] {
+		key, label, err := newCrosstabKey(cellAtIndex(row, cfg.groupCol))
+		if err != nil {
+			return fmt.Errorf("row %d: %w", first+i, err)
+		}
+		keys[i], labels[i] = key, label
+		width = max(width, len(row))
+	}
+	if cfg.sort {
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			return keys[a].compare(keys[b])
+		})
+		data := append(s.data[:first:first], make([][]interface{}, len(order))...)
+		sortedKeys := make([]crosstabKey, len(order))
+		sortedLabels := make([]interface{}, len(order))
+		for i, j := range order {
+			data[first+i], sortedKeys[i], sortedLabels[i] = s.data[first+j], keys[j], labels[j]
+		}
+		s.data, s.dataOwned = data, true
+		keys, labels = sortedKeys, sortedLabels
+	}
+
+	var groups []subtotalGroup
+	seen := make(map[crosstabKey]bool)
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			groups[len(groups)-1].last++
+			continue
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: row %d is apart from the rows of its group %v", ErrUnsortedGroups, first+i, labels[i])
+		}
+		seen[key] = true
+		groups = append(groups, subtotalGroup{first: first + i, last: first + i + 1, label: groupLabel(labels[i])})
+	}
+	if rows := len(s.data) + len(groups) + 1; rows > maxRow+1 {
+		return fmt.Errorf("%w: %d rows with subtotals, the most is %d", ErrOutOfRange, rows, maxRow+1)
+	}
+
+	subtotal := func(int) Style { return s.w.theme.Subtotal }
+	data := make([][]interface{}, 0, len(s.data)+len(groups)+1)
+	data = append(data, s.data[:first]...)
+	levels := make(map[int]int)
+	for _, g := range groups {
+		start := len(data)
+		for _, row := range s.data[g.first:g.last] {
+			levels[len(data)] = detailLevel
+			data = append(data, row)
+		}
+		levels[len(data)] = subtotalLevel
+		data = append(data, s.subtotalRow(cfg, width, g, start, len(data), subtotal))
+	}
+	total := subtotalGroup{first: first, last: len(s.data), label: "Grand Total"}
+	data = append(data, s.subtotalRow(cfg, width, total, first, len(data), s.summaryStyle))
+
+	s.data, s.dataOwned, s.rowLevels = data, true, levels
+	return nil
+}
+
+// subtotalRow returns the subtotal row of the data rows of g, whose
+// formulas sum the rows from to, exclusive, once the subtotals are
+// inserted.
+func (s *SheetWriter) subtotalRow(cfg *subtotals, width int, g subtotalGroup, from, to int, style func(col int) Style) []interface{} {
+	cells := make([]interface{}, width)
+	for col := range cells {
+		st := style(col)
+		var value interface{}
+		switch {
+		case col == cfg.groupCol:
+			value = g.label
+		case slices.Contains(cfg.sumCols, col):
+			sum, dates := s.aggregate(Sum, col, g.first, g.last)
+			if dates && st.NumberFormat == "" {
+				st.NumberFormat = s.w.theme.DateFormat
+			}
+			value = sum
+			if sum != nil && cfg.formulas {
+				value = FormulaCell{
+					Expr:   fmt.Sprintf("SUBTOTAL(9,%s%d:%s%d)", columnName(col), from+1, columnName(col), to),
+					Cached: sum,
+				}
+			}
+		}
+		cells[col] = Styled(value, st)
+	}
+	return cells
+}
+
+// groupLabel returns the label of the subtotal row of a group.
+func groupLabel(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "Total"
+	case time.Time:
+		return v.Format("2006-01-02") + " Total"
+	}
+	return fmt.Sprint(value) + " Total"
+}
+
+// outlineLevel returns the deepest outline level of the rows of the sheet.
+func (s *SheetWriter) outlineLevel() int {
+	level := 0
+	for _, l := range s.rowLevels {
+		level = max(level, l)
+	}
+	return level
+}
@@ -0,0 +1,127 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// rowLevels returns the outline levels of the ROW records of records.
+func rowLevels(records []Record) map[int]int {
+	levels := make(map[int]int)
+	for _, rec := range recordsOfType(records, recTypeROW) {
+		row := int(binary.LittleEndian.Uint16(rec.Data[0:2]))
+		levels[row] = int(rec.Data[12] & 0x07)
+	}
+	return levels
+}
+
+var subtotalData = [][]interface{}{
+	{"Dept", "Name", "Salary", "Bonus"},
+	{"Sales", "Ann", 100, 10},
+	{"Sales", "Bob", 200},
+	{"Support", "Cid", 50, 5},
+}
+
+func TestWithGroupSubtotals(t *testing.T) {
+	w := New(WithHeaderRows(1), WithGroupSubtotals(0, []int{2, 3}), WithPostWriteVerification())
+	if err := w.Write(subtotalData); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	records := writtenRecords(t, w)
+	sheet := readBack(t, w)
+
+	want := [][]interface{}{
+		{"Dept", "Name", "Salary", "Bonus"},
+		{"Sales", "Ann", 100.0, 10.0},
+		{"Sales", "Bob", 200.0, nil},
+		{"Sales Total", nil, 300.0, 10.0},
+		{"Support", "Cid", 50.0, 5.0},
+		{"Support Total", nil, 50.0, 5.0},
+		{"Grand Total", nil, 350.0, 15.0},
+	}
+	for r, row := range want {
+		for c, value := range row {
+			if got := sheet.Cell(r, c); got.Value != value {
+				t.Errorf("Cell (%d, %d): expected %v, got %v", r, c, value, got.Value)
+			}
+		}
+	}
+
+	levels := rowLevels(records)
+	for r, level := range []int{0, 2, 2, 1, 2, 1, 0} {
+		if levels[r] != level {
+			t.Errorf("Row %d: expected outline level %d, got %d", r, level, levels[r])
+		}
+	}
+	guts := recordsOfType(records, recTypeGUTS)
+	if len(guts) != 1 || binary.LittleEndian.Uint16(guts[0].Data[4:6]) != 3 {
+		t.Errorf("Expected GUTS with 3 row levels, got %v", guts)
+	}
+
+	xfs := cellXFs(records)
+	if xfs[[2]int{3, 2}] == xfs[[2]int{1, 2}] || xfs[[2]int{6, 2}] == xfs[[2]int{3, 2}] {
+		t.Errorf("Expected subtotal and grand total rows styled apart from the data, got %v", xfs)
+	}
+
+	// The levels move with their rows
+	if err := w.InsertRow(0, []interface{}{"Salaries"}); err != nil {
+		t.Fatalf("InsertRow() failed: %v", err)
+	}
+	if levels := rowLevels(writtenRecords(t, w)); levels[0] != 0 || levels[2] != 2 || levels[4] != 1 {
+		t.Errorf("Expected the outline levels to move down, got %v", levels)
+	}
+}
+
+func TestSubtotalFormulas(t *testing.T) {
+	w := New(WithHeaderRows(1), WithGroupSubtotals(0, []int{2}, WithSubtotalFormulas()), WithPostWriteVerification())
+	if err := w.Write(subtotalData); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	sheet := readBack(t, w)
+	for r, want := range map[int]struct {
+		formula string
+		value   float64
+	}{
+		3: {"SUBTOTAL(9,C2:C3)", 300},
+		5: {"SUBTOTAL(9,C5:C5)", 50},
+		6: {"SUBTOTAL(9,C2:C6)", 350},
+	} {
+		if got := sheet.Cell(r, 2); got.Kind != KindFormula || got.Formula != want.formula || got.Value != want.value {
+			t.Errorf("Row %d: expected %s = %v, got %s %q = %v", r, want.formula, want.value, got.Kind, got.Formula, got.Value)
+		}
+	}
+	if got := sheet.Cell(3, 3); got.Kind != KindBlank {
+		t.Errorf("Expected no subtotal for a column not summed, got %s %v", got.Kind, got.Value)
+	}
+}
+
+func TestSubtotalsUnsorted(t *testing.T) {
+	data := [][]interface{}{
+		{"Dept", "Amount"},
+		{"b", 1},
+		{"a", 2},
+		{"b", 3},
+	}
+	err := New(WithHeaderRows(1), WithGroupSubtotals(0, []int{1})).Write(data)
+	if !errors.Is(err, ErrUnsortedGroups) {
+		t.Fatalf("Expected ErrUnsortedGroups, got %v", err)
+	}
+
+	w := New(WithHeaderRows(1), WithGroupSubtotals(0, []int{1}, WithGroupSorting()))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	sheet := readBack(t, w)
+	for r, want := range []interface{}{"Dept", "a", "a Total", "b", "b", "b Total", "Grand Total"} {
+		if got := sheet.Cell(r, 0); got.Value != want {
+			t.Errorf("Row %d: expected %v, got %v", r, want, got.Value)
+		}
+	}
+	if got := sheet.Cell(3, 1); got.Value != 1.0 {
+		t.Errorf("Expected the rows of a group to keep their order, got %v", got.Value)
+	}
+	if data[1][0] != "b" {
+		t.Errorf("Expected the written rows to be left as they are, got %v", data)
+	}
+}
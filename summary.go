@@ -0,0 +1,228 @@
+package xls
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// Aggregate is a function a summary row computes over a column.
+type Aggregate int
+
+// Aggregates of a summary row. Sum, Avg, Min and Max take the numbers of
+// the column, dates included, and leave the cell blank for a column
+// without numbers; Count counts its non-empty cells.
+const (
+	Sum Aggregate = iota
+	Avg
+	Count
+	Min
+	Max
+)
+
+var aggregateFuncs = [...]string{"SUM", "AVERAGE", "COUNTA", "MIN", "MAX"}
+
+func (a Aggregate) String() string {
+	if a >= 0 && int(a) < len(aggregateFuncs) {
+		return aggregateFuncs[a]
+	}
+	return fmt.Sprintf("Aggregate(%d)", int(a))
+}
+
+// WithSummaryRow makes Write append a summary row to the data of every
+// sheet, as AddSummaryRow does.
+func WithSummaryRow(spec map[int]Aggregate) Option {
+	return func(w *Writer) {
+		w.summary = spec
+	}
+}
+
+// WithSummaryFormulas makes summary rows hold SUM, AVERAGE, COUNTA, MIN and
+// MAX formulas over the data, with the computed values cached, instead of
+// the values alone.
+func WithSummaryFormulas() Option {
+	return func(w *Writer) {
+		w.summaryFormulas = true
+	}
+}
+
+// WithSummaryStyle sets the style of the cells of summary rows, merged over
+// the column style like any StyledCell, in place of the Summary style of the
+// theme.
+func WithSummaryStyle(st Style) Option {
+	return func(w *Writer) {
+		w.summaryStyle = &st
+	}
+}
+
+// AddSummaryRow appends a summary row to the data of the first sheet. See
+// SheetWriter.AddSummaryRow.
+func (w *Writer) AddSummaryRow(spec map[int]Aggregate) error {
+	return w.sheets[0].AddSummaryRow(spec)
+}
+
+// AddSummaryRow appends a row after the data with the aggregate of each
+// zero-based column of spec over the data rows, those after the header
+// rows set with WithHeaderRows. The values are computed now, so cells set
+// later are not counted; with WithSummaryFormulas the cells are formulas
+// that Excel recalculates.
+//
+// Every cell of the row across the width of the data is styled, by default
+// in the Summary style of the theme over the column style: bold with a thin
+// top border with ThemeDefault. Aggregates without a number format in their
+// style get the NumberFormat of the theme, or its date format for dates.
+func (s *SheetWriter) AddSummaryRow(spec map[int]Aggregate) error {
+	s.materialize()
+	row := len(s.data)
+	if row > maxRow {
+		return fmt.Errorf("%w: no room for a summary row after %d rows", ErrOutOfRange, row)
+	}
+	width := 0
+	for _, cells := range s.data {
+		width = max(width, len(cells))
+	}
+	for _, col := range slices.Sorted(maps.Keys(spec)) {
+		agg := spec[col]
+		if _, _, err := cellIndex(row, col); err != nil {
+			return err
+		}
+		if agg < Sum || agg > Max {
+			return fmt.Errorf("xls: unknown aggregate %d for column %d", int(agg), col)
+		}
+		width = max(width, col+1)
+	}
+
+	first := min(s.w.headerRows, row)
+	cells := make([]interface{}, width)
+	for col := range cells {
+		st := s.summaryStyle(col)
+		agg, ok := spec[col]
+		if !ok {
+			cells[col] = Styled(nil, st)
+			continue
+		}
+		value, dates := s.aggregate(agg, col, first, row)
+		if value != nil && st.NumberFormat == "" && agg != Count {
+			st.NumberFormat = s.w.theme.NumberFormat
+			if dates {
+				st.NumberFormat = s.w.theme.DateFormat
+			}
+		}
+		if value != nil && s.w.summaryFormulas && first < row {
+			value = FormulaCell{
+				Expr:   fmt.Sprintf("%s(%s%d:%s%d)", agg, columnName(col), first+1, columnName(col), row),
+				Cached: value,
+			}
+		}
+		cells[col] = Styled(value, st)
+	}
+	s.data = append(s.data[:row:row], cells)
+	s.dataOwned = true
+	return nil
+}
+
+// summaryStyle returns the style of the summary cell of a column: the
+// Summary style of the theme over the column style.
+func (s *SheetWriter) summaryStyle(col int) Style {
+	if s.w.summaryStyle != nil {
+		return *s.w.summaryStyle
+	}
+	return s.w.theme.Summary.over(s.colStyles[col])
+}
+
+// aggregate computes agg over the rows first to last, exclusive, of a
+// column. See aggregator.
+func (s *SheetWriter) aggregate(agg Aggregate, col, first, last int) (interface{}, bool) {
+	var a aggregator
+	for _, cells := range s.data[first:last] {
+		if col < len(cells) {
+			f, isDate, ok := s.w.summaryNumber(cells[col])
+			a.add(cells[col], f, isDate, ok)
+		}
+	}
+	return a.result(agg)
+}
+
+// aggregator computes an Aggregate as Excel does over cell values.
+type aggregator struct {
+	sum, lo, hi            float64
+	numbers, filled, dates int
+}
+
+// add adds a cell value, the number it is written as, if ok, and whether
+// that is a date.
+func (a *aggregator) add(value interface{}, f float64, isDate, ok bool) {
+	if value == nil {
+		return
+	}
+	if sc, styled := value.(StyledCell); styled && sc.Value == nil {
+		return
+	}
+	a.filled++
+	if !ok {
+		return
+	}
+	if a.numbers == 0 {
+		a.lo, a.hi = f, f
+	}
+	a.numbers++
+	a.sum += f
+	a.lo, a.hi = math.Min(a.lo, f), math.Max(a.hi, f)
+	if isDate {
+		a.dates++
+	}
+}
+
+// result returns the aggregate of the values added and whether all its
+// numbers are dates. It returns nil for Sum, Avg, Min and Max without
+// numbers.
+func (a *aggregator) result(agg Aggregate) (interface{}, bool) {
+	if agg == Count {
+		return float64(a.filled), false
+	}
+	if a.numbers == 0 {
+		return nil, false
+	}
+	allDates := a.dates == a.numbers
+	switch agg {
+	case Avg:
+		return a.sum / float64(a.numbers), allDates
+	case Min:
+		return a.lo, allDates
+	case Max:
+		return a.hi, allDates
+	}
+	return a.sum, false
+}
+
+// summaryNumber returns the number a cell value is written as, and whether
+// it is a date, or false if it is not written as a number.
+func (w *Writer) summaryNumber(value interface{}) (float64, bool, bool) {
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	if f, ok := w.numberText(value); ok {
+		return f, false, true
+	}
+	return cellNumber(value)
+}
+
+// cellNumber returns the number a cell value is written as, and whether it
+// is a date, or false if it is not written as a number. Formulas count
+// with their cached result.
+func cellNumber(value interface{}) (float64, bool, bool) {
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	switch v := value.(type) {
+	case time.Time:
+		serial, err := timeToSerial(v, false)
+		return serial, true, err == nil
+	case FormulaCell:
+		value = v.Cached
+	}
+	f, ok := toFloat64(value)
+	return f, false, ok
+}
@@ -0,0 +1,123 @@
+package xls
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var summaryData = [][]interface{}{
+	{"name", "qty", "price", "note", "when"},
+	{"a", 1, 10.0, "x", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+	{"b", 2, 20.0, nil, time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)},
+	{"c", "n/a", 45.0, "y", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+func TestWithSummaryRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.xls")
+	if err := WriteToFile(path, summaryData, WithSummaryRow(map[int]Aggregate{1: Sum, 2: Avg})); err != nil {
+		t.Fatalf("WriteToFile() failed: %v", err)
+	}
+	wb, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() failed: %v", err)
+	}
+	rows := wb.Sheets()[0].Rows()
+	if len(rows) != len(summaryData)+1 {
+		t.Fatalf("Expected %d rows, got %d", len(summaryData)+1, len(rows))
+	}
+	last := rows[len(rows)-1]
+	want := []Cell{{Kind: KindBlank}, {Kind: KindNumber, Value: 3.0}, {Kind: KindNumber, Value: 25.0}}
+	for col, w := range want {
+		if got := last[col]; got.Kind != w.Kind || got.Value != w.Value {
+			t.Errorf("Column %d: expected %s %v, got %s %v", col, w.Kind, w.Value, got.Kind, got.Value)
+		}
+	}
+}
+
+func TestAddSummaryRow(t *testing.T) {
+	spec := map[int]Aggregate{0: Count, 1: Sum, 2: Max, 3: Sum, 4: Min}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want []interface{}
+	}{
+		{"values", nil, []interface{}{4.0, 3.0, 45.0, nil, 45300.0}},
+		{"header rows", []Option{WithHeaderRows(2)}, []interface{}{2.0, 2.0, 45.0, nil, 45300.0}},
+		{"formulas", []Option{WithSummaryFormulas()}, []interface{}{
+			FormulaCell{Expr: "COUNTA(A1:A4)", Cached: 4.0},
+			FormulaCell{Expr: "SUM(B1:B4)", Cached: 3.0},
+			FormulaCell{Expr: "MAX(C1:C4)", Cached: 45.0},
+			nil,
+			FormulaCell{Expr: "MIN(E1:E4)", Cached: 45300.0},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := New(tc.opts...)
+			w.SetColStyle(2, Style{NumberFormat: "0.00"})
+			if err := w.Write(summaryData); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			if err := w.AddSummaryRow(spec); err != nil {
+				t.Fatalf("AddSummaryRow() failed: %v", err)
+			}
+			data := w.sheets[0].data
+			if len(summaryData[0]) != 5 || len(data) != len(summaryData)+1 {
+				t.Fatalf("AddSummaryRow() changed the caller's data or added %d rows", len(data)-len(summaryData))
+			}
+			row := data[len(data)-1]
+			for col, want := range tc.want {
+				sc := row[col].(StyledCell)
+				if !reflect.DeepEqual(sc.Value, want) {
+					t.Errorf("Column %d: expected %#v, got %#v", col, want, sc.Value)
+				}
+				if !sc.Style.Font.Bold || sc.Style.Border.Top != BorderThin {
+					t.Errorf("Column %d: expected bold with a top border, got %+v", col, sc.Style)
+				}
+			}
+			if f := row[2].(StyledCell).Style.NumberFormat; f != "0.00" {
+				t.Errorf("Expected the column number format, got %q", f)
+			}
+			if f := row[4].(StyledCell).Style.NumberFormat; f != "yyyy-mm-dd" {
+				t.Errorf("Expected a date format for the earliest date, got %q", f)
+			}
+
+			file, err := w.build()
+			if err != nil {
+				t.Fatalf("build() failed: %v", err)
+			}
+			if err := Verify(file, "Sheet1", data); err != nil {
+				t.Errorf("Verify() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddSummaryRowStyle(t *testing.T) {
+	st := Style{Fill: ColorYellow}
+	w := New(WithSummaryStyle(st))
+	w.Write([][]interface{}{{1, 2}})
+	if err := w.AddSummaryRow(map[int]Aggregate{1: Avg}); err != nil {
+		t.Fatalf("AddSummaryRow() failed: %v", err)
+	}
+	want := []interface{}{Styled(nil, st), Styled(2.0, st)}
+	if got := w.sheets[0].data[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestAddSummaryRowErrors(t *testing.T) {
+	w := New()
+	if err := w.AddSummaryRow(map[int]Aggregate{maxColumn + 1: Sum}); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected an out of range error, got %v", err)
+	}
+	if err := w.AddSummaryRow(map[int]Aggregate{0: Max + 1}); err == nil {
+		t.Error("Expected an error for an unknown aggregate")
+	}
+	w.Write(make([][]interface{}, maxRow+1))
+	if err := w.AddSummaryRow(map[int]Aggregate{0: Sum}); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected an out of range error for a full sheet, got %v", err)
+	}
+}
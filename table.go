@@ -0,0 +1,151 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// TableStyle configures FormatAsTable. A zero body style, and a zero header
+// or band style in a theme without one, leave the cells with only the table
+// borders.
+type TableStyle struct {
+	Header Style // Style of the first row of the range
+	Body   Style // Style of the other rows
+	// Band is the style of every second body row when Banded is set.
+	Band       Style
+	Banded     bool
+	AutoFilter bool // Adds an AutoFilter over the header row
+}
+
+// cellRange is an inclusive range of zero-based cells.
+type cellRange struct {
+	firstRow, lastRow, firstCol, lastCol int
+}
+
+// FormatAsTable styles an inclusive, zero-based range of cells of the first
+// sheet as a table. See SheetWriter.FormatAsTable.
+func (w *Writer) FormatAsTable(firstRow, lastRow, firstCol, lastCol int, opts TableStyle) error {
+	return w.sheets[0].FormatAsTable(firstRow, lastRow, firstCol, lastCol, opts)
+}
+
+// FormatAsTable styles the inclusive, zero-based range of cells as a table:
+// the header style on its first row, the body style (alternating with the
+// band style if banded) on the others, and the borders of the theme: thin
+// between cells and medium around the range with ThemeDefault. A zero
+// header or band style takes the Header style or Band fill of the theme.
+// The Border fields of the styles are replaced by the table borders.
+//
+// Table cells get cell styles, so they override column and row styles.
+// Only one AutoFilter is kept per sheet.
+func (s *SheetWriter) FormatAsTable(firstRow, lastRow, firstCol, lastCol int, opts TableStyle) error {
+	if firstRow < 0 || firstCol < 0 || lastRow > maxRow || lastCol > maxColumn || firstRow > lastRow || firstCol > lastCol {
+		return fmt.Errorf("%w: table range rows %d-%d, columns %d-%d", ErrOutOfRange, firstRow, lastRow, firstCol, lastCol)
+	}
+
+	theme := &s.w.theme
+	if opts.Header == (Style{}) {
+		opts.Header = theme.Header
+	}
+	if opts.Band == (Style{}) {
+		opts.Band = Style{Fill: theme.Band}
+	}
+
+	for row := firstRow; row <= lastRow; row++ {
+		st := opts.Body
+		switch {
+		case row == firstRow:
+			st = opts.Header
+		case opts.Banded && (row-firstRow)%2 == 0:
+			st = opts.Band
+		}
+
+		color := st.Border.Color
+		if color == ColorAuto {
+			color = theme.BorderColor
+		}
+		for col := firstCol; col <= lastCol; col++ {
+			st.Border = Border{
+				Top:    theme.tableEdge(row == firstRow),
+				Bottom: theme.tableEdge(row == lastRow),
+				Left:   theme.tableEdge(col == firstCol),
+				Right:  theme.tableEdge(col == lastCol),
+				Color:  color,
+			}
+			s.SetCellStyle(row, col, st)
+		}
+	}
+
+	if opts.AutoFilter {
+		s.autoFilter = &cellRange{firstRow: firstRow, lastRow: lastRow, firstCol: firstCol, lastCol: lastCol}
+	}
+	return nil
+}
+
+// writeAutoFilterNames writes the hidden built-in _FilterDatabase name
+// that defines the AutoFilter range of each sheet with one, with the SUPBOOK
+// and EXTERNSHEET records their 3D references need.
+func (w *Writer) writeAutoFilterNames(writer io.Writer) error {
+	var filtered []int
+	for i, s := range w.sheets {
+		if s.autoFilter != nil {
+			filtered = append(filtered, i)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	supbook := make([]byte, 4)
+	binary.LittleEndian.PutUint16(supbook[0:2], uint16(len(w.sheets))) // Sheet count
+	binary.LittleEndian.PutUint16(supbook[2:4], 0x0401)                // References within this workbook
+	if err := w.writeRecord(writer, recTypeSUPBOOK, supbook); err != nil {
+		return err
+	}
+
+	// One XTI per sheet: SUPBOOK 0, from and to the sheet
+	externSheet := make([]byte, 2+6*len(w.sheets))
+	binary.LittleEndian.PutUint16(externSheet[0:2], uint16(len(w.sheets)))
+	for i := range w.sheets {
+		binary.LittleEndian.PutUint16(externSheet[2+6*i+2:], uint16(i))
+		binary.LittleEndian.PutUint16(externSheet[2+6*i+4:], uint16(i))
+	}
+	if err := w.writeRecord(writer, recTypeEXTERNSHEET, externSheet); err != nil {
+		return err
+	}
+
+	for _, i := range filtered {
+		r := w.sheets[i].autoFilter
+		rgce := make([]byte, 11)
+		rgce[0] = 0x3B                                      // ptgArea3d
+		binary.LittleEndian.PutUint16(rgce[1:3], uint16(i)) // XTI index
+		binary.LittleEndian.PutUint16(rgce[3:5], uint16(r.firstRow))
+		binary.LittleEndian.PutUint16(rgce[5:7], uint16(r.lastRow))
+		binary.LittleEndian.PutUint16(rgce[7:9], uint16(r.firstCol))
+		binary.LittleEndian.PutUint16(rgce[9:11], uint16(r.lastCol))
+
+		data := make([]byte, 16, 16+len(rgce))
+		binary.LittleEndian.PutUint16(data[0:2], 0x0021) // fHidden, fBuiltin
+		data[3] = 1                                      // Name length
+		binary.LittleEndian.PutUint16(data[4:6], uint16(len(rgce)))
+		binary.LittleEndian.PutUint16(data[8:10], uint16(i+1)) // 1-based sheet index
+		data[14] = 0x00                                        // Compressed name
+		data[15] = 0x0D                                        // Built-in _FilterDatabase
+		data = append(data, rgce...)
+		if err := w.writeRecord(writer, recTypeNAME, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAutoFilterInfo writes the AUTOFILTERINFO record with the number of
+// filtered columns.
+func (w *Writer) writeAutoFilterInfo(writer io.Writer, s *SheetWriter) error {
+	if s.autoFilter == nil {
+		return nil
+	}
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(s.autoFilter.lastCol-s.autoFilter.firstCol+1))
+	return w.writeRecord(writer, recTypeAUTOFILTERINFO, data)
+}
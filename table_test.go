@@ -0,0 +1,79 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestFormatAsTable(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := [][]interface{}{
+		{"Name", "Qty", "Price"},
+		{"Apple", 3, 1.5},
+		{"Banana", 6, 0.5},
+		{"Cherry", 9, 4.0},
+	}
+	w.Write(data)
+
+	header := Style{Font: Font{Bold: true}, Fill: ColorNavy}
+	band := Style{Fill: ColorLightCyan}
+	err := w.FormatAsTable(0, 3, 0, 2, TableStyle{Header: header, Band: band, Banded: true, AutoFilter: true})
+	if err != nil {
+		t.Fatalf("FormatAsTable() failed: %v", err)
+	}
+
+	tests := []struct {
+		row, col int
+		want     Style
+	}{
+		{0, 0, Style{Font: header.Font, Fill: header.Fill, Border: Border{Top: BorderMedium, Bottom: BorderThin, Left: BorderMedium, Right: BorderThin}}},
+		{0, 2, Style{Font: header.Font, Fill: header.Fill, Border: Border{Top: BorderMedium, Bottom: BorderThin, Left: BorderThin, Right: BorderMedium}}},
+		{1, 1, Style{Border: Border{Top: BorderThin, Bottom: BorderThin, Left: BorderThin, Right: BorderThin}}},
+		{2, 1, Style{Fill: band.Fill, Border: Border{Top: BorderThin, Bottom: BorderThin, Left: BorderThin, Right: BorderThin}}},
+		{3, 0, Style{Border: Border{Top: BorderThin, Bottom: BorderMedium, Left: BorderMedium, Right: BorderThin}}},
+		{3, 2, Style{Border: Border{Top: BorderThin, Bottom: BorderMedium, Left: BorderThin, Right: BorderMedium}}},
+	}
+	for _, tt := range tests {
+		if got := w.sheets[0].cellStyles[[2]int{tt.row, tt.col}]; got != tt.want {
+			t.Errorf("Cell (%d, %d): expected %+v, got %+v", tt.row, tt.col, tt.want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if err := Verify(buf.Bytes(), "Sheet1", data); err != nil {
+		t.Errorf("Table does not read back: %v", err)
+	}
+
+	records := writtenRecords(t, w)
+	names := recordsOfType(records, recTypeNAME)
+	if len(names) != 1 {
+		t.Fatalf("Expected 1 NAME record, got %d", len(names))
+	}
+	name := names[0].Data
+	if name[15] != 0x0D || name[0]&0x21 != 0x21 {
+		t.Errorf("Expected a hidden built-in _FilterDatabase name, got % X", name[:16])
+	}
+	rgce := name[16:]
+	if rgce[0] != 0x3B || binary.LittleEndian.Uint16(rgce[5:7]) != 3 || binary.LittleEndian.Uint16(rgce[9:11]) != 2 {
+		t.Errorf("Expected the name to refer to A1:C4, got % X", rgce)
+	}
+	infos := recordsOfType(records, recTypeAUTOFILTERINFO)
+	if len(infos) != 1 || binary.LittleEndian.Uint16(infos[0].Data) != 3 {
+		t.Errorf("Expected an AUTOFILTERINFO record for 3 columns, got %v", infos)
+	}
+}
+
+func TestFormatAsTableInvalidRange(t *testing.T) {
+	w := New()
+	defer w.Close()
+	for _, r := range [][4]int{{2, 1, 0, 0}, {0, 0, 3, 2}, {-1, 0, 0, 0}, {0, 0, 0, 256}} {
+		if err := w.FormatAsTable(r[0], r[1], r[2], r[3], TableStyle{}); err == nil {
+			t.Errorf("Expected an error for range %v", r)
+		}
+	}
+}
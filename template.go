@@ -0,0 +1,578 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrUnsupportedFeature is returned by OpenTemplate with WithStrictTemplate
+// when the template holds content the Writer cannot keep.
+var ErrUnsupportedFeature = errors.New("xls: template has unsupported content")
+
+// Worksheet records a template keeps verbatim
+const (
+	recTypeMERGEDCELLS  = 0x00E5
+	recTypeCONDFMT      = 0x01B0
+	recTypeCF           = 0x01B1
+	recTypeDVAL         = 0x01B2
+	recTypeHLINK        = 0x01B8
+	recTypeDV           = 0x01BE
+	recTypeHLINKTOOLTIP = 0x0800
+)
+
+// Records a template drops
+const (
+	recTypeNOTE            = 0x001C
+	recTypeOBJ             = 0x005D
+	recTypeOBPROJ          = 0x00D3
+	recTypeMSODRAWINGGROUP = 0x00EB
+	recTypeMSODRAWING      = 0x00EC
+	recTypeTXO             = 0x01B6
+)
+
+// BOUNDSHEET sheet types
+const (
+	sheetTypeWorksheet = 0x00
+	sheetTypeMacro     = 0x01
+	sheetTypeChart     = 0x02
+	sheetTypeVBModule  = 0x06
+)
+
+// templateRecords lists the worksheet records OpenTemplate preserves as raw
+// records. They all belong after the window records, where raw worksheet
+// records are written, and do not refer to XF indices.
+var templateRecords = map[uint16]bool{
+	recTypeMERGEDCELLS:  true,
+	recTypeCONDFMT:      true,
+	recTypeCF:           true,
+	recTypeDVAL:         true,
+	recTypeHLINK:        true,
+	recTypeDV:           true,
+	recTypeHLINKTOOLTIP: true,
+}
+
+// UnsupportedKind is the kind of template content an UnsupportedFeature
+// reports.
+type UnsupportedKind int
+
+const (
+	// UnsupportedChartSheet reports a chart sheet. RecordType is
+	// BOUNDSHEET.
+	UnsupportedChartSheet UnsupportedKind = iota
+	// UnsupportedMacroSheet reports an Excel 4.0 macro sheet. RecordType is
+	// BOUNDSHEET.
+	UnsupportedMacroSheet
+	// UnsupportedVBA reports a VB module sheet, or the OBPROJ record of
+	// the workbook globals that marks a VBA project.
+	UnsupportedVBA
+	// UnsupportedEmbeddedChart reports charts embedded in a worksheet.
+	// RecordType is BOF, counting their substreams.
+	UnsupportedEmbeddedChart
+	// UnsupportedDrawing reports drawing and object records: images,
+	// shapes, text boxes, form controls and the anchors of charts and
+	// comments.
+	UnsupportedDrawing
+	// UnsupportedComment reports cell comments, counting NOTE records.
+	UnsupportedComment
+)
+
+func (k UnsupportedKind) String() string {
+	switch k {
+	case UnsupportedChartSheet:
+		return "chart sheet"
+	case UnsupportedMacroSheet:
+		return "macro sheet"
+	case UnsupportedVBA:
+		return "VBA"
+	case UnsupportedEmbeddedChart:
+		return "embedded chart"
+	case UnsupportedDrawing:
+		return "drawing"
+	case UnsupportedComment:
+		return "comment"
+	}
+	return fmt.Sprintf("UnsupportedKind(%d)", int(k))
+}
+
+// UnsupportedFeature is template content OpenTemplate dropped: Count
+// records of RecordType in Sheet, or in the workbook globals when Sheet is
+// empty.
+type UnsupportedFeature struct {
+	Kind       UnsupportedKind
+	Sheet      string
+	RecordType uint16
+	Count      int
+}
+
+func (f UnsupportedFeature) String() string {
+	where := "workbook"
+	if f.Sheet != "" {
+		where = fmt.Sprintf("sheet %q", f.Sheet)
+	}
+	return fmt.Sprintf("%s: %s (%d %s records)", where, f.Kind, f.Count, RecordName(f.RecordType))
+}
+
+// unsupportedRecords maps the records of the workbook globals and
+// worksheets that a template drops to what they hold.
+var unsupportedRecords = map[uint16]UnsupportedKind{
+	recTypeOBPROJ:          UnsupportedVBA,
+	recTypeMSODRAWINGGROUP: UnsupportedDrawing,
+	recTypeMSODRAWING:      UnsupportedDrawing,
+	recTypeOBJ:             UnsupportedDrawing,
+	recTypeTXO:             UnsupportedDrawing,
+	recTypeNOTE:            UnsupportedComment,
+}
+
+// unsupportedFeatures collects the content a template drops, counting the
+// records of each kind, sheet and record type.
+type unsupportedFeatures []UnsupportedFeature
+
+func (u *unsupportedFeatures) add(kind UnsupportedKind, sheet string, recType uint16) {
+	for i := range *u {
+		if f := &(*u)[i]; f.Kind == kind && f.Sheet == sheet && f.RecordType == recType {
+			f.Count++
+			return
+		}
+	}
+	*u = append(*u, UnsupportedFeature{Kind: kind, Sheet: sheet, RecordType: recType, Count: 1})
+}
+
+// WithStrictTemplate makes OpenTemplate fail with ErrUnsupportedFeature
+// instead of dropping content it cannot keep, such as charts, drawings,
+// comments and VBA.
+func WithStrictTemplate() Option {
+	return func(w *Writer) {
+		w.strictTemplate = true
+	}
+}
+
+// UnsupportedFeatures returns the content OpenTemplate dropped from the
+// template the Writer was opened from, one entry for each kind, sheet and
+// record type. The records of the workbook globals come first, then those
+// of each worksheet in sheet order, then the chart, macro and VB module
+// sheets in sheet order. It is empty for a Writer created with New.
+func (w *Writer) UnsupportedFeatures() []UnsupportedFeature {
+	return w.unsupported
+}
+
+// OpenTemplate opens the XLS file at path as a Writer holding its
+// worksheets, so that cells can be filled in with SetCell or SetCellRef and
+// the result saved with SaveAs or WriteTo. The options apply as for New.
+//
+// The Writer takes the sheets' cells, the cell, column and row styles it can
+// express as a Style, column widths, frozen panes, zoom, print setup and
+// protection. Merged cells, hyperlinks, conditional formats and data
+// validation are kept verbatim. Everything else is dropped, notably images,
+// charts, comments, defined names, row heights, custom palettes and
+// rich-text runs; UnsupportedFeatures lists the charts, drawings, comments
+// and VBA dropped, and WithStrictTemplate makes them an error. Dates of a
+// 1904 workbook are converted to time.Time values; formulas the reader
+// cannot decompile, or the writer cannot compile, are replaced by their
+// cached values.
+func OpenTemplate(path string, opts ...Option) (*Writer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return openTemplate(data, opts...)
+}
+
+// openTemplate opens a complete XLS file held in memory as a Writer.
+func openTemplate(data []byte, opts ...Option) (*Writer, error) {
+	wb, err := openWorkbook(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(wb.sheets) == 0 {
+		return nil, fmt.Errorf("%w: workbook has no worksheets", ErrSheetNotFound)
+	}
+	var dropped unsupportedFeatures
+	styles, err := templateStyles(wb, &dropped)
+	if err != nil {
+		return nil, err
+	}
+
+	w := New(opts...)
+	for i, sheet := range wb.sheets {
+		s := w.sheets[0]
+		if i == 0 {
+			s.name = sheet.Name
+		} else if s, err = w.AddSheet(sheet.Name); err != nil {
+			return nil, err
+		}
+		if err := s.readTemplateSettings(wb, sheet.offset, styles, &dropped); err != nil {
+			return nil, fmt.Errorf("failed to read sheet %q: %w", sheet.Name, err)
+		}
+		s.readTemplateCells(sheet, styles)
+	}
+	for _, b := range wb.otherSheets {
+		switch b.sheetType {
+		case sheetTypeChart:
+			dropped.add(UnsupportedChartSheet, b.name, recTypeBOUNDSHEET)
+		case sheetTypeMacro:
+			dropped.add(UnsupportedMacroSheet, b.name, recTypeBOUNDSHEET)
+		case sheetTypeVBModule:
+			dropped.add(UnsupportedVBA, b.name, recTypeBOUNDSHEET)
+		}
+	}
+
+	if w.strictTemplate && len(dropped) > 0 {
+		features := make([]string, len(dropped))
+		for i, f := range dropped {
+			features[i] = f.String()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFeature, strings.Join(features, "; "))
+	}
+	w.unsupported = dropped
+	return w, nil
+}
+
+// templateStyles returns the style of every XF record of the workbook
+// globals, by XF index. Style XFs, which cells do not use, get the default
+// style. The unsupported records of the globals are added to dropped.
+func templateStyles(wb *Workbook, dropped *unsupportedFeatures) ([]Style, error) {
+	var fonts []Font
+	var styles []Style
+	r := wb.records(0)
+	for {
+		recType, data, offset, err := r.next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: missing EOF in workbook globals", ErrInvalidFormat)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if kind, ok := unsupportedRecords[recType]; ok {
+			dropped.add(kind, "", recType)
+		}
+		switch recType {
+		case recTypeEOF:
+			return styles, nil
+		case recTypeFONT:
+			f, err := templateFont(data)
+			if err != nil {
+				return nil, recordError(recType, offset)
+			}
+			fonts = append(fonts, f)
+		case recTypeXF:
+			if len(data) < 20 {
+				return nil, recordError(recType, offset)
+			}
+			styles = append(styles, templateStyle(wb, fonts, data))
+		}
+	}
+}
+
+// templateFont decodes a FONT record. The defaults of Font, Arial and 10
+// points, are left zero so that styles compare equal to those built in code.
+func templateFont(data []byte) (Font, error) {
+	if len(data) < 15 {
+		return Font{}, io.ErrUnexpectedEOF
+	}
+	name, _, err := readUnicodeString(data[14:], 1)
+	if err != nil {
+		return Font{}, err
+	}
+
+	f := Font{
+		Name:      name,
+		Size:      float64(binary.LittleEndian.Uint16(data[0:2])) / 20,
+		Bold:      binary.LittleEndian.Uint16(data[6:8]) >= 700,
+		Italic:    data[2]&0x02 != 0,
+		Strikeout: data[2]&0x08 != 0,
+		Underline: data[10] != 0,
+		Color:     templateColor(binary.LittleEndian.Uint16(data[4:6])),
+	}
+	if f.Name == "Arial" {
+		f.Name = ""
+	}
+	if f.Size == 10 {
+		f.Size = 0
+	}
+	return f, nil
+}
+
+// templateStyle decodes a cell XF record, the reverse of writeStyleXF.
+func templateStyle(wb *Workbook, fonts []Font, data []byte) Style {
+	if binary.LittleEndian.Uint16(data[4:6])&0x0004 != 0 {
+		return Style{} // Style XF
+	}
+
+	var s Style
+	// Font index 4 is never written, so the FONT records skip it
+	ifnt := int(binary.LittleEndian.Uint16(data[0:2]))
+	if ifnt >= 4 {
+		ifnt--
+	}
+	if ifnt < len(fonts) {
+		s.Font = fonts[ifnt]
+	}
+
+	ifmt := binary.LittleEndian.Uint16(data[2:4])
+	format, ok := wb.formats[ifmt]
+	if !ok {
+		format = builtinFormats[ifmt]
+	}
+	if format != "General" {
+		s.NumberFormat = format
+	}
+
+	switch h := data[6] & 0x07; h {
+	case 6: // Centered across the selection
+		s.HAlign = HAlignCenter
+	case 7: // Distributed
+		s.HAlign = HAlignJustify
+	default:
+		s.HAlign = HAlign(h)
+	}
+	s.VAlign = [...]VAlign{VAlignTop, VAlignCenter, VAlignBottom, VAlignJustify, VAlignBottom, VAlignBottom, VAlignBottom, VAlignBottom}[data[6]>>4&0x07]
+	s.WrapText = data[6]&0x08 != 0
+
+	lines := binary.LittleEndian.Uint32(data[10:14])
+	topBottom := binary.LittleEndian.Uint32(data[14:18])
+	s.Border = Border{
+		Left:   templateBorder(lines),
+		Right:  templateBorder(lines >> 4),
+		Top:    templateBorder(lines >> 8),
+		Bottom: templateBorder(lines >> 12),
+	}
+	// Border has one color; take that of the first side drawn
+	switch {
+	case s.Border.Left != BorderNone:
+		s.Border.Color = templateColor(uint16(lines >> 16 & 0x7F))
+	case s.Border.Right != BorderNone:
+		s.Border.Color = templateColor(uint16(lines >> 23 & 0x7F))
+	case s.Border.Top != BorderNone:
+		s.Border.Color = templateColor(uint16(topBottom & 0x7F))
+	case s.Border.Bottom != BorderNone:
+		s.Border.Color = templateColor(uint16(topBottom >> 7 & 0x7F))
+	}
+
+	// Patterns other than solid are approximated by their foreground color
+	if topBottom>>26 != 0 {
+		s.Fill = templateColor(binary.LittleEndian.Uint16(data[18:20]) & 0x7F)
+	}
+	return s
+}
+
+// templateBorder decodes the 4-bit line style at the bottom of v. The
+// dashed styles Style does not have become dashed or medium lines.
+func templateBorder(v uint32) BorderStyle {
+	switch line := v & 0x0F; line {
+	case 9, 11: // Dash-dot, dash-dot-dot
+		return BorderDashed
+	case 8, 10, 12, 13: // Medium dashed, dash-dot, dash-dot-dot, slanted
+		return BorderMedium
+	default:
+		return BorderStyle(line)
+	}
+}
+
+// templateColor maps a palette index to a Color, with the system colors as
+// ColorAuto.
+func templateColor(index uint16) Color {
+	if index < uint16(ColorBlack) || index >= colorSystemText {
+		return ColorAuto
+	}
+	return Color(index)
+}
+
+// readTemplateSettings reads the column and row styles, column widths,
+// view, print and protection settings and the preserved records of the
+// worksheet substream at offset, and adds its unsupported records to
+// dropped.
+func (s *SheetWriter) readTemplateSettings(wb *Workbook, offset uint32, styles []Style, dropped *unsupportedFeatures) error {
+	style := func(ixfe uint16) (Style, bool) {
+		if int(ixfe) < len(styles) && styles[ixfe] != (Style{}) {
+			return styles[ixfe], true
+		}
+		return Style{}, false
+	}
+
+	r := wb.records(offset)
+	var frozen bool
+	var setup []byte
+	var fitToPage, gridlines bool
+	depth := 0
+	for {
+		// The substream was validated when the workbook was opened
+		recType, data, recOffset, err := r.next()
+		if err != nil {
+			return err
+		}
+		if recType == recTypeBOF {
+			if depth++; depth == 2 {
+				dropped.add(UnsupportedEmbeddedChart, s.name, recTypeBOF)
+			}
+			continue
+		}
+		if depth > 1 {
+			if recType == recTypeEOF {
+				depth--
+			}
+			continue
+		}
+		if kind, ok := unsupportedRecords[recType]; ok {
+			dropped.add(kind, s.name, recType)
+		}
+
+		switch {
+		case recType == recTypeEOF:
+			s.readTemplateSetup(setup, fitToPage, gridlines)
+			if !frozen {
+				s.freezeRows, s.freezeCols = 0, 0
+			}
+			return nil
+		case templateRecords[recType]:
+			if err := s.AppendRawWorksheetRecord(recType, data); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch recType {
+		case recTypeCOLINFO:
+			if len(data) < 10 {
+				return recordError(recType, recOffset)
+			}
+			first := int(binary.LittleEndian.Uint16(data[0:2]))
+			last := min(int(binary.LittleEndian.Uint16(data[2:4])), maxColumn)
+			width := binary.LittleEndian.Uint16(data[4:6])
+			hidden := binary.LittleEndian.Uint16(data[8:10])&0x0001 != 0
+			st, styled := style(binary.LittleEndian.Uint16(data[6:8]))
+			for col := first; col <= last; col++ {
+				if hidden {
+					s.SetColWidth(col, 0)
+				} else if width != defaultColWidth {
+					s.SetColWidth(col, float64(width)/256)
+				}
+				if styled {
+					s.SetColStyle(col, st)
+				}
+			}
+		case recTypeROW:
+			if len(data) < 16 {
+				return recordError(recType, recOffset)
+			}
+			if binary.LittleEndian.Uint16(data[12:14])&0x0080 == 0 {
+				continue // fGhostDirty unset: the row has no style
+			}
+			row := int(binary.LittleEndian.Uint16(data[0:2]))
+			if st, ok := style(binary.LittleEndian.Uint16(data[14:16]) & 0x0FFF); ok {
+				s.SetRowStyle(row, st)
+			}
+		case recTypeWINDOW2:
+			if len(data) < 2 {
+				return recordError(recType, recOffset)
+			}
+			frozen = binary.LittleEndian.Uint16(data[0:2])&0x0008 != 0
+		case recTypePANE:
+			if len(data) < 4 {
+				return recordError(recType, recOffset)
+			}
+			s.freezeCols = int(binary.LittleEndian.Uint16(data[0:2]))
+			s.freezeRows = int(binary.LittleEndian.Uint16(data[2:4]))
+		case recTypeSCL:
+			if len(data) < 4 {
+				return recordError(recType, recOffset)
+			}
+			num, den := int(binary.LittleEndian.Uint16(data[0:2])), int(binary.LittleEndian.Uint16(data[2:4]))
+			if zoom := num * 100 / max(den, 1); den != 0 && zoom != 100 && zoom >= 10 && zoom <= 400 {
+				s.zoom = zoom
+			}
+		case recTypeSETUP:
+			setup = data
+		case recTypeWSBOOL:
+			if len(data) < 2 {
+				return recordError(recType, recOffset)
+			}
+			fitToPage = binary.LittleEndian.Uint16(data[0:2])&0x0100 != 0
+		case recTypePRINTGRIDLINES:
+			gridlines = len(data) >= 2 && data[0] != 0
+		case recTypePROTECT:
+			s.protected = len(data) >= 2 && data[0] != 0
+		case recTypePASSWORD:
+			if len(data) >= 2 {
+				s.verifier = binary.LittleEndian.Uint16(data[0:2])
+			}
+		}
+	}
+}
+
+// readTemplateSetup sets the print setup from a SETUP record, if it holds
+// anything but the defaults.
+func (s *SheetWriter) readTemplateSetup(setup []byte, fitToPage, gridlines bool) {
+	var p PrintSetup
+	p.Gridlines = gridlines
+	// fNoPls means the printer fields are not initialized
+	if len(setup) >= 12 && binary.LittleEndian.Uint16(setup[10:12])&0x0004 == 0 {
+		grbit := binary.LittleEndian.Uint16(setup[10:12])
+		p.Landscape = grbit&0x0002 == 0
+		if paper := int(binary.LittleEndian.Uint16(setup[0:2])); paper != 1 {
+			p.PaperSize = paper
+		}
+		if scale := int(binary.LittleEndian.Uint16(setup[2:4])); scale != 100 && scale >= 10 && scale <= 400 {
+			p.Scale = scale
+		}
+		if fitToPage {
+			p.FitToWidth = min(int(binary.LittleEndian.Uint16(setup[6:8])), 0x7FFF)
+			p.FitToHeight = min(int(binary.LittleEndian.Uint16(setup[8:10])), 0x7FFF)
+		}
+	}
+	if p != (PrintSetup{}) {
+		s.SetPrintSetup(p)
+	}
+}
+
+// readTemplateCells sets the data of the sheet to the cells of sheet, with
+// their styles set as cell styles where they differ from their column's or
+// row's. Column and row styles must have been read.
+func (s *SheetWriter) readTemplateCells(sheet *Sheet, styles []Style) {
+	rows := sheet.Rows()
+	data := make([][]interface{}, len(rows))
+	for row, cells := range rows {
+		if len(cells) > 0 {
+			data[row] = make([]interface{}, len(cells))
+		}
+		for col, cell := range cells {
+			data[row][col] = templateValue(cell)
+
+			var st Style
+			if int(cell.xf) < len(styles) {
+				st = styles[cell.xf]
+			}
+			if cell.Kind == KindBlank && cell.xf == 0 {
+				continue // A gap between cells
+			}
+			if inherited, _ := s.cellStyle(row, col); st != inherited {
+				s.SetCellStyle(row, col, st)
+			}
+		}
+	}
+	s.data, s.dataOwned, s.cols = data, true, nil
+}
+
+// templateValue returns the value a read cell is written back with.
+func templateValue(cell Cell) interface{} {
+	switch cell.Kind {
+	case KindBlank:
+		return nil
+	case KindFormula:
+		if cell.Formula != "" {
+			if _, err := compileFormula(cell.Formula); err == nil {
+				return FormulaCell{Expr: cell.Formula, Cached: cell.Value}
+			}
+		}
+	}
+	if cell.date1904 && cell.IsDate() {
+		if t, err := cell.Time(nil); err == nil {
+			return t
+		}
+	}
+	return cell.Value
+}
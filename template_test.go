@@ -0,0 +1,321 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// invoiceTemplate returns a hand-designed invoice template with the
+// customer, date and amount cells left to fill in.
+func invoiceTemplate(t *testing.T) []byte {
+	t.Helper()
+	w := New(WithSheetName("Invoice"))
+	defer w.Close()
+	w.Write([][]interface{}{
+		{"INVOICE"},
+		{"Customer:", nil},
+		{"Date:", nil},
+		{"Amount:", nil},
+		{"Tax:", FormulaCell{Expr: "B4*0.1", Cached: 0.0}},
+	})
+	s := w.sheets[0]
+	s.SetCellStyle(0, 0, Style{Font: Font{Name: "Times New Roman", Size: 16, Bold: true}, HAlign: HAlignCenter})
+	s.SetCellStyle(2, 1, Style{NumberFormat: "yyyy-mm-dd"})
+	s.SetCellStyle(3, 1, Style{NumberFormat: "#,##0.00", Border: Border{Bottom: BorderDouble, Color: ColorNavy}})
+	s.SetColStyle(3, Style{Fill: ColorLightYellow, VAlign: VAlignTop, WrapText: true})
+	s.SetRowStyle(6, Style{Font: Font{Italic: true, Color: ColorGray}})
+	s.SetColWidth(0, 12)
+	s.SetColWidth(2, 0)
+	s.FreezePanes(1, 0)
+	s.SetZoom(120)
+	s.SetPrintSetup(PrintSetup{Landscape: true, PaperSize: 9, FitToWidth: 1, Gridlines: true})
+	s.Protect("secret")
+	// A1:B1 merged
+	s.AppendRawWorksheetRecord(recTypeMERGEDCELLS, le(1, 0, 0, 0, 1))
+
+	notes, _ := w.AddSheet("Notes")
+	notes.Write([][]interface{}{{"Payable within", 30, "days"}})
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestOpenTemplate(t *testing.T) {
+	w, err := openTemplate(invoiceTemplate(t))
+	if err != nil {
+		t.Fatalf("openTemplate() failed: %v", err)
+	}
+	defer w.Close()
+
+	if len(w.sheets) != 2 || w.sheets[0].name != "Invoice" || w.sheets[1].name != "Notes" {
+		t.Fatalf("Expected sheets Invoice and Notes, got %d sheets", len(w.sheets))
+	}
+	s := w.sheets[0]
+	wantData := [][]interface{}{
+		{"INVOICE"},
+		{"Customer:"},
+		{"Date:", nil},
+		{"Amount:", nil},
+		{"Tax:", FormulaCell{Expr: "B4*0.1", Cached: 0.0}},
+	}
+	if !reflect.DeepEqual(s.data, wantData) {
+		t.Errorf("Expected data %v, got %v", wantData, s.data)
+	}
+	wantCells := map[[2]int]Style{
+		{0, 0}: {Font: Font{Name: "Times New Roman", Size: 16, Bold: true}, HAlign: HAlignCenter},
+		{2, 1}: {NumberFormat: "yyyy-mm-dd"},
+		{3, 1}: {NumberFormat: "#,##0.00", Border: Border{Bottom: BorderDouble, Color: ColorNavy}},
+	}
+	if !reflect.DeepEqual(s.cellStyles, wantCells) {
+		t.Errorf("Expected cell styles %v, got %v", wantCells, s.cellStyles)
+	}
+	if st := s.colStyles[3]; st != (Style{Fill: ColorLightYellow, VAlign: VAlignTop, WrapText: true}) {
+		t.Errorf("Expected the column style, got %+v", st)
+	}
+	if st := s.rowStyles[6]; st != (Style{Font: Font{Italic: true, Color: ColorGray}}) {
+		t.Errorf("Expected the row style, got %+v", st)
+	}
+	if want := map[int]float64{0: 12, 2: 0}; !reflect.DeepEqual(s.colWidths, want) {
+		t.Errorf("Expected column widths %v, got %v", want, s.colWidths)
+	}
+	if s.freezeRows != 1 || s.freezeCols != 0 || s.zoom != 120 {
+		t.Errorf("Expected 1 frozen row at 120%%, got %d rows, %d columns at %d%%", s.freezeRows, s.freezeCols, s.zoom)
+	}
+	if want := (PrintSetup{Landscape: true, PaperSize: 9, FitToWidth: 1, Gridlines: true}); s.print == nil || *s.print != want {
+		t.Errorf("Expected print setup %+v, got %+v", want, s.print)
+	}
+	if !s.protected || s.passwordVerifier() != passwordHash("secret") {
+		t.Errorf("Expected the protection and its password kept, got %v, %04X", s.protected, s.passwordVerifier())
+	}
+	if len(s.raw) != 1 || s.raw[0].recType != recTypeMERGEDCELLS {
+		t.Errorf("Expected the MERGEDCELLS record kept, got %v", s.raw)
+	}
+	if notes := w.sheets[1]; !reflect.DeepEqual(notes.data, [][]interface{}{{"Payable within", 30.0, "days"}}) {
+		t.Errorf("Expected the notes, got %v", notes.data)
+	}
+
+	// Fill in the invoice
+	date := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	for ref, value := range map[string]interface{}{"B2": "Acme Corp", "b3": date, "$B$4": 1234.5} {
+		if err := w.SetCellRef(ref, value); err != nil {
+			t.Fatalf("SetCellRef(%q) failed: %v", ref, err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to read the filled invoice: %v", err)
+	}
+	invoice, _ := wb.Sheet("Invoice")
+	if c := invoice.Cell(1, 1); c.Value != "Acme Corp" {
+		t.Errorf("Expected the customer, got %v", c.Value)
+	}
+	if c := invoice.Cell(2, 1); !c.IsDate() || c.FormatString != "yyyy-mm-dd" {
+		t.Errorf("Expected a date in the template's format, got %v in %q", c.Value, c.FormatString)
+	} else if got, _ := c.Time(nil); !got.Equal(date) {
+		t.Errorf("Expected %v, got %v", date, got)
+	}
+	if c := invoice.Cell(3, 1); c.Value != 1234.5 || c.FormatString != "#,##0.00" {
+		t.Errorf("Expected the amount in the template's format, got %v in %q", c.Value, c.FormatString)
+	}
+	if c := invoice.Cell(4, 1); c.Formula != "B4*0.1" {
+		t.Errorf("Expected the tax formula kept, got %q", c.Formula)
+	}
+
+	// Reopening the filled file gives back the same formatting
+	again, err := openTemplate(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openTemplate() of the output failed: %v", err)
+	}
+	a, b := w.sheets[0], again.sheets[0]
+	if !reflect.DeepEqual(a.cellStyles, b.cellStyles) || !reflect.DeepEqual(a.colStyles, b.colStyles) ||
+		!reflect.DeepEqual(a.rowStyles, b.rowStyles) || !reflect.DeepEqual(a.colWidths, b.colWidths) ||
+		!reflect.DeepEqual(a.print, b.print) || !reflect.DeepEqual(a.raw, b.raw) ||
+		a.freezeRows != b.freezeRows || a.zoom != b.zoom || a.passwordVerifier() != b.passwordVerifier() {
+		t.Error("Expected the saved invoice to keep the template's formatting")
+	}
+}
+
+func TestOpenTemplateFixtures(t *testing.T) {
+	for _, fx := range readerFixtures {
+		t.Run(fx.name, func(t *testing.T) {
+			file, err := os.ReadFile(filepath.Join("testdata", fx.name))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			w, err := openTemplate(file)
+			if err != nil {
+				t.Fatalf("openTemplate() failed: %v", err)
+			}
+			var buf bytes.Buffer
+			if _, err := w.WriteTo(&buf); err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+
+			want, _ := openWorkbook(file)
+			got, err := openWorkbook(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to read the output: %v", err)
+			}
+			for r, row := range want.Sheets()[0].Rows() {
+				for c, cell := range row {
+					out := got.Sheets()[0].Cell(r, c)
+					if cell.Kind == KindBlank && cell.FormatString == "General" {
+						continue // Unstyled blanks are not written
+					}
+					if out.Value != cell.Value || out.FormatString != cell.FormatString {
+						t.Errorf("Cell(%d, %d): expected %v in %q, got %v in %q", r, c, cell.Value, cell.FormatString, out.Value, out.FormatString)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOpenTemplateDate1904(t *testing.T) {
+	file := buildFixture(t,
+		[]testRecord{{recTypeDATEMODE, le(1)}},
+		[]testRecord{{recTypeNUMBER, le(0, 0, 1, 0.0)}})
+	w, err := openTemplate(file)
+	if err != nil {
+		t.Fatalf("openTemplate() failed: %v", err)
+	}
+	want := time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, ok := w.sheets[0].data[0][0].(time.Time); !ok || !got.Equal(want) {
+		t.Errorf("Expected a 1904 date converted to %v, got %v", want, w.sheets[0].data[0][0])
+	}
+}
+
+func TestOpenTemplateErrors(t *testing.T) {
+	if _, err := OpenTemplate(filepath.Join(t.TempDir(), "missing.xls")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := openTemplate([]byte("not an xls file")); err == nil {
+		t.Error("Expected an error for an invalid file")
+	}
+}
+
+func TestTemplateColor(t *testing.T) {
+	tests := []struct {
+		index uint16
+		want  Color
+	}{
+		{0x00, ColorAuto},
+		{0x08, ColorBlack},
+		{0x3F, Color(0x3F)},
+		{0x40, ColorAuto},
+		{0x7FFF, ColorAuto},
+	}
+	for _, tt := range tests {
+		if got := templateColor(tt.index); got != tt.want {
+			t.Errorf("templateColor(0x%X): expected %d, got %d", tt.index, tt.want, got)
+		}
+	}
+}
+
+// chartTemplate returns the records of testdata/chart.xls: a worksheet with
+// an embedded chart, a drawing and a comment, followed by a chart sheet.
+func chartTemplate(t *testing.T) []byte {
+	t.Helper()
+	encode := func(records []testRecord) []byte {
+		var b []byte
+		for _, rec := range records {
+			b = append(b, le(rec.recType, len(rec.data), rec.data)...)
+		}
+		return b
+	}
+	bof := func(subType int) testRecord {
+		return testRecord{recTypeBOF, le(0x0600, subType, 0x0DBB, 0x07CC, uint32(0), uint32(6))}
+	}
+	boundSheet := func(offset int, sheetType byte, name string) testRecord {
+		return testRecord{recTypeBOUNDSHEET, le(uint32(offset), byte(0), sheetType, byte(len(name)), byte(0), name)}
+	}
+	obj := testRecord{recTypeOBJ, le(0x15, 0x12, 0x05, 1, 0x6011, uint32(0), uint32(0), uint32(0), 0, 0)}
+	worksheet := encode([]testRecord{
+		bof(bofWorksheet),
+		{recTypeLABEL, le(0, 0, 0, 5, byte(0), "Sales")},
+		{recTypeMSODRAWING, make([]byte, 8)},
+		obj,
+		bof(0x0020), {recTypeEOF, nil}, // The embedded chart
+		{recTypeMSODRAWING, make([]byte, 8)},
+		obj,
+		{recTypeTXO, make([]byte, 18)},
+		{recTypeNOTE, le(0, 0, 0, 1, 1, byte(0), "A")},
+		{recTypeEOF, nil},
+	})
+	chart := encode([]testRecord{bof(0x0020), {recTypeEOF, nil}})
+
+	globals := func(sheetOffset int) []byte {
+		return encode([]testRecord{
+			bof(bofWorkbook),
+			{recTypeXF, le(0, 0, 0, make([]byte, 14))},
+			{recTypeMSODRAWINGGROUP, make([]byte, 8)},
+			boundSheet(sheetOffset, sheetTypeWorksheet, "Sales"),
+			boundSheet(sheetOffset+len(worksheet), sheetTypeChart, "Chart1"),
+			{recTypeEOF, nil},
+		})
+	}
+	stream := globals(len(globals(0)))
+	stream = append(append(stream, worksheet...), chart...)
+
+	var file bytes.Buffer
+	if err := WriteCFB(&file, stream); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+	return file.Bytes()
+}
+
+func TestOpenTemplateUnsupported(t *testing.T) {
+	path := filepath.Join("testdata", "chart.xls")
+	if *update {
+		if err := os.WriteFile(path, chartTemplate(t), 0o644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+	}
+
+	w, err := OpenTemplate(path)
+	if err != nil {
+		t.Fatalf("OpenTemplate() failed: %v", err)
+	}
+	want := []UnsupportedFeature{
+		{UnsupportedDrawing, "", recTypeMSODRAWINGGROUP, 1},
+		{UnsupportedDrawing, "Sales", recTypeMSODRAWING, 2},
+		{UnsupportedDrawing, "Sales", recTypeOBJ, 2},
+		{UnsupportedEmbeddedChart, "Sales", recTypeBOF, 1},
+		{UnsupportedDrawing, "Sales", recTypeTXO, 1},
+		{UnsupportedComment, "Sales", recTypeNOTE, 1},
+		{UnsupportedChartSheet, "Chart1", recTypeBOUNDSHEET, 1},
+	}
+	if got := w.UnsupportedFeatures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected unsupported features %v, got %v", want, got)
+	}
+	if len(w.sheets) != 1 || w.sheets[0].data[0][0] != "Sales" {
+		t.Errorf("Expected the worksheet kept, got %d sheets", len(w.sheets))
+	}
+
+	_, err = OpenTemplate(path, WithStrictTemplate())
+	if !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("Expected ErrUnsupportedFeature, got %v", err)
+	}
+	for _, s := range []string{`sheet "Chart1": chart sheet (1 BOUNDSHEET records)`, `sheet "Sales": embedded chart (1 BOF records)`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Expected %q in the error, got %v", s, err)
+		}
+	}
+
+	if w, err := openTemplate(invoiceTemplate(t), WithStrictTemplate()); err != nil || len(w.UnsupportedFeatures()) != 0 {
+		t.Errorf("Expected a plain template accepted in strict mode, got %v", err)
+	}
+}
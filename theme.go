@@ -0,0 +1,134 @@
+package xls
+
+import "time"
+
+// Theme is a house style: the fonts, styles, formats and borders the
+// convenience features of the Writer use in place of defaults of their
+// own. Start from ThemeDefault or ThemeModern and change the fields that
+// differ; a zero field leaves out what it would add, except for the font
+// and the date formats, which fall back to those of ThemeDefault.
+type Theme struct {
+	// FontName and FontSize, in points, are the default font of the
+	// workbook, that of the cells whose style sets no font name or size.
+	FontName string
+	FontSize float64
+
+	// Header styles the header rows the Writer builds: those of
+	// WriteStructs and WriteMaps, and the first row of FormatAsTable when
+	// its TableStyle has no Header style.
+	Header Style
+
+	// Summary styles the summary rows of AddSummaryRow and WithSummaryRow
+	// and the grand total of WithGroupSubtotals, merged over the column
+	// style. WithSummaryStyle replaces it.
+	Summary Style
+
+	// Subtotal styles the subtotal rows of WithGroupSubtotals.
+	Subtotal Style
+
+	// NumberFormat is the number format of ColumnNumber columns of a
+	// schema without a Format, and of the numbers of summary rows in
+	// columns without one.
+	NumberFormat string
+
+	// CurrencyFormat is the number format of ColumnCurrency columns of a
+	// schema without a Format.
+	CurrencyFormat string
+
+	// DateFormat and DateTimeFormat are the number formats of dates
+	// without one, without and with a time of day.
+	DateFormat     string
+	DateTimeFormat string
+
+	// Band is the fill of every second body row of a banded FormatAsTable
+	// whose TableStyle has no Band style.
+	Band Color
+
+	// TableBorder and TableOutline are the borders FormatAsTable draws
+	// between the cells of a table and around it, in BorderColor unless
+	// the style of the cell has a border color.
+	TableBorder  BorderStyle
+	TableOutline BorderStyle
+	BorderColor  Color
+}
+
+// ThemeDefault is the theme of a Writer without WithTheme: 10pt Arial,
+// bold summary rows with a thin top border, ISO 8601 dates and tables
+// drawn with thin lines inside a medium outline.
+var ThemeDefault = Theme{
+	FontName:       "Arial",
+	FontSize:       10,
+	Summary:        Style{Font: Font{Bold: true}, Border: Border{Top: BorderThin}},
+	Subtotal:       Style{Font: Font{Bold: true}},
+	CurrencyFormat: "#,##0.00",
+	DateFormat:     "yyyy-mm-dd",
+	DateTimeFormat: "yyyy-mm-dd hh:mm:ss",
+	TableBorder:    BorderThin,
+	TableOutline:   BorderMedium,
+}
+
+// ThemeModern is an example of a theme: 11pt Calibri, white on blue-gray
+// headers, pale blue bands, thousands separators and hairlines.
+var ThemeModern = Theme{
+	FontName:       "Calibri",
+	FontSize:       11,
+	Header:         Style{Font: Font{Bold: true, Color: ColorWhite}, Fill: ColorBlueGray, HAlign: HAlignCenter},
+	Summary:        Style{Font: Font{Bold: true}, Fill: ColorPaleBlue, Border: Border{Top: BorderDouble}},
+	Subtotal:       Style{Font: Font{Bold: true, Italic: true}},
+	NumberFormat:   "#,##0.##",
+	CurrencyFormat: "$#,##0.00",
+	DateFormat:     "d mmm yyyy",
+	DateTimeFormat: "d mmm yyyy hh:mm",
+	Band:           ColorPaleBlue,
+	TableBorder:    BorderHair,
+	TableOutline:   BorderThin,
+	BorderColor:    ColorGray40,
+}
+
+// WithTheme sets the theme of the Writer, ThemeDefault by default. The
+// styles of a StyleSet are prepared apart from any Writer: those in 10pt
+// Arial are written in the font of the theme, like those without a font.
+func WithTheme(t Theme) Option {
+	return func(w *Writer) {
+		if t.FontName == "" {
+			t.FontName = ThemeDefault.FontName
+		}
+		if t.FontSize == 0 {
+			t.FontSize = ThemeDefault.FontSize
+		}
+		if t.DateFormat == "" {
+			t.DateFormat = ThemeDefault.DateFormat
+		}
+		if t.DateTimeFormat == "" {
+			t.DateTimeFormat = ThemeDefault.DateTimeFormat
+		}
+		w.theme = t
+	}
+}
+
+// font returns the default font of the theme.
+func (t *Theme) font() Font {
+	return Font{Name: t.FontName, Size: t.FontSize}
+}
+
+// dateFormat returns the number format of a date without one.
+func (t *Theme) dateFormat(d time.Time) string {
+	if hasTimeOfDay(d) {
+		return t.DateTimeFormat
+	}
+	return t.DateFormat
+}
+
+// builtinDates reports whether dates without a number format are written
+// with the built-in date XFs, whose formats are those of ThemeDefault.
+func (t *Theme) builtinDates() bool {
+	return t.DateFormat == "yyyy-mm-dd" && t.DateTimeFormat == "yyyy-mm-dd hh:mm:ss"
+}
+
+// tableEdge returns the border of a table cell on its outline or inside.
+func (t *Theme) tableEdge(outline bool) BorderStyle {
+	if outline {
+		return t.TableOutline
+	}
+	return t.TableBorder
+}
@@ -0,0 +1,213 @@
+package xls
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// themeRecord is a row of themeWorkbook.
+type themeRecord struct {
+	Region  string
+	Date    time.Time
+	Updated time.Time
+	Units   int
+	Amount  float64
+}
+
+var themeRecords = []themeRecord{
+	{"North", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC), 1200, 1234.5},
+	{"South", time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 2, 17, 0, 0, 0, time.UTC), 80, 99.95},
+	{"East", time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 3, 12, 15, 0, 0, time.UTC), 5, 0.5},
+	{"West", time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 4, 8, 0, 0, 0, time.UTC), 42000, 12345.67},
+}
+
+// themeOptions are the options of the theme workbooks, a schema and a
+// summary row, with the theme first.
+func themeOptions(theme Theme) []Option {
+	return []Option{
+		WithTheme(theme),
+		WithSchema(ColumnSchema{
+			{Name: "Units", Type: ColumnNumber},
+			{Name: "Amount", Type: ColumnCurrency},
+		}),
+		WithSummaryRow(map[int]Aggregate{1: Max, 3: Sum, 4: Sum}),
+	}
+}
+
+// themeWorkbook returns a workbook using every feature a theme styles:
+// struct headers, a schema, a banded table and a summary row.
+func themeWorkbook(theme Theme) *Writer {
+	w := New(append(themeOptions(theme), WithSheetName("Sales"))...)
+	w.WriteStructs(themeRecords)
+	w.FormatAsTable(0, len(themeRecords), 0, 4, TableStyle{Banded: true, AutoFilter: true})
+	return w
+}
+
+func TestThemeDefault(t *testing.T) {
+	// ThemeDefault is the output without WithTheme
+	for _, build := range []func(opts ...Option) *Writer{
+		func(opts ...Option) *Writer {
+			w := New(opts...)
+			w.WriteStructs(themeRecords)
+			w.FormatAsTable(0, len(themeRecords), 0, 4, TableStyle{Banded: true})
+			return w
+		},
+		func(opts ...Option) *Writer {
+			w := New(append(opts, WithHeaderRows(1), WithGroupSubtotals(0, []int{3, 4}, WithGroupSorting()))...)
+			w.WriteStructs(themeRecords)
+			return w
+		},
+	} {
+		plain := writtenBytes(t, build(WithSummaryRow(map[int]Aggregate{3: Sum})))
+		themed := writtenBytes(t, build(WithSummaryRow(map[int]Aggregate{3: Sum}), WithTheme(ThemeDefault)))
+		if !bytes.Equal(plain, themed) {
+			t.Errorf("Expected ThemeDefault to leave the output unchanged:\n%s", diffXLS(plain, themed))
+		}
+	}
+}
+
+func TestWithTheme(t *testing.T) {
+	dir := t.TempDir()
+	data := [][]interface{}{
+		{"Region", "Date", "Updated", "Units", "Amount"},
+		{"North", themeRecords[0].Date, themeRecords[0].Updated, "1200", "1234.5"},
+		{"South", themeRecords[1].Date, themeRecords[1].Updated, 80, 99.95},
+	}
+	files := make(map[string][]byte)
+	for name, theme := range map[string]Theme{"default": ThemeDefault, "modern": ThemeModern} {
+		path := filepath.Join(dir, name+".xls")
+		if err := WriteToFile(path, data, themeOptions(theme)...); err != nil {
+			t.Fatalf("%s: WriteToFile() failed: %v", name, err)
+		}
+		file, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = file
+	}
+	if bytes.Equal(files["default"], files["modern"]) {
+		t.Fatal("Expected the themes to write different files")
+	}
+
+	// Structurally identical: the same cells with the same values
+	sheets := make(map[string]*Sheet)
+	for name, file := range files {
+		wb, err := openWorkbook(file)
+		if err != nil {
+			t.Fatalf("%s: openWorkbook() failed: %v", name, err)
+		}
+		sheets[name] = wb.Sheets()[0]
+	}
+	plain, modern := sheets["default"].Rows(), sheets["modern"].Rows()
+	if len(plain) != len(data)+1 || len(plain) != len(modern) {
+		t.Fatalf("Expected %d rows, got %d and %d", len(data)+1, len(plain), len(modern))
+	}
+	for row := range plain {
+		if len(plain[row]) != len(modern[row]) {
+			t.Fatalf("Row %d: %d and %d cells", row, len(plain[row]), len(modern[row]))
+		}
+		for col, cell := range plain[row] {
+			if got := modern[row][col]; got.Kind != cell.Kind || got.Value != cell.Value {
+				t.Errorf("Cell (%d, %d): expected %v %v, got %v %v", row, col, cell.Kind, cell.Value, got.Kind, got.Value)
+			}
+		}
+	}
+
+	// Visibly different: the formats of the theme
+	for _, tc := range []struct {
+		row, col      int
+		plain, modern string
+	}{
+		{1, 1, "yyyy-mm-dd", "d mmm yyyy"},
+		{1, 2, "yyyy-mm-dd hh:mm:ss", "d mmm yyyy hh:mm"},
+		{1, 4, "#,##0.00", "$#,##0.00"},
+		{3, 1, "yyyy-mm-dd", "d mmm yyyy"},
+		{3, 3, "General", "#,##0.##"},
+	} {
+		if got := sheets["default"].Cell(tc.row, tc.col).FormatString; got != tc.plain {
+			t.Errorf("Default cell (%d, %d): expected format %q, got %q", tc.row, tc.col, tc.plain, got)
+		}
+		if got := sheets["modern"].Cell(tc.row, tc.col).FormatString; got != tc.modern {
+			t.Errorf("Modern cell (%d, %d): expected format %q, got %q", tc.row, tc.col, tc.modern, got)
+		}
+	}
+}
+
+func TestThemeStyles(t *testing.T) {
+	w := themeWorkbook(ThemeModern)
+	records := writtenRecords(t, w)
+	xfs := cellXFs(records)
+	style := func(row, col int) Style {
+		t.Helper()
+		xf, ok := xfs[[2]int{row, col}]
+		if !ok || xf < firstCustomXF {
+			t.Fatalf("Cell (%d, %d): expected a custom XF, got %d", row, col, xf)
+		}
+		return w.styles.xfs[xf-firstCustomXF]
+	}
+
+	// The default font is FONT 0
+	fonts := recordsOfType(records, recTypeFONT)
+	if name := string(fonts[0].Data[16:]); name != "Calibri" || fonts[0].Data[0] != 220 {
+		t.Errorf("Expected 11pt Calibri as FONT 0, got %q of %d twips", name, fonts[0].Data[0])
+	}
+
+	header := style(0, 0)
+	if header.Fill != ColorBlueGray || !header.Font.Bold || header.Font.Color != ColorWhite {
+		t.Errorf("Expected the header style of the theme, got %+v", header)
+	}
+	if header.Border != (Border{Top: BorderThin, Bottom: BorderHair, Left: BorderThin, Right: BorderHair, Color: ColorGray40}) {
+		t.Errorf("Expected the table borders of the theme, got %+v", header.Border)
+	}
+	if band, plain := style(2, 0), style(1, 0); band.Fill != ColorPaleBlue || plain.Fill != ColorAuto {
+		t.Errorf("Expected pale blue bands, got %v and %v", band.Fill, plain.Fill)
+	}
+	summary := style(len(themeRecords)+1, 4)
+	if summary.Fill != ColorPaleBlue || summary.Border.Top != BorderDouble || summary.NumberFormat != "$#,##0.00" {
+		t.Errorf("Expected the summary style of the theme over the currency format, got %+v", summary)
+	}
+
+	// A table style of its own wins over the theme
+	w = New(WithTheme(ThemeModern))
+	w.Write([][]interface{}{{"a"}, {"b"}})
+	own := Style{Font: Font{Italic: true}, Border: Border{Color: ColorRed}}
+	w.FormatAsTable(0, 1, 0, 0, TableStyle{Header: own})
+	records = writtenRecords(t, w)
+	xfs = cellXFs(records)
+	if st := w.styles.xfs[xfs[[2]int{0, 0}]-firstCustomXF]; st.Fill != ColorAuto || !st.Font.Italic || st.Border.Color != ColorRed {
+		t.Errorf("Expected the header style of the table, got %+v", st)
+	}
+
+	// Headers of WriteMaps outside a table, blank ones included
+	w = New(WithTheme(ThemeModern))
+	w.WriteMaps([]map[string]interface{}{{"a": 1, "": 2}})
+	xfs = cellXFs(writtenRecords(t, w))
+	for col := range 2 {
+		if xf := xfs[[2]int{0, col}]; xf < firstCustomXF || w.styles.xfs[xf-firstCustomXF] != ThemeModern.Header {
+			t.Errorf("Column %d: expected the header style of the theme, got XF %d", col, xf)
+		}
+	}
+
+	// Subtotal rows
+	w = New(WithTheme(ThemeModern), WithHeaderRows(1), WithGroupSubtotals(0, []int{3}))
+	w.WriteStructs(themeRecords[:1])
+	records = writtenRecords(t, w)
+	xfs = cellXFs(records)
+	if st := w.styles.xfs[xfs[[2]int{2, 3}]-firstCustomXF]; !st.Font.Bold || !st.Font.Italic {
+		t.Errorf("Expected the subtotal style of the theme, got %+v", st)
+	}
+}
+
+func TestWithThemeFallbacks(t *testing.T) {
+	w := New(WithTheme(Theme{Header: Style{Font: Font{Bold: true}}}))
+	if w.theme.font() != ThemeDefault.font() || !w.theme.builtinDates() {
+		t.Errorf("Expected the font and dates of ThemeDefault, got %+v", w.theme)
+	}
+	w.Write([][]interface{}{{time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)}})
+	if xf := cellXFs(writtenRecords(t, w))[[2]int{0, 0}]; xf != xfDate {
+		t.Errorf("Expected the built-in date XF, got %d", xf)
+	}
+}
@@ -0,0 +1,29 @@
+package xls
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithStreamTransform passes the Workbook stream through fn once it is
+// written, before it is obfuscated with WithLegacyXORPassword and wrapped in
+// the compound file, for records the Writer does not model, such as a
+// drawing group taken from a template. fn is given the whole stream, which
+// it may modify in place, and the bytes it returns are used as they are,
+// their length sizing the compound file. It must keep the stream valid: a
+// record inserted before a worksheet moves it, so its BOUNDSHEET offset
+// must be updated. An error from fn fails the write.
+func WithStreamTransform(fn func(workbookStream []byte) ([]byte, error)) Option {
+	return func(w *Writer) {
+		w.transform = fn
+	}
+}
+
+// transformStream applies the transform of WithStreamTransform to buf.
+func (w *Writer) transformStream(buf *bytes.Buffer) (*bytes.Buffer, error) {
+	out, err := w.transform(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("stream transform failed: %w", err)
+	}
+	return bytes.NewBuffer(out), nil
+}
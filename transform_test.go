@@ -0,0 +1,56 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestWithStreamTransform(t *testing.T) {
+	data := [][]interface{}{{"name", 1.5}, {"other", 2.0}}
+	custom := le(0x0887, 4, uint32(0xDEADBEEF))
+
+	var before int
+	w := New(WithPostWriteVerification(), WithStreamTransform(func(stream []byte) ([]byte, error) {
+		before = len(stream)
+		// Insert a record before the EOF of the worksheet, the last record
+		end := len(stream) - 4
+		if binary.LittleEndian.Uint16(stream[end:]) != recTypeEOF {
+			t.Errorf("Expected the whole stream, ending in EOF")
+		}
+		out := append([]byte(nil), stream[:end]...)
+		out = append(out, custom...)
+		return append(out, stream[end:]...), nil
+	}))
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	var file bytes.Buffer
+	if _, err := w.WriteTo(&file); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	stream, err := workbookStream(file.Bytes())
+	if err != nil {
+		t.Fatalf("workbookStream() failed: %v", err)
+	}
+	if len(stream) != max(before+len(custom), cfbMiniStreamCutoff) {
+		t.Errorf("Expected the transformed stream of %d bytes, got %d", before+len(custom), len(stream))
+	}
+	if !bytes.Contains(stream, custom) {
+		t.Error("Expected the custom record in the stream")
+	}
+	if err := Verify(file.Bytes(), "Sheet1", data); err != nil {
+		t.Errorf("Verify() failed: %v", err)
+	}
+
+	failing := errors.New("rejected")
+	w = New(WithStreamTransform(func([]byte) ([]byte, error) { return nil, failing }))
+	if _, err := w.WriteTo(new(bytes.Buffer)); !errors.Is(err, failing) {
+		t.Errorf("Expected the transform error, got %v", err)
+	}
+	if _, err := w.WorkbookStream(); !errors.Is(err, failing) {
+		t.Errorf("Expected the transform error from WorkbookStream, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package xls
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CellValue is a cell value of a type the Writer writes as it is: Text,
+// RichText, Number, Bool, DateTime, FormulaCell, CellError or StyledCell.
+// Write, SetCell and InsertRow take these alongside plain Go values;
+// FromInterface returns the CellValue a plain value is written as.
+//
+// Every CellValue has a String method for debugging and marshals to JSON
+// as its value: Text as a string, Number as a number, Bool as a boolean,
+// DateTime as an RFC 3339 string and CellError by name. RichText marshals
+// as an object of its Text and Runs.
+type CellValue interface {
+	fmt.Stringer
+	cellValue()
+}
+
+// Text is a text cell value, written like a string.
+type Text string
+
+// Number is a numeric cell value, written like a float64.
+type Number float64
+
+// Bool is a boolean cell value, written like a bool.
+type Bool bool
+
+// DateTime is a date cell value, written like a time.Time: as a date
+// serial number with a date format, or a date-time format if it has a time
+// of day.
+type DateTime time.Time
+
+func (Text) cellValue()        {}
+func (Number) cellValue()      {}
+func (Bool) cellValue()        {}
+func (DateTime) cellValue()    {}
+func (FormulaCell) cellValue() {}
+func (CellError) cellValue()   {}
+func (StyledCell) cellValue()  {}
+
+// String returns the text.
+func (t Text) String() string {
+	return string(t)
+}
+
+// String returns the number as the General format displays it.
+func (n Number) String() string {
+	return formatGeneral(float64(n))
+}
+
+// String returns TRUE or FALSE.
+func (b Bool) String() string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// String returns the date as the format it is written with displays it.
+func (d DateTime) String() string {
+	t := time.Time(d)
+	if hasTimeOfDay(t) {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return t.Format("2006-01-02")
+}
+
+// String returns the formula with a leading "=".
+func (f FormulaCell) String() string {
+	return "=" + strings.TrimPrefix(f.Expr, "=")
+}
+
+// String returns the text of the value, or "" for a blank styled cell.
+func (sc StyledCell) String() string {
+	if sc.Value == nil {
+		return ""
+	}
+	return renderValue(sc.Value, BytesList)
+}
+
+// MarshalJSON encodes the date as an RFC 3339 string.
+func (d DateTime) MarshalJSON() ([]byte, error) {
+	return time.Time(d).MarshalJSON()
+}
+
+// UnmarshalJSON decodes an RFC 3339 string.
+func (d *DateTime) UnmarshalJSON(data []byte) error {
+	return (*time.Time)(d).UnmarshalJSON(data)
+}
+
+// MarshalJSON encodes the error by name, such as "#N/A".
+func (e CellError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+// UnmarshalJSON decodes an error name such as "#N/A".
+func (e *CellError) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for v, n := range cellErrorNames {
+		if n == name {
+			*e = v
+			return nil
+		}
+	}
+	return fmt.Errorf("xls: unknown cell error %q", name)
+}
+
+// FromInterface returns the CellValue the Writer writes v as, and nil for
+// nil, the empty cell. Strings are Text, numbers of any Go numeric type are
+// Number, time.Time is DateTime, and values of other types are Text, which
+// WithStrictTypes rejects instead. The value inside a StyledCell is
+// converted the same way. The text of other types follows the first of
+// these rules that applies:
+//
+//   - nil pointers are the empty string.
+//   - Values with a String or Error method are what it returns.
+//   - Bools are "true" or "false", integers are in decimal, and floats in
+//     their shortest representation, as strconv.FormatFloat with 'g' and
+//     -1 gives it.
+//   - Byte slices are encoded as WithByteEncoding says, if it is set.
+//   - Slices and arrays are their elements, by these rules, joined with
+//     ", ". Nil elements are empty.
+//   - Pointers are the value they point to.
+//   - Other values, such as structs and maps, are formatted with %+v.
+//
+// It returns the error writing v would: ErrOutOfRange for text longer than
+// a cell holds or a RichText with runs out of order, and an error for a
+// date before 1900 or a formula that does not parse. Options that change values, such as WithAutoNumberConversion or
+// WithCellConverter, are not applied.
+func FromInterface(v interface{}) (CellValue, error) {
+	if sc, ok := v.(StyledCell); ok {
+		if sc.Value == nil {
+			return sc, nil
+		}
+		value, err := FromInterface(sc.Value)
+		if err != nil {
+			return nil, err
+		}
+		sc.Value = value
+		return sc, nil
+	}
+
+	var value CellValue
+	v, _ = plainValue(v)
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		value = Bool(v)
+	case time.Time:
+		if _, err := timeToSerial(v, false); err != nil {
+			return nil, fmt.Errorf("invalid date: %w", err)
+		}
+		value = DateTime(v)
+	case CellError:
+		value = v
+	case RichText:
+		if err := check("MaxCellChars", textLength(cleanText(v.Text)), maxTextLength, false); err != nil {
+			return nil, err
+		}
+		if _, err := v.positions(); err != nil {
+			return nil, err
+		}
+		value = v
+	case FormulaCell:
+		if _, err := compileFormula(v.Expr); err != nil {
+			return nil, fmt.Errorf("invalid formula: %w", err)
+		}
+		if v.Cached != nil {
+			if _, _, err := formulaResult(v.Cached); err != nil {
+				return nil, fmt.Errorf("invalid formula: %w", err)
+			}
+		}
+		if cached, ok := v.Cached.(string); ok {
+			if err := DefaultLimits().checkText(cached, 0, 0, false); err != nil {
+				return nil, err
+			}
+		}
+		value = v
+	default:
+		if f, ok := toFloat64(v); ok {
+			value = Number(f)
+			break
+		}
+		str, _ := sstString(v)
+		if err := check("MaxCellChars", textLength(str), maxTextLength, false); err != nil {
+			return nil, err
+		}
+		value = Text(str)
+	}
+	return value, nil
+}
+
+// plainValue returns the Go value a Text, Number, Bool or DateTime stands
+// for, also inside a StyledCell or as the cached result of a FormulaCell,
+// and false for a value it leaves as it is.
+func plainValue(v interface{}) (interface{}, bool) {
+	if _, ok := v.(CellValue); !ok {
+		return v, false
+	}
+	switch v := v.(type) {
+	case Text:
+		return string(v), true
+	case Number:
+		return float64(v), true
+	case Bool:
+		return bool(v), true
+	case DateTime:
+		return time.Time(v), true
+	case FormulaCell:
+		cached, ok := plainValue(v.Cached)
+		v.Cached = cached
+		return v, ok
+	case StyledCell:
+		value, ok := plainValue(v.Value)
+		v.Value = value
+		return v, ok
+	}
+	return v, false
+}
+
+// plainRow returns row with the values plainValue changes replaced, and
+// false if there are none.
+func plainRow(row []interface{}) ([]interface{}, bool) {
+	var out []interface{}
+	for c, cell := range row {
+		value, ok := plainValue(cell)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), row...)
+		}
+		out[c] = value
+	}
+	return out, out != nil
+}
+
+// plainValues returns data with the values plainValue changes replaced,
+// copying the rows that change. owned reports whether the outer slice of
+// data is already a copy.
+func plainValues(data [][]interface{}, owned bool) ([][]interface{}, bool) {
+	out := data
+	for r, row := range data {
+		plain, ok := plainRow(row)
+		if !ok {
+			continue
+		}
+		if !owned {
+			out, owned = append([][]interface{}(nil), data...), true
+		}
+		out[r] = plain
+	}
+	return out, owned
+}
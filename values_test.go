@@ -0,0 +1,214 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cellValues are one value of every CellValue type with the kind and text
+// they are read back as, and their String.
+var cellValues = []struct {
+	value CellValue
+	kind  CellKind
+	text  string
+	str   string
+}{
+	{Text("hello"), KindText, "hello", "hello"},
+	{Text("123"), KindText, "123", "123"},
+	{Number(3.25), KindNumber, "3.25", "3.25"},
+	{Bool(true), KindBool, "TRUE", "TRUE"},
+	{DateTime(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)), KindNumber, "2024-02-29", "2024-02-29"},
+	{DateTime(time.Date(2024, 2, 29, 13, 5, 9, 0, time.UTC)), KindNumber, "2024-02-29 13:05:09", "2024-02-29 13:05:09"},
+	{FormulaCell{Expr: "1+2", Cached: Number(3)}, KindFormula, "3", "=1+2"},
+	{CellErrorNA, KindError, "#N/A", "#N/A"},
+	{Styled(Number(0.5), Style{NumberFormat: "0%"}), KindNumber, "50%", "0.5"},
+	{Styled(Text("bold"), Style{Font: Font{Bold: true}}), KindText, "bold", "bold"},
+	{RichText{Text: "rich", Runs: []TextRun{{Start: 2, Font: Font{Italic: true}}}}, KindText, "rich", "rich"},
+}
+
+func TestCellValueRoundTrip(t *testing.T) {
+	for name, set := range map[string]func(w *Writer, values []interface{}) error{
+		"Write": func(w *Writer, values []interface{}) error {
+			return w.Write([][]interface{}{values})
+		},
+		"SetCell": func(w *Writer, values []interface{}) error {
+			for col, v := range values {
+				if err := w.SetCell(0, col, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		"InsertRow": func(w *Writer, values []interface{}) error {
+			return w.InsertRow(0, values)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			values := make([]interface{}, len(cellValues))
+			for i, c := range cellValues {
+				values[i] = c.value
+			}
+			original := append([]interface{}(nil), values...)
+
+			w := New()
+			if err := set(w, values); err != nil {
+				t.Fatalf("%s() failed: %v", name, err)
+			}
+			var file bytes.Buffer
+			if _, err := w.WriteTo(&file); err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+			if !reflect.DeepEqual(values, original) {
+				t.Errorf("%s() modified the caller's row", name)
+			}
+			if err := Verify(file.Bytes(), "Sheet1", [][]interface{}{values}); err != nil {
+				t.Errorf("Verify() failed: %v", err)
+			}
+
+			wb, err := openWorkbook(file.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to open the workbook: %v", err)
+			}
+			for col, c := range cellValues {
+				got := wb.Sheets()[0].Cell(0, col)
+				if got.Kind != c.kind || got.FormattedValue() != c.text {
+					t.Errorf("%#v: expected %s %q, got %s %q", c.value, c.kind, c.text, got.Kind, got.FormattedValue())
+				}
+				if _, isDate := c.value.(DateTime); isDate && !got.IsDate() {
+					t.Errorf("%#v: expected a date format, got %q", c.value, got.FormatString)
+				}
+			}
+		})
+	}
+}
+
+func TestCellValueString(t *testing.T) {
+	for _, c := range cellValues {
+		if got := c.value.String(); got != c.str {
+			t.Errorf("%#v: expected %q, got %q", c.value, c.str, got)
+		}
+	}
+	if got := Number(1e20).String(); got != "1E+20" {
+		t.Errorf("Expected 1E+20, got %q", got)
+	}
+}
+
+func TestCellValueJSON(t *testing.T) {
+	day := time.Date(2024, 2, 29, 13, 5, 9, 0, time.UTC)
+	row := []interface{}{Text("a"), Number(1.5), Bool(false), DateTime(day), CellErrorDiv0, nil}
+	got, err := json.Marshal(row)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	want := `["a",1.5,false,"2024-02-29T13:05:09Z","#DIV/0!",null]`
+	if string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	var decoded struct {
+		When DateTime
+		Err  CellError
+	}
+	if err := json.Unmarshal([]byte(`{"When":"2024-02-29T13:05:09Z","Err":"#N/A"}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if !time.Time(decoded.When).Equal(day) || decoded.Err != CellErrorNA {
+		t.Errorf("Unexpected decoded values: %v, %v", decoded.When, decoded.Err)
+	}
+	if err := json.Unmarshal([]byte(`"#OOPS"`), &decoded.Err); err == nil {
+		t.Error("Expected an error for an unknown cell error")
+	}
+}
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+func TestFromInterface(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		in   interface{}
+		want CellValue
+	}{
+		{nil, nil},
+		{"x", Text("x")},
+		{Text("x"), Text("x")},
+		{int8(-3), Number(-3)},
+		{uint64(7), Number(7)},
+		{float32(0.5), Number(0.5)},
+		{Number(2), Number(2)},
+		{true, Bool(true)},
+		{day, DateTime(day)},
+		{CellErrorRef, CellErrorRef},
+		{FormulaCell{Expr: "=SUM(A1:A2)"}, FormulaCell{Expr: "=SUM(A1:A2)"}},
+		{FormulaCell{Expr: "A1", Cached: Text("x")}, FormulaCell{Expr: "A1", Cached: "x"}},
+		{stringer{}, Text("stringer")},
+		{struct{ A int }{1}, Text("{A:1}")},
+		{Styled(3, Style{}), Styled(Number(3), Style{})},
+		{Styled(nil, Style{}), Styled(nil, Style{})},
+		{RichText{Text: "ab", Runs: []TextRun{{Start: 1}}}, RichText{Text: "ab", Runs: []TextRun{{Start: 1}}}},
+	} {
+		got, err := FromInterface(tc.in)
+		if err != nil {
+			t.Errorf("FromInterface(%#v) failed: %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("FromInterface(%#v): expected %#v, got %#v", tc.in, tc.want, got)
+		}
+	}
+
+	for _, in := range []interface{}{
+		strings.Repeat("a", maxTextLength+1),
+		time.Date(1899, 12, 1, 0, 0, 0, 0, time.UTC),
+		FormulaCell{Expr: "SUM("},
+		FormulaCell{Expr: "A1", Cached: []int{1}},
+		Styled(FormulaCell{Expr: "1+"}, Style{}),
+		RichText{Text: "ab", Runs: []TextRun{{Start: 2}}},
+		RichText{Text: "ab", Runs: []TextRun{{Start: 1}, {Start: 1}}},
+	} {
+		if _, err := FromInterface(in); err == nil {
+			t.Errorf("FromInterface(%.20v): expected an error", in)
+		}
+	}
+	if _, err := FromInterface(Text(strings.Repeat("a", maxTextLength+1))); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected an out of range error, got %v", err)
+	}
+}
+
+// TestFromInterfaceMatchesWriter checks that the kind FromInterface gives
+// a plain value is the kind the Writer writes it as.
+func TestFromInterfaceMatchesWriter(t *testing.T) {
+	values := []interface{}{"x", 1, uint16(2), 3.5, true, time.Now().UTC(), CellErrorNum, stringer{}, []byte("b")}
+	kinds := map[string]CellKind{"Text": KindText, "Number": KindNumber, "Bool": KindBool, "DateTime": KindNumber, "CellError": KindError}
+
+	w := New()
+	if err := w.Write([][]interface{}{values}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	var file bytes.Buffer
+	if _, err := w.WriteTo(&file); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	wb, err := openWorkbook(file.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to open the workbook: %v", err)
+	}
+	for col, v := range values {
+		cv, err := FromInterface(v)
+		if err != nil {
+			t.Fatalf("FromInterface(%#v) failed: %v", v, err)
+		}
+		want := kinds[reflect.TypeOf(cv).Name()]
+		if got := wb.Sheets()[0].Cell(0, col); got.Kind != want {
+			t.Errorf("%#v: FromInterface gives %T, but the Writer wrote %s", v, cv, got.Kind)
+		} else if cv.String() != got.FormattedValue() && want != KindNumber {
+			t.Errorf("%#v: FromInterface gives %q, but the Writer wrote %q", v, cv, got.FormattedValue())
+		}
+	}
+}
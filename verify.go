@@ -0,0 +1,170 @@
+package xls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrVerificationFailed is returned when a written file does not read back as
+// the data it was written from.
+var ErrVerificationFailed = errors.New("xls: verification failed")
+
+// maxReportedMismatches limits the mismatches listed in an error message.
+const maxReportedMismatches = 10
+
+// Mismatch describes a cell whose decoded value differs from the written data.
+type Mismatch struct {
+	Row, Col int
+	Want     interface{}
+	Got      Cell
+}
+
+// VerificationError lists the cells that did not read back as written. It
+// wraps ErrVerificationFailed.
+type VerificationError struct {
+	Sheet      string
+	Mismatches []Mismatch
+}
+
+func (e *VerificationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: sheet %q has %d mismatched cells", ErrVerificationFailed, e.Sheet, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		if i == maxReportedMismatches {
+			b.WriteString("; ...")
+			break
+		}
+		fmt.Fprintf(&b, "; %s%d: expected %v (%T), got %s %v", columnName(m.Col), m.Row+1, m.Want, m.Want, m.Got.Kind, m.Got.Value)
+	}
+	return b.String()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return ErrVerificationFailed
+}
+
+// Verify opens the XLS file held in file and compares the named sheet
+// cell by cell with data, using the same value conversions as the Writer.
+// Numbers are compared with a small relative tolerance. Cells beyond data
+// must be blank. The sector counts of the compound file header must match
+// those recomputed from its FAT, and the records of the workbook stream must
+// keep to the sizes and CONTINUE records the Writer allows for their types.
+func Verify(file []byte, sheet string, data [][]interface{}) error {
+	data, _ = plainValues(data, false)
+	return verify(file, sheet, data, defaultPassword)
+}
+
+// verify is Verify for a file that opens with password.
+func verify(file []byte, sheet string, data [][]interface{}, password string) error {
+	cfb, err := ReadCFB(file)
+	if err == nil {
+		err = cfb.checkHeader()
+	}
+	if err == nil {
+		err = checkWorkbookRecords(cfb)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrVerificationFailed, err)
+	}
+	wb, err := openWorkbookWithPassword(file, password, DefaultLimits())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrVerificationFailed, err)
+	}
+	s, err := wb.Sheet(sheet)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrVerificationFailed, err)
+	}
+
+	verr := &VerificationError{Sheet: sheet}
+	rows := s.Rows()
+	for r := 0; r < max(len(rows), len(data)); r++ {
+		width := 0
+		if r < len(rows) {
+			width = len(rows[r])
+		}
+		if r < len(data) {
+			width = max(width, len(data[r]))
+		}
+
+		for c := 0; c < width; c++ {
+			var want interface{}
+			if r < len(data) && c < len(data[r]) {
+				want = data[r][c]
+			}
+			if got := s.Cell(r, c); !cellMatches(want, got) {
+				verr.Mismatches = append(verr.Mismatches, Mismatch{Row: r, Col: c, Want: want, Got: got})
+			}
+		}
+	}
+
+	if len(verr.Mismatches) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// cellMatches reports whether got is what the Writer produces for want.
+func cellMatches(want interface{}, got Cell) bool {
+	if sc, ok := want.(StyledCell); ok {
+		want = sc.Value
+	}
+	switch v := want.(type) {
+	case nil:
+		return got.Kind == KindBlank
+	case FormulaCell:
+		if got.Kind != KindFormula {
+			return false
+		}
+		if v.Cached == nil {
+			// Written with a zero result, recalculated on load
+			return got.Value == 0.0
+		}
+		return valueMatches(v.Cached, got.Value)
+	case bool:
+		return got.Kind == KindBool && got.Value == v
+	case CellError:
+		return got.Kind == KindError && got.Value == v
+	case time.Time:
+		return got.Kind == KindNumber && valueMatches(v, got.Value)
+	case string:
+		return got.Kind == KindText && got.Value == cleanText(v)
+	case RichText:
+		return got.Kind == KindText && got.Value == cleanText(v.Text)
+	}
+	if _, ok := toFloat64(want); ok {
+		return got.Kind == KindNumber && valueMatches(want, got.Value)
+	}
+	return got.Kind == KindText && valueMatches(want, got.Value)
+}
+
+// valueMatches compares a written value with a decoded cell value.
+func valueMatches(want, got interface{}) bool {
+	if t, ok := want.(time.Time); ok {
+		serial, err := timeToSerial(t, false)
+		if err != nil {
+			return false
+		}
+		want = serial
+	}
+	if n, ok := toFloat64(want); ok {
+		f, ok := got.(float64)
+		return ok && floatsEqual(n, f)
+	}
+	switch v := want.(type) {
+	case string, bool, CellError:
+		return got == v
+	}
+	return got == renderValue(want, BytesList)
+}
+
+// floatsEqual compares floats with a relative tolerance, as values such as
+// float32 widen to float64 inexactly.
+func floatsEqual(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
@@ -0,0 +1,117 @@
+package xls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type verifyPoint struct{ X, Y int }
+
+func TestPostWriteVerification(t *testing.T) {
+	data := [][]interface{}{
+		{"Name", nil, float32(0.1), int64(-7)},
+		{verifyPoint{1, 2}, "Name", true, CellErrorRef},
+		{},
+		{time.Date(2024, time.June, 1, 12, 30, 0, 0, time.UTC), FormulaCell{Expr: "SUM(C1:D1)", Cached: 3}, FormulaCell{Expr: "C1*2"}},
+	}
+
+	w := New(WithPostWriteVerification())
+	defer w.Close()
+	if err := w.Write(data); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	n, err := w.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected %d bytes reported, got %d", buf.Len(), n)
+	}
+
+	wb, err := openWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openWorkbook() failed: %v", err)
+	}
+	sheet := wb.Sheets()[0]
+	if cell := sheet.Cell(0, 1); cell.Kind != KindBlank {
+		t.Errorf("Expected nil to be written as blank, got %s %v", cell.Kind, cell.Value)
+	}
+	if v := sheet.Cell(1, 0).Value; v != "{X:1 Y:2}" {
+		t.Errorf("Expected '{X:1 Y:2}', got '%v'", v)
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	w := New()
+	defer w.Close()
+	if err := w.Write([][]interface{}{{"a", 1.5}, {true}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if _, err := w.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	if err := Verify(buf.Bytes(), "Sheet1", [][]interface{}{{"a", 1.5}, {true}}); err != nil {
+		t.Errorf("Verify() failed: %v", err)
+	}
+
+	err := Verify(buf.Bytes(), "Sheet1", [][]interface{}{{"b", 1.5}, {}})
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("Expected ErrVerificationFailed, got %v", err)
+	}
+	var verr *VerificationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *VerificationError, got %T", err)
+	}
+	if len(verr.Mismatches) != 2 {
+		t.Fatalf("Expected 2 mismatches, got %d: %v", len(verr.Mismatches), err)
+	}
+	if m := verr.Mismatches[0]; m.Row != 0 || m.Col != 0 || m.Got.Value != "a" {
+		t.Errorf("Unexpected first mismatch %+v", m)
+	}
+	if m := verr.Mismatches[1]; m.Row != 1 || m.Col != 0 || m.Want != nil {
+		t.Errorf("Unexpected second mismatch %+v", m)
+	}
+
+	if err := Verify([]byte("garbage"), "Sheet1", nil); !errors.Is(err, ErrInvalidFormat) || !errors.Is(err, ErrVerificationFailed) {
+		t.Errorf("Expected ErrVerificationFailed wrapping ErrInvalidFormat, got %v", err)
+	}
+}
+
+func benchmarkData() [][]interface{} {
+	data := make([][]interface{}, 5000)
+	for i := range data {
+		data[i] = []interface{}{"Item", i, float64(i) * 1.5, i%2 == 0}
+	}
+	return data
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := New()
+		w.Write(data)
+		if _, err := w.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteToVerified(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := New(WithPostWriteVerification())
+		w.Write(data)
+		if _, err := w.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
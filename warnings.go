@@ -0,0 +1,221 @@
+package xls
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// duplicateRowsWarning is the number of identical consecutive rows from
+// which a WarningDuplicateRows is reported.
+const duplicateRowsWarning = 100
+
+// WarningKind is the kind of condition a Warning reports.
+type WarningKind int
+
+const (
+	// WarningDuplicateRows reports a run of identical consecutive rows, as
+	// an exploded join produces. Cell is the first row of the run and
+	// Count the number of rows in it.
+	WarningDuplicateRows WarningKind = iota
+	// WarningEmptyColumn reports a column without a value in any row,
+	// within the width of the widest row. Cell is the top of the column.
+	WarningEmptyColumn
+	// WarningDateAsText reports strings that look like dates, such as
+	// "2024-01-02", which Excel shows as text instead of dates. Cell is the
+	// first such cell of the column and Count their number in it.
+	WarningDateAsText
+	// WarningNumberAsText reports strings that hold a decimal number, such
+	// as "42" or "1.5", which Excel marks as numbers stored as text. Cell
+	// is the first such cell of the column and Count their number in it.
+	WarningNumberAsText
+	// WarningCoercionFailed reports a value that WithSchema could not
+	// convert to the type of its column and wrote as text. Cell is the
+	// value's cell and Count is 1.
+	WarningCoercionFailed
+)
+
+func (k WarningKind) String() string {
+	switch k {
+	case WarningDuplicateRows:
+		return "duplicate rows"
+	case WarningEmptyColumn:
+		return "empty column"
+	case WarningDateAsText:
+		return "date stored as text"
+	case WarningNumberAsText:
+		return "number stored as text"
+	case WarningCoercionFailed:
+		return "coercion failed"
+	}
+	return fmt.Sprintf("WarningKind(%d)", int(k))
+}
+
+// Warning is a suspicious condition in the written data. It does not stop
+// the write.
+type Warning struct {
+	Kind  WarningKind
+	Cell  CellRef
+	Count int
+}
+
+func (w Warning) String() string {
+	switch w.Kind {
+	case WarningDuplicateRows:
+		return fmt.Sprintf("%s: %d identical consecutive rows", w.Cell, w.Count)
+	case WarningEmptyColumn:
+		return fmt.Sprintf("%s: column %s is empty", w.Cell, columnName(w.Cell.Col))
+	case WarningCoercionFailed:
+		return fmt.Sprintf("%s: value does not match the column type, written as text", w.Cell)
+	}
+	return fmt.Sprintf("%s: %s in %d cells of column %s", w.Cell, w.Kind, w.Count, columnName(w.Cell.Col))
+}
+
+// WithDataWarnings makes every write analyze the data first and call fn
+// for each suspicious condition found, in sheet order: runs of 100 or more
+// identical consecutive rows, empty columns, and columns with dates or
+// numbers stored as text, and values WithSchema could not convert. Within
+// a sheet, the values that could not be converted come first, in row
+// order, then duplicate rows, then the column warnings by column. Without
+// it the data is not analyzed.
+func WithDataWarnings(fn func(Warning)) Option {
+	return func(w *Writer) {
+		w.warn = fn
+	}
+}
+
+// reportWarnings analyzes the data of every sheet and reports what it finds
+// to the function set with WithDataWarnings.
+func (w *Writer) reportWarnings() {
+	for _, s := range w.sheets {
+		for _, warning := range append(s.schemaWarnings, s.warnings()...) {
+			w.warn(warning)
+		}
+	}
+}
+
+// warnings returns the warnings of the sheet's data: duplicate rows in row
+// order, then the column warnings in column order.
+func (s *SheetWriter) warnings() []Warning {
+	var warnings []Warning
+	data := s.rowsData()
+	for start := 0; start < len(data); {
+		end := start + 1
+		for end < len(data) && len(data[start]) > 0 && reflect.DeepEqual(data[end], data[start]) {
+			end++
+		}
+		if end-start >= duplicateRowsWarning {
+			warnings = append(warnings, Warning{
+				Kind:  WarningDuplicateRows,
+				Cell:  CellRef{Sheet: s.name, Row: start},
+				Count: end - start,
+			})
+		}
+		start = end
+	}
+
+	type columnText struct {
+		first int
+		count int
+	}
+	width := 0
+	for _, row := range data {
+		width = max(width, len(row))
+	}
+	used := make([]bool, width)
+	dates := make([]columnText, width)
+	numbers := make([]columnText, width)
+	for r, row := range data {
+		for c, cell := range row {
+			if sc, ok := cell.(StyledCell); ok {
+				cell = sc.Value
+			}
+			if cell == nil || cell == "" {
+				continue
+			}
+			used[c] = true
+			if _, ok := s.w.numberText(cell); ok {
+				continue // Written as a number
+			}
+			str, ok := cell.(string)
+			if !ok {
+				continue
+			}
+			var counts *columnText
+			switch {
+			case looksLikeDate(str):
+				counts = &dates[c]
+			case looksLikeNumber(str):
+				counts = &numbers[c]
+			default:
+				continue
+			}
+			if counts.count == 0 {
+				counts.first = r
+			}
+			counts.count++
+		}
+	}
+	for c := 0; c < width; c++ {
+		if !used[c] {
+			warnings = append(warnings, Warning{Kind: WarningEmptyColumn, Cell: CellRef{Sheet: s.name, Col: c}})
+		}
+		for _, text := range []struct {
+			kind WarningKind
+			columnText
+		}{{WarningDateAsText, dates[c]}, {WarningNumberAsText, numbers[c]}} {
+			if text.count > 0 {
+				warnings = append(warnings, Warning{
+					Kind:  text.kind,
+					Cell:  CellRef{Sheet: s.name, Row: text.first, Col: c},
+					Count: text.count,
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// dateLayouts are the layouts of the strings reported as dates stored as
+// text.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+	"01/02/2006",
+	"02.01.2006",
+}
+
+// looksLikeDate reports whether s is a date or a date and time in one of
+// the common numeric layouts.
+func looksLikeDate(s string) bool {
+	if len(s) < len("2006-1-2") || s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeNumber reports whether s is a decimal number as Excel reads one:
+// digits with an optional sign, decimal point and exponent, but not the
+// infinities, NaN and hexadecimal forms strconv also accepts.
+func looksLikeNumber(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9', c == '+', c == '-', c == '.', c == 'e', c == 'E':
+		default:
+			return false
+		}
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return s != "" && err == nil
+}
@@ -0,0 +1,134 @@
+package xls
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// collectWarnings writes data with WithDataWarnings and returns the
+// warnings reported.
+func collectWarnings(t *testing.T, data [][]interface{}) []Warning {
+	t.Helper()
+	var warnings []Warning
+	w := New(WithSheetName("Data"), WithDataWarnings(func(warning Warning) {
+		warnings = append(warnings, warning)
+	}))
+	defer w.Close()
+	w.Write(data)
+	if _, err := w.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+	return warnings
+}
+
+func TestWarningDuplicateRows(t *testing.T) {
+	data := [][]interface{}{{"id", "name"}}
+	for i := 0; i < 150; i++ {
+		data = append(data, []interface{}{1, "Alice"})
+	}
+	// A shorter run is not reported
+	for i := 0; i < 99; i++ {
+		data = append(data, []interface{}{2, "Bob"})
+	}
+	for i := 0; i < 100; i++ {
+		data = append(data, []interface{}{3, StyledCell{Value: "Carol"}})
+	}
+	// Empty rows are not duplicates
+	for i := 0; i < 200; i++ {
+		data = append(data, nil)
+	}
+
+	want := []Warning{
+		{Kind: WarningDuplicateRows, Cell: CellRef{Sheet: "Data", Row: 1}, Count: 150},
+		{Kind: WarningDuplicateRows, Cell: CellRef{Sheet: "Data", Row: 250}, Count: 100},
+	}
+	if got := collectWarnings(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWarningEmptyColumn(t *testing.T) {
+	data := [][]interface{}{
+		{"a", nil, "", 1},
+		{"b", StyledCell{Style: Style{Fill: ColorYellow}}, nil},
+		{"c"},
+	}
+	want := []Warning{
+		{Kind: WarningEmptyColumn, Cell: CellRef{Sheet: "Data", Col: 1}},
+		{Kind: WarningEmptyColumn, Cell: CellRef{Sheet: "Data", Col: 2}},
+	}
+	if got := collectWarnings(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWarningDateAsText(t *testing.T) {
+	data := [][]interface{}{
+		{"date", "note"},
+		{"2024-01-02", "2024-01-02 is a Tuesday"},
+		{"not a date", "2024/13/01"},
+		{"2024-01-03T10:00:00Z", "03/15/2024"},
+	}
+	want := []Warning{
+		{Kind: WarningDateAsText, Cell: CellRef{Sheet: "Data", Row: 1, Col: 0}, Count: 2},
+		{Kind: WarningDateAsText, Cell: CellRef{Sheet: "Data", Row: 3, Col: 1}, Count: 1},
+	}
+	if got := collectWarnings(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWarningNumberAsText(t *testing.T) {
+	data := [][]interface{}{
+		{"qty", "price", "code"},
+		{"3", 1.5, "NaN"},
+		{"-4", Styled("1e3", Style{}), "0x1F"},
+		{"seven", 2.0, "Inf"},
+	}
+	want := []Warning{
+		{Kind: WarningNumberAsText, Cell: CellRef{Sheet: "Data", Row: 1, Col: 0}, Count: 2},
+		{Kind: WarningNumberAsText, Cell: CellRef{Sheet: "Data", Row: 2, Col: 1}, Count: 1},
+	}
+	if got := collectWarnings(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWarningsDoNotChangeOutput(t *testing.T) {
+	data := [][]interface{}{{"2024-01-02", "42", nil}}
+	write := func(opts ...Option) []byte {
+		w := New(opts...)
+		w.Write(data)
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	n := 0
+	with := write(WithDataWarnings(func(Warning) { n++ }))
+	if !bytes.Equal(with, write()) {
+		t.Error("Expected the same file with warnings enabled")
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 warnings, got %d", n)
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	tests := []struct {
+		warning Warning
+		want    string
+	}{
+		{Warning{Kind: WarningDuplicateRows, Cell: CellRef{Sheet: "Data", Row: 1}, Count: 500}, "Data!A2: 500 identical consecutive rows"},
+		{Warning{Kind: WarningEmptyColumn, Cell: CellRef{Sheet: "Data", Col: 2}}, "Data!C1: column C is empty"},
+		{Warning{Kind: WarningDateAsText, Cell: CellRef{Sheet: "Q1 Sales", Row: 4, Col: 1}, Count: 3}, "'Q1 Sales'!B5: date stored as text in 3 cells of column B"},
+		{Warning{Kind: WarningNumberAsText, Cell: CellRef{Sheet: "Data", Row: 1}, Count: 2}, "Data!A2: number stored as text in 2 cells of column A"},
+	}
+	for _, tt := range tests {
+		if got := tt.warning.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -3,10 +3,16 @@ package xls
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
-	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf16"
 
 	"golang.org/x/text/encoding/unicode"
 )
@@ -20,6 +26,17 @@ const (
 	recTypeLABEL      = 0x0204
 	recTypeNUMBER     = 0x0203
 	recTypeBOOLERR    = 0x0205
+	recTypeFORMULA    = 0x0006
+	recTypeSTRING     = 0x0207
+	recTypeBLANK      = 0x0201
+	recTypeMULBLANK   = 0x00BE
+	recTypeRK         = 0x027E
+	recTypeMULRK      = 0x00BD
+	recTypeRSTRING    = 0x00D6
+	recTypeSHRFMLA    = 0x04BC
+	recTypeARRAY      = 0x0221
+	recTypeTABLE      = 0x0236
+	recTypeCONTINUE   = 0x003C
 	recTypeSST        = 0x00FC
 	recTypeEXTSST     = 0x00FF
 	recTypeLABELSST   = 0x00FD
@@ -78,6 +95,36 @@ const (
 	recTypeVBREAK       = 0x001A
 	recTypeHEADER       = 0x0014
 	recTypeFOOTER       = 0x0015
+	recTypeCOLINFO      = 0x007D
+
+	recTypeSUPBOOK        = 0x01AE
+	recTypeEXTERNSHEET    = 0x0017
+	recTypeNAME           = 0x0018
+	recTypeAUTOFILTERINFO = 0x009D
+	recTypeRECALCID       = 0x01C1
+	recTypePANE           = 0x0041
+	recTypeSELECTION      = 0x001D
+	recTypeSCL            = 0x00A0
+)
+
+// ErrUnsupportedCellType is returned in strict mode for a cell value whose
+// type the Writer does not handle explicitly.
+var ErrUnsupportedCellType = errors.New("xls: unsupported cell type")
+
+// Number formats and cell XF indices for date cells. General is built in;
+// the date formats are the first user-defined ones.
+const (
+	fmtGeneral  = 0x0000 // "General"
+	fmtDate     = 0x00A4 // "yyyy-mm-dd"
+	fmtDateTime = 0x00A5 // "yyyy-mm-dd hh:mm:ss"
+
+	xfDate     = 18
+	xfDateTime = 19
+)
+
+const (
+	defaultBOFBuild = 0x0DBB // Build identifier (Excel 2000)
+	defaultBOFYear  = 0x07CC // Build year (1996)
 )
 
 const (
@@ -87,386 +134,576 @@ const (
 )
 
 // Writer writes Excel XLS files in BIFF8 format.
+//
+// Output is deterministic: the same data, styles and options produce the
+// same bytes, whatever order the styles were set in and however the data
+// slices were built. Cells are written in (row, col) order, shared strings
+// in the order they are first seen in that walk, and styles get their
+// indices in a fixed order: those of a StyleSet, column styles by column,
+// row styles by row, then styles in the data, then cell styles by (row,
+// col). Nothing in the file depends on the clock, map iteration or the
+// environment.
 type Writer struct {
-	data      [][]interface{}
-	sheetName string
+	sheets    []*SheetWriter // The first is the sheet the Writer methods act on
+	verify    bool
+	strict    bool
+	minimal   bool
+	profile   Profile // Set with WithCompatibility
+	hybrid    int // Occurrences from which strings go to the SST, 0 for all
+	identity  *bofIdentity
+	codePage  uint16 // Set with WithCodePage, 0 for UTF-16
+	limits    Limits // Set with WithLimits
+	defColWidth float64 // Set with WithDefaultColumnWidth, 0 for Excel's
+	transform   func([]byte) ([]byte, error) // Set with WithStreamTransform
+	byteEncoding ByteEncoding // Set with WithByteEncoding
+	noDedup   bool // Set with WithoutStringDeduplication
+	autoTune  bool // Set with WithAutoTuning
+	styleSet  *StyleSet // Set with WithStyleSet
+	logger    *slog.Logger
+
+	concurrency int // Sheets written at once, 0 for GOMAXPROCS
+
+	cfbV4       bool   // Set with WithCFBVersion4
+	xorPassword string // Set with WithLegacyXORPassword, "" for none
+
+	warn         func(Warning)      // Set with WithDataWarnings
+	convert      func(string) bool // Set with WithNumberConversion
+	schema       ColumnSchema      // Set with WithSchema
+	converter    CellConverter     // Set with WithCellConverter
+	escapePrefix string            // Set with WithFormulaEscaping, "" for none
+	norm         *NormOptions      // Set with WithStringNormalization
+	dateLayouts  []string          // Set with WithDateStringDetection
+	dict         *stringDict       // Set with PredeclareStrings
+	noAutoWrap   bool              // Set with WithAutoWrapMultiline(false)
+	theme        Theme             // Set with WithTheme
+
+	// Separators of numbers in text, set with WithNumberLocale, and the
+	// error of an unknown locale, returned by the next write
+	locale    *numberLocale
+	localeErr error
+
+	headerRows  int // Rows repeated at the top of every file, set with WithHeaderRows
+	rowsPerFile int // Rows of each file of SaveAsFiles, 0 for the most a sheet holds
+	sheetSplit  *sheetSplit // Set with WithSheetSplitting
+
+	summary         map[int]Aggregate // Set with WithSummaryRow
+	summaryFormulas bool              // Set with WithSummaryFormulas
+	summaryStyle    *Style            // Set with WithSummaryStyle, nil for the default
+	subtotals       *subtotals        // Set with WithGroupSubtotals
+	metadata        *metadataColumn   // Set with WithMetadataColumn
+
+	general GeneralNumberPolicy // Set with WithGeneralNumberPolicy
+	bools   BoolRendering       // Set with WithBoolRendering
+
+	riskySheetNames bool // Set with WithAllowRiskySheetNames
+
+	lowMemorySST      bool       // Spill the SST to a file from the first string
+	sstSpillThreshold int        // Unique strings from which the SST is spilled, 0 for the default
+	tempDir           string     // Set with WithTempDir, "" for os.TempDir
+	tempFiles         *tempFiles // Temporary files left behind, removed by Close
+
+	// recordCounts counts written records per type while a logger is set
+	recordCounts map[uint16]int
+
+	styles *styleTable
+
+	rawGlobals []rawRecord
+
+	strictTemplate bool                 // Set with WithStrictTemplate
+	unsupported    []UnsupportedFeature // Content OpenTemplate dropped
+
+	embedManifest bool // Set with WithEmbeddedManifest
+
+	collectErrors bool        // Set with WithErrorCollection
+	maxCellErrors int         // Most cell errors of WithErrorCollection, 0 for no limit
+	cellErrs      *cellErrors // Cells that failed in the current write
 }
 
 // New creates a new Writer.
-func New() *Writer {
-	return &Writer{
-		sheetName: "Sheet1",
+func New(opts ...Option) *Writer {
+	w := &Writer{tempFiles: new(tempFiles), limits: DefaultLimits(), theme: ThemeDefault}
+	w.sheets = []*SheetWriter{newSheet(w, "Sheet1")}
+	for _, opt := range opts {
+		opt(w)
 	}
+	return w
 }
 
-// SetSheetName sets the sheet name.
+// SetSheetName sets the name of the first sheet.
 func (w *Writer) SetSheetName(name string) {
-	w.sheetName = name
+	w.sheets[0].name = name
 }
 
-// Write sets the data to be written.
+// Write sets the data of the first sheet.
 func (w *Writer) Write(data [][]interface{}) error {
-	w.data = data
-	return nil
+	return w.sheets[0].Write(data)
 }
 
-// SaveAs writes the XLS file to the specified path.
+// SaveAs writes the XLS file to the specified path. The file is written
+// under a temporary name in the same directory and renamed into place.
 func (w *Writer) SaveAs(filename string) error {
-	buf := new(bytes.Buffer)
-	if err := w.writeBIFF8(buf); err != nil {
-		return fmt.Errorf("failed to write BIFF8 data: %w", err)
-	}
-
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	if err := WriteCFB(file, buf.Bytes()); err != nil {
-		return fmt.Errorf("failed to write CFB container: %w", err)
-	}
-
-	return nil
+	return w.SaveToFS(osFS{}, filename)
 }
 
-func (w *Writer) writeBIFF8(buf *bytes.Buffer) error {
-	// Build Shared String Table (SST)
-	sst := newSST()
-	for _, row := range w.data {
-		for _, cell := range row {
-			if str, ok := cell.(string); ok {
-				sst.addString(str)
-			}
-		}
-	}
-
-	// BOF (Workbook Globals)
-	if err := w.writeBOF(buf, bofWorkbook); err != nil {
-		return err
+// WriteTo writes the XLS file to out.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	data, err := w.build()
+	if data == nil {
+		return 0, err
 	}
-
-	if err := w.writeInterfaceHdr(buf); err != nil {
-		return err
+	n, writeErr := out.Write(data)
+	if writeErr != nil {
+		return int64(n), writeErr
 	}
+	return int64(n), err
+}
 
-	if err := w.writeMMS(buf); err != nil {
-		return err
+// build serializes the workbook into a complete XLS file and, if enabled,
+// verifies it before anything is written out. With WithErrorCollection, the
+// file comes with the *WriteErrors of the cells that failed, if any; the
+// callers write it and return the error.
+func (w *Writer) build() ([]byte, error) {
+	if w.logger != nil {
+		w.recordCounts = make(map[uint16]int)
+		defer func() { w.recordCounts = nil }()
 	}
 
-	if err := w.writeInterfaceEnd(buf); err != nil {
-		return err
+	done, err := w.beginWrite()
+	if err != nil {
+		return nil, err
 	}
+	defer done()
 
-	if err := w.writeWriteAccess(buf); err != nil {
-		return err
+	buf, err := w.workbookStream()
+	if err != nil {
+		if w.cellErrs != nil && w.cellErrs.aborted {
+			return nil, w.cellErrs.result(w)
+		}
+		return nil, err
 	}
 
-	if err := w.writeCodePage(buf); err != nil {
-		return err
+	extra, err := w.manifestStreams()
+	if err != nil {
+		return nil, err
 	}
-
-	if err := w.writeDSF(buf); err != nil {
-		return err
+	size := cfbFileSize(buf.Len(), w.cfbSectorSize(), extra...)
+	if err := check("MaxFileBytes", int64(size), w.limits.MaxFileBytes, false); err != nil {
+		return nil, err
 	}
-
-	if err := w.writeFnGroupCount(buf); err != nil {
-		return err
+	file := new(bytes.Buffer)
+	file.Grow(size)
+	if err := writeCFBWorkbook(file, buf.Bytes(), w.cfbSectorSize(), extra...); err != nil {
+		return nil, fmt.Errorf("failed to write CFB container: %w", err)
 	}
-
-	if err := w.writeUnknown9C(buf); err != nil {
-		return err
+	if w.logger != nil {
+		w.logWritten(buf.Len(), file.Len())
 	}
 
-	if err := w.writeWindowProtect(buf); err != nil {
-		return err
+	if w.verify {
+		for _, s := range w.sheets {
+			if err := verify(file.Bytes(), s.name, s.verifiedData(), w.password()); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	if err := w.writeProtect(buf); err != nil {
-		return err
+	if w.cellErrs != nil {
+		return file.Bytes(), w.cellErrs.result(w)
 	}
+	return file.Bytes(), nil
+}
 
-	if err := w.writeObjProtect(buf); err != nil {
-		return err
+// WorkbookStream returns the BIFF8 Workbook stream of the workbook, which
+// SaveAs and WriteTo wrap in a compound file with WriteCFB. It is meant for
+// tools that build their own container, or add the stream to an existing
+// one. The checks, WithDataWarnings and WithStreamTransform run as for
+// SaveAs; the stream is not verified, since WithPostWriteVerification reads
+// back a whole file. With WithErrorCollection, the stream is returned along
+// with the *WriteErrors of the cells that failed.
+func (w *Writer) WorkbookStream() ([]byte, error) {
+	done, err := w.beginWrite()
+	if err != nil {
+		return nil, err
 	}
+	defer done()
 
-	if err := w.writePassword(buf); err != nil {
-		return err
+	buf, err := w.workbookStream()
+	if err != nil {
+		if w.cellErrs != nil && w.cellErrs.aborted {
+			return nil, w.cellErrs.result(w)
+		}
+		return nil, err
 	}
-
-	if err := w.writeProt4Rev(buf); err != nil {
-		return err
+	if w.cellErrs != nil {
+		return buf.Bytes(), w.cellErrs.result(w)
 	}
+	return buf.Bytes(), nil
+}
 
-	if err := w.writePasswordRev4(buf); err != nil {
-		return err
+// beginWrite checks the settings of the Writer and, with WithSheetSplitting,
+// replaces its sheets with the split ones for one write. With
+// WithErrorCollection, it starts collecting the cells that fail. The
+// returned function restores the sheets and ends the collection.
+func (w *Writer) beginWrite() (func(), error) {
+	if w.localeErr != nil {
+		return nil, w.localeErr
 	}
-
-	if err := w.writeBackup(buf); err != nil {
-		return err
+	if err := w.checkCodePage(); err != nil {
+		return nil, err
 	}
-
-	if err := w.writeHideObj(buf); err != nil {
-		return err
+	if err := w.checkDefColWidth(); err != nil {
+		return nil, err
 	}
-
-	if err := w.writeWindow1(buf); err != nil {
-		return err
+	orig := w.sheets
+	if w.sheetSplit != nil {
+		sheets, err := w.splitSheets()
+		if err != nil {
+			return nil, err
+		}
+		w.sheets = sheets
 	}
-
-	if err := w.writeDateMode(buf); err != nil {
-		return err
+	if w.collectErrors {
+		w.cellErrs = &cellErrors{max: w.maxCellErrors}
 	}
+	return func() { w.sheets, w.cellErrs = orig, nil }, nil
+}
 
-	if err := w.writePrecision(buf); err != nil {
-		return err
+// workbookStream reports the data warnings and writes the Workbook stream,
+// through the transform of WithStreamTransform.
+func (w *Writer) workbookStream() (*bytes.Buffer, error) {
+	if w.warn != nil {
+		w.reportWarnings()
 	}
 
-	if err := w.writeRefreshAll(buf); err != nil {
-		return err
+	buf := new(bytes.Buffer)
+	if err := w.writeBIFF8(buf); err != nil {
+		return nil, fmt.Errorf("failed to write BIFF8 data: %w", err)
 	}
-
-	if err := w.writeBookBool(buf); err != nil {
-		return err
+	if w.transform != nil {
+		var err error
+		if buf, err = w.transformStream(buf); err != nil {
+			return nil, err
+		}
 	}
-
-	// BIFF8 requires 7 default font records
-	for i := 0; i < 7; i++ {
-		if err := w.writeDefaultFont(buf); err != nil {
-			return err
+	if w.xorPassword != "" {
+		x, _ := newXORObfuscation(w.xorPassword)
+		if err := cryptRecords(buf.Bytes(), x.encrypt); err != nil {
+			return nil, err
 		}
 	}
+	return buf, nil
+}
 
-	if err := w.writeFormat(buf); err != nil {
+func (w *Writer) writeBIFF8(buf *bytes.Buffer) error {
+	if err := w.checkBIFF8(); err != nil {
 		return err
 	}
 
-	// First 16 XF records are style XF
-	for i := 0; i < 16; i++ {
-		if err := w.writeXF(buf, true, 6); err != nil {
-			return err
-		}
-	}
-	// Cell XF records
-	if err := w.writeXF(buf, false, 6); err != nil {
+	// The worksheets are written first, in the one pass over the cells that
+	// decides which cells are strings and adds them to the SST, so the SST
+	// written in the globals always matches the LABELSST records.
+	sst := w.newStringTable()
+	defer w.closeStringTable(sst)
+	if err := w.prepareStringTable(sst); err != nil {
 		return err
 	}
-	if err := w.writeXF(buf, false, 7); err != nil {
+	sheets, err := w.writeSheets(sst)
+	if err != nil {
 		return err
 	}
-
-	if err := w.writeDefaultStyle(buf); err != nil {
+	st, err := w.writeGlobals(bufferAt{buf}, sst)
+	if err != nil {
 		return err
 	}
 
-	if err := w.writeUseSelfs(buf); err != nil {
-		return err
+	size := 0
+	for _, sheet := range sheets {
+		size += sheet.Len()
 	}
-
-	// Calculate worksheet offset for BOUNDSHEET record
-	sstBuf := new(bytes.Buffer)
-	if err := w.writeSST(sstBuf, sst); err != nil {
-		return err
+	buf.Grow(size)
+	index := w.spec().index
+	for i, s := range w.sheets {
+		sheetStart := buf.Len()
+		binary.LittleEndian.PutUint32(buf.Bytes()[st.boundSheets[i]:], uint32(sheetStart))
+		if index {
+			if err := relocateIndex(bufferAt{sheets[i]}, sheetStart); err != nil {
+				return err
+			}
+		}
+		if _, err := buf.Write(sheets[i].Bytes()); err != nil {
+			return err
+		}
+		if w.logger != nil {
+			w.logger.Debug("xls: sheet written", "sheet", s.name, "start", sheetStart, "end", buf.Len())
+		}
 	}
 
-	sheetNameBytes := stringToUTF16LE(w.sheetName)
-	boundsheetSize := 4 + 6 + 1 + len(sheetNameBytes) + 1
-
-	worksheetOffset := buf.Len() + sstBuf.Len() + boundsheetSize + 4 // +4 for EOF
+	return nil
+}
 
-	if _, err := buf.Write(sstBuf.Bytes()); err != nil {
+// checkBIFF8 prepares the styles and checks the sheets before anything is
+// written.
+func (w *Writer) checkBIFF8() error {
+	if err := w.prepareStyles(); err != nil {
 		return err
 	}
-
-	if err := w.writeBoundSheet(buf, uint32(worksheetOffset), w.sheetName); err != nil {
+	if err := check("MaxSheets", len(w.sheets), w.limits.MaxSheets, false); err != nil {
 		return err
 	}
-
-	if err := w.writeEOF(buf); err != nil {
-		return err
+	for _, s := range w.sheets {
+		if err := w.checkSheetName(s.name, s); err != nil {
+			return err
+		}
+		if err := s.checkSettings(); err != nil {
+			return err
+		}
+		if err := s.checkData(); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// BOF (Worksheet)
-	if err := w.writeBOF(buf, bofWorksheet); err != nil {
-		return err
+// prepareStringTable sets where the strings of sst are kept during the
+// write: in memory, or in a temporary file from the start or once there
+// are enough of them.
+func (w *Writer) prepareStringTable(sst *sharedStringTable) error {
+	sst.tempDir = w.tempDir
+	if sst.refs != nil {
+		// The declared strings stay in memory
+	} else if w.lowMemorySST {
+		if err := sst.spill(); err != nil {
+			return err
+		}
+	} else {
+		sst.spillAt = w.spillAt()
 	}
+	return nil
+}
 
-	if err := w.writeCalcMode(buf); err != nil {
-		return err
-	}
-	if err := w.writeCalcCount(buf); err != nil {
-		return err
+// closeStringTable removes the temporary file of sst, if any, leaving it to
+// Close if it cannot be removed.
+func (w *Writer) closeStringTable(sst *sharedStringTable) {
+	if name, err := sst.close(); err != nil {
+		w.tempFiles.leak(name)
 	}
-	if err := w.writeRefMode(buf); err != nil {
-		return err
-	}
-	if err := w.writeIteration(buf); err != nil {
-		return err
+}
+
+// writeGlobals writes the workbook globals substream to buf, once the
+// worksheets have filled sst, and returns its state: the positions of the
+// BOUNDSHEET offsets are patched once the worksheets are placed.
+func (w *Writer) writeGlobals(buf streamBuffer, sst *sharedStringTable) (*substream, error) {
+	if sst.refs != nil {
+		sst.totalCount = int(sst.refs.Load())
 	}
-	if err := w.writeDelta(buf); err != nil {
-		return err
+	if err := check("MaxUniqueStrings", sst.uniqueCount, w.limits.MaxUniqueStrings, false); err != nil {
+		return nil, err
 	}
-	if err := w.writeSaveRecalc(buf); err != nil {
-		return err
+	if w.logger != nil {
+		w.logger.Debug("xls: SST built", "strings", sst.totalCount, "unique", sst.uniqueCount, "spilled", sst.spilled != nil)
 	}
 
-	if err := w.writeGuts(buf); err != nil {
-		return err
+	// Records between BOUNDSHEET and EOF: names, then raw records
+	names := new(bytes.Buffer)
+	if err := w.writeAutoFilterNames(names); err != nil {
+		return nil, err
 	}
-
-	if err := w.writeDefaultRowHeight(buf); err != nil {
-		return err
+	if err := w.writeRawRecords(names, w.rawGlobals); err != nil {
+		return nil, err
 	}
 
-	if err := w.writeWSBool(buf); err != nil {
-		return err
+	st := &substream{buf: buf, sst: sst, names: names}
+	if err := w.writeSequence(w.globalsSequence(), st); err != nil {
+		return nil, err
 	}
-
-	// DIMENSIONS must come before ROW records
-	if err := w.writeDimensions(buf); err != nil {
-		return err
+	if w.logger != nil {
+		w.logger.Debug("xls: globals written", "offset", 0, "size", buf.Len())
 	}
+	return st, nil
+}
 
-	if err := w.writePrintHeaders(buf); err != nil {
-		return err
-	}
-	if err := w.writePrintGridlines(buf); err != nil {
-		return err
-	}
-	if err := w.writeGridSet(buf); err != nil {
-		return err
-	}
-	if err := w.writeHBreak(buf); err != nil {
-		return err
-	}
-	if err := w.writeVBreak(buf); err != nil {
-		return err
-	}
-	if err := w.writeHeader(buf); err != nil {
-		return err
-	}
-	if err := w.writeFooter(buf); err != nil {
-		return err
-	}
-	if err := w.writeHCenter(buf); err != nil {
-		return err
-	}
-	if err := w.writeVCenter(buf); err != nil {
-		return err
-	}
-	if err := w.writeLeftMargin(buf); err != nil {
-		return err
-	}
-	if err := w.writeRightMargin(buf); err != nil {
-		return err
-	}
-	if err := w.writeTopMargin(buf); err != nil {
-		return err
-	}
-	if err := w.writeBottomMargin(buf); err != nil {
-		return err
-	}
-	if err := w.writeSetup(buf); err != nil {
-		return err
+// writeSheets writes the worksheet substream of every sheet into a buffer of
+// its own. With more than one sheet and more than one worker, the sheets are
+// written concurrently: their strings are first added to sst in sheet order,
+// so the SST and its indices are those of the sequential path, and the
+// workers only look them up. The styles are all registered beforehand by
+// prepareStyles, so the workers only read the style table too.
+func (w *Writer) writeSheets(sst *sharedStringTable) ([]*bytes.Buffer, error) {
+	sheets := make([]*bytes.Buffer, len(w.sheets))
+	for i := range sheets {
+		sheets[i] = new(bytes.Buffer)
 	}
 
-	if err := w.writeProtect(buf); err != nil {
-		return err
-	}
-	if err := w.writeScenProtect(buf); err != nil {
-		return err
-	}
-	if err := w.writeWindowProtect(buf); err != nil {
-		return err
+	workers := w.sheetWorkers()
+	if workers < 2 {
+		for i, s := range w.sheets {
+			if err := w.writeSheet(bufferAt{sheets[i]}, s, sst); err != nil {
+				return nil, err
+			}
+		}
+		return sheets, nil
+	}
+
+	if !sst.filled {
+		sst.fill(w)
+	}
+	errs := make([]error, len(w.sheets))
+	counts := make([]map[uint16]int, len(w.sheets))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				// With a logger, each sheet counts its records in a
+				// copy of the Writer of its own
+				sw := w
+				if w.recordCounts != nil {
+					c := *w
+					c.recordCounts = make(map[uint16]int)
+					sw, counts[i] = &c, c.recordCounts
+				}
+				errs[i] = sw.writeSheet(bufferAt{sheets[i]}, w.sheets[i], sst.forSheet(i))
+			}
+		})
 	}
-	if err := w.writeObjProtect(buf); err != nil {
-		return err
+	for i := range w.sheets {
+		next <- i
 	}
-	if err := w.writePassword(buf); err != nil {
-		return err
+	close(next)
+	wg.Wait()
+	for _, c := range counts {
+		for recType, n := range c {
+			w.recordCounts[recType] += n
+		}
 	}
 
-	if err := w.writeRowsAndCells(buf, sst); err != nil {
-		return err
+	// The error of the first sheet that failed, as the sequential path
+	// would have returned
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
 	}
+	return sheets, nil
+}
 
-	// WINDOW2 must come after cell data
-	if err := w.writeWindow2(buf); err != nil {
-		return err
+// sheetWorkers returns how many sheets are written at once.
+func (w *Writer) sheetWorkers() int {
+	n := w.concurrency
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
 	}
+	return min(n, len(w.sheets))
+}
 
-	if err := w.writeEOF(buf); err != nil {
-		return err
+// writeSheet writes the worksheet substream of s, adding its strings to sst.
+// The positions of an INDEX record are relative to the start of writer.
+func (w *Writer) writeSheet(writer streamBuffer, s *SheetWriter, sst *sharedStringTable) error {
+	st := &substream{buf: writer, sst: sst, sheet: s, ext: s.sheetExtents()}
+	return w.writeSequence(worksheetRecords, st)
+}
+
+// optional writes a record that only restates a default, unless the Writer
+// was configured with WithMinimalRecords.
+//
+// Omitted in minimal mode, all optional in the [MS-XLS] substream grammar:
+//   - Globals: INTERFACEHDR, MMS and INTERFACEEND (the user interface
+//     block), WRITEACCESS, the undocumented 0x009C record, OBJPROTECT (not
+//     part of the globals grammar at all), BACKUP, HIDEOBJ, REFRESHALL and
+//     BOOKBOOL, which all hold their default values.
+//   - Worksheets: the empty page breaks, HEADER and FOOTER, HCENTER,
+//     VCENTER, the margins (the defaults apply when absent) and the
+//     protection block, which only says the sheet is unprotected.
+//
+// Kept even in minimal mode: records the grammar requires (CODEPAGE, DSF,
+// the workbook protection block, WINDOW1, the fonts, XFs and STYLE,
+// BOUNDSHEET, DIMENSIONS, WINDOW2) and the worksheet calculation, print and
+// GUTS/WSBOOL records, which readers are known to consult without a
+// fallback. Files written this way are checked against this package's
+// reader only.
+func (w *Writer) optional(writer io.Writer, write func(io.Writer) error) error {
+	if w.omitsOptional() {
+		return nil
 	}
+	return write(writer)
+}
 
-	return nil
+// omitsOptional reports whether the records optional leaves out are left
+// out.
+func (w *Writer) omitsOptional() bool {
+	return w.minimal && !w.spec().keepOptional
 }
 
-// Close releases resources.
+// Close releases resources. It removes the temporary files that writes
+// could not remove and returns an error for each one it cannot remove
+// either.
 func (w *Writer) Close() error {
-	return nil
+	return w.tempFiles.remove()
 }
 
 func (w *Writer) writeBOF(writer io.Writer, subType uint16) error {
 	data := make([]byte, 16)
 	binary.LittleEndian.PutUint16(data[0:2], biffVersion)
 	binary.LittleEndian.PutUint16(data[2:4], subType)
-	binary.LittleEndian.PutUint16(data[4:6], 0x0DBB) // Build identifier (Excel 2000)
-	binary.LittleEndian.PutUint16(data[6:8], 0x07CC) // Build year (1996)
+	build, year := uint16(defaultBOFBuild), uint16(defaultBOFYear)
+	if w.identity != nil {
+		build, year = w.identity.build, w.identity.year
+	}
+	binary.LittleEndian.PutUint16(data[4:6], build)
+	binary.LittleEndian.PutUint16(data[6:8], year)
 	binary.LittleEndian.PutUint32(data[8:12], 0x00000000)
 	binary.LittleEndian.PutUint32(data[12:16], 0x00000006) // Lowest BIFF version
 	return w.writeRecord(writer, recTypeBOF, data)
 }
 
+// writeRecalcID writes the RECALCID record Excel 2000 and later add to
+// identify the build that last calculated the workbook. It is only written
+// with a configured BOF identity.
+func (w *Writer) writeRecalcID(writer io.Writer) error {
+	if w.identity == nil {
+		return nil
+	}
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint16(data[0:2], recTypeRECALCID) // Future record header
+	binary.LittleEndian.PutUint32(data[4:8], uint32(w.identity.build))
+	return w.writeRecord(writer, recTypeRECALCID, data)
+}
+
 func (w *Writer) writeEOF(writer io.Writer) error {
 	return w.writeRecord(writer, recTypeEOF, []byte{})
 }
 
 func (w *Writer) writeCodePage(writer io.Writer) error {
 	data := make([]byte, 2)
-	binary.LittleEndian.PutUint16(data[0:2], 0x04B0) // UTF-16LE (1200)
+	binary.LittleEndian.PutUint16(data[0:2], codePageUTF16)
+	if w.codePage != 0 {
+		binary.LittleEndian.PutUint16(data[0:2], w.codePage)
+	}
 	return w.writeRecord(writer, recTypeCODEPAGE, data)
 }
 
 func (w *Writer) writeDefaultFont(writer io.Writer) error {
-	fontName := "Arial"
-
-	// FONT record uses compressed string (8-bit)
-	data := make([]byte, 14+1+1+len(fontName))
-	binary.LittleEndian.PutUint16(data[0:2], 200) // Height (200 = 10pt)
-	binary.LittleEndian.PutUint16(data[2:4], 0)
-	binary.LittleEndian.PutUint16(data[4:6], 0x7FFF) // Color index
-	binary.LittleEndian.PutUint16(data[6:8], 400) // Weight
-	binary.LittleEndian.PutUint16(data[8:10], 0)
-	data[10] = 0
-	data[11] = 0
-	data[12] = 1 // Character set (1 = default)
-	data[13] = 0
-	data[14] = byte(len(fontName))
-	data[15] = 0x00 // Compressed string (8-bit)
-	copy(data[16:], []byte(fontName))
-
-	return w.writeRecord(writer, recTypeFONT, data)
+	return w.writeFont(writer, w.theme.font())
 }
 
-func (w *Writer) writeFormat(writer io.Writer) error {
-	formatString := "General"
-
-	data := make([]byte, 2+2+1+len(formatString))
-	binary.LittleEndian.PutUint16(data[0:2], 0x00A4) // Format index (164 = user-defined)
-	binary.LittleEndian.PutUint16(data[2:4], uint16(len(formatString)))
-	data[4] = 0x00 // Compressed string (8-bit)
-	copy(data[5:], []byte(formatString))
+func (w *Writer) writeFormat(writer io.Writer, index uint16, formatString string) error {
+	return w.writeRecord(writer, recTypeFORMAT, formatRecord(index, formatString, w.maxCompressed()))
+}
 
-	return w.writeRecord(writer, recTypeFORMAT, data)
+// formatRecord returns the body of a FORMAT record, with the characters of
+// the format up to maxCompressed written as 8-bit characters.
+func formatRecord(index uint16, formatString string, maxCompressed rune) []byte {
+	data := make([]byte, 2, 2+2+1+2*len(formatString))
+	binary.LittleEndian.PutUint16(data[0:2], index) // Format index (164+ = user-defined)
+	return append(data, encodeText(formatString, 2, maxCompressed)...)
 }
 
-func (w *Writer) writeXF(writer io.Writer, isStyleXF bool, fontIndex uint16) error {
+func (w *Writer) writeXF(writer io.Writer, isStyleXF bool, fontIndex, formatIndex uint16) error {
 	data := make([]byte, 20)
 
 	if isStyleXF {
 		binary.LittleEndian.PutUint16(data[0:2], fontIndex)
-		binary.LittleEndian.PutUint16(data[2:4], 0x00A4) // Format index (164 = General)
+		binary.LittleEndian.PutUint16(data[2:4], formatIndex)
 		binary.LittleEndian.PutUint16(data[4:6], 0xFFF5) // Style XF flag
 		binary.LittleEndian.PutUint16(data[6:8], 0x0020)
 		binary.LittleEndian.PutUint32(data[8:12], 0x0000F400)
@@ -474,7 +711,7 @@ func (w *Writer) writeXF(writer io.Writer, isStyleXF bool, fontIndex uint16) err
 		binary.LittleEndian.PutUint32(data[16:20], 0x20C00000)
 	} else {
 		binary.LittleEndian.PutUint16(data[0:2], fontIndex)
-		binary.LittleEndian.PutUint16(data[2:4], 0x00A4)
+		binary.LittleEndian.PutUint16(data[2:4], formatIndex)
 		binary.LittleEndian.PutUint16(data[4:6], 0x0001) // Parent style XF (XF #0)
 		binary.LittleEndian.PutUint16(data[6:8], 0x0020)
 		binary.LittleEndian.PutUint32(data[8:12], 0x0000F800)
@@ -507,22 +744,13 @@ func (w *Writer) writeWindow1(writer io.Writer) error {
 	return w.writeRecord(writer, recTypeWINDOW1, data)
 }
 
-func (w *Writer) writeWindow2(writer io.Writer) error {
-	data := make([]byte, 18)
-	binary.LittleEndian.PutUint16(data[0:2], 0x06B6)
-	binary.LittleEndian.PutUint16(data[2:4], 0)
-	binary.LittleEndian.PutUint16(data[4:6], 0)
-	binary.LittleEndian.PutUint16(data[6:8], 0x0040)
-	binary.LittleEndian.PutUint16(data[8:10], 0)
-	binary.LittleEndian.PutUint16(data[10:12], 0)
-	binary.LittleEndian.PutUint32(data[12:16], 0)
-	binary.LittleEndian.PutUint16(data[16:18], 0)
-	return w.writeRecord(writer, recTypeWINDOW2, data)
-}
-
 func (w *Writer) writeDefColWidth(writer io.Writer) error {
+	width := uint16(8)
+	if w.defColWidth != 0 {
+		width = uint16(w.defColWidth) // Whole characters, without the padding
+	}
 	data := make([]byte, 2)
-	binary.LittleEndian.PutUint16(data[0:2], 8)
+	binary.LittleEndian.PutUint16(data[0:2], width)
 	return w.writeRecord(writer, recTypeDEFCOLWIDTH, data)
 }
 
@@ -533,12 +761,6 @@ func (w *Writer) writeDefaultRowHeight(writer io.Writer) error {
 	return w.writeRecord(writer, recTypeDEFAULTROWHEIGHT, data)
 }
 
-func (w *Writer) writeWSBool(writer io.Writer) error {
-	data := make([]byte, 2)
-	binary.LittleEndian.PutUint16(data[0:2], 0x04C1)
-	return w.writeRecord(writer, recTypeWSBOOL, data)
-}
-
 func (w *Writer) writeBookBool(writer io.Writer) error {
 	data := make([]byte, 2)
 	binary.LittleEndian.PutUint16(data[0:2], 0)
@@ -561,15 +783,30 @@ func (w *Writer) writeInterfaceEnd(writer io.Writer) error {
 	return w.writeRecord(writer, recTypeINTERFACEEND, []byte{})
 }
 
+// writeAccessUser is the user name recorded in the WRITEACCESS record.
+const writeAccessUser = "Go XLS Writer"
+
 func (w *Writer) writeWriteAccess(writer io.Writer) error {
-	// Fixed length: 112 bytes, space-padded
+	data, err := writeAccessRecord(writeAccessUser)
+	if err != nil {
+		return err
+	}
+	return w.writeRecord(writer, recTypeWRITEACCESS, data)
+}
+
+// writeAccessRecord returns the body of a WRITEACCESS record: the user name
+// as a unicode string, padded with spaces to the fixed 112 bytes.
+func writeAccessRecord(user string) ([]byte, error) {
+	name := encodeUnicodeString(user, 2)
+	if len(name) > 112 {
+		return nil, fmt.Errorf("user name %q is too long for the WRITEACCESS record", user)
+	}
 	data := make([]byte, 112)
-	username := "Go XLS Writer"
-	copy(data, []byte(username))
-	for i := len(username); i < 112; i++ {
+	copy(data, name)
+	for i := len(name); i < len(data); i++ {
 		data[i] = 0x20
 	}
-	return w.writeRecord(writer, recTypeWRITEACCESS, data)
+	return data, nil
 }
 
 func (w *Writer) writeDateMode(writer io.Writer) error {
@@ -580,7 +817,7 @@ func (w *Writer) writeDateMode(writer io.Writer) error {
 
 func (w *Writer) writePrecision(writer io.Writer) error {
 	data := make([]byte, 2)
-	binary.LittleEndian.PutUint16(data[0:2], 1) // 1 = calculate with displayed precision
+	binary.LittleEndian.PutUint16(data[0:2], 1) // 1 = full precision, precision as displayed is off
 	return w.writeRecord(writer, recTypePRECISION, data)
 }
 
@@ -632,12 +869,6 @@ func (w *Writer) writePrintHeaders(writer io.Writer) error {
 	return w.writeRecord(writer, recTypePRINTHEADERS, data)
 }
 
-func (w *Writer) writePrintGridlines(writer io.Writer) error {
-	data := make([]byte, 2)
-	binary.LittleEndian.PutUint16(data[0:2], 0)
-	return w.writeRecord(writer, recTypePRINTGRIDLINES, data)
-}
-
 func (w *Writer) writeProtect(writer io.Writer) error {
 	data := make([]byte, 2)
 	binary.LittleEndian.PutUint16(data[0:2], 0)
@@ -740,32 +971,20 @@ func (w *Writer) writeVCenter(writer io.Writer) error {
 	return w.writeRecord(writer, recTypeVCENTER, data)
 }
 
-func (w *Writer) writeSetup(writer io.Writer) error {
-	data := make([]byte, 34)
-	binary.LittleEndian.PutUint16(data[0:2], 1)
-	binary.LittleEndian.PutUint16(data[2:4], 100)
-	binary.LittleEndian.PutUint16(data[4:6], 1)
-	binary.LittleEndian.PutUint16(data[6:8], 1)
-	binary.LittleEndian.PutUint16(data[8:10], 1)
-	binary.LittleEndian.PutUint16(data[10:12], 0x0000)
-	binary.LittleEndian.PutUint16(data[12:14], 600)
-	binary.LittleEndian.PutUint16(data[14:16], 600)
-	binary.LittleEndian.PutUint16(data[16:18], 1)
-	return w.writeRecord(writer, recTypeSETUP, data)
-}
-
 func (w *Writer) writeGridSet(writer io.Writer) error {
 	data := make([]byte, 2)
 	binary.LittleEndian.PutUint16(data[0:2], 1)
 	return w.writeRecord(writer, recTypeGRIDSET, data)
 }
 
-func (w *Writer) writeGuts(writer io.Writer) error {
+// writeGuts writes the GUTS record, with a row gutter wide enough for the
+// outline buttons of the sheet's rows, sized as Excel sizes it.
+func (w *Writer) writeGuts(writer io.Writer, s *SheetWriter) error {
 	data := make([]byte, 8)
-	binary.LittleEndian.PutUint16(data[0:2], 0)
-	binary.LittleEndian.PutUint16(data[2:4], 0)
-	binary.LittleEndian.PutUint16(data[4:6], 0)
-	binary.LittleEndian.PutUint16(data[6:8], 0)
+	if level := s.outlineLevel(); level > 0 {
+		binary.LittleEndian.PutUint16(data[0:2], uint16(29+12*level)) // dxRwGut
+		binary.LittleEndian.PutUint16(data[4:6], uint16(level+1))     // iLevelRwMax
+	}
 	return w.writeRecord(writer, recTypeGUTS, data)
 }
 
@@ -826,115 +1045,256 @@ func (w *Writer) writeBoundSheet(writer io.Writer, offset uint32, sheetName stri
 	return w.writeRecord(writer, recTypeBOUNDSHEET, data)
 }
 
-func (w *Writer) writeDimensions(writer io.Writer) error {
-	rowCount := uint32(len(w.data))
-	colCount := uint16(0)
-	for _, row := range w.data {
-		if uint16(len(row)) > colCount {
-			colCount = uint16(len(row))
-		}
-	}
-
+func (w *Writer) writeDimensions(writer io.Writer, ext sheetExtents) error {
 	data := make([]byte, 14)
-	binary.LittleEndian.PutUint32(data[0:4], 0)
-	binary.LittleEndian.PutUint32(data[4:8], rowCount) // Last row + 1
-	binary.LittleEndian.PutUint16(data[8:10], 0)
-	binary.LittleEndian.PutUint16(data[10:12], colCount) // Last column + 1
+	binary.LittleEndian.PutUint32(data[0:4], uint32(ext.firstRow))
+	binary.LittleEndian.PutUint32(data[4:8], uint32(ext.lastRow)) // Last row + 1
+	binary.LittleEndian.PutUint16(data[8:10], uint16(ext.firstCol))
+	binary.LittleEndian.PutUint16(data[10:12], uint16(ext.lastCol)) // Last column + 1
 	binary.LittleEndian.PutUint16(data[12:14], 0)
 
 	return w.writeRecord(writer, recTypeDIMENSIONS, data)
 }
 
-func (w *Writer) writeRowsAndCells(writer io.Writer, sst *sharedStringTable) error {
-	for rowIndex, row := range w.data {
-		if err := w.writeRow(writer, uint16(rowIndex), uint16(len(row))); err != nil {
+// writeRowsAndCells writes the ROW and cell records of the sheet. With a
+// profile that writes INDEX records, the rows go in blocks of rowBlockSize:
+// their ROW records, their cells and a DBCELL record, whose positions in
+// writer it returns.
+func (w *Writer) writeRowsAndCells(writer streamBuffer, s *SheetWriter, sst *sharedStringTable, ext sheetExtents) ([]int, error) {
+	if !w.spec().index {
+		for _, re := range ext.rows {
+			if err := w.writeRow(writer, s, re); err != nil {
+				return nil, err
+			}
+			if err := w.writeRowCells(writer, s, sst, re); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	var dbCells []int
+	for start := 0; start < len(ext.rows); start += rowBlockSize {
+		block := ext.rows[start:min(start+rowBlockSize, len(ext.rows))]
+		firstRow := writer.Len()
+		for _, re := range block {
+			if err := w.writeRow(writer, s, re); err != nil {
+				return nil, err
+			}
+		}
+		cells := make([]int, len(block))
+		for i, re := range block {
+			cells[i] = writer.Len()
+			if err := w.writeRowCells(writer, s, sst, re); err != nil {
+				return nil, err
+			}
+		}
+		dbCells = append(dbCells, writer.Len())
+		if err := w.writeDBCell(writer, firstRow, cells); err != nil {
+			return nil, err
+		}
+	}
+	return dbCells, nil
+}
+
+// writeRowCells writes the cell records of a row, with runs of styled
+// blanks as MULBLANK records.
+func (w *Writer) writeRowCells(writer io.Writer, s *SheetWriter, sst *sharedStringTable, re rowExtent) error {
+	rowIndex := re.row
+	var row []interface{}
+	if rowIndex < len(s.data) {
+		row = s.data[rowIndex]
+	}
+
+	cellAt := func(col int) interface{} {
+		if col < len(row) {
+			return row[col]
+		}
+		return nil
+	}
+	for colIndex := re.firstCol; colIndex < re.lastCol; {
+		var run []uint16
+		for c := colIndex; c < re.lastCol; c++ {
+			if s.cols != nil && s.cols.present(rowIndex, c) {
+				break
+			}
+			xf, ok := s.blankXF(rowIndex, c, cellAt(c))
+			if !ok {
+				break
+			}
+			run = append(run, xf)
+		}
+		r, col, err := cellIndex(rowIndex, colIndex)
+		if err != nil {
 			return err
 		}
+		if len(run) > 0 {
+			if err := w.writeBlanks(writer, r, col, run); err != nil {
+				return err
+			}
+			colIndex += len(run)
+			continue
+		}
 
-		for colIndex, cell := range row {
-			if err := w.writeCell(writer, uint16(rowIndex), uint16(colIndex), cell, sst); err != nil {
+		if s.cols != nil {
+			err = w.writeColumnCell(writer, s, r, col, sst)
+		} else {
+			err = w.writeCell(writer, s, r, col, cellAt(colIndex), sst)
+		}
+		if err != nil {
+			if err := w.cellFailed(writer, s, r, col, err); err != nil {
 				return err
 			}
 		}
+		colIndex++
 	}
 	return nil
 }
 
-func (w *Writer) writeRow(writer io.Writer, rowIndex, colCount uint16) error {
+// writeRow writes the ROW record of a row extent.
+func (w *Writer) writeRow(writer io.Writer, s *SheetWriter, re rowExtent) error {
+	rowIndex, _, err := cellIndex(re.row, 0)
+	if err != nil {
+		return err
+	}
+	flags := uint32(0x000F0000)
+	if xf, ok := s.rowXF(re.row); ok {
+		flags = uint32(xf)<<16 | 0x0100 | 0x0080 // ixfe, reserved bit and fGhostDirty
+	}
+	flags |= uint32(s.rowLevels[re.row]) // iOutLevel
+
 	data := make([]byte, 16)
-	binary.LittleEndian.PutUint16(data[0:2], rowIndex)
-	binary.LittleEndian.PutUint16(data[2:4], 0)
-	binary.LittleEndian.PutUint16(data[4:6], colCount) // Last defined column + 1
+	binary.LittleEndian.PutUint16(data[0:2], uint16(rowIndex))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(re.firstCol)) // First defined column
+	binary.LittleEndian.PutUint16(data[4:6], uint16(re.lastCol))  // Last defined column + 1
 	binary.LittleEndian.PutUint16(data[6:8], 0x00FF)
 	binary.LittleEndian.PutUint16(data[8:10], 0)
 	binary.LittleEndian.PutUint16(data[10:12], 0)
-	binary.LittleEndian.PutUint32(data[12:16], 0x000F0000)
+	binary.LittleEndian.PutUint32(data[12:16], flags)
 
 	return w.writeRecord(writer, recTypeROW, data)
 }
 
-func (w *Writer) writeCell(writer io.Writer, row, col uint16, value interface{}, sst *sharedStringTable) error {
+func (w *Writer) writeCell(writer io.Writer, s *SheetWriter, row rowIdx, col colIdx, value interface{}, sst *sharedStringTable) error {
+	xf := s.cellXF(int(row), int(col), value)
+	str, ok, err := s.cellText(int(row), int(col), value)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if sst.inline(str) {
+			return w.writeLabel(writer, row, col, xf, str)
+		}
+		return w.writeLabelSST(writer, row, col, xf, sst.addString(str))
+	}
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	if f, ok := w.numberText(value); ok {
+		return w.writeNumber(writer, row, col, xf, f)
+	}
+
 	switch v := value.(type) {
-	case string:
-		return w.writeLabelSST(writer, row, col, v, sst)
-	case int:
-		return w.writeNumber(writer, row, col, float64(v))
-	case int8:
-		return w.writeNumber(writer, row, col, float64(v))
-	case int16:
-		return w.writeNumber(writer, row, col, float64(v))
-	case int32:
-		return w.writeNumber(writer, row, col, float64(v))
-	case int64:
-		return w.writeNumber(writer, row, col, float64(v))
-	case uint:
-		return w.writeNumber(writer, row, col, float64(v))
-	case uint8:
-		return w.writeNumber(writer, row, col, float64(v))
-	case uint16:
-		return w.writeNumber(writer, row, col, float64(v))
-	case uint32:
-		return w.writeNumber(writer, row, col, float64(v))
-	case uint64:
-		return w.writeNumber(writer, row, col, float64(v))
-	case float32:
-		return w.writeNumber(writer, row, col, float64(v))
-	case float64:
-		return w.writeNumber(writer, row, col, v)
+	case nil:
+		return nil
 	case bool:
-		return w.writeBool(writer, row, col, v)
-	default:
-		return w.writeLabelSST(writer, row, col, fmt.Sprintf("%v", v), sst)
+		if f, ok := s.boolNumber(int(col), v); ok {
+			return w.writeNumber(writer, row, col, xf, f)
+		}
+		return w.writeBool(writer, row, col, xf, v)
+	case time.Time:
+		return w.writeDate(writer, row, col, xf, v)
+	case CellError:
+		return w.writeError(writer, row, col, xf, v)
+	case FormulaCell:
+		if cached, ok := v.Cached.(string); ok {
+			if err := w.limits.checkText(cached, int(row), int(col), false); err != nil {
+				return err
+			}
+		}
+		if err := w.writeFormula(writer, row, col, xf, v); err != nil {
+			return fmt.Errorf("invalid formula at row %d, column %d: %w", row, col, err)
+		}
+		return nil
 	}
+	f, _ := toFloat64(value)
+	return w.writeNumber(writer, row, col, xf, f)
 }
 
-func (w *Writer) writeLabelSST(writer io.Writer, row, col uint16, value string, sst *sharedStringTable) error {
-	sstIndex := sst.getIndex(value)
+// sstString returns the text a cell value is written with as a shared string,
+// or false if the value is not written as text. It decides which cells
+// writeCell writes as LABELSST records.
+func sstString(value interface{}) (string, bool) {
+	if sc, ok := value.(StyledCell); ok {
+		value = sc.Value
+	}
+	switch v := value.(type) {
+	case nil, bool, time.Time, CellError, FormulaCell:
+		return "", false
+	case string:
+		return cleanText(v), true
+	case RichText:
+		return cleanText(v.Text), true
+	}
+	if _, ok := toFloat64(value); ok {
+		return "", false
+	}
+	return renderValue(value, BytesList), true
+}
 
+func (w *Writer) writeLabelSST(writer io.Writer, row rowIdx, col colIdx, xf uint16, sstIndex int) error {
 	data := make([]byte, 10)
-	binary.LittleEndian.PutUint16(data[0:2], row)
-	binary.LittleEndian.PutUint16(data[2:4], col)
-	binary.LittleEndian.PutUint16(data[4:6], 0)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	binary.LittleEndian.PutUint16(data[4:6], xf)
 	binary.LittleEndian.PutUint32(data[6:10], uint32(sstIndex))
 
 	return w.writeRecord(writer, recTypeLABELSST, data)
 }
 
-func (w *Writer) writeNumber(writer io.Writer, row, col uint16, value float64) error {
+// writeLabel writes a string inline in a LABEL record, or rich text in an
+// RSTRING record, the same followed by its runs.
+func (w *Writer) writeLabel(writer io.Writer, row rowIdx, col colIdx, xf uint16, value string) error {
+	text, runs, rich := splitRichKey(value)
+	data := make([]byte, 6, 6+3+2*len(text)+2+len(runs))
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	binary.LittleEndian.PutUint16(data[4:6], xf)
+	data = append(data, encodeText(text, 2, w.maxCompressed())...)
+	if !rich {
+		return w.writeRecord(writer, recTypeLABEL, data)
+	}
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(runs)/4))
+	data = append(data, runs...)
+
+	return w.writeRecord(writer, recTypeRSTRING, data)
+}
+
+func (w *Writer) writeNumber(writer io.Writer, row rowIdx, col colIdx, xf uint16, value float64) error {
 	data := make([]byte, 14)
-	binary.LittleEndian.PutUint16(data[0:2], row)
-	binary.LittleEndian.PutUint16(data[2:4], col)
-	binary.LittleEndian.PutUint16(data[4:6], 0)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	binary.LittleEndian.PutUint16(data[4:6], xf)
 	binary.LittleEndian.PutUint64(data[6:14], math.Float64bits(value))
 
 	return w.writeRecord(writer, recTypeNUMBER, data)
 }
 
-func (w *Writer) writeBool(writer io.Writer, row, col uint16, value bool) error {
+// writeDate writes a time.Time as a date serial number. The XF chosen by
+// cellXF carries a date or date-time format.
+func (w *Writer) writeDate(writer io.Writer, row rowIdx, col colIdx, xf uint16, value time.Time) error {
+	serial, err := timeToSerial(value, false)
+	if err != nil {
+		return fmt.Errorf("invalid date at row %d, column %d: %w", row, col, err)
+	}
+	return w.writeNumber(writer, row, col, xf, serial)
+}
+
+func (w *Writer) writeBool(writer io.Writer, row rowIdx, col colIdx, xf uint16, value bool) error {
 	data := make([]byte, 8)
-	binary.LittleEndian.PutUint16(data[0:2], row)
-	binary.LittleEndian.PutUint16(data[2:4], col)
-	binary.LittleEndian.PutUint16(data[4:6], 0)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	binary.LittleEndian.PutUint16(data[4:6], xf)
 	if value {
 		data[6] = 1
 	} else {
@@ -945,46 +1305,255 @@ func (w *Writer) writeBool(writer io.Writer, row, col uint16, value bool) error
 	return w.writeRecord(writer, recTypeBOOLERR, data)
 }
 
-func (w *Writer) writeSST(writer io.Writer, sst *sharedStringTable) error {
+func (w *Writer) writeError(writer io.Writer, row rowIdx, col colIdx, xf uint16, value CellError) error {
 	data := make([]byte, 8)
-	binary.LittleEndian.PutUint32(data[0:4], uint32(sst.totalCount))
-	binary.LittleEndian.PutUint32(data[4:8], uint32(sst.uniqueCount))
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	binary.LittleEndian.PutUint16(data[4:6], xf)
+	data[6] = byte(value)
+	data[7] = 1 // Error value
 
-	for _, str := range sst.strings {
-		strData, err := encodeStringForSST(str)
-		if err != nil {
+	return w.writeRecord(writer, recTypeBOOLERR, data)
+}
+
+func (w *Writer) writeFormula(writer io.Writer, row rowIdx, col colIdx, xf uint16, value FormulaCell) error {
+	rgce, err := compileFormula(value.Expr)
+	if err != nil {
+		return err
+	}
+
+	var result [8]byte
+	var str *string
+	flags := uint16(0x0001) // fAlwaysCalc: no cached result, recalculate on load
+	if value.Cached != nil {
+		if result, str, err = formulaResult(value.Cached); err != nil {
 			return err
 		}
-		data = append(data, strData...)
+		flags = 0
 	}
 
-	return w.writeRecord(writer, recTypeSST, data)
-}
+	data := make([]byte, 22+len(rgce))
+	binary.LittleEndian.PutUint16(data[0:2], uint16(row))
+	binary.LittleEndian.PutUint16(data[2:4], uint16(col))
+	binary.LittleEndian.PutUint16(data[4:6], xf)
+	copy(data[6:14], result[:])
+	binary.LittleEndian.PutUint16(data[14:16], flags)
+	binary.LittleEndian.PutUint32(data[16:20], 0)
+	binary.LittleEndian.PutUint16(data[20:22], uint16(len(rgce)))
+	copy(data[22:], rgce)
 
-func (w *Writer) writeRecord(writer io.Writer, recType uint16, data []byte) error {
-	header := make([]byte, 4)
-	binary.LittleEndian.PutUint16(header[0:2], recType)
-	binary.LittleEndian.PutUint16(header[2:4], uint16(len(data)))
+	if err := w.writeRecord(writer, recTypeFORMULA, data); err != nil {
+		return err
+	}
 
-	if _, err := writer.Write(header); err != nil {
+	if str != nil {
+		return w.writeString(writer, *str)
+	}
+	return nil
+}
+
+// writeString writes the STRING record carrying a cached string formula result.
+func (w *Writer) writeString(writer io.Writer, value string) error {
+	encoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder()
+	utf16, err := encoder.String(value)
+	if err != nil {
 		return err
 	}
-	if len(data) > 0 {
-		if _, err := writer.Write(data); err != nil {
+
+	data := make([]byte, 3+len(utf16))
+	binary.LittleEndian.PutUint16(data[0:2], uint16(len(utf16)/2)) // Character count
+	data[2] = 0x01 // Unicode flag
+	copy(data[3:], utf16)
+
+	return w.writeRecord(writer, recTypeSTRING, data)
+}
+
+// writeSST writes the SST record of sst and the CONTINUE records it
+// overflows into. With bucket set, it returns the position of every bucket
+// of that many strings for the EXTSST record.
+func (w *Writer) writeSST(writer io.Writer, sst *sharedStringTable, bucket int) ([]extSSTEntry, error) {
+	sw := &sstWriter{w: w, out: writer, bucket: bucket, body: make([]byte, 8, maxRecordSize)}
+	binary.LittleEndian.PutUint32(sw.body[0:4], uint32(sst.totalCount))
+	binary.LittleEndian.PutUint32(sw.body[4:8], uint32(sst.uniqueCount))
+
+	if sst.spilled != nil {
+		if err := sst.spilled.writeTo(sw); err != nil {
+			return nil, err
+		}
+	} else {
+		var str []byte
+		for _, s := range sst.strings {
+			str = appendSSTString(str[:0], s)
+			if err := sw.add(str); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sw.ext, sw.flush()
+}
+
+// extSSTEntry is where the first string of an EXTSST bucket starts: its
+// position from the start of the SST record and its offset in the SST or
+// CONTINUE record holding it, both counting record headers.
+type extSSTEntry struct {
+	pos, offset int
+}
+
+// sstWriter writes an SST record, continued in CONTINUE records past
+// maxRecordSize bytes. The character count and flags of a string stay in
+// one record with at least its first character; the characters that do not
+// fit continue in the next record after a repeated flags byte. The runs of
+// rich text that do not fit continue in whole runs, with no flags byte.
+type sstWriter struct {
+	w       *Writer
+	out     io.Writer
+	body    []byte // The record being filled
+	written int    // Bytes of the records before it
+	cont    bool   // The record is a CONTINUE record
+
+	bucket  int // Strings per EXTSST bucket, 0 without EXTSST
+	strings int
+	ext     []extSSTEntry
+}
+
+// add appends a string encoded by appendSSTString.
+func (sw *sstWriter) add(str []byte) error {
+	head, runs := 3, 0
+	if str[2]&0x08 != 0 {
+		head, runs = 5, 4*int(binary.LittleEndian.Uint16(str[3:5]))
+	}
+	if len(sw.body)+min(len(str), head+2) > maxRecordSize {
+		if err := sw.flush(); err != nil {
 			return err
 		}
 	}
+	if sw.bucket > 0 && sw.strings%sw.bucket == 0 {
+		sw.ext = append(sw.ext, extSSTEntry{pos: sw.written + 4 + len(sw.body), offset: 4 + len(sw.body)})
+	}
+	sw.strings++
+
+	sw.body = append(sw.body, str[:head]...)
+	for chars := str[head : len(str)-runs]; len(chars) > 0; {
+		room := (maxRecordSize - len(sw.body)) &^ 1 // Whole characters
+		if room == 0 {
+			if err := sw.flush(); err != nil {
+				return err
+			}
+			sw.body = append(sw.body, str[2]) // Flags of the characters
+			continue
+		}
+		n := min(room, len(chars))
+		sw.body = append(sw.body, chars[:n]...)
+		chars = chars[n:]
+	}
+	for rest := str[len(str)-runs:]; len(rest) > 0; {
+		room := (maxRecordSize - len(sw.body)) &^ 3 // Whole runs
+		if room == 0 {
+			if err := sw.flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		n := min(room, len(rest))
+		sw.body = append(sw.body, rest[:n]...)
+		rest = rest[n:]
+	}
+	return nil
+}
+
+// flush writes the record being filled and starts a CONTINUE record.
+func (sw *sstWriter) flush() error {
+	recType := uint16(recTypeSST)
+	if sw.cont {
+		recType = recTypeCONTINUE
+	}
+	if err := sw.w.writeRecord(sw.out, recType, sw.body); err != nil {
+		return err
+	}
+	sw.written += 4 + len(sw.body)
+	sw.body, sw.cont = sw.body[:0], true
 	return nil
 }
 
+// writeRecord writes a record, checked against the policy of its type: a
+// body longer than the policy allows is an error, and one longer than
+// maxRecordSize continues in CONTINUE records as the policy says.
+func (w *Writer) writeRecord(writer io.Writer, recType uint16, data []byte) error {
+	p := recordPolicyOf(recType)
+	if len(data) > p.maxSize {
+		return fmt.Errorf("%w: %s record 0x%04X of %d bytes, more than %d", ErrOutOfRange, RecordName(recType), recType, len(data), p.maxSize)
+	}
+	if len(data) > maxRecordSize {
+		return w.writeContinued(writer, recType, data)
+	}
+	return w.putRecord(writer, recType, data)
+}
+
+// putRecord writes one record with its header.
+func (w *Writer) putRecord(writer io.Writer, recType uint16, data []byte) error {
+	if w.recordCounts != nil {
+		w.recordCounts[recType]++
+	}
+
+	var header [4]byte
+	binary.LittleEndian.PutUint16(header[0:2], recType)
+	binary.LittleEndian.PutUint16(header[2:4], uint16(len(data)))
+
+	// Records almost always go to a buffer, which does not keep the slices
+	// it is given, so the header and the record bodies of the callers can
+	// stay on the stack. Other writers get a copy of the record.
+	if b, ok := writer.(bufferAt); ok {
+		writer = b.Buffer
+	}
+	if buf, ok := writer.(*bytes.Buffer); ok {
+		buf.Write(header[:])
+		buf.Write(data)
+		return nil
+	}
+	record := make([]byte, 0, len(header)+len(data))
+	record = append(append(record, header[:]...), data...)
+	_, err := writer.Write(record)
+	return err
+}
+
 // sharedStringTable manages the Shared String Table.
 type sharedStringTable struct {
 	strings     []string
 	stringMap   map[string]int
 	uniqueCount int
 	totalCount  int
+
+	// With WithHybridStrings, the occurrences of every string in the data;
+	// strings seen fewer than threshold times are written inline
+	counts    map[string]int
+	threshold int
+
+	// filled is set once fill has added every string; addString then only
+	// looks strings up
+	filled bool
+
+	// Without deduplication, every occurrence is a string of its own:
+	// next is the index of the next one once the table is filled, and
+	// sheetStarts the index of the first string of each sheet
+	noDedup     bool
+	next        int
+	sheetStarts []int
+
+	// The strings once they are moved to a file, which happens when
+	// uniqueCount reaches spillAt, unless it is 0
+	spilled *spilledStrings
+	spillAt int
+	tempDir string // Directory of the file, "" for os.TempDir
+
+	// With PredeclareStrings, the table holds the declared strings from the
+	// start and refs counts the cells referring to them, as sheets written
+	// concurrently look them up
+	refs *atomic.Int64
 }
 
+// maxLabelChars is the longest string a LABEL record holds.
+const maxLabelChars = 255
+
 func newSST() *sharedStringTable {
 	return &sharedStringTable{
 		strings:   make([]string, 0),
@@ -992,17 +1561,138 @@ func newSST() *sharedStringTable {
 	}
 }
 
-func (sst *sharedStringTable) addString(s string) {
+// newStringTable returns an empty SST for a write, or the table of the
+// strings declared with PredeclareStrings. With WithHybridStrings it counts
+// the strings of the data first, using the same decision as writeCell, so
+// inline can tell the rare strings apart.
+func (w *Writer) newStringTable() *sharedStringTable {
+	if w.dict != nil {
+		return w.dict.declaredTable()
+	}
+	sst := newSST()
+	sst.noDedup = !w.dedupStrings()
+	if w.hybrid < 2 {
+		return sst
+	}
+	sst.counts = make(map[string]int)
+	sst.threshold = w.hybrid
+	for _, s := range w.sheets {
+		s.eachText(func(_, _ int, str string) {
+			sst.counts[str]++
+		})
+	}
+	return sst
+}
+
+// inline reports whether s is written in a LABEL record instead of the SST.
+func (sst *sharedStringTable) inline(s string) bool {
+	if sst.counts == nil || sst.counts[s] >= sst.threshold {
+		return false
+	}
+	text, _, _ := splitRichKey(s)
+	return len(text) <= maxLabelChars || len(utf16.Encode([]rune(text))) <= maxLabelChars
+}
+
+// fill adds the strings of the sheets of w to the table in the order
+// writeCell adds them, then freezes it, so the sheets can be written
+// concurrently.
+func (sst *sharedStringTable) fill(w *Writer) {
+	for _, s := range w.sheets {
+		sst.sheetStarts = append(sst.sheetStarts, sst.totalCount)
+		s.eachText(func(_, _ int, str string) {
+			if !sst.inline(str) {
+				sst.addString(str)
+			}
+		})
+	}
+	sst.filled = true
+}
+
+// addString counts an occurrence of s and returns its index in the table.
+// Once the table is filled, it only looks s up.
+func (sst *sharedStringTable) addString(s string) int {
+	if sst.noDedup {
+		return sst.appendString(s)
+	}
+	if sst.spilled != nil {
+		return sst.addSpilled(s)
+	}
+	if sst.filled {
+		if sst.refs != nil {
+			sst.refs.Add(1)
+		}
+		return sst.stringMap[s]
+	}
 	sst.totalCount++
-	if _, exists := sst.stringMap[s]; !exists {
-		sst.stringMap[s] = sst.uniqueCount
+	index, exists := sst.stringMap[s]
+	if !exists {
+		index = sst.uniqueCount
+		sst.stringMap[s] = index
 		sst.strings = append(sst.strings, s)
 		sst.uniqueCount++
+		if sst.uniqueCount == sst.spillAt {
+			// Without a temporary file the table stays in memory
+			if sst.spill() != nil {
+				sst.spillAt = 0
+			}
+		}
+	}
+	return index
+}
+
+// close removes the file of a spilled table, returning its name if it
+// could not be removed.
+func (sst *sharedStringTable) close() (string, error) {
+	if sst.spilled == nil {
+		return "", nil
 	}
+	return sst.spilled.close()
 }
 
-func (sst *sharedStringTable) getIndex(s string) int {
-	return sst.stringMap[s]
+// encodeUnicodeString encodes a string as a BIFF8 unicode string with a
+// character count of lenSize bytes, followed by compressed 8-bit characters
+// when every character fits and by UTF-16LE otherwise. The count is in
+// UTF-16 code units.
+func encodeUnicodeString(s string, lenSize int) []byte {
+	return encodeText(s, lenSize, 0xFF)
+}
+
+// encodeText is encodeUnicodeString with compressed characters up to
+// maxCompressed.
+func encodeText(s string, lenSize int, maxCompressed rune) []byte {
+	runes := []rune(s)
+	compressed := true
+	for _, r := range runes {
+		if r > maxCompressed {
+			compressed = false
+			break
+		}
+	}
+
+	var units []uint16
+	count := len(runes)
+	if !compressed {
+		units = utf16.Encode(runes)
+		count = len(units)
+	}
+
+	out := make([]byte, lenSize+1, lenSize+1+count*2)
+	if lenSize == 1 {
+		out[0] = byte(count)
+	} else {
+		binary.LittleEndian.PutUint16(out[0:2], uint16(count))
+	}
+	if compressed {
+		for _, r := range runes {
+			out = append(out, byte(r))
+		}
+		return out
+	}
+	out[lenSize] = 0x01 // UTF-16LE characters
+	for _, u := range units {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return out
 }
 
 // encodeString encodes a string in BIFF8 format (length + flag + UTF-16LE).
@@ -1024,18 +1714,33 @@ func encodeString(s string) ([]byte, error) {
 
 // encodeStringForSST encodes a string for the SST record.
 func encodeStringForSST(s string) ([]byte, error) {
-	encoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder()
-	utf16, err := encoder.String(s)
-	if err != nil {
-		return nil, err
+	return appendSSTString(nil, s), nil
+}
+
+// appendSSTString appends s to dst as an SST string: a 16-bit character
+// count, the Unicode flag and UTF-16LE characters. Invalid UTF-8 bytes
+// become U+FFFD, one per byte. The key of rich text also sets the rich
+// text flag, with the number of runs after the flags and the runs after
+// the characters.
+func appendSSTString(dst []byte, s string) []byte {
+	s, runs, rich := splitRichKey(s)
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(textLength(s))) // Character count
+	if rich {
+		dst = append(dst, 0x09) // Unicode and rich text flags
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(len(runs)/4))
+	} else {
+		dst = append(dst, 0x01) // Unicode flag
+	}
+	for _, r := range s {
+		if r >= 0x10000 {
+			r1, r2 := utf16.EncodeRune(r)
+			dst = binary.LittleEndian.AppendUint16(dst, uint16(r1))
+			dst = binary.LittleEndian.AppendUint16(dst, uint16(r2))
+			continue
+		}
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(r))
 	}
-
-	result := make([]byte, 3+len(utf16))
-	binary.LittleEndian.PutUint16(result[0:2], uint16(len([]rune(s)))) // Character count
-	result[2] = 0x01 // Unicode flag
-	copy(result[3:], utf16)
-
-	return result, nil
+	return append(dst, runs...)
 }
 
 // Option is a functional option for configuring the Writer.
@@ -1044,19 +1749,112 @@ type Option func(*Writer)
 // WithSheetName sets the sheet name.
 func WithSheetName(name string) Option {
 	return func(w *Writer) {
-		w.sheetName = name
+		w.sheets[0].name = name
+	}
+}
+
+// WithPostWriteVerification makes SaveAs and WriteTo read the produced file
+// back with the package's reader and compare it with the written data before
+// writing it out. A mismatch is returned as a *VerificationError. It is off
+// by default because reading the file back costs about twice as much as
+// writing it.
+func WithPostWriteVerification() Option {
+	return func(w *Writer) {
+		w.verify = true
+	}
+}
+
+// WithCFBVersion4 makes SaveAs and WriteTo write a version 4 compound file,
+// with 4096-byte sectors instead of the 512-byte sectors of version 3. The
+// FAT of a large workbook is then an eighth of the size, and needs no DIFAT
+// sectors up to 436 MiB instead of 6.8 MiB. Excel and LibreOffice read both versions, but
+// some older tools only read version 3, which stays the default.
+func WithCFBVersion4() Option {
+	return func(w *Writer) {
+		w.cfbV4 = true
+	}
+}
+
+// cfbSectorSize returns the size of the sectors of the compound files the
+// Writer produces.
+func (w *Writer) cfbSectorSize() int {
+	if w.cfbV4 {
+		return cfbV4SectorSize
+	}
+	return cfbSectorSize
+}
+
+// WithLogger makes the Writer log the phases of each write at Debug level:
+// the shared string table, the globals and sheet substream offsets, the CFB
+// sectors and a final summary with the number of records of each type.
+// Nothing is logged, or counted, without a logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Writer) {
+		w.logger = logger
+	}
+}
+
+// WithMinimalRecords omits records that are optional in BIFF8 and only
+// restate default values, such as empty page breaks, unprotected sheet
+// protection and the user interface records, for smaller files. By default
+// they are written for maximum compatibility with other readers.
+func WithMinimalRecords() Option {
+	return func(w *Writer) {
+		w.minimal = true
+	}
+}
+
+// bofIdentity is the application build recorded in BOF records.
+type bofIdentity struct {
+	build, year uint16
+}
+
+// WithBOFIdentity sets the build identifier and build year written in every
+// BOF record, instead of those of Excel 2000 (build 0x0DBB, year 1996), and
+// adds a RECALCID record with the build. It is meant for consumers that
+// identify files by these fields.
+func WithBOFIdentity(build, year uint16) Option {
+	return func(w *Writer) {
+		w.identity = &bofIdentity{build: build, year: year}
+	}
+}
+
+// WithHybridStrings writes strings that occur fewer than threshold times in
+// the data inline as LABEL records, or RSTRING records for RichText with
+// runs, and only the repeated ones to the shared string table, as Excel
+// itself does for some files. It suits data where
+// most strings are unique, such as log exports. Strings longer than 255
+// characters, which LABEL cannot hold, always go to the SST. A threshold
+// below 2 writes every string to the SST, the default.
+func WithHybridStrings(threshold int) Option {
+	return func(w *Writer) {
+		w.hybrid = threshold
+	}
+}
+
+// WithConcurrency sets how many sheets of a workbook are serialized at once.
+// By default up to GOMAXPROCS sheets are written concurrently; 1 writes them
+// one after the other. The file is the same either way.
+func WithConcurrency(n int) Option {
+	return func(w *Writer) {
+		w.concurrency = n
+	}
+}
+
+// WithStrictTypes makes writing fail with ErrUnsupportedCellType for cell
+// values of types the Writer does not handle explicitly, such as structs and
+// maps. By default they are written as text, by the rules of FromInterface.
+func WithStrictTypes() Option {
+	return func(w *Writer) {
+		w.strict = true
 	}
 }
 
 // WriteToFile writes the data directly to a file with optional configurations.
 func WriteToFile(filename string, data [][]interface{}, opts ...Option) error {
-	w := New()
+	w := New(opts...)
 	defer w.Close()
 
-	for _, opt := range opts {
-		opt(w)
-	}
-
 	if err := w.Write(data); err != nil {
 		return err
 	}
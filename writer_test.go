@@ -1,8 +1,18 @@
 package xls
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/unicode"
 )
 
 func TestNew(t *testing.T) {
@@ -10,8 +20,8 @@ func TestNew(t *testing.T) {
 	if w == nil {
 		t.Fatal("New() returned nil")
 	}
-	if w.sheetName != "Sheet1" {
-		t.Errorf("Expected default sheet name 'Sheet1', got '%s'", w.sheetName)
+	if w.sheets[0].name != "Sheet1" {
+		t.Errorf("Expected default sheet name 'Sheet1', got '%s'", w.sheets[0].name)
 	}
 	w.Close()
 }
@@ -23,8 +33,8 @@ func TestSetSheetName(t *testing.T) {
 	newName := "TestSheet"
 	w.SetSheetName(newName)
 
-	if w.sheetName != newName {
-		t.Errorf("Expected sheet name '%s', got '%s'", newName, w.sheetName)
+	if w.sheets[0].name != newName {
+		t.Errorf("Expected sheet name '%s', got '%s'", newName, w.sheets[0].name)
 	}
 }
 
@@ -43,8 +53,8 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("Write() failed: %v", err)
 	}
 
-	if len(w.data) != len(data) {
-		t.Errorf("Expected data length %d, got %d", len(data), len(w.data))
+	if len(w.sheets[0].data) != len(data) {
+		t.Errorf("Expected data length %d, got %d", len(data), len(w.sheets[0].data))
 	}
 }
 
@@ -81,6 +91,14 @@ func TestSaveAs(t *testing.T) {
 	if info.Size() == 0 {
 		t.Error("File size is 0")
 	}
+
+	saved, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if err := Verify(saved, "Sheet1", data); err != nil {
+		t.Errorf("Saved file does not match the data: %v", err)
+	}
 }
 
 func TestWriteToFile(t *testing.T) {
@@ -109,6 +127,14 @@ func TestWriteToFile(t *testing.T) {
 	if info.Size() == 0 {
 		t.Error("File size is 0")
 	}
+
+	saved, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if err := Verify(saved, "Sheet1", data); err != nil {
+		t.Errorf("Saved file does not match the data: %v", err)
+	}
 }
 
 func TestWriteToFileWithSheetName(t *testing.T) {
@@ -130,6 +156,14 @@ func TestWriteToFileWithSheetName(t *testing.T) {
 	if _, err := os.Stat(tmpFile); os.IsNotExist(err) {
 		t.Fatal("File was not created")
 	}
+
+	saved, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if err := Verify(saved, sheetName, data); err != nil {
+		t.Errorf("Saved file does not match the data: %v", err)
+	}
 }
 
 func TestWriteEmptyData(t *testing.T) {
@@ -214,9 +248,9 @@ func TestWriteLargeData(t *testing.T) {
 func TestSharedStringTable(t *testing.T) {
 	sst := newSST()
 
-	sst.addString("Hello")
-	sst.addString("World")
-	sst.addString("Hello") // duplicate
+	hello := sst.addString("Hello")
+	world := sst.addString("World")
+	again := sst.addString("Hello") // duplicate
 
 	if sst.uniqueCount != 2 {
 		t.Errorf("Expected uniqueCount 2, got %d", sst.uniqueCount)
@@ -226,12 +260,62 @@ func TestSharedStringTable(t *testing.T) {
 		t.Errorf("Expected totalCount 3, got %d", sst.totalCount)
 	}
 
-	if idx := sst.getIndex("Hello"); idx != 0 {
-		t.Errorf("Expected index 0 for 'Hello', got %d", idx)
+	if hello != 0 || again != 0 {
+		t.Errorf("Expected index 0 for 'Hello', got %d and %d", hello, again)
+	}
+
+	if world != 1 {
+		t.Errorf("Expected index 1 for 'World', got %d", world)
+	}
+}
+
+type sku struct{ code int }
+
+func (s sku) String() string { return fmt.Sprintf("SKU-%03d", s.code) }
+
+func TestSSTCountsMatchCells(t *testing.T) {
+	w := New()
+	defer w.Close()
+	data := [][]interface{}{
+		{"Item", sku{7}, 1.5},
+		{sku{7}, Styled(sku{8}, Style{Font: Font{Bold: true}}), nil},
+		{"Item", map[string]int{"a": 1}, true},
+	}
+	w.Write(data)
+
+	records := writtenRecords(t, w)
+	ssts := recordsOfType(records, recTypeSST)
+	if len(ssts) != 1 {
+		t.Fatalf("Expected 1 SST record, got %d", len(ssts))
 	}
+	total := binary.LittleEndian.Uint32(ssts[0].Data[0:4])
+	unique := binary.LittleEndian.Uint32(ssts[0].Data[4:8])
 
-	if idx := sst.getIndex("World"); idx != 1 {
-		t.Errorf("Expected index 1 for 'World', got %d", idx)
+	labels := recordsOfType(records, recTypeLABELSST)
+	if int(total) != len(labels) {
+		t.Errorf("Expected cstTotal %d to match the LABELSST records, got %d", len(labels), total)
+	}
+	indices := make(map[uint32]bool)
+	for _, rec := range labels {
+		index := binary.LittleEndian.Uint32(rec.Data[6:10])
+		if index >= unique {
+			t.Errorf("LABELSST index %d is out of the %d SST strings", index, unique)
+		}
+		indices[index] = true
+	}
+	if len(indices) != int(unique) {
+		t.Errorf("Expected cstUnique %d to match the strings in use, got %d", len(indices), unique)
+	}
+
+	var buf bytes.Buffer
+	w.WriteTo(&buf)
+	want := [][]interface{}{
+		{"Item", "SKU-007", 1.5},
+		{"SKU-007", "SKU-008", nil},
+		{"Item", "map[a:1]", true},
+	}
+	if err := Verify(buf.Bytes(), "Sheet1", want); err != nil {
+		t.Errorf("Stringified cells do not read back: %v", err)
 	}
 }
 
@@ -272,3 +356,605 @@ func TestEncodeStringForSST(t *testing.T) {
 		t.Errorf("Expected Unicode flag 0x01, got 0x%02x", encoded[2])
 	}
 }
+
+func TestAppendSSTStringMatchesTranscoder(t *testing.T) {
+	encoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder()
+	for _, s := range []string{"", "SST", "Grüße", "日本語", "emoji 😀", "bad \xff\xfe byte", "\xed\xa0\x80"} {
+		units, err := encoder.String(s)
+		if err != nil {
+			t.Fatalf("Transcoder failed on %q: %v", s, err)
+		}
+		want := binary.LittleEndian.AppendUint16(nil, uint16(len(units)/2))
+		want = append(append(want, 0x01), units...)
+		if got := appendSSTString(nil, s); !bytes.Equal(got, want) {
+			t.Errorf("appendSSTString(%q) = % X, expected % X", s, got, want)
+		}
+	}
+}
+
+func TestStrictTypes(t *testing.T) {
+	values := map[string]interface{}{
+		"map":     map[string]int{"a": 1},
+		"struct":  struct{ A int }{1},
+		"channel": make(chan int),
+		"func":    func() {},
+	}
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			data := [][]interface{}{{"ok", 1, value}}
+
+			lenient := New()
+			lenient.Write(data)
+			var buf bytes.Buffer
+			if _, err := lenient.WriteTo(&buf); err != nil {
+				t.Errorf("Lenient WriteTo() failed: %v", err)
+			}
+
+			strict := New(WithStrictTypes())
+			strict.Write(data)
+			_, err := strict.WriteTo(io.Discard)
+			if !errors.Is(err, ErrUnsupportedCellType) {
+				t.Fatalf("Expected ErrUnsupportedCellType, got %v", err)
+			}
+			if want := fmt.Sprintf("%T at row 0, column 2", value); !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected the error to contain %q, got %q", want, err)
+			}
+		})
+	}
+}
+
+func TestHybridStrings(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	data := [][]interface{}{
+		{"Status", "Message"},
+		{"ok", "started"},
+		{"ok", "日本語"},
+		{"error", long},
+		{"ok", Styled("started", Style{Font: Font{Bold: true}})},
+	}
+
+	for _, tt := range []struct {
+		threshold   int
+		wantLabels  int
+		wantStrings uint32 // Distinct strings in the SST
+	}{
+		{0, 0, 7},
+		{1, 0, 7},
+		{2, 4, 3}, // "ok", "started" and the long string
+		{4, 9, 1}, // Nothing repeats 4 times, but the long string never goes inline
+	} {
+		t.Run(fmt.Sprint(tt.threshold), func(t *testing.T) {
+			w := New(WithHybridStrings(tt.threshold))
+			defer w.Close()
+			w.Write(data)
+
+			records := writtenRecords(t, w)
+			if n := len(recordsOfType(records, recTypeLABEL)); n != tt.wantLabels {
+				t.Errorf("Expected %d LABEL records, got %d", tt.wantLabels, n)
+			}
+			sst := recordsOfType(records, recTypeSST)[0].Data
+			total, unique := binary.LittleEndian.Uint32(sst[0:4]), binary.LittleEndian.Uint32(sst[4:8])
+			if labels := len(recordsOfType(records, recTypeLABELSST)); int(total) != labels {
+				t.Errorf("Expected cstTotal %d to match the LABELSST records, got %d", labels, total)
+			}
+			if unique != tt.wantStrings {
+				t.Errorf("Expected %d strings in the SST, got %d", tt.wantStrings, unique)
+			}
+
+			var buf bytes.Buffer
+			w.WriteTo(&buf)
+			if err := Verify(buf.Bytes(), "Sheet1", data); err != nil {
+				t.Errorf("Hybrid strings do not read back: %v", err)
+			}
+		})
+	}
+}
+
+func TestMinimalRecords(t *testing.T) {
+	data := [][]interface{}{
+		{"Name", "Score"},
+		{"Alice", 9.5},
+	}
+	write := func(opts ...Option) []byte {
+		w := New(opts...)
+		defer w.Close()
+		w.Write(data)
+		var buf bytes.Buffer
+		if _, err := w.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	full, minimal := write(), write(WithMinimalRecords())
+
+	if err := Verify(minimal, "Sheet1", data); err != nil {
+		t.Errorf("Minimal file does not read back: %v", err)
+	}
+
+	// Both streams are padded to the CFB minimum, so compare record bytes
+	recordBytes := func(file []byte) ([]Record, int) {
+		stream, err := workbookStream(file)
+		if err != nil {
+			t.Fatalf("workbookStream() failed: %v", err)
+		}
+		records, err := readAllRecords(stream)
+		if err != nil {
+			t.Fatalf("readAllRecords() failed: %v", err)
+		}
+		size := 0
+		for _, rec := range records {
+			size += 4 + len(rec.Data)
+		}
+		return records, size
+	}
+	_, fullSize := recordBytes(full)
+	records, minimalSize := recordBytes(minimal)
+	if minimalSize >= fullSize {
+		t.Errorf("Expected fewer record bytes, got %d vs %d", minimalSize, fullSize)
+	}
+	counts := make(map[uint16]int)
+	for _, rec := range records {
+		counts[rec.Type]++
+	}
+	for _, recType := range []uint16{recTypeINTERFACEHDR, recTypeWRITEACCESS, recTypeHBREAK, recTypeHEADER, recTypeLEFTMARGIN, recTypeSCENPROTECT, recTypeOBJPROTECT} {
+		if counts[recType] != 0 {
+			t.Errorf("Expected no %s record, got %d", RecordName(recType), counts[recType])
+		}
+	}
+	for _, recType := range []uint16{recTypeCODEPAGE, recTypeWINDOW1, recTypeBOUNDSHEET, recTypeSST, recTypeDIMENSIONS, recTypeWINDOW2, recTypeGUTS} {
+		if counts[recType] == 0 {
+			t.Errorf("Expected a %s record", RecordName(recType))
+		}
+	}
+	// The workbook protection block is required in the globals
+	if counts[recTypePROTECT] != 1 || counts[recTypePASSWORD] != 1 {
+		t.Errorf("Expected PROTECT and PASSWORD once in the globals, got %d and %d", counts[recTypePROTECT], counts[recTypePASSWORD])
+	}
+}
+
+func TestBOFIdentity(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		build, year uint16
+		recalcID    bool
+	}{
+		{"default", nil, 0x0DBB, 0x07CC, false},
+		{"custom", []Option{WithBOFIdentity(0x2775, 0x07CD)}, 0x2775, 0x07CD, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(tt.opts...)
+			defer w.Close()
+			records := writtenRecords(t, w)
+
+			bofs := recordsOfType(records, recTypeBOF)
+			if len(bofs) != 2 {
+				t.Fatalf("Expected 2 BOF records, got %d", len(bofs))
+			}
+			for _, bof := range bofs {
+				build := binary.LittleEndian.Uint16(bof.Data[4:6])
+				year := binary.LittleEndian.Uint16(bof.Data[6:8])
+				if build != tt.build || year != tt.year {
+					t.Errorf("Expected build 0x%04X year 0x%04X, got 0x%04X 0x%04X", tt.build, tt.year, build, year)
+				}
+			}
+
+			recalc := recordsOfType(records, recTypeRECALCID)
+			if !tt.recalcID {
+				if len(recalc) != 0 {
+					t.Errorf("Expected no RECALCID record, got %d", len(recalc))
+				}
+				return
+			}
+			if len(recalc) != 1 || binary.LittleEndian.Uint32(recalc[0].Data[4:8]) != uint32(tt.build) {
+				t.Errorf("Expected a RECALCID record with build 0x%04X, got %v", tt.build, recalc)
+			}
+		})
+	}
+}
+
+func TestEncodeUnicodeString(t *testing.T) {
+	tests := []struct {
+		s       string
+		lenSize int
+		want    []byte
+	}{
+		{"Arial", 1, []byte{5, 0x00, 'A', 'r', 'i', 'a', 'l'}},
+		{"café", 2, []byte{4, 0, 0x00, 'c', 'a', 'f', 0xE9}},
+		{"山田", 1, []byte{2, 0x01, 0x71, 0x5C, 0x30, 0x75}},
+		{"😀", 2, []byte{2, 0, 0x01, 0x3D, 0xD8, 0x00, 0xDE}}, // Counted in UTF-16 code units
+	}
+	for _, tt := range tests {
+		got := encodeUnicodeString(tt.s, tt.lenSize)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeUnicodeString(%q, %d) = % X, want % X", tt.s, tt.lenSize, got, tt.want)
+		}
+		decoded, n, err := readUnicodeString(got, tt.lenSize)
+		if err != nil || decoded != tt.s || n != len(got) {
+			t.Errorf("readUnicodeString() = %q, %d, %v; want %q, %d", decoded, n, err, tt.s, len(got))
+		}
+	}
+}
+
+func TestWriteAccessRecord(t *testing.T) {
+	for _, user := range []string{writeAccessUser, "山田太郎"} {
+		data, err := writeAccessRecord(user)
+		if err != nil {
+			t.Fatalf("writeAccessRecord(%q) failed: %v", user, err)
+		}
+		if len(data) != 112 {
+			t.Errorf("Expected 112 bytes, got %d", len(data))
+		}
+		got, n, err := readUnicodeString(data, 2)
+		if err != nil || got != user {
+			t.Errorf("Expected user name %q, got %q (%v)", user, got, err)
+		}
+		if pad := bytes.TrimLeft(data[n:], " "); len(pad) != 0 {
+			t.Errorf("Expected space padding, got % X", data[n:])
+		}
+	}
+
+	if _, err := writeAccessRecord(strings.Repeat("長", 60)); err == nil {
+		t.Error("Expected an error for a user name longer than the record")
+	}
+}
+
+func TestFontRecordName(t *testing.T) {
+	for _, name := range []string{"Arial", "メイリオ"} {
+		data := fontRecord(Font{Name: name, Size: 11}, 0xFF)
+		if height := binary.LittleEndian.Uint16(data[0:2]); height != 220 {
+			t.Errorf("Expected height 220, got %d", height)
+		}
+		got, n, err := readUnicodeString(data[14:], 1)
+		if err != nil || got != name || 14+n != len(data) {
+			t.Errorf("Expected font name %q filling the record, got %q (%v)", name, got, err)
+		}
+	}
+}
+
+// worksheetOrder is the order of the worksheet records the Writer emits, as
+// given by the [MS-XLS] worksheet substream grammar.
+var worksheetOrder = []uint16{
+	recTypeBOF,
+	recTypeCALCMODE, recTypeCALCCOUNT, recTypeREFMODE, recTypeITERATION, recTypeDELTA, recTypeSAVERECALC,
+	recTypePRINTHEADERS, recTypePRINTGRIDLINES, recTypeGRIDSET, recTypeGUTS, recTypeDEFAULTROWHEIGHT, recTypeWSBOOL,
+	recTypeHBREAK, recTypeVBREAK,
+	recTypeHEADER, recTypeFOOTER, recTypeHCENTER, recTypeVCENTER,
+	recTypeLEFTMARGIN, recTypeRIGHTMARGIN, recTypeTOPMARGIN, recTypeBOTTOMMARGIN, recTypeSETUP,
+	recTypePROTECT, recTypeSCENPROTECT, recTypeOBJPROTECT, recTypePASSWORD,
+	recTypeDEFCOLWIDTH, recTypeCOLINFO, recTypeAUTOFILTERINFO,
+	recTypeDIMENSIONS,
+	recTypeROW, // The cell table
+	recTypeWINDOW2, recTypeSCL, recTypePANE, recTypeSELECTION,
+	recTypeSTANDARDWIDTH,
+	recTypeEOF,
+}
+
+func TestWorksheetRecordOrder(t *testing.T) {
+	rank := make(map[uint16]int)
+	for i, recType := range worksheetOrder {
+		rank[recType] = i
+	}
+	cellTable := rank[recTypeROW]
+	for _, recType := range []uint16{recTypeLABELSST, recTypeNUMBER, recTypeBOOLERR, recTypeFORMULA, recTypeSTRING, recTypeBLANK, recTypeMULBLANK} {
+		rank[recType] = cellTable
+	}
+
+	for _, opts := range [][]Option{nil, {WithMinimalRecords()}, {WithDefaultColumnWidth(12)}} {
+		w := New(opts...)
+		first, _ := w.Sheet("Sheet1")
+		first.FreezePanes(1, 1)
+		first.SetZoom(120)
+		first.Protect("secret")
+		w.SetColStyle(1, Style{NumberFormat: "0.00"})
+		w.FormatAsTable(0, 1, 0, 1, TableStyle{AutoFilter: true})
+		w.Write([][]interface{}{{"a", 1}, {FormulaCell{Expr: "B1", Cached: "x"}, nil}})
+		records := writtenRecords(t, w)
+
+		// Skip the globals
+		sheet := 0
+		for i, rec := range records {
+			if rec.Type == recTypeEOF {
+				sheet = i + 1
+				break
+			}
+		}
+
+		last := -1
+		for _, rec := range records[sheet:] {
+			r, ok := rank[rec.Type]
+			if !ok {
+				t.Errorf("Unexpected %s record in the worksheet", RecordName(rec.Type))
+				continue
+			}
+			if r < last {
+				t.Errorf("%s record is out of order", RecordName(rec.Type))
+			}
+			last = r
+		}
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	bold := Style{Font: Font{Bold: true}}
+	fill := Style{Fill: ColorLightYellow}
+	money := Style{NumberFormat: "#,##0.00"}
+
+	// The same logical workbook built three ways: a literal, row by row
+	// with styles set in reverse order, and column by column with styles
+	// overwritten and data replaced
+	builds := []func() *Writer{
+		func() *Writer {
+			w := New(WithSheetName("Report"))
+			w.SetColStyle(2, money)
+			w.SetColStyle(4, fill)
+			w.SetRowStyle(0, bold)
+			w.SetRowStyle(9, fill)
+			w.SetCellStyle(1, 0, fill)
+			w.SetCellStyle(3, 1, bold)
+			w.SetCellStyle(12, 3, money)
+			w.Write([][]interface{}{
+				{"Name", "Kind", "Amount", "Date"},
+				{"Alice", "a", 12.5, day},
+				{"Bob", Styled("b", bold), 7, day},
+				{"Alice", nil, true, "a"},
+			})
+			return w
+		},
+		func() *Writer {
+			w := New(WithSheetName("Report"))
+			w.SetCellStyle(12, 3, money)
+			w.SetCellStyle(3, 1, bold)
+			w.SetCellStyle(1, 0, fill)
+			w.SetRowStyle(9, fill)
+			w.SetRowStyle(0, bold)
+			w.SetColStyle(4, fill)
+			w.SetColStyle(2, money)
+			var data [][]interface{}
+			data = append(data, []interface{}{"Name", "Kind", "Amount", "Date"})
+			data = append(data, []interface{}{"Alice", "a", 12.5, day})
+			data = append(data, []interface{}{"Bob", Styled("b", bold), 7, day})
+			data = append(data, []interface{}{"Alice", nil, true, "a"})
+			w.Write(data)
+			return w
+		},
+		func() *Writer {
+			w := New()
+			w.Write([][]interface{}{{"discarded"}})
+			w.SetColStyle(2, bold)
+			w.SetCellStyle(3, 1, fill)
+			w.SetRowStyle(9, fill)
+			w.SetColStyle(4, fill)
+			w.SetCellStyle(12, 3, money)
+			w.SetCellStyle(1, 0, fill)
+			w.SetColStyle(2, money)
+			w.SetCellStyle(3, 1, bold)
+			w.SetRowStyle(0, bold)
+			columns := [][]interface{}{
+				{"Name", "Alice", "Bob", "Alice"},
+				{"Kind", "a", Styled("b", bold), nil},
+				{"Amount", 12.5, 7, true},
+				{"Date", day, day, "a"},
+			}
+			data := make([][]interface{}, 4)
+			for i := range data {
+				data[i] = make([]interface{}, len(columns))
+			}
+			for col, values := range columns {
+				for row, v := range values {
+					data[row][col] = v
+				}
+			}
+			w.Write(data)
+			w.SetSheetName("Report")
+			return w
+		},
+	}
+
+	for _, opts := range [][]Option{nil, {WithHybridStrings(2)}} {
+		var want []byte
+		for i, build := range builds {
+			w := build()
+			for _, opt := range opts {
+				opt(w)
+			}
+			for run := 0; run < 2; run++ {
+				var buf bytes.Buffer
+				if _, err := w.WriteTo(&buf); err != nil {
+					t.Fatalf("WriteTo() failed: %v", err)
+				}
+				if want == nil {
+					want = buf.Bytes()
+					continue
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("Build %d, run %d: output differs from the first build", i, run)
+				}
+			}
+			w.Close()
+		}
+	}
+}
+
+func TestConcurrentSheets(t *testing.T) {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	bold := Style{Font: Font{Bold: true}}
+
+	// Sheets that share strings, so the SST indices depend on the order the
+	// sheets are walked in
+	build := func(opts ...Option) *Writer {
+		w := New(opts...)
+		for i := 0; i < 6; i++ {
+			s := w.sheets[0]
+			if i > 0 {
+				s, _ = w.AddSheet(fmt.Sprintf("Sheet%d", i+1))
+			}
+			s.SetColStyle(1, Style{NumberFormat: "#,##0.00"})
+			s.SetCellStyle(i+2, 3, Style{Fill: ColorLightYellow})
+			data := [][]interface{}{{"Name", "Amount", "Date", fmt.Sprintf("only in %d", i)}}
+			for r := 0; r < 50; r++ {
+				data = append(data, []interface{}{
+					fmt.Sprintf("item %d", (r*7+i)%30), r * i, day.AddDate(0, 0, r), Styled("total", bold),
+				})
+			}
+			s.Write(data)
+		}
+		return w
+	}
+
+	for _, opts := range [][]Option{nil, {WithHybridStrings(2)}, {WithMinimalRecords()}} {
+		sequential := build(append(opts, WithConcurrency(1))...)
+		var want bytes.Buffer
+		if _, err := sequential.WriteTo(&want); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		for _, n := range []int{2, 4, 16} {
+			var got bytes.Buffer
+			if _, err := build(append(opts, WithConcurrency(n))...).WriteTo(&got); err != nil {
+				t.Fatalf("WriteTo() with %d workers failed: %v", n, err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("Output with %d workers differs from the sequential output", n)
+			}
+		}
+	}
+
+	// The logged record counts add up the same
+	logged := func(n int) string {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+		if _, err := build(WithLogger(logger), WithConcurrency(n)).WriteTo(io.Discard); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		return logs.String()
+	}
+	if want, got := logged(1), logged(4); got != want {
+		t.Errorf("Expected the same log with 4 workers:\n%s\ngot:\n%s", want, got)
+	}
+
+	// The error is that of the first failing sheet
+	w := build(WithStrictTypes(), WithConcurrency(4))
+	w.sheets[2].SetCell(1, 0, struct{}{})
+	w.sheets[4].SetCell(1, 0, []int{1})
+	_, err := w.WriteTo(io.Discard)
+	if !errors.Is(err, ErrUnsupportedCellType) || !strings.Contains(err.Error(), "struct {}") {
+		t.Errorf("Expected the error of the third sheet, got %v", err)
+	}
+}
+
+func TestWorkbookStream(t *testing.T) {
+	newWriter := func() *Writer {
+		w := New(WithSheetName("Data"), WithHeaderRows(1), WithSheetSplitting(3, ""))
+		w.Write([][]interface{}{{"Name", "Score"}, {"Alice", 90}, {"Bob", 85.5}, {"Carol", true}})
+		w.SetColStyle(1, Style{NumberFormat: "0.0"})
+		s, _ := w.AddSheet("Notes")
+		s.Write([][]interface{}{{"written", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}})
+		return w
+	}
+
+	var want bytes.Buffer
+	if _, err := newWriter().WriteTo(&want); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	w := newWriter()
+	stream, err := w.WorkbookStream()
+	if err != nil {
+		t.Fatalf("WorkbookStream() failed: %v", err)
+	}
+	var got bytes.Buffer
+	if err := WriteCFB(&got, stream); err != nil {
+		t.Fatalf("WriteCFB() failed: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("Expected WorkbookStream wrapped by WriteCFB to equal the WriteTo output")
+	}
+	if len(w.sheets) != 2 {
+		t.Errorf("Expected the split sheets restored, got %d sheets", len(w.sheets))
+	}
+
+	if _, err := New(WithNumberLocale("xx")).WorkbookStream(); err == nil {
+		t.Error("Expected the error of an unknown locale")
+	}
+}
+
+// TestSSTContinue writes an SST past the size of a record: strings are
+// split across CONTINUE records, with the EXTSST buckets pointing at the
+// record each of them starts in.
+func TestSSTContinue(t *testing.T) {
+	var row []interface{}
+	for i := range 3000 {
+		row = append(row, fmt.Sprintf("string %d 😀 売上", i))
+	}
+	row = append(row, strings.Repeat("long ", 5000), strings.Repeat("😀", maxTextLength/2))
+	data := make([][]interface{}, 0, len(row)/200+1)
+	for start := 0; start < len(row); start += 200 {
+		data = append(data, row[start:min(start+200, len(row))])
+	}
+
+	for _, opts := range [][]Option{
+		{WithCompatibility(ProfileExcel97)},
+		{WithCompatibility(ProfileExcel97), WithLowMemorySST()},
+	} {
+		w := New(opts...)
+		w.Write(data)
+		var file bytes.Buffer
+		if _, err := w.WriteTo(&file); err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		wb, err := openWorkbook(file.Bytes())
+		if err != nil {
+			t.Fatalf("Failed to open the workbook: %v", err)
+		}
+		for r, cells := range data {
+			for c, want := range cells {
+				if got := wb.Sheets()[0].Cell(r, c).FormattedValue(); got != want {
+					t.Fatalf("Cell (%d, %d): expected %.20q, got %.20q", r, c, want, got)
+				}
+			}
+		}
+
+		records := writtenRecords(t, w)
+		sst, continues := -1, 0
+		starts := make(map[int]bool) // Record positions
+		for i, rec := range records {
+			if len(rec.Data) > maxRecordSize {
+				t.Errorf("%s at %d: %d bytes", RecordName(rec.Type), rec.Offset, len(rec.Data))
+			}
+			switch rec.Type {
+			case recTypeSST:
+				sst = i
+			case recTypeCONTINUE:
+				continues++
+			}
+			starts[rec.Offset] = true
+		}
+		if continues < 20 {
+			t.Errorf("Expected the SST continued in at least 20 records, got %d", continues)
+		}
+
+		ext := recordsOfType(records, recTypeEXTSST)[0].Data
+		stream, _ := w.WorkbookStream()
+		n := int(binary.LittleEndian.Uint16(ext))
+		for j, i := 2, 0; j < len(ext); j, i = j+8, i+n {
+			pos, off := int(binary.LittleEndian.Uint32(ext[j:])), int(binary.LittleEndian.Uint16(ext[j+4:]))
+			if !starts[pos-off] || pos-off < records[sst].Offset {
+				t.Errorf("Bucket %d: offset %d does not lead to a record start from %d", i, off, pos)
+			}
+			want := appendSSTString(nil, row[i].(string))[:3]
+			if got := stream[pos : pos+3]; !bytes.Equal(got, want) {
+				t.Errorf("Bucket %d: expected the string header % X at %d, got % X", i, want, pos, got)
+			}
+		}
+	}
+}